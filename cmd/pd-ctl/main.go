@@ -27,13 +27,23 @@ import (
 )
 
 var (
-	url    string
-	detach bool
+	url      string
+	detach   bool
+	output   string
+	caPath   string
+	certPath string
+	keyPath  string
+	token    string
 )
 
 func init() {
 	flag.StringVarP(&url, "pd", "u", "http://127.0.0.1:2379", "The pd address")
 	flag.BoolVarP(&detach, "detach", "d", false, "Run pdctl without readline")
+	flag.StringVarP(&output, "output", "o", "", "The output format, json or table")
+	flag.StringVar(&caPath, "cacert", "", "The path of the CA cert to verify pd in https")
+	flag.StringVar(&certPath, "cert", "", "The path of the cert presented to pd")
+	flag.StringVar(&keyPath, "key", "", "The path of the key of the cert")
+	flag.StringVar(&token, "token", "", "The token to access the pd apis which require authentication")
 }
 
 func main() {
@@ -82,6 +92,7 @@ func loop() {
 	l, err := readline.NewEx(&readline.Config{
 		Prompt:            "\033[31m»\033[0m ",
 		HistoryFile:       "/tmp/readline.tmp",
+		AutoComplete:      pdctl.GetCompleter(url),
 		InterruptPrompt:   "^C",
 		EOFPrompt:         "exit",
 		HistorySearchFold: true,
@@ -104,6 +115,18 @@ func loop() {
 		}
 		args := strings.Split(strings.TrimSpace(line), " ")
 		args = append(args, "-u", url)
+		if output != "" {
+			args = append(args, "-o", output)
+		}
+		if caPath != "" {
+			args = append(args, "--cacert", caPath)
+		}
+		if certPath != "" {
+			args = append(args, "--cert", certPath, "--key", keyPath)
+		}
+		if token != "" {
+			args = append(args, "--token", token)
+		}
 		pdctl.Start(args)
 	}
 }
@@ -0,0 +1,61 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/ngaut/log"
+	"github.com/pingcap/pd/simulator"
+)
+
+var (
+	pdAddrs     = flag.String("pd", "http://127.0.0.1:2379", "pd addresses of a fresh cluster, separated by comma, the first one should be the leader")
+	stores      = flag.Int("stores", 3, "number of stores")
+	regions     = flag.Int("regions", 10000, "number of regions")
+	regionRate  = flag.Int("region-rate", 0, "region heartbeats sent per second, 0 sends as fast as possible")
+	storeRate   = flag.Int("store-rate", 10, "store heartbeats sent per second, 0 sends as fast as possible")
+	concurrency = flag.Int("C", 10, "number of connections sending the region heartbeats")
+	duration    = flag.Duration("duration", time.Minute, "duration of the benchmark")
+)
+
+func main() {
+	flag.Parse()
+
+	cfg := simulator.NewConfig()
+	cfg.StoreCount = *stores
+	cfg.RegionCount = *regions
+	if cfg.Replicas > cfg.StoreCount {
+		cfg.Replicas = cfg.StoreCount
+	}
+
+	sim := simulator.NewSimulator(*pdAddrs, cfg)
+	defer sim.Close()
+	if err := sim.Bootstrap(); err != nil {
+		log.Fatalf("bootstrap err %v", err)
+	}
+
+	result, err := sim.Bench(&simulator.BenchConfig{
+		RegionRate:  *regionRate,
+		StoreRate:   *storeRate,
+		Concurrency: *concurrency,
+		Duration:    *duration,
+	})
+	if err != nil {
+		log.Fatalf("bench err %v", err)
+	}
+	fmt.Println(result)
+}
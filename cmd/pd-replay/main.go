@@ -0,0 +1,71 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ngaut/log"
+	"github.com/pingcap/pd/server"
+)
+
+var (
+	captureFile = flag.String("capture", "heartbeat.capture", "capture file downloaded from /pd/api/v1/cluster/capture")
+	verbose     = flag.Bool("v", false, "print all the diverged responses")
+)
+
+func main() {
+	flag.Parse()
+
+	f, err := os.Open(*captureFile)
+	if err != nil {
+		log.Fatalf("open capture err %v", err)
+	}
+	defer f.Close()
+	replayer, err := server.NewReplayer(f)
+	if err != nil {
+		log.Fatalf("read capture err %v", err)
+	}
+	defer replayer.Close()
+
+	snap := replayer.Header().Snapshot
+	fmt.Printf("cluster %d captured at %v, %d stores, %d regions, %d operators\n",
+		snap.ClusterID, snap.CreateTime, len(snap.Stores), len(snap.Regions), len(snap.Operators))
+
+	var total, diverged int
+	for {
+		result, err := replayer.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// A capture aborted by the leader is truncated.
+			log.Errorf("replay stops at heartbeat %d: %v", total+1, err)
+			break
+		}
+		total++
+		if !result.Diverged {
+			continue
+		}
+		diverged++
+		if *verbose || diverged == 1 {
+			fmt.Printf("heartbeat %d at %v diverged:\n  request:  %v\n  captured: %v\n  replayed: %v\n",
+				total, result.Record.Time, result.Record.Request, result.Record.Response, result.Response)
+		}
+	}
+	fmt.Printf("%d heartbeats are replayed, %d responses diverged\n", total, diverged)
+}
@@ -18,14 +18,20 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
+	"github.com/pingcap/pd/pkg/failpoint"
 	"github.com/pingcap/pd/pkg/metricutil"
 	"github.com/pingcap/pd/server"
 	"github.com/pingcap/pd/server/api"
 )
 
+// gracefulCloseTimeout is the max time to hand off the leadership and drain
+// requests when the server is terminated.
+const gracefulCloseTimeout = 10 * time.Second
+
 func main() {
 	cfg := server.NewConfig()
 	err := cfg.Parse(os.Args[1:])
@@ -52,6 +58,12 @@ func main() {
 
 	metricutil.Push(&cfg.Metric)
 
+	if cfg.EnableFailpoint {
+		if err = failpoint.EnableAll(os.Getenv("PD_FAILPOINTS")); err != nil {
+			log.Fatalf("enable failpoints err %s\n", err)
+		}
+	}
+
 	svr := server.CreateServer(cfg)
 	err = svr.StartEtcd(api.NewHandler(svr))
 	if err != nil {
@@ -68,7 +80,17 @@ func main() {
 	go svr.Run()
 
 	sig := <-sc
-	svr.Close()
+	for sig == syscall.SIGHUP {
+		if _, err = svr.ReloadConfig(); err != nil {
+			log.Errorf("reload config err %s", errors.ErrorStack(err))
+		}
+		sig = <-sc
+	}
+	if sig == syscall.SIGTERM {
+		svr.GracefulClose(gracefulCloseTimeout)
+	} else {
+		svr.Close()
+	}
 	log.Infof("Got signal [%d] to exit.", sig)
 	switch sig {
 	case syscall.SIGTERM:
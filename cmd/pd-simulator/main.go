@@ -0,0 +1,72 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ngaut/log"
+	"github.com/pingcap/pd/simulator"
+)
+
+var (
+	pdAddrs    = flag.String("pd", "http://127.0.0.1:2379", "pd addresses of a fresh cluster, separated by comma")
+	configFile = flag.String("config", "", "workload config file, the default workload is used if it is not set")
+	duration   = flag.Duration("duration", 0, "stop after the duration, run until interrupted if it is 0")
+)
+
+func main() {
+	flag.Parse()
+
+	cfg := simulator.NewConfig()
+	if *configFile != "" {
+		var err error
+		if cfg, err = simulator.LoadConfig(*configFile); err != nil {
+			log.Fatalf("load config err %v", err)
+		}
+	}
+
+	sim := simulator.NewSimulator(*pdAddrs, cfg)
+	defer sim.Close()
+	if err := sim.Bootstrap(); err != nil {
+		log.Fatalf("bootstrap err %v", err)
+	}
+
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc,
+		syscall.SIGHUP,
+		syscall.SIGINT,
+		syscall.SIGTERM,
+		syscall.SIGQUIT)
+	quit := make(chan struct{})
+	var timeout <-chan time.Time
+	if *duration > 0 {
+		timeout = time.After(*duration)
+	}
+	go func() {
+		select {
+		case <-sc:
+		case <-timeout:
+		}
+		close(quit)
+	}()
+
+	sim.Run(quit)
+	fmt.Println(sim.Stats())
+}
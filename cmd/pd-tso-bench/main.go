@@ -27,6 +27,7 @@ var (
 	concurrency = flag.Int("C", 100, "concurrency")
 	num         = flag.Int("N", 1000, "number of request per request worker")
 	sleep       = flag.Duration("sleep", time.Millisecond, "sleep time after a request, used to adjust pressure")
+	batch       = flag.Int("batch", 1, "number of async requests sent together by a request worker")
 )
 
 func main() {
@@ -112,9 +113,14 @@ func reqWorker(pdCli pd.Client, statsCh chan *stats) {
 	s := newStats(*num)
 	for i := 0; i < s.count; i++ {
 		start := time.Now()
-		_, _, err := pdCli.GetTS()
-		if err != nil {
-			log.Fatal(err)
+		futures := make([]pd.TSFuture, 0, *batch)
+		for j := 0; j < *batch; j++ {
+			futures = append(futures, pdCli.GetTSAsync())
+		}
+		for _, f := range futures {
+			if _, _, err := f.Wait(); err != nil {
+				log.Fatal(err)
+			}
 		}
 		dur := time.Since(start)
 		s.update(dur)
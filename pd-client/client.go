@@ -18,78 +18,196 @@ import (
 
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
+	"github.com/pingcap/kvproto/pkg/errorpb"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/pkg/apiutil"
+	"github.com/pingcap/pd/pkg/tlsutil"
 )
 
 // Client is a PD (Placement Driver) client.
+// It discovers the PD members and connects the leader, the requests failed
+// on the connection are retried with backoff, e.g. when the leader changes.
 // It should not be used after calling Close().
 type Client interface {
 	// GetClusterID gets the cluster ID from PD.
 	GetClusterID() uint64
 	// GetTS gets a timestamp from PD.
 	GetTS() (int64, int64, error)
-	// GetRegion gets a region and its leader Peer from PD by key.
-	// The region may expire after split. Caller is responsible for caching and
-	// taking care of region change.
+	// GetTSAsync gets a timestamp from PD without blocking the caller. The
+	// concurrent requests are sent to PD in one batch.
+	GetTSAsync() TSFuture
+	// GetRegion gets a region and its leader Peer by key, the region is
+	// cached by the client and got from PD if it is not cached.
+	// The cached region may expire after split or leader change. Caller
+	// should report the region errors by OnRegionError.
 	// Also it may return nil if PD finds no Region for the key temporarily,
 	// client should retry later.
 	GetRegion(key []byte) (*metapb.Region, *metapb.Peer, error)
+	// OnRegionError updates the cached region by the region error returned
+	// by the store. The leader is changed by the hint of NotLeader, and the
+	// region is invalidated by the other errors, e.g. StaleEpoch, so it is
+	// got from PD again.
+	OnRegionError(regionID uint64, regionErr *errorpb.Error)
+	// GetRegionByID gets a region and its leader Peer from PD by id, it is
+	// not cached.
+	GetRegionByID(regionID uint64) (*metapb.Region, *metapb.Peer, error)
+	// GetPrevRegion gets the region before the region of the key and its
+	// leader Peer from PD, it is not cached.
+	GetPrevRegion(key []byte) (*metapb.Region, *metapb.Peer, error)
+	// ScanRegions gets at most limit regions in key order from the region of
+	// the key, the leaders are not included. PD returns 10240 regions at
+	// most for one call.
+	ScanRegions(key []byte, limit int) ([]*metapb.Region, error)
 	// GetStore gets a store from PD by store id.
 	// The store may expire later. Caller is responsible for caching and taking care
 	// of store change.
 	GetStore(storeID uint64) (*metapb.Store, error)
+	// IsBootstrapped returns true if the cluster is bootstrapped.
+	IsBootstrapped() (bool, error)
+	// Bootstrap bootstraps the cluster with the first store and region, it
+	// fails if the cluster is bootstrapped already. It is not retried if the
+	// connection fails, the caller may check IsBootstrapped then.
+	Bootstrap(store *metapb.Store, region *metapb.Region) error
+	// WatchMembers returns a channel which receives the leader and the
+	// members when they change, only the latest ones are kept if the caller
+	// lags behind. The current ones are received first once they are known.
+	// The channel is closed after calling Close().
+	WatchMembers() <-chan *Members
 	// Close closes the client.
 	Close()
 }
 
 type client struct {
-	worker *rpcWorker
+	worker      *rpcWorker
+	regionCache *regionCache
+	metrics     Metrics
+}
+
+// SecurityOption is the paths of the certs and key in PEM, which are used
+// to connect the PD servers in https.
+type SecurityOption struct {
+	CAPath   string
+	CertPath string
+	KeyPath  string
+}
+
+// Option is the options to create a PD client.
+type Option struct {
+	Security SecurityOption
+	// Metrics observes the requests of the client, the prometheus metrics
+	// are used if it is not set.
+	Metrics Metrics
 }
 
 // NewClient creates a PD client.
 func NewClient(pdAddrs []string) (Client, error) {
+	return NewClientWithOption(pdAddrs, Option{})
+}
+
+// NewClientWithSecurity creates a PD client, the servers are verified by the
+// CA, and the cert is presented if it is set.
+func NewClientWithSecurity(pdAddrs []string, security SecurityOption) (Client, error) {
+	return NewClientWithOption(pdAddrs, Option{Security: security})
+}
+
+// NewClientWithOption creates a PD client with the options.
+func NewClientWithOption(pdAddrs []string, option Option) (Client, error) {
 	log.Infof("[pd] create pd client with endpoints %v", pdAddrs)
-	worker, err := newRPCWorker(pdAddrs)
+	tlsCfg, err := tlsutil.SecurityConfig{
+		CAPath:   option.Security.CAPath,
+		CertPath: option.Security.CertPath,
+		KeyPath:  option.Security.KeyPath,
+	}.ToClientTLSConfig()
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	return &client{worker: worker}, nil
+	metrics := option.Metrics
+	if metrics == nil {
+		metrics = prometheusMetrics{}
+	}
+	worker, err := newRPCWorker(pdAddrs, tlsCfg, metrics)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &client{
+		worker:      worker,
+		metrics:     metrics,
+		regionCache: newRegionCache(regionCacheTTL),
+	}, nil
 }
 
 func (c *client) Close() {
 	c.worker.stop(errors.New("[pd] pd-client closing"))
 }
 
+func (c *client) WatchMembers() <-chan *Members {
+	return c.worker.watcher.subscribe()
+}
+
 func (c *client) GetClusterID() uint64 {
 	return c.worker.clusterID
 }
 
+// TSFuture is a future which promises to return a timestamp.
+type TSFuture interface {
+	// Wait gets the physical and logical time, it blocks the caller until the
+	// timestamp is returned.
+	Wait() (int64, int64, error)
+}
+
 func (c *client) GetTS() (int64, int64, error) {
-	req := &tsoRequest{
-		done: make(chan error, 1),
-	}
+	return c.GetTSAsync().Wait()
+}
 
-	start := time.Now()
+func (c *client) GetTSAsync() TSFuture {
+	req := &tsoRequest{requestBase: newRequestBase("tso", c.metrics)}
 	c.worker.requests <- req
-	err := <-req.done
-	requestDuration.WithLabelValues("tso").Observe(time.Since(start).Seconds())
+	return req
+}
 
+func (req *tsoRequest) Wait() (int64, int64, error) {
+	err := req.wait()
 	return req.physical, req.logical, err
 }
 
 func (c *client) GetRegion(key []byte) (*metapb.Region, *metapb.Peer, error) {
+	if region, leader := c.regionCache.search(key); region != nil {
+		regionCacheCounter.WithLabelValues("hit").Inc()
+		return region, leader, nil
+	}
+	regionCacheCounter.WithLabelValues("miss").Inc()
+
 	req := &regionRequest{
+		requestBase: newRequestBase("get_region", c.metrics),
 		pbReq: &pdpb.GetRegionRequest{
 			RegionKey: key,
 		},
-		done: make(chan error, 1),
 	}
 
-	start := time.Now()
 	c.worker.requests <- req
-	err := <-req.done
-	requestDuration.WithLabelValues("get_region").Observe(time.Since(start).Seconds())
+	err := req.wait()
+
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	region, leader := req.pbResp.GetRegion(), req.pbResp.GetLeader()
+	if region != nil {
+		c.regionCache.update(region, leader)
+	}
+	return region, leader, nil
+}
+
+func (c *client) GetRegionByID(regionID uint64) (*metapb.Region, *metapb.Peer, error) {
+	req := &regionByIDRequest{
+		requestBase: newRequestBase("get_region_by_id", c.metrics),
+		pbReq: &pdpb.GetRegionByIDRequest{
+			RegionId: regionID,
+		},
+	}
+
+	c.worker.requests <- req
+	err := req.wait()
 
 	if err != nil {
 		return nil, nil, errors.Trace(err)
@@ -97,18 +215,68 @@ func (c *client) GetRegion(key []byte) (*metapb.Region, *metapb.Peer, error) {
 	return req.pbResp.GetRegion(), req.pbResp.GetLeader(), nil
 }
 
+func (c *client) GetPrevRegion(key []byte) (*metapb.Region, *metapb.Peer, error) {
+	var (
+		region *metapb.Region
+		leader *metapb.Peer
+	)
+	err := c.callAPI("get_prev_region", func(api *apiutil.Client) error {
+		var err error
+		region, leader, err = api.GetPrevRegion(key)
+		return errors.Trace(err)
+	})
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	return region, leader, nil
+}
+
+func (c *client) ScanRegions(key []byte, limit int) ([]*metapb.Region, error) {
+	var regions []*metapb.Region
+	err := c.callAPI("scan_regions", func(api *apiutil.Client) error {
+		var err error
+		regions, err = api.ScanRegions(key, limit)
+		return errors.Trace(err)
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return regions, nil
+}
+
+// callAPI calls the http api of the members until it succeeds, the api is
+// redirected to the leader by the member.
+func (c *client) callAPI(name string, call func(api *apiutil.Client) error) error {
+	start := time.Now()
+	var err error
+	for _, u := range c.worker.getURLs() {
+		var api *apiutil.Client
+		if api, err = apiutil.NewClient(u, requestPDTimeout, c.worker.tlsConfig); err != nil {
+			continue
+		}
+		if err = call(api); err == nil {
+			break
+		}
+		log.Warnf("[pd] failed to call %s api of %s: %v", name, u, err)
+	}
+	c.metrics.ObserveRequest(name, time.Since(start), err)
+	return errors.Trace(err)
+}
+
+func (c *client) OnRegionError(regionID uint64, regionErr *errorpb.Error) {
+	c.regionCache.onRegionError(regionID, regionErr)
+}
+
 func (c *client) GetStore(storeID uint64) (*metapb.Store, error) {
 	req := &storeRequest{
+		requestBase: newRequestBase("get_store", c.metrics),
 		pbReq: &pdpb.GetStoreRequest{
 			StoreId: storeID,
 		},
-		done: make(chan error, 1),
 	}
 
-	start := time.Now()
 	c.worker.requests <- req
-	err := <-req.done
-	requestDuration.WithLabelValues("get_store").Observe(time.Since(start).Seconds())
+	err := req.wait()
 
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -122,3 +290,30 @@ func (c *client) GetStore(storeID uint64) (*metapb.Store, error) {
 	}
 	return store, nil
 }
+
+func (c *client) IsBootstrapped() (bool, error) {
+	req := &isBootstrappedRequest{requestBase: newRequestBase("is_bootstrapped", c.metrics)}
+
+	c.worker.requests <- req
+	err := req.wait()
+
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	return req.pbResp.GetBootstrapped(), nil
+}
+
+func (c *client) Bootstrap(store *metapb.Store, region *metapb.Region) error {
+	req := &bootstrapRequest{
+		requestBase: newRequestBase("bootstrap", c.metrics),
+		pbReq: &pdpb.BootstrapRequest{
+			Store:  store,
+			Region: region,
+		},
+	}
+
+	c.worker.requests <- req
+	err := req.wait()
+
+	return errors.Trace(err)
+}
@@ -14,16 +14,20 @@
 package pd
 
 import (
+	"io"
 	"os"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/juju/errors"
 	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/errorpb"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/pingcap/pd/pkg/testutil"
 	"github.com/pingcap/pd/server"
+	"github.com/pingcap/pd/server/api"
 	"github.com/twinj/uuid"
 )
 
@@ -96,8 +100,8 @@ func (s *testClientSuite) TearDownSuite(c *C) {
 func newServer(c *C) (*server.Server, cleanupFunc) {
 	cfg := server.NewTestSingleConfig()
 
-	s, err := server.NewServer(cfg)
-	c.Assert(err, IsNil)
+	s := server.CreateServer(cfg)
+	c.Assert(s.StartEtcd(api.NewHandler(s)), IsNil)
 
 	go s.Run()
 
@@ -168,6 +172,26 @@ func (s *testClientSuite) TestTSO(c *C) {
 	}
 }
 
+func (s *testClientSuite) TestTSOAsync(c *C) {
+	p, l, err := s.client.GetTS()
+	c.Assert(err, IsNil)
+	last := p<<18 + l
+
+	futures := make([]TSFuture, 0, 100)
+	for i := 0; i < 100; i++ {
+		futures = append(futures, s.client.GetTSAsync())
+	}
+	tss := make(map[int64]struct{}, 100)
+	for _, f := range futures {
+		p, l, err := f.Wait()
+		c.Assert(err, IsNil)
+		ts := p<<18 + l
+		c.Assert(ts, Greater, last)
+		tss[ts] = struct{}{}
+	}
+	c.Assert(tss, HasLen, 100)
+}
+
 func (s *testClientSuite) TestGetRegion(c *C) {
 	heartbeatRegion(c, s.srv)
 
@@ -175,6 +199,40 @@ func (s *testClientSuite) TestGetRegion(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(r, DeepEquals, region)
 	c.Assert(leader, DeepEquals, peer)
+
+	// The region is cached until it is invalidated.
+	c.Assert(s.client.(*client).regionCache.length(), Equals, 1)
+	s.client.OnRegionError(region.GetId(), &errorpb.Error{StaleEpoch: &errorpb.StaleEpoch{}})
+	c.Assert(s.client.(*client).regionCache.length(), Equals, 0)
+	r, _, err = s.client.GetRegion([]byte("a"))
+	c.Assert(err, IsNil)
+	c.Assert(r, DeepEquals, region)
+}
+
+func (s *testClientSuite) TestGetRegionByID(c *C) {
+	heartbeatRegion(c, s.srv)
+
+	r, leader, err := s.client.GetRegionByID(region.GetId())
+	c.Assert(err, IsNil)
+	c.Assert(r, DeepEquals, region)
+	c.Assert(leader, DeepEquals, peer)
+
+	r, _, err = s.client.GetRegionByID(100)
+	c.Assert(err, IsNil)
+	c.Assert(r, IsNil)
+}
+
+func (s *testClientSuite) TestScanRegions(c *C) {
+	heartbeatRegion(c, s.srv)
+
+	regions, err := s.client.ScanRegions([]byte("a"), 10)
+	c.Assert(err, IsNil)
+	c.Assert(regions, DeepEquals, []*metapb.Region{region})
+
+	// The region has no previous region.
+	r, _, err := s.client.GetPrevRegion([]byte("a"))
+	c.Assert(err, IsNil)
+	c.Assert(r, IsNil)
 }
 
 func (s *testClientSuite) TestGetStore(c *C) {
@@ -203,3 +261,53 @@ func (s *testClientSuite) TestGetStore(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(n, IsNil)
 }
+
+func (s *testClientSuite) TestBootstrap(c *C) {
+	bootstrapped, err := s.client.IsBootstrapped()
+	c.Assert(err, IsNil)
+	c.Assert(bootstrapped, IsTrue)
+	c.Assert(s.client.Bootstrap(store, region), NotNil)
+
+	// The bootstrap is not retried if the connection fails.
+	cli := s.client.(*client)
+	req := &bootstrapRequest{requestBase: newRequestBase("bootstrap", cli.metrics)}
+	w := &rpcWorker{}
+	w.failRequest(req, errors.Trace(&connError{err: io.EOF}))
+	c.Assert(w.retrying, HasLen, 0)
+	c.Assert(req.wait(), NotNil)
+}
+
+func (s *testClientSuite) TestDiscoverMembers(c *C) {
+	// The unreachable url is replaced by the urls of the members.
+	cli, err := NewClient(append(s.srv.GetEndpoints(), "http://127.0.0.1:1"))
+	c.Assert(err, IsNil)
+	defer cli.Close()
+	c.Assert(cli.(*client).worker.getURLs(), DeepEquals, s.srv.GetEndpoints())
+}
+
+func (s *testClientSuite) TestWatchMembers(c *C) {
+	cli, err := NewClient(s.srv.GetEndpoints())
+	c.Assert(err, IsNil)
+	ch := cli.WatchMembers()
+
+	select {
+	case members := <-ch:
+		c.Assert(members.Leader, Equals, s.srv.GetAddr())
+		c.Assert(members.Members, HasLen, 1)
+	case <-time.After(10 * time.Second):
+		c.Fatal("failed to watch members")
+	}
+
+	cli.Close()
+	_, ok := <-ch
+	c.Assert(ok, IsFalse)
+	_, ok = <-cli.WatchMembers()
+	c.Assert(ok, IsFalse)
+}
+
+func (s *testClientSuite) TestBackoff(c *C) {
+	backoff := nextBackoff(0)
+	c.Assert(backoff, Equals, minRetryBackoff)
+	c.Assert(nextBackoff(backoff), Equals, 2*minRetryBackoff)
+	c.Assert(nextBackoff(connectPDTimeout), Equals, connectPDTimeout)
+}
@@ -15,6 +15,7 @@ package pd
 
 import (
 	"bufio"
+	"crypto/tls"
 	"net"
 	"strings"
 	"sync"
@@ -45,6 +46,9 @@ type conn struct {
 	quit       chan struct{}
 	ConnChan   chan *conn
 	ReadWriter *bufio.ReadWriter
+	// leaderAddr is the address of the leader if it is connected to the
+	// leader, or it is empty.
+	leaderAddr string
 }
 
 func newConn(c net.Conn) *conn {
@@ -58,19 +62,21 @@ func newConn(c net.Conn) *conn {
 	}
 }
 
-func mustNewConn(urls []string, quit chan struct{}) *conn {
+func mustNewConn(urls []string, tlsCfg *tls.Config, quit chan struct{}) *conn {
 	for {
-		conn, err := rpcConnectLeader(urls)
+		conn, leaderAddr, err := rpcConnectLeader(urls, tlsCfg)
 		if err == nil {
-			return newConn(conn)
+			c := newConn(conn)
+			c.leaderAddr = leaderAddr
+			return c
 		}
 		log.Warn(err)
 
-		conn, err = rpcConnect(urls)
+		conn, err = rpcConnect(urls, tlsCfg)
 		if err == nil {
 			c := newConn(conn)
 			c.wg.Add(1)
-			go c.connectLeader(urls, reconnectPDTimeout)
+			go c.connectLeader(urls, tlsCfg, reconnectPDTimeout)
 			return c
 		}
 		log.Warn(err)
@@ -97,7 +103,7 @@ func (c *conn) Close() {
 	}
 }
 
-func (c *conn) connectLeader(urls []string, interval time.Duration) {
+func (c *conn) connectLeader(urls []string, tlsCfg *tls.Config, interval time.Duration) {
 	defer c.wg.Done()
 
 	ticker := time.NewTicker(interval)
@@ -106,9 +112,11 @@ func (c *conn) connectLeader(urls []string, interval time.Duration) {
 	for {
 		select {
 		case <-ticker.C:
-			conn, err := rpcConnectLeader(urls)
+			conn, leaderAddr, err := rpcConnectLeader(urls, tlsCfg)
 			if err == nil {
-				c.ConnChan <- newConn(conn)
+				leaderConn := newConn(conn)
+				leaderConn.leaderAddr = leaderAddr
+				c.ConnChan <- leaderConn
 				return
 			}
 			log.Warn(err)
@@ -118,9 +126,9 @@ func (c *conn) connectLeader(urls []string, interval time.Duration) {
 	}
 }
 
-func getLeader(urls []string) (*pdpb.Leader, error) {
+func getLeader(urls []string, tlsCfg *tls.Config) (*pdpb.Leader, error) {
 	for _, u := range urls {
-		client, err := apiutil.NewClient(u, connectPDTimeout)
+		client, err := apiutil.NewClient(u, connectPDTimeout, tlsCfg)
 		if err != nil {
 			continue
 		}
@@ -133,19 +141,20 @@ func getLeader(urls []string) (*pdpb.Leader, error) {
 	return nil, errors.Errorf("failed to get leader from %v", urls)
 }
 
-func rpcConnect(urls []string) (net.Conn, error) {
+func rpcConnect(urls []string, tlsCfg *tls.Config) (net.Conn, error) {
 	s := strings.Join(urls, ",")
-	return rpcutil.ConnectUrls(s, connectPDTimeout)
+	return rpcutil.ConnectUrls(s, connectPDTimeout, tlsCfg)
 }
 
-func rpcConnectLeader(urls []string) (net.Conn, error) {
-	leader, err := getLeader(urls)
+// rpcConnectLeader returns the connection to the leader and its address.
+func rpcConnectLeader(urls []string, tlsCfg *tls.Config) (net.Conn, string, error) {
+	leader, err := getLeader(urls, tlsCfg)
 	if err != nil {
-		return nil, errors.Trace(err)
+		return nil, "", errors.Trace(err)
 	}
-	conn, err := rpcutil.ConnectUrls(leader.GetAddr(), connectPDTimeout)
+	conn, err := rpcutil.ConnectUrls(leader.GetAddr(), connectPDTimeout, tlsCfg)
 	if err != nil {
-		return nil, errors.Trace(err)
+		return nil, "", errors.Trace(err)
 	}
-	return conn, nil
+	return conn, leader.GetAddr(), nil
 }
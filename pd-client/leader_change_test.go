@@ -14,10 +14,12 @@
 package pd
 
 import (
+	"sync"
 	"time"
 
 	"github.com/coreos/etcd/clientv3"
 	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/pingcap/pd/server"
 	"github.com/pingcap/pd/server/api"
 )
@@ -73,14 +75,16 @@ func (s *testLeaderChangeSuite) TestLeaderChange(c *C) {
 		}
 	}()
 
-	cli, err := NewClient(endpoints)
+	metrics := &testMetrics{requests: make(map[string]int)}
+	cli, err := NewClientWithOption(endpoints, Option{Metrics: metrics})
 	c.Assert(err, IsNil)
 	defer cli.Close()
+	members := cli.WatchMembers()
 
 	p1, l1, err := cli.GetTS()
 	c.Assert(err, IsNil)
 
-	leader, err := getLeader(endpoints)
+	leader, err := getLeader(endpoints, nil)
 	c.Assert(err, IsNil)
 	mustConnectLeader(c, endpoints, leader.GetAddr())
 
@@ -88,9 +92,10 @@ func (s *testLeaderChangeSuite) TestLeaderChange(c *C) {
 	delete(svrs, leader.GetAddr())
 
 	// wait leader changes
+	var newLeader *pdpb.Leader
 	changed := false
 	for i := 0; i < 20; i++ {
-		newLeader, _ := getLeader(endpoints)
+		newLeader, _ = getLeader(endpoints, nil)
 		if newLeader != nil && newLeader.GetAddr() != leader.GetAddr() {
 			mustConnectLeader(c, endpoints, newLeader.GetAddr())
 			changed = true
@@ -99,11 +104,14 @@ func (s *testLeaderChangeSuite) TestLeaderChange(c *C) {
 		time.Sleep(500 * time.Millisecond)
 	}
 	c.Assert(changed, IsTrue)
+	mustWatchLeader(c, members, newLeader.GetAddr())
 
 	for i := 0; i < 20; i++ {
 		p2, l2, err := cli.GetTS()
 		if err == nil {
 			c.Assert(p1<<18+l1, Less, p2<<18+l2)
+			c.Assert(metrics.get("tso"), GreaterEqual, 2)
+			c.Assert(metrics.get("leader"), GreaterEqual, 1)
 			return
 		}
 		time.Sleep(500 * time.Millisecond)
@@ -111,10 +119,25 @@ func (s *testLeaderChangeSuite) TestLeaderChange(c *C) {
 	c.Error("failed getTS from new leader after 10 seconds")
 }
 
+func mustWatchLeader(c *C, ch <-chan *Members, leaderAddr string) {
+	timeout := time.After(leaderWatchTimeout)
+	for {
+		select {
+		case members := <-ch:
+			if members.Leader == leaderAddr {
+				c.Assert(members.Members, HasLen, 3)
+				return
+			}
+		case <-timeout:
+			c.Fatalf("failed to watch leader %s", leaderAddr)
+		}
+	}
+}
+
 func mustConnectLeader(c *C, urls []string, leaderAddr string) {
 	connCh := make(chan *conn)
 	go func() {
-		conn := mustNewConn(urls, nil)
+		conn := mustNewConn(urls, nil, nil)
 		connCh <- conn
 	}()
 
@@ -128,7 +151,7 @@ func mustConnectLeader(c *C, urls []string, leaderAddr string) {
 	}
 
 	conn.wg.Add(1)
-	go conn.connectLeader(urls, time.Second)
+	go conn.connectLeader(urls, nil, time.Second)
 
 	select {
 	case leaderConn := <-conn.ConnChan:
@@ -141,10 +164,37 @@ func mustConnectLeader(c *C, urls []string, leaderAddr string) {
 	// Create another goroutine and return to close the connection.
 	// Make sure it will not block forever.
 	conn.wg.Add(1)
-	go conn.connectLeader(urls, time.Second)
+	go conn.connectLeader(urls, nil, time.Second)
 	time.Sleep(time.Second * 3)
 	// Ensure the leader connection will be closed if we don't use it.
 	c.Assert(len(conn.ConnChan), Equals, 1)
 	conn.Close()
 	c.Assert(len(conn.ConnChan), Equals, 0)
 }
+
+type testMetrics struct {
+	sync.Mutex
+	requests map[string]int
+}
+
+func (m *testMetrics) ObserveRequest(name string, duration time.Duration, err error) {
+	m.Lock()
+	defer m.Unlock()
+	if err == nil {
+		m.requests[name]++
+	}
+}
+
+func (m *testMetrics) ObserveRetry(name string) {}
+
+func (m *testMetrics) ObserveLeaderChange(leader string) {
+	m.Lock()
+	defer m.Unlock()
+	m.requests["leader"]++
+}
+
+func (m *testMetrics) get(name string) int {
+	m.Lock()
+	defer m.Unlock()
+	return m.requests[name]
+}
@@ -0,0 +1,162 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pd
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ngaut/log"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/pkg/apiutil"
+)
+
+// leaderWatchTimeout is the max time a watch request waits for the leader to
+// change, the members are updated after every watch request.
+const leaderWatchTimeout = 30 * time.Second
+
+// Members is the leader and the members of the PD cluster.
+type Members struct {
+	// Leader is the address of the leader, it is empty if the leader is
+	// being elected.
+	Leader  string
+	Members []*pdpb.PDMember
+}
+
+// leaderWatcher watches the leader by the members, so the worker connects
+// the new leader once it is elected, rather than after the requests fail.
+// The subscribers are notified when the leader or the members change.
+type leaderWatcher struct {
+	sync.Mutex
+	w           *rpcWorker
+	members     *Members
+	subscribers []chan *Members
+	closed      bool
+}
+
+func newLeaderWatcher(w *rpcWorker) *leaderWatcher {
+	return &leaderWatcher{w: w}
+}
+
+// subscribe returns a channel which receives the latest members, the members
+// which are not received are replaced by the newer ones. The channel is
+// closed when the client is closed.
+func (lw *leaderWatcher) subscribe() <-chan *Members {
+	lw.Lock()
+	defer lw.Unlock()
+
+	ch := make(chan *Members, 1)
+	if lw.closed {
+		close(ch)
+		return ch
+	}
+	if lw.members != nil {
+		ch <- lw.members
+	}
+	lw.subscribers = append(lw.subscribers, ch)
+	return ch
+}
+
+func (lw *leaderWatcher) run() {
+	defer lw.w.wg.Done()
+	defer lw.closeSubscribers()
+
+	var revision int64
+	for {
+		result, err := lw.watch(revision)
+		if err != nil {
+			log.Warnf("[pd] failed to watch leader: %v", err)
+			select {
+			case <-time.After(connectPDTimeout):
+			case <-lw.w.quit:
+				return
+			}
+			continue
+		}
+		revision = result.Revision
+		lw.update(result)
+	}
+}
+
+// watch watches the leader by the members in turn until one succeeds.
+func (lw *leaderWatcher) watch(revision int64) (*apiutil.LeaderWatchResult, error) {
+	var lastErr error
+	for _, u := range lw.w.getURLs() {
+		api, err := apiutil.NewClient(u, leaderWatchTimeout+connectPDTimeout, lw.w.tlsConfig)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		result, err := api.WatchLeader(revision, leaderWatchTimeout, lw.w.quit)
+		if err == nil {
+			return result, nil
+		}
+		lastErr = err
+		select {
+		case <-lw.w.quit:
+			return nil, err
+		default:
+		}
+	}
+	return nil, lastErr
+}
+
+func (lw *leaderWatcher) update(result *apiutil.LeaderWatchResult) {
+	members := &Members{
+		Leader:  result.Leader.GetAddr(),
+		Members: result.Members,
+	}
+	lw.w.setMembers(&pdpb.GetPDMembersResponse{Members: result.Members})
+
+	lw.Lock()
+	defer lw.Unlock()
+
+	if lw.members != nil && lw.members.Leader == members.Leader && memberURLs(lw.members) == memberURLs(members) {
+		return
+	}
+	lw.members = members
+	if members.Leader != "" {
+		select {
+		case <-lw.w.leaderChanged:
+		default:
+		}
+		lw.w.leaderChanged <- members.Leader
+	}
+	for _, ch := range lw.subscribers {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- members
+	}
+}
+
+func (lw *leaderWatcher) closeSubscribers() {
+	lw.Lock()
+	defer lw.Unlock()
+
+	for _, ch := range lw.subscribers {
+		close(ch)
+	}
+	lw.subscribers, lw.closed = nil, true
+}
+
+func memberURLs(members *Members) string {
+	var urls []string
+	for _, m := range members.Members {
+		urls = append(urls, m.GetClientUrls()...)
+	}
+	return strings.Join(urls, ",")
+}
@@ -13,7 +13,11 @@
 
 package pd
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
 
 var (
 	cmdCounter = prometheus.NewCounterVec(
@@ -58,6 +62,29 @@ var (
 			Help:      "Bucketed histogram of processing time (s) of handled requests.",
 			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 13),
 		}, []string{"type"})
+	requestRetryCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd_client",
+			Subsystem: "request",
+			Name:      "retries_total",
+			Help:      "Counter of the retried requests.",
+		}, []string{"type"})
+
+	leaderChangeCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "pd_client",
+			Subsystem: "leader",
+			Name:      "changes_total",
+			Help:      "Counter of the leader changes.",
+		})
+
+	regionCacheCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd_client",
+			Subsystem: "region_cache",
+			Name:      "requests_total",
+			Help:      "Counter of the region cache hits and misses.",
+		}, []string{"type"})
 )
 
 func init() {
@@ -66,4 +93,36 @@ func init() {
 	prometheus.MustRegister(cmdDuration)
 	prometheus.MustRegister(cmdFailedDuration)
 	prometheus.MustRegister(requestDuration)
+	prometheus.MustRegister(requestRetryCounter)
+	prometheus.MustRegister(leaderChangeCounter)
+	prometheus.MustRegister(regionCacheCounter)
+}
+
+// Metrics observes the requests of the client, the applications can
+// implement it to monitor the interaction with PD.
+type Metrics interface {
+	// ObserveRequest is called when a request returns, err is nil if it
+	// succeeds. The names of the requests are tso, get_region,
+	// get_region_by_id, get_prev_region, scan_regions, get_store,
+	// is_bootstrapped and bootstrap.
+	ObserveRequest(name string, duration time.Duration, err error)
+	// ObserveRetry is called when a request is retried on a new connection.
+	ObserveRetry(name string)
+	// ObserveLeaderChange is called when the client connects a new leader.
+	ObserveLeaderChange(leader string)
+}
+
+// prometheusMetrics is the default metrics.
+type prometheusMetrics struct{}
+
+func (prometheusMetrics) ObserveRequest(name string, duration time.Duration, err error) {
+	requestDuration.WithLabelValues(name).Observe(duration.Seconds())
+}
+
+func (prometheusMetrics) ObserveRetry(name string) {
+	requestRetryCounter.WithLabelValues(name).Inc()
+}
+
+func (prometheusMetrics) ObserveLeaderChange(leader string) {
+	leaderChangeCounter.Inc()
 }
@@ -0,0 +1,193 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pd
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/google/btree"
+	"github.com/pingcap/kvproto/pkg/errorpb"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+const (
+	// regionCacheTTL is the time a cached region is used without asking PD
+	// again, the region may be changed by PD scheduling in rare cases without
+	// the caller seeing any error.
+	regionCacheTTL         = 10 * time.Minute
+	regionCacheBTreeDegree = 32
+)
+
+type cachedRegion struct {
+	region   *metapb.Region
+	leader   *metapb.Peer
+	expireAt time.Time
+}
+
+// Less returns true if the region start key is greater than the other, so
+// the regions are sorted by start key reversely, the same as the regions
+// tree of PD.
+func (r *cachedRegion) Less(other btree.Item) bool {
+	return bytes.Compare(r.region.GetStartKey(), other.(*cachedRegion).region.GetStartKey()) > 0
+}
+
+func (r *cachedRegion) contains(key []byte) bool {
+	start, end := r.region.GetStartKey(), r.region.GetEndKey()
+	return bytes.Compare(key, start) >= 0 && (len(end) == 0 || bytes.Compare(key, end) < 0)
+}
+
+// regionCache caches the regions and their leaders returned by PD. The
+// regions are invalidated by the region errors reported by the caller, e.g.
+// NotLeader and StaleEpoch, or when they expire.
+type regionCache struct {
+	sync.Mutex
+	ttl     time.Duration
+	tree    *btree.BTree
+	regions map[uint64]*cachedRegion
+}
+
+func newRegionCache(ttl time.Duration) *regionCache {
+	return &regionCache{
+		ttl:     ttl,
+		tree:    btree.New(regionCacheBTreeDegree),
+		regions: make(map[uint64]*cachedRegion),
+	}
+}
+
+// search returns the cached region containing the key and its leader, nil if
+// it is not cached or expires.
+func (c *regionCache) search(key []byte) (*metapb.Region, *metapb.Peer) {
+	c.Lock()
+	defer c.Unlock()
+
+	r := c.find(key)
+	if r == nil {
+		return nil, nil
+	}
+	if time.Now().After(r.expireAt) {
+		c.remove(r)
+		return nil, nil
+	}
+	return r.region, r.leader
+}
+
+// update caches the region, the overlapped regions are removed. It does
+// nothing if a newer epoch of the region is cached.
+func (c *regionCache) update(region *metapb.Region, leader *metapb.Peer) {
+	c.Lock()
+	defer c.Unlock()
+
+	if old, ok := c.regions[region.GetId()]; ok {
+		if isEpochStale(region.GetRegionEpoch(), old.region.GetRegionEpoch()) {
+			return
+		}
+		c.remove(old)
+	}
+
+	item := &cachedRegion{
+		region:   region,
+		leader:   leader,
+		expireAt: time.Now().Add(c.ttl),
+	}
+	var overlaps []*cachedRegion
+	// Starts from the region containing the start key, or the first region
+	// after the start key.
+	pivot := item
+	if r := c.find(region.GetStartKey()); r != nil {
+		pivot = r
+	}
+	c.tree.DescendLessOrEqual(pivot, func(i btree.Item) bool {
+		over := i.(*cachedRegion)
+		if len(region.GetEndKey()) > 0 && bytes.Compare(region.GetEndKey(), over.region.GetStartKey()) <= 0 {
+			return false
+		}
+		overlaps = append(overlaps, over)
+		return true
+	})
+	for _, over := range overlaps {
+		c.remove(over)
+	}
+	c.tree.ReplaceOrInsert(item)
+	c.regions[region.GetId()] = item
+}
+
+// invalidate removes the region from the cache.
+func (c *regionCache) invalidate(regionID uint64) {
+	c.Lock()
+	defer c.Unlock()
+
+	if r, ok := c.regions[regionID]; ok {
+		c.remove(r)
+	}
+}
+
+// updateLeader changes the leader of the cached region, the region is
+// removed if the leader is not a peer of the cached region.
+func (c *regionCache) updateLeader(regionID uint64, leader *metapb.Peer) {
+	c.Lock()
+	defer c.Unlock()
+
+	r, ok := c.regions[regionID]
+	if !ok {
+		return
+	}
+	for _, peer := range r.region.GetPeers() {
+		if peer.GetId() == leader.GetId() {
+			r.leader = peer
+			return
+		}
+	}
+	c.remove(r)
+}
+
+// onRegionError updates the cache by the region error returned by the store.
+func (c *regionCache) onRegionError(regionID uint64, regionErr *errorpb.Error) {
+	if notLeader := regionErr.GetNotLeader(); notLeader != nil && notLeader.GetLeader() != nil {
+		c.updateLeader(regionID, notLeader.GetLeader())
+		return
+	}
+	// The new regions of StaleEpoch are not cached, as their leaders are
+	// unknown.
+	c.invalidate(regionID)
+}
+
+func (c *regionCache) length() int {
+	c.Lock()
+	defer c.Unlock()
+	return len(c.regions)
+}
+
+func (c *regionCache) find(key []byte) *cachedRegion {
+	var result *cachedRegion
+	c.tree.AscendGreaterOrEqual(&cachedRegion{region: &metapb.Region{StartKey: key}}, func(i btree.Item) bool {
+		result = i.(*cachedRegion)
+		return false
+	})
+	if result == nil || !result.contains(key) {
+		return nil
+	}
+	return result
+}
+
+func (c *regionCache) remove(r *cachedRegion) {
+	c.tree.Delete(r)
+	delete(c.regions, r.region.GetId())
+}
+
+// isEpochStale returns true if the epoch is older than the other.
+func isEpochStale(epoch, other *metapb.RegionEpoch) bool {
+	return epoch.GetVersion() < other.GetVersion() || epoch.GetConfVer() < other.GetConfVer()
+}
@@ -0,0 +1,105 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pd
+
+import (
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/errorpb"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+var _ = Suite(&testRegionCacheSuite{})
+
+type testRegionCacheSuite struct{}
+
+func newCacheRegion(id uint64, start, end string, version uint64) *metapb.Region {
+	return &metapb.Region{
+		Id:          id,
+		StartKey:    []byte(start),
+		EndKey:      []byte(end),
+		RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: version},
+		Peers: []*metapb.Peer{
+			{Id: id*10 + 1, StoreId: 1},
+			{Id: id*10 + 2, StoreId: 2},
+		},
+	}
+}
+
+func (s *testRegionCacheSuite) TestUpdate(c *C) {
+	cache := newRegionCache(time.Minute)
+	r1 := newCacheRegion(1, "", "m", 1)
+	r2 := newCacheRegion(2, "m", "", 1)
+	cache.update(r1, r1.Peers[0])
+	cache.update(r2, r2.Peers[0])
+
+	region, leader := cache.search([]byte("a"))
+	c.Assert(region, DeepEquals, r1)
+	c.Assert(leader, DeepEquals, r1.Peers[0])
+	region, _ = cache.search([]byte("z"))
+	c.Assert(region, DeepEquals, r2)
+
+	// A stale epoch is ignored.
+	cache.update(newCacheRegion(1, "", "z", 0), nil)
+	region, _ = cache.search([]byte("a"))
+	c.Assert(region, DeepEquals, r1)
+
+	// r1 is split, and r2 overlapped by r3 is removed too.
+	r1 = newCacheRegion(1, "", "c", 2)
+	r3 := newCacheRegion(3, "c", "n", 1)
+	cache.update(r1, r1.Peers[0])
+	cache.update(r3, r3.Peers[0])
+	c.Assert(cache.length(), Equals, 2)
+	region, _ = cache.search([]byte("d"))
+	c.Assert(region, DeepEquals, r3)
+	region, _ = cache.search([]byte("z"))
+	c.Assert(region, IsNil)
+}
+
+func (s *testRegionCacheSuite) TestRegionError(c *C) {
+	cache := newRegionCache(time.Minute)
+	r1 := newCacheRegion(1, "", "", 1)
+	cache.update(r1, r1.Peers[0])
+
+	// The leader is changed by the hint.
+	cache.onRegionError(1, &errorpb.Error{
+		NotLeader: &errorpb.NotLeader{RegionId: proto.Uint64(1), Leader: r1.Peers[1]},
+	})
+	_, leader := cache.search([]byte("a"))
+	c.Assert(leader, DeepEquals, r1.Peers[1])
+
+	// The region is removed if the leader is not a cached peer.
+	cache.onRegionError(1, &errorpb.Error{
+		NotLeader: &errorpb.NotLeader{RegionId: proto.Uint64(1), Leader: &metapb.Peer{Id: 100, StoreId: 3}},
+	})
+	region, _ := cache.search([]byte("a"))
+	c.Assert(region, IsNil)
+
+	cache.update(r1, r1.Peers[0])
+	cache.onRegionError(1, &errorpb.Error{StaleEpoch: &errorpb.StaleEpoch{}})
+	region, _ = cache.search([]byte("a"))
+	c.Assert(region, IsNil)
+}
+
+func (s *testRegionCacheSuite) TestExpire(c *C) {
+	cache := newRegionCache(time.Millisecond)
+	r1 := newCacheRegion(1, "", "", 1)
+	cache.update(r1, r1.Peers[0])
+	time.Sleep(10 * time.Millisecond)
+	region, _ := cache.search([]byte("a"))
+	c.Assert(region, IsNil)
+	c.Assert(cache.length(), Equals, 0)
+}
@@ -15,6 +15,8 @@ package pd
 
 import (
 	"bufio"
+	"crypto/tls"
+	"fmt"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -32,57 +34,164 @@ import (
 const (
 	maxPipelineRequest    = 10000
 	maxInitClusterRetries = 300
+	// maxRequestRetries is the max times a request is retried after the
+	// connection fails, e.g. the leader changes.
+	maxRequestRetries = 10
+	// updateMembersInterval is the interval to discover the members, so the
+	// client can connect the members added after it is created.
+	updateMembersInterval = time.Minute
+	minRetryBackoff       = 100 * time.Millisecond
 )
 
 // errInvalidResponse represents response message is invalid.
 var errInvalidResponse = errors.New("invalid response")
 
+// connError is returned if the rpc fails on the connection, the request is
+// retried on a new connection then.
+type connError struct {
+	err error
+}
+
+func (e *connError) Error() string {
+	return fmt.Sprintf("[pd] rpc failed: %v", e.err)
+}
+
+func isConnError(err error) bool {
+	_, ok := errors.Cause(err).(*connError)
+	return ok
+}
+
+// request is sent to the worker, done receives the result.
+type request interface {
+	finish(err error)
+	// retry returns false if the request is retried too many times, or it
+	// can't be retried.
+	retry() bool
+}
+
+type requestBase struct {
+	name    string
+	start   time.Time
+	metrics Metrics
+	done    chan error
+	retries int
+}
+
+func newRequestBase(name string, metrics Metrics) requestBase {
+	return requestBase{
+		name:    name,
+		start:   time.Now(),
+		metrics: metrics,
+		done:    make(chan error, 1),
+	}
+}
+
+func (r *requestBase) finish(err error) {
+	r.done <- err
+}
+
+func (r *requestBase) retry() bool {
+	r.retries++
+	if r.retries > maxRequestRetries {
+		return false
+	}
+	r.metrics.ObserveRetry(r.name)
+	return true
+}
+
+// wait waits for the request to finish.
+func (r *requestBase) wait() error {
+	err := <-r.done
+	r.metrics.ObserveRequest(r.name, time.Since(r.start), err)
+	return err
+}
+
 type tsoRequest struct {
-	done     chan error
+	requestBase
 	physical int64
 	logical  int64
 }
 
 type storeRequest struct {
+	requestBase
 	pbReq  *pdpb.GetStoreRequest
-	done   chan error
 	pbResp *pdpb.GetStoreResponse
 }
 
 type regionRequest struct {
+	requestBase
 	pbReq  *pdpb.GetRegionRequest
-	done   chan error
 	pbResp *pdpb.GetRegionResponse
 }
 
 type clusterConfigRequest struct {
+	requestBase
 	pbReq  *pdpb.GetClusterConfigRequest
-	done   chan error
 	pbResp *pdpb.GetClusterConfigResponse
 }
 
+type regionByIDRequest struct {
+	requestBase
+	pbReq  *pdpb.GetRegionByIDRequest
+	pbResp *pdpb.GetRegionResponse
+}
+
+type isBootstrappedRequest struct {
+	requestBase
+	pbResp *pdpb.IsBootstrappedResponse
+}
+
+type bootstrapRequest struct {
+	requestBase
+	pbReq *pdpb.BootstrapRequest
+}
+
+// retry returns false. PD may bootstrap the cluster before the connection
+// fails, then the retried request fails as the cluster is bootstrapped.
+func (r *bootstrapRequest) retry() bool {
+	return false
+}
+
 type rpcWorker struct {
+	// urls are changed by the members got from PD, they are read by getURLs
+	// after the worker is started.
+	urlsLock  sync.RWMutex
 	urls      []string
+	tlsConfig *tls.Config
+	metrics   Metrics
 	clusterID uint64
-	requests  chan interface{}
-	wg        sync.WaitGroup
-	quit      chan struct{}
+	requests  chan request
+	// retrying are the requests to retry on the next connection.
+	retrying []request
+	// leader is the address of the leader connected last time.
+	leader string
+	// leaderChanged receives the new leader watched by the leader watcher.
+	leaderChanged chan string
+	watcher       *leaderWatcher
+	wg            sync.WaitGroup
+	quit          chan struct{}
 }
 
-func newRPCWorker(addrs []string) (*rpcWorker, error) {
+func newRPCWorker(addrs []string, tlsCfg *tls.Config, metrics Metrics) (*rpcWorker, error) {
 	w := &rpcWorker{
-		urls:     addrsToUrls(addrs),
-		requests: make(chan interface{}, maxPipelineRequest),
-		quit:     make(chan struct{}),
+		urls:      addrsToUrls(addrs),
+		tlsConfig: tlsCfg,
+		metrics:   metrics,
+		requests:  make(chan request, maxPipelineRequest),
+		quit:      make(chan struct{}),
+
+		leaderChanged: make(chan string, 1),
 	}
+	w.watcher = newLeaderWatcher(w)
 
 	if err := w.initClusterID(); err != nil {
 		return nil, errors.Trace(err)
 	}
-	log.Infof("[pd] init cluster id %v", w.clusterID)
+	log.Infof("[pd] init cluster id %v, members %v", w.clusterID, w.urls)
 
-	w.wg.Add(1)
+	w.wg.Add(2)
 	go w.work()
+	go w.watcher.run()
 	return w, nil
 }
 
@@ -90,35 +199,54 @@ func (w *rpcWorker) stop(err error) {
 	close(w.quit)
 	w.wg.Wait()
 
+	for _, req := range w.retrying {
+		req.finish(err)
+	}
 	n := len(w.requests)
 	for i := 0; i < n; i++ {
 		req := <-w.requests
-		switch r := req.(type) {
-		case *tsoRequest:
-			r.done <- err
-		case *clusterConfigRequest:
-			r.done <- err
-		case *storeRequest:
-			r.done <- err
-		case *regionRequest:
-			r.done <- err
-		}
+		req.finish(err)
 	}
 }
 
 func (w *rpcWorker) work() {
 	defer w.wg.Done()
 
+	ticker := time.NewTicker(updateMembersInterval)
+	defer ticker.Stop()
+	var backoff time.Duration
+
 RECONNECT:
-	log.Infof("[pd] connect to pd server %v", w.urls)
-	conn := mustNewConn(w.urls, w.quit)
+	if len(w.retrying) > 0 {
+		// Wait for the leader to change with backoff before retrying.
+		backoff = nextBackoff(backoff)
+		select {
+		case <-time.After(backoff):
+		case <-w.quit:
+			return
+		}
+	}
+	urls := w.getURLs()
+	log.Infof("[pd] connect to pd server %v", urls)
+	conn := mustNewConn(urls, w.tlsConfig, w.quit)
 	if conn == nil {
 		return // Closed.
 	}
 	log.Infof("[pd] connected to %v", conn.RemoteAddr())
+	w.checkLeader(conn)
+
+	if len(w.retrying) > 0 {
+		pending := w.retrying
+		w.retrying = nil
+		if ok := w.handleRequests(pending, conn.ReadWriter); !ok {
+			conn.Close()
+			goto RECONNECT
+		}
+	}
+	backoff = 0
 
 	for {
-		var pending []interface{}
+		var pending []request
 		select {
 		case req := <-w.requests:
 			pending = append(pending, req)
@@ -135,6 +263,18 @@ RECONNECT:
 				conn.Close()
 				goto RECONNECT
 			}
+		case <-ticker.C:
+			if err := w.updateMembers(conn.ReadWriter); err != nil {
+				log.Warnf("[pd] failed to update members: %v", err)
+				conn.Close()
+				goto RECONNECT
+			}
+		case leader := <-w.leaderChanged:
+			if leader != conn.leaderAddr {
+				log.Infof("[pd] leader is changed to %s, reconnect", leader)
+				conn.Close()
+				goto RECONNECT
+			}
 		case <-w.quit:
 			conn.Close()
 			return
@@ -142,13 +282,40 @@ RECONNECT:
 			conn.Close()
 			conn = leaderConn
 			log.Infof("[pd] reconnected to leader %v", conn.RemoteAddr())
+			w.checkLeader(conn)
 		}
 	}
 }
 
-func (w *rpcWorker) handleRequests(requests []interface{}, conn *bufio.ReadWriter) bool {
+// checkLeader observes the leader change if the connection is to a new
+// leader.
+func (w *rpcWorker) checkLeader(conn *conn) {
+	if conn.leaderAddr != "" && conn.leaderAddr != w.leader {
+		w.leader = conn.leaderAddr
+		w.metrics.ObserveLeaderChange(conn.leaderAddr)
+	}
+}
+
+func nextBackoff(backoff time.Duration) time.Duration {
+	if backoff < minRetryBackoff {
+		return minRetryBackoff
+	}
+	if backoff *= 2; backoff > connectPDTimeout {
+		return connectPDTimeout
+	}
+	return backoff
+}
+
+// handleRequests returns false if any request fails, the requests failed on
+// the connection are kept to retry unless they are retried too many times.
+func (w *rpcWorker) handleRequests(requests []request, conn *bufio.ReadWriter) bool {
 	var tsoRequests []*tsoRequest
 	ok := true
+	fail := func(req request, err error) {
+		ok = false
+		log.Error(err)
+		w.failRequest(req, err)
+	}
 	for _, req := range requests {
 		switch r := req.(type) {
 		case *tsoRequest:
@@ -156,32 +323,48 @@ func (w *rpcWorker) handleRequests(requests []interface{}, conn *bufio.ReadWrite
 		case *storeRequest:
 			storeResp, err := w.getStoreFromRemote(conn, r.pbReq)
 			if err != nil {
-				ok = false
-				log.Error(err)
-				r.done <- err
+				fail(r, err)
 			} else {
 				r.pbResp = storeResp
-				r.done <- nil
+				r.finish(nil)
 			}
 		case *regionRequest:
 			regionResp, err := w.getRegionFromRemote(conn, r.pbReq)
 			if err != nil {
-				ok = false
-				log.Error(err)
-				r.done <- err
+				fail(r, err)
+			} else {
+				r.pbResp = regionResp
+				r.finish(nil)
+			}
+		case *regionByIDRequest:
+			regionResp, err := w.getRegionByIDFromRemote(conn, r.pbReq)
+			if err != nil {
+				fail(r, err)
 			} else {
 				r.pbResp = regionResp
-				r.done <- nil
+				r.finish(nil)
 			}
 		case *clusterConfigRequest:
 			clusterConfigResp, err := w.getClusterConfigFromRemote(conn, r.pbReq)
 			if err != nil {
-				ok = false
-				log.Error(err)
-				r.done <- err
+				fail(r, err)
 			} else {
 				r.pbResp = clusterConfigResp
-				r.done <- nil
+				r.finish(nil)
+			}
+		case *isBootstrappedRequest:
+			isBootstrappedResp, err := w.isBootstrappedFromRemote(conn)
+			if err != nil {
+				fail(r, err)
+			} else {
+				r.pbResp = isBootstrappedResp
+				r.finish(nil)
+			}
+		case *bootstrapRequest:
+			if err := w.bootstrapFromRemote(conn, r.pbReq); err != nil {
+				fail(r, err)
+			} else {
+				r.finish(nil)
 			}
 		default:
 			log.Errorf("[pd] invalid request %v", r)
@@ -199,11 +382,11 @@ func (w *rpcWorker) handleRequests(requests []interface{}, conn *bufio.ReadWrite
 		physical := ts.GetPhysical()
 		for _, req := range tsoRequests {
 			if err != nil {
-				req.done <- err
+				w.failRequest(req, err)
 			} else {
 				req.physical = physical
 				req.logical = logicalHigh
-				req.done <- nil
+				req.finish(nil)
 				logicalHigh--
 			}
 		}
@@ -212,6 +395,14 @@ func (w *rpcWorker) handleRequests(requests []interface{}, conn *bufio.ReadWrite
 	return ok
 }
 
+func (w *rpcWorker) failRequest(req request, err error) {
+	if isConnError(err) && req.retry() {
+		w.retrying = append(w.retrying, req)
+		return
+	}
+	req.finish(err)
+}
+
 var msgID uint64
 
 func newMsgID() uint64 {
@@ -220,17 +411,18 @@ func newMsgID() uint64 {
 
 func (w *rpcWorker) initClusterID() error {
 	for i := 0; i < maxInitClusterRetries; i++ {
-		conn := mustNewConn(w.urls, w.quit)
+		conn := mustNewConn(w.urls, w.tlsConfig, w.quit)
 		if conn == nil {
 			return errors.New("client closed")
 		}
 
-		clusterID, err := w.getClusterID(conn.ReadWriter)
+		resp, err := w.getMembers(conn.ReadWriter)
 		// We need to close this connection no matter success or not.
 		conn.Close()
 
 		if err == nil {
-			w.clusterID = clusterID
+			w.clusterID = resp.GetHeader().GetClusterId()
+			w.setMembers(resp.GetGetPdMembers())
 			return nil
 		}
 
@@ -241,7 +433,7 @@ func (w *rpcWorker) initClusterID() error {
 	return errors.New("failed to get cluster id")
 }
 
-func (w *rpcWorker) getClusterID(conn *bufio.ReadWriter) (uint64, error) {
+func (w *rpcWorker) getMembers(conn *bufio.ReadWriter) (*pdpb.Response, error) {
 	// PD will not check the cluster ID in the GetPDMembersRequest, so we
 	// can send this request with any cluster ID, then PD will return its
 	// cluster ID in the response header.
@@ -256,10 +448,74 @@ func (w *rpcWorker) getClusterID(conn *bufio.ReadWriter) (uint64, error) {
 
 	resp, err := w.callRPC(conn, req)
 	if err != nil {
-		return 0, errors.Trace(err)
+		return nil, errors.Trace(err)
 	}
+	return resp, nil
+}
 
-	return resp.GetHeader().GetClusterId(), nil
+func (w *rpcWorker) updateMembers(conn *bufio.ReadWriter) error {
+	resp, err := w.getMembers(conn)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	w.setMembers(resp.GetGetPdMembers())
+	return nil
+}
+
+// setMembers replaces the urls with the client urls of the members, the
+// urls are kept if no member is returned.
+func (w *rpcWorker) setMembers(resp *pdpb.GetPDMembersResponse) {
+	var urls []string
+	for _, m := range resp.GetMembers() {
+		urls = append(urls, m.GetClientUrls()...)
+	}
+	if len(urls) == 0 {
+		return
+	}
+	if strings.Join(urls, ",") != strings.Join(w.getURLs(), ",") {
+		log.Infof("[pd] members are changed to %v", urls)
+	}
+	w.urlsLock.Lock()
+	w.urls = urls
+	w.urlsLock.Unlock()
+}
+
+func (w *rpcWorker) getURLs() []string {
+	w.urlsLock.RLock()
+	defer w.urlsLock.RUnlock()
+	return w.urls
+}
+
+func (w *rpcWorker) isBootstrappedFromRemote(conn *bufio.ReadWriter) (*pdpb.IsBootstrappedResponse, error) {
+	req := &pdpb.Request{
+		Header: &pdpb.RequestHeader{
+			Uuid:      uuid.NewV4().Bytes(),
+			ClusterId: w.clusterID,
+		},
+		CmdType:        pdpb.CommandType_IsBootstrapped,
+		IsBootstrapped: &pdpb.IsBootstrappedRequest{},
+	}
+	resp, err := w.callRPC(conn, req)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if resp.GetIsBootstrapped() == nil {
+		return nil, errors.New("[pd] IsBootstrapped field in rpc response not set")
+	}
+	return resp.GetIsBootstrapped(), nil
+}
+
+func (w *rpcWorker) bootstrapFromRemote(conn *bufio.ReadWriter, bootstrapReq *pdpb.BootstrapRequest) error {
+	req := &pdpb.Request{
+		Header: &pdpb.RequestHeader{
+			Uuid:      uuid.NewV4().Bytes(),
+			ClusterId: w.clusterID,
+		},
+		CmdType:   pdpb.CommandType_Bootstrap,
+		Bootstrap: bootstrapReq,
+	}
+	_, err := w.callRPC(conn, req)
+	return errors.Trace(err)
 }
 
 func (w *rpcWorker) getTSFromRemote(conn *bufio.ReadWriter, n int) (pdpb.Timestamp, error) {
@@ -326,6 +582,25 @@ func (w *rpcWorker) getRegionFromRemote(conn *bufio.ReadWriter, regionReq *pdpb.
 	return rsp.GetGetRegion(), nil
 }
 
+func (w *rpcWorker) getRegionByIDFromRemote(conn *bufio.ReadWriter, regionReq *pdpb.GetRegionByIDRequest) (*pdpb.GetRegionResponse, error) {
+	req := &pdpb.Request{
+		Header: &pdpb.RequestHeader{
+			Uuid:      uuid.NewV4().Bytes(),
+			ClusterId: w.clusterID,
+		},
+		CmdType:       pdpb.CommandType_GetRegionByID,
+		GetRegionById: regionReq,
+	}
+	rsp, err := w.callRPC(conn, req)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if rsp.GetGetRegionById() == nil {
+		return nil, errors.New("[pd] GetRegionById field in rpc response not set")
+	}
+	return rsp.GetGetRegionById(), nil
+}
+
 func (w *rpcWorker) getClusterConfigFromRemote(conn *bufio.ReadWriter, clusterConfigReq *pdpb.GetClusterConfigRequest) (*pdpb.GetClusterConfigResponse, error) {
 	req := &pdpb.Request{
 		Header: &pdpb.RequestHeader{
@@ -364,11 +639,13 @@ func (w *rpcWorker) callRPC(conn *bufio.ReadWriter, req *pdpb.Request) (resp *pd
 		PdReq:   req,
 	}
 	if err = util.WriteMessage(conn, newMsgID(), msg); err != nil {
-		return nil, errors.Errorf("[pd] rpc failed: %v", err)
+		return nil, errors.Trace(&connError{err})
+	}
+	if err = conn.Flush(); err != nil {
+		return nil, errors.Trace(&connError{err})
 	}
-	conn.Flush()
 	if _, err = util.ReadMessage(conn, msg); err != nil {
-		return nil, errors.Errorf("[pd] rpc failed: %v", err)
+		return nil, errors.Trace(&connError{err})
 	}
 	if msg.GetMsgType() != msgpb.MessageType_PdResp {
 		return nil, errors.Trace(errInvalidResponse)
@@ -0,0 +1,51 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/spf13/cobra"
+)
+
+var backupPrefix = "pd/api/v1/backup"
+
+// NewBackupCommand return a backup subcommand of rootCmd
+func NewBackupCommand() *cobra.Command {
+	b := &cobra.Command{
+		Use:   "backup <file>",
+		Short: "export the metadata to the file, restore it by starting a fresh pd-server with --restore <file>",
+		Run:   backupCommandFunc,
+	}
+	return b
+}
+
+func backupCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	r, err := doRequest(cmd, backupPrefix, http.MethodGet)
+	if err != nil {
+		fmt.Printf("Failed to backup: %s\n", err)
+		return
+	}
+	if err = ioutil.WriteFile(args[0], []byte(r), 0600); err != nil {
+		fmt.Printf("Failed to write the backup file: %s\n", err)
+		return
+	}
+	printSuccess(cmd)
+}
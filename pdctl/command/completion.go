@@ -0,0 +1,119 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+// completionCacheTTL is the time the listed ids are cached, so pressing tab
+// repeatedly doesn't fetch all the regions every time.
+const completionCacheTTL = 10 * time.Second
+
+type cachedIDs struct {
+	ids     []string
+	updated time.Time
+}
+
+var completionCache = struct {
+	sync.Mutex
+	entries map[string]*cachedIDs
+}{entries: make(map[string]*cachedIDs)}
+
+// listCachedIDs returns the ids cached for the prefix of addr, or lists and
+// caches them if they are expired. The failed list is not cached.
+func listCachedIDs(addr string, prefix string, list func() []string) []string {
+	key := addr + "/" + prefix
+	completionCache.Lock()
+	entry, ok := completionCache.entries[key]
+	completionCache.Unlock()
+	if ok && time.Since(entry.updated) < completionCacheTTL {
+		return entry.ids
+	}
+
+	ids := list()
+	if ids != nil {
+		completionCache.Lock()
+		completionCache.entries[key] = &cachedIDs{ids: ids, updated: time.Now()}
+		completionCache.Unlock()
+	}
+	return ids
+}
+
+// ListStoreIDs returns the ids of all stores, used for completion.
+func ListStoreIDs(addr string) []string {
+	return listCachedIDs(addr, storesPrefix, func() []string {
+		return listStoreIDs(addr)
+	})
+}
+
+func listStoreIDs(addr string) []string {
+	var info struct {
+		Stores []struct {
+			Store *metapb.Store `json:"store"`
+		} `json:"stores"`
+	}
+	if err := getJSON(addr, storesPrefix, &info); err != nil {
+		return nil
+	}
+	ids := make([]string, 0, len(info.Stores))
+	for _, s := range info.Stores {
+		ids = append(ids, strconv.FormatUint(s.Store.GetId(), 10))
+	}
+	return ids
+}
+
+// ListRegionIDs returns the ids of all regions, used for completion.
+func ListRegionIDs(addr string) []string {
+	return listCachedIDs(addr, regionsPrefix, func() []string {
+		return listRegionIDs(addr)
+	})
+}
+
+func listRegionIDs(addr string) []string {
+	var info struct {
+		Regions []*metapb.Region `json:"regions"`
+	}
+	if err := getJSON(addr, regionsPrefix, &info); err != nil {
+		return nil
+	}
+	ids := make([]string, 0, len(info.Regions))
+	for _, r := range info.Regions {
+		ids = append(ids, strconv.FormatUint(r.GetId(), 10))
+	}
+	return ids
+}
+
+func getJSON(addr string, prefix string, v interface{}) error {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	resp, err := dailClient.Get(fmt.Sprintf("%s/%s", addr, prefix))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return genResponseError(resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,232 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// binaryName is the name completions are registered for.
+const binaryName = "pd-ctl"
+
+// schedulerNameCommands are the commands taking a running scheduler name.
+var schedulerNameCommands = []string{
+	"scheduler remove",
+	"scheduler pause",
+	"scheduler resume",
+	"scheduler config",
+	"scheduler diagnosis",
+}
+
+const bashCompletionFunc = `__pd-ctl_get_schedulers()
+{
+    local pd_flags=()
+    if [[ -n ${flaghash[--pd]} ]]; then
+        pd_flags=(-u "${flaghash[--pd]}")
+    elif [[ -n ${flaghash[-u]} ]]; then
+        pd_flags=(-u "${flaghash[-u]}")
+    fi
+    local out
+    out=$(pd-ctl -d -o json "${pd_flags[@]}" scheduler show </dev/null 2>/dev/null)
+    [[ ${out} == \[* ]] || return
+    out=$(echo "${out}" | tr -d '[]" ' | tr ',' ' ')
+    COMPREPLY=( $(compgen -W "${out}" -- "$cur") )
+}
+
+__custom_func()
+{
+    case ${last_command} in
+        %s)
+            __pd-ctl_get_schedulers
+            return
+            ;;
+    esac
+}
+`
+
+// NewCompletionCommand return a completion subcommand of rootCmd
+func NewCompletionCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "completion <bash|zsh|fish>",
+		Short: "generate the shell completion script",
+		Run:   completionCommandFunc,
+	}
+	return c
+}
+
+func completionCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	var err error
+	root := cmd.Root()
+	switch args[0] {
+	case "bash":
+		err = genBashCompletion(root, os.Stdout)
+	case "zsh":
+		err = genZshCompletion(root, os.Stdout)
+	case "fish":
+		err = genFishCompletion(root, os.Stdout)
+	default:
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	if err != nil {
+		fmt.Printf("Failed to generate completion: %s\n", err)
+	}
+}
+
+func genBashCompletion(root *cobra.Command, w io.Writer) error {
+	// The generated functions are looked up by the name of the binary.
+	use := root.Use
+	root.Use = binaryName
+	defer func() { root.Use = use }()
+
+	lastCommands := make([]string, 0, len(schedulerNameCommands))
+	for _, c := range schedulerNameCommands {
+		lastCommands = append(lastCommands, binaryName+"_"+strings.Replace(c, " ", "_", -1))
+	}
+	root.BashCompletionFunction = fmt.Sprintf(bashCompletionFunc, strings.Join(lastCommands, " | "))
+	return root.GenBashCompletion(w)
+}
+
+func genZshCompletion(root *cobra.Command, w io.Writer) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "#compdef %s\n\n", binaryName)
+	buf.WriteString(`__pd-ctl_get_schedulers() {
+    local out
+    out=$(pd-ctl -d -o json "$@" scheduler show </dev/null 2>/dev/null)
+    [[ $out == \[* ]] || return
+    echo $out | tr -d '[]" ' | tr ',' '\n'
+}
+
+_pd-ctl() {
+    local -a args pd_flags candidates
+    local skip=0 w
+    for w in ${words[2,CURRENT-1]}; do
+        if (( skip )); then
+            if [[ $skip == u ]]; then
+                pd_flags=(-u $w)
+            fi
+            skip=0
+            continue
+        fi
+        case $w in
+            -u|--pd) skip=u ;;
+            -o|--output) skip=1 ;;
+            -*) ;;
+            *) args+=($w) ;;
+        esac
+    done
+
+    case "${args[*]}" in
+`)
+	walkCommands(root, nil, func(path []string, cmd *cobra.Command) {
+		var items []string
+		for _, c := range cmd.Commands() {
+			desc := strings.Replace(c.Short, ":", "\\:", -1)
+			items = append(items, fmt.Sprintf("'%s:%s'", c.Name(), strings.Replace(desc, "'", "'\\''", -1)))
+		}
+		if len(items) == 0 {
+			return
+		}
+		fmt.Fprintf(&buf, "        %q)\n", strings.Join(path, " "))
+		fmt.Fprintf(&buf, "            candidates=(%s)\n", strings.Join(items, " "))
+		buf.WriteString("            _describe 'command' candidates\n")
+		buf.WriteString("            ;;\n")
+	})
+	for _, c := range schedulerNameCommands {
+		fmt.Fprintf(&buf, "        %q)\n", c)
+		buf.WriteString("            compadd -- $(__pd-ctl_get_schedulers $pd_flags)\n")
+		buf.WriteString("            ;;\n")
+	}
+	buf.WriteString(`    esac
+}
+
+compdef _pd-ctl pd-ctl
+`)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+func genFishCompletion(root *cobra.Command, w io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteString(`function __pd_ctl_using_command
+    set -l cmd (commandline -opc)
+    set -e cmd[1]
+    set -l words
+    set -l skip 0
+    for w in $cmd
+        if test $skip -eq 1
+            set skip 0
+            continue
+        end
+        switch $w
+            case -u --pd -o --output
+                set skip 1
+            case '-*'
+            case '*'
+                set words $words $w
+        end
+    end
+    test "$words" = "$argv"
+end
+
+function __pd_ctl_schedulers
+    set -l out (pd-ctl -d -o json scheduler show </dev/null 2>/dev/null)
+    string match -q -- '[*' "$out"; or return
+    echo $out | tr -d '[]" ' | tr ',' '\n'
+end
+
+complete -c pd-ctl -f
+`)
+	for _, f := range []struct{ short, long, desc string }{
+		{"u", "pd", "pd address"},
+		{"o", "output", "output format, json or table"},
+		{"d", "detach", "run pdctl without readline"},
+	} {
+		fmt.Fprintf(&buf, "complete -c %s -s %s -l %s -d '%s'\n", binaryName, f.short, f.long, f.desc)
+	}
+	walkCommands(root, nil, func(path []string, cmd *cobra.Command) {
+		for _, c := range cmd.Commands() {
+			fmt.Fprintf(&buf, "complete -c %s -n '__pd_ctl_using_command %s' -a %s -d '%s'\n",
+				binaryName, strings.Join(path, " "), c.Name(), strings.Replace(c.Short, "'", "\\'", -1))
+		}
+	})
+	for _, c := range schedulerNameCommands {
+		fmt.Fprintf(&buf, "complete -c %s -n '__pd_ctl_using_command %s' -a '(__pd_ctl_schedulers)'\n", binaryName, c)
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// walkCommands calls fn with each available command and its path from the
+// root, the root itself has an empty path.
+func walkCommands(cmd *cobra.Command, path []string, fn func(path []string, cmd *cobra.Command)) {
+	fn(path, cmd)
+	for _, c := range cmd.Commands() {
+		if !c.IsAvailableCommand() {
+			continue
+		}
+		walkCommands(c, append(append([]string(nil), path...), c.Name()), fn)
+	}
+}
@@ -14,18 +14,25 @@
 package command
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	configPrefix   = "pd/api/v1/config"
-	schedulePrefix = "pd/api/v1/config/schedule"
+	configPrefix        = "pd/api/v1/config"
+	schedulePrefix      = "pd/api/v1/config/schedule"
+	replicationPrefix   = "pd/api/v1/config/replication"
+	reloadPrefix        = "pd/api/v1/config/reload"
+	historyPrefix       = "pd/api/v1/config/history"
+	diffPrefix          = "pd/api/v1/config/diff"
+	rollbackPrefix      = "pd/api/v1/config/rollback/%s"
+	labelPropertyPrefix = "pd/api/v1/config/label-property"
 )
 
 // NewConfigCommand return a config subcommand of rootCmd
@@ -36,13 +43,18 @@ func NewConfigCommand() *cobra.Command {
 	}
 	conf.AddCommand(NewShowConfigCommand())
 	conf.AddCommand(NewSetConfigCommand())
+	conf.AddCommand(NewReloadConfigCommand())
+	conf.AddCommand(NewConfigHistoryCommand())
+	conf.AddCommand(NewConfigDiffCommand())
+	conf.AddCommand(NewRollbackConfigCommand())
+	conf.AddCommand(NewLabelPropertyConfigCommand())
 	return conf
 }
 
 // NewShowConfigCommand return a show subcommand of configCmd
 func NewShowConfigCommand() *cobra.Command {
 	sc := &cobra.Command{
-		Use:   "show",
+		Use:   "show [all|schedule|replication|label-property]",
 		Short: "show config of PD",
 		Run:   showConfigCommandFunc,
 	}
@@ -53,19 +65,89 @@ func NewShowConfigCommand() *cobra.Command {
 func NewSetConfigCommand() *cobra.Command {
 	sc := &cobra.Command{
 		Use:   "set <option> <value>",
-		Short: "set the option with value",
+		Short: "set the schedule or replication option with value",
 		Run:   setConfigCommandFunc,
 	}
 	return sc
 }
 
+// NewReloadConfigCommand return a reload subcommand of configCmd
+func NewReloadConfigCommand() *cobra.Command {
+	sc := &cobra.Command{
+		Use:   "reload",
+		Short: "reload the config file of the PD leader",
+		Run:   reloadConfigCommandFunc,
+	}
+	return sc
+}
+
+// NewConfigHistoryCommand return a history subcommand of configCmd
+func NewConfigHistoryCommand() *cobra.Command {
+	sc := &cobra.Command{
+		Use:   "history",
+		Short: "show the versions of the schedule and replication config",
+		Run:   configHistoryCommandFunc,
+	}
+	return sc
+}
+
+// NewConfigDiffCommand return a diff subcommand of configCmd
+func NewConfigDiffCommand() *cobra.Command {
+	sc := &cobra.Command{
+		Use:   "diff",
+		Short: "show the config differing from the defaults and between members",
+		Run:   configDiffCommandFunc,
+	}
+	return sc
+}
+
+// NewRollbackConfigCommand return a rollback subcommand of configCmd
+func NewRollbackConfigCommand() *cobra.Command {
+	sc := &cobra.Command{
+		Use:   "rollback <version>",
+		Short: "roll back the schedule and replication config to the version",
+		Run:   rollbackConfigCommandFunc,
+	}
+	return sc
+}
+
+// NewLabelPropertyConfigCommand return a label-property subcommand of configCmd
+func NewLabelPropertyConfigCommand() *cobra.Command {
+	sc := &cobra.Command{
+		Use:   "label-property [set|delete] <type> <key> <value>",
+		Short: "set or delete the label property, the types are reject-leader and slow-store",
+		Run:   labelPropertyConfigCommandFunc,
+	}
+	return sc
+}
+
 func showConfigCommandFunc(cmd *cobra.Command, args []string) {
-	r, err := doRequest(cmd, schedulePrefix, http.MethodGet)
+	prefix := schedulePrefix
+	if len(args) > 1 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	if len(args) == 1 {
+		switch args[0] {
+		case "all":
+			prefix = configPrefix
+		case "schedule":
+		case "replication":
+			prefix = replicationPrefix
+		case "label-property":
+			prefix = labelPropertyPrefix
+		default:
+			fmt.Println(cmd.UsageString())
+			return
+		}
+	}
+
+	r, err := doRequest(cmd, prefix, http.MethodGet)
 	if err != nil {
-		fmt.Printf("Failed to get config: %s", err)
+		fmt.Printf("Failed to get config: %s\n", err)
 		return
 	}
-	fmt.Println(r)
+	printResponse(cmd, r)
 }
 
 func setConfigCommandFunc(cmd *cobra.Command, args []string) {
@@ -74,44 +156,138 @@ func setConfigCommandFunc(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	url := getAddressFromCmd(cmd, schedulePrefix)
-	var value interface{}
-	data := make(map[string]interface{})
+	// Find out which config the option belongs to, unknown options are
+	// rejected here, otherwise the server ignores them silently.
+	key := args[0]
+	var (
+		prefix string
+		data   map[string]interface{}
+		keys   []string
+	)
+	for _, p := range []string{schedulePrefix, replicationPrefix} {
+		config, err := getConfigMap(cmd, p)
+		if err != nil {
+			fmt.Printf("Failed to set config: %s\n", err)
+			return
+		}
+		if _, ok := config[key]; ok {
+			prefix, data = p, config
+			break
+		}
+		for k := range config {
+			keys = append(keys, k)
+		}
+	}
+	if data == nil {
+		sort.Strings(keys)
+		fmt.Printf("Unknown option %s, options are: %s\n", key, strings.Join(keys, ", "))
+		return
+	}
+
+	value, err := parseConfigValue(data[key], args[1])
+	if err != nil {
+		fmt.Printf("Invalid value of %s: %s\n", key, err)
+		return
+	}
+	data[key] = value
+	postJSON(cmd, prefix, data)
+}
 
-	r, err := http.Get(url)
+func reloadConfigCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	r, err := doRequest(cmd, reloadPrefix, http.MethodPost)
 	if err != nil {
-		fmt.Printf("Failed to set config:[%s]\n", err)
+		fmt.Printf("Failed to reload config: %s\n", err)
 		return
 	}
-	if r.StatusCode != http.StatusOK {
-		printResponseError(r)
-		r.Body.Close()
+	printResponse(cmd, r)
+}
+
+func configHistoryCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	r, err := doRequest(cmd, historyPrefix, http.MethodGet)
+	if err != nil {
+		fmt.Printf("Failed to get config history: %s\n", err)
 		return
 	}
+	printResponse(cmd, r)
+}
 
-	json.NewDecoder(r.Body).Decode(&data)
-	r.Body.Close()
-	value, err = strconv.ParseFloat(args[1], 64)
+func configDiffCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	r, err := doRequest(cmd, diffPrefix, http.MethodGet)
 	if err != nil {
-		value = args[1]
+		fmt.Printf("Failed to get config diff: %s\n", err)
+		return
 	}
-	data[args[0]] = value
+	printResponse(cmd, r)
+}
 
-	req, err := json.Marshal(data)
+func rollbackConfigCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	if _, err := strconv.ParseUint(args[0], 10, 64); err != nil {
+		fmt.Println("version should be a number")
+		return
+	}
+	r, err := doRequest(cmd, fmt.Sprintf(rollbackPrefix, args[0]), http.MethodPost)
 	if err != nil {
-		fmt.Printf("Failed to set config:[%s]\n", err)
+		fmt.Printf("Failed to roll back config: %s\n", err)
 		return
 	}
+	printResponse(cmd, r)
+}
 
-	url = getAddressFromCmd(cmd, configPrefix)
-	r, err = http.Post(url, "application/json", bytes.NewBuffer(req))
+func labelPropertyConfigCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 4 || (args[0] != "set" && args[0] != "delete") {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	input := map[string]interface{}{
+		"action":      args[0],
+		"type":        args[1],
+		"label-key":   args[2],
+		"label-value": args[3],
+	}
+	postJSON(cmd, labelPropertyPrefix, input)
+}
+
+func getConfigMap(cmd *cobra.Command, prefix string) (map[string]interface{}, error) {
+	r, err := doRequest(cmd, prefix, http.MethodGet)
 	if err != nil {
-		fmt.Printf("Failed to set config:[%s]\n", err)
+		return nil, err
+	}
+	config := make(map[string]interface{})
+	if err = json.Unmarshal([]byte(r), &config); err != nil {
+		return nil, err
 	}
-	defer r.Body.Close()
-	if r.StatusCode == http.StatusOK {
-		fmt.Println("Success!")
-	} else {
-		printResponseError(r)
+	return config, nil
+}
+
+// parseConfigValue parses the value as the type of the old value.
+func parseConfigValue(old interface{}, value string) (interface{}, error) {
+	switch old.(type) {
+	case float64:
+		return strconv.ParseFloat(value, 64)
+	case bool:
+		return strconv.ParseBool(value)
+	case []interface{}, nil:
+		// Lists are separated by commas, e.g. location-labels.
+		if value == "" {
+			return []string{}, nil
+		}
+		return strings.Split(value, ","), nil
 	}
+	return value, nil
 }
@@ -0,0 +1,67 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	encryptionKeysPrefix   = "pd/api/v1/encryption/keys"
+	encryptionRotatePrefix = "pd/api/v1/encryption/keys/rotate"
+)
+
+// NewEncryptionCommand return a encryption subcommand of rootCmd
+func NewEncryptionCommand() *cobra.Command {
+	e := &cobra.Command{
+		Use:   "encryption [rotate]",
+		Short: "show the data keys of the encryption at rest, or rotate the current key",
+		Run:   showEncryptionKeysCommandFunc,
+	}
+	e.AddCommand(&cobra.Command{
+		Use:   "rotate",
+		Short: "rotate the current data key",
+		Run:   rotateEncryptionKeyCommandFunc,
+	})
+	return e
+}
+
+func showEncryptionKeysCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	r, err := doRequest(cmd, encryptionKeysPrefix, http.MethodGet)
+	if err != nil {
+		fmt.Printf("Failed to get encryption keys: %s\n", err)
+		return
+	}
+	printResponse(cmd, r)
+}
+
+func rotateEncryptionKeyCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	r, err := doRequest(cmd, encryptionRotatePrefix, http.MethodPost)
+	if err != nil {
+		fmt.Printf("Failed to rotate encryption key: %s\n", err)
+		return
+	}
+	printResponse(cmd, r)
+}
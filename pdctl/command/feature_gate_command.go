@@ -0,0 +1,77 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	featureGatesPrefix = "pd/api/v1/feature-gates"
+	featureGatePrefix  = "pd/api/v1/feature-gates/%s"
+)
+
+// NewFeatureGateCommand return a feature-gate subcommand of rootCmd
+func NewFeatureGateCommand() *cobra.Command {
+	f := &cobra.Command{
+		Use:   "feature-gate [set <name> <true|false>]",
+		Short: "show or set the feature gates",
+		Run:   showFeatureGatesCommandFunc,
+	}
+	f.AddCommand(NewSetFeatureGateCommand())
+	return f
+}
+
+// NewSetFeatureGateCommand return a set subcommand of featureGateCmd
+func NewSetFeatureGateCommand() *cobra.Command {
+	f := &cobra.Command{
+		Use:   "set <name> <true|false>",
+		Short: "enable or disable the feature gate",
+		Run:   setFeatureGateCommandFunc,
+	}
+	return f
+}
+
+func showFeatureGatesCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	r, err := doRequest(cmd, featureGatesPrefix, http.MethodGet)
+	if err != nil {
+		fmt.Printf("Failed to get feature gates: %s\n", err)
+		return
+	}
+	printResponse(cmd, r)
+}
+
+func setFeatureGateCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	enabled, err := strconv.ParseBool(args[1])
+	if err != nil {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	input := map[string]interface{}{
+		"enabled": enabled,
+	}
+	postJSON(cmd, fmt.Sprintf(featureGatePrefix, args[0]), input)
+}
@@ -0,0 +1,169 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	gcSafePointPrefix       = "pd/api/v1/gc/safepoint"
+	serviceSafePointsPrefix = "pd/api/v1/gc/safepoint/service"
+	serviceSafePointPrefix  = "pd/api/v1/gc/safepoint/service/%s"
+)
+
+// NewGCCommand return a gc subcommand of rootCmd
+func NewGCCommand() *cobra.Command {
+	g := &cobra.Command{
+		Use:   "gc <subcommand>",
+		Short: "gc related commands",
+	}
+	g.AddCommand(NewGCSafePointCommand())
+	g.AddCommand(NewServiceSafePointCommand())
+	return g
+}
+
+// NewGCSafePointCommand return a safepoint subcommand of gcCmd
+func NewGCSafePointCommand() *cobra.Command {
+	s := &cobra.Command{
+		Use:   "safepoint [set <timestamp>]",
+		Short: "show or update the gc safe point",
+		Run:   showGCSafePointCommandFunc,
+	}
+	s.AddCommand(NewSetGCSafePointCommand())
+	return s
+}
+
+// NewSetGCSafePointCommand return a set subcommand of gcSafePointCmd
+func NewSetGCSafePointCommand() *cobra.Command {
+	s := &cobra.Command{
+		Use:   "set <timestamp>",
+		Short: "update the gc safe point, it can't go backward",
+		Run:   setGCSafePointCommandFunc,
+	}
+	return s
+}
+
+// NewServiceSafePointCommand return a service-safepoint subcommand of gcCmd
+func NewServiceSafePointCommand() *cobra.Command {
+	s := &cobra.Command{
+		Use:   "service-safepoint [set <service_id> <ttl> <timestamp>|delete <service_id>]",
+		Short: "show, set or delete the safe points of the services",
+		Run:   showServiceSafePointsCommandFunc,
+	}
+	s.AddCommand(NewSetServiceSafePointCommand())
+	s.AddCommand(NewDeleteServiceSafePointCommand())
+	return s
+}
+
+// NewSetServiceSafePointCommand return a set subcommand of serviceSafePointCmd
+func NewSetServiceSafePointCommand() *cobra.Command {
+	s := &cobra.Command{
+		Use:   "set <service_id> <ttl> <timestamp>",
+		Short: "set the safe point of the service, which expires after ttl seconds",
+		Run:   setServiceSafePointCommandFunc,
+	}
+	return s
+}
+
+// NewDeleteServiceSafePointCommand return a delete subcommand of serviceSafePointCmd
+func NewDeleteServiceSafePointCommand() *cobra.Command {
+	s := &cobra.Command{
+		Use:   "delete <service_id>",
+		Short: "delete the safe point of the service",
+		Run:   deleteServiceSafePointCommandFunc,
+	}
+	return s
+}
+
+func showGCSafePointCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	r, err := doRequest(cmd, gcSafePointPrefix, http.MethodGet)
+	if err != nil {
+		fmt.Printf("Failed to get gc safe point: %s\n", err)
+		return
+	}
+	printResponse(cmd, r)
+}
+
+func setGCSafePointCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	safePoint, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		fmt.Printf("Invalid timestamp %s: %s\n", args[0], err)
+		return
+	}
+	input := map[string]interface{}{
+		"safe_point": safePoint,
+	}
+	postJSON(cmd, gcSafePointPrefix, input)
+}
+
+func showServiceSafePointsCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	r, err := doRequest(cmd, serviceSafePointsPrefix, http.MethodGet)
+	if err != nil {
+		fmt.Printf("Failed to get service safe points: %s\n", err)
+		return
+	}
+	printResponse(cmd, r)
+}
+
+func setServiceSafePointCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 3 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	ttl, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		fmt.Printf("Invalid ttl %s: %s\n", args[1], err)
+		return
+	}
+	safePoint, err := strconv.ParseUint(args[2], 10, 64)
+	if err != nil {
+		fmt.Printf("Invalid timestamp %s: %s\n", args[2], err)
+		return
+	}
+	input := map[string]interface{}{
+		"ttl":        ttl,
+		"safe_point": safePoint,
+	}
+	postJSON(cmd, fmt.Sprintf(serviceSafePointPrefix, args[0]), input)
+}
+
+func deleteServiceSafePointCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	_, err := doRequest(cmd, fmt.Sprintf(serviceSafePointPrefix, args[0]), http.MethodDelete)
+	if err != nil {
+		fmt.Printf("Failed to delete service safe point: %s\n", err)
+		return
+	}
+	printSuccess(cmd)
+}
@@ -14,6 +14,9 @@
 package command
 
 import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -24,12 +27,15 @@ import (
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
 	"github.com/pingcap/pd/pd-client"
+	"github.com/pingcap/pd/pkg/tlsutil"
 	"github.com/spf13/cobra"
 )
 
 var (
 	pdClient   pd.Client
 	dailClient = &http.Client{}
+	// tlsConfig is used to connect pd in https, nil if the certs are not set.
+	tlsConfig *tls.Config
 
 	pingPrefix     = "pd/ping"
 	errInvalidAddr = errors.New("Invalid pd address, Cannot get connect to it")
@@ -62,6 +68,27 @@ func doRequest(cmd *cobra.Command, prefix string, method string) (string, error)
 	return res, nil
 }
 
+func postJSON(cmd *cobra.Command, prefix string, input map[string]interface{}) {
+	data, err := json.Marshal(input)
+	if err != nil {
+		fmt.Printf("Failed to marshal request: %s\n", err)
+		return
+	}
+
+	url := getAddressFromCmd(cmd, prefix)
+	r, err := dailClient.Post(url, "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		fmt.Printf("Failed to send request: %s\n", err)
+		return
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		printResponseError(r)
+		return
+	}
+	printSuccess(cmd)
+}
+
 func genResponseError(r *http.Response) error {
 	res, _ := ioutil.ReadAll(r.Body)
 	return errors.Errorf("[%d] %s", r.StatusCode, res)
@@ -77,17 +104,90 @@ func InitPDClient(cmd *cobra.Command) error {
 	if pdClient != nil {
 		return nil
 	}
+	security, err := getSecurityFromCmd(cmd)
+	if err != nil {
+		return err
+	}
+	if err = initTLSConfig(security); err != nil {
+		return err
+	}
+	token, err := cmd.Flags().GetString("token")
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		dailClient.Transport = newTokenTransport(token, dailClient.Transport)
+		pingClient.Transport = newTokenTransport(token, pingClient.Transport)
+	}
 	err = validPDAddr(addr)
 	if err != nil {
 		return err
 	}
-	pdClient, err = pd.NewClient([]string{addr})
+	pdClient, err = pd.NewClientWithSecurity([]string{addr}, security)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func getSecurityFromCmd(cmd *cobra.Command) (pd.SecurityOption, error) {
+	var security pd.SecurityOption
+	var err error
+	if security.CAPath, err = cmd.Flags().GetString("cacert"); err != nil {
+		return security, err
+	}
+	if security.CertPath, err = cmd.Flags().GetString("cert"); err != nil {
+		return security, err
+	}
+	if security.KeyPath, err = cmd.Flags().GetString("key"); err != nil {
+		return security, err
+	}
+	return security, nil
+}
+
+// initTLSConfig makes the http requests to pd in https verify the server by
+// the CA and present the cert if it is set.
+func initTLSConfig(security pd.SecurityOption) error {
+	cfg, err := tlsutil.SecurityConfig{
+		CAPath:   security.CAPath,
+		CertPath: security.CertPath,
+		KeyPath:  security.KeyPath,
+	}.ToClientTLSConfig()
 	if err != nil {
 		return err
 	}
+	if cfg != nil {
+		tlsConfig = cfg
+		dailClient = &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+		pingClient = &http.Client{Timeout: pingTimeout, Transport: &http.Transport{TLSClientConfig: cfg}}
+	}
 	return nil
 }
 
+// tokenTransport adds the api token to the requests to pd.
+type tokenTransport struct {
+	token string
+	rt    http.RoundTripper
+}
+
+func newTokenTransport(token string, rt http.RoundTripper) *tokenTransport {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return &tokenTransport{token: token, rt: rt}
+}
+
+func (t *tokenTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	req := new(http.Request)
+	*req = *r
+	req.Header = make(http.Header, len(r.Header)+1)
+	for k, v := range r.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.rt.RoundTrip(req)
+}
+
 func getClient() (pd.Client, error) {
 	if pdClient == nil {
 		return nil, errors.New("Must initialized pdClient firstly")
@@ -127,7 +227,7 @@ func validPDAddr(pd string) error {
 		u.Scheme = "http"
 	}
 	addr := u.String()
-	reps, err := http.Get(fmt.Sprintf("%s/%s", addr, pingPrefix))
+	reps, err := dailClient.Get(fmt.Sprintf("%s/%s", addr, pingPrefix))
 	if err != nil {
 		return err
 	}
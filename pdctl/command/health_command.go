@@ -0,0 +1,197 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pingClient = &http.Client{Timeout: pingTimeout}
+
+	regionCheckTypes = []string{"miss-peer", "down-peer", "pending-peer", "offline-peer"}
+)
+
+// NewHealthCommand return a health subcommand of rootCmd
+func NewHealthCommand() *cobra.Command {
+	h := &cobra.Command{
+		Use:   "health",
+		Short: "show the health summary of the cluster",
+		Run:   showHealthCommandFunc,
+	}
+	return h
+}
+
+type memberHealth struct {
+	Name       string   `json:"name"`
+	ClientUrls []string `json:"client_urls"`
+	Healthy    bool     `json:"healthy"`
+	IsLeader   bool     `json:"is_leader"`
+}
+
+type healthReport struct {
+	Members          []memberHealth `json:"members"`
+	StoreCount       int            `json:"store_count"`
+	StoreStates      map[string]int `json:"store_states"`
+	AbnormalRegions  map[string]int `json:"abnormal_regions"`
+	PendingOperators *int           `json:"pending_operators"`
+	Errors           []string       `json:"errors,omitempty"`
+}
+
+func showHealthCommandFunc(cmd *cobra.Command, args []string) {
+	report := &healthReport{}
+	if err := report.collectMembers(cmd); err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("failed to get members: %s", err))
+	}
+	if err := report.collectStores(cmd); err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("failed to get stores: %s", err))
+	}
+	if err := report.collectRegions(cmd); err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("failed to check regions: %s", err))
+	}
+	if err := report.collectOperators(cmd); err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("failed to get operators: %s", err))
+	}
+	printValue(cmd, report, report.print)
+}
+
+func (r *healthReport) collectMembers(cmd *cobra.Command) error {
+	var info struct {
+		Members []memberHealth `json:"members"`
+	}
+	if err := getCmdJSON(cmd, membersPrefix, &info); err != nil {
+		return err
+	}
+	for _, m := range info.Members {
+		m.Healthy = len(m.ClientUrls) > 0 && pingMember(m.ClientUrls[0]) == nil
+		r.Members = append(r.Members, m)
+	}
+	return nil
+}
+
+func (r *healthReport) collectStores(cmd *cobra.Command) error {
+	var info struct {
+		Stores []struct {
+			Store struct {
+				StateName string `json:"state_name"`
+			} `json:"store"`
+		} `json:"stores"`
+	}
+	if err := getCmdJSON(cmd, storesPrefix, &info); err != nil {
+		return err
+	}
+	r.StoreCount = len(info.Stores)
+	r.StoreStates = make(map[string]int)
+	for _, s := range info.Stores {
+		r.StoreStates[s.Store.StateName]++
+	}
+	return nil
+}
+
+func (r *healthReport) collectRegions(cmd *cobra.Command) error {
+	regions := make(map[string]int)
+	for _, typ := range regionCheckTypes {
+		var info struct {
+			Count int `json:"count"`
+		}
+		if err := getCmdJSON(cmd, fmt.Sprintf(regionsCheckPrefix, typ), &info); err != nil {
+			return err
+		}
+		regions[typ] = info.Count
+	}
+	r.AbnormalRegions = regions
+	return nil
+}
+
+func (r *healthReport) collectOperators(cmd *cobra.Command) error {
+	var ops []json.RawMessage
+	if err := getCmdJSON(cmd, operatorsPrefix, &ops); err != nil {
+		return err
+	}
+	count := len(ops)
+	r.PendingOperators = &count
+	return nil
+}
+
+func (r *healthReport) print() {
+	fmt.Printf("Members: %d\n", len(r.Members))
+	for _, m := range r.Members {
+		health := "unhealthy"
+		if m.Healthy {
+			health = "healthy"
+		}
+		role := "follower"
+		if m.IsLeader {
+			role = "leader"
+		}
+		fmt.Printf("  %-16s %-32s %-10s %s\n", m.Name, strings.Join(m.ClientUrls, ","), health, role)
+	}
+
+	if r.StoreStates != nil {
+		names := make([]string, 0, len(r.StoreStates))
+		for name := range r.StoreStates {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		counts := make([]string, 0, len(names))
+		for _, name := range names {
+			counts = append(counts, fmt.Sprintf("%s %d", name, r.StoreStates[name]))
+		}
+		fmt.Printf("Stores: %d (%s)\n", r.StoreCount, strings.Join(counts, ", "))
+	}
+
+	if r.AbnormalRegions != nil {
+		counts := make([]string, 0, len(regionCheckTypes))
+		for _, typ := range regionCheckTypes {
+			counts = append(counts, fmt.Sprintf("%s %d", typ, r.AbnormalRegions[typ]))
+		}
+		fmt.Printf("Abnormal regions: %s\n", strings.Join(counts, ", "))
+	}
+
+	if r.PendingOperators != nil {
+		fmt.Printf("Pending operators: %d\n", *r.PendingOperators)
+	}
+	for _, err := range r.Errors {
+		fmt.Printf("Error: %s\n", err)
+	}
+}
+
+func getCmdJSON(cmd *cobra.Command, prefix string, v interface{}) error {
+	r, err := doRequest(cmd, prefix, http.MethodGet)
+	if err != nil {
+		return errors.New(strings.TrimSpace(err.Error()))
+	}
+	return json.Unmarshal([]byte(r), v)
+}
+
+func pingMember(addr string) error {
+	resp, err := pingClient.Get(fmt.Sprintf("%s/%s", addr, pingPrefix))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return errInvalidAddr
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	keyspacesPrefix     = "pd/api/v1/keyspaces"
+	keyspacePrefix      = "pd/api/v1/keyspaces/%s"
+	keyspaceStatePrefix = "pd/api/v1/keyspaces/%s/state"
+)
+
+// NewKeyspaceCommand return a keyspace subcommand of rootCmd
+func NewKeyspaceCommand() *cobra.Command {
+	k := &cobra.Command{
+		Use:   "keyspace [show|create|enable|disable|archive]",
+		Short: "show or change the keyspaces",
+		Run:   showKeyspacesCommandFunc,
+	}
+	k.AddCommand(&cobra.Command{
+		Use:   "show <name>",
+		Short: "show the keyspace",
+		Run:   showKeyspaceCommandFunc,
+	})
+	k.AddCommand(&cobra.Command{
+		Use:   "create <name>",
+		Short: "create a keyspace",
+		Run:   createKeyspaceCommandFunc,
+	})
+	k.AddCommand(newKeyspaceStateCommand("enable", "enabled"))
+	k.AddCommand(newKeyspaceStateCommand("disable", "disabled"))
+	k.AddCommand(newKeyspaceStateCommand("archive", "archived"))
+	return k
+}
+
+func newKeyspaceStateCommand(verb, state string) *cobra.Command {
+	return &cobra.Command{
+		Use:   verb + " <name>",
+		Short: verb + " the keyspace",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				fmt.Println(cmd.UsageString())
+				return
+			}
+			input := map[string]interface{}{
+				"state": state,
+			}
+			postJSON(cmd, fmt.Sprintf(keyspaceStatePrefix, args[0]), input)
+		},
+	}
+}
+
+func showKeyspacesCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	r, err := doRequest(cmd, keyspacesPrefix, http.MethodGet)
+	if err != nil {
+		fmt.Printf("Failed to get keyspaces: %s\n", err)
+		return
+	}
+	printResponse(cmd, r)
+}
+
+func showKeyspaceCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	r, err := doRequest(cmd, fmt.Sprintf(keyspacePrefix, args[0]), http.MethodGet)
+	if err != nil {
+		fmt.Printf("Failed to get keyspace: %s\n", err)
+		return
+	}
+	printResponse(cmd, r)
+}
+
+func createKeyspaceCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	input := map[string]interface{}{
+		"name": args[0],
+	}
+	postJSON(cmd, keyspacesPrefix, input)
+}
@@ -52,7 +52,7 @@ func showLabelsCommandFunc(cmd *cobra.Command, args []string) {
 		fmt.Printf("Failed to get labels: %s", err)
 		return
 	}
-	fmt.Println(r)
+	printResponse(cmd, r)
 }
 
 func getValue(args []string, i int) string {
@@ -75,5 +75,5 @@ func showLabelListStoresCommandFunc(cmd *cobra.Command, args []string) {
 		fmt.Printf("Failed to get stores through label: %s", err)
 		return
 	}
-	fmt.Println(r)
+	printResponse(cmd, r)
 }
@@ -16,25 +16,30 @@ package command
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	membersPrefix      = "pd/api/v1/members"
-	memberPrefix       = "pd/api/v1/members/%s"
-	leaderMemberPrefix = "pd/api/v1/leader"
+	membersPrefix        = "pd/api/v1/members"
+	memberPrefix         = "pd/api/v1/members/%s"
+	memberIDPrefix       = "pd/api/v1/members/id/%d"
+	leaderMemberPrefix   = "pd/api/v1/leader"
+	leaderResignPrefix   = "pd/api/v1/leader/resign"
+	leaderTransferPrefix = "pd/api/v1/leader/transfer/%s"
 )
 
 // NewMemberCommand return a member subcommand of rootCmd
 func NewMemberCommand() *cobra.Command {
 	m := &cobra.Command{
-		Use:   "member [leader|delete]",
+		Use:   "member [leader|delete|leader_priority]",
 		Short: "show the pd member status",
 		Run:   showMemberCommandFunc,
 	}
 	m.AddCommand(NewLeaderMemberCommand())
 	m.AddCommand(NewDeleteMemberCommand())
+	m.AddCommand(NewLeaderPriorityCommand())
 	return m
 }
 
@@ -45,26 +50,51 @@ func NewDeleteMemberCommand() *cobra.Command {
 		Short: "delete the member",
 		Run:   deleteMemberCommandFunc,
 	}
+	d.AddCommand(&cobra.Command{
+		Use:   "id <member_id>",
+		Short: "delete a member by id",
+		Run:   deleteMemberByIDCommandFunc,
+	})
 	return d
 }
 
 // NewLeaderMemberCommand return a leader subcommand of memberCmd
 func NewLeaderMemberCommand() *cobra.Command {
 	l := &cobra.Command{
-		Use:   "leader",
+		Use:   "leader [resign|transfer]",
 		Short: "show the leader member status",
 		Run:   getLeaderMemberCommandFunc,
 	}
+	l.AddCommand(&cobra.Command{
+		Use:   "resign",
+		Short: "resign the leadership, a random member becomes the next leader",
+		Run:   resignMemberLeaderCommandFunc,
+	})
+	l.AddCommand(&cobra.Command{
+		Use:   "transfer <member_name>",
+		Short: "transfer the leadership to the member",
+		Run:   transferMemberLeaderCommandFunc,
+	})
 	return l
 }
 
+// NewLeaderPriorityCommand return a leader_priority subcommand of memberCmd
+func NewLeaderPriorityCommand() *cobra.Command {
+	p := &cobra.Command{
+		Use:   "leader_priority <member_name> <priority>",
+		Short: "set the priority of the member to be elected as leader",
+		Run:   setLeaderPriorityCommandFunc,
+	}
+	return p
+}
+
 func showMemberCommandFunc(cmd *cobra.Command, args []string) {
 	r, err := doRequest(cmd, membersPrefix, http.MethodGet)
 	if err != nil {
 		fmt.Printf("Failed to get pd members: %s", err)
 		return
 	}
-	fmt.Println(r)
+	printResponse(cmd, r)
 }
 
 func deleteMemberCommandFunc(cmd *cobra.Command, args []string) {
@@ -78,7 +108,26 @@ func deleteMemberCommandFunc(cmd *cobra.Command, args []string) {
 		fmt.Printf("Failed to delete member %s: %s", args[0], err)
 		return
 	}
-	fmt.Println("Success!")
+	printSuccess(cmd)
+}
+
+func deleteMemberByIDCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Println("Usage: member delete id <member_id>")
+		return
+	}
+	id, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		fmt.Println("member_id should be a number")
+		return
+	}
+	prefix := fmt.Sprintf(memberIDPrefix, id)
+	_, err = doRequest(cmd, prefix, http.MethodDelete)
+	if err != nil {
+		fmt.Printf("Failed to delete member %d: %s", id, err)
+		return
+	}
+	printSuccess(cmd)
 }
 
 func getLeaderMemberCommandFunc(cmd *cobra.Command, args []string) {
@@ -87,5 +136,42 @@ func getLeaderMemberCommandFunc(cmd *cobra.Command, args []string) {
 		fmt.Printf("Failed to get the leader of pd members: %s", err)
 		return
 	}
-	fmt.Println(r)
+	printResponse(cmd, r)
+}
+
+func resignMemberLeaderCommandFunc(cmd *cobra.Command, args []string) {
+	_, err := doRequest(cmd, leaderResignPrefix, http.MethodPost)
+	if err != nil {
+		fmt.Printf("Failed to resign the leader: %s", err)
+		return
+	}
+	printSuccess(cmd)
+}
+
+func transferMemberLeaderCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Println("Usage: member leader transfer <member_name>")
+		return
+	}
+	prefix := fmt.Sprintf(leaderTransferPrefix, args[0])
+	_, err := doRequest(cmd, prefix, http.MethodPost)
+	if err != nil {
+		fmt.Printf("Failed to transfer the leader to %s: %s", args[0], err)
+		return
+	}
+	printSuccess(cmd)
+}
+
+func setLeaderPriorityCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		fmt.Println("Usage: member leader_priority <member_name> <priority>")
+		return
+	}
+	priority, err := strconv.Atoi(args[1])
+	if err != nil {
+		fmt.Println("priority should be a number")
+		return
+	}
+	prefix := fmt.Sprintf(memberPrefix, args[0])
+	postJSON(cmd, prefix, map[string]interface{}{"leader-priority": priority})
 }
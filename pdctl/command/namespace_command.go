@@ -0,0 +1,246 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	namespacesPrefix      = "pd/api/v1/namespaces"
+	namespacePrefix       = "pd/api/v1/namespaces/%s"
+	namespaceTablesPrefix = "pd/api/v1/namespaces/%s/tables"
+	namespaceTablePrefix  = "pd/api/v1/namespaces/%s/tables/%d"
+	namespaceRangesPrefix = "pd/api/v1/namespaces/%s/ranges"
+	namespaceRangePrefix  = "pd/api/v1/namespaces/%s/ranges?start_key=%s"
+	namespaceStoresPrefix = "pd/api/v1/namespaces/%s/stores"
+	namespaceStorePrefix  = "pd/api/v1/namespaces/%s/stores/%d"
+	namespaceConfigPrefix = "pd/api/v1/namespaces/%s/config"
+)
+
+// NewNamespaceCommand return a namespace subcommand of rootCmd
+func NewNamespaceCommand() *cobra.Command {
+	n := &cobra.Command{
+		Use:   "namespace [create|delete|add-table|remove-table|add-range|remove-range|add-store|remove-store|set-config]",
+		Short: "show or change the namespaces",
+		Run:   showNamespacesCommandFunc,
+	}
+	n.AddCommand(&cobra.Command{
+		Use:   "create <name>",
+		Short: "create a namespace",
+		Run:   createNamespaceCommandFunc,
+	})
+	n.AddCommand(&cobra.Command{
+		Use:   "delete <name>",
+		Short: "delete a namespace",
+		Run:   deleteNamespaceCommandFunc,
+	})
+	n.AddCommand(&cobra.Command{
+		Use:   "add-table <name> <table_id>",
+		Short: "add a table to the namespace",
+		Run:   addNamespaceTableCommandFunc,
+	})
+	n.AddCommand(&cobra.Command{
+		Use:   "remove-table <name> <table_id>",
+		Short: "remove a table from the namespace",
+		Run:   removeNamespaceTableCommandFunc,
+	})
+	n.AddCommand(&cobra.Command{
+		Use:   "add-range <name> <start_key> <end_key>",
+		Short: "add a key range to the namespace, the keys are raw keys in hex",
+		Run:   addNamespaceRangeCommandFunc,
+	})
+	n.AddCommand(&cobra.Command{
+		Use:   "remove-range <name> <start_key>",
+		Short: "remove the key range starting from the key from the namespace",
+		Run:   removeNamespaceRangeCommandFunc,
+	})
+	n.AddCommand(&cobra.Command{
+		Use:   "add-store <name> <store_id>",
+		Short: "assign a store to the namespace",
+		Run:   addNamespaceStoreCommandFunc,
+	})
+	n.AddCommand(&cobra.Command{
+		Use:   "remove-store <name> <store_id>",
+		Short: "remove a store from the namespace",
+		Run:   removeNamespaceStoreCommandFunc,
+	})
+	c := &cobra.Command{
+		Use:   "set-config <name> [--max-replicas=<n>] [--location-labels=<label>,...] [--leader-schedule-limit=<n>] [--region-schedule-limit=<n>] [--replica-schedule-limit=<n>] [--disabled-schedulers=<name>,...]",
+		Short: "set the scheduling config of the namespace",
+		Run:   setNamespaceConfigCommandFunc,
+	}
+	c.Flags().Uint64("max-replicas", 0, "the max replicas of the regions, 0 means the global max-replicas")
+	c.Flags().StringSlice("location-labels", nil, "the labels to isolate the replicas, empty means the global location-labels")
+	c.Flags().Uint64("leader-schedule-limit", 0, "the leader schedule limit of the regions, 0 means the global limit")
+	c.Flags().Uint64("region-schedule-limit", 0, "the region schedule limit of the regions, 0 means the global limit")
+	c.Flags().Uint64("replica-schedule-limit", 0, "the replica schedule limit of the regions, 0 means the global limit")
+	c.Flags().StringSlice("disabled-schedulers", nil, "the schedulers which do not schedule the regions")
+	n.AddCommand(c)
+	return n
+}
+
+func showNamespacesCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	r, err := doRequest(cmd, namespacesPrefix, http.MethodGet)
+	if err != nil {
+		fmt.Printf("Failed to get namespaces: %s\n", err)
+		return
+	}
+	printResponse(cmd, r)
+}
+
+func createNamespaceCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	input := map[string]interface{}{
+		"name": args[0],
+	}
+	postJSON(cmd, namespacesPrefix, input)
+}
+
+func deleteNamespaceCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	deleteNamespaceResource(cmd, fmt.Sprintf(namespacePrefix, args[0]))
+}
+
+func addNamespaceTableCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	tableID, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		fmt.Printf("Invalid table ID %s: %s\n", args[1], err)
+		return
+	}
+	input := map[string]interface{}{
+		"table_id": tableID,
+	}
+	postJSON(cmd, fmt.Sprintf(namespaceTablesPrefix, args[0]), input)
+}
+
+func removeNamespaceTableCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	tableID, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		fmt.Printf("Invalid table ID %s: %s\n", args[1], err)
+		return
+	}
+	deleteNamespaceResource(cmd, fmt.Sprintf(namespaceTablePrefix, args[0], tableID))
+}
+
+func addNamespaceRangeCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 3 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	input := map[string]interface{}{
+		"start_key": args[1],
+		"end_key":   args[2],
+	}
+	postJSON(cmd, fmt.Sprintf(namespaceRangesPrefix, args[0]), input)
+}
+
+func removeNamespaceRangeCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	deleteNamespaceResource(cmd, fmt.Sprintf(namespaceRangePrefix, args[0], url.QueryEscape(args[1])))
+}
+
+func addNamespaceStoreCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	storeID, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		fmt.Printf("Invalid store ID %s: %s\n", args[1], err)
+		return
+	}
+	input := map[string]interface{}{
+		"store_id": storeID,
+	}
+	postJSON(cmd, fmt.Sprintf(namespaceStoresPrefix, args[0]), input)
+}
+
+func removeNamespaceStoreCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	storeID, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		fmt.Printf("Invalid store ID %s: %s\n", args[1], err)
+		return
+	}
+	deleteNamespaceResource(cmd, fmt.Sprintf(namespaceStorePrefix, args[0], storeID))
+}
+
+func deleteNamespaceResource(cmd *cobra.Command, prefix string) {
+	_, err := doRequest(cmd, prefix, http.MethodDelete)
+	if err != nil {
+		fmt.Printf("Failed to change namespace: %s\n", err)
+		return
+	}
+	printSuccess(cmd)
+}
+
+func setNamespaceConfigCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	input := make(map[string]interface{})
+	for _, name := range []string{"max-replicas", "leader-schedule-limit", "region-schedule-limit", "replica-schedule-limit"} {
+		value, err := cmd.Flags().GetUint64(name)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		input[strings.Replace(name, "-", "_", -1)] = value
+	}
+	labels, err := cmd.Flags().GetStringSlice("location-labels")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	input["location_labels"] = labels
+	schedulers, err := cmd.Flags().GetStringSlice("disabled-schedulers")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	input["disabled_schedulers"] = schedulers
+	postJSON(cmd, fmt.Sprintf(namespaceConfigPrefix, args[0]), input)
+}
@@ -0,0 +1,325 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	operatorsPrefix = "pd/api/v1/operators"
+	operatorPrefix  = "pd/api/v1/operators/%s"
+)
+
+// NewOperatorCommand return a operator subcommand of rootCmd
+func NewOperatorCommand() *cobra.Command {
+	o := &cobra.Command{
+		Use:   "operator <command>",
+		Short: "operator commands",
+	}
+	o.AddCommand(NewShowOperatorCommand())
+	o.AddCommand(NewAddOperatorCommand())
+	o.AddCommand(NewRemoveOperatorCommand())
+	return o
+}
+
+// NewShowOperatorCommand return a show subcommand of operatorCmd
+func NewShowOperatorCommand() *cobra.Command {
+	s := &cobra.Command{
+		Use:   "show [<region_id>]",
+		Short: "show the running operators",
+		Run:   showOperatorCommandFunc,
+	}
+	return s
+}
+
+// NewAddOperatorCommand return a add subcommand of operatorCmd
+func NewAddOperatorCommand() *cobra.Command {
+	a := &cobra.Command{
+		Use:   "add <operator>",
+		Short: "add an operator",
+	}
+	a.AddCommand(NewTransferLeaderCommand())
+	a.AddCommand(NewTransferPeerCommand())
+	a.AddCommand(NewAddPeerCommand())
+	a.AddCommand(NewRemovePeerCommand())
+	a.AddCommand(NewMoveRegionCommand())
+	a.AddCommand(NewSplitRegionCommand())
+	a.AddCommand(NewMergeRegionCommand())
+	return a
+}
+
+// NewTransferLeaderCommand return a transfer-leader subcommand of addCmd
+func NewTransferLeaderCommand() *cobra.Command {
+	t := &cobra.Command{
+		Use:   "transfer-leader <region_id> <to_store_id>",
+		Short: "transfer the leader of the region to the store",
+		Run:   transferLeaderCommandFunc,
+	}
+	return t
+}
+
+// NewTransferPeerCommand return a transfer-peer subcommand of addCmd
+func NewTransferPeerCommand() *cobra.Command {
+	t := &cobra.Command{
+		Use:   "transfer-peer <region_id> <from_store_id> <to_store_id>",
+		Short: "move the peer of the region from one store to another",
+		Run:   transferPeerCommandFunc,
+	}
+	return t
+}
+
+// NewAddPeerCommand return a add-peer subcommand of addCmd
+func NewAddPeerCommand() *cobra.Command {
+	a := &cobra.Command{
+		Use:   "add-peer <region_id> <to_store_id>",
+		Short: "add a peer of the region to the store",
+		Run:   addPeerCommandFunc,
+	}
+	return a
+}
+
+// NewRemovePeerCommand return a remove-peer subcommand of addCmd
+func NewRemovePeerCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:   "remove-peer <region_id> <from_store_id>",
+		Short: "remove the peer of the region from the store",
+		Run:   removePeerCommandFunc,
+	}
+	return r
+}
+
+// NewMoveRegionCommand return a move-region subcommand of addCmd
+func NewMoveRegionCommand() *cobra.Command {
+	m := &cobra.Command{
+		Use:   "move-region <region_id> <store_id> [<store_id>...]",
+		Short: "move the peers of the region to the stores",
+		Run:   moveRegionCommandFunc,
+	}
+	return m
+}
+
+// NewSplitRegionCommand return a split-region subcommand of addCmd
+func NewSplitRegionCommand() *cobra.Command {
+	s := &cobra.Command{
+		Use:   "split-region <region_id>",
+		Short: "split the region",
+		Run:   splitRegionCommandFunc,
+	}
+	return s
+}
+
+// NewMergeRegionCommand return a merge-region subcommand of addCmd
+func NewMergeRegionCommand() *cobra.Command {
+	m := &cobra.Command{
+		Use:   "merge-region <source_region_id> <target_region_id>",
+		Short: "merge the source region into the target region",
+		Run:   mergeRegionCommandFunc,
+	}
+	return m
+}
+
+// NewRemoveOperatorCommand return a remove subcommand of operatorCmd
+func NewRemoveOperatorCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:   "remove <region_id>",
+		Short: "remove the operator of the region",
+		Run:   removeOperatorCommandFunc,
+	}
+	return r
+}
+
+func showOperatorCommandFunc(cmd *cobra.Command, args []string) {
+	var prefix string
+	switch len(args) {
+	case 0:
+		prefix = operatorsPrefix
+	case 1:
+		prefix = fmt.Sprintf(operatorPrefix, args[0])
+	default:
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	r, err := doRequest(cmd, prefix, http.MethodGet)
+	if err != nil {
+		fmt.Printf("Failed to get operators: %s\n", err)
+		return
+	}
+	printResponse(cmd, r)
+}
+
+func transferLeaderCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	ids, err := parseUint64s(args)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	input := make(map[string]interface{})
+	input["name"] = cmd.Name()
+	input["region_id"] = ids[0]
+	input["to_store_id"] = ids[1]
+	postJSON(cmd, operatorsPrefix, input)
+}
+
+func transferPeerCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 3 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	ids, err := parseUint64s(args)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	input := make(map[string]interface{})
+	input["name"] = cmd.Name()
+	input["region_id"] = ids[0]
+	input["from_store_id"] = ids[1]
+	input["to_store_id"] = ids[2]
+	postJSON(cmd, operatorsPrefix, input)
+}
+
+func addPeerCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	ids, err := parseUint64s(args)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	input := make(map[string]interface{})
+	input["name"] = cmd.Name()
+	input["region_id"] = ids[0]
+	input["to_store_id"] = ids[1]
+	postJSON(cmd, operatorsPrefix, input)
+}
+
+func removePeerCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	ids, err := parseUint64s(args)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	input := make(map[string]interface{})
+	input["name"] = cmd.Name()
+	input["region_id"] = ids[0]
+	input["from_store_id"] = ids[1]
+	postJSON(cmd, operatorsPrefix, input)
+}
+
+func moveRegionCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) < 2 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	ids, err := parseUint64s(args)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	input := make(map[string]interface{})
+	input["name"] = cmd.Name()
+	input["region_id"] = ids[0]
+	input["store_ids"] = ids[1:]
+	postJSON(cmd, operatorsPrefix, input)
+}
+
+func splitRegionCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	ids, err := parseUint64s(args)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	input := make(map[string]interface{})
+	input["name"] = cmd.Name()
+	input["region_id"] = ids[0]
+	postJSON(cmd, operatorsPrefix, input)
+}
+
+func mergeRegionCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	ids, err := parseUint64s(args)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	input := make(map[string]interface{})
+	input["name"] = cmd.Name()
+	input["region_id"] = ids[0]
+	input["target_region_id"] = ids[1]
+	postJSON(cmd, operatorsPrefix, input)
+}
+
+func removeOperatorCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	prefix := fmt.Sprintf(operatorPrefix, args[0])
+	_, err := doRequest(cmd, prefix, http.MethodDelete)
+	if err != nil {
+		fmt.Printf("Failed to remove operator: %s\n", err)
+		return
+	}
+	printSuccess(cmd)
+}
+
+func parseUint64s(args []string) ([]uint64, error) {
+	ids := make([]uint64, 0, len(args))
+	for _, arg := range args {
+		id, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id %s: %s", arg, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
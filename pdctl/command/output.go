@@ -0,0 +1,185 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// Output formats of the --output flag, commands keep their own format if
+// the flag is not set.
+const (
+	outputJSON  = "json"
+	outputTable = "table"
+)
+
+func getOutputFormat(cmd *cobra.Command) string {
+	format, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return ""
+	}
+	return format
+}
+
+// printResponse prints the JSON response of the API in the output format.
+func printResponse(cmd *cobra.Command, r string) {
+	switch getOutputFormat(cmd) {
+	case outputJSON:
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, []byte(r)); err != nil {
+			fmt.Println(r)
+			return
+		}
+		fmt.Println(buf.String())
+	case outputTable:
+		// Use numbers to keep large ids, e.g. member ids, precise.
+		var v interface{}
+		d := json.NewDecoder(strings.NewReader(r))
+		d.UseNumber()
+		if err := d.Decode(&v); err != nil {
+			fmt.Println(r)
+			return
+		}
+		printTable(v)
+	default:
+		fmt.Println(r)
+	}
+}
+
+// printValue prints v in the output format, or calls text if the format is
+// not set.
+func printValue(cmd *cobra.Command, v interface{}, text func()) {
+	if getOutputFormat(cmd) == "" {
+		text()
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		fmt.Printf("Failed to marshal output: %s\n", err)
+		return
+	}
+	printResponse(cmd, string(data))
+}
+
+func printSuccess(cmd *cobra.Command) {
+	printValue(cmd, map[string]bool{"success": true}, func() {
+		fmt.Println("Success!")
+	})
+}
+
+// printTable prints a list of objects as rows, the list is either v itself
+// or the first list of objects in v, e.g. stores in the stores API. Other
+// values are printed as key value pairs.
+func printTable(v interface{}) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	if rows, ok := findRows(v); ok {
+		var columns []string
+		seen := make(map[string]bool)
+		flatRows := make([]map[string]string, 0, len(rows))
+		for _, row := range rows {
+			flat := make(map[string]string)
+			flatten("", row, flat)
+			for k := range flat {
+				if !seen[k] {
+					seen[k] = true
+					columns = append(columns, k)
+				}
+			}
+			flatRows = append(flatRows, flat)
+		}
+		sort.Strings(columns)
+
+		fmt.Fprintln(w, strings.ToUpper(strings.Join(columns, "\t")))
+		for _, flat := range flatRows {
+			values := make([]string, 0, len(columns))
+			for _, c := range columns {
+				values = append(values, flat[c])
+			}
+			fmt.Fprintln(w, strings.Join(values, "\t"))
+		}
+		return
+	}
+
+	flat := make(map[string]string)
+	flatten("", v, flat)
+	keys := make([]string, 0, len(flat))
+	for k := range flat {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s\t%s\n", k, flat[k])
+	}
+}
+
+func findRows(v interface{}) ([]interface{}, bool) {
+	if rows, ok := v.([]interface{}); ok {
+		return rows, isObjects(rows)
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if rows, ok := m[k].([]interface{}); ok && len(rows) > 0 && isObjects(rows) {
+			return rows, true
+		}
+	}
+	return nil, false
+}
+
+func isObjects(rows []interface{}) bool {
+	for _, row := range rows {
+		if _, ok := row.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// flatten flattens nested objects to keys joined by dots, lists are kept
+// in JSON.
+func flatten(prefix string, v interface{}, flat map[string]string) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, sub := range t {
+			if prefix != "" {
+				k = prefix + "." + k
+			}
+			flatten(k, sub, flat)
+		}
+	case []interface{}:
+		data, _ := json.Marshal(t)
+		flat[prefix] = string(data)
+	case nil:
+		flat[prefix] = ""
+	default:
+		flat[prefix] = fmt.Sprint(t)
+	}
+}
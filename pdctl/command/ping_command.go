@@ -0,0 +1,135 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/pkg/rpcutil"
+	"github.com/spf13/cobra"
+)
+
+const pingTimeout = 3 * time.Second
+
+type memberLatency struct {
+	Name        string `json:"name"`
+	ClientURL   string `json:"client_url"`
+	IsLeader    bool   `json:"is_leader"`
+	HTTPLatency string `json:"http_latency,omitempty"`
+	HTTPError   string `json:"http_error,omitempty"`
+	RPCLatency  string `json:"rpc_latency,omitempty"`
+	RPCError    string `json:"rpc_error,omitempty"`
+}
+
+// NewPingCommand return a ping subcommand of rootCmd
+func NewPingCommand() *cobra.Command {
+	p := &cobra.Command{
+		Use:   "ping",
+		Short: "show the http and rpc latency of each pd member",
+		Run:   pingCommandFunc,
+	}
+	return p
+}
+
+func pingCommandFunc(cmd *cobra.Command, args []string) {
+	var info struct {
+		Members []struct {
+			Name       string   `json:"name"`
+			ClientUrls []string `json:"client_urls"`
+			IsLeader   bool     `json:"is_leader"`
+		} `json:"members"`
+	}
+	if err := getCmdJSON(cmd, membersPrefix, &info); err != nil {
+		fmt.Printf("Failed to get pd members: %s\n", err)
+		return
+	}
+
+	var latencies []*memberLatency
+	for _, m := range info.Members {
+		if len(m.ClientUrls) == 0 {
+			continue
+		}
+		l := &memberLatency{
+			Name:      m.Name,
+			ClientURL: m.ClientUrls[0],
+			IsLeader:  m.IsLeader,
+		}
+		if d, err := pingHTTP(l.ClientURL); err != nil {
+			l.HTTPError = err.Error()
+		} else {
+			l.HTTPLatency = d.String()
+		}
+		if d, err := pingRPC(l.ClientURL); err != nil {
+			l.RPCError = err.Error()
+		} else {
+			l.RPCLatency = d.String()
+		}
+		latencies = append(latencies, l)
+	}
+
+	printValue(cmd, latencies, func() {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		defer w.Flush()
+		fmt.Fprintln(w, "NAME\tCLIENT_URL\tROLE\tHTTP\tRPC")
+		for _, l := range latencies {
+			role := "follower"
+			if l.IsLeader {
+				role = "leader"
+			}
+			httpLatency, rpcLatency := l.HTTPLatency, l.RPCLatency
+			if l.HTTPError != "" {
+				httpLatency = "error: " + l.HTTPError
+			}
+			if l.RPCError != "" {
+				rpcLatency = "error: " + l.RPCError
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", l.Name, l.ClientURL, role, httpLatency, rpcLatency)
+		}
+	})
+}
+
+func pingHTTP(addr string) (time.Duration, error) {
+	start := time.Now()
+	if err := pingMember(addr); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
+// pingRPC measures the round trip of GetPDMembers, it is served by the
+// member itself and doesn't need the cluster id.
+func pingRPC(addr string) (time.Duration, error) {
+	conn, err := rpcutil.ConnectUrls(addr, pingTimeout, tlsConfig)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	if err = conn.SetDeadline(time.Now().Add(pingTimeout)); err != nil {
+		return 0, err
+	}
+
+	req := &pdpb.Request{
+		CmdType:      pdpb.CommandType_GetPDMembers,
+		GetPdMembers: &pdpb.GetPDMembersRequest{},
+	}
+	start := time.Now()
+	if _, err = rpcutil.Call(conn, 0, req); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
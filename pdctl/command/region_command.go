@@ -15,6 +15,7 @@ package command
 
 import (
 	"bytes"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -26,8 +27,9 @@ import (
 )
 
 var (
-	regionsPrefix = "pd/api/v1/regions"
-	regionPrefix  = "pd/api/v1/region/%s"
+	regionsPrefix      = "pd/api/v1/regions"
+	regionPrefix       = "pd/api/v1/region/%s"
+	regionsCheckPrefix = "pd/api/v1/regions/check/%s"
 )
 
 type regionInfo struct {
@@ -43,6 +45,7 @@ func NewRegionCommand() *cobra.Command {
 		Run:   showRegionCommandFunc,
 	}
 	r.AddCommand(NewRegionWithKeyCommand())
+	r.AddCommand(NewRegionWithCheckCommand())
 	return r
 }
 
@@ -61,13 +64,13 @@ func showRegionCommandFunc(cmd *cobra.Command, args []string) {
 		fmt.Printf("Failed to get region: %s", err)
 		return
 	}
-	fmt.Println(r)
+	printResponse(cmd, r)
 }
 
 // NewRegionWithKeyCommand return a region with key subcommand of regionCmd
 func NewRegionWithKeyCommand() *cobra.Command {
 	r := &cobra.Command{
-		Use:   "key [--format=raw|pb|proto|protobuf] <key>",
+		Use:   "key [--format=raw|hex|pb|proto|protobuf] <key>",
 		Short: "show the region with key",
 		Run:   showRegionWithTableCommandFunc,
 	}
@@ -90,6 +93,12 @@ func showRegionWithTableCommandFunc(cmd *cobra.Command, args []string) {
 	switch format {
 	case "raw":
 		key = []byte(args[0])
+	case "hex":
+		key, err = hex.DecodeString(args[0])
+		if err != nil {
+			fmt.Println("Error: ", err)
+			return
+		}
 	case "pb", "proto", "protobuf":
 		key, err = decodeProtobufText(args[0])
 		if err != nil {
@@ -121,7 +130,37 @@ func showRegionWithTableCommandFunc(cmd *cobra.Command, args []string) {
 		fmt.Println("Error: ", err)
 		return
 	}
-	fmt.Println(string(infos))
+	printResponse(cmd, string(infos))
+}
+
+// NewRegionWithCheckCommand return a region with check subcommand of regionCmd
+func NewRegionWithCheckCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:   "check [miss-peer|down-peer|pending-peer|offline-peer]",
+		Short: "show the regions with abnormal peers",
+		Run:   showRegionWithCheckCommandFunc,
+	}
+	return r
+}
+
+func showRegionWithCheckCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	switch args[0] {
+	case "miss-peer", "down-peer", "pending-peer", "offline-peer":
+	default:
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	prefix := fmt.Sprintf(regionsCheckPrefix, args[0])
+	r, err := doRequest(cmd, prefix, http.MethodGet)
+	if err != nil {
+		fmt.Printf("Failed to get region: %s", err)
+		return
+	}
+	printResponse(cmd, r)
 }
 
 func decodeProtobufText(text string) ([]byte, error) {
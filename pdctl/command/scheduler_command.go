@@ -0,0 +1,269 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	schedulersPrefix = "pd/api/v1/schedulers"
+	schedulerPrefix  = "pd/api/v1/schedulers/%s"
+)
+
+// NewSchedulerCommand return a scheduler subcommand of rootCmd
+func NewSchedulerCommand() *cobra.Command {
+	s := &cobra.Command{
+		Use:   "scheduler <command>",
+		Short: "scheduler commands",
+	}
+	s.AddCommand(NewShowSchedulerCommand())
+	s.AddCommand(NewAddSchedulerCommand())
+	s.AddCommand(NewRemoveSchedulerCommand())
+	s.AddCommand(NewPauseSchedulerCommand())
+	s.AddCommand(NewResumeSchedulerCommand())
+	s.AddCommand(NewConfigSchedulerCommand())
+	s.AddCommand(NewDiagnosisSchedulerCommand())
+	return s
+}
+
+// NewShowSchedulerCommand return a show subcommand of schedulerCmd
+func NewShowSchedulerCommand() *cobra.Command {
+	s := &cobra.Command{
+		Use:   "show",
+		Short: "show the running schedulers",
+		Run:   showSchedulerCommandFunc,
+	}
+	return s
+}
+
+// NewAddSchedulerCommand return a add subcommand of schedulerCmd
+func NewAddSchedulerCommand() *cobra.Command {
+	a := &cobra.Command{
+		Use:   "add <scheduler>",
+		Short: "add a scheduler",
+	}
+	a.AddCommand(NewBalanceLeaderSchedulerCommand())
+	a.AddCommand(NewGrantLeaderSchedulerCommand())
+	a.AddCommand(NewEvictLeaderSchedulerCommand())
+	a.AddCommand(NewShuffleLeaderSchedulerCommand())
+	return a
+}
+
+// NewBalanceLeaderSchedulerCommand return a balance-leader-scheduler subcommand of addCmd
+func NewBalanceLeaderSchedulerCommand() *cobra.Command {
+	b := &cobra.Command{
+		Use:   "balance-leader-scheduler",
+		Short: "add a scheduler to balance leaders between stores",
+		Run:   addSchedulerCommandFunc,
+	}
+	return b
+}
+
+// NewGrantLeaderSchedulerCommand return a grant-leader-scheduler subcommand of addCmd
+func NewGrantLeaderSchedulerCommand() *cobra.Command {
+	g := &cobra.Command{
+		Use:   "grant-leader-scheduler <store_id>",
+		Short: "add a scheduler to transfer all leaders to the store",
+		Run:   addSchedulerForStoreCommandFunc,
+	}
+	return g
+}
+
+// NewEvictLeaderSchedulerCommand return a evict-leader-scheduler subcommand of addCmd
+func NewEvictLeaderSchedulerCommand() *cobra.Command {
+	e := &cobra.Command{
+		Use:   "evict-leader-scheduler <store_id>",
+		Short: "add a scheduler to transfer all leaders out of the store",
+		Run:   addSchedulerForStoreCommandFunc,
+	}
+	return e
+}
+
+// NewShuffleLeaderSchedulerCommand return a shuffle-leader-scheduler subcommand of addCmd
+func NewShuffleLeaderSchedulerCommand() *cobra.Command {
+	s := &cobra.Command{
+		Use:   "shuffle-leader-scheduler",
+		Short: "add a scheduler to shuffle leaders between stores",
+		Run:   addSchedulerCommandFunc,
+	}
+	return s
+}
+
+// NewRemoveSchedulerCommand return a remove subcommand of schedulerCmd
+func NewRemoveSchedulerCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:   "remove <scheduler>",
+		Short: "remove the scheduler",
+		Run:   removeSchedulerCommandFunc,
+	}
+	return r
+}
+
+// NewPauseSchedulerCommand return a pause subcommand of schedulerCmd
+func NewPauseSchedulerCommand() *cobra.Command {
+	p := &cobra.Command{
+		Use:   "pause <scheduler>",
+		Short: "pause the scheduler",
+		Run:   pauseSchedulerCommandFunc,
+	}
+	return p
+}
+
+// NewResumeSchedulerCommand return a resume subcommand of schedulerCmd
+func NewResumeSchedulerCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:   "resume <scheduler>",
+		Short: "resume the paused scheduler",
+		Run:   resumeSchedulerCommandFunc,
+	}
+	return r
+}
+
+// NewConfigSchedulerCommand return a config subcommand of schedulerCmd
+func NewConfigSchedulerCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "config <scheduler>",
+		Short: "show the config of the scheduler",
+		Run:   showSchedulerConfigCommandFunc,
+	}
+	return c
+}
+
+// NewDiagnosisSchedulerCommand return a diagnosis subcommand of schedulerCmd
+func NewDiagnosisSchedulerCommand() *cobra.Command {
+	d := &cobra.Command{
+		Use:   "diagnosis <scheduler>",
+		Short: "show the counts of the schedule results of the scheduler",
+		Run:   showSchedulerDiagnosisCommandFunc,
+	}
+	return d
+}
+
+func showSchedulerCommandFunc(cmd *cobra.Command, args []string) {
+	r, err := doRequest(cmd, schedulersPrefix, http.MethodGet)
+	if err != nil {
+		fmt.Printf("Failed to get schedulers: %s\n", err)
+		return
+	}
+	printResponse(cmd, r)
+}
+
+func addSchedulerCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	input := make(map[string]interface{})
+	input["name"] = cmd.Name()
+	postJSON(cmd, schedulersPrefix, input)
+}
+
+func addSchedulerForStoreCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	storeID, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	input := make(map[string]interface{})
+	input["name"] = cmd.Name()
+	input["store_id"] = storeID
+	postJSON(cmd, schedulersPrefix, input)
+}
+
+func removeSchedulerCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	prefix := fmt.Sprintf(schedulerPrefix, args[0])
+	_, err := doRequest(cmd, prefix, http.MethodDelete)
+	if err != nil {
+		fmt.Printf("Failed to remove scheduler %s: %s\n", args[0], err)
+		return
+	}
+	printSuccess(cmd)
+}
+
+func pauseSchedulerCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	prefix := fmt.Sprintf(schedulerPrefix, args[0]) + "/pause"
+	_, err := doRequest(cmd, prefix, http.MethodPost)
+	if err != nil {
+		fmt.Printf("Failed to pause scheduler %s: %s\n", args[0], err)
+		return
+	}
+	printSuccess(cmd)
+}
+
+func resumeSchedulerCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	prefix := fmt.Sprintf(schedulerPrefix, args[0]) + "/resume"
+	_, err := doRequest(cmd, prefix, http.MethodPost)
+	if err != nil {
+		fmt.Printf("Failed to resume scheduler %s: %s\n", args[0], err)
+		return
+	}
+	printSuccess(cmd)
+}
+
+func showSchedulerConfigCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	prefix := fmt.Sprintf(schedulerPrefix, args[0])
+	r, err := doRequest(cmd, prefix, http.MethodGet)
+	if err != nil {
+		fmt.Printf("Failed to get config of scheduler %s: %s\n", args[0], err)
+		return
+	}
+	printResponse(cmd, r)
+}
+
+func showSchedulerDiagnosisCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	prefix := fmt.Sprintf(schedulerPrefix, args[0]) + "/diagnosis"
+	r, err := doRequest(cmd, prefix, http.MethodGet)
+	if err != nil {
+		fmt.Printf("Failed to get diagnosis of scheduler %s: %s\n", args[0], err)
+		return
+	}
+	printResponse(cmd, r)
+}
@@ -0,0 +1,84 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/spf13/cobra"
+)
+
+const securityReloadPrefix = "pd/api/v1/security/reload"
+
+// NewSecurityCommand return a security subcommand of rootCmd
+func NewSecurityCommand() *cobra.Command {
+	s := &cobra.Command{
+		Use:   "security <subcommand>",
+		Short: "manage the TLS of pd members",
+	}
+	s.AddCommand(NewReloadSecurityCommand())
+	return s
+}
+
+// NewReloadSecurityCommand return a reload subcommand of securityCmd
+func NewReloadSecurityCommand() *cobra.Command {
+	r := &cobra.Command{
+		Use:   "reload",
+		Short: "reload the cert files of each pd member",
+		Run:   reloadSecurityCommandFunc,
+	}
+	return r
+}
+
+func reloadSecurityCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	var info struct {
+		Members []struct {
+			Name       string   `json:"name"`
+			ClientUrls []string `json:"client_urls"`
+		} `json:"members"`
+	}
+	if err := getCmdJSON(cmd, membersPrefix, &info); err != nil {
+		fmt.Printf("Failed to get pd members: %s\n", err)
+		return
+	}
+	// The request is served by each member itself, it is not redirected to
+	// the leader.
+	for _, m := range info.Members {
+		if len(m.ClientUrls) == 0 {
+			continue
+		}
+		if err := reloadMemberSecurity(m.ClientUrls[0]); err != nil {
+			fmt.Printf("Failed to reload cert files of %s: %s\n", m.Name, err)
+			continue
+		}
+		fmt.Printf("%s: Success!\n", m.Name)
+	}
+}
+
+func reloadMemberSecurity(addr string) error {
+	resp, err := dailClient.Post(fmt.Sprintf("%s/%s", addr, securityReloadPrefix), "application/json", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return genResponseError(resp)
+	}
+	return nil
+}
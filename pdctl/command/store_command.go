@@ -16,7 +16,9 @@ package command
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -29,11 +31,14 @@ var (
 // NewStoreCommand return a store subcommand of rootCmd
 func NewStoreCommand() *cobra.Command {
 	s := &cobra.Command{
-		Use:   "store [delete] <store_id>",
+		Use:   "store [delete|label] <store_id> [--state=up,offline] [--label=zone=z1]",
 		Short: "show the store status",
 		Run:   showStoreCommandFunc,
 	}
+	s.Flags().StringSlice("state", nil, "only show the stores in the states, up and offline by default")
+	s.Flags().StringSlice("label", nil, "only show the stores which have all the labels, e.g. zone=z1")
 	s.AddCommand(NewDeleteStoreCommand())
+	s.AddCommand(NewLabelStoreCommand())
 	return s
 }
 
@@ -47,6 +52,16 @@ func NewDeleteStoreCommand() *cobra.Command {
 	return d
 }
 
+// NewLabelStoreCommand return a label subcommand of storeCmd
+func NewLabelStoreCommand() *cobra.Command {
+	l := &cobra.Command{
+		Use:   "label <store_id> <key=value>...",
+		Short: "set the labels of the store",
+		Run:   labelStoreCommandFunc,
+	}
+	return l
+}
+
 func showStoreCommandFunc(cmd *cobra.Command, args []string) {
 	var prefix string
 	prefix = storesPrefix
@@ -56,13 +71,46 @@ func showStoreCommandFunc(cmd *cobra.Command, args []string) {
 			return
 		}
 		prefix = fmt.Sprintf(storePrefix, args[0])
+	} else {
+		query, err := getStoreFilterQuery(cmd)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if len(query) != 0 {
+			prefix = fmt.Sprintf("%s?%s", prefix, query)
+		}
 	}
 	r, err := doRequest(cmd, prefix, http.MethodGet)
 	if err != nil {
 		fmt.Printf("Failed to get store: %s", err)
 		return
 	}
-	fmt.Println(r)
+	printResponse(cmd, r)
+}
+
+// getStoreFilterQuery converts the state and label flags to the url query
+// of the stores API.
+func getStoreFilterQuery(cmd *cobra.Command) (string, error) {
+	states, err := cmd.Flags().GetStringSlice("state")
+	if err != nil {
+		return "", err
+	}
+	labels, err := cmd.Flags().GetStringSlice("label")
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{}
+	for _, state := range states {
+		query.Add("state", state)
+	}
+	for _, label := range labels {
+		if !strings.Contains(label, "=") {
+			return "", fmt.Errorf("label should be like key=value, but got %s", label)
+		}
+		query.Add("label", label)
+	}
+	return query.Encode(), nil
 }
 
 func deleteStoreCommandFunc(cmd *cobra.Command, args []string) {
@@ -80,5 +128,27 @@ func deleteStoreCommandFunc(cmd *cobra.Command, args []string) {
 		fmt.Printf("Failed to delete store %s: %s", args[0], err)
 		return
 	}
-	fmt.Println("Success!")
+	printSuccess(cmd)
+}
+
+func labelStoreCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) < 2 {
+		fmt.Println("Usage: store label <store_id> <key=value>...")
+		return
+	}
+	if _, err := strconv.Atoi(args[0]); err != nil {
+		fmt.Println("store_id should be a number")
+		return
+	}
+	input := make(map[string]interface{})
+	for _, label := range args[1:] {
+		kv := strings.SplitN(label, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			fmt.Printf("label should be like key=value, but got %s\n", label)
+			return
+		}
+		input[kv[0]] = kv[1]
+	}
+	prefix := fmt.Sprintf(storePrefix, args[0]) + "/label"
+	postJSON(cmd, prefix, input)
 }
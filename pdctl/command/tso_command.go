@@ -0,0 +1,60 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// logicalBits must be the same as the one in server/tso.go.
+const logicalBits = 18
+
+// NewTSOCommand return a tso subcommand of rootCmd
+func NewTSOCommand() *cobra.Command {
+	t := &cobra.Command{
+		Use:   "tso <timestamp>",
+		Short: "parse the physical and logical time of TSO",
+		Run:   showTSOCommandFunc,
+	}
+	return t
+}
+
+func showTSOCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	ts, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		fmt.Printf("Invalid timestamp %s: %s\n", args[0], err)
+		return
+	}
+
+	logical := ts & (1<<logicalBits - 1)
+	physical := int64(ts >> logicalBits)
+	physicalTime := time.Unix(physical/1000, physical%1000*int64(time.Millisecond))
+	v := map[string]interface{}{
+		"system": physicalTime,
+		"logic":  logical,
+	}
+	printValue(cmd, v, func() {
+		fmt.Printf("system:  %v\n", physicalTime)
+		fmt.Printf("logic:   %v\n", logical)
+	})
+}
@@ -0,0 +1,124 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	usersPrefix    = "pd/api/v1/users"
+	userPrefix     = "pd/api/v1/users/%s"
+	userRolePrefix = "pd/api/v1/users/%s/role"
+)
+
+// NewUserCommand return a user subcommand of rootCmd
+func NewUserCommand() *cobra.Command {
+	u := &cobra.Command{
+		Use:   "user [create|delete|set-role]",
+		Short: "show or change the api users",
+		Run:   showUsersCommandFunc,
+	}
+	u.AddCommand(&cobra.Command{
+		Use:   "create <name> <viewer|operator|admin>",
+		Short: "create a user and print its token",
+		Run:   createUserCommandFunc,
+	})
+	u.AddCommand(&cobra.Command{
+		Use:   "delete <name>",
+		Short: "delete the user",
+		Run:   deleteUserCommandFunc,
+	})
+	u.AddCommand(&cobra.Command{
+		Use:   "set-role <name> <viewer|operator|admin>",
+		Short: "change the role of the user",
+		Run:   setUserRoleCommandFunc,
+	})
+	return u
+}
+
+func showUsersCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	r, err := doRequest(cmd, usersPrefix, http.MethodGet)
+	if err != nil {
+		fmt.Printf("Failed to get users: %s\n", err)
+		return
+	}
+	printResponse(cmd, r)
+}
+
+// createUserCommandFunc prints the response, the token is only shown once.
+func createUserCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"name": args[0],
+		"role": args[1],
+	})
+	if err != nil {
+		fmt.Printf("Failed to marshal request: %s\n", err)
+		return
+	}
+	resp, err := dailClient.Post(getAddressFromCmd(cmd, usersPrefix), "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		fmt.Printf("Failed to create user: %s\n", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		printResponseError(resp)
+		return
+	}
+	r, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		fmt.Printf("Failed to create user: %s\n", err)
+		return
+	}
+	printResponse(cmd, string(r))
+}
+
+func deleteUserCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	_, err := doRequest(cmd, fmt.Sprintf(userPrefix, args[0]), http.MethodDelete)
+	if err != nil {
+		fmt.Printf("Failed to delete user: %s\n", err)
+		return
+	}
+	printSuccess(cmd)
+}
+
+func setUserRoleCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+	input := map[string]interface{}{
+		"role": args[1],
+	}
+	postJSON(cmd, fmt.Sprintf(userRolePrefix, args[0]), input)
+}
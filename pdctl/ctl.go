@@ -17,13 +17,19 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/chzyer/readline"
 	"github.com/pingcap/pd/pdctl/command"
 	"github.com/spf13/cobra"
 )
 
 // CommandFlags are flags that used in all Commands
 type CommandFlags struct {
-	URL string
+	URL      string
+	Output   string
+	CAPath   string
+	CertPath string
+	KeyPath  string
+	Token    string
 }
 
 var (
@@ -32,10 +38,21 @@ var (
 		Short: "Placement Driver control",
 	}
 	commandFlags = CommandFlags{}
+
+	// offlineCommands don't need to connect to pd.
+	offlineCommands = map[string]bool{
+		"pdctl completion": true,
+		"pdctl tso":        true,
+	}
 )
 
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&commandFlags.URL, "pd", "u", "http://127.0.0.1:2379", "pd address")
+	rootCmd.PersistentFlags().StringVarP(&commandFlags.Output, "output", "o", "", "output format, json or table")
+	rootCmd.PersistentFlags().StringVar(&commandFlags.CAPath, "cacert", "", "path of file that contains list of trusted TLS CAs")
+	rootCmd.PersistentFlags().StringVar(&commandFlags.CertPath, "cert", "", "path of file that contains X509 certificate in PEM format")
+	rootCmd.PersistentFlags().StringVar(&commandFlags.KeyPath, "key", "", "path of file that contains X509 key in PEM format")
+	rootCmd.PersistentFlags().StringVar(&commandFlags.Token, "token", "", "token to access the pd apis which require authentication")
 	rootCmd.AddCommand(
 		command.NewConfigCommand(),
 		command.NewRegionCommand(),
@@ -43,6 +60,20 @@ func init() {
 		command.NewMemberCommand(),
 		command.NewExitCommand(),
 		command.NewLabelCommand(),
+		command.NewOperatorCommand(),
+		command.NewSchedulerCommand(),
+		command.NewTSOCommand(),
+		command.NewHealthCommand(),
+		command.NewFeatureGateCommand(),
+		command.NewGCCommand(),
+		command.NewNamespaceCommand(),
+		command.NewKeyspaceCommand(),
+		command.NewEncryptionCommand(),
+		command.NewUserCommand(),
+		command.NewBackupCommand(),
+		command.NewPingCommand(),
+		command.NewSecurityCommand(),
+		command.NewCompletionCommand(),
 	)
 	cobra.EnablePrefixMatching = true
 }
@@ -52,13 +83,45 @@ func Start(args []string) {
 	rootCmd.SetArgs(args)
 	rootCmd.SilenceErrors = true
 	rootCmd.ParseFlags(args)
-	err := command.InitPDClient(rootCmd)
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	switch commandFlags.Output {
+	case "", "json", "table":
+	default:
+		fmt.Printf("Unknown output format %s, should be json or table\n", commandFlags.Output)
+		return
+	}
+	if cmd, _, err := rootCmd.Find(args); err != nil || !offlineCommands[cmd.CommandPath()] {
+		if err = command.InitPDClient(rootCmd); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 	}
 	rootCmd.SetUsageTemplate(command.UsageTemplate)
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(rootCmd.UsageString())
 	}
 }
+
+// GetCompleter returns the completer of all commands for interactive mode,
+// store and region ids are completed by querying the pd at addr.
+func GetCompleter(addr string) *readline.PrefixCompleter {
+	return readline.NewPrefixCompleter(genCompleter(rootCmd, addr)...)
+}
+
+func genCompleter(cmd *cobra.Command, addr string) []readline.PrefixCompleterInterface {
+	pc := []readline.PrefixCompleterInterface{}
+	for _, c := range cmd.Commands() {
+		children := genCompleter(c, addr)
+		switch c.CommandPath() {
+		case "pdctl store", "pdctl store delete":
+			children = append(children, readline.PcItemDynamic(func(string) []string {
+				return command.ListStoreIDs(addr)
+			}))
+		case "pdctl region":
+			children = append(children, readline.PcItemDynamic(func(string) []string {
+				return command.ListRegionIDs(addr)
+			}))
+		}
+		pc = append(pc, readline.PcItem(c.Name(), children...))
+	}
+	return pc
+}
@@ -14,6 +14,7 @@
 package apiutil
 
 import (
+	"crypto/tls"
 	"encoding/json"
 	"io"
 	"io/ioutil"
@@ -41,9 +42,10 @@ func ReadJSON(r io.ReadCloser, data interface{}) error {
 	return nil
 }
 
-// NewHTTPClient returns a HTTP client according to the scheme.
-func NewHTTPClient(scheme string, timeout time.Duration) *http.Client {
-	tr := NewHTTPTransport(scheme)
+// NewHTTPClient returns a HTTP client according to the scheme, tlsCfg is used
+// by the secure schemes.
+func NewHTTPClient(scheme string, timeout time.Duration, tlsCfg *tls.Config) *http.Client {
+	tr := NewHTTPTransport(scheme, tlsCfg)
 	return &http.Client{
 		Timeout:   timeout,
 		Transport: tr,
@@ -51,14 +53,26 @@ func NewHTTPClient(scheme string, timeout time.Duration) *http.Client {
 }
 
 // NewHTTPTransport returns a proper http.RoundTripper.
-func NewHTTPTransport(scheme string) *http.Transport {
-	tr := &http.Transport{}
+func NewHTTPTransport(scheme string, tlsCfg *tls.Config) *http.Transport {
+	tr := &http.Transport{TLSClientConfig: tlsCfg}
 	if scheme == "unix" || scheme == "unixs" {
 		tr.Dial = unixDial
 	}
 	return tr
 }
 
+// ToHTTPScheme returns the scheme of the requests to the url in the scheme,
+// the unix schemes are only used in tests and dialed by unix socket.
+func ToHTTPScheme(scheme string) string {
+	switch scheme {
+	case "unix":
+		return "http"
+	case "unixs":
+		return "https"
+	}
+	return scheme
+}
+
 func unixDial(_, addr string) (net.Conn, error) {
 	return net.Dial("unix", addr)
 }
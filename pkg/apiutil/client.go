@@ -14,11 +14,14 @@
 package apiutil
 
 import (
+	"crypto/tls"
+	"fmt"
 	"net/http"
 	"net/url"
 	"time"
 
 	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
 )
 
@@ -32,8 +35,9 @@ type Client struct {
 	url string
 }
 
-// NewClient returns a client to access PD APIs.
-func NewClient(addr string, timeout time.Duration) (*Client, error) {
+// NewClient returns a client to access PD APIs, tlsCfg is used if the addr
+// is in a secure scheme.
+func NewClient(addr string, timeout time.Duration, tlsCfg *tls.Config) (*Client, error) {
 	u, err := url.Parse(addr)
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -41,12 +45,10 @@ func NewClient(addr string, timeout time.Duration) (*Client, error) {
 	u.Path = apiPrefix
 
 	scheme := u.Scheme
-	if u.Scheme == "unix" || u.Scheme == "unixs" {
-		u.Scheme = "http"
-	}
+	u.Scheme = ToHTTPScheme(u.Scheme)
 
 	client := &Client{
-		hc:  NewHTTPClient(scheme, timeout),
+		hc:  NewHTTPClient(scheme, timeout, tlsCfg),
 		url: u.String(),
 	}
 	return client, nil
@@ -54,17 +56,75 @@ func NewClient(addr string, timeout time.Duration) (*Client, error) {
 
 // GetLeader returns the PD leader info.
 func (c *Client) GetLeader() (*pdpb.Leader, error) {
-	leaderURL := c.url + "/leader"
-	resp, err := c.hc.Get(leaderURL)
-	if err != nil {
+	leader := &pdpb.Leader{}
+	if err := c.get("/leader", leader); err != nil {
 		return nil, errors.Trace(err)
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.Errorf("GET %s: %s", leaderURL, resp.Status)
+	return leader, nil
+}
+
+// GetPrevRegion returns the region before the region of the key and its
+// leader.
+func (c *Client) GetPrevRegion(key []byte) (*metapb.Region, *metapb.Peer, error) {
+	var info struct {
+		Region *metapb.Region `json:"region"`
+		Leader *metapb.Peer   `json:"leader"`
 	}
-	leader := &pdpb.Leader{}
-	if err := ReadJSON(resp.Body, leader); err != nil {
+	if err := c.get("/regions/prev?key="+url.QueryEscape(string(key)), &info); err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	return info.Region, info.Leader, nil
+}
+
+// ScanRegions returns at most limit regions in key order from the region of
+// the key.
+func (c *Client) ScanRegions(key []byte, limit int) ([]*metapb.Region, error) {
+	var info struct {
+		Regions []*metapb.Region `json:"regions"`
+	}
+	if err := c.get(fmt.Sprintf("/regions/scan?key=%s&limit=%d", url.QueryEscape(string(key)), limit), &info); err != nil {
 		return nil, errors.Trace(err)
 	}
-	return leader, nil
+	return info.Regions, nil
+}
+
+// LeaderWatchResult is the leader and the members returned by WatchLeader.
+type LeaderWatchResult struct {
+	Revision int64            `json:"revision"`
+	Leader   *pdpb.Leader     `json:"leader"`
+	Members  []*pdpb.PDMember `json:"members"`
+}
+
+// WatchLeader waits for the leader to change after the revision within the
+// timeout, the request is canceled if cancel is closed. The timeout of the
+// client should be longer.
+func (c *Client) WatchLeader(revision int64, timeout time.Duration, cancel <-chan struct{}) (*LeaderWatchResult, error) {
+	result := &LeaderWatchResult{}
+	path := fmt.Sprintf("/leader/watch?revision=%d&timeout=%s", revision, timeout)
+	if err := c.getWithCancel(path, result, cancel); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return result, nil
+}
+
+func (c *Client) get(path string, v interface{}) error {
+	return errors.Trace(c.getWithCancel(path, v, nil))
+}
+
+func (c *Client) getWithCancel(path string, v interface{}, cancel <-chan struct{}) error {
+	u := c.url + path
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	req.Cancel = cancel
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return errors.Errorf("GET %s: %s", u, resp.Status)
+	}
+	return errors.Trace(ReadJSON(resp.Body, v))
 }
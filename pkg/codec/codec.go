@@ -0,0 +1,118 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"encoding/binary"
+
+	"github.com/juju/errors"
+)
+
+const (
+	encGroupSize = 8
+	encMarker    = byte(0xFF)
+	encPad       = byte(0x0)
+	signMask     = uint64(0x8000000000000000)
+)
+
+var tablePrefix = []byte{'t'}
+
+// EncodeBytes encodes the key in the memory-comparable format used by TiKV
+// for the region keys. The key is split into groups of 8 bytes, and every
+// group is padded with 0 and followed by a marker of 0xFF minus the padding
+// size.
+func EncodeBytes(data []byte) []byte {
+	dLen := len(data)
+	result := make([]byte, 0, (dLen/encGroupSize+1)*(encGroupSize+1))
+	for idx := 0; idx <= dLen; idx += encGroupSize {
+		remain := dLen - idx
+		padCount := 0
+		if remain >= encGroupSize {
+			result = append(result, data[idx:idx+encGroupSize]...)
+		} else {
+			padCount = encGroupSize - remain
+			result = append(result, data[idx:]...)
+			for i := 0; i < padCount; i++ {
+				result = append(result, encPad)
+			}
+		}
+		result = append(result, encMarker-byte(padCount))
+	}
+	return result
+}
+
+// DecodeBytes decodes the key encoded by EncodeBytes, and returns the
+// remaining bytes after it.
+func DecodeBytes(b []byte) ([]byte, []byte, error) {
+	var data []byte
+	for {
+		if len(b) < encGroupSize+1 {
+			return nil, nil, errors.New("insufficient bytes to decode")
+		}
+		group := b[:encGroupSize]
+		marker := b[encGroupSize]
+		b = b[encGroupSize+1:]
+
+		padCount := encMarker - marker
+		if padCount > encGroupSize {
+			return nil, nil, errors.Errorf("invalid marker byte %x", marker)
+		}
+		realGroupSize := encGroupSize - int(padCount)
+		data = append(data, group[:realGroupSize]...)
+		if padCount != 0 {
+			for _, v := range group[realGroupSize:] {
+				if v != encPad {
+					return nil, nil, errors.Errorf("invalid padding byte %x", v)
+				}
+			}
+			return data, b, nil
+		}
+	}
+}
+
+// EncodeInt encodes the int64 in the memory-comparable format.
+func EncodeInt(v int64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(v)^signMask)
+	return b[:]
+}
+
+// DecodeInt decodes the int64 encoded by EncodeInt.
+func DecodeInt(b []byte) (int64, error) {
+	if len(b) < 8 {
+		return 0, errors.New("insufficient bytes to decode")
+	}
+	return int64(binary.BigEndian.Uint64(b) ^ signMask), nil
+}
+
+// GenerateTableKey returns the encoded key of the first key of the table,
+// which is the table prefix and the table ID in TiDB.
+func GenerateTableKey(tableID int64) []byte {
+	key := append([]byte(nil), tablePrefix...)
+	return EncodeBytes(append(key, EncodeInt(tableID)...))
+}
+
+// DecodeTableID returns the table ID of the encoded key, or 0 if the key does
+// not belong to any table.
+func DecodeTableID(key []byte) int64 {
+	data, _, err := DecodeBytes(key)
+	if err != nil || len(data) < len(tablePrefix)+8 || data[0] != tablePrefix[0] {
+		return 0
+	}
+	tableID, err := DecodeInt(data[len(tablePrefix):])
+	if err != nil {
+		return 0
+	}
+	return tableID
+}
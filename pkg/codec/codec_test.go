@@ -0,0 +1,68 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/pingcap/check"
+)
+
+func TestCodec(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testCodecSuite{})
+
+type testCodecSuite struct{}
+
+func (s *testCodecSuite) TestBytes(c *C) {
+	inputs := [][]byte{
+		{},
+		{1, 2, 3},
+		{1, 2, 3, 4, 5, 6, 7, 8},
+		{1, 2, 3, 4, 5, 6, 7, 8, 9},
+	}
+	for _, input := range inputs {
+		encoded := EncodeBytes(input)
+		c.Assert(len(encoded)%(encGroupSize+1), Equals, 0)
+		data, rest, err := DecodeBytes(append(encoded, 0xF))
+		c.Assert(err, IsNil)
+		c.Assert(bytes.Equal(data, input), IsTrue)
+		c.Assert(rest, DeepEquals, []byte{0xF})
+	}
+
+	// The order is kept.
+	c.Assert(bytes.Compare(EncodeBytes([]byte{1, 2}), EncodeBytes([]byte{1, 2, 0})), Equals, -1)
+
+	_, _, err := DecodeBytes([]byte{1, 2, 3})
+	c.Assert(err, NotNil)
+	_, _, err = DecodeBytes([]byte{1, 2, 3, 0, 0, 0, 0, 1, 0xFA})
+	c.Assert(err, NotNil)
+}
+
+func (s *testCodecSuite) TestTableID(c *C) {
+	for _, tableID := range []int64{1, 42, 1 << 40} {
+		c.Assert(DecodeTableID(GenerateTableKey(tableID)), Equals, tableID)
+		key := append([]byte("t"), EncodeInt(tableID)...)
+		key = append(key, []byte("_r12345678")...)
+		c.Assert(DecodeTableID(EncodeBytes(key)), Equals, tableID)
+	}
+	c.Assert(bytes.Compare(GenerateTableKey(1), GenerateTableKey(2)), Equals, -1)
+
+	c.Assert(DecodeTableID(nil), Equals, int64(0))
+	c.Assert(DecodeTableID(EncodeBytes([]byte("m_meta"))), Equals, int64(0))
+	c.Assert(DecodeTableID([]byte("t")), Equals, int64(0))
+}
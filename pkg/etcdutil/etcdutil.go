@@ -14,8 +14,8 @@
 package etcdutil
 
 import (
+	"crypto/tls"
 	"net/url"
-	"strings"
 	"time"
 
 	"github.com/coreos/etcd/clientv3"
@@ -43,12 +43,10 @@ const (
 	checkEtcdRunningDelay    = 1 * time.Second
 )
 
-// unixToHTTP replace unix scheme with http.
-var unixToHTTP = strings.NewReplacer("unix://", "http://", "unixs://", "http://")
-
 // CheckClusterID checks Etcd's cluster ID, returns an error if mismatch.
-// This function will never block even quorum is not satisfied.
-func CheckClusterID(localClusterID types.ID, um types.URLsMap) error {
+// This function will never block even quorum is not satisfied. tlsCfg is
+// used to connect the peers in secure schemes.
+func CheckClusterID(localClusterID types.ID, um types.URLsMap, tlsCfg *tls.Config) error {
 	if len(um) == 0 {
 		return nil
 	}
@@ -63,13 +61,12 @@ func CheckClusterID(localClusterID types.ID, um types.URLsMap) error {
 		if gerr != nil {
 			return errors.Trace(gerr)
 		}
-		trp := apiutil.NewHTTPTransport(u.Scheme)
+		trp := apiutil.NewHTTPTransport(u.Scheme, tlsCfg)
 
-		// For tests, change scheme to http.
+		// For tests, change scheme to http or https.
 		// etcdserver/api/v3rpc does not recognize unix protocol.
-		if u.Scheme == "unix" || u.Scheme == "unixs" {
-			peerURLs[i] = unixToHTTP.Replace(peerURLs[i])
-		}
+		u.Scheme = apiutil.ToHTTPScheme(u.Scheme)
+		peerURLs[i] = u.String()
 
 		remoteCluster, gerr := etcdserver.GetClusterFromRemotePeers([]string{peerURLs[i]}, trp)
 		trp.CloseIdleConnections()
@@ -0,0 +1,189 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package failpoint injects the failures at the named points of the code,
+// so the tests and the chaos tools can trigger the failures precisely. The
+// failpoints are enabled by the terms:
+//
+//	return(value)    Eval returns the value, the code fails there.
+//	sleep(duration)  Eval sleeps for the duration, e.g. sleep(100ms).
+//	panic(message)   Eval panics with the message.
+//	off              Eval does nothing.
+//
+// A term prefixed by "N*" takes effect N times only, e.g. 1*return(err).
+// Eval costs an atomic load only if no failpoint is enabled.
+package failpoint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+var termRegexp = regexp.MustCompile(`^(?:(\d+)\*)?(return|sleep|panic|off)(?:\((.*)\))?$`)
+
+type failpoint struct {
+	term   string
+	action string
+	arg    string
+	sleep  time.Duration
+	// count is the times left to take effect, -1 means unlimited.
+	count int
+}
+
+var (
+	enabled    int32
+	mu         sync.Mutex
+	failpoints = make(map[string]*failpoint)
+)
+
+func parse(term string) (*failpoint, error) {
+	m := termRegexp.FindStringSubmatch(term)
+	if m == nil {
+		return nil, errors.Errorf("invalid failpoint term %q", term)
+	}
+	fp := &failpoint{term: term, action: m[2], arg: m[3], count: -1}
+	if m[1] != "" {
+		count, err := strconv.Atoi(m[1])
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		fp.count = count
+	}
+	if fp.action == "sleep" {
+		d, err := time.ParseDuration(fp.arg)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		fp.sleep = d
+	}
+	return fp, nil
+}
+
+// Enable enables the failpoint by the term, the last term is replaced.
+func Enable(name, term string) error {
+	fp, err := parse(term)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	failpoints[name] = fp
+	atomic.StoreInt32(&enabled, int32(len(failpoints)))
+	return nil
+}
+
+// EnableAll enables the failpoints in the form of "name=term;name=term",
+// e.g. the value of an environment variable.
+func EnableAll(s string) error {
+	for _, item := range strings.Split(s, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			return errors.Errorf("invalid failpoint %q", item)
+		}
+		if err := Enable(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// Disable disables the failpoint, it returns false if the failpoint is not
+// enabled.
+func Disable(name string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	_, ok := failpoints[name]
+	delete(failpoints, name)
+	atomic.StoreInt32(&enabled, int32(len(failpoints)))
+	return ok
+}
+
+// Failpoint is an enabled failpoint.
+type Failpoint struct {
+	Name string `json:"name"`
+	Term string `json:"term"`
+	// Count is the times left to take effect, -1 means unlimited.
+	Count int `json:"count"`
+}
+
+// All returns the enabled failpoints ordered by name.
+func All() []*Failpoint {
+	mu.Lock()
+	defer mu.Unlock()
+
+	list := make([]*Failpoint, 0, len(failpoints))
+	for name, fp := range failpoints {
+		list = append(list, &Failpoint{Name: name, Term: fp.term, Count: fp.count})
+	}
+	sort.Sort(byName(list))
+	return list
+}
+
+type byName []*Failpoint
+
+func (s byName) Len() int           { return len(s) }
+func (s byName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+func (s byName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// Eval evaluates the failpoint, it returns the value and true if the term is
+// return, the caller should fail then.
+func Eval(name string) (string, bool) {
+	if atomic.LoadInt32(&enabled) == 0 {
+		return "", false
+	}
+
+	mu.Lock()
+	fp, ok := failpoints[name]
+	if !ok || fp.count == 0 {
+		mu.Unlock()
+		return "", false
+	}
+	if fp.count > 0 {
+		fp.count--
+	}
+	mu.Unlock()
+
+	switch fp.action {
+	case "return":
+		return fp.arg, true
+	case "sleep":
+		time.Sleep(fp.sleep)
+	case "panic":
+		panic(fmt.Sprintf("failpoint %s panic: %s", name, fp.arg))
+	}
+	return "", false
+}
+
+// Error returns the error of the failpoint if its term is return, nil
+// otherwise.
+func Error(name string) error {
+	if value, ok := Eval(name); ok {
+		return errors.Errorf("failpoint %s: %s", name, value)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package failpoint
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+func TestFailpoint(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testFailpointSuite{})
+
+type testFailpointSuite struct{}
+
+func (s *testFailpointSuite) TestEval(c *C) {
+	_, ok := Eval("test")
+	c.Assert(ok, IsFalse)
+
+	c.Assert(Enable("test", "return(err)"), IsNil)
+	value, ok := Eval("test")
+	c.Assert(ok, IsTrue)
+	c.Assert(value, Equals, "err")
+	c.Assert(Error("test"), ErrorMatches, "failpoint test: err")
+
+	c.Assert(Enable("test", "2*return"), IsNil)
+	c.Assert(Error("test"), NotNil)
+	c.Assert(All(), DeepEquals, []*Failpoint{{Name: "test", Term: "2*return", Count: 1}})
+	c.Assert(Error("test"), NotNil)
+	c.Assert(Error("test"), IsNil)
+
+	c.Assert(Enable("test", "sleep(50ms)"), IsNil)
+	start := time.Now()
+	_, ok = Eval("test")
+	c.Assert(ok, IsFalse)
+	c.Assert(time.Since(start) >= 50*time.Millisecond, IsTrue)
+
+	c.Assert(Enable("test", "panic(boom)"), IsNil)
+	c.Assert(func() { Eval("test") }, PanicMatches, ".*boom")
+
+	c.Assert(Enable("test", "off"), IsNil)
+	c.Assert(Error("test"), IsNil)
+
+	c.Assert(Disable("test"), IsTrue)
+	c.Assert(Disable("test"), IsFalse)
+	c.Assert(All(), HasLen, 0)
+}
+
+func (s *testFailpointSuite) TestEnable(c *C) {
+	for _, term := range []string{"", "fail", "return(", "sleep(1)", "x*return"} {
+		c.Assert(Enable("test", term), NotNil, Commentf("term %q", term))
+	}
+	c.Assert(EnableAll("a=return(1); b=1*sleep(1ms);"), IsNil)
+	c.Assert(All(), DeepEquals, []*Failpoint{
+		{Name: "a", Term: "return(1)", Count: -1},
+		{Name: "b", Term: "1*sleep(1ms)", Count: 1},
+	})
+	c.Assert(EnableAll("c"), NotNil)
+	Disable("a")
+	Disable("b")
+}
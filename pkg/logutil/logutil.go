@@ -0,0 +1,164 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coreos/pkg/capnslog"
+	"github.com/juju/errors"
+)
+
+// Log formats.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+const defaultComponent = "pd"
+
+// LogConfig is the log configuration.
+type LogConfig struct {
+	// Format is the log format, text or json.
+	Format string `toml:"format" json:"format"`
+	// MaxSize is the max size in MB of the log file, the file is rotated
+	// when it grows larger. The file is rotated by day if it is 0.
+	MaxSize int `toml:"max-size" json:"max-size"`
+	// MaxDays is the max days to retain the rotated files, 0 retains all.
+	MaxDays int `toml:"max-days" json:"max-days"`
+	// MaxBackups is the max number of the rotated files to retain, 0
+	// retains all.
+	MaxBackups int `toml:"max-backups" json:"max-backups"`
+}
+
+// NewLogWriter returns the writer for the log lines, it writes to the file
+// with rotation, or stderr if the file is empty.
+func NewLogWriter(file string, cfg *LogConfig) (io.Writer, error) {
+	var w io.Writer = os.Stderr
+	if len(file) != 0 {
+		rw, err := newRotateWriter(file, cfg)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		w = rw
+	}
+
+	switch cfg.Format {
+	case FormatText:
+		return w, nil
+	case FormatJSON:
+		return &jsonWriter{w: w}, nil
+	}
+	return nil, errors.Errorf("unknown log format %s", cfg.Format)
+}
+
+// logEntry is a log line in json format.
+type logEntry struct {
+	Time      string `json:"time"`
+	Level     string `json:"level"`
+	Component string `json:"component"`
+	Caller    string `json:"caller,omitempty"`
+	Message   string `json:"message"`
+}
+
+// JSONLogFlags are the flags of the ngaut log in json format, the lines
+// are like "leader.go:60: [info] msg" and the writer adds the time.
+const JSONLogFlags = log.Lshortfile
+
+// jsonWriter writes the log entries in json. It takes the caller and the
+// level of a ngaut log line from its prefix, which is fixed by
+// JSONLogFlags. It is also a capnslog formatter, so the etcd logs are
+// written with their own fields.
+type jsonWriter struct {
+	w io.Writer
+}
+
+func (w *jsonWriter) Write(p []byte) (int, error) {
+	if err := w.writeEntry(parseLogLine(string(p))); err != nil {
+		return 0, errors.Trace(err)
+	}
+	return len(p), nil
+}
+
+// Format implements capnslog.Formatter.
+func (w *jsonWriter) Format(pkg string, level capnslog.LogLevel, depth int, entries ...interface{}) {
+	entry := &logEntry{
+		Time:      time.Now().Format(time.RFC3339),
+		Level:     strings.ToLower(level.String()),
+		Component: pkg,
+		Message:   strings.TrimSuffix(fmt.Sprint(entries...), "\n"),
+	}
+	if entry.Component == "" {
+		entry.Component = defaultComponent
+	}
+	if _, file, line, ok := runtime.Caller(depth); ok {
+		entry.Caller = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+	}
+	if err := w.writeEntry(entry); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// Flush implements capnslog.Formatter.
+func (w *jsonWriter) Flush() {}
+
+func (w *jsonWriter) writeEntry(entry *logEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	_, err = w.w.Write(append(data, '\n'))
+	return errors.Trace(err)
+}
+
+// parseLogLine returns the entry of a ngaut log line. Only the caller and
+// the level prefixes are taken, the rest is the message as is, which may
+// have colons, brackets or new lines.
+func parseLogLine(line string) *logEntry {
+	entry := &logEntry{
+		Time:      time.Now().Format(time.RFC3339),
+		Component: defaultComponent,
+	}
+	line = strings.TrimSuffix(line, "\n")
+
+	if i := strings.Index(line, ": "); i > 0 && isCaller(line[:i]) {
+		entry.Caller, line = line[:i], line[i+2:]
+	}
+	if strings.HasPrefix(line, "[") {
+		if i := strings.Index(line, "] "); i > 0 && !strings.ContainsAny(line[1:i], " \n") {
+			entry.Level, line = line[1:i], line[i+2:]
+		}
+	}
+	entry.Message = line
+	return entry
+}
+
+// isCaller returns true if s is like "leader.go:60".
+func isCaller(s string) bool {
+	i := strings.LastIndex(s, ":")
+	if i <= 0 || strings.ContainsAny(s, " \n") {
+		return false
+	}
+	_, err := strconv.Atoi(s[i+1:])
+	return err == nil
+}
@@ -0,0 +1,152 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coreos/pkg/capnslog"
+	. "github.com/pingcap/check"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testLogSuite{})
+
+type testLogSuite struct {
+}
+
+func (s *testLogSuite) TestParseLogLine(c *C) {
+	entry := parseLogLine("leader.go:60: [info] server is closed\n")
+	c.Assert(entry.Level, Equals, "info")
+	c.Assert(entry.Caller, Equals, "leader.go:60")
+	c.Assert(entry.Component, Equals, defaultComponent)
+	c.Assert(entry.Message, Equals, "server is closed")
+	_, err := time.Parse(time.RFC3339, entry.Time)
+	c.Assert(err, IsNil)
+
+	// The message is kept as is.
+	entry = parseLogLine("leader.go:60: [error] failed: [1 2] a: b\n\tat leader.go:61\n")
+	c.Assert(entry.Level, Equals, "error")
+	c.Assert(entry.Caller, Equals, "leader.go:60")
+	c.Assert(entry.Message, Equals, "failed: [1 2] a: b\n\tat leader.go:61")
+	entry = parseLogLine("leader.go:60: [warning] \n")
+	c.Assert(entry.Level, Equals, "warning")
+	c.Assert(entry.Message, Equals, "")
+	entry = parseLogLine("leader.go:60: [info] [store 1] is up\n")
+	c.Assert(entry.Level, Equals, "info")
+	c.Assert(entry.Message, Equals, "[store 1] is up")
+
+	// Unknown format.
+	entry = parseLogLine("unknown format")
+	c.Assert(entry.Message, Equals, "unknown format")
+	c.Assert(entry.Caller, Equals, "")
+	entry = parseLogLine("key: value\n")
+	c.Assert(entry.Message, Equals, "key: value")
+	c.Assert(entry.Caller, Equals, "")
+	entry = parseLogLine("[not a level] msg")
+	c.Assert(entry.Level, Equals, "")
+	c.Assert(entry.Message, Equals, "[not a level] msg")
+}
+
+func (s *testLogSuite) TestJSONWriter(c *C) {
+	var buf bytes.Buffer
+	w := &jsonWriter{w: &buf}
+	_, err := w.Write([]byte("leader.go:60: [warning] a \"quoted\" msg\nin two lines\n"))
+	c.Assert(err, IsNil)
+
+	c.Assert(strings.HasSuffix(buf.String(), "\n"), IsTrue)
+	c.Assert(strings.Count(buf.String(), "\n"), Equals, 1)
+	var entry logEntry
+	c.Assert(json.Unmarshal(buf.Bytes(), &entry), IsNil)
+	c.Assert(entry.Level, Equals, "warning")
+	c.Assert(entry.Message, Equals, "a \"quoted\" msg\nin two lines")
+
+	// The etcd log is formatted with its own fields.
+	buf.Reset()
+	w.Format("etcdserver", capnslog.NOTICE, 1, "published member [a: b]\n")
+	c.Assert(json.Unmarshal(buf.Bytes(), &entry), IsNil)
+	c.Assert(entry.Component, Equals, "etcdserver")
+	c.Assert(entry.Level, Equals, "notice")
+	c.Assert(entry.Message, Equals, "published member [a: b]")
+	c.Assert(entry.Caller, Matches, "logutil_test.go:[0-9]+")
+
+	_, err = NewLogWriter("", &LogConfig{Format: "xml"})
+	c.Assert(err, NotNil)
+}
+
+func (s *testLogSuite) TestRotateBySize(c *C) {
+	dir, err := ioutil.TempDir("", "test_log")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "pd.log")
+	w, err := newRotateWriter(path, &LogConfig{MaxSize: 1, MaxBackups: 2})
+	c.Assert(err, IsNil)
+
+	line := bytes.Repeat([]byte("a"), megabyte/2)
+	for i := 0; i < 8; i++ {
+		_, err = w.Write(line)
+		c.Assert(err, IsNil)
+		// Backups are named in milliseconds.
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	backups, err := filepath.Glob(path + ".*")
+	c.Assert(err, IsNil)
+	c.Assert(backups, HasLen, 2)
+	info, err := os.Stat(path)
+	c.Assert(err, IsNil)
+	c.Assert(info.Size(), Equals, int64(megabyte))
+}
+
+func (s *testLogSuite) TestRotateByDay(c *C) {
+	dir, err := ioutil.TempDir("", "test_log")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "pd.log")
+	c.Assert(ioutil.WriteFile(path, []byte("old\n"), 0644), IsNil)
+	yesterday := time.Now().Add(-24 * time.Hour)
+	c.Assert(os.Chtimes(path, yesterday, yesterday), IsNil)
+
+	// An expired backup is removed.
+	expired := path + "." + time.Now().Add(-72*time.Hour).Format(dayFormat)
+	c.Assert(ioutil.WriteFile(expired, []byte("expired\n"), 0644), IsNil)
+	longAgo := time.Now().Add(-72 * time.Hour)
+	c.Assert(os.Chtimes(expired, longAgo, longAgo), IsNil)
+
+	w, err := newRotateWriter(path, &LogConfig{MaxDays: 2})
+	c.Assert(err, IsNil)
+	_, err = w.Write([]byte("new\n"))
+	c.Assert(err, IsNil)
+
+	data, err := ioutil.ReadFile(path + "." + yesterday.Format(dayFormat))
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "old\n")
+	data, err = ioutil.ReadFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "new\n")
+	_, err = os.Stat(expired)
+	c.Assert(os.IsNotExist(err), IsTrue)
+}
@@ -0,0 +1,166 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logutil
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+const (
+	// dayFormat is the suffix of the files rotated by day, it is the same
+	// as the rotated files of ngaut log.
+	dayFormat = "20060102"
+	// backupTimeFormat is the suffix of the files rotated by size.
+	backupTimeFormat = "2006-01-02T15-04-05.000"
+
+	megabyte = 1024 * 1024
+)
+
+// rotateWriter writes to the log file, and renames it to a backup file when
+// it is larger than max-size or the day changes. The backups out of
+// max-days or max-backups are removed after rotation.
+type rotateWriter struct {
+	sync.Mutex
+	path string
+	cfg  *LogConfig
+	file *os.File
+	size int64
+	day  string
+}
+
+func newRotateWriter(path string, cfg *LogConfig) (*rotateWriter, error) {
+	w := &rotateWriter{path: path, cfg: cfg}
+	if err := w.open(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return w, nil
+}
+
+func (w *rotateWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return errors.Trace(err)
+	}
+	w.file = f
+	w.size = info.Size()
+	w.day = time.Now().Format(dayFormat)
+	if w.size > 0 {
+		// The existing file belongs to the day it was last written.
+		w.day = info.ModTime().Format(dayFormat)
+	}
+	return nil
+}
+
+func (w *rotateWriter) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.shouldRotate(len(p)) {
+		if err := w.rotate(); err != nil {
+			return 0, errors.Trace(err)
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, errors.Trace(err)
+}
+
+func (w *rotateWriter) shouldRotate(n int) bool {
+	if w.size == 0 {
+		return false
+	}
+	if w.cfg.MaxSize > 0 {
+		return w.size+int64(n) > int64(w.cfg.MaxSize)*megabyte
+	}
+	return time.Now().Format(dayFormat) != w.day
+}
+
+func (w *rotateWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return errors.Trace(err)
+	}
+
+	backup := w.path + "." + w.day
+	if w.cfg.MaxSize > 0 {
+		backup = w.path + "." + time.Now().Format(backupTimeFormat)
+	}
+	if err := os.Rename(w.path, backup); err != nil {
+		return errors.Trace(err)
+	}
+	if err := w.open(); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(w.removeBackups())
+}
+
+type backupFile struct {
+	path    string
+	modTime time.Time
+}
+
+// newestFirst sorts the backups by modification time in descending order.
+type newestFirst []backupFile
+
+func (b newestFirst) Len() int           { return len(b) }
+func (b newestFirst) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b newestFirst) Less(i, j int) bool { return b[i].modTime.After(b[j].modTime) }
+
+// removeBackups removes the backups older than max-days, and the oldest
+// backups beyond max-backups.
+func (w *rotateWriter) removeBackups() error {
+	if w.cfg.MaxDays <= 0 && w.cfg.MaxBackups <= 0 {
+		return nil
+	}
+
+	paths, err := filepath.Glob(w.path + ".*")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	var backups []backupFile
+	for _, path := range paths {
+		// Only the suffixes of rotation start with a digit.
+		if suffix := path[len(w.path)+1:]; suffix[0] < '0' || suffix[0] > '9' {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupFile{path: path, modTime: info.ModTime()})
+	}
+	sort.Sort(newestFirst(backups))
+
+	deadline := time.Now().Add(-time.Duration(w.cfg.MaxDays) * 24 * time.Hour)
+	for i, b := range backups {
+		expired := w.cfg.MaxDays > 0 && b.modTime.Before(deadline)
+		overflow := w.cfg.MaxBackups > 0 && i >= w.cfg.MaxBackups
+		if expired || overflow {
+			if err := os.Remove(b.path); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	return nil
+}
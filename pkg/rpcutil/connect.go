@@ -14,20 +14,23 @@
 package rpcutil
 
 import (
+	"crypto/tls"
 	"net"
 	"net/http"
 	"net/url"
 	"time"
 
 	"github.com/juju/errors"
+	"github.com/pingcap/pd/pkg/tlsutil"
 )
 
 const (
 	rpcPrefix = "/pd/rpc"
 )
 
-// connectURL returns a rpc connection to the url.
-func connectURL(u url.URL, timeout time.Duration) (net.Conn, error) {
+// connectURL returns a rpc connection to the url, the connection is in TLS
+// if the url is in a secure scheme.
+func connectURL(u url.URL, timeout time.Duration, tlsCfg *tls.Config) (net.Conn, error) {
 	req, err := http.NewRequest("GET", rpcPrefix, nil)
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -43,6 +46,14 @@ func connectURL(u url.URL, timeout time.Duration) (net.Conn, error) {
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	if tlsutil.IsSecureScheme(u.Scheme) {
+		tlsConn, err := tlsutil.Client(conn, u.Host, tlsCfg, timeout)
+		if err != nil {
+			conn.Close()
+			return nil, errors.Trace(err)
+		}
+		conn = tlsConn
+	}
 
 	if err = req.Write(conn); err != nil {
 		conn.Close()
@@ -52,14 +63,15 @@ func connectURL(u url.URL, timeout time.Duration) (net.Conn, error) {
 	return conn, nil
 }
 
-// ConnectUrls returns a rpc connection to any one of the urls.
-func ConnectUrls(urls string, timeout time.Duration) (net.Conn, error) {
+// ConnectUrls returns a rpc connection to any one of the urls, tlsCfg is used
+// by the urls in secure schemes.
+func ConnectUrls(urls string, timeout time.Duration, tlsCfg *tls.Config) (net.Conn, error) {
 	us, err := ParseUrls(urls)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
 	for _, u := range us {
-		conn, err := connectURL(u, timeout)
+		conn, err := connectURL(u, timeout, tlsCfg)
 		if err == nil {
 			return conn, nil
 		}
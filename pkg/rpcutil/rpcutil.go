@@ -60,7 +60,7 @@ func Call(conn net.Conn, reqID uint64, request *pdpb.Request) (*pdpb.Response, e
 
 // Request connects to urls, then sends the request and wait for the response.
 func Request(urls string, reqID uint64, request *pdpb.Request) (*pdpb.Response, error) {
-	conn, err := ConnectUrls(urls, 0)
+	conn, err := ConnectUrls(urls, 0, nil)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
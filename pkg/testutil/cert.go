@@ -0,0 +1,79 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"time"
+
+	"github.com/pingcap/check"
+)
+
+// MustNewCerts generates a CA, and a cert signed by the CA for localhost
+// which is used by both the servers and the clients, in the dir. It returns
+// the paths of the CA cert, the cert and the key, used for test only.
+func MustNewCerts(c *check.C, dir string) (caPath, certPath, keyPath string) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, check.IsNil)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "pd test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	c.Assert(err, check.IsNil)
+	ca, err := x509.ParseCertificate(caDER)
+	c.Assert(err, check.IsNil)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, check.IsNil)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	c.Assert(err, check.IsNil)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	c.Assert(err, check.IsNil)
+
+	writePEM := func(name, typ string, der []byte) string {
+		p := filepath.Join(dir, name)
+		err := ioutil.WriteFile(p, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600)
+		c.Assert(err, check.IsNil)
+		return p
+	}
+	caPath = writePEM("ca.pem", "CERTIFICATE", caDER)
+	certPath = writePEM("pd.pem", "CERTIFICATE", certDER)
+	keyPath = writePEM("pd-key.pem", "EC PRIVATE KEY", keyDER)
+	return caPath, certPath, keyPath
+}
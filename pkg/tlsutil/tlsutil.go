@@ -0,0 +1,200 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlsutil
+
+import (
+	"crypto/tls"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/coreos/etcd/pkg/transport"
+	"github.com/juju/errors"
+	"github.com/pingcap/pd/pkg/typeutil"
+)
+
+// SecurityConfig is the TLS configuration, the certs and key are in PEM.
+// The TLS is disabled if the cert is not set.
+type SecurityConfig struct {
+	// CAPath is the path of the CA cert which signs the certs of the servers
+	// and the clients.
+	CAPath string `toml:"cacert-path" json:"cacert-path"`
+	// CertPath and KeyPath are the cert and key used by both the servers
+	// and the clients.
+	CertPath string `toml:"cert-path" json:"cert-path"`
+	KeyPath  string `toml:"key-path" json:"key-path"`
+	// ClientCertAuth makes the servers require the clients to present a cert
+	// signed by the CA.
+	ClientCertAuth bool `toml:"client-cert-auth" json:"client-cert-auth"`
+	// CertReloadInterval is the interval to check whether the files are
+	// renewed and reload them.
+	CertReloadInterval typeutil.Duration `toml:"cert-reload-interval" json:"cert-reload-interval"`
+}
+
+// Enabled returns true if the TLS is enabled.
+func (s SecurityConfig) Enabled() bool {
+	return s.CertPath != ""
+}
+
+// Validate checks the cert and key are set together, and the CA is set if
+// the client certs are required.
+func (s SecurityConfig) Validate() error {
+	if (s.CertPath == "") != (s.KeyPath == "") {
+		return errors.New("cert-path and key-path should be set together")
+	}
+	if s.ClientCertAuth && (s.CAPath == "" || !s.Enabled()) {
+		return errors.New("client-cert-auth needs cacert-path, cert-path and key-path")
+	}
+	return nil
+}
+
+// TLSInfo returns the TLS info used by etcd.
+func (s SecurityConfig) TLSInfo() transport.TLSInfo {
+	return transport.TLSInfo{
+		CertFile:       s.CertPath,
+		KeyFile:        s.KeyPath,
+		TrustedCAFile:  s.CAPath,
+		ClientCertAuth: s.ClientCertAuth,
+	}
+}
+
+// ToClientTLSConfig returns the TLS config of the clients, which verify the
+// servers by the CA and present the cert if it is set. It returns nil if
+// neither the CA nor the cert is set.
+func (s SecurityConfig) ToClientTLSConfig() (*tls.Config, error) {
+	if s.CAPath == "" && !s.Enabled() {
+		return nil, nil
+	}
+	info := s.TLSInfo()
+	info.ClientCertAuth = false
+	cfg, err := info.ClientConfig()
+	return cfg, errors.Trace(err)
+}
+
+// IsSecureScheme returns true if the scheme of the url is served by TLS.
+func IsSecureScheme(scheme string) bool {
+	return scheme == "https" || scheme == "unixs"
+}
+
+// Client starts the TLS handshake on the conn to the host within timeout,
+// the cert of the server is verified by the host without port. cfg can be
+// nil, then the server is verified by the system CAs.
+func Client(conn net.Conn, host string, cfg *tls.Config, timeout time.Duration) (net.Conn, error) {
+	if cfg == nil {
+		cfg = &tls.Config{}
+	} else {
+		cfg = transport.ShallowCopyTLSConfig(cfg)
+	}
+	if cfg.ServerName == "" {
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		cfg.ServerName = host
+	}
+
+	tlsConn := tls.Client(conn, cfg)
+	if timeout > 0 {
+		if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if timeout > 0 {
+		if err := conn.SetDeadline(time.Time{}); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return tlsConn, nil
+}
+
+// Reloader keeps the client TLS config loaded from the files of the security
+// config, so the renewed certs are used by the new connections without
+// restarting. The existing connections are kept.
+type Reloader struct {
+	sync.RWMutex
+	cfg      SecurityConfig
+	tlsCfg   *tls.Config
+	modTimes []time.Time
+}
+
+// NewReloader returns a reloader with the TLS config loaded.
+func NewReloader(cfg SecurityConfig) (*Reloader, error) {
+	r := &Reloader{cfg: cfg}
+	if _, err := r.Reload(true); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return r, nil
+}
+
+// TLSConfig returns the latest loaded TLS config, nil if neither the CA nor
+// the cert is set.
+func (r *Reloader) TLSConfig() *tls.Config {
+	r.RLock()
+	defer r.RUnlock()
+	return r.tlsCfg
+}
+
+// Reload loads the TLS config again if any of the files is modified since
+// last loading, or force is true. It returns true if the config is reloaded,
+// the last config is kept if the files are invalid, e.g. the cert is renewed
+// but the key is not yet.
+func (r *Reloader) Reload(force bool) (bool, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	modTimes, err := r.cfg.modTimes()
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	if !force && equalTimes(modTimes, r.modTimes) {
+		return false, nil
+	}
+	tlsCfg, err := r.cfg.ToClientTLSConfig()
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	r.tlsCfg, r.modTimes = tlsCfg, modTimes
+	return true, nil
+}
+
+func (s SecurityConfig) modTimes() ([]time.Time, error) {
+	var modTimes []time.Time
+	for _, name := range []string{s.CAPath, s.CertPath, s.KeyPath} {
+		if name == "" {
+			modTimes = append(modTimes, time.Time{})
+			continue
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		modTimes = append(modTimes, info.ModTime())
+	}
+	return modTimes, nil
+}
+
+func equalTimes(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
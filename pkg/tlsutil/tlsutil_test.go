@@ -0,0 +1,78 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlsutil
+
+import (
+	"testing"
+
+	. "github.com/pingcap/check"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testTLSUtilSuite{})
+
+type testTLSUtilSuite struct{}
+
+func (s *testTLSUtilSuite) TestValidate(c *C) {
+	cfg := SecurityConfig{}
+	c.Assert(cfg.Enabled(), IsFalse)
+	c.Assert(cfg.Validate(), IsNil)
+
+	cfg.CertPath = "pd.pem"
+	c.Assert(cfg.Validate(), NotNil)
+	cfg.KeyPath = "pd-key.pem"
+	c.Assert(cfg.Enabled(), IsTrue)
+	c.Assert(cfg.Validate(), IsNil)
+
+	cfg.ClientCertAuth = true
+	c.Assert(cfg.Validate(), NotNil)
+	cfg.CAPath = "ca.pem"
+	c.Assert(cfg.Validate(), IsNil)
+}
+
+func (s *testTLSUtilSuite) TestClientTLSConfig(c *C) {
+	cfg := SecurityConfig{}
+	tlsCfg, err := cfg.ToClientTLSConfig()
+	c.Assert(err, IsNil)
+	c.Assert(tlsCfg, IsNil)
+
+	cfg.CAPath = "not-exist.pem"
+	_, err = cfg.ToClientTLSConfig()
+	c.Assert(err, NotNil)
+
+	c.Assert(IsSecureScheme("https"), IsTrue)
+	c.Assert(IsSecureScheme("unixs"), IsTrue)
+	c.Assert(IsSecureScheme("http"), IsFalse)
+	c.Assert(IsSecureScheme("unix"), IsFalse)
+}
+
+func (s *testTLSUtilSuite) TestReloader(c *C) {
+	r, err := NewReloader(SecurityConfig{})
+	c.Assert(err, IsNil)
+	c.Assert(r.TLSConfig(), IsNil)
+
+	// The files are not changed.
+	reloaded, err := r.Reload(false)
+	c.Assert(err, IsNil)
+	c.Assert(reloaded, IsFalse)
+	reloaded, err = r.Reload(true)
+	c.Assert(err, IsNil)
+	c.Assert(reloaded, IsTrue)
+
+	_, err = NewReloader(SecurityConfig{CAPath: "not-exist.pem"})
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,177 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"crypto/subtle"
+	"crypto/x509"
+	"net/http"
+	"strings"
+
+	"github.com/ngaut/log"
+	"github.com/pingcap/pd/server"
+)
+
+const (
+	errUnauthorized = "unauthorized"
+	errForbidden    = "forbidden"
+	bearerPrefix    = "Bearer "
+	apiV1Prefix     = apiPrefix + "/api/v1"
+)
+
+var (
+	// adminPaths require the admin role, even the read-only requests. The
+	// backup carries the users, the data keys and the webhooks, and the
+	// snapshot and the capture dump the whole cluster.
+	adminPaths = []string{
+		"/users", "/encryption", "/failpoints",
+		"/backup", "/cluster/snapshot", "/cluster/capture",
+	}
+	// adminWritePaths require the admin role to change, the other mutating
+	// apis require the operator role.
+	adminWritePaths = []string{
+		"/config", "/members", "/leader", "/security", "/feature-gates",
+		"/webhooks", "/gc", "/tso", "/namespaces", "/keyspaces",
+	}
+)
+
+// authenticator checks the requests by the api-auth config before they are
+// served or redirected to the leader. The static tokens, the allowed common
+// names and the common name of the members have the admin role, and the
+// users have their own roles.
+type authenticator struct {
+	s *server.Server
+}
+
+func newAuthenticator(s *server.Server) *authenticator {
+	return &authenticator{s: s}
+}
+
+func (a *authenticator) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	cfg := a.s.GetConfig().APIAuth
+	required := requiredRole(r)
+	if !cfg.Enabled() || (required == server.RoleViewer && !cfg.ReadRequireAuth) {
+		next(w, r)
+		return
+	}
+	role := a.authenticate(cfg, r)
+	if role == "" {
+		log.Warnf("unauthorized %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+		http.Error(w, errUnauthorized, http.StatusUnauthorized)
+		return
+	}
+	if !server.RoleAllows(role, required) {
+		log.Warnf("forbidden %s %s from %s with role %s", r.Method, r.URL.Path, r.RemoteAddr, role)
+		http.Error(w, errForbidden, http.StatusForbidden)
+		return
+	}
+	next(w, r)
+}
+
+// requiredRole returns the role required by the request.
+func requiredRole(r *http.Request) string {
+	path := r.URL.Path
+	if !strings.HasPrefix(path, apiV1Prefix+"/") {
+		if isReadOnly(r) {
+			return server.RoleViewer
+		}
+		return server.RoleOperator
+	}
+	path = strings.TrimPrefix(path, apiV1Prefix)
+	for _, p := range adminPaths {
+		if hasPathPrefix(path, p) {
+			return server.RoleAdmin
+		}
+	}
+	if isReadOnly(r) {
+		return server.RoleViewer
+	}
+	for _, p := range adminWritePaths {
+		if hasPathPrefix(path, p) {
+			return server.RoleAdmin
+		}
+	}
+	// Deleting a store makes it offline and removes its data.
+	if r.Method == http.MethodDelete && hasPathPrefix(path, "/store") {
+		return server.RoleAdmin
+	}
+	return server.RoleOperator
+}
+
+func hasPathPrefix(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+func isReadOnly(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// authenticate returns the role of the request, "" if it is not
+// authenticated.
+func (a *authenticator) authenticate(cfg server.APIAuthConfig, r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, bearerPrefix) {
+		token := strings.TrimPrefix(auth, bearerPrefix)
+		for _, t := range cfg.Tokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+				return server.RoleAdmin
+			}
+		}
+		user, err := a.s.AuthenticateUser(token)
+		if err != nil {
+			log.Errorf("authenticate user err %v", err)
+		}
+		if user != nil {
+			return user.Role
+		}
+	}
+	if a.checkCert(cfg, r) {
+		return server.RoleAdmin
+	}
+	return ""
+}
+
+// checkCert checks the common name of the verified client cert.
+func (a *authenticator) checkCert(cfg server.APIAuthConfig, r *http.Request) bool {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return false
+	}
+	cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	if cn == "" {
+		return false
+	}
+	for _, allowed := range cfg.AllowedCNs {
+		if cn == allowed {
+			return true
+		}
+	}
+	return len(cfg.AllowedCNs) > 0 && cn == a.memberCN()
+}
+
+// memberCN returns the common name of the cert used to connect the members.
+func (a *authenticator) memberCN() string {
+	tlsCfg := a.s.GetTLSConfig()
+	if tlsCfg == nil || len(tlsCfg.Certificates) == 0 || len(tlsCfg.Certificates[0].Certificate) == 0 {
+		return ""
+	}
+	cert, err := x509.ParseCertificate(tlsCfg.Certificates[0].Certificate[0])
+	if err != nil {
+		log.Errorf("parse member cert err %v", err)
+		return ""
+	}
+	return cert.Subject.CommonName
+}
@@ -0,0 +1,174 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"strings"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/server"
+)
+
+var _ = Suite(&testAuthSuite{})
+
+type testAuthSuite struct {
+	hc *http.Client
+}
+
+func (s *testAuthSuite) SetUpSuite(c *C) {
+	s.hc = newUnixSocketClient()
+}
+
+func mustNewAuthServer(c *C, auth server.APIAuthConfig) (*server.Server, cleanUpFunc) {
+	cfg := server.NewTestSingleConfig()
+	cfg.APIAuth = auth
+	return mustNewServerWithCfg(c, cfg)
+}
+
+func mustNewServerWithCfg(c *C, cfg *server.Config) (*server.Server, cleanUpFunc) {
+	svr := server.CreateServer(cfg)
+	c.Assert(svr.StartEtcd(NewHandler(svr)), IsNil)
+	go svr.Run()
+	mustWaitLeader(c, []*server.Server{svr})
+
+	cleanup := func() {
+		svr.Close()
+		cleanServer(cfg)
+	}
+	return svr, cleanup
+}
+
+func (s *testAuthSuite) do(c *C, method, url, token string) int {
+	req, err := http.NewRequest(method, url, nil)
+	c.Assert(err, IsNil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := s.hc.Do(req)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func (s *testAuthSuite) TestToken(c *C) {
+	svr, cleanup := mustNewAuthServer(c, server.APIAuthConfig{Tokens: []string{"t1", "t2"}})
+	defer cleanup()
+
+	addr := mustUnixAddrToHTTPAddr(c, svr.GetAddr()) + apiPrefix + "/api/v1"
+	c.Assert(s.do(c, "GET", addr+"/leader", ""), Equals, http.StatusOK)
+
+	// The reload fails as the TLS is disabled when it is authorized.
+	reload := addr + "/security/reload"
+	c.Assert(s.do(c, "POST", reload, ""), Equals, http.StatusUnauthorized)
+	c.Assert(s.do(c, "POST", reload, "t3"), Equals, http.StatusUnauthorized)
+	c.Assert(s.do(c, "POST", reload, "t2"), Equals, http.StatusInternalServerError)
+
+	// The tokens are hidden.
+	resp, err := s.hc.Get(fmt.Sprintf("%s/config", addr))
+	c.Assert(err, IsNil)
+	cfg := &server.Config{}
+	c.Assert(readJSON(resp.Body, cfg), IsNil)
+	c.Assert(cfg.APIAuth.Tokens, HasLen, 0)
+}
+
+func (s *testAuthSuite) TestReadRequireAuth(c *C) {
+	svr, cleanup := mustNewAuthServer(c, server.APIAuthConfig{Tokens: []string{"t1"}, ReadRequireAuth: true})
+	defer cleanup()
+
+	addr := mustUnixAddrToHTTPAddr(c, svr.GetAddr()) + apiPrefix + "/api/v1"
+	c.Assert(s.do(c, "GET", addr+"/leader", ""), Equals, http.StatusUnauthorized)
+	c.Assert(s.do(c, "GET", addr+"/leader", "t1"), Equals, http.StatusOK)
+}
+
+func (s *testAuthSuite) TestRequiredRole(c *C) {
+	tbl := []struct {
+		method string
+		path   string
+		role   string
+	}{
+		{"GET", "/stores", server.RoleViewer},
+		{"GET", "/users", server.RoleAdmin},
+		{"GET", "/encryption/keys", server.RoleAdmin},
+		{"GET", "/backup", server.RoleAdmin},
+		{"GET", "/cluster/snapshot", server.RoleAdmin},
+		{"GET", "/cluster/capture", server.RoleAdmin},
+		{"GET", "/cluster/status", server.RoleViewer},
+		{"POST", "/schedulers", server.RoleOperator},
+		{"POST", "/operators", server.RoleOperator},
+		{"POST", "/store/1/state", server.RoleOperator},
+		{"DELETE", "/store/1", server.RoleAdmin},
+		{"POST", "/config", server.RoleAdmin},
+		{"DELETE", "/members/name/pd1", server.RoleAdmin},
+		{"POST", "/keyspaces", server.RoleAdmin},
+		{"POST", "/configs", server.RoleOperator},
+	}
+	for _, t := range tbl {
+		req, err := http.NewRequest(t.method, apiV1Prefix+t.path, nil)
+		c.Assert(err, IsNil)
+		c.Assert(requiredRole(req), Equals, t.role, Commentf("%s %s", t.method, t.path))
+	}
+}
+
+func (s *testAuthSuite) TestRoles(c *C) {
+	svr, cleanup := mustNewAuthServer(c, server.APIAuthConfig{Tokens: []string{"t1"}})
+	defer cleanup()
+
+	addr := mustUnixAddrToHTTPAddr(c, svr.GetAddr()) + apiPrefix + "/api/v1"
+	resp, err := s.post(c, addr+"/users", "t1", `{"name":"viewer","role":"viewer"}`)
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	created := make(map[string]string)
+	c.Assert(readJSON(resp.Body, &created), IsNil)
+	viewer := created["token"]
+	c.Assert(viewer, Not(Equals), "")
+	operator, err := svr.CreateUser("operator", server.RoleOperator)
+	c.Assert(err, IsNil)
+
+	// The users are only managed by the admins.
+	c.Assert(s.do(c, "GET", addr+"/users", ""), Equals, http.StatusUnauthorized)
+	c.Assert(s.do(c, "GET", addr+"/users", operator), Equals, http.StatusForbidden)
+	c.Assert(s.do(c, "GET", addr+"/users", "t1"), Equals, http.StatusOK)
+
+	// The backup has the users, so it is only read by the admins too.
+	c.Assert(s.do(c, "GET", addr+"/backup", ""), Equals, http.StatusUnauthorized)
+	c.Assert(s.do(c, "GET", addr+"/backup", viewer), Equals, http.StatusForbidden)
+	code := s.do(c, "GET", addr+"/backup", "t1")
+	c.Assert(code, Not(Equals), http.StatusUnauthorized)
+	c.Assert(code, Not(Equals), http.StatusForbidden)
+
+	schedulers := addr + "/schedulers"
+	c.Assert(s.do(c, "GET", addr+"/leader", viewer), Equals, http.StatusOK)
+	c.Assert(s.do(c, "POST", schedulers, viewer), Equals, http.StatusForbidden)
+	code = s.do(c, "POST", schedulers, operator)
+	c.Assert(code, Not(Equals), http.StatusUnauthorized)
+	c.Assert(code, Not(Equals), http.StatusForbidden)
+
+	reload := addr + "/security/reload"
+	c.Assert(s.do(c, "POST", reload, operator), Equals, http.StatusForbidden)
+	c.Assert(s.do(c, "POST", reload, "t1"), Equals, http.StatusInternalServerError)
+
+	c.Assert(s.do(c, "DELETE", addr+"/users/operator", "t1"), Equals, http.StatusOK)
+	c.Assert(s.do(c, "DELETE", addr+"/users/operator", "t1"), Equals, http.StatusNotFound)
+	c.Assert(s.do(c, "POST", schedulers, operator), Equals, http.StatusUnauthorized)
+}
+
+func (s *testAuthSuite) post(c *C, url, token, body string) (*http.Response, error) {
+	req, err := http.NewRequest("POST", url, strings.NewReader(body))
+	c.Assert(err, IsNil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return s.hc.Do(req)
+}
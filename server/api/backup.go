@@ -0,0 +1,48 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/pingcap/pd/server"
+	"github.com/unrolled/render"
+)
+
+type backupHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newBackupHandler(svr *server.Server, rd *render.Render) *backupHandler {
+	return &backupHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+// Get exports the metadata, which is restored by starting a fresh pd-server
+// with the restore flag.
+func (h *backupHandler) Get(w http.ResponseWriter, r *http.Request) {
+	if h.svr.GetRaftCluster() == nil {
+		h.rd.JSON(w, http.StatusInternalServerError, errNotBootstrapped.Error())
+		return
+	}
+	backup, err := h.svr.Backup()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, backup)
+}
@@ -0,0 +1,60 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/server"
+)
+
+var _ = Suite(&testBackupSuite{})
+
+type testBackupSuite struct {
+	hc        *http.Client
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testBackupSuite) SetUpSuite(c *C) {
+	s.hc = newUnixSocketClient()
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+	s.urlPrefix = mustUnixAddrToHTTPAddr(c, s.svr.GetAddr()+apiPrefix+"/api/v1")
+}
+
+func (s *testBackupSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testBackupSuite) TestBackup(c *C) {
+	resp, err := s.hc.Get(s.urlPrefix + "/backup")
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusInternalServerError)
+
+	mustBootstrapCluster(c, s.svr)
+	resp, err = s.hc.Get(s.urlPrefix + "/backup")
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	backup := &server.Backup{}
+	c.Assert(json.NewDecoder(resp.Body).Decode(backup), IsNil)
+	c.Assert(backup.ClusterID, Equals, s.svr.ClusterID())
+	c.Assert(backup.Stores, HasLen, 1)
+	c.Assert(backup.Meta.GetId(), Equals, s.svr.ClusterID())
+}
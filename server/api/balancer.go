@@ -15,6 +15,8 @@ package api
 
 import (
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/pingcap/pd/server"
 	"github.com/unrolled/render"
@@ -88,3 +90,65 @@ func (h *historyOperatorHandler) ServeHTTP(w http.ResponseWriter, r *http.Reques
 
 	h.rd.JSON(w, http.StatusOK, balancersInfo)
 }
+
+const defaultOperatorRecordsLimit = 1000
+
+type operatorRecordsHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newOperatorRecordsHandler(svr *server.Server, rd *render.Render) *operatorRecordsHandler {
+	return &operatorRecordsHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+// ServeHTTP returns the saved records of the operators, which can be filtered
+// by the region and the range of the end time in unix seconds, e.g.
+// "?region=2&start=1490000000&end=1490003600&limit=10".
+func (h *operatorRecordsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, http.StatusInternalServerError, errNotBootstrapped.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	regionID, err := parseUintParam(query.Get("region"), 0)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, "invalid region")
+		return
+	}
+	start, err := parseUintParam(query.Get("start"), 0)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, "invalid start")
+		return
+	}
+	end, err := parseUintParam(query.Get("end"), uint64(time.Now().Unix())+1)
+	if err != nil || end < start {
+		h.rd.JSON(w, http.StatusBadRequest, "invalid end")
+		return
+	}
+	limit, err := parseUintParam(query.Get("limit"), defaultOperatorRecordsLimit)
+	if err != nil || limit == 0 {
+		h.rd.JSON(w, http.StatusBadRequest, "invalid limit")
+		return
+	}
+
+	records, err := cluster.GetOperatorRecords(regionID, time.Unix(int64(start), 0), time.Unix(int64(end), 0), int(limit))
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, records)
+}
+
+// parseUintParam returns the default value if the param is empty.
+func parseUintParam(value string, defaultValue uint64) (uint64, error) {
+	if len(value) == 0 {
+		return defaultValue, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
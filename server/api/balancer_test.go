@@ -15,6 +15,8 @@ package api
 
 import (
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	. "github.com/pingcap/check"
@@ -118,3 +120,51 @@ func (s *testBalancerSuite) TestGet(c *C) *balancersInfo {
 	c.Assert(err, IsNil)
 	return info
 }
+
+func (s *testBalancerSuite) TestOperatorRecords(c *C) {
+	client := newUnixSocketClient()
+	url := strings.Replace(s.url, "balancers", "history/operators/records", 1)
+
+	resp, err := client.Get(url + "?region=8&limit=10")
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	var records []*server.OperatorRecord
+	c.Assert(readJSON(resp.Body, &records), IsNil)
+	c.Assert(records, HasLen, 0)
+
+	for _, query := range []string{"?region=a", "?start=10&end=1", "?limit=0"} {
+		resp, err = client.Get(url + query)
+		c.Assert(err, IsNil)
+		resp.Body.Close()
+		c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+	}
+}
+
+func (s *testBalancerSuite) TestReplicationProgress(c *C) {
+	client := newUnixSocketClient()
+	url := strings.Replace(s.url, "balancers", "config/replication/progress", 1)
+
+	resp, err := client.Get(url)
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	progress := &server.ReplicationProgress{}
+	c.Assert(readJSON(resp.Body, progress), IsNil)
+	// The bootstrapped region has only one peer.
+	c.Assert(progress.MaxReplicas, Equals, 3)
+	c.Assert(progress.RegionCount, Equals, 1)
+	c.Assert(progress.MissPeerCount, Equals, 1)
+	c.Assert(progress.ConvergedRatio, Equals, float64(0))
+}
+
+func (s *testBalancerSuite) TestEngineReplicaProgress(c *C) {
+	client := newUnixSocketClient()
+	url := strings.Replace(s.url, "balancers", "config/engine-replica/progress", 1)
+
+	resp, err := client.Get(url)
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	var progress []*server.EngineReplicaProgress
+	c.Assert(readJSON(resp.Body, &progress), IsNil)
+	// No engine replicas are configured.
+	c.Assert(progress, HasLen, 0)
+}
@@ -14,10 +14,19 @@
 package api
 
 import (
+	"bytes"
 	"net/http"
+	"time"
 
+	"github.com/ngaut/log"
 	"github.com/pingcap/pd/server"
 	"github.com/unrolled/render"
+	"golang.org/x/net/context"
+)
+
+const (
+	defaultCaptureDuration = time.Minute
+	maxCaptureDuration     = time.Hour
 )
 
 type clusterHandler struct {
@@ -41,3 +50,75 @@ func (h *clusterHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	h.rd.JSON(w, http.StatusOK, cluster.GetConfig())
 }
+
+// clusterStatus is the status of the cluster, it is served before the
+// cluster is bootstrapped.
+type clusterStatus struct {
+	Bootstrapped bool                `json:"bootstrapped"`
+	FeatureGates server.FeatureGates `json:"feature_gates"`
+}
+
+func (h *clusterHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	h.rd.JSON(w, http.StatusOK, &clusterStatus{
+		Bootstrapped: h.svr.GetRaftCluster() != nil,
+		FeatureGates: h.svr.GetFeatureGates(),
+	})
+}
+
+// GetSnapshot dumps the in-memory cluster state, which is loaded by
+// server.LoadClusterSnapshot for debugging offline.
+func (h *clusterHandler) GetSnapshot(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, http.StatusInternalServerError, errNotBootstrapped.Error())
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := cluster.WriteSnapshot(&buf); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Disposition", "attachment; filename=cluster.snapshot")
+	h.rd.Data(w, http.StatusOK, buf.Bytes())
+}
+
+// GetCapture streams the heartbeats handled in the duration set by
+// "?duration=", 1 minute by default, which are replayed by pd-replay. The
+// capture stops early if the request is canceled.
+func (h *clusterHandler) GetCapture(w http.ResponseWriter, r *http.Request) {
+	if h.svr.GetRaftCluster() == nil {
+		h.rd.JSON(w, http.StatusInternalServerError, errNotBootstrapped.Error())
+		return
+	}
+	duration := defaultCaptureDuration
+	if durationStr := r.URL.Query().Get("duration"); durationStr != "" {
+		var err error
+		if duration, err = time.ParseDuration(durationStr); err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if duration > maxCaptureDuration {
+			duration = maxCaptureDuration
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(h.svr.GetClient().Ctx(), duration)
+	defer cancel()
+	if notifier, ok := w.(http.CloseNotifier); ok {
+		closed := notifier.CloseNotify()
+		go func() {
+			select {
+			case <-closed:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", "attachment; filename=heartbeat.capture")
+	if err := h.svr.CaptureHeartbeats(ctx, w); err != nil {
+		// The status is sent already, the client gets a truncated capture.
+		log.Errorf("capture heartbeats err %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"io"
+	"net/http"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/server"
+)
+
+var _ = Suite(&testClusterSuite{})
+
+type testClusterSuite struct {
+	hc        *http.Client
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testClusterSuite) SetUpSuite(c *C) {
+	s.hc = newUnixSocketClient()
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+	s.urlPrefix = mustUnixAddrToHTTPAddr(c, s.svr.GetAddr()+apiPrefix+"/api/v1")
+}
+
+func (s *testClusterSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testClusterSuite) TestSnapshot(c *C) {
+	resp, err := s.hc.Get(s.urlPrefix + "/cluster/snapshot")
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusInternalServerError)
+
+	mustBootstrapCluster(c, s.svr)
+	resp, err = s.hc.Get(s.urlPrefix + "/cluster/snapshot")
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	snap, err := server.LoadClusterSnapshot(resp.Body)
+	c.Assert(err, IsNil)
+	c.Assert(snap.ClusterID, Equals, s.svr.ClusterID())
+	c.Assert(snap.Stores, HasLen, 1)
+	c.Assert(snap.Regions, HasLen, 1)
+}
+
+var _ = Suite(&testCaptureSuite{})
+
+type testCaptureSuite struct {
+	hc        *http.Client
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testCaptureSuite) SetUpSuite(c *C) {
+	s.hc = newUnixSocketClient()
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+	s.urlPrefix = mustUnixAddrToHTTPAddr(c, s.svr.GetAddr()+apiPrefix+"/api/v1")
+}
+
+func (s *testCaptureSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testCaptureSuite) TestCapture(c *C) {
+	resp, err := s.hc.Get(s.urlPrefix + "/cluster/capture")
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusInternalServerError)
+
+	mustBootstrapCluster(c, s.svr)
+	resp, err = s.hc.Get(s.urlPrefix + "/cluster/capture?duration=1")
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+
+	resp, err = s.hc.Get(s.urlPrefix + "/cluster/capture?duration=100ms")
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	replayer, err := server.NewReplayer(resp.Body)
+	c.Assert(err, IsNil)
+	defer replayer.Close()
+	snap := replayer.Header().Snapshot
+	c.Assert(snap.ClusterID, Equals, s.svr.ClusterID())
+	c.Assert(snap.Stores, HasLen, 1)
+	c.Assert(snap.Regions, HasLen, 1)
+	_, err = replayer.Next()
+	c.Assert(err, Equals, io.EOF)
+}
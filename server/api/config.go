@@ -14,8 +14,16 @@
 package api
 
 import (
+	"crypto/tls"
+	"encoding/json"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/gorilla/mux"
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/pd/pkg/etcdutil"
 	"github.com/pingcap/pd/server"
 	"github.com/unrolled/render"
 )
@@ -40,14 +48,214 @@ func (h *confHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, &h.svr.GetConfig().Schedule)
 }
 
+func (h *confHandler) GetReplication(w http.ResponseWriter, r *http.Request) {
+	h.rd.JSON(w, http.StatusOK, &h.svr.GetConfig().Replication)
+}
+
+func (h *confHandler) PostReplication(w http.ResponseWriter, r *http.Request) {
+	config := &server.ReplicationConfig{}
+	err := readJSONStrict(r.Body, config)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err = config.Validate(); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, err = h.svr.UpdateReplicationConfig(*config, getAuthor(r)); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
 func (h *confHandler) Post(w http.ResponseWriter, r *http.Request) {
 	config := &server.ScheduleConfig{}
-	err := readJSON(r.Body, config)
+	err := readJSONStrict(r.Body, config)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err = config.Validate(); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, err = h.svr.UpdateScheduleConfig(*config, getAuthor(r)); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+// configDiff is the config items of the leader which differ from the
+// defaults, and the config items of the members which differ from the leader.
+type configDiff struct {
+	Default []*server.ConfigDiffItem            `json:"default"`
+	Members map[string][]*server.ConfigDiffItem `json:"members"`
+	// Errors are the members whose config can not be fetched.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+func (h *confHandler) GetDiff(w http.ResponseWriter, r *http.Request) {
+	cfg := h.svr.GetConfig()
+	def, err := server.DiffDefaultConfig(cfg)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	listResp, err := etcdutil.ListEtcdMembers(h.svr.GetClient())
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	diff := &configDiff{
+		Default: def,
+		Members: make(map[string][]*server.ConfigDiffItem),
+		Errors:  make(map[string]string),
+	}
+	for _, m := range listResp.Members {
+		if m.Name == h.svr.Name() {
+			continue
+		}
+		memberCfg, err := getMemberConfig(m.ClientURLs, h.svr.GetTLSConfig())
+		if err != nil {
+			diff.Errors[m.Name] = err.Error()
+			continue
+		}
+		diff.Members[m.Name] = server.DiffMemberConfig(cfg, memberCfg)
+	}
+	h.rd.JSON(w, http.StatusOK, diff)
+}
+
+// getMemberConfig gets the config of the member itself, which is not
+// redirected to leader.
+func getMemberConfig(clientUrls []string, tlsCfg *tls.Config) (*server.Config, error) {
+	urls, err := server.ParseUrls(strings.Join(clientUrls, ","))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, u := range urls {
+		client := newURLClient(&u, tlsCfg)
+		client.Timeout = defaultDialTimeout
+		u.Path = apiPrefix + "/api/v1/config/local"
+		resp, err := client.Get(u.String())
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, errors.Errorf("failed to get config from %s: %s", u.Host, resp.Status)
+		}
+		cfg := &server.Config{}
+		err = json.NewDecoder(resp.Body).Decode(cfg)
+		resp.Body.Close()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return cfg, nil
+	}
+	return nil, errors.Errorf("failed to get config from %v", clientUrls)
+}
+
+func (h *confHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	versions, err := h.svr.GetConfigHistory()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, versions)
+}
+
+func (h *confHandler) Rollback(w http.ResponseWriter, r *http.Request) {
+	version, err := strconv.ParseUint(mux.Vars(r)["version"], 10, 64)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	v, err := h.svr.RollbackConfig(version, getAuthor(r))
 	if err != nil {
+		if errors.Cause(err) == server.ErrConfigVersionNotFound {
+			h.rd.JSON(w, http.StatusNotFound, err.Error())
+			return
+		}
 		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.rd.JSON(w, http.StatusOK, v)
+}
+
+// getAuthor returns the author of the config change, which is the PD-Author
+// header or the address of the client.
+func getAuthor(r *http.Request) string {
+	if author := r.Header.Get("PD-Author"); author != "" {
+		return author
+	}
+	return r.RemoteAddr
+}
+
+func (h *confHandler) Reload(w http.ResponseWriter, r *http.Request) {
+	result, err := h.svr.ReloadConfig()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, result)
+}
+
+func (h *confHandler) GetReplicationProgress(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, http.StatusInternalServerError, errNotBootstrapped.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, cluster.GetReplicationProgress())
+}
+
+func (h *confHandler) GetEngineReplicaProgress(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, http.StatusInternalServerError, errNotBootstrapped.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, cluster.GetEngineReplicaProgress())
+}
+
+func (h *confHandler) GetLabelProperty(w http.ResponseWriter, r *http.Request) {
+	h.rd.JSON(w, http.StatusOK, h.svr.GetConfig().LabelProperty)
+}
+
+func (h *confHandler) PostLabelProperty(w http.ResponseWriter, r *http.Request) {
+	input := make(map[string]string)
+	if err := readJSON(r.Body, &input); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	h.svr.SetScheduleConfig(*config)
+	typ := input["type"]
+	label := server.StoreLabel{Key: input["label-key"], Value: input["label-value"]}
+	err := server.LabelPropertyConfig{typ: {label}}.Validate()
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	switch input["action"] {
+	case "set":
+		_, err = h.svr.SetLabelProperty(typ, label, getAuthor(r))
+	case "delete":
+		_, err = h.svr.DeleteLabelProperty(typ, label, getAuthor(r))
+	default:
+		h.rd.JSON(w, http.StatusBadRequest, "action should be set or delete")
+		return
+	}
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 	h.rd.JSON(w, http.StatusOK, nil)
 }
@@ -85,6 +85,186 @@ func (s *testConfigSuite) TestConfigSchedule(c *C) {
 		sc1 := &server.ScheduleConfig{}
 		json.NewDecoder(resp.Body).Decode(sc1)
 
-		c.Assert(*sc, Equals, *sc1)
+		c.Assert(*sc, DeepEquals, *sc1)
+	}
+}
+
+func (s *testConfigSuite) TestConfigReplication(c *C) {
+	cfgs, _, clean := mustNewCluster(c, 1)
+	defer clean()
+
+	parts := []string{cfgs[0].ClientUrls, apiPrefix, "/api/v1/config/replication"}
+	addr := mustUnixAddrToHTTPAddr(c, strings.Join(parts, ""))
+	resp, err := s.hc.Get(addr)
+	c.Assert(err, IsNil)
+	rc := &server.ReplicationConfig{}
+	err = json.NewDecoder(resp.Body).Decode(rc)
+	resp.Body.Close()
+	c.Assert(err, IsNil)
+	c.Assert(rc.MaxReplicas, Equals, uint64(3))
+
+	rc.MaxReplicas = 5
+	rc.LocationLabels = []string{"zone", "rack"}
+	postData, err := json.Marshal(rc)
+	c.Assert(err, IsNil)
+	resp, err = s.hc.Post(addr, "application/json", bytes.NewBuffer(postData))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+
+	resp, err = s.hc.Get(addr)
+	c.Assert(err, IsNil)
+	rc1 := &server.ReplicationConfig{}
+	err = json.NewDecoder(resp.Body).Decode(rc1)
+	resp.Body.Close()
+	c.Assert(err, IsNil)
+	c.Assert(rc1, DeepEquals, rc)
+
+	// Invalid max replicas.
+	resp, err = s.hc.Post(addr, "application/json", strings.NewReader(`{"max-replicas": 0}`))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+
+	// Unknown items.
+	resp, err = s.hc.Post(addr, "application/json", strings.NewReader(`{"max-replica": 3}`))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+}
+
+func (s *testConfigSuite) TestConfigHistory(c *C) {
+	cfgs, _, clean := mustNewCluster(c, 1)
+	defer clean()
+
+	prefix := mustUnixAddrToHTTPAddr(c, cfgs[0].ClientUrls+apiPrefix+"/api/v1/config")
+	getHistory := func() []*server.ConfigVersion {
+		resp, err := s.hc.Get(prefix + "/history")
+		c.Assert(err, IsNil)
+		defer resp.Body.Close()
+		var versions []*server.ConfigVersion
+		c.Assert(json.NewDecoder(resp.Body).Decode(&versions), IsNil)
+		return versions
+	}
+	c.Assert(getHistory(), HasLen, 0)
+
+	req, err := http.NewRequest(http.MethodPost, prefix+"/replication", strings.NewReader(`{"max-replicas": 5}`))
+	c.Assert(err, IsNil)
+	req.Header.Set("PD-Author", "test")
+	resp, err := s.hc.Do(req)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+
+	versions := getHistory()
+	c.Assert(versions, HasLen, 1)
+	c.Assert(versions[0].Version, Equals, uint64(1))
+	c.Assert(versions[0].Author, Equals, "test")
+	c.Assert(versions[0].Replication.MaxReplicas, Equals, uint64(5))
+
+	resp, err = s.hc.Post(prefix+"/replication", "application/json", strings.NewReader(`{"max-replicas": 1}`))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(getHistory(), HasLen, 2)
+
+	// Roll back to the first version.
+	resp, err = s.hc.Post(prefix+"/rollback/1", "application/json", nil)
+	c.Assert(err, IsNil)
+	v := &server.ConfigVersion{}
+	c.Assert(json.NewDecoder(resp.Body).Decode(v), IsNil)
+	resp.Body.Close()
+	c.Assert(v.Version, Equals, uint64(3))
+	c.Assert(v.RollbackVersion, Equals, uint64(1))
+
+	resp, err = s.hc.Get(prefix + "/replication")
+	c.Assert(err, IsNil)
+	rc := &server.ReplicationConfig{}
+	c.Assert(json.NewDecoder(resp.Body).Decode(rc), IsNil)
+	resp.Body.Close()
+	c.Assert(rc.MaxReplicas, Equals, uint64(5))
+
+	resp, err = s.hc.Post(prefix+"/rollback/10", "application/json", nil)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusNotFound)
+}
+
+func (s *testConfigSuite) TestConfigLabelProperty(c *C) {
+	cfgs, _, clean := mustNewCluster(c, 1)
+	defer clean()
+
+	addr := mustUnixAddrToHTTPAddr(c, cfgs[0].ClientUrls+apiPrefix+"/api/v1/config/label-property")
+	getLabelProperty := func() server.LabelPropertyConfig {
+		resp, err := s.hc.Get(addr)
+		c.Assert(err, IsNil)
+		defer resp.Body.Close()
+		cfg := server.LabelPropertyConfig{}
+		c.Assert(json.NewDecoder(resp.Body).Decode(&cfg), IsNil)
+		return cfg
+	}
+	postLabelProperty := func(data string) int {
+		resp, err := s.hc.Post(addr, "application/json", strings.NewReader(data))
+		c.Assert(err, IsNil)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	c.Assert(getLabelProperty(), HasLen, 0)
+
+	c.Assert(postLabelProperty(`{"type": "reject-leader", "action": "set", "label-key": "zone", "label-value": "z1"}`), Equals, http.StatusOK)
+	c.Assert(postLabelProperty(`{"type": "slow-store", "action": "set", "label-key": "host", "label-value": "h1"}`), Equals, http.StatusOK)
+	c.Assert(getLabelProperty(), DeepEquals, server.LabelPropertyConfig{
+		server.RejectLeader: {{Key: "zone", Value: "z1"}},
+		server.SlowStore:    {{Key: "host", Value: "h1"}},
+	})
+
+	c.Assert(postLabelProperty(`{"type": "reject-leader", "action": "delete", "label-key": "zone", "label-value": "z1"}`), Equals, http.StatusOK)
+	c.Assert(getLabelProperty(), DeepEquals, server.LabelPropertyConfig{
+		server.SlowStore: {{Key: "host", Value: "h1"}},
+	})
+
+	// Unknown types, actions and empty labels are rejected.
+	c.Assert(postLabelProperty(`{"type": "unknown", "action": "set", "label-key": "zone", "label-value": "z1"}`), Equals, http.StatusBadRequest)
+	c.Assert(postLabelProperty(`{"type": "slow-store", "action": "unknown", "label-key": "zone", "label-value": "z1"}`), Equals, http.StatusBadRequest)
+	c.Assert(postLabelProperty(`{"type": "slow-store", "action": "set", "label-key": "", "label-value": "z1"}`), Equals, http.StatusBadRequest)
+}
+
+func (s *testConfigSuite) TestConfigDiff(c *C) {
+	cfgs, _, clean := mustNewCluster(c, 2)
+	defer clean()
+
+	addr := mustUnixAddrToHTTPAddr(c, cfgs[0].ClientUrls+apiPrefix+"/api/v1/config")
+	resp, err := s.hc.Post(addr+"/replication", "application/json", strings.NewReader(`{"max-replicas": 5}`))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+
+	resp, err = s.hc.Get(addr + "/diff")
+	c.Assert(err, IsNil)
+	diff := &configDiff{}
+	c.Assert(json.NewDecoder(resp.Body).Decode(diff), IsNil)
+	resp.Body.Close()
+	var maxReplicas *server.ConfigDiffItem
+	for _, item := range diff.Default {
+		if item.Key == "replication.max-replicas" {
+			maxReplicas = item
+		}
+	}
+	c.Assert(maxReplicas, NotNil)
+	c.Assert(maxReplicas.Value, Equals, float64(5))
+	// The replication config of the follower is not compared.
+	c.Assert(diff.Members, HasLen, 1)
+	for _, items := range diff.Members {
+		c.Assert(items, HasLen, 0)
+	}
+	c.Assert(diff.Errors, HasLen, 0)
+
+	// The local config is served by the member itself.
+	for _, cfg := range cfgs {
+		resp, err = s.hc.Get(mustUnixAddrToHTTPAddr(c, cfg.ClientUrls+apiPrefix+"/api/v1/config/local"))
+		c.Assert(err, IsNil)
+		local := &server.Config{}
+		c.Assert(json.NewDecoder(resp.Body).Decode(local), IsNil)
+		resp.Body.Close()
+		c.Assert(local.Name, Equals, cfg.Name)
 	}
 }
@@ -0,0 +1,78 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/juju/errors"
+	"github.com/pingcap/pd/server"
+	"github.com/unrolled/render"
+)
+
+// encryptionHandler serves the data keys to the stores. The apis always
+// require authentication if api-auth is enabled, even the read-only ones.
+type encryptionHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newEncryptionHandler(svr *server.Server, rd *render.Render) *encryptionHandler {
+	return &encryptionHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+func (h *encryptionHandler) List(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.svr.GetEncryptionKeys()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, keys)
+}
+
+func (h *encryptionHandler) GetCurrent(w http.ResponseWriter, r *http.Request) {
+	key, err := h.svr.GetCurrentEncryptionKey()
+	h.respond(w, key, err)
+}
+
+func (h *encryptionHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	key, err := h.svr.GetEncryptionKey(id)
+	h.respond(w, key, err)
+}
+
+func (h *encryptionHandler) Rotate(w http.ResponseWriter, r *http.Request) {
+	key, err := h.svr.RotateEncryptionKey()
+	h.respond(w, key, err)
+}
+
+func (h *encryptionHandler) respond(w http.ResponseWriter, key *server.EncryptionKey, err error) {
+	switch errors.Cause(err) {
+	case nil:
+		h.rd.JSON(w, http.StatusOK, key)
+	case server.ErrEncryptionKeyNotFound:
+		h.rd.JSON(w, http.StatusNotFound, err.Error())
+	default:
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+	}
+}
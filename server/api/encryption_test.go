@@ -0,0 +1,98 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/server"
+)
+
+var _ = Suite(&testEncryptionSuite{})
+
+type testEncryptionSuite struct {
+	hc *http.Client
+}
+
+func (s *testEncryptionSuite) SetUpSuite(c *C) {
+	s.hc = newUnixSocketClient()
+}
+
+func (s *testEncryptionSuite) do(c *C, method, url string, data interface{}) int {
+	req, err := http.NewRequest(method, url, nil)
+	c.Assert(err, IsNil)
+	req.Header.Set("Authorization", "Bearer t1")
+	resp, err := s.hc.Do(req)
+	c.Assert(err, IsNil)
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	c.Assert(readJSON(resp.Body, data), IsNil)
+	return resp.StatusCode
+}
+
+func (s *testEncryptionSuite) TestEncryptionKeys(c *C) {
+	f, err := ioutil.TempFile("", "pd_master_key")
+	c.Assert(err, IsNil)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(strings.Repeat("ab", 32))
+	c.Assert(err, IsNil)
+	f.Close()
+
+	cfg := server.NewTestSingleConfig()
+	cfg.APIAuth.Tokens = []string{"t1"}
+	cfg.Encryption.MasterKeyPath = f.Name()
+	svr, cleanup := mustNewServerWithCfg(c, cfg)
+	defer cleanup()
+
+	addr := mustUnixAddrToHTTPAddr(c, svr.GetAddr()) + apiPrefix + "/api/v1/encryption/keys"
+
+	// The data keys always require authentication.
+	resp, err := s.hc.Get(addr + "/current")
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusUnauthorized)
+
+	// The leader creates the first key.
+	current := &server.EncryptionKey{}
+	for i := 0; i < 50; i++ {
+		if s.do(c, "GET", addr+"/current", current) == http.StatusOK {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	c.Assert(current.Key, HasLen, 32)
+
+	rotated := &server.EncryptionKey{}
+	c.Assert(s.do(c, "POST", addr+"/rotate", rotated), Equals, http.StatusOK)
+	c.Assert(rotated.ID, Greater, current.ID)
+	c.Assert(rotated.Key, IsNil)
+
+	old := &server.EncryptionKey{}
+	c.Assert(s.do(c, "GET", fmt.Sprintf("%s/%d", addr, current.ID), old), Equals, http.StatusOK)
+	c.Assert(old, DeepEquals, current)
+	c.Assert(s.do(c, "GET", fmt.Sprintf("%s/%d", addr, rotated.ID+1), nil), Equals, http.StatusNotFound)
+
+	var keys []*server.EncryptionKey
+	c.Assert(s.do(c, "GET", addr, &keys), Equals, http.StatusOK)
+	c.Assert(keys, HasLen, 2)
+	c.Assert(keys[1].ID, Equals, rotated.ID)
+}
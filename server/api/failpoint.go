@@ -0,0 +1,63 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pingcap/pd/pkg/failpoint"
+	"github.com/unrolled/render"
+)
+
+type failpointHandler struct {
+	rd *render.Render
+}
+
+func newFailpointHandler(rd *render.Render) *failpointHandler {
+	return &failpointHandler{
+		rd: rd,
+	}
+}
+
+// List returns the enabled failpoints of the member.
+func (h *failpointHandler) List(w http.ResponseWriter, r *http.Request) {
+	h.rd.JSON(w, http.StatusOK, failpoint.All())
+}
+
+// Post enables the failpoint by the term, e.g. {"term": "1*return(err)"}.
+func (h *failpointHandler) Post(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Term string `json:"term"`
+	}
+	if err := readJSONStrict(r.Body, &input); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := failpoint.Enable(mux.Vars(r)["name"], input.Term); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+// Delete disables the failpoint.
+func (h *failpointHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if !failpoint.Disable(name) {
+		h.rd.JSON(w, http.StatusNotFound, "failpoint "+name+" is not enabled")
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
@@ -0,0 +1,96 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/pkg/failpoint"
+	"github.com/pingcap/pd/server"
+)
+
+var _ = Suite(&testFailpointSuite{})
+
+type testFailpointSuite struct {
+	cfg *server.Config
+	svr *server.Server
+	url string
+	hc  *http.Client
+}
+
+func (s *testFailpointSuite) SetUpSuite(c *C) {
+	s.cfg = server.NewTestSingleConfig()
+	s.cfg.EnableFailpoint = true
+	s.svr = server.CreateServer(s.cfg)
+	c.Assert(s.svr.StartEtcd(NewHandler(s.svr)), IsNil)
+	go s.svr.Run()
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	httpAddr := mustUnixAddrToHTTPAddr(c, s.svr.GetAddr())
+	s.url = fmt.Sprintf("%s%s/api/v1/failpoints", httpAddr, apiPrefix)
+	s.hc = newUnixSocketClient()
+}
+
+func (s *testFailpointSuite) TearDownSuite(c *C) {
+	s.svr.Close()
+	cleanServer(s.cfg)
+}
+
+func (s *testFailpointSuite) TestFailpoint(c *C) {
+	name := "test-failpoint"
+	resp, err := s.hc.Post(s.url+"/"+name, "application/json", strings.NewReader(`{"term": "1*return(err)"}`))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+
+	resp, err = s.hc.Get(s.url)
+	c.Assert(err, IsNil)
+	var list []*failpoint.Failpoint
+	c.Assert(json.NewDecoder(resp.Body).Decode(&list), IsNil)
+	resp.Body.Close()
+	c.Assert(list, DeepEquals, []*failpoint.Failpoint{{Name: name, Term: "1*return(err)", Count: 1}})
+	c.Assert(failpoint.Error(name), NotNil)
+	c.Assert(failpoint.Error(name), IsNil)
+
+	for _, body := range []string{`{"term": "fail"}`, `{"value": "return"}`} {
+		resp, err = s.hc.Post(s.url+"/"+name, "application/json", strings.NewReader(body))
+		c.Assert(err, IsNil)
+		resp.Body.Close()
+		c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+	}
+
+	for _, code := range []int{http.StatusOK, http.StatusNotFound} {
+		req, err := http.NewRequest("DELETE", s.url+"/"+name, nil)
+		c.Assert(err, IsNil)
+		resp, err = s.hc.Do(req)
+		c.Assert(err, IsNil)
+		resp.Body.Close()
+		c.Assert(resp.StatusCode, Equals, code)
+	}
+}
+
+func (s *testFailpointSuite) TestDisabled(c *C) {
+	svr, cleanup := mustNewServer(c)
+	defer cleanup()
+
+	url := mustUnixAddrToHTTPAddr(c, svr.GetAddr()) + apiPrefix + "/api/v1/failpoints"
+	resp, err := s.hc.Get(url)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusNotFound)
+}
@@ -0,0 +1,64 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/juju/errors"
+	"github.com/pingcap/pd/server"
+	"github.com/unrolled/render"
+)
+
+type featureGateHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newFeatureGateHandler(svr *server.Server, rd *render.Render) *featureGateHandler {
+	return &featureGateHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+func (h *featureGateHandler) List(w http.ResponseWriter, r *http.Request) {
+	h.rd.JSON(w, http.StatusOK, h.svr.GetFeatureGates())
+}
+
+// Post enables or disables the feature gate by {"enabled": true|false}.
+func (h *featureGateHandler) Post(w http.ResponseWriter, r *http.Request) {
+	input := make(map[string]bool)
+	if err := readJSON(r.Body, &input); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	enabled, ok := input["enabled"]
+	if !ok {
+		h.rd.JSON(w, http.StatusBadRequest, "missing enabled")
+		return
+	}
+
+	err := h.svr.SetFeatureGate(mux.Vars(r)["name"], enabled)
+	if errors.Cause(err) == server.ErrUnknownFeatureGate {
+		h.rd.JSON(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
@@ -0,0 +1,78 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/server"
+)
+
+var _ = Suite(&testFeatureGateSuite{})
+
+type testFeatureGateSuite struct {
+	hc        *http.Client
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testFeatureGateSuite) SetUpSuite(c *C) {
+	s.hc = newUnixSocketClient()
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+	s.urlPrefix = mustUnixAddrToHTTPAddr(c, s.svr.GetAddr()+apiPrefix+"/api/v1")
+}
+
+func (s *testFeatureGateSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testFeatureGateSuite) getStatus(c *C) *clusterStatus {
+	resp, err := s.hc.Get(s.urlPrefix + "/cluster/status")
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	status := &clusterStatus{}
+	c.Assert(json.NewDecoder(resp.Body).Decode(status), IsNil)
+	return status
+}
+
+func (s *testFeatureGateSuite) postFeatureGate(c *C, name, data string) int {
+	resp, err := s.hc.Post(s.urlPrefix+"/feature-gates/"+name, "application/json", strings.NewReader(data))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func (s *testFeatureGateSuite) TestFeatureGates(c *C) {
+	status := s.getStatus(c)
+	c.Assert(status.Bootstrapped, IsFalse)
+	c.Assert(status.FeatureGates[server.FeatureLocationReplacement], IsTrue)
+
+	c.Assert(s.postFeatureGate(c, server.FeatureLocationReplacement, `{"enabled": false}`), Equals, http.StatusOK)
+	c.Assert(s.getStatus(c).FeatureGates[server.FeatureLocationReplacement], IsFalse)
+
+	resp, err := s.hc.Get(s.urlPrefix + "/feature-gates")
+	c.Assert(err, IsNil)
+	gates := server.FeatureGates{}
+	c.Assert(json.NewDecoder(resp.Body).Decode(&gates), IsNil)
+	resp.Body.Close()
+	c.Assert(gates, DeepEquals, s.svr.GetFeatureGates())
+
+	c.Assert(s.postFeatureGate(c, "unknown", `{"enabled": true}`), Equals, http.StatusNotFound)
+	c.Assert(s.postFeatureGate(c, server.FeatureLocationReplacement, `{}`), Equals, http.StatusBadRequest)
+}
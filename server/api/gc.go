@@ -0,0 +1,114 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pingcap/pd/server"
+	"github.com/unrolled/render"
+)
+
+type gcSafePointHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newGCSafePointHandler(svr *server.Server, rd *render.Render) *gcSafePointHandler {
+	return &gcSafePointHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+type gcSafePoint struct {
+	SafePoint uint64 `json:"safe_point"`
+}
+
+func (h *gcSafePointHandler) Get(w http.ResponseWriter, r *http.Request) {
+	safePoint, err := h.svr.GetGCSafePoint()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, &gcSafePoint{SafePoint: safePoint})
+}
+
+func (h *gcSafePointHandler) Post(w http.ResponseWriter, r *http.Request) {
+	input := &gcSafePoint{}
+	if err := readJSON(r.Body, input); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.svr.UpdateGCSafePoint(input.SafePoint); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+type serviceSafePointHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newServiceSafePointHandler(svr *server.Server, rd *render.Render) *serviceSafePointHandler {
+	return &serviceSafePointHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+type serviceSafePointInput struct {
+	// TTL is in seconds, the safe point is removed if it is not positive.
+	TTL       int64  `json:"ttl"`
+	SafePoint uint64 `json:"safe_point"`
+}
+
+type minServiceSafePoint struct {
+	MinSafePoint uint64 `json:"min_safe_point"`
+}
+
+func (h *serviceSafePointHandler) List(w http.ResponseWriter, r *http.Request) {
+	ssps, err := h.svr.GetServiceSafePoints()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, ssps)
+}
+
+func (h *serviceSafePointHandler) Post(w http.ResponseWriter, r *http.Request) {
+	input := &serviceSafePointInput{}
+	if err := readJSON(r.Body, input); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.update(w, mux.Vars(r)["service_id"], input.TTL, input.SafePoint)
+}
+
+func (h *serviceSafePointHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	h.update(w, mux.Vars(r)["service_id"], 0, 0)
+}
+
+func (h *serviceSafePointHandler) update(w http.ResponseWriter, serviceID string, ttl int64, safePoint uint64) {
+	min, err := h.svr.UpdateServiceSafePoint(serviceID, ttl, safePoint)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, &minServiceSafePoint{MinSafePoint: min})
+}
@@ -0,0 +1,112 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/server"
+)
+
+var _ = Suite(&testGCSuite{})
+
+type testGCSuite struct {
+	hc *http.Client
+}
+
+func (s *testGCSuite) SetUpSuite(c *C) {
+	s.hc = newUnixSocketClient()
+}
+
+func (s *testGCSuite) getSafePoint(c *C, addr string) uint64 {
+	resp, err := s.hc.Get(addr)
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+
+	safePoint := &gcSafePoint{}
+	c.Assert(json.NewDecoder(resp.Body).Decode(safePoint), IsNil)
+	return safePoint.SafePoint
+}
+
+func (s *testGCSuite) updateSafePoint(c *C, addr string, safePoint uint64) int {
+	data, err := json.Marshal(&gcSafePoint{SafePoint: safePoint})
+	c.Assert(err, IsNil)
+	resp, err := s.hc.Post(addr, "application/json", bytes.NewBuffer(data))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func (s *testGCSuite) getServiceSafePoints(c *C, addr string) []*server.ServiceSafePoint {
+	resp, err := s.hc.Get(addr)
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+
+	var ssps []*server.ServiceSafePoint
+	c.Assert(json.NewDecoder(resp.Body).Decode(&ssps), IsNil)
+	return ssps
+}
+
+func (s *testGCSuite) TestGCSafePoint(c *C) {
+	cfgs, _, clean := mustNewCluster(c, 1)
+	defer clean()
+
+	parts := []string{cfgs[0].ClientUrls, apiPrefix, "/api/v1/gc/safepoint"}
+	addr := mustUnixAddrToHTTPAddr(c, strings.Join(parts, ""))
+
+	c.Assert(s.getSafePoint(c, addr), Equals, uint64(0))
+	c.Assert(s.updateSafePoint(c, addr, 100), Equals, http.StatusOK)
+	c.Assert(s.getSafePoint(c, addr), Equals, uint64(100))
+
+	// Safe point can't go backward.
+	c.Assert(s.updateSafePoint(c, addr, 99), Equals, http.StatusInternalServerError)
+	c.Assert(s.getSafePoint(c, addr), Equals, uint64(100))
+}
+
+func (s *testGCSuite) TestServiceSafePoint(c *C) {
+	cfgs, _, clean := mustNewCluster(c, 1)
+	defer clean()
+
+	parts := []string{cfgs[0].ClientUrls, apiPrefix, "/api/v1/gc/safepoint/service"}
+	addr := mustUnixAddrToHTTPAddr(c, strings.Join(parts, ""))
+
+	data, err := json.Marshal(&serviceSafePointInput{TTL: 10, SafePoint: 100})
+	c.Assert(err, IsNil)
+	resp, err := s.hc.Post(addr+"/a", "application/json", bytes.NewBuffer(data))
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	min := &minServiceSafePoint{}
+	c.Assert(json.NewDecoder(resp.Body).Decode(min), IsNil)
+	resp.Body.Close()
+	c.Assert(min.MinSafePoint, Equals, uint64(100))
+
+	ssps := s.getServiceSafePoints(c, addr)
+	c.Assert(ssps, HasLen, 1)
+	c.Assert(ssps[0].ServiceID, Equals, "a")
+	c.Assert(ssps[0].SafePoint, Equals, uint64(100))
+
+	req, err := http.NewRequest(http.MethodDelete, addr+"/a", nil)
+	c.Assert(err, IsNil)
+	resp, err = s.hc.Do(req)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	c.Assert(s.getServiceSafePoints(c, addr), HasLen, 0)
+}
@@ -0,0 +1,44 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/pingcap/pd/server"
+	"github.com/unrolled/render"
+)
+
+type idHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newIDHandler(svr *server.Server, rd *render.Render) *idHandler {
+	return &idHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+// Get returns the step, the last allocated ID and the end of the reserved
+// IDs of the leader.
+func (h *idHandler) Get(w http.ResponseWriter, r *http.Request) {
+	status, err := h.svr.GetIDAllocStatus()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, status)
+}
@@ -0,0 +1,56 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/server"
+)
+
+var _ = Suite(&testIDSuite{})
+
+type testIDSuite struct {
+	hc        *http.Client
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testIDSuite) SetUpSuite(c *C) {
+	s.hc = newUnixSocketClient()
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+	s.urlPrefix = mustUnixAddrToHTTPAddr(c, s.svr.GetAddr()+apiPrefix+"/api/v1")
+}
+
+func (s *testIDSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testIDSuite) TestGetStatus(c *C) {
+	mustBootstrapCluster(c, s.svr)
+
+	resp, err := s.hc.Get(s.urlPrefix + "/id")
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	status := &server.IDAllocStatus{}
+	c.Assert(json.NewDecoder(resp.Body).Decode(status), IsNil)
+	c.Assert(status.Step, Equals, uint64(1000))
+	c.Assert(status.End, GreaterEqual, status.Last)
+	c.Assert(status.End-status.Last, Less, status.Step)
+}
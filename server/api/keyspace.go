@@ -0,0 +1,80 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/juju/errors"
+	"github.com/pingcap/pd/server"
+	"github.com/unrolled/render"
+)
+
+type keyspaceHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newKeyspaceHandler(svr *server.Server, rd *render.Render) *keyspaceHandler {
+	return &keyspaceHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+func (h *keyspaceHandler) List(w http.ResponseWriter, r *http.Request) {
+	keyspaces, err := h.svr.GetKeyspaces()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, keyspaces)
+}
+
+func (h *keyspaceHandler) Get(w http.ResponseWriter, r *http.Request) {
+	keyspace, err := h.svr.GetKeyspace(mux.Vars(r)["name"])
+	h.respond(w, keyspace, err)
+}
+
+func (h *keyspaceHandler) Post(w http.ResponseWriter, r *http.Request) {
+	input := &server.Keyspace{}
+	if err := readJSON(r.Body, input); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	keyspace, err := h.svr.CreateKeyspace(input.Name)
+	h.respond(w, keyspace, err)
+}
+
+func (h *keyspaceHandler) SetState(w http.ResponseWriter, r *http.Request) {
+	input := &server.Keyspace{}
+	if err := readJSON(r.Body, input); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	keyspace, err := h.svr.UpdateKeyspaceState(mux.Vars(r)["name"], input.State)
+	h.respond(w, keyspace, err)
+}
+
+func (h *keyspaceHandler) respond(w http.ResponseWriter, keyspace *server.Keyspace, err error) {
+	switch errors.Cause(err) {
+	case nil:
+		h.rd.JSON(w, http.StatusOK, keyspace)
+	case server.ErrKeyspaceNotFound:
+		h.rd.JSON(w, http.StatusNotFound, err.Error())
+	default:
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/server"
+)
+
+var _ = Suite(&testKeyspaceSuite{})
+
+type testKeyspaceSuite struct {
+	hc        *http.Client
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testKeyspaceSuite) SetUpSuite(c *C) {
+	s.hc = newUnixSocketClient()
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+	s.urlPrefix = mustUnixAddrToHTTPAddr(c, s.svr.GetAddr()+apiPrefix+"/api/v1/keyspaces")
+}
+
+func (s *testKeyspaceSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+// post returns the status code and the keyspace in the response if it is OK.
+func (s *testKeyspaceSuite) post(c *C, url string, input interface{}) (int, *server.Keyspace) {
+	data, err := json.Marshal(input)
+	c.Assert(err, IsNil)
+	resp, err := s.hc.Post(url, "application/json", bytes.NewBuffer(data))
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, nil
+	}
+	keyspace := &server.Keyspace{}
+	c.Assert(json.NewDecoder(resp.Body).Decode(keyspace), IsNil)
+	return resp.StatusCode, keyspace
+}
+
+func (s *testKeyspaceSuite) TestKeyspace(c *C) {
+	code, keyspace := s.post(c, s.urlPrefix, map[string]string{"name": "ks1"})
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(keyspace.Name, Equals, "ks1")
+	c.Assert(keyspace.State, Equals, server.KeyspaceEnabled)
+	code, _ = s.post(c, s.urlPrefix, map[string]string{"name": "ks1"})
+	c.Assert(code, Equals, http.StatusInternalServerError)
+
+	code, keyspace = s.post(c, s.urlPrefix+"/ks1/state", map[string]string{"state": server.KeyspaceDisabled})
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(keyspace.State, Equals, server.KeyspaceDisabled)
+	code, _ = s.post(c, s.urlPrefix+"/ks2/state", map[string]string{"state": server.KeyspaceDisabled})
+	c.Assert(code, Equals, http.StatusNotFound)
+
+	resp, err := s.hc.Get(s.urlPrefix + "/ks1")
+	c.Assert(err, IsNil)
+	got := &server.Keyspace{}
+	c.Assert(json.NewDecoder(resp.Body).Decode(got), IsNil)
+	resp.Body.Close()
+	c.Assert(got, DeepEquals, keyspace)
+	resp, err = s.hc.Get(s.urlPrefix + "/ks2")
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusNotFound)
+
+	resp, err = s.hc.Get(s.urlPrefix)
+	c.Assert(err, IsNil)
+	var keyspaces []*server.Keyspace
+	c.Assert(json.NewDecoder(resp.Body).Decode(&keyspaces), IsNil)
+	resp.Body.Close()
+	c.Assert(keyspaces, DeepEquals, []*server.Keyspace{keyspace})
+}
@@ -16,6 +16,7 @@ package api
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -24,9 +25,14 @@ import (
 	"github.com/pingcap/pd/pkg/etcdutil"
 	"github.com/pingcap/pd/server"
 	"github.com/unrolled/render"
+	"golang.org/x/net/context"
 )
 
-const defaultDialTimeout = 5 * time.Second
+const (
+	defaultDialTimeout        = 5 * time.Second
+	defaultLeaderWatchTimeout = 30 * time.Second
+	maxLeaderWatchTimeout     = 5 * time.Minute
+)
 
 type memberListHandler struct {
 	svr *server.Server
@@ -40,15 +46,51 @@ func newMemberListHandler(svr *server.Server, rd *render.Render) *memberListHand
 	}
 }
 
+// memberInfo is a pd member with its leadership state.
+type memberInfo struct {
+	*pdpb.PDMember
+	MemberID       uint64 `json:"member_id"`
+	IsLeader       bool   `json:"is_leader"`
+	IsEtcdLeader   bool   `json:"is_etcd_leader"`
+	LeaderPriority int    `json:"leader_priority"`
+}
+
 func (h *memberListHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	client := h.svr.GetClient()
 
-	members, err := server.GetPDMembers(client)
+	listResp, err := etcdutil.ListEtcdMembers(client)
 	if err != nil {
 		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	ret := make(map[string][]*pdpb.PDMember)
+	leader, err := h.svr.GetLeader()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	etcdLeader := h.svr.GetEtcdLeader()
+
+	members := make([]*memberInfo, 0, len(listResp.Members))
+	for _, m := range listResp.Members {
+		priority, err := h.svr.GetMemberLeaderPriority(m.ID)
+		if err != nil {
+			h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		name := m.Name
+		members = append(members, &memberInfo{
+			PDMember: &pdpb.PDMember{
+				Name:       &name,
+				ClientUrls: m.ClientURLs,
+				PeerUrls:   m.PeerURLs,
+			},
+			MemberID:       m.ID,
+			IsLeader:       m.ID == leader.GetId(),
+			IsEtcdLeader:   m.ID == etcdLeader,
+			LeaderPriority: priority,
+		})
+	}
+	ret := make(map[string][]*memberInfo)
 	ret["members"] = members
 	h.rd.JSON(w, http.StatusOK, ret)
 }
@@ -65,25 +107,42 @@ func newMemberDeleteHandler(svr *server.Server, rd *render.Render) *memberDelete
 	}
 }
 
+// ServeHTTP removes the member by its name, or by its id if the route has
+// the id variable.
 func (h *memberDeleteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	client := h.svr.GetClient()
 
 	// step 1. get etcd id
 	var id uint64
-	name := (mux.Vars(r))["name"]
+	vars := mux.Vars(r)
+	name, byID := vars["name"], false
+	if idStr, ok := vars["id"]; ok {
+		var err error
+		id, err = strconv.ParseUint(idStr, 10, 64)
+		if err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		byID = true
+	}
 	listResp, err := etcdutil.ListEtcdMembers(client)
 	if err != nil {
 		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	found := false
 	for _, m := range listResp.Members {
-		if name == m.Name {
-			id = m.ID
+		if (byID && id == m.ID) || (!byID && name == m.Name) {
+			id, name, found = m.ID, m.Name, true
 			break
 		}
 	}
-	if id == 0 {
-		h.rd.JSON(w, http.StatusNotFound, fmt.Sprintf("not found, pd: %s", name))
+	if !found {
+		if byID {
+			h.rd.JSON(w, http.StatusNotFound, fmt.Sprintf("not found, pd id: %d", id))
+		} else {
+			h.rd.JSON(w, http.StatusNotFound, fmt.Sprintf("not found, pd: %s", name))
+		}
 		return
 	}
 
@@ -134,6 +193,59 @@ func (h *leaderHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, ret)
 }
 
+// Resign resigns the leadership, a random member becomes the next leader.
+func (h *leaderHandler) Resign(w http.ResponseWriter, r *http.Request) {
+	if err := h.svr.ResignLeader(""); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+// Transfer transfers the leadership to the member named next_leader.
+func (h *leaderHandler) Transfer(w http.ResponseWriter, r *http.Request) {
+	if err := h.svr.ResignLeader(mux.Vars(r)["next_leader"]); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+// Watch waits for the leader to change after the revision in the query, and
+// returns the leader and the members. It is served by the member itself, it
+// returns at once if the revision is not set, or after the timeout in the
+// query, which is 30s by default.
+func (h *leaderHandler) Watch(w http.ResponseWriter, r *http.Request) {
+	var revision int64
+	if revisionStr := r.URL.Query().Get("revision"); revisionStr != "" {
+		var err error
+		if revision, err = strconv.ParseInt(revisionStr, 10, 64); err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	timeout := defaultLeaderWatchTimeout
+	if timeoutStr := r.URL.Query().Get("timeout"); timeoutStr != "" {
+		var err error
+		if timeout, err = time.ParseDuration(timeoutStr); err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if timeout > maxLeaderWatchTimeout {
+			timeout = maxLeaderWatchTimeout
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(h.svr.GetClient().Ctx(), timeout)
+	defer cancel()
+	result, err := h.svr.WatchLeader(ctx, revision)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, result)
+}
+
 func (h *leaderHandler) getNameByID(id uint64) (string, error) {
 	client := h.svr.GetClient()
 	listResp, err := etcdutil.ListEtcdMembers(client)
@@ -153,3 +265,53 @@ func (h *leaderHandler) getNameByID(id uint64) (string, error) {
 	}
 	return name, nil
 }
+
+type memberLeaderPriorityInput struct {
+	LeaderPriority int `json:"leader-priority"`
+}
+
+type memberLeaderPriorityHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newMemberLeaderPriorityHandler(svr *server.Server, rd *render.Render) *memberLeaderPriorityHandler {
+	return &memberLeaderPriorityHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+func (h *memberLeaderPriorityHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	client := h.svr.GetClient()
+
+	var id uint64
+	name := (mux.Vars(r))["name"]
+	listResp, err := etcdutil.ListEtcdMembers(client)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for _, m := range listResp.Members {
+		if name == m.Name {
+			id = m.ID
+			break
+		}
+	}
+	if id == 0 {
+		h.rd.JSON(w, http.StatusNotFound, fmt.Sprintf("not found, pd: %s", name))
+		return
+	}
+
+	input := &memberLeaderPriorityInput{}
+	if err = readJSON(r.Body, input); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err = h.svr.SetMemberLeaderPriority(id, input.LeaderPriority); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
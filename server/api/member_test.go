@@ -24,7 +24,6 @@ import (
 	"time"
 
 	. "github.com/pingcap/check"
-	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/pingcap/pd/server"
 )
 
@@ -49,11 +48,20 @@ func relaxEqualStings(c *C, a, b []string) {
 }
 
 func checkListResponse(c *C, body []byte, cfgs []*server.Config) {
-	got := make(map[string][]*pdpb.PDMember)
+	got := make(map[string][]*memberInfo)
 	json.Unmarshal(body, &got)
 
 	c.Assert(len(got["members"]), Equals, len(cfgs))
 
+	leaders := 0
+	for _, memb := range got["members"] {
+		c.Assert(memb.MemberID, Not(Equals), uint64(0))
+		if memb.IsLeader {
+			leaders++
+		}
+	}
+	c.Assert(leaders, Equals, 1)
+
 	for _, memb := range got["members"] {
 		for _, cfg := range cfgs {
 			if *memb.Name != cfg.Name {
@@ -162,6 +170,30 @@ func (s *testMemberAPISuite) TestMemberDelete(c *C) {
 	}
 }
 
+func (s *testMemberAPISuite) TestMemberDeleteByID(c *C) {
+	cfgs, _, clean := mustNewCluster(c, 1)
+	defer clean()
+
+	var table = []struct {
+		id     string
+		status int
+	}{
+		{id: fmt.Sprintf("%d", rand.Int63()), status: http.StatusNotFound},
+		{id: "pd1", status: http.StatusBadRequest},
+	}
+
+	for _, t := range table {
+		parts := []string{cfgs[0].ClientUrls, apiPrefix, "/api/v1/members/id/", t.id}
+		addr := mustUnixAddrToHTTPAddr(c, strings.Join(parts, ""))
+		req, err := http.NewRequest("DELETE", addr, nil)
+		c.Assert(err, IsNil)
+		resp, err := s.hc.Do(req)
+		c.Assert(err, IsNil)
+		resp.Body.Close()
+		c.Assert(resp.StatusCode, Equals, t.status)
+	}
+}
+
 func (s *testMemberAPISuite) TestMemberLeader(c *C) {
 	cfgs, svrs, clean := mustNewCluster(c, 3)
 	defer clean()
@@ -183,3 +215,60 @@ func (s *testMemberAPISuite) TestMemberLeader(c *C) {
 	c.Assert(got.Addr, Equals, leader.GetAddr())
 	c.Assert(got.ID, Equals, leader.GetId())
 }
+
+func (s *testMemberAPISuite) TestMemberLeaderWatch(c *C) {
+	cfgs, svrs, clean := mustNewCluster(c, 3)
+	defer clean()
+
+	leader, err := svrs[0].GetLeader()
+	c.Assert(err, IsNil)
+
+	watch := func(query string) *server.LeaderWatchResult {
+		parts := []string{cfgs[rand.Intn(len(cfgs))].ClientUrls, apiPrefix, "/api/v1/leader/watch?", query}
+		addr := mustUnixAddrToHTTPAddr(c, strings.Join(parts, ""))
+		resp, err := s.hc.Get(addr)
+		c.Assert(err, IsNil)
+		defer resp.Body.Close()
+		c.Assert(resp.StatusCode, Equals, http.StatusOK)
+		got := &server.LeaderWatchResult{}
+		c.Assert(json.NewDecoder(resp.Body).Decode(got), IsNil)
+		return got
+	}
+
+	// The current leader is returned without waiting if the revision is 0.
+	got := watch("")
+	c.Assert(got.Revision, Greater, int64(0))
+	c.Assert(got.Leader.GetAddr(), Equals, leader.GetAddr())
+	c.Assert(got.Members, HasLen, 3)
+
+	// The leader is returned after the timeout if it is not changed.
+	start := time.Now()
+	got = watch(fmt.Sprintf("revision=%d&timeout=500ms", got.Revision))
+	c.Assert(time.Since(start), GreaterEqual, 500*time.Millisecond)
+	c.Assert(got.Leader.GetAddr(), Equals, leader.GetAddr())
+}
+
+func (s *testMemberAPISuite) TestMemberLeaderPriority(c *C) {
+	cfgs, svrs, clean := mustNewCluster(c, 3)
+	defer clean()
+
+	target := svrs[rand.Intn(len(svrs))]
+	parts := []string{cfgs[rand.Intn(len(cfgs))].ClientUrls, apiPrefix, "/api/v1/members/", target.Name()}
+	addr := mustUnixAddrToHTTPAddr(c, strings.Join(parts, ""))
+	resp, err := s.hc.Post(addr, "application/json", strings.NewReader(`{"leader-priority": 5}`))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+
+	priority, err := target.GetMemberLeaderPriority(target.ID())
+	c.Assert(err, IsNil)
+	c.Assert(priority, Equals, 5)
+
+	// Unknown member.
+	parts = []string{cfgs[0].ClientUrls, apiPrefix, "/api/v1/members/", fmt.Sprintf("test-%d", rand.Int63())}
+	addr = mustUnixAddrToHTTPAddr(c, strings.Join(parts, ""))
+	resp, err = s.hc.Post(addr, "application/json", strings.NewReader(`{"leader-priority": 5}`))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusNotFound)
+}
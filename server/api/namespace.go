@@ -0,0 +1,146 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/juju/errors"
+	"github.com/pingcap/pd/server"
+	"github.com/unrolled/render"
+)
+
+type namespaceHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newNamespaceHandler(svr *server.Server, rd *render.Render) *namespaceHandler {
+	return &namespaceHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+func (h *namespaceHandler) List(w http.ResponseWriter, r *http.Request) {
+	namespaces, err := h.svr.GetNamespaces()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, namespaces)
+}
+
+func (h *namespaceHandler) Post(w http.ResponseWriter, r *http.Request) {
+	input := &server.Namespace{}
+	if err := readJSON(r.Body, input); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.respond(w, h.svr.CreateNamespace(input.Name))
+}
+
+func (h *namespaceHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	h.respond(w, h.svr.DeleteNamespace(mux.Vars(r)["name"]))
+}
+
+func (h *namespaceHandler) AddTable(w http.ResponseWriter, r *http.Request) {
+	input := make(map[string]int64)
+	if err := readJSON(r.Body, &input); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	tableID, ok := input["table_id"]
+	if !ok {
+		h.rd.JSON(w, http.StatusBadRequest, "missing table_id")
+		return
+	}
+	h.respond(w, h.svr.AddNamespaceTableID(mux.Vars(r)["name"], tableID))
+}
+
+func (h *namespaceHandler) RemoveTable(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tableID, err := strconv.ParseInt(vars["table_id"], 10, 64)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.respond(w, h.svr.RemoveNamespaceTableID(vars["name"], tableID))
+}
+
+func (h *namespaceHandler) AddKeyRange(w http.ResponseWriter, r *http.Request) {
+	input := &server.KeyRange{}
+	if err := readJSON(r.Body, input); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := input.Validate(); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.respond(w, h.svr.AddNamespaceKeyRange(mux.Vars(r)["name"], input))
+}
+
+// RemoveKeyRange removes the key range by its start key, an empty start key
+// is passed in the query since it can't be a path segment.
+func (h *namespaceHandler) RemoveKeyRange(w http.ResponseWriter, r *http.Request) {
+	h.respond(w, h.svr.RemoveNamespaceKeyRange(mux.Vars(r)["name"], r.URL.Query().Get("start_key")))
+}
+
+func (h *namespaceHandler) AddStore(w http.ResponseWriter, r *http.Request) {
+	input := make(map[string]uint64)
+	if err := readJSON(r.Body, &input); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	storeID, ok := input["store_id"]
+	if !ok {
+		h.rd.JSON(w, http.StatusBadRequest, "missing store_id")
+		return
+	}
+	h.respond(w, h.svr.AddNamespaceStoreID(mux.Vars(r)["name"], storeID))
+}
+
+func (h *namespaceHandler) RemoveStore(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	storeID, err := strconv.ParseUint(vars["store_id"], 10, 64)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.respond(w, h.svr.RemoveNamespaceStoreID(vars["name"], storeID))
+}
+
+// SetConfig replaces the scheduling config of the namespace.
+func (h *namespaceHandler) SetConfig(w http.ResponseWriter, r *http.Request) {
+	input := &server.NamespaceConfig{}
+	if err := readJSON(r.Body, input); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.respond(w, h.svr.SetNamespaceConfig(mux.Vars(r)["name"], input))
+}
+
+func (h *namespaceHandler) respond(w http.ResponseWriter, err error) {
+	switch errors.Cause(err) {
+	case nil:
+		h.rd.JSON(w, http.StatusOK, nil)
+	case server.ErrNamespaceNotFound:
+		h.rd.JSON(w, http.StatusNotFound, err.Error())
+	default:
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+	}
+}
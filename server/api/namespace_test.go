@@ -0,0 +1,104 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/server"
+)
+
+var _ = Suite(&testNamespaceSuite{})
+
+type testNamespaceSuite struct {
+	hc        *http.Client
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testNamespaceSuite) SetUpSuite(c *C) {
+	s.hc = newUnixSocketClient()
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+	s.urlPrefix = mustUnixAddrToHTTPAddr(c, s.svr.GetAddr()+apiPrefix+"/api/v1/namespaces")
+}
+
+func (s *testNamespaceSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testNamespaceSuite) post(c *C, url string, input interface{}) int {
+	data, err := json.Marshal(input)
+	c.Assert(err, IsNil)
+	resp, err := s.hc.Post(url, "application/json", bytes.NewBuffer(data))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func (s *testNamespaceSuite) delete(c *C, url string) int {
+	req, err := http.NewRequest(http.MethodDelete, url, nil)
+	c.Assert(err, IsNil)
+	resp, err := s.hc.Do(req)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func (s *testNamespaceSuite) list(c *C) []*server.Namespace {
+	resp, err := s.hc.Get(s.urlPrefix)
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+
+	var namespaces []*server.Namespace
+	c.Assert(json.NewDecoder(resp.Body).Decode(&namespaces), IsNil)
+	return namespaces
+}
+
+func (s *testNamespaceSuite) TestNamespace(c *C) {
+	c.Assert(s.list(c), HasLen, 0)
+	c.Assert(s.post(c, s.urlPrefix, map[string]string{"name": "ns1"}), Equals, http.StatusOK)
+	c.Assert(s.post(c, s.urlPrefix+"/ns1/tables", map[string]int64{"table_id": 1}), Equals, http.StatusOK)
+	c.Assert(s.post(c, s.urlPrefix+"/ns1/tables", map[string]int64{}), Equals, http.StatusBadRequest)
+	c.Assert(s.post(c, s.urlPrefix+"/ns1/ranges", &server.KeyRange{StartKey: "6d", EndKey: "6e"}), Equals, http.StatusOK)
+	c.Assert(s.post(c, s.urlPrefix+"/ns1/ranges", &server.KeyRange{StartKey: "xx"}), Equals, http.StatusBadRequest)
+	c.Assert(s.post(c, s.urlPrefix+"/ns1/stores", map[string]uint64{"store_id": 1}), Equals, http.StatusOK)
+	c.Assert(s.post(c, s.urlPrefix+"/ns2/stores", map[string]uint64{"store_id": 2}), Equals, http.StatusNotFound)
+	nsConfig := &server.NamespaceConfig{MaxReplicas: 5, DisabledSchedulers: []string{"balance-leader-scheduler"}}
+	c.Assert(s.post(c, s.urlPrefix+"/ns1/config", nsConfig), Equals, http.StatusOK)
+
+	namespaces := s.list(c)
+	c.Assert(namespaces, HasLen, 1)
+	c.Assert(namespaces[0].TableIDs, DeepEquals, []int64{1})
+	c.Assert(namespaces[0].KeyRanges, DeepEquals, []*server.KeyRange{{StartKey: "6d", EndKey: "6e"}})
+	c.Assert(namespaces[0].StoreIDs, DeepEquals, []uint64{1})
+	c.Assert(namespaces[0].NamespaceConfig, DeepEquals, *nsConfig)
+
+	c.Assert(s.delete(c, s.urlPrefix+"/ns1/tables/1"), Equals, http.StatusOK)
+	c.Assert(s.delete(c, s.urlPrefix+"/ns1/ranges?start_key=6d"), Equals, http.StatusOK)
+	c.Assert(s.delete(c, s.urlPrefix+"/ns1/stores/1"), Equals, http.StatusOK)
+	namespaces = s.list(c)
+	c.Assert(namespaces[0].TableIDs, HasLen, 0)
+	c.Assert(namespaces[0].KeyRanges, HasLen, 0)
+	c.Assert(namespaces[0].StoreIDs, HasLen, 0)
+
+	c.Assert(s.delete(c, s.urlPrefix+"/ns1"), Equals, http.StatusOK)
+	c.Assert(s.delete(c, s.urlPrefix+"/ns1"), Equals, http.StatusNotFound)
+	c.Assert(s.list(c), HasLen, 0)
+}
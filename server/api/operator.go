@@ -0,0 +1,159 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/pingcap/pd/server"
+	"github.com/unrolled/render"
+)
+
+type operatorHandler struct {
+	*server.Handler
+	r *render.Render
+}
+
+func newOperatorHandler(handler *server.Handler, r *render.Render) *operatorHandler {
+	return &operatorHandler{
+		Handler: handler,
+		r:       r,
+	}
+}
+
+func (h *operatorHandler) Get(w http.ResponseWriter, r *http.Request) {
+	regionID, err := strconv.ParseUint(mux.Vars(r)["region_id"], 10, 64)
+	if err != nil {
+		h.r.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	op, err := h.GetOperator(regionID)
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.r.JSON(w, http.StatusOK, op)
+}
+
+func (h *operatorHandler) List(w http.ResponseWriter, r *http.Request) {
+	ops, err := h.GetOperators()
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.r.JSON(w, http.StatusOK, ops)
+}
+
+func (h *operatorHandler) Post(w http.ResponseWriter, r *http.Request) {
+	var input map[string]interface{}
+	if err := readJSON(r.Body, &input); err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	name, ok := input["name"].(string)
+	if !ok {
+		h.r.JSON(w, http.StatusBadRequest, "missing operator name")
+		return
+	}
+	regionID, ok := input["region_id"].(float64)
+	if !ok {
+		h.r.JSON(w, http.StatusBadRequest, "missing region id")
+		return
+	}
+
+	var err error
+	switch name {
+	case "transfer-leader":
+		storeID, ok := input["to_store_id"].(float64)
+		if !ok {
+			h.r.JSON(w, http.StatusBadRequest, "missing store id to transfer leader to")
+			return
+		}
+		err = h.AddTransferLeaderOperator(uint64(regionID), uint64(storeID))
+	case "transfer-peer":
+		fromID, ok := input["from_store_id"].(float64)
+		if !ok {
+			h.r.JSON(w, http.StatusBadRequest, "missing store id to transfer peer from")
+			return
+		}
+		toID, ok := input["to_store_id"].(float64)
+		if !ok {
+			h.r.JSON(w, http.StatusBadRequest, "missing store id to transfer peer to")
+			return
+		}
+		err = h.AddTransferPeerOperator(uint64(regionID), uint64(fromID), uint64(toID))
+	case "add-peer":
+		storeID, ok := input["to_store_id"].(float64)
+		if !ok {
+			h.r.JSON(w, http.StatusBadRequest, "missing store id to add peer to")
+			return
+		}
+		err = h.AddAddPeerOperator(uint64(regionID), uint64(storeID))
+	case "remove-peer":
+		storeID, ok := input["from_store_id"].(float64)
+		if !ok {
+			h.r.JSON(w, http.StatusBadRequest, "missing store id to remove peer from")
+			return
+		}
+		err = h.AddRemovePeerOperator(uint64(regionID), uint64(storeID))
+	case "move-region":
+		ids, ok := input["store_ids"].([]interface{})
+		if !ok {
+			h.r.JSON(w, http.StatusBadRequest, "missing store ids to move region to")
+			return
+		}
+		var storeIDs []uint64
+		for _, id := range ids {
+			storeID, ok := id.(float64)
+			if !ok {
+				h.r.JSON(w, http.StatusBadRequest, "invalid store id")
+				return
+			}
+			storeIDs = append(storeIDs, uint64(storeID))
+		}
+		err = h.AddMoveRegionOperator(uint64(regionID), storeIDs)
+	case "split-region", "merge-region":
+		// Region heartbeat responses can only change peers or transfer leader.
+		h.r.JSON(w, http.StatusBadRequest, name+" is not supported")
+		return
+	default:
+		h.r.JSON(w, http.StatusBadRequest, "unknown operator "+name)
+		return
+	}
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.r.JSON(w, http.StatusOK, nil)
+}
+
+func (h *operatorHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	regionID, err := strconv.ParseUint(mux.Vars(r)["region_id"], 10, 64)
+	if err != nil {
+		h.r.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err = h.RemoveOperator(regionID); err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.r.JSON(w, http.StatusOK, nil)
+}
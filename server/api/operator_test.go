@@ -0,0 +1,86 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/server"
+)
+
+var _ = Suite(&testOperatorSuite{})
+
+type testOperatorSuite struct {
+	svr     *server.Server
+	cleanup cleanUpFunc
+	url     string
+	hc      *http.Client
+}
+
+func (s *testOperatorSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	httpAddr := mustUnixAddrToHTTPAddr(c, s.svr.GetAddr())
+	s.url = fmt.Sprintf("%s%s/api/v1/operators", httpAddr, apiPrefix)
+	s.hc = newUnixSocketClient()
+
+	mustBootstrapCluster(c, s.svr)
+	mustPutStore(c, s.svr, 2)
+	mustPutStore(c, s.svr, 3)
+}
+
+func (s *testOperatorSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testOperatorSuite) do(c *C, method string, url string, body string) int {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	c.Assert(err, IsNil)
+	resp, err := s.hc.Do(req)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func (s *testOperatorSuite) TestOperators(c *C) {
+	regionURL := fmt.Sprintf("%s/%d", s.url, region.GetId())
+
+	c.Assert(s.do(c, "GET", regionURL, ""), Equals, http.StatusInternalServerError)
+	c.Assert(s.do(c, "DELETE", regionURL, ""), Equals, http.StatusInternalServerError)
+
+	c.Assert(s.do(c, "POST", s.url, `{"name": "add-peer", "region_id": 8, "to_store_id": 2}`), Equals, http.StatusOK)
+	c.Assert(s.do(c, "GET", regionURL, ""), Equals, http.StatusOK)
+	c.Assert(s.do(c, "GET", s.url, ""), Equals, http.StatusOK)
+
+	// The region has an operator already.
+	c.Assert(s.do(c, "POST", s.url, `{"name": "add-peer", "region_id": 8, "to_store_id": 3}`), Equals, http.StatusInternalServerError)
+
+	c.Assert(s.do(c, "DELETE", regionURL, ""), Equals, http.StatusOK)
+	c.Assert(s.do(c, "GET", regionURL, ""), Equals, http.StatusInternalServerError)
+
+	// Invalid operators.
+	c.Assert(s.do(c, "POST", s.url, `{"name": "transfer-leader", "region_id": 8, "to_store_id": 3}`), Equals, http.StatusInternalServerError)
+	c.Assert(s.do(c, "POST", s.url, `{"name": "add-peer", "region_id": 8, "to_store_id": 1}`), Equals, http.StatusInternalServerError)
+	c.Assert(s.do(c, "POST", s.url, `{"name": "add-peer", "region_id": 8, "to_store_id": 4}`), Equals, http.StatusInternalServerError)
+	c.Assert(s.do(c, "POST", s.url, `{"name": "move-region", "region_id": 8, "store_ids": [1]}`), Equals, http.StatusInternalServerError)
+	c.Assert(s.do(c, "POST", s.url, `{"name": "add-peer", "region_id": 8}`), Equals, http.StatusBadRequest)
+	c.Assert(s.do(c, "POST", s.url, `{"name": "split-region", "region_id": 8}`), Equals, http.StatusBadRequest)
+
+	c.Assert(s.do(c, "POST", s.url, `{"name": "move-region", "region_id": 8, "store_ids": [1, 2, 3]}`), Equals, http.StatusOK)
+	c.Assert(s.do(c, "DELETE", regionURL, ""), Equals, http.StatusOK)
+}
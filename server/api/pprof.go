@@ -0,0 +1,50 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/gorilla/mux"
+	"github.com/unrolled/render"
+)
+
+// pprofHandler serves the profiles of the leader, the requests to followers
+// are redirected to leader like the other APIs.
+type pprofHandler struct {
+	rd *render.Render
+}
+
+func newPProfHandler(rd *render.Render) *pprofHandler {
+	return &pprofHandler{rd: rd}
+}
+
+// Profile serves the CPU profile of the duration set by "?seconds=N",
+// 30 seconds by default.
+func (h *pprofHandler) Profile(w http.ResponseWriter, r *http.Request) {
+	pprof.Profile(w, r)
+}
+
+// Trace serves the execution trace of the duration set by "?seconds=N",
+// 1 second by default.
+func (h *pprofHandler) Trace(w http.ResponseWriter, r *http.Request) {
+	pprof.Trace(w, r)
+}
+
+// Lookup serves the named profile, e.g. heap and goroutine. Goroutine dumps
+// with stacks are served by "goroutine?debug=2".
+func (h *pprofHandler) Lookup(w http.ResponseWriter, r *http.Request) {
+	pprof.Handler(mux.Vars(r)["name"]).ServeHTTP(w, r)
+}
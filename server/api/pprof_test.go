@@ -0,0 +1,73 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/server"
+)
+
+var _ = Suite(&testPProfSuite{})
+
+type testPProfSuite struct {
+	cfg *server.Config
+	svr *server.Server
+	url string
+	hc  *http.Client
+}
+
+func (s *testPProfSuite) SetUpSuite(c *C) {
+	s.cfg = server.NewTestSingleConfig()
+	s.cfg.EnablePProf = true
+	s.svr = server.CreateServer(s.cfg)
+	c.Assert(s.svr.StartEtcd(NewHandler(s.svr)), IsNil)
+	go s.svr.Run()
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	httpAddr := mustUnixAddrToHTTPAddr(c, s.svr.GetAddr())
+	s.url = fmt.Sprintf("%s%s/api/v1/debug/pprof", httpAddr, apiPrefix)
+	s.hc = newUnixSocketClient()
+}
+
+func (s *testPProfSuite) TearDownSuite(c *C) {
+	s.svr.Close()
+	cleanServer(s.cfg)
+}
+
+func (s *testPProfSuite) TestPProf(c *C) {
+	resp, err := s.hc.Get(s.url + "/goroutine?debug=2")
+	c.Assert(err, IsNil)
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	c.Assert(strings.Contains(string(data), "goroutine"), IsTrue)
+
+	for _, path := range []string{"/heap", "/profile?seconds=1"} {
+		resp, err = s.hc.Get(s.url + path)
+		c.Assert(err, IsNil)
+		resp.Body.Close()
+		c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	}
+
+	resp, err = s.hc.Get(s.url + "/unknown")
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusNotFound)
+}
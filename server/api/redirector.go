@@ -14,6 +14,7 @@
 package api
 
 import (
+	"crypto/tls"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -66,7 +67,7 @@ func (h *redirector) ServeHTTP(w http.ResponseWriter, r *http.Request, next http
 		return
 	}
 
-	newCustomReverseProxies(urls).ServeHTTP(w, r)
+	newCustomReverseProxies(urls, h.s.GetTLSConfig()).ServeHTTP(w, r)
 }
 
 type customReverseProxies struct {
@@ -74,20 +75,11 @@ type customReverseProxies struct {
 	clients []*http.Client
 }
 
-func newCustomReverseProxies(urls []url.URL) *customReverseProxies {
+func newCustomReverseProxies(urls []url.URL, tlsCfg *tls.Config) *customReverseProxies {
 	p := &customReverseProxies{}
 
 	for _, u := range urls {
-		var client *http.Client
-
-		// Use unix socket in tests.
-		if u.Scheme == "unix" {
-			u.Scheme = "http"
-			client = &http.Client{Transport: &http.Transport{Dial: unixDial}}
-		} else {
-			client = &http.Client{}
-		}
-
+		client := newURLClient(&u, tlsCfg)
 		p.urls = append(p.urls, u)
 		p.clients = append(p.clients, client)
 	}
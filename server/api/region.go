@@ -14,6 +14,7 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -23,6 +24,11 @@ import (
 	"github.com/unrolled/render"
 )
 
+const (
+	defaultScanRegionsLimit = 16
+	maxScanRegionsLimit     = 10240
+)
+
 type regionInfo struct {
 	Region *metapb.Region `json:"region"`
 	Leader *metapb.Peer   `json:"leader"`
@@ -94,3 +100,109 @@ func (h *regionsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	h.rd.JSON(w, http.StatusOK, regionsInfo)
 }
+
+// Scan returns at most limit regions in key order, from the region of the
+// key. The key is the raw key escaped in the query.
+func (h *regionsHandler) Scan(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, http.StatusInternalServerError, errNotBootstrapped.Error())
+		return
+	}
+
+	limit := defaultScanRegionsLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitStr); err != nil || limit <= 0 {
+			h.rd.JSON(w, http.StatusBadRequest, fmt.Sprintf("invalid limit %q", limitStr))
+			return
+		}
+	}
+	if limit > maxScanRegionsLimit {
+		limit = maxScanRegionsLimit
+	}
+	regions := cluster.ScanRegions([]byte(r.URL.Query().Get("key")), limit)
+	regionsInfo := &regionsInfo{
+		Count:   len(regions),
+		Regions: regions,
+	}
+	h.rd.JSON(w, http.StatusOK, regionsInfo)
+}
+
+// Prev returns the region before the region of the key.
+func (h *regionsHandler) Prev(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, http.StatusInternalServerError, errNotBootstrapped.Error())
+		return
+	}
+
+	region, leader := cluster.GetPrevRegion([]byte(r.URL.Query().Get("key")))
+	regionInfo := &regionInfo{
+		Region: region,
+		Leader: leader,
+	}
+	h.rd.JSON(w, http.StatusOK, regionInfo)
+}
+
+type regionsCheckHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newRegionsCheckHandler(svr *server.Server, rd *render.Render) *regionsCheckHandler {
+	return &regionsCheckHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+func (h *regionsCheckHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, http.StatusInternalServerError, errNotBootstrapped.Error())
+		return
+	}
+
+	var regions []*metapb.Region
+	switch typ := mux.Vars(r)["type"]; typ {
+	case "miss-peer":
+		regions = cluster.GetMissPeerRegions()
+	case "down-peer":
+		regions = cluster.GetDownPeerRegions()
+	case "pending-peer":
+		regions = cluster.GetPendingPeerRegions()
+	case "offline-peer":
+		regions = cluster.GetOfflinePeerRegions()
+	default:
+		h.rd.JSON(w, http.StatusBadRequest, fmt.Sprintf("unknown check type %s", typ))
+		return
+	}
+	regionsInfo := &regionsInfo{
+		Count:   len(regions),
+		Regions: regions,
+	}
+	h.rd.JSON(w, http.StatusOK, regionsInfo)
+}
+
+type unavailableRegionsHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newUnavailableRegionsHandler(svr *server.Server, rd *render.Render) *unavailableRegionsHandler {
+	return &unavailableRegionsHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+func (h *unavailableRegionsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, http.StatusInternalServerError, errNotBootstrapped.Error())
+		return
+	}
+
+	h.rd.JSON(w, http.StatusOK, cluster.GetUnavailableRegions())
+}
@@ -37,34 +37,137 @@ func createRouter(prefix string, svr *server.Server) *mux.Router {
 	schedulerHandler := newSchedulerHandler(handler, rd)
 	router.HandleFunc("/api/v1/schedulers", schedulerHandler.List).Methods("GET")
 	router.HandleFunc("/api/v1/schedulers", schedulerHandler.Post).Methods("POST")
+	router.HandleFunc("/api/v1/schedulers/{name}", schedulerHandler.Get).Methods("GET")
 	router.HandleFunc("/api/v1/schedulers/{name}", schedulerHandler.Delete).Methods("DELETE")
+	router.HandleFunc("/api/v1/schedulers/{name}/diagnosis", schedulerHandler.GetDiagnosis).Methods("GET")
+	router.HandleFunc("/api/v1/schedulers/{name}/pause", schedulerHandler.Pause).Methods("POST")
+	router.HandleFunc("/api/v1/schedulers/{name}/resume", schedulerHandler.Resume).Methods("POST")
 
-	router.Handle("/api/v1/cluster", newClusterHandler(svr, rd)).Methods("GET")
+	operatorHandler := newOperatorHandler(handler, rd)
+	router.HandleFunc("/api/v1/operators", operatorHandler.List).Methods("GET")
+	router.HandleFunc("/api/v1/operators", operatorHandler.Post).Methods("POST")
+	router.HandleFunc("/api/v1/operators/{region_id}", operatorHandler.Get).Methods("GET")
+	router.HandleFunc("/api/v1/operators/{region_id}", operatorHandler.Delete).Methods("DELETE")
+
+	clusterHandler := newClusterHandler(svr, rd)
+	router.Handle("/api/v1/cluster", clusterHandler).Methods("GET")
+	router.HandleFunc("/api/v1/cluster/status", clusterHandler.GetStatus).Methods("GET")
+	router.HandleFunc("/api/v1/cluster/snapshot", clusterHandler.GetSnapshot).Methods("GET")
+	router.HandleFunc("/api/v1/cluster/capture", clusterHandler.GetCapture).Methods("GET")
+
+	backupHandler := newBackupHandler(svr, rd)
+	router.HandleFunc("/api/v1/backup", backupHandler.Get).Methods("GET")
+
+	idHandler := newIDHandler(svr, rd)
+	router.HandleFunc("/api/v1/id", idHandler.Get).Methods("GET")
+
+	featureGateHandler := newFeatureGateHandler(svr, rd)
+	router.HandleFunc("/api/v1/feature-gates", featureGateHandler.List).Methods("GET")
+	router.HandleFunc("/api/v1/feature-gates/{name}", featureGateHandler.Post).Methods("POST")
 
 	confHandler := newConfHandler(svr, rd)
 	router.HandleFunc("/api/v1/config", confHandler.Get).Methods("GET")
 	router.HandleFunc("/api/v1/config", confHandler.Post).Methods("POST")
+	router.HandleFunc("/api/v1/config/reload", confHandler.Reload).Methods("POST")
+	router.HandleFunc("/api/v1/config/diff", confHandler.GetDiff).Methods("GET")
+	router.HandleFunc("/api/v1/config/history", confHandler.GetHistory).Methods("GET")
+	router.HandleFunc("/api/v1/config/rollback/{version}", confHandler.Rollback).Methods("POST")
 	router.HandleFunc("/api/v1/config/schedule", confHandler.GetSchedule).Methods("GET")
+	router.HandleFunc("/api/v1/config/schedule", confHandler.Post).Methods("POST")
+	router.HandleFunc("/api/v1/config/replication", confHandler.GetReplication).Methods("GET")
+	router.HandleFunc("/api/v1/config/replication", confHandler.PostReplication).Methods("POST")
+	router.HandleFunc("/api/v1/config/replication/progress", confHandler.GetReplicationProgress).Methods("GET")
+	router.HandleFunc("/api/v1/config/engine-replica/progress", confHandler.GetEngineReplicaProgress).Methods("GET")
+	router.HandleFunc("/api/v1/config/label-property", confHandler.GetLabelProperty).Methods("GET")
+	router.HandleFunc("/api/v1/config/label-property", confHandler.PostLabelProperty).Methods("POST")
 
 	storeHandler := newStoreHandler(svr, rd)
 	router.HandleFunc("/api/v1/store/{id}", storeHandler.Get).Methods("GET")
 	router.HandleFunc("/api/v1/store/{id}", storeHandler.Delete).Methods("DELETE")
+	router.HandleFunc("/api/v1/store/{id}/label", storeHandler.SetLabels).Methods("POST")
 	router.Handle("/api/v1/stores", newStoresHandler(svr, rd)).Methods("GET")
+	router.Handle("/api/v1/stores/batch", newStoresBatchHandler(svr, rd)).Methods("POST")
 
 	labelsHandler := newLabelsHandler(svr, rd)
 	router.HandleFunc("/api/v1/labels", labelsHandler.Get).Methods("GET")
 	router.HandleFunc("/api/v1/labels/stores", labelsHandler.GetStores).Methods("GET")
 
 	router.Handle("/api/v1/events", newEventsHandler(svr, rd)).Methods("GET")
+	webhookHandler := newWebhookHandler(svr, rd)
+	router.HandleFunc("/api/v1/webhooks", webhookHandler.List).Methods("GET")
+	router.HandleFunc("/api/v1/webhooks", webhookHandler.Post).Methods("POST")
+	router.HandleFunc("/api/v1/webhooks/{name}", webhookHandler.Delete).Methods("DELETE")
+
+	namespaceHandler := newNamespaceHandler(svr, rd)
+	router.HandleFunc("/api/v1/namespaces", namespaceHandler.List).Methods("GET")
+	router.HandleFunc("/api/v1/namespaces", namespaceHandler.Post).Methods("POST")
+	router.HandleFunc("/api/v1/namespaces/{name}", namespaceHandler.Delete).Methods("DELETE")
+	router.HandleFunc("/api/v1/namespaces/{name}/tables", namespaceHandler.AddTable).Methods("POST")
+	router.HandleFunc("/api/v1/namespaces/{name}/tables/{table_id}", namespaceHandler.RemoveTable).Methods("DELETE")
+	router.HandleFunc("/api/v1/namespaces/{name}/ranges", namespaceHandler.AddKeyRange).Methods("POST")
+	router.HandleFunc("/api/v1/namespaces/{name}/ranges", namespaceHandler.RemoveKeyRange).Methods("DELETE")
+	router.HandleFunc("/api/v1/namespaces/{name}/stores", namespaceHandler.AddStore).Methods("POST")
+	router.HandleFunc("/api/v1/namespaces/{name}/stores/{store_id}", namespaceHandler.RemoveStore).Methods("DELETE")
+	router.HandleFunc("/api/v1/namespaces/{name}/config", namespaceHandler.SetConfig).Methods("POST")
+
+	keyspaceHandler := newKeyspaceHandler(svr, rd)
+	router.HandleFunc("/api/v1/keyspaces", keyspaceHandler.List).Methods("GET")
+	router.HandleFunc("/api/v1/keyspaces", keyspaceHandler.Post).Methods("POST")
+	router.HandleFunc("/api/v1/keyspaces/{name}", keyspaceHandler.Get).Methods("GET")
+	router.HandleFunc("/api/v1/keyspaces/{name}/state", keyspaceHandler.SetState).Methods("POST")
+
+	userHandler := newUserHandler(svr, rd)
+	router.HandleFunc("/api/v1/users", userHandler.List).Methods("GET")
+	router.HandleFunc("/api/v1/users", userHandler.Post).Methods("POST")
+	router.HandleFunc("/api/v1/users/{name}", userHandler.Delete).Methods("DELETE")
+	router.HandleFunc("/api/v1/users/{name}/role", userHandler.SetRole).Methods("POST")
+
+	encryptionHandler := newEncryptionHandler(svr, rd)
+	router.HandleFunc("/api/v1/encryption/keys", encryptionHandler.List).Methods("GET")
+	router.HandleFunc("/api/v1/encryption/keys/current", encryptionHandler.GetCurrent).Methods("GET")
+	router.HandleFunc("/api/v1/encryption/keys/rotate", encryptionHandler.Rotate).Methods("POST")
+	router.HandleFunc("/api/v1/encryption/keys/{id}", encryptionHandler.Get).Methods("GET")
+
 	router.Handle("/api/v1/feed", newFeedHandler(svr, rd)).Methods("GET")
 	router.Handle("/api/v1/history/operators", newHistoryOperatorHandler(svr, rd)).Methods("GET")
+	router.Handle("/api/v1/history/operators/records", newOperatorRecordsHandler(svr, rd)).Methods("GET")
 	router.Handle("/api/v1/region/{id}", newRegionHandler(svr, rd)).Methods("GET")
-	router.Handle("/api/v1/regions", newRegionsHandler(svr, rd)).Methods("GET")
+	regionsHandler := newRegionsHandler(svr, rd)
+	router.Handle("/api/v1/regions", regionsHandler).Methods("GET")
+	router.HandleFunc("/api/v1/regions/scan", regionsHandler.Scan).Methods("GET")
+	router.HandleFunc("/api/v1/regions/prev", regionsHandler.Prev).Methods("GET")
+	router.Handle("/api/v1/regions/check/{type}", newRegionsCheckHandler(svr, rd)).Methods("GET")
+	router.Handle("/api/v1/regions/unavailable", newUnavailableRegionsHandler(svr, rd)).Methods("GET")
 	router.Handle("/api/v1/version", newVersionHandler(rd)).Methods("GET")
 
+	if svr.GetConfig().EnablePProf {
+		pprofHandler := newPProfHandler(rd)
+		router.HandleFunc("/api/v1/debug/pprof/profile", pprofHandler.Profile).Methods("GET")
+		router.HandleFunc("/api/v1/debug/pprof/trace", pprofHandler.Trace).Methods("GET")
+		router.HandleFunc("/api/v1/debug/pprof/{name}", pprofHandler.Lookup).Methods("GET")
+	}
+
 	router.Handle("/api/v1/members", newMemberListHandler(svr, rd)).Methods("GET")
 	router.Handle("/api/v1/members/{name}", newMemberDeleteHandler(svr, rd)).Methods("DELETE")
-	router.Handle("/api/v1/leader", newLeaderHandler(svr, rd)).Methods("GET")
+	router.Handle("/api/v1/members/id/{id}", newMemberDeleteHandler(svr, rd)).Methods("DELETE")
+	router.Handle("/api/v1/members/{name}", newMemberLeaderPriorityHandler(svr, rd)).Methods("POST")
+	leaderHandler := newLeaderHandler(svr, rd)
+	router.Handle("/api/v1/leader", leaderHandler).Methods("GET")
+	router.HandleFunc("/api/v1/leader/resign", leaderHandler.Resign).Methods("POST")
+	router.HandleFunc("/api/v1/leader/transfer/{next_leader}", leaderHandler.Transfer).Methods("POST")
+
+	externalTSHandler := newExternalTSHandler(svr, rd)
+	router.HandleFunc("/api/v1/tso/external", externalTSHandler.Get).Methods("GET")
+	router.HandleFunc("/api/v1/tso/external", externalTSHandler.Post).Methods("POST")
+	router.Handle("/api/v1/tso/check", newTSOCheckHandler(svr, rd)).Methods("GET")
+
+	gcSafePointHandler := newGCSafePointHandler(svr, rd)
+	router.HandleFunc("/api/v1/gc/safepoint", gcSafePointHandler.Get).Methods("GET")
+	router.HandleFunc("/api/v1/gc/safepoint", gcSafePointHandler.Post).Methods("POST")
+	serviceSafePointHandler := newServiceSafePointHandler(svr, rd)
+	router.HandleFunc("/api/v1/gc/safepoint/service", serviceSafePointHandler.List).Methods("GET")
+	router.HandleFunc("/api/v1/gc/safepoint/service/{service_id}", serviceSafePointHandler.Post).Methods("POST")
+	router.HandleFunc("/api/v1/gc/safepoint/service/{service_id}", serviceSafePointHandler.Delete).Methods("DELETE")
 
 	balancerHandler := newBalancerHandler(svr, rd)
 	router.HandleFunc("/api/v1/balancers", balancerHandler.Get).Methods("GET")
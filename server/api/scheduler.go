@@ -86,6 +86,9 @@ func (h *schedulerHandler) Post(w http.ResponseWriter, r *http.Request) {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
 			return
 		}
+	default:
+		h.r.JSON(w, http.StatusBadRequest, "unknown scheduler "+name)
+		return
 	}
 
 	h.r.JSON(w, http.StatusOK, nil)
@@ -101,3 +104,49 @@ func (h *schedulerHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	h.r.JSON(w, http.StatusOK, nil)
 }
+
+func (h *schedulerHandler) Get(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	config, err := h.GetSchedulerConfig(name)
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.r.JSON(w, http.StatusOK, config)
+}
+
+func (h *schedulerHandler) GetDiagnosis(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	diagnosis, err := h.GetSchedulerDiagnosis(name)
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.r.JSON(w, http.StatusOK, diagnosis)
+}
+
+func (h *schedulerHandler) Pause(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	if err := h.PauseScheduler(name); err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.r.JSON(w, http.StatusOK, nil)
+}
+
+func (h *schedulerHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	if err := h.ResumeScheduler(name); err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.r.JSON(w, http.StatusOK, nil)
+}
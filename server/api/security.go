@@ -0,0 +1,42 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/pingcap/pd/server"
+	"github.com/unrolled/render"
+)
+
+type securityHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newSecurityHandler(svr *server.Server, rd *render.Render) *securityHandler {
+	return &securityHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+// Reload reloads the cert files of the member which serves the request.
+func (h *securityHandler) Reload(w http.ResponseWriter, r *http.Request) {
+	if err := h.svr.ReloadTLSConfig(); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
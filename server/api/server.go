@@ -19,6 +19,7 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/pingcap/pd/server"
+	"github.com/unrolled/render"
 	"github.com/urfave/negroni"
 )
 
@@ -35,11 +36,30 @@ func NewHandler(svr *server.Server) http.Handler {
 	recovery := negroni.NewRecovery()
 	engine.Use(recovery)
 
+	engine.Use(newAuthenticator(svr))
+
 	static := negroni.NewStatic(assetFS())
 	static.Prefix = apiPrefix + "/web"
 	engine.Use(static)
 
 	router := mux.NewRouter()
+	// Local tso is served by the local tso allocator rather than leader.
+	rd := render.New(render.Options{IndentJSON: true})
+	router.Handle(apiPrefix+"/api/v1/tso/local", newLocalTSOHandler(svr, rd)).Methods("GET")
+	// The config of the member itself, used to find the config drift between members.
+	router.HandleFunc(apiPrefix+"/api/v1/config/local", newConfHandler(svr, rd).Get).Methods("GET")
+	// The cert files are reloaded by the member itself.
+	router.HandleFunc(apiPrefix+"/api/v1/security/reload", newSecurityHandler(svr, rd).Reload).Methods("POST")
+	// The leader is watched by the member itself, so the clients are notified
+	// even if there is no leader.
+	router.HandleFunc(apiPrefix+"/api/v1/leader/watch", newLeaderHandler(svr, rd).Watch).Methods("GET")
+	// The failpoints are injected into the member itself.
+	if svr.GetConfig().EnableFailpoint {
+		failpointHandler := newFailpointHandler(rd)
+		router.HandleFunc(apiPrefix+"/api/v1/failpoints", failpointHandler.List).Methods("GET")
+		router.HandleFunc(apiPrefix+"/api/v1/failpoints/{name}", failpointHandler.Post).Methods("POST")
+		router.HandleFunc(apiPrefix+"/api/v1/failpoints/{name}", failpointHandler.Delete).Methods("DELETE")
+	}
 	router.PathPrefix(apiPrefix).Handler(negroni.New(
 		newRedirector(svr),
 		negroni.Wrap(createRouter(apiPrefix, svr)),
@@ -14,9 +14,11 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -152,6 +154,42 @@ func (h *storeHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, nil)
 }
 
+// SetLabels merges the labels in the body, e.g. {"zone": "z1"}, into the
+// labels of the store.
+func (h *storeHandler) SetLabels(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, http.StatusInternalServerError, errNotBootstrapped.Error())
+		return
+	}
+
+	vars := mux.Vars(r)
+	storeIDStr := vars["id"]
+	storeID, err := strconv.ParseUint(storeIDStr, 10, 64)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var labels map[string]string
+	if err = readJSON(r.Body, &labels); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	op, err := newStoreBatchOp(&storesBatchInput{Labels: labels})
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err = cluster.BatchUpdateStores([]uint64{storeID}, op); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
 type storesHandler struct {
 	svr *server.Server
 	rd  *render.Render
@@ -181,8 +219,13 @@ func (h *storesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	labelFilter, err := newStoreLabelFilter(r.URL)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 
-	stores = urlFilter.filter(cluster.GetStores())
+	stores = labelFilter.filter(urlFilter.filter(cluster.GetStores()))
 	for _, s := range stores {
 		store, status, err := cluster.GetStore(s.GetId())
 		if err != nil {
@@ -198,6 +241,112 @@ func (h *storesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, storesInfo)
 }
 
+// storesBatchInput is the request body of the stores batch API.
+// The stores are selected by IDs or by a label selector.
+type storesBatchInput struct {
+	StoreIDs []uint64          `json:"store_ids"`
+	Selector map[string]string `json:"selector"`
+	Labels   map[string]string `json:"labels"`
+	State    string            `json:"state"`
+	Force    bool              `json:"force"`
+}
+
+type storesBatchHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newStoresBatchHandler(svr *server.Server, rd *render.Render) *storesBatchHandler {
+	return &storesBatchHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+func (h *storesBatchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cluster := h.svr.GetRaftCluster()
+	if cluster == nil {
+		h.rd.JSON(w, http.StatusInternalServerError, errNotBootstrapped.Error())
+		return
+	}
+
+	input := &storesBatchInput{}
+	if err := readJSON(r.Body, input); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	storeIDs, err := selectBatchStores(cluster.GetStores(), input)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(storeIDs) > server.MaxStoreBatchSize {
+		h.rd.JSON(w, http.StatusBadRequest, fmt.Sprintf("%d stores exceed the max batch size %d", len(storeIDs), server.MaxStoreBatchSize))
+		return
+	}
+
+	op, err := newStoreBatchOp(input)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err = cluster.BatchUpdateStores(storeIDs, op); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.rd.JSON(w, http.StatusOK, storeIDs)
+}
+
+func selectBatchStores(stores []*metapb.Store, input *storesBatchInput) ([]uint64, error) {
+	if len(input.StoreIDs) > 0 && len(input.Selector) > 0 {
+		return nil, errors.New("store_ids and selector can not be provided at the same time")
+	}
+	if len(input.StoreIDs) > 0 {
+		return input.StoreIDs, nil
+	}
+	if len(input.Selector) == 0 {
+		return nil, errors.New("missing store_ids or selector")
+	}
+
+	filter := &storeLabelFilter{}
+	for k, v := range input.Selector {
+		filter.labels = append(filter.labels, &metapb.StoreLabel{Key: k, Value: v})
+	}
+	var storeIDs []uint64
+	for _, s := range filter.filter(stores) {
+		// Tombstone stores can't be updated, just skip them.
+		if s.GetState() == metapb.StoreState_Tombstone {
+			continue
+		}
+		storeIDs = append(storeIDs, s.GetId())
+	}
+	if len(storeIDs) == 0 {
+		return nil, errors.Errorf("no store matches selector %v", input.Selector)
+	}
+	return storeIDs, nil
+}
+
+func newStoreBatchOp(input *storesBatchInput) (*server.StoreBatchOp, error) {
+	op := &server.StoreBatchOp{Force: input.Force}
+	for k, v := range input.Labels {
+		op.Labels = append(op.Labels, &metapb.StoreLabel{Key: k, Value: v})
+	}
+	if len(input.State) != 0 {
+		state, err := parseStoreState(input.State)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		op.State = &state
+	}
+	if len(op.Labels) == 0 && op.State == nil {
+		return nil, errors.New("nothing to update, labels or state should be provided")
+	}
+	return op, nil
+}
+
 type storeStateFilter struct {
 	accepts []metapb.StoreState
 }
@@ -206,12 +355,11 @@ func newStoreStateFilter(u *url.URL) (*storeStateFilter, error) {
 	var acceptStates []metapb.StoreState
 	if v, ok := u.Query()["state"]; ok {
 		for _, s := range v {
-			state, err := strconv.Atoi(s)
+			storeState, err := parseStoreState(s)
 			if err != nil {
 				return nil, errors.Trace(err)
 			}
 
-			storeState := metapb.StoreState(state)
 			switch storeState {
 			case metapb.StoreState_Up, metapb.StoreState_Offline, metapb.StoreState_Tombstone:
 				acceptStates = append(acceptStates, storeState)
@@ -242,3 +390,67 @@ func (filter *storeStateFilter) filter(stores []*metapb.Store) []*metapb.Store {
 	}
 	return ret
 }
+
+// parseStoreState parses a store state from either its numeric value
+// or its case-insensitive name, e.g. "1" or "offline".
+func parseStoreState(s string) (metapb.StoreState, error) {
+	if state, err := strconv.Atoi(s); err == nil {
+		return metapb.StoreState(state), nil
+	}
+	for name, value := range metapb.StoreState_value {
+		if strings.EqualFold(name, s) {
+			return metapb.StoreState(value), nil
+		}
+	}
+	return 0, errors.Errorf("unknown StoreState: %v", s)
+}
+
+// storeLabelFilter accepts stores which have all the given labels.
+// The labels are specified by "label=key=value" in the url query.
+type storeLabelFilter struct {
+	labels []*metapb.StoreLabel
+}
+
+func newStoreLabelFilter(u *url.URL) (*storeLabelFilter, error) {
+	var labels []*metapb.StoreLabel
+	for _, s := range u.Query()["label"] {
+		kv := strings.SplitN(s, "=", 2)
+		if len(kv) != 2 || len(kv[0]) == 0 {
+			return nil, errors.Errorf("invalid label selector: %v", s)
+		}
+		labels = append(labels, &metapb.StoreLabel{Key: kv[0], Value: kv[1]})
+	}
+
+	return &storeLabelFilter{
+		labels: labels,
+	}, nil
+}
+
+func (filter *storeLabelFilter) filter(stores []*metapb.Store) []*metapb.Store {
+	if len(filter.labels) == 0 {
+		return stores
+	}
+	ret := make([]*metapb.Store, 0, len(stores))
+	for _, s := range stores {
+		if filter.match(s) {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+func (filter *storeLabelFilter) match(store *metapb.Store) bool {
+	for _, want := range filter.labels {
+		found := false
+		for _, l := range store.GetLabels() {
+			if l.GetKey() == want.GetKey() && l.GetValue() == want.GetValue() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -63,6 +63,10 @@ func (s *testStoreSuite) TestUrlStoreFilter(c *C) {
 			u:    "http://localhost:2379/pd/api/v1/stores?state=2&state=1",
 			want: stores[2:],
 		},
+		{
+			u:    "http://localhost:2379/pd/api/v1/stores?state=tombstone&state=Offline",
+			want: stores[2:],
+		},
 	}
 
 	for _, t := range table {
@@ -83,3 +87,110 @@ func (s *testStoreSuite) TestUrlStoreFilter(c *C) {
 	_, err = newStoreStateFilter(u)
 	c.Assert(err, NotNil)
 }
+
+func (s *testStoreSuite) TestUrlStoreLabelFilter(c *C) {
+	stores := []*metapb.Store{
+		{
+			Id: 1,
+			Labels: []*metapb.StoreLabel{
+				{Key: "zone", Value: "z1"},
+				{Key: "disk", Value: "ssd"},
+			},
+		},
+		{
+			Id: 2,
+			Labels: []*metapb.StoreLabel{
+				{Key: "zone", Value: "z1"},
+				{Key: "disk", Value: "hdd"},
+			},
+		},
+		{
+			Id: 3,
+			Labels: []*metapb.StoreLabel{
+				{Key: "zone", Value: "z2"},
+			},
+		},
+	}
+
+	var table = []struct {
+		u    string
+		want []*metapb.Store
+	}{
+		{
+			u:    "http://localhost:2379/pd/api/v1/stores",
+			want: stores,
+		},
+		{
+			u:    "http://localhost:2379/pd/api/v1/stores?label=zone=z1",
+			want: stores[:2],
+		},
+		{
+			u:    "http://localhost:2379/pd/api/v1/stores?label=zone=z1&label=disk=ssd",
+			want: stores[:1],
+		},
+		{
+			u:    "http://localhost:2379/pd/api/v1/stores?label=zone=z3",
+			want: []*metapb.Store{},
+		},
+	}
+
+	for _, t := range table {
+		uu, err := url.Parse(t.u)
+		c.Assert(err, IsNil)
+		f, err := newStoreLabelFilter(uu)
+		c.Assert(err, IsNil)
+		c.Assert(f.filter(stores), DeepEquals, t.want)
+	}
+
+	u, err := url.Parse("http://localhost:2379/pd/api/v1/stores?label=zone")
+	c.Assert(err, IsNil)
+	_, err = newStoreLabelFilter(u)
+	c.Assert(err, NotNil)
+}
+
+func (s *testStoreSuite) TestStoresBatchInput(c *C) {
+	stores := []*metapb.Store{
+		{
+			Id:     1,
+			Labels: []*metapb.StoreLabel{{Key: "zone", Value: "z1"}},
+		},
+		{
+			Id:     2,
+			Labels: []*metapb.StoreLabel{{Key: "zone", Value: "z1"}},
+			State:  metapb.StoreState_Tombstone,
+		},
+		{
+			Id:     3,
+			Labels: []*metapb.StoreLabel{{Key: "zone", Value: "z2"}},
+		},
+	}
+
+	ids, err := selectBatchStores(stores, &storesBatchInput{StoreIDs: []uint64{3, 1}})
+	c.Assert(err, IsNil)
+	c.Assert(ids, DeepEquals, []uint64{3, 1})
+
+	// Tombstone stores are skipped.
+	ids, err = selectBatchStores(stores, &storesBatchInput{Selector: map[string]string{"zone": "z1"}})
+	c.Assert(err, IsNil)
+	c.Assert(ids, DeepEquals, []uint64{1})
+
+	_, err = selectBatchStores(stores, &storesBatchInput{Selector: map[string]string{"zone": "z3"}})
+	c.Assert(err, NotNil)
+	_, err = selectBatchStores(stores, &storesBatchInput{})
+	c.Assert(err, NotNil)
+	_, err = selectBatchStores(stores, &storesBatchInput{
+		StoreIDs: []uint64{1},
+		Selector: map[string]string{"zone": "z1"},
+	})
+	c.Assert(err, NotNil)
+
+	op, err := newStoreBatchOp(&storesBatchInput{State: "offline", Labels: map[string]string{"disk": "ssd"}})
+	c.Assert(err, IsNil)
+	c.Assert(*op.State, Equals, metapb.StoreState_Offline)
+	c.Assert(op.Labels, DeepEquals, []*metapb.StoreLabel{{Key: "disk", Value: "ssd"}})
+
+	_, err = newStoreBatchOp(&storesBatchInput{State: "unknown"})
+	c.Assert(err, NotNil)
+	_, err = newStoreBatchOp(&storesBatchInput{})
+	c.Assert(err, NotNil)
+}
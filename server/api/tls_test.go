@@ -0,0 +1,176 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/pkg/apiutil"
+	"github.com/pingcap/pd/pkg/rpcutil"
+	"github.com/pingcap/pd/pkg/testutil"
+	"github.com/pingcap/pd/pkg/tlsutil"
+	"github.com/pingcap/pd/pkg/typeutil"
+	"github.com/pingcap/pd/server"
+)
+
+var _ = Suite(&testTLSSuite{})
+
+type testTLSSuite struct {
+	certDir  string
+	security tlsutil.SecurityConfig
+}
+
+func (s *testTLSSuite) SetUpSuite(c *C) {
+	var err error
+	s.certDir, err = ioutil.TempDir("/tmp", "test_pd_cert")
+	c.Assert(err, IsNil)
+	s.security.CAPath, s.security.CertPath, s.security.KeyPath = testutil.MustNewCerts(c, s.certDir)
+	s.security.ClientCertAuth = true
+	s.security.CertReloadInterval = typeutil.NewDuration(time.Minute)
+}
+
+func (s *testTLSSuite) TearDownSuite(c *C) {
+	os.RemoveAll(s.certDir)
+}
+
+// The etcd client can't dial the unix sockets in TLS, so the TLS server is
+// served on the local tcp ports.
+func mustFreeURL(c *C) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	defer l.Close()
+	return "https://" + l.Addr().String()
+}
+
+func mustNewTLSServer(c *C, security tlsutil.SecurityConfig, auth server.APIAuthConfig) (*server.Server, cleanUpFunc) {
+	cfg := server.NewTestSingleConfig()
+	cfg.ClientUrls, cfg.PeerUrls = mustFreeURL(c), mustFreeURL(c)
+	cfg.AdvertiseClientUrls, cfg.AdvertisePeerUrls = cfg.ClientUrls, cfg.PeerUrls
+	cfg.InitialCluster = fmt.Sprintf("%s=%s", cfg.Name, cfg.PeerUrls)
+	cfg.Security = security
+	cfg.APIAuth = auth
+
+	svr := server.CreateServer(cfg)
+	c.Assert(svr.StartEtcd(NewHandler(svr)), IsNil)
+	go svr.Run()
+	mustWaitLeader(c, []*server.Server{svr})
+
+	cleanup := func() {
+		svr.Close()
+		os.RemoveAll(cfg.DataDir)
+	}
+	return svr, cleanup
+}
+
+func (s *testTLSSuite) TestTLS(c *C) {
+	svr, cleanup := mustNewTLSServer(c, s.security, server.APIAuthConfig{})
+	defer cleanup()
+
+	addr := svr.GetAddr()
+	c.Assert(strings.HasPrefix(addr, "https://"), IsTrue)
+	tlsCfg := svr.GetTLSConfig()
+	c.Assert(tlsCfg, NotNil)
+
+	// The APIs are served in TLS.
+	client, err := apiutil.NewClient(addr, 5*time.Second, tlsCfg)
+	c.Assert(err, IsNil)
+	leader, err := client.GetLeader()
+	c.Assert(err, IsNil)
+	c.Assert(leader.GetAddr(), Equals, addr)
+
+	// The rpc is served in TLS.
+	conn, err := rpcutil.ConnectUrls(addr, 5*time.Second, tlsCfg)
+	c.Assert(err, IsNil)
+	conn.Close()
+
+	// The clients without a cert are rejected.
+	noCert := tlsutil.SecurityConfig{CAPath: s.security.CAPath}
+	noCertCfg, err := noCert.ToClientTLSConfig()
+	c.Assert(err, IsNil)
+	client, err = apiutil.NewClient(addr, 5*time.Second, noCertCfg)
+	c.Assert(err, IsNil)
+	_, err = client.GetLeader()
+	c.Assert(err, NotNil)
+
+	// The clients without the CA can't verify the server.
+	_, err = rpcutil.ConnectUrls(addr, 5*time.Second, nil)
+	c.Assert(err, NotNil)
+}
+
+func (s *testTLSSuite) TestReload(c *C) {
+	dir, err := ioutil.TempDir("/tmp", "test_pd_cert")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	security := s.security
+	security.CAPath, security.CertPath, security.KeyPath = testutil.MustNewCerts(c, dir)
+
+	svr, cleanup := mustNewTLSServer(c, security, server.APIAuthConfig{})
+	defer cleanup()
+	tlsCfg := svr.GetTLSConfig()
+
+	// Renew the files and reload them by the api.
+	testutil.MustNewCerts(c, dir)
+	u, err := url.Parse(svr.GetAddr() + apiPrefix + "/api/v1/security/reload")
+	c.Assert(err, IsNil)
+	hc := apiutil.NewHTTPClient(u.Scheme, 5*time.Second, tlsCfg)
+	resp, err := hc.Post(u.String(), "application/json", nil)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+
+	reloaded := svr.GetTLSConfig()
+	c.Assert(reloaded, Not(Equals), tlsCfg)
+	c.Assert(bytes.Equal(reloaded.Certificates[0].Certificate[0], tlsCfg.Certificates[0].Certificate[0]), IsFalse)
+
+	// The existing connections are kept.
+	conn, err := rpcutil.ConnectUrls(svr.GetAddr(), 5*time.Second, tlsCfg)
+	c.Assert(err, IsNil)
+	defer conn.Close()
+	c.Assert(svr.ReloadTLSConfig(), IsNil)
+	testutil.MustRPCCall(c, conn, &pdpb.Request{
+		CmdType:      pdpb.CommandType_GetPDMembers,
+		GetPdMembers: &pdpb.GetPDMembersRequest{},
+	})
+}
+
+func (s *testTLSSuite) TestAuthByCert(c *C) {
+	svr, cleanup := mustNewTLSServer(c, s.security, server.APIAuthConfig{AllowedCNs: []string{"admin"}})
+	defer cleanup()
+
+	u, err := url.Parse(svr.GetAddr() + apiPrefix + "/api/v1/security/reload")
+	c.Assert(err, IsNil)
+	hc := apiutil.NewHTTPClient(u.Scheme, 5*time.Second, svr.GetTLSConfig())
+
+	// The common name of the members is allowed.
+	resp, err := hc.Post(u.String(), "application/json", nil)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+
+	// The read-only apis are open.
+	resp, err = hc.Get(svr.GetAddr() + apiPrefix + "/api/v1/leader")
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+}
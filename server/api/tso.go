@@ -0,0 +1,148 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ngaut/log"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/server"
+	"github.com/unrolled/render"
+)
+
+// localTSOHandler serves the local timestamps. Unlike other APIs, it is
+// forwarded to the local tso allocator of the dc-location instead of leader.
+type localTSOHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newLocalTSOHandler(svr *server.Server, rd *render.Render) *localTSOHandler {
+	return &localTSOHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+func (h *localTSOHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.svr.IsLocalTSOLeader() {
+		h.redirect(w, r)
+		return
+	}
+
+	count := uint64(1)
+	if value := r.URL.Query().Get("count"); len(value) != 0 {
+		var err error
+		count, err = strconv.ParseUint(value, 10, 32)
+		if err != nil || count == 0 {
+			h.rd.JSON(w, http.StatusBadRequest, "invalid count")
+			return
+		}
+	}
+
+	ts, err := h.svr.GetLocalTS(uint32(count))
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.rd.JSON(w, http.StatusOK, &pdpb.TsoResponse{Timestamp: ts, Count: uint32(count)})
+}
+
+func (h *localTSOHandler) redirect(w http.ResponseWriter, r *http.Request) {
+	// Prevent more than one redirection.
+	if name := r.Header.Get(redirectorHeader); len(name) != 0 {
+		log.Errorf("redirect from %v, but %v is not local tso allocator", name, h.svr.Name())
+		http.Error(w, errRedirectToNotLeader, http.StatusInternalServerError)
+		return
+	}
+
+	r.Header.Set(redirectorHeader, h.svr.Name())
+
+	leader, err := h.svr.GetLocalTSOLeader()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	urls, err := server.ParseUrls(leader.GetAddr())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	newCustomReverseProxies(urls, h.svr.GetTLSConfig()).ServeHTTP(w, r)
+}
+
+type externalTSHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newExternalTSHandler(svr *server.Server, rd *render.Render) *externalTSHandler {
+	return &externalTSHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+type externalTS struct {
+	Timestamp uint64 `json:"timestamp"`
+}
+
+func (h *externalTSHandler) Get(w http.ResponseWriter, r *http.Request) {
+	ts, err := h.svr.GetExternalTS()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, &externalTS{Timestamp: ts})
+}
+
+func (h *externalTSHandler) Post(w http.ResponseWriter, r *http.Request) {
+	input := &externalTS{}
+	if err := readJSON(r.Body, input); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.svr.SetExternalTS(input.Timestamp); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+type tsoCheckHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newTSOCheckHandler(svr *server.Server, rd *render.Render) *tsoCheckHandler {
+	return &tsoCheckHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+func (h *tsoCheckHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	result := h.svr.GetTSOCheckResult()
+	if result == nil {
+		h.rd.JSON(w, http.StatusInternalServerError, "timestamp is not checked")
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, result)
+}
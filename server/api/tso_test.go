@@ -0,0 +1,95 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strings"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/server"
+)
+
+var _ = Suite(&testTSOSuite{})
+
+type testTSOSuite struct {
+	hc *http.Client
+}
+
+func (s *testTSOSuite) SetUpSuite(c *C) {
+	s.hc = newUnixSocketClient()
+}
+
+func (s *testTSOSuite) getExternalTS(c *C, addr string) uint64 {
+	resp, err := s.hc.Get(addr)
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+
+	ts := &externalTS{}
+	c.Assert(json.NewDecoder(resp.Body).Decode(ts), IsNil)
+	return ts.Timestamp
+}
+
+func (s *testTSOSuite) setExternalTS(c *C, addr string, ts uint64) int {
+	data, err := json.Marshal(&externalTS{Timestamp: ts})
+	c.Assert(err, IsNil)
+	resp, err := s.hc.Post(addr, "application/json", bytes.NewBuffer(data))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func (s *testTSOSuite) TestExternalTS(c *C) {
+	cfgs, _, clean := mustNewCluster(c, 1)
+	defer clean()
+
+	parts := []string{cfgs[0].ClientUrls, apiPrefix, "/api/v1/tso/external"}
+	addr := mustUnixAddrToHTTPAddr(c, strings.Join(parts, ""))
+
+	c.Assert(s.getExternalTS(c, addr), Equals, uint64(0))
+
+	c.Assert(s.setExternalTS(c, addr, 100), Equals, http.StatusOK)
+	c.Assert(s.getExternalTS(c, addr), Equals, uint64(100))
+
+	// Setting the same timestamp is OK.
+	c.Assert(s.setExternalTS(c, addr, 100), Equals, http.StatusOK)
+
+	// Timestamp can't go backward.
+	c.Assert(s.setExternalTS(c, addr, 99), Equals, http.StatusInternalServerError)
+
+	// Timestamp can't be greater than the global one.
+	c.Assert(s.setExternalTS(c, addr, math.MaxInt64), Equals, http.StatusInternalServerError)
+	c.Assert(s.getExternalTS(c, addr), Equals, uint64(100))
+}
+
+func (s *testTSOSuite) TestTSOCheck(c *C) {
+	cfgs, _, clean := mustNewCluster(c, 3)
+	defer clean()
+
+	parts := []string{cfgs[0].ClientUrls, apiPrefix, "/api/v1/tso/check"}
+	addr := mustUnixAddrToHTTPAddr(c, strings.Join(parts, ""))
+	resp, err := s.hc.Get(addr)
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+
+	result := &server.TSOCheckResult{}
+	c.Assert(json.NewDecoder(resp.Body).Decode(result), IsNil)
+	c.Assert(result.Error, Equals, "")
+	c.Assert(result.SavedRevision, Greater, result.LastRevision)
+}
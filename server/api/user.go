@@ -0,0 +1,89 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/juju/errors"
+	"github.com/pingcap/pd/server"
+	"github.com/unrolled/render"
+)
+
+type userHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newUserHandler(svr *server.Server, rd *render.Render) *userHandler {
+	return &userHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+func (h *userHandler) List(w http.ResponseWriter, r *http.Request) {
+	users, err := h.svr.GetUsers()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, users)
+}
+
+// Post creates a user, the token is only returned here.
+func (h *userHandler) Post(w http.ResponseWriter, r *http.Request) {
+	input := &server.User{}
+	if err := readJSON(r.Body, input); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	token, err := h.svr.CreateUser(input.Name, input.Role)
+	if err != nil {
+		h.respond(w, err)
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, map[string]string{
+		"name":  input.Name,
+		"role":  input.Role,
+		"token": token,
+	})
+}
+
+func (h *userHandler) SetRole(w http.ResponseWriter, r *http.Request) {
+	input := &server.User{}
+	if err := readJSON(r.Body, input); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.respond(w, h.svr.SetUserRole(mux.Vars(r)["name"], input.Role))
+}
+
+func (h *userHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	h.respond(w, h.svr.DeleteUser(mux.Vars(r)["name"]))
+}
+
+func (h *userHandler) respond(w http.ResponseWriter, err error) {
+	switch errors.Cause(err) {
+	case nil:
+		h.rd.JSON(w, http.StatusOK, nil)
+	case server.ErrUserNotFound:
+		h.rd.JSON(w, http.StatusNotFound, err.Error())
+	case server.ErrUserExists:
+		h.rd.JSON(w, http.StatusConflict, err.Error())
+	default:
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -14,12 +14,19 @@
 package api
 
 import (
+	"crypto/tls"
 	"encoding/json"
 	"io"
 	"io/ioutil"
 	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
 
 	"github.com/juju/errors"
+	"github.com/pingcap/pd/pkg/apiutil"
 )
 
 func readJSON(r io.ReadCloser, data interface{}) error {
@@ -38,6 +45,70 @@ func readJSON(r io.ReadCloser, data interface{}) error {
 	return nil
 }
 
+// readJSONStrict is like readJSON, but rejects the keys which are not the
+// fields of data, data should be a pointer to struct.
+func readJSONStrict(r io.ReadCloser, data interface{}) error {
+	defer r.Close()
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	if err = checkJSONFields(b, data); err != nil {
+		return errors.Trace(err)
+	}
+
+	err = json.Unmarshal(b, data)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	return nil
+}
+
+func checkJSONFields(b []byte, data interface{}) error {
+	var items map[string]json.RawMessage
+	if err := json.Unmarshal(b, &items); err != nil {
+		return errors.Trace(err)
+	}
+
+	fields := make(map[string]struct{})
+	typ := reflect.TypeOf(data).Elem()
+	for i := 0; i < typ.NumField(); i++ {
+		name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields[name] = struct{}{}
+		}
+	}
+
+	var unknown []string
+	for name := range items {
+		if _, ok := fields[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return errors.Errorf("unknown items: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
 func unixDial(_, addr string) (net.Conn, error) {
 	return net.Dial("unix", addr)
 }
+
+// newURLClient returns a client to send requests to the url, the unix
+// schemes are changed to http or https and dialed by unix socket in tests.
+// tlsCfg is used if the url is in a secure scheme.
+func newURLClient(u *url.URL, tlsCfg *tls.Config) *http.Client {
+	if u.Scheme == "http" {
+		return &http.Client{}
+	}
+	// The client is not reused, so the connection is not kept alive.
+	tr := apiutil.NewHTTPTransport(u.Scheme, tlsCfg)
+	tr.DisableKeepAlives = true
+	u.Scheme = apiutil.ToHTTPScheme(u.Scheme)
+	return &http.Client{Transport: tr}
+}
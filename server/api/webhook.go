@@ -0,0 +1,76 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/juju/errors"
+	"github.com/pingcap/pd/server"
+	"github.com/unrolled/render"
+)
+
+type webhookHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newWebhookHandler(svr *server.Server, rd *render.Render) *webhookHandler {
+	return &webhookHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+func (h *webhookHandler) List(w http.ResponseWriter, r *http.Request) {
+	hooks, err := h.svr.GetWebhooks()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, hooks)
+}
+
+// Post adds the webhook, or updates it if the name exists.
+func (h *webhookHandler) Post(w http.ResponseWriter, r *http.Request) {
+	hook := &server.Webhook{}
+	if err := readJSON(r.Body, hook); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := hook.Validate(); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.svr.AddWebhook(hook); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+func (h *webhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	err := h.svr.DeleteWebhook(mux.Vars(r)["name"])
+	if errors.Cause(err) == server.ErrWebhookNotFound {
+		h.rd.JSON(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
@@ -0,0 +1,87 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/server"
+)
+
+var _ = Suite(&testWebhookSuite{})
+
+type testWebhookSuite struct {
+	svr     *server.Server
+	cleanup cleanUpFunc
+	url     string
+	hc      *http.Client
+}
+
+func (s *testWebhookSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	httpAddr := mustUnixAddrToHTTPAddr(c, s.svr.GetAddr())
+	s.url = fmt.Sprintf("%s%s/api/v1/webhooks", httpAddr, apiPrefix)
+	s.hc = newUnixSocketClient()
+}
+
+func (s *testWebhookSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testWebhookSuite) mustListWebhooks(c *C) []*server.Webhook {
+	resp, err := s.hc.Get(s.url)
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	var hooks []*server.Webhook
+	c.Assert(readJSON(resp.Body, &hooks), IsNil)
+	return hooks
+}
+
+func (s *testWebhookSuite) TestWebhook(c *C) {
+	c.Assert(s.mustListWebhooks(c), HasLen, 0)
+
+	resp, err := s.hc.Post(s.url, "application/json", strings.NewReader(`{"name": "test", "url": "http://127.0.0.1:1/events", "types": ["store_down"]}`))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+
+	hooks := s.mustListWebhooks(c)
+	c.Assert(hooks, HasLen, 1)
+	c.Assert(hooks[0].Name, Equals, "test")
+	c.Assert(hooks[0].Types, DeepEquals, []string{server.ClusterEventStoreDown})
+
+	// Invalid event type.
+	resp, err = s.hc.Post(s.url, "application/json", strings.NewReader(`{"name": "test", "url": "http://127.0.0.1:1/events", "types": ["unknown"]}`))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+
+	req, err := http.NewRequest("DELETE", s.url+"/test", nil)
+	c.Assert(err, IsNil)
+	resp, err = s.hc.Do(req)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	c.Assert(s.mustListWebhooks(c), HasLen, 0)
+
+	resp, err = s.hc.Do(req)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusNotFound)
+}
@@ -0,0 +1,327 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/gogo/protobuf/proto"
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"golang.org/x/net/context"
+)
+
+const (
+	// restoreAllocIDMargin is added to the backup alloc ID when restoring, so
+	// the IDs allocated after the backup is taken are never reused.
+	restoreAllocIDMargin = uint64(100000000)
+	// restoreBatchSize is the default max operations of an etcd txn.
+	restoreBatchSize = 128
+)
+
+// Backup is the metadata owned by PD. The regions are not included, they
+// are reported by the TiKVs again after restoring. The schedulers are not
+// persisted, they are recorded for reference and added manually if needed.
+type Backup struct {
+	CreateTime        time.Time        `json:"create_time"`
+	ClusterID         uint64           `json:"cluster_id"`
+	AllocID           uint64           `json:"alloc_id"`
+	Timestamp         time.Time        `json:"timestamp"`
+	ExternalTimestamp uint64           `json:"external_timestamp"`
+	GCSafePoint       uint64           `json:"gc_safe_point"`
+	Meta              *metapb.Cluster  `json:"meta"`
+	Stores            []*metapb.Store  `json:"stores"`
+	ConfigVersions    []*ConfigVersion `json:"config_versions"`
+	FeatureGates      map[string]bool  `json:"feature_gates"`
+	Webhooks          []*Webhook       `json:"webhooks"`
+	Namespaces        []*Namespace     `json:"namespaces"`
+	Keyspaces         []*Keyspace      `json:"keyspaces"`
+	// The service safe points keep the old data for backup or CDC, which may
+	// still be read after restoring.
+	ServiceSafePoints []*ServiceSafePoint `json:"service_safe_points"`
+	// The data keys are kept encrypted by the master key.
+	EncryptionKeys         []*EncryptedKey `json:"encryption_keys"`
+	CurrentEncryptionKeyID uint64          `json:"current_encryption_key_id"`
+	// The users are kept with the token hashes.
+	Users      []*User  `json:"users"`
+	Schedulers []string `json:"schedulers"`
+}
+
+// Backup returns the metadata of the bootstrapped cluster.
+func (s *Server) Backup() (*Backup, error) {
+	cluster := s.GetRaftCluster()
+	if cluster == nil {
+		return nil, errors.Trace(errNotBootstrapped)
+	}
+
+	b := &Backup{
+		CreateTime: time.Now(),
+		ClusterID:  s.clusterID,
+		Meta:       &metapb.Cluster{},
+		Stores:     cluster.GetStores(),
+	}
+	schedulers, err := s.handler.GetSchedulers()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	b.Schedulers = schedulers
+	value, err := getValue(s.client, s.getAllocIDPath())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if value != nil {
+		if b.AllocID, err = bytesToUint64(value); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	if b.Timestamp, _, err = loadTimestamp(s.client, s.getTimestampPath()); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if b.ExternalTimestamp, err = s.kv.loadExternalTimestamp(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if b.GCSafePoint, err = s.kv.loadGCSafePoint(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if b.ServiceSafePoints, err = s.kv.loadServiceSafePoints(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if _, err = s.kv.loadMeta(b.Meta); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if b.ConfigVersions, err = s.kv.loadConfigVersions(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if b.FeatureGates, err = s.kv.loadFeatureGates(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if b.Webhooks, err = s.kv.loadWebhooks(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if b.Namespaces, err = s.kv.loadNamespaces(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if b.Keyspaces, err = s.kv.loadKeyspaces(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if b.EncryptionKeys, err = s.kv.loadEncryptedKeys(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if b.CurrentEncryptionKeyID, err = s.kv.loadCurrentEncryptionKeyID(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if b.Users, err = s.kv.loadUsers(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return b, nil
+}
+
+func readBackupFile(name string) (*Backup, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	b := &Backup{}
+	if err = json.Unmarshal(data, b); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if b.ClusterID == 0 || b.Meta == nil {
+		return nil, errors.Errorf("invalid backup file %s", name)
+	}
+	return b, nil
+}
+
+// restoreBackup writes the metadata of the backup file into a fresh cluster
+// before the cluster ID is initialized. It does nothing if the cluster is
+// restored already, e.g. the server is restarted with the same flag.
+func (s *Server) restoreBackup(name string) error {
+	b, err := readBackupFile(name)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	rootPath := path.Join(pdRootPath, strconv.FormatUint(b.ClusterID, 10))
+
+	value, err := getValue(s.client, pdClusterIDPath)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if value != nil {
+		clusterID, err := bytesToUint64(value)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if clusterID != b.ClusterID {
+			return errors.Errorf("cannot restore cluster %d into the existing cluster %d", b.ClusterID, clusterID)
+		}
+		log.Warnf("cluster %d is restored already, skip restoring %s", clusterID, name)
+		return nil
+	}
+	// The keys of the backup cluster may exist if the last restoring failed.
+	resp, err := kvGet(s.client, pdRootPath, clientv3.WithFirstCreate()...)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if len(resp.Kvs) > 0 && !strings.HasPrefix(string(resp.Kvs[0].Key), rootPath+"/") {
+		return errors.Errorf("cannot restore cluster %d into a non-empty cluster", b.ClusterID)
+	}
+
+	s.clusterID, s.rootPath = b.ClusterID, rootPath
+	kvs, err := b.kvs(s)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	batch := make(map[string]string, restoreBatchSize)
+	for key, value := range kvs {
+		batch[key] = value
+		if len(batch) < restoreBatchSize {
+			continue
+		}
+		if err = putKVs(s.client, batch); err != nil {
+			return errors.Trace(err)
+		}
+		batch = make(map[string]string, restoreBatchSize)
+	}
+	if err = putKVs(s.client, batch); err != nil {
+		return errors.Trace(err)
+	}
+
+	// The cluster ID is written at last, so the restoring is retried if any
+	// write above fails.
+	clusterID, err := initClusterIDValue(s.client, pdClusterIDPath, b.ClusterID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if clusterID != b.ClusterID {
+		return errors.Errorf("cluster %d is initialized while restoring cluster %d", clusterID, b.ClusterID)
+	}
+	log.Infof("cluster %d is restored from %s created at %v, %d stores", b.ClusterID, name, b.CreateTime, len(b.Stores))
+	if len(b.Schedulers) > 0 {
+		log.Infof("schedulers in the backup are not restored: %v", b.Schedulers)
+	}
+	return nil
+}
+
+// kvs returns the keys and values to restore. The alloc ID is increased by
+// restoreAllocIDMargin.
+func (b *Backup) kvs(s *Server) (map[string]string, error) {
+	kv := newKV(s)
+	kvs := make(map[string]string)
+	putProto := func(key string, msg proto.Message) error {
+		value, err := proto.Marshal(msg)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		kvs[key] = string(value)
+		return nil
+	}
+	putJSON := func(key string, v interface{}) error {
+		value, err := json.Marshal(v)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		kvs[key] = string(value)
+		return nil
+	}
+
+	kvs[s.getAllocIDPath()] = string(uint64ToBytes(b.AllocID + restoreAllocIDMargin))
+	if !b.Timestamp.IsZero() {
+		kvs[s.getTimestampPath()] = string(uint64ToBytes(uint64(b.Timestamp.UnixNano())))
+	}
+	if b.ExternalTimestamp != 0 {
+		kvs[kv.externalTimestampPath()] = string(uint64ToBytes(b.ExternalTimestamp))
+	}
+	if b.GCSafePoint != 0 {
+		kvs[kv.gcSafePointPath()] = string(uint64ToBytes(b.GCSafePoint))
+	}
+	for _, ssp := range b.ServiceSafePoints {
+		if err := putJSON(kv.serviceSafePointPath(ssp.ServiceID), ssp); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	if err := putProto(kv.clusterPath, b.Meta); err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, store := range b.Stores {
+		if err := putProto(kv.storePath(store.GetId()), store); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	for _, v := range b.ConfigVersions {
+		if err := putJSON(kv.configVersionPath(v.Version), v); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	for name, enabled := range b.FeatureGates {
+		kvs[kv.featureGatePath(name)] = strconv.FormatBool(enabled)
+	}
+	for _, hook := range b.Webhooks {
+		if err := putJSON(kv.webhookPath(hook.Name), hook); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	for _, ns := range b.Namespaces {
+		if err := putJSON(kv.namespacePath(ns.Name), ns); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	// The keyspace IDs are allocated after the last ID in the backup.
+	var lastKeyspaceID uint32
+	for _, keyspace := range b.Keyspaces {
+		if err := putJSON(kv.keyspacePath(keyspace.Name), keyspace); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if keyspace.ID > lastKeyspaceID {
+			lastKeyspaceID = keyspace.ID
+		}
+	}
+	if lastKeyspaceID > 0 {
+		kvs[kv.keyspaceAllocIDPath()] = string(uint64ToBytes(uint64(lastKeyspaceID)))
+	}
+	for _, ek := range b.EncryptionKeys {
+		if err := putJSON(kv.encryptionKeyPath(ek.ID), ek); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	if b.CurrentEncryptionKeyID != 0 {
+		kvs[kv.currentEncryptionKeyPath()] = string(uint64ToBytes(b.CurrentEncryptionKeyID))
+	}
+	for _, user := range b.Users {
+		if err := putJSON(kv.userPath(user.Name), user); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return kvs, nil
+}
+
+func putKVs(c *clientv3.Client, kvs map[string]string) error {
+	if len(kvs) == 0 {
+		return nil
+	}
+	ops := make([]clientv3.Op, 0, len(kvs))
+	for key, value := range kvs {
+		ops = append(ops, clientv3.OpPut(key, value))
+	}
+
+	ctx, cancel := context.WithTimeout(c.Ctx(), requestTimeout)
+	defer cancel()
+	_, err := c.Txn(ctx).Then(ops...).Commit()
+	return errors.Trace(err)
+}
@@ -0,0 +1,116 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testBackupSuite{})
+
+type testBackupSuite struct {
+	testClusterBaseSuite
+}
+
+func (s *testBackupSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustRunTestServer(c)
+	s.client = s.svr.client
+}
+
+func (s *testBackupSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testBackupSuite) TestBackupRestore(c *C) {
+	_, err := s.svr.Backup()
+	c.Assert(err, NotNil)
+
+	conn, err := rpcConnect(s.svr.GetAddr())
+	c.Assert(err, IsNil)
+	defer conn.Close()
+	clusterID := s.svr.clusterID
+	s.bootstrapCluster(c, conn, clusterID, "127.0.0.1:0")
+	store := s.newStore(c, 0, "127.0.0.1:1")
+	c.Assert(putStore(c, conn, clusterID, store).PutStore, NotNil)
+	c.Assert(s.svr.SetFeatureGate(FeatureLabelProperty, false), IsNil)
+	c.Assert(s.svr.UpdateGCSafePoint(100), IsNil)
+	_, err = s.svr.UpdateServiceSafePoint("br", 3600, 200)
+	c.Assert(err, IsNil)
+	c.Assert(s.svr.CreateNamespace("ns1"), IsNil)
+	_, err = s.svr.CreateKeyspace("ks1")
+	c.Assert(err, IsNil)
+
+	b, err := s.svr.Backup()
+	c.Assert(err, IsNil)
+	c.Assert(b.ClusterID, Equals, clusterID)
+	c.Assert(b.AllocID, Greater, uint64(0))
+	c.Assert(b.GCSafePoint, Equals, uint64(100))
+	c.Assert(b.ServiceSafePoints, HasLen, 1)
+	c.Assert(b.Stores, HasLen, 2)
+	c.Assert(b.FeatureGates, DeepEquals, map[string]bool{FeatureLabelProperty: false})
+	c.Assert(b.Schedulers, Not(HasLen), 0)
+
+	dir, err := ioutil.TempDir("", "pd_backup")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	file := path.Join(dir, "backup.json")
+	data, err := json.Marshal(b)
+	c.Assert(err, IsNil)
+	c.Assert(ioutil.WriteFile(file, data, 0600), IsNil)
+
+	// Restore into a fresh cluster.
+	cfg := NewTestSingleConfig()
+	cfg.restoreFile = file
+	svr, err := NewServer(cfg)
+	c.Assert(err, IsNil)
+	defer cleanServer(cfg)
+	defer svr.Close()
+	go svr.Run()
+	mustWaitLeader(c, []*Server{svr})
+
+	c.Assert(svr.ClusterID(), Equals, clusterID)
+	cluster := svr.GetRaftCluster()
+	c.Assert(cluster, NotNil)
+	_, _, err = cluster.GetStore(store.GetId())
+	c.Assert(err, IsNil)
+	c.Assert(svr.GetFeatureGates()[FeatureLabelProperty], IsFalse)
+	safePoint, err := svr.GetGCSafePoint()
+	c.Assert(err, IsNil)
+	c.Assert(safePoint, Equals, uint64(100))
+	ssps, err := svr.GetServiceSafePoints()
+	c.Assert(err, IsNil)
+	c.Assert(ssps, DeepEquals, b.ServiceSafePoints)
+	namespaces, err := svr.GetNamespaces()
+	c.Assert(err, IsNil)
+	c.Assert(namespaces, DeepEquals, []*Namespace{{Name: "ns1"}})
+	keyspace, err := svr.CreateKeyspace("ks2")
+	c.Assert(err, IsNil)
+	c.Assert(keyspace.ID, Equals, uint32(2))
+	id, err := svr.idAlloc.Alloc()
+	c.Assert(err, IsNil)
+	c.Assert(id, Greater, b.AllocID+restoreAllocIDMargin)
+
+	// It is skipped if the cluster is restored already.
+	c.Assert(svr.restoreBackup(file), IsNil)
+	b.ClusterID++
+	data, err = json.Marshal(b)
+	c.Assert(err, IsNil)
+	c.Assert(ioutil.WriteFile(file, data, 0600), IsNil)
+	c.Assert(svr.restoreBackup(file), NotNil)
+}
@@ -14,6 +14,8 @@
 package server
 
 import (
+	"math"
+	"sync"
 	"time"
 
 	"github.com/ngaut/log"
@@ -33,6 +35,8 @@ func newBalanceLeaderScheduler(opt *scheduleOption) *balanceLeaderScheduler {
 	filters = append(filters, newStateFilter(opt))
 	filters = append(filters, newHealthFilter(opt))
 	filters = append(filters, newLeaderCountFilter(opt))
+	filters = append(filters, newRejectLeaderFilter(opt))
+	filters = append(filters, newEngineFilter(opt))
 
 	return &balanceLeaderScheduler{
 		opt:      opt,
@@ -56,15 +60,35 @@ func (l *balanceLeaderScheduler) Prepare(cluster *clusterInfo) error { return ni
 
 func (l *balanceLeaderScheduler) Cleanup(cluster *clusterInfo) {}
 
+// Schedule balances the leaders within every namespace.
 func (l *balanceLeaderScheduler) Schedule(cluster *clusterInfo) Operator {
-	region, newLeader := scheduleTransferLeader(cluster, l.selector)
+	classifier := l.opt.GetClassifier()
+	for _, namespace := range classifier.GetAllNamespaces() {
+		if l.opt.IsSchedulerDisabled(namespace, l.GetName()) {
+			continue
+		}
+		if op := l.scheduleNamespace(cluster, classifier, namespace); op != nil {
+			return op
+		}
+	}
+	return nil
+}
+
+func (l *balanceLeaderScheduler) scheduleNamespace(cluster *clusterInfo, classifier Classifier, namespace string) Operator {
+	region, newLeader := scheduleTransferLeader(cluster, l.GetName(), l.selector, newNamespaceFilter(classifier, namespace))
 	if region == nil {
 		return nil
 	}
+	// The region is moved to its namespace by the namespace checker.
+	if classifier.GetRegionNamespace(region.Region) != namespace {
+		recordSchedule(l.GetName(), scheduleOtherNamespace)
+		return nil
+	}
 
 	source := cluster.getStore(region.Leader.GetStoreId())
 	target := cluster.getStore(newLeader.GetStoreId())
 	if source.leaderRatio()-target.leaderRatio() < l.opt.GetMinBalanceDiffRatio() {
+		recordSchedule(l.GetName(), scheduleSmallDiff)
 		return nil
 	}
 
@@ -73,7 +97,6 @@ func (l *balanceLeaderScheduler) Schedule(cluster *clusterInfo) Operator {
 
 type balanceStorageScheduler struct {
 	opt      *scheduleOption
-	rep      *Replication
 	cache    *idCache
 	selector Selector
 }
@@ -87,10 +110,12 @@ func newBalanceStorageScheduler(opt *scheduleOption) *balanceStorageScheduler {
 	filters = append(filters, newHealthFilter(opt))
 	filters = append(filters, newRegionCountFilter(opt))
 	filters = append(filters, newSnapshotCountFilter(opt))
+	filters = append(filters, newSlowStoreFilter(opt))
+	filters = append(filters, newEngineFilter(opt))
+	filters = append(filters, newEnginePeerFilter(opt))
 
 	return &balanceStorageScheduler{
 		opt:      opt,
-		rep:      opt.GetReplication(),
 		cache:    cache,
 		selector: newBalanceSelector(regionKind, filters),
 	}
@@ -112,15 +137,35 @@ func (s *balanceStorageScheduler) Prepare(cluster *clusterInfo) error { return n
 
 func (s *balanceStorageScheduler) Cleanup(cluster *clusterInfo) {}
 
+// Schedule balances the storage within every namespace.
 func (s *balanceStorageScheduler) Schedule(cluster *clusterInfo) Operator {
+	classifier := s.opt.GetClassifier()
+	for _, namespace := range classifier.GetAllNamespaces() {
+		if s.opt.IsSchedulerDisabled(namespace, s.GetName()) {
+			continue
+		}
+		if op := s.scheduleNamespace(cluster, classifier, namespace); op != nil {
+			return op
+		}
+	}
+	return nil
+}
+
+func (s *balanceStorageScheduler) scheduleNamespace(cluster *clusterInfo, classifier Classifier, namespace string) Operator {
 	// Select a peer from the store with largest storage ratio.
-	region, oldPeer := scheduleRemovePeer(cluster, s.selector)
+	region, oldPeer := scheduleRemovePeer(cluster, s.GetName(), s.selector, newNamespaceFilter(classifier, namespace))
 	if region == nil {
 		return nil
 	}
+	if classifier.GetRegionNamespace(region.Region) != namespace {
+		recordSchedule(s.GetName(), scheduleOtherNamespace)
+		return nil
+	}
 
 	// We don't schedule region with abnormal number of replicas.
-	if len(region.GetPeers()) != s.rep.GetMaxReplicas() {
+	region = withoutEnginePeers(cluster, s.opt, region)
+	if len(region.GetPeers()) != s.opt.GetRegionMaxReplicas(region.Region) {
+		recordSchedule(s.GetName(), scheduleAbnormalReplicas)
 		return nil
 	}
 
@@ -137,16 +182,18 @@ func (s *balanceStorageScheduler) transferPeer(cluster *clusterInfo, region *reg
 	// scoreGuard guarantees that the distinct score will not decrease.
 	stores := cluster.getRegionStores(region)
 	source := cluster.getStore(oldPeer.GetStoreId())
-	scoreGuard := newDistinctScoreFilter(s.rep, stores, source)
+	scoreGuard := newDistinctScoreFilter(s.opt.GetRegionReplication(region.Region), stores, source)
 
 	checker := newReplicaChecker(s.opt, cluster)
 	newPeer, _ := checker.selectBestPeer(region, scoreGuard)
 	if newPeer == nil {
+		recordSchedule(s.GetName(), scheduleNoTarget)
 		return nil
 	}
 
 	target := cluster.getStore(newPeer.GetStoreId())
 	if source.storageRatio()-target.storageRatio() < s.opt.GetMinBalanceDiffRatio() {
+		recordSchedule(s.GetName(), scheduleSmallDiff)
 		return nil
 	}
 
@@ -156,9 +203,14 @@ func (s *balanceStorageScheduler) transferPeer(cluster *clusterInfo, region *reg
 // replicaChecker ensures region has the best replicas.
 type replicaChecker struct {
 	opt     *scheduleOption
-	rep     *Replication
 	cluster *clusterInfo
 	filters []Filter
+	// limiter limits the rate to add or remove replicas for max-replicas.
+	limiter *rateLimiter
+	// recovering are the regions whose down peers are removed, adding the
+	// replicas back to them is not limited.
+	recoveringLock sync.Mutex
+	recovering     map[uint64]struct{}
 }
 
 func newReplicaChecker(opt *scheduleOption, cluster *clusterInfo) *replicaChecker {
@@ -166,16 +218,19 @@ func newReplicaChecker(opt *scheduleOption, cluster *clusterInfo) *replicaChecke
 	filters = append(filters, newHealthFilter(opt))
 	filters = append(filters, newSnapshotCountFilter(opt))
 	filters = append(filters, newStorageThresholdFilter(opt))
+	filters = append(filters, newSlowStoreFilter(opt))
 
 	return &replicaChecker{
-		opt:     opt,
-		rep:     opt.GetReplication(),
-		cluster: cluster,
-		filters: filters,
+		opt:        opt,
+		cluster:    cluster,
+		filters:    filters,
+		limiter:    newRateLimiter(),
+		recovering: make(map[uint64]struct{}),
 	}
 }
 
 func (r *replicaChecker) Check(region *regionInfo) Operator {
+	region = withoutEnginePeers(r.cluster, r.opt, region)
 	if op := r.checkDownPeer(region); op != nil {
 		return op
 	}
@@ -183,30 +238,86 @@ func (r *replicaChecker) Check(region *regionInfo) Operator {
 		return op
 	}
 
-	if len(region.GetPeers()) < r.rep.GetMaxReplicas() {
+	if len(region.GetPeers()) != r.opt.GetRegionMaxReplicas(region.Region) {
+		return r.checkReplicaCount(region)
+	}
+	// The replicas are added back by others, e.g. the operators by the api.
+	r.setRecovering(region, false)
+
+	return r.checkBestReplacement(region)
+}
+
+// checkReplicaCount adds or removes a replica if the region does not have
+// max-replicas replicas. At most replica-change-rate operators are created
+// per second for max-replicas changes, so changing it does not flood the
+// cluster. The replicas lost by removing the down peers are added back
+// without the limit.
+func (r *replicaChecker) checkReplicaCount(region *regionInfo) Operator {
+	lacking := len(region.GetPeers()) < r.opt.GetRegionMaxReplicas(region.Region)
+	rate := r.opt.GetReplicaChangeRate()
+	if lacking && r.isRecovering(region) {
+		rate = 0
+	}
+	if !r.limiter.allow(rate) {
+		return nil
+	}
+
+	var op Operator
+	if lacking {
 		newPeer, _ := r.selectBestPeer(region, r.filters...)
 		if newPeer == nil {
 			return nil
 		}
-		return newAddPeer(region, newPeer)
-	}
-
-	if len(region.GetPeers()) > r.rep.GetMaxReplicas() {
+		op = newAddPeer(region, newPeer)
+		r.setRecovering(region, false)
+	} else {
 		oldPeer, _ := r.selectWorstPeer(region)
 		if oldPeer == nil {
 			return nil
 		}
-		return newRemovePeer(region, oldPeer)
+		op = newRemovePeer(region, oldPeer)
 	}
+	r.limiter.take(rate)
+	return op
+}
 
-	return r.checkBestReplacement(region)
+func (r *replicaChecker) isRecovering(region *regionInfo) bool {
+	r.recoveringLock.Lock()
+	defer r.recoveringLock.Unlock()
+	_, ok := r.recovering[region.GetId()]
+	return ok
+}
+
+func (r *replicaChecker) setRecovering(region *regionInfo, recovering bool) {
+	r.recoveringLock.Lock()
+	defer r.recoveringLock.Unlock()
+	if !recovering {
+		delete(r.recovering, region.GetId())
+		return
+	}
+	// The regions merged or removed before the replicas are added back are
+	// never checked again. A merged region stays in the cache, but its range
+	// belongs to another region.
+	for id := range r.recovering {
+		cached := r.cluster.getRegion(id)
+		if cached == nil {
+			delete(r.recovering, id)
+			continue
+		}
+		if owner := r.cluster.searchRegion(cached.GetStartKey()); owner == nil || owner.GetId() != id {
+			delete(r.recovering, id)
+		}
+	}
+	r.recovering[region.GetId()] = struct{}{}
 }
 
-// selectBestPeer returns the best peer in other stores.
 func (r *replicaChecker) selectBestPeer(region *regionInfo, filters ...Filter) (*metapb.Peer, float64) {
 	// Add some must have filters.
 	filters = append(filters, newStateFilter(r.opt))
+	filters = append(filters, newEngineFilter(r.opt))
 	filters = append(filters, newExcludedFilter(nil, region.GetStoreIds()))
+	classifier := r.opt.GetClassifier()
+	filters = append(filters, newNamespaceFilter(classifier, classifier.GetRegionNamespace(region.Region)))
 
 	var (
 		bestStore *storeInfo
@@ -215,12 +326,13 @@ func (r *replicaChecker) selectBestPeer(region *regionInfo, filters ...Filter) (
 
 	// Select the store with best distinct score.
 	// If the scores are the same, select the store with minimal storage ratio.
+	rep := r.opt.GetRegionReplication(region.Region)
 	stores := r.cluster.getRegionStores(region)
 	for _, store := range r.cluster.getStores() {
 		if filterTarget(store, filters) {
 			continue
 		}
-		score := r.rep.GetDistinctScore(stores, store)
+		score := rep.GetDistinctScore(stores, store)
 		if bestStore == nil || compareStoreScore(store, score, bestStore, bestScore) > 0 {
 			bestStore = store
 			bestScore = score
@@ -248,12 +360,13 @@ func (r *replicaChecker) selectWorstPeer(region *regionInfo, filters ...Filter)
 
 	// Select the store with lowest distinct score.
 	// If the scores are the same, select the store with maximal storage ratio.
+	rep := r.opt.GetRegionReplication(region.Region)
 	stores := r.cluster.getRegionStores(region)
 	for _, store := range stores {
 		if filterSource(store, filters) {
 			continue
 		}
-		score := r.rep.GetDistinctScore(stores, store)
+		score := rep.GetDistinctScore(stores, store)
 		if worstStore == nil || compareStoreScore(store, score, worstStore, worstScore) < 0 {
 			worstStore = store
 			worstScore = score
@@ -287,6 +400,7 @@ func (r *replicaChecker) checkDownPeer(region *regionInfo) Operator {
 		if stats.GetDownSeconds() < uint64(r.opt.GetMaxStoreDownTime().Seconds()) {
 			continue
 		}
+		r.setRecovering(region, true)
 		return newRemovePeer(region, peer)
 	}
 	return nil
@@ -308,7 +422,12 @@ func (r *replicaChecker) checkOfflinePeer(region *regionInfo) Operator {
 	return nil
 }
 
+// checkBestReplacement moves a replica to the store with better location
+// isolation, if the location replacement feature gate is enabled.
 func (r *replicaChecker) checkBestReplacement(region *regionInfo) Operator {
+	if !r.opt.IsFeatureEnabled(FeatureLocationReplacement) {
+		return nil
+	}
 	oldPeer, oldScore := r.selectWorstPeer(region)
 	if oldPeer == nil {
 		return nil
@@ -323,3 +442,52 @@ func (r *replicaChecker) checkBestReplacement(region *regionInfo) Operator {
 	}
 	return newTransferPeer(region, oldPeer, newPeer)
 }
+
+// rateLimiter is a token bucket which holds at most one second of tokens,
+// the rate is passed in every call so config changes take effect at once.
+// It does not limit if the rate is not positive.
+type rateLimiter struct {
+	sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter returns a full limiter.
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{}
+}
+
+// allow returns true if there is a token.
+func (l *rateLimiter) allow(rate float64) bool {
+	if rate <= 0 {
+		return true
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	l.refill(rate)
+	return l.tokens >= 1
+}
+
+// take takes a token.
+func (l *rateLimiter) take(rate float64) {
+	if rate <= 0 {
+		return
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	l.refill(rate)
+	l.tokens--
+}
+
+func (l *rateLimiter) refill(rate float64) {
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * rate
+	l.last = now
+	if burst := math.Max(rate, 1); l.tokens > burst {
+		l.tokens = burst
+	}
+}
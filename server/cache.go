@@ -14,6 +14,7 @@
 package server
 
 import (
+	"sort"
 	"sync"
 	"time"
 
@@ -92,14 +93,23 @@ func (s *storesInfo) unblockStore(storeID uint64) {
 	store.unblock()
 }
 
+// getStores returns the stores ordered by ID, so the stores of the same
+// score are selected in the same order, which the replay depends on.
 func (s *storesInfo) getStores() []*storeInfo {
 	stores := make([]*storeInfo, 0, len(s.stores))
 	for _, store := range s.stores {
 		stores = append(stores, store.clone())
 	}
+	sort.Sort(storesByID(stores))
 	return stores
 }
 
+type storesByID []*storeInfo
+
+func (s storesByID) Len() int           { return len(s) }
+func (s storesByID) Less(i, j int) bool { return s[i].GetId() < s[j].GetId() }
+func (s storesByID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
 func (s *storesInfo) getMetaStores() []*metapb.Store {
 	stores := make([]*metapb.Store, 0, len(s.stores))
 	for _, store := range s.stores {
@@ -196,6 +206,24 @@ func (r *regionsInfo) searchRegion(regionKey []byte) *regionInfo {
 	return r.getRegion(region.GetId())
 }
 
+func (r *regionsInfo) scanRegions(regionKey []byte, limit int) []*regionInfo {
+	var regions []*regionInfo
+	for _, region := range r.tree.scan(regionKey, limit) {
+		if info := r.getRegion(region.GetId()); info != nil {
+			regions = append(regions, info)
+		}
+	}
+	return regions
+}
+
+func (r *regionsInfo) getPrevRegion(regionKey []byte) *regionInfo {
+	region := r.tree.getPrev(regionKey)
+	if region == nil {
+		return nil
+	}
+	return r.getRegion(region.GetId())
+}
+
 func (r *regionsInfo) getRegions() []*regionInfo {
 	regions := make([]*regionInfo, 0, len(r.regions))
 	for _, region := range r.regions {
@@ -228,6 +256,14 @@ func (r *regionsInfo) getStoreFollowerCount(storeID uint64) int {
 	return len(r.followers[storeID])
 }
 
+func (r *regionsInfo) getStoreLeaderRegions(storeID uint64) []*regionInfo {
+	regions := make([]*regionInfo, 0, len(r.leaders[storeID]))
+	for _, region := range r.leaders[storeID] {
+		regions = append(regions, region.clone())
+	}
+	return regions
+}
+
 func (r *regionsInfo) randLeaderRegion(storeID uint64) *regionInfo {
 	return randRegion(r.leaders[storeID])
 }
@@ -260,6 +296,9 @@ type clusterInfo struct {
 	meta    *metapb.Cluster
 	stores  *storesInfo
 	regions *regionsInfo
+	// regionSaver batches the region writes, the regions are saved at once
+	// if it is nil.
+	regionSaver *regionSaver
 }
 
 func newClusterInfo(id IDAllocator) *clusterInfo {
@@ -359,6 +398,25 @@ func (c *clusterInfo) putStoreLocked(store *storeInfo) error {
 	return nil
 }
 
+func (c *clusterInfo) putStores(stores []*storeInfo) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.kv != nil {
+		metas := make([]*metapb.Store, 0, len(stores))
+		for _, store := range stores {
+			metas = append(metas, store.Store)
+		}
+		if err := c.kv.saveStores(metas); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	for _, store := range stores {
+		c.stores.setStore(store.clone())
+	}
+	return nil
+}
+
 func (c *clusterInfo) blockStore(storeID uint64) error {
 	c.Lock()
 	defer c.Unlock()
@@ -401,14 +459,30 @@ func (c *clusterInfo) searchRegion(regionKey []byte) *regionInfo {
 	return c.regions.searchRegion(regionKey)
 }
 
+func (c *clusterInfo) scanRegions(regionKey []byte, limit int) []*regionInfo {
+	c.RLock()
+	defer c.RUnlock()
+	return c.regions.scanRegions(regionKey, limit)
+}
+
+func (c *clusterInfo) getPrevRegion(regionKey []byte) *regionInfo {
+	c.RLock()
+	defer c.RUnlock()
+	return c.regions.getPrevRegion(regionKey)
+}
+
 func (c *clusterInfo) putRegion(region *regionInfo) error {
 	c.Lock()
 	defer c.Unlock()
 	return c.putRegionLocked(region.clone())
 }
 
+// putRegionLocked saves the region by the region saver if there is one, so
+// the region is saved later in a batch.
 func (c *clusterInfo) putRegionLocked(region *regionInfo) error {
-	if c.kv != nil {
+	if c.regionSaver != nil {
+		c.regionSaver.add(region.Region)
+	} else if c.kv != nil {
 		if err := c.kv.saveRegion(region.Region); err != nil {
 			return errors.Trace(err)
 		}
@@ -447,6 +521,12 @@ func (c *clusterInfo) getStoreLeaderCount(storeID uint64) int {
 	return c.regions.getStoreLeaderCount(storeID)
 }
 
+func (c *clusterInfo) getStoreLeaderRegions(storeID uint64) []*regionInfo {
+	c.RLock()
+	defer c.RUnlock()
+	return c.regions.getStoreLeaderRegions(storeID)
+}
+
 func (c *clusterInfo) randLeaderRegion(storeID uint64) *regionInfo {
 	c.RLock()
 	defer c.RUnlock()
@@ -14,8 +14,6 @@
 package server
 
 import (
-	"sync/atomic"
-
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
@@ -210,7 +208,7 @@ func (s *testClusterInfoSuite) Test(c *C) {
 	// Test without kv.
 	{
 		for _, test := range tests {
-			cluster := newClusterInfo(newMockIDAllocator())
+			cluster := newClusterInfo(NewMockIDAllocator())
 			test(c, cluster)
 		}
 	}
@@ -488,16 +486,3 @@ func (s *testClusterUtilSuite) TestCheckStaleRegion(c *C) {
 	c.Assert(checkStaleRegion(origin, region), IsNil)
 	c.Assert(checkStaleRegion(region, origin), NotNil)
 }
-
-// mockIDAllocator mocks IDAllocator and it is only used for test.
-type mockIDAllocator struct {
-	base uint64
-}
-
-func newMockIDAllocator() *mockIDAllocator {
-	return &mockIDAllocator{base: 0}
-}
-
-func (alloc *mockIDAllocator) Alloc() (uint64, error) {
-	return atomic.AddUint64(&alloc.base, 1), nil
-}
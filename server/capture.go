@@ -0,0 +1,268 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"golang.org/x/net/context"
+)
+
+const (
+	// heartbeatCaptureVersion is bumped when the format of the capture
+	// changes.
+	heartbeatCaptureVersion = 1
+	// captureBufferSize is the max number of heartbeats waiting to be
+	// written, the capture is aborted if the writer falls behind more.
+	captureBufferSize = 10000
+)
+
+var errCaptureOverflow = errors.New("heartbeats are too many to capture")
+
+// CaptureHeader is written at the beginning of a capture, it has the cluster
+// state and the options the heartbeats are applied to.
+type CaptureHeader struct {
+	Version       int
+	Snapshot      *ClusterSnapshot
+	Schedule      ScheduleConfig
+	Replication   ReplicationConfig
+	LabelProperty LabelPropertyConfig
+	FeatureGates  FeatureGates
+	Namespaces    []*Namespace
+	// LastID is the last ID allocated by the leader, the IDs replayed are
+	// allocated after it.
+	LastID uint64
+}
+
+// CaptureRecord is a heartbeat handled by the leader and its response, or
+// an operator which is not generated by the heartbeats, i.e. added by the
+// schedulers or manually, or canceled.
+type CaptureRecord struct {
+	Time     time.Time
+	Request  *pdpb.Request
+	Response *pdpb.Response
+	Operator *regionOperator
+	Canceled bool
+}
+
+// captureRecord is the encoded CaptureRecord. The messages are marshaled by
+// protobuf, gob drops the pointers to zero values, e.g. the change type of
+// adding a peer.
+type captureRecord struct {
+	Time     time.Time
+	Request  []byte
+	Response []byte
+	Operator *regionOperator
+	Canceled bool
+}
+
+func (r *CaptureRecord) encode() (*captureRecord, error) {
+	record := &captureRecord{
+		Time:     r.Time,
+		Operator: r.Operator,
+		Canceled: r.Canceled,
+	}
+	if r.Request != nil {
+		var err error
+		if record.Request, err = proto.Marshal(r.Request); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if record.Response, err = proto.Marshal(r.Response); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return record, nil
+}
+
+func (r *captureRecord) decode() (*CaptureRecord, error) {
+	record := &CaptureRecord{
+		Time:     r.Time,
+		Operator: r.Operator,
+		Canceled: r.Canceled,
+	}
+	if r.Request != nil {
+		record.Request, record.Response = &pdpb.Request{}, &pdpb.Response{}
+		if err := proto.Unmarshal(r.Request, record.Request); err != nil {
+			return nil, errors.Trace(err)
+		}
+		if err := proto.Unmarshal(r.Response, record.Response); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return record, nil
+}
+
+// heartbeatCaptures dispatches the heartbeats handled by the leader and the
+// operators to the running captures. A nil heartbeatCaptures captures nothing.
+type heartbeatCaptures struct {
+	sync.Mutex
+	// count is the number of the running captures, read without the lock.
+	count    int32
+	captures map[*heartbeatCapture]struct{}
+}
+
+type heartbeatCapture struct {
+	records  chan *CaptureRecord
+	overflow chan struct{}
+}
+
+func newHeartbeatCaptures() *heartbeatCaptures {
+	return &heartbeatCaptures{captures: make(map[*heartbeatCapture]struct{})}
+}
+
+func (h *heartbeatCaptures) add() *heartbeatCapture {
+	h.Lock()
+	defer h.Unlock()
+
+	capture := &heartbeatCapture{
+		records:  make(chan *CaptureRecord, captureBufferSize),
+		overflow: make(chan struct{}),
+	}
+	h.captures[capture] = struct{}{}
+	atomic.StoreInt32(&h.count, int32(len(h.captures)))
+	return capture
+}
+
+func (h *heartbeatCaptures) remove(capture *heartbeatCapture) {
+	h.Lock()
+	defer h.Unlock()
+
+	delete(h.captures, capture)
+	atomic.StoreInt32(&h.count, int32(len(h.captures)))
+}
+
+// record sends the heartbeat to the captures. The request and response are
+// copied, they are changed after being handled.
+func (h *heartbeatCaptures) record(req *pdpb.Request, resp *pdpb.Response) {
+	if h == nil || atomic.LoadInt32(&h.count) == 0 {
+		return
+	}
+	h.send(&CaptureRecord{
+		Time:     time.Now(),
+		Request:  proto.Clone(req).(*pdpb.Request),
+		Response: proto.Clone(resp).(*pdpb.Response),
+	})
+}
+
+// recordOperator sends the operator to the captures, the operator is copied
+// since its index changes later.
+func (h *heartbeatCaptures) recordOperator(op *regionOperator, canceled bool) {
+	if h == nil || atomic.LoadInt32(&h.count) == 0 {
+		return
+	}
+	clone := *op
+	clone.Region = op.Region.clone()
+	h.send(&CaptureRecord{
+		Time:     time.Now(),
+		Operator: &clone,
+		Canceled: canceled,
+	})
+}
+
+func (h *heartbeatCaptures) send(record *CaptureRecord) {
+	h.Lock()
+	defer h.Unlock()
+
+	for capture := range h.captures {
+		select {
+		case capture.records <- record:
+		default:
+			// The capture misses the record, it can't be replayed.
+			close(capture.overflow)
+			delete(h.captures, capture)
+		}
+	}
+	atomic.StoreInt32(&h.count, int32(len(h.captures)))
+}
+
+// CaptureHeartbeats writes the cluster state and the heartbeats handled
+// until the context is done. The capture is encoded by gob and compressed by
+// gzip, it is replayed by NewReplayer.
+func (s *Server) CaptureHeartbeats(ctx context.Context, w io.Writer) error {
+	cluster := s.GetRaftCluster()
+	if cluster == nil {
+		return errors.Trace(errNotBootstrapped)
+	}
+
+	// The capture is added before the snapshot, so no heartbeat is missed.
+	capture := s.captures.add()
+	defer s.captures.remove(capture)
+
+	header, err := s.captureHeader(cluster)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	zw := gzip.NewWriter(w)
+	enc := gob.NewEncoder(zw)
+	if err = enc.Encode(header); err != nil {
+		return errors.Trace(err)
+	}
+
+	log.Info("start capturing heartbeats")
+	count := 0
+	for done := false; !done; {
+		select {
+		case record := <-capture.records:
+			encoded, err := record.encode()
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if err = enc.Encode(encoded); err != nil {
+				return errors.Trace(err)
+			}
+			count++
+		case <-capture.overflow:
+			return errors.Annotatef(errCaptureOverflow, "%d heartbeats are captured", count)
+		case <-ctx.Done():
+			done = true
+		}
+	}
+	log.Infof("%d heartbeats are captured", count)
+	return errors.Trace(zw.Close())
+}
+
+func (s *Server) captureHeader(cluster *RaftCluster) (*CaptureHeader, error) {
+	namespaces, err := s.kv.loadNamespaces()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	status, err := s.idAlloc.Status()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	// The leader reserves the IDs after the end if it uses up the reserved.
+	lastID := status.Last
+	if lastID == 0 || lastID >= status.End {
+		lastID = status.End
+	}
+	return &CaptureHeader{
+		Version:       heartbeatCaptureVersion,
+		Snapshot:      cluster.snapshot(),
+		Schedule:      *s.scheduleOpt.load(),
+		Replication:   *s.scheduleOpt.GetReplication().cfg,
+		LabelProperty: s.scheduleOpt.GetLabelProperty(),
+		FeatureGates:  s.scheduleOpt.GetFeatureGates(),
+		Namespaces:    namespaces,
+		LastID:        lastID,
+	}, nil
+}
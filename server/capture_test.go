@@ -0,0 +1,94 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"io"
+	"sync/atomic"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"golang.org/x/net/context"
+)
+
+func (s *testClusterWorkerSuite) TestCaptureReplay(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var buf bytes.Buffer
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.svr.CaptureHeartbeats(ctx, &buf)
+	}()
+	for atomic.LoadInt32(&s.svr.captures.count) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	leaderPd := mustGetLeader(c, s.client, s.svr.getLeaderPath())
+	conn, err := rpcConnect(leaderPd.GetAddr())
+	c.Assert(err, IsNil)
+	defer conn.Close()
+
+	cluster := s.svr.GetRaftCluster()
+	msgID := uint64(0)
+	for _, store := range cluster.GetStores() {
+		msgID++
+		s.heartbeatStore(c, conn, msgID, &pdpb.StoreStats{
+			StoreId:   store.GetId(),
+			Capacity:  100,
+			Available: 50,
+		})
+	}
+	// The region has 1 peer, the replica checker adds peers to it, and the
+	// operator is canceled and added again.
+	region, _ := cluster.getRegion([]byte("a"))
+	leader := region.Peers[0]
+	var changePeers []*pdpb.ChangePeer
+	for i := 0; i < 3; i++ {
+		msgID++
+		changePeers = append(changePeers, heartbeatRegion(c, conn, s.clusterID, msgID, region, leader))
+		c.Assert(changePeers[i], NotNil)
+		if i == 1 {
+			c.Assert(s.svr.GetHandler().RemoveOperator(region.GetId()), IsNil)
+		}
+	}
+	c.Assert(changePeers[1], DeepEquals, changePeers[0])
+	c.Assert(changePeers[2].GetPeer().GetId(), Not(Equals), changePeers[0].GetPeer().GetId())
+
+	cancel()
+	c.Assert(<-errCh, IsNil)
+	capture := buf.Bytes()
+
+	// The replays get the same responses as the captured.
+	for i := 0; i < 2; i++ {
+		replayer, err := NewReplayer(bytes.NewReader(capture))
+		c.Assert(err, IsNil)
+		c.Assert(replayer.Header().Snapshot.Regions, HasLen, 1)
+		var replayed []*pdpb.ChangePeer
+		for {
+			result, err := replayer.Next()
+			if err == io.EOF {
+				break
+			}
+			c.Assert(err, IsNil)
+			c.Assert(result.Diverged, IsFalse, Commentf("captured %v, replayed %v", result.Record.Response, result.Response))
+			if result.Record.Request.GetCmdType() == pdpb.CommandType_RegionHeartbeat {
+				replayed = append(replayed, result.Response.GetRegionHeartbeat().GetChangePeer())
+			}
+		}
+		c.Assert(replayed, DeepEquals, changePeers)
+		c.Assert(replayer.Close(), IsNil)
+	}
+}
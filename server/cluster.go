@@ -16,6 +16,7 @@ package server
 import (
 	"fmt"
 	"path"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -53,6 +54,11 @@ type RaftCluster struct {
 
 	coordinator *coordinator
 
+	// The down stores which the events are published for, it is only
+	// accessed by the background jobs.
+	downStores         map[uint64]struct{}
+	unavailableRegions *unavailableRegions
+
 	wg   sync.WaitGroup
 	quit chan struct{}
 }
@@ -83,13 +89,27 @@ func (c *RaftCluster) start() error {
 		return nil
 	}
 	c.cachedCluster = cluster
+	saver := newRegionSaver(c.s.kv, c.s.cfg.RegionFlushInterval.Duration)
+	saver.syncer = c.s.regionSyncer
+	c.cachedCluster.regionSaver = saver
 
 	c.coordinator = newCoordinator(c.cachedCluster, c.s.scheduleOpt)
+	c.coordinator.bus = c.s.eventBus
+	c.coordinator.captures = c.s.captures
+	if c.s.cfg.EnableScheduleThrottle {
+		c.coordinator.throttle = c.s.scheduleThrottle
+	}
 	c.coordinator.run()
+	c.downStores = make(map[uint64]struct{})
+	c.unavailableRegions = newUnavailableRegions(c.s.eventBus)
 
-	c.wg.Add(1)
+	c.wg.Add(2)
 	c.quit = make(chan struct{})
 	go c.runBackgroundJobs(backgroundJobInterval)
+	go func() {
+		defer c.wg.Done()
+		saver.run(c.quit)
+	}()
 
 	c.running = true
 
@@ -130,6 +150,21 @@ func (s *Server) SetScheduleConfig(cfg ScheduleConfig) {
 	s.scheduleOpt.store(&cfg)
 }
 
+func (s *Server) setLabelProperty(cfg LabelPropertyConfig) {
+	s.cfg.LabelProperty = cfg.clone()
+	s.scheduleOpt.SetLabelProperty(cfg)
+}
+
+// SetReplicationConfig sets the replication config, the replication of the
+// schedule option shares the config with the server.
+func (s *Server) SetReplicationConfig(cfg ReplicationConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return errors.Trace(err)
+	}
+	s.cfg.Replication = cfg
+	return nil
+}
+
 func (s *Server) getClusterRootPath() string {
 	return path.Join(s.rootPath, "raft")
 }
@@ -237,9 +272,13 @@ func (s *Server) bootstrapCluster(req *pdpb.BootstrapRequest) (*pdpb.Response, e
 		return nil, errors.Trace(err)
 	}
 
-	// Set region meta with region id.
-	regionPath := makeRegionKey(clusterRootPath, req.GetRegion().GetId())
-	ops = append(ops, clientv3.OpPut(regionPath, string(regionValue)))
+	// Set region meta with region id, it is saved after bootstrapping if the
+	// regions are not saved in etcd.
+	localRegion := s.cfg.RegionStorage == RegionStorageLocal
+	if !localRegion {
+		regionPath := makeRegionKey(clusterRootPath, req.GetRegion().GetId())
+		ops = append(ops, clientv3.OpPut(regionPath, string(regionValue)))
+	}
 
 	// TODO: we must figure out a better way to handle bootstrap failed, maybe intervene manually.
 	bootstrapCmp := clientv3.Compare(clientv3.CreateRevision(clusterRootPath), "=", 0)
@@ -252,6 +291,12 @@ func (s *Server) bootstrapCluster(req *pdpb.BootstrapRequest) (*pdpb.Response, e
 		return newBootstrappedError(), nil
 	}
 
+	if localRegion {
+		if err = s.kv.saveRegion(req.GetRegion()); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+
 	log.Infof("bootstrap cluster %d ok", clusterID)
 
 	if err := s.cluster.start(); err != nil {
@@ -280,11 +325,128 @@ func (c *RaftCluster) GetRegionByID(regionID uint64) (*metapb.Region, *metapb.Pe
 	return region.Region, region.Leader
 }
 
+// GetPrevRegion gets the region before the region of the key and its leader.
+func (c *RaftCluster) GetPrevRegion(regionKey []byte) (*metapb.Region, *metapb.Peer) {
+	region := c.cachedCluster.getPrevRegion(regionKey)
+	if region == nil {
+		return nil, nil
+	}
+	return region.Region, region.Leader
+}
+
+// ScanRegions gets at most limit regions in key order from the region of the
+// key.
+func (c *RaftCluster) ScanRegions(regionKey []byte, limit int) []*metapb.Region {
+	regions := c.cachedCluster.scanRegions(regionKey, limit)
+	metaRegions := make([]*metapb.Region, 0, len(regions))
+	for _, region := range regions {
+		metaRegions = append(metaRegions, region.Region)
+	}
+	return metaRegions
+}
+
 // GetRegions gets regions from cluster.
 func (c *RaftCluster) GetRegions() []*metapb.Region {
 	return c.cachedCluster.getMetaRegions()
 }
 
+// GetMissPeerRegions gets the regions with fewer peers than max replicas.
+func (c *RaftCluster) GetMissPeerRegions() []*metapb.Region {
+	return c.filterRegions(c.isMissPeerRegion)
+}
+
+// GetDownPeerRegions gets the regions with down peers.
+func (c *RaftCluster) GetDownPeerRegions() []*metapb.Region {
+	return c.filterRegions(isDownPeerRegion)
+}
+
+// GetPendingPeerRegions gets the regions with pending peers.
+func (c *RaftCluster) GetPendingPeerRegions() []*metapb.Region {
+	return c.filterRegions(isPendingPeerRegion)
+}
+
+// GetOfflinePeerRegions gets the regions with peers on offline stores.
+func (c *RaftCluster) GetOfflinePeerRegions() []*metapb.Region {
+	return c.filterRegions(c.isOfflinePeerRegion)
+}
+
+// GetUnavailableRegions gets the unavailable regions sorted by the time they
+// are found, it does not scan the regions.
+func (c *RaftCluster) GetUnavailableRegions() []*UnavailableRegion {
+	return c.unavailableRegions.list()
+}
+
+// ReplicationProgress is the progress of converging the regions to
+// max-replicas, e.g. after max-replicas is changed.
+type ReplicationProgress struct {
+	MaxReplicas       int     `json:"max-replicas"`
+	RegionCount       int     `json:"region-count"`
+	MissPeerCount     int     `json:"miss-peer-count"`
+	ExtraPeerCount    int     `json:"extra-peer-count"`
+	ConvergedRatio    float64 `json:"converged-ratio"`
+	ReplicaChangeRate float64 `json:"replica-change-rate"`
+}
+
+// GetReplicationProgress returns how many regions do not have max-replicas
+// replicas yet.
+func (c *RaftCluster) GetReplicationProgress() *ReplicationProgress {
+	progress := &ReplicationProgress{
+		MaxReplicas:       c.s.scheduleOpt.GetMaxReplicas(),
+		ReplicaChangeRate: c.s.scheduleOpt.GetReplicaChangeRate(),
+		ConvergedRatio:    1,
+	}
+	for _, region := range c.cachedCluster.getRegions() {
+		progress.RegionCount++
+		if c.isMissPeerRegion(region) {
+			progress.MissPeerCount++
+		} else if c.isExtraPeerRegion(region) {
+			progress.ExtraPeerCount++
+		}
+	}
+	if progress.RegionCount > 0 {
+		converged := progress.RegionCount - progress.MissPeerCount - progress.ExtraPeerCount
+		progress.ConvergedRatio = float64(converged) / float64(progress.RegionCount)
+	}
+	return progress
+}
+
+func (c *RaftCluster) isMissPeerRegion(region *regionInfo) bool {
+	region = withoutEnginePeers(c.cachedCluster, c.s.scheduleOpt, region)
+	return len(region.GetPeers()) < c.s.scheduleOpt.GetRegionMaxReplicas(region.Region)
+}
+
+func (c *RaftCluster) isExtraPeerRegion(region *regionInfo) bool {
+	region = withoutEnginePeers(c.cachedCluster, c.s.scheduleOpt, region)
+	return len(region.GetPeers()) > c.s.scheduleOpt.GetRegionMaxReplicas(region.Region)
+}
+
+func isDownPeerRegion(region *regionInfo) bool {
+	return len(region.DownPeers) > 0
+}
+
+func isPendingPeerRegion(region *regionInfo) bool {
+	return len(region.PendingPeers) > 0
+}
+
+func (c *RaftCluster) isOfflinePeerRegion(region *regionInfo) bool {
+	for _, store := range c.cachedCluster.getRegionStores(region) {
+		if store.isOffline() {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *RaftCluster) filterRegions(f func(*regionInfo) bool) []*metapb.Region {
+	var regions []*metapb.Region
+	for _, region := range c.cachedCluster.getRegions() {
+		if f(region) {
+			regions = append(regions, region.Region)
+		}
+	}
+	return regions
+}
+
 // GetStores gets stores from cluster.
 func (c *RaftCluster) GetStores() []*metapb.Store {
 	return c.cachedCluster.getMetaStores()
@@ -403,6 +565,79 @@ func (c *RaftCluster) BuryStore(storeID uint64, force bool) error {
 	return cluster.putStore(store)
 }
 
+// StoreBatchOp describes the changes applied to a batch of stores.
+type StoreBatchOp struct {
+	// Labels are merged into the store labels by key.
+	Labels []*metapb.StoreLabel
+	// State is the target state of the stores, nil means unchanged.
+	State *metapb.StoreState
+	// Force allows to bury an up store.
+	Force bool
+}
+
+// MaxStoreBatchSize is the max number of stores updated in one batch. The
+// stores are saved in one txn, which is limited by the max operations of an
+// etcd txn.
+const MaxStoreBatchSize = 128
+
+// BatchUpdateStores applies the op to the stores, either all of them
+// are updated or none of them is.
+func (c *RaftCluster) BatchUpdateStores(storeIDs []uint64, op *StoreBatchOp) error {
+	if len(storeIDs) > MaxStoreBatchSize {
+		return errors.Errorf("%d stores exceed the max batch size %d", len(storeIDs), MaxStoreBatchSize)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	cluster := c.cachedCluster
+
+	stores := make([]*storeInfo, 0, len(storeIDs))
+	for _, storeID := range storeIDs {
+		store := cluster.getStore(storeID)
+		if store == nil {
+			return errors.Trace(errStoreNotFound(storeID))
+		}
+		if store.isTombstone() {
+			return errors.Errorf("store %d has been removed", storeID)
+		}
+
+		for _, label := range op.Labels {
+			store.mergeLabel(label)
+		}
+
+		if op.State != nil {
+			if err := checkStoreStateTransition(store, *op.State, op.Force); err != nil {
+				return errors.Trace(err)
+			}
+			if *op.State == metapb.StoreState_Tombstone {
+				store.stats = new(StoreStatus)
+			}
+			store.State = *op.State
+		}
+
+		stores = append(stores, store)
+	}
+
+	return cluster.putStores(stores)
+}
+
+// checkStoreStateTransition checks whether the store can transfer to the state.
+// It follows the same rules as RemoveStore and BuryStore.
+func checkStoreStateTransition(store *storeInfo, state metapb.StoreState, force bool) error {
+	switch state {
+	case metapb.StoreState_Offline:
+		return nil
+	case metapb.StoreState_Tombstone:
+		if store.isUp() && !force {
+			return errors.Errorf("store %d is still up, please remove store gracefully", store.GetId())
+		}
+		return nil
+	default:
+		return errors.Errorf("unsupported store state transition from %v to %v", store.GetState(), state)
+	}
+}
+
 func (c *RaftCluster) checkStores() {
 	cluster := c.cachedCluster
 	for _, store := range cluster.getMetaStores() {
@@ -415,11 +650,38 @@ func (c *RaftCluster) checkStores() {
 				log.Errorf("bury store %v failed: %v", store, err)
 			} else {
 				log.Infof("buried store %v", store)
+				c.s.eventBus.publish(&ClusterEvent{
+					Type:    ClusterEventStoreTombstone,
+					StoreID: store.GetId(),
+					Message: fmt.Sprintf("store %d is offline and becomes tombstone", store.GetId()),
+				})
 			}
 		}
 	}
 }
 
+// checkDownStores publishes the events of the stores which become down.
+func (c *RaftCluster) checkDownStores() {
+	maxDownTime := c.s.scheduleOpt.GetMaxStoreDownTime()
+	for _, s := range c.cachedCluster.getStores() {
+		storeID := s.GetId()
+		if s.isTombstone() || s.downTime() < maxDownTime {
+			delete(c.downStores, storeID)
+			continue
+		}
+		if _, ok := c.downStores[storeID]; ok {
+			continue
+		}
+		c.downStores[storeID] = struct{}{}
+		c.unavailableRegions.markNoLeader(c.cachedCluster.getStoreLeaderRegions(storeID))
+		c.s.eventBus.publish(&ClusterEvent{
+			Type:    ClusterEventStoreDown,
+			StoreID: storeID,
+			Message: fmt.Sprintf("store %d is down for %v", storeID, s.downTime()),
+		})
+	}
+}
+
 func (c *RaftCluster) collectMetrics() {
 	cluster := c.cachedCluster
 
@@ -433,6 +695,9 @@ func (c *RaftCluster) collectMetrics() {
 	minLeaderRatio, maxLeaderRatio := float64(1.0), float64(0.0)
 	minStorageRatio, maxStorageRatio := float64(1.0), float64(0.0)
 
+	// Reset the store metrics, so the removed stores are not reported.
+	storeStatusGauge.Reset()
+
 	for _, s := range cluster.getStores() {
 		// Store state.
 		switch s.GetState() {
@@ -449,6 +714,7 @@ func (c *RaftCluster) collectMetrics() {
 		if s.downTime() >= c.coordinator.opt.GetMaxStoreDownTime() {
 			storeDownCount++
 		}
+		collectStoreMetrics(s)
 
 		// Store stats.
 		storageSize += s.stats.GetUsedSize()
@@ -488,6 +754,71 @@ func (c *RaftCluster) collectMetrics() {
 	}
 }
 
+// collectRegionMetrics counts the unhealthy regions in one pass, the checks
+// are the same as the regions check API.
+func (c *RaftCluster) collectRegionMetrics() {
+	checks := map[string]func(*regionInfo) bool{
+		"miss_peer":    c.isMissPeerRegion,
+		"extra_peer":   c.isExtraPeerRegion,
+		"down_peer":    isDownPeerRegion,
+		"pending_peer": isPendingPeerRegion,
+		"offline_peer": c.isOfflinePeerRegion,
+	}
+
+	metrics := make(map[string]float64)
+	for label := range checks {
+		metrics[label] = 0
+	}
+	for _, region := range c.cachedCluster.getRegions() {
+		for label, check := range checks {
+			if check(region) {
+				metrics[label]++
+			}
+		}
+	}
+
+	for label, value := range metrics {
+		regionStatusGauge.WithLabelValues(label).Set(value)
+	}
+}
+
+// collectStoreHeartbeatMetrics collects the interval since the last
+// heartbeat of the store and the processing time of the heartbeat started at
+// start, the store is the one before handling the heartbeat.
+func collectStoreHeartbeatMetrics(s *storeInfo, start time.Time) {
+	if s == nil {
+		return
+	}
+	address, id := s.GetAddress(), strconv.FormatUint(s.GetId(), 10)
+	if last := s.stats.LastHeartbeatTS; !last.IsZero() {
+		storeHeartbeatInterval.WithLabelValues(address, id).Observe(start.Sub(last).Seconds())
+	}
+	storeHeartbeatDuration.WithLabelValues(address, id).Observe(time.Since(start).Seconds())
+}
+
+func collectStoreMetrics(s *storeInfo) {
+	scores := s.resourceScores()
+	blocked := 0
+	if s.isBlocked() {
+		blocked = 1
+	}
+
+	metrics := make(map[string]float64)
+	metrics["region_count"] = float64(s.stats.GetRegionCount())
+	metrics["leader_count"] = float64(s.stats.LeaderRegionCount)
+	metrics["capacity"] = float64(s.stats.GetCapacity())
+	metrics["available"] = float64(s.stats.GetAvailable())
+	metrics["leader_score"] = float64(scores[0])
+	metrics["storage_score"] = float64(scores[1])
+	metrics["down_seconds"] = s.downTime().Seconds()
+	metrics["blocked"] = float64(blocked)
+
+	address, id := s.GetAddress(), strconv.FormatUint(s.GetId(), 10)
+	for label, value := range metrics {
+		storeStatusGauge.WithLabelValues(address, id, label).Set(value)
+	}
+}
+
 func (c *RaftCluster) runBackgroundJobs(interval time.Duration) {
 	defer c.wg.Done()
 
@@ -500,11 +831,22 @@ func (c *RaftCluster) runBackgroundJobs(interval time.Duration) {
 			return
 		case <-ticker.C:
 			c.checkStores()
+			c.checkDownStores()
 			c.collectMetrics()
+			c.collectRegionMetrics()
+			c.gcOperatorRecords()
 		}
 	}
 }
 
+// gcOperatorRecords removes the operator records out of the retention.
+func (c *RaftCluster) gcOperatorRecords() {
+	deadline := time.Now().Add(-c.s.scheduleOpt.GetOperatorHistoryRetention())
+	if err := c.s.kv.deleteOperatorRecords(deadline); err != nil {
+		log.Errorf("delete operator records before %v error: %v", deadline, err)
+	}
+}
+
 // GetConfig gets config from cluster.
 func (c *RaftCluster) GetConfig() *metapb.Cluster {
 	return c.cachedCluster.getMeta()
@@ -527,6 +869,13 @@ func (c *RaftCluster) GetHistoryOperators() []Operator {
 	return c.coordinator.getHistories()
 }
 
+// GetOperatorRecords gets the saved records of the operators which end in
+// [start, end), only the records of the region are returned if regionID is not 0.
+func (c *RaftCluster) GetOperatorRecords(regionID uint64, start, end time.Time, limit int) ([]*OperatorRecord, error) {
+	records, err := c.s.kv.loadOperatorRecords(regionID, start, end, limit)
+	return records, errors.Trace(err)
+}
+
 // GetScores gets store scores from balancer.
 func (c *RaftCluster) GetScores(store *metapb.Store, status *StoreStatus) []int {
 	storeInfo := &storeInfo{
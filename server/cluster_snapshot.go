@@ -0,0 +1,146 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"io"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+// clusterSnapshotVersion is bumped when the format of the snapshot changes.
+const clusterSnapshotVersion = 1
+
+func init() {
+	// The steps of the region operators are encoded as interfaces.
+	gob.Register(&changePeerOperator{})
+	gob.Register(&transferLeaderOperator{})
+}
+
+// ClusterSnapshot is a copy of the in-memory cluster state for debugging
+// offline. It is encoded by gob and compressed by gzip.
+type ClusterSnapshot struct {
+	Version    int
+	CreateTime time.Time
+	ClusterID  uint64
+	Meta       *metapb.Cluster
+	Stores     []*snapshotStore
+	Regions    []*regionInfo
+	Operators  []*regionOperator
+}
+
+type snapshotStore struct {
+	Store   *metapb.Store
+	Status  *StoreStatus
+	Blocked bool
+}
+
+// WriteSnapshot writes the snapshot of the cluster state. The stores and
+// regions are copied at the same time, the operators are copied after them.
+func (c *RaftCluster) WriteSnapshot(w io.Writer) error {
+	snap := c.snapshot()
+	zw := gzip.NewWriter(w)
+	if err := gob.NewEncoder(zw).Encode(snap); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(zw.Close())
+}
+
+func (c *RaftCluster) snapshot() *ClusterSnapshot {
+	snap := c.cachedCluster.snapshot()
+	snap.ClusterID = c.clusterID
+	snap.Operators = c.coordinator.snapshotOperators()
+	return snap
+}
+
+func (c *clusterInfo) snapshot() *ClusterSnapshot {
+	c.RLock()
+	defer c.RUnlock()
+
+	snap := &ClusterSnapshot{
+		Version:    clusterSnapshotVersion,
+		CreateTime: time.Now(),
+		Meta:       proto.Clone(c.meta).(*metapb.Cluster),
+	}
+	for _, store := range c.stores.getStores() {
+		snap.Stores = append(snap.Stores, &snapshotStore{
+			Store:   store.Store,
+			Status:  store.stats,
+			Blocked: store.isBlocked(),
+		})
+	}
+	snap.Regions = c.regions.getRegions()
+	return snap
+}
+
+// snapshotOperators copies the region operators, the steps are shared since
+// they are not changed after the operators are created.
+func (c *coordinator) snapshotOperators() []*regionOperator {
+	c.RLock()
+	defer c.RUnlock()
+
+	operators := make([]*regionOperator, 0, len(c.operators))
+	for _, op := range c.operators {
+		if op, ok := op.(*regionOperator); ok {
+			clone := *op
+			clone.Region = op.Region.clone()
+			operators = append(operators, &clone)
+		}
+	}
+	return operators
+}
+
+// LoadClusterSnapshot reads the snapshot written by WriteSnapshot.
+func LoadClusterSnapshot(r io.Reader) (*ClusterSnapshot, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer zr.Close()
+
+	snap := &ClusterSnapshot{}
+	if err = gob.NewDecoder(zr).Decode(snap); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if snap.Version != clusterSnapshotVersion {
+		return nil, errors.Errorf("unsupported cluster snapshot version %d", snap.Version)
+	}
+	return snap, nil
+}
+
+// newCoordinator rehydrates the snapshot into a coordinator which is not
+// running, the cluster is not backed by the kv.
+func (snap *ClusterSnapshot) newCoordinator(id IDAllocator, opt *scheduleOption) *coordinator {
+	cluster := newClusterInfo(id)
+	cluster.meta = snap.Meta
+	for _, s := range snap.Stores {
+		store := &storeInfo{Store: s.Store, stats: s.Status}
+		store.stats.blocked = s.Blocked
+		cluster.stores.setStore(store)
+	}
+	for _, region := range snap.Regions {
+		cluster.regions.setRegion(region)
+	}
+
+	co := newCoordinator(cluster, opt)
+	for _, op := range snap.Operators {
+		co.addOperator(op, op.Source)
+	}
+	return co
+}
@@ -0,0 +1,81 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+var _ = Suite(&testClusterSnapshotSuite{})
+
+type testClusterSnapshotSuite struct{}
+
+func (s *testClusterSnapshotSuite) TestWriteLoad(c *C) {
+	_, opt := newTestScheduleConfig()
+	cluster := newClusterInfo(NewMockIDAllocator())
+	cluster.meta = &metapb.Cluster{Id: 1, MaxPeerCount: 3}
+	tc := newTestClusterInfo(cluster)
+	tc.AddLeaderStore(1, 1, 2)
+	tc.AddLeaderStore(2, 1, 2)
+	tc.AddLeaderRegion(1, 1, 2)
+	tc.AddLeaderRegion(2, 2, 1)
+	// The regions don't overlap, so the search results are stable.
+	region1, region2 := cluster.getRegion(1), cluster.getRegion(2)
+	region1.EndKey, region2.StartKey = []byte("a"), []byte("a")
+	cluster.putRegion(region1)
+	cluster.putRegion(region2)
+	c.Assert(cluster.blockStore(2), IsNil)
+
+	co := newCoordinator(cluster, opt)
+	region := cluster.getRegion(1)
+	op := newRegionOperator(region, newTransferLeaderOperator(1, region.Leader, region.GetStorePeer(2)))
+	c.Assert(co.addOperator(op, "test"), IsTrue)
+
+	rc := &RaftCluster{clusterID: 1, cachedCluster: cluster, coordinator: co}
+	var buf bytes.Buffer
+	c.Assert(rc.WriteSnapshot(&buf), IsNil)
+
+	snap, err := LoadClusterSnapshot(&buf)
+	c.Assert(err, IsNil)
+	c.Assert(snap.ClusterID, Equals, uint64(1))
+	c.Assert(snap.Meta, DeepEquals, cluster.meta)
+	c.Assert(snap.Stores, HasLen, 2)
+	c.Assert(snap.Regions, HasLen, 2)
+	c.Assert(snap.Operators, HasLen, 1)
+
+	loaded := snap.newCoordinator(NewMockIDAllocator(), opt)
+	for _, store := range cluster.getStores() {
+		loadedStore := loaded.cluster.getStore(store.GetId())
+		c.Assert(loadedStore.Store, DeepEquals, store.Store)
+		c.Assert(loadedStore.stats.StoreStats, DeepEquals, store.stats.StoreStats)
+		c.Assert(loadedStore.stats.LeaderRegionCount, Equals, store.stats.LeaderRegionCount)
+		c.Assert(loadedStore.stats.StartTS.Equal(store.stats.StartTS), IsTrue)
+	}
+	c.Assert(loaded.cluster.getStore(2).isBlocked(), IsTrue)
+	c.Assert(loaded.cluster.getStoreLeaderCount(1), Equals, 1)
+	c.Assert(loaded.cluster.searchRegion([]byte{}).GetId(), Equals, cluster.searchRegion([]byte{}).GetId())
+	for _, region := range cluster.getRegions() {
+		c.Assert(loaded.cluster.getRegion(region.GetId()), DeepEquals, region)
+	}
+	loadedOp := loaded.getOperator(1).(*regionOperator)
+	c.Assert(loadedOp.Source, Equals, "test")
+	c.Assert(loadedOp.Ops, DeepEquals, op.Ops)
+
+	// The data is not a snapshot.
+	_, err = LoadClusterSnapshot(bytes.NewBufferString("invalid"))
+	c.Assert(err, NotNil)
+}
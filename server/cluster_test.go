@@ -14,6 +14,7 @@
 package server
 
 import (
+	"fmt"
 	"net"
 
 	"github.com/coreos/etcd/clientv3"
@@ -295,6 +296,9 @@ func (s *testClusterSuite) TestGetPutConfig(c *C) {
 	store.Address = "127.0.0.1:1"
 	s.testPutStore(c, conn, clusterID, store)
 
+	// Batch update stores.
+	s.testBatchUpdateStores(c, store)
+
 	// Remove store.
 	s.testRemoveStore(c, conn, clusterID, store)
 
@@ -371,6 +375,49 @@ func (s *testClusterSuite) resetStoreState(c *C, storeID uint64, state metapb.St
 	cluster.putStore(store)
 }
 
+func (s *testClusterSuite) testBatchUpdateStores(c *C, store *metapb.Store) {
+	cluster := s.getRaftCluster(c)
+
+	// Case 1: unknown store should fail and nothing is changed.
+	op := &StoreBatchOp{Labels: []*metapb.StoreLabel{{Key: "zone", Value: "z1"}}}
+	err := cluster.BatchUpdateStores([]uint64{store.GetId(), 0}, op)
+	c.Assert(err, NotNil)
+	meta, _, err := cluster.GetStore(store.GetId())
+	c.Assert(err, IsNil)
+	c.Assert(meta.GetLabels(), HasLen, 0)
+
+	// Case 2: labels are merged by key.
+	c.Assert(cluster.BatchUpdateStores([]uint64{store.GetId()}, op), IsNil)
+	op.Labels = []*metapb.StoreLabel{{Key: "zone", Value: "z2"}, {Key: "disk", Value: "ssd"}}
+	c.Assert(cluster.BatchUpdateStores([]uint64{store.GetId()}, op), IsNil)
+	meta, _, err = cluster.GetStore(store.GetId())
+	c.Assert(err, IsNil)
+	c.Assert(meta.GetLabels(), DeepEquals, op.Labels)
+
+	// Case 3: bury an up store w/o force should fail.
+	state := metapb.StoreState_Tombstone
+	err = cluster.BatchUpdateStores([]uint64{store.GetId()}, &StoreBatchOp{State: &state})
+	c.Assert(err, NotNil)
+
+	// Case 4: Up -> Offline should be OK.
+	state = metapb.StoreState_Offline
+	c.Assert(cluster.BatchUpdateStores([]uint64{store.GetId()}, &StoreBatchOp{State: &state}), IsNil)
+	meta, _, err = cluster.GetStore(store.GetId())
+	c.Assert(err, IsNil)
+	c.Assert(meta.GetState(), Equals, metapb.StoreState_Offline)
+
+	// Case 5: only Offline and Tombstone are allowed.
+	state = metapb.StoreState_Up
+	err = cluster.BatchUpdateStores([]uint64{store.GetId()}, &StoreBatchOp{State: &state})
+	c.Assert(err, NotNil)
+
+	// Reset the store.
+	cachedStore := cluster.cachedCluster.getStore(store.GetId())
+	cachedStore.Labels = nil
+	cachedStore.State = metapb.StoreState_Up
+	c.Assert(cluster.cachedCluster.putStore(cachedStore), IsNil)
+}
+
 func (s *testClusterSuite) testRemoveStore(c *C, conn net.Conn, clusterID uint64, store *metapb.Store) {
 	cluster := s.getRaftCluster(c)
 
@@ -492,6 +539,44 @@ func (s *testClusterSuite) testCheckStores(c *C, conn net.Conn, clusterID uint64
 	c.Assert(tmpStore.GetState(), Equals, metapb.StoreState_Tombstone)
 }
 
+func (s *testClusterSuite) TestBatchUpdateManyStores(c *C) {
+	svr, cleanup := newTestServer(c)
+	defer cleanup()
+	go svr.Run()
+
+	leader := mustGetLeader(c, svr.client, svr.getLeaderPath())
+
+	conn, err := rpcConnect(leader.GetAddr())
+	c.Assert(err, IsNil)
+	defer conn.Close()
+
+	clusterID := svr.clusterID
+	s.tryBootstrapCluster(c, conn, clusterID, "127.0.0.1:0")
+
+	cluster := svr.GetRaftCluster()
+	c.Assert(cluster, NotNil)
+	storeIDs := make([]uint64, 0, MaxStoreBatchSize+1)
+	for i := 0; i <= MaxStoreBatchSize; i++ {
+		store := s.newStore(c, 0, fmt.Sprintf("127.0.0.1:%d", i+1))
+		c.Assert(cluster.cachedCluster.putStore(newStoreInfo(store)), IsNil)
+		storeIDs = append(storeIDs, store.GetId())
+	}
+
+	// The stores more than an etcd txn can save are rejected.
+	op := &StoreBatchOp{Labels: []*metapb.StoreLabel{{Key: "zone", Value: "z1"}}}
+	c.Assert(cluster.BatchUpdateStores(storeIDs, op), NotNil)
+	meta, _, err := cluster.GetStore(storeIDs[0])
+	c.Assert(err, IsNil)
+	c.Assert(meta.GetLabels(), HasLen, 0)
+
+	c.Assert(cluster.BatchUpdateStores(storeIDs[:MaxStoreBatchSize], op), IsNil)
+	store := &metapb.Store{}
+	ok, err := svr.kv.loadStore(storeIDs[MaxStoreBatchSize-1], store)
+	c.Assert(err, IsNil)
+	c.Assert(ok, IsTrue)
+	c.Assert(store.GetLabels(), DeepEquals, op.Labels)
+}
+
 // Make sure PD will not panic if it start and stop again and again.
 func (s *testClusterSuite) TestClosedChannel(c *C) {
 	svr, cleanup := newTestServer(c)
@@ -542,3 +627,42 @@ func (s *testClusterSuite) TestGetPDMembers(c *C) {
 	// A more strict test can be found at api/member_test.go
 	c.Assert(len(resp.GetPdMembers.Members), Not(Equals), 0)
 }
+
+var _ = Suite(&testCheckRegionsSuite{})
+
+type testCheckRegionsSuite struct{}
+
+func (s *testCheckRegionsSuite) TestCheckRegions(c *C) {
+	_, opt := newTestScheduleConfig()
+	cluster := newClusterInfo(NewMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+	rc := &RaftCluster{s: &Server{scheduleOpt: opt}, cachedCluster: cluster}
+
+	for storeID := uint64(1); storeID <= 4; storeID++ {
+		tc.AddRegionStore(storeID, 1, 0.1)
+	}
+	tc.AddLeaderRegion(1, 1, 2, 3)
+	tc.AddLeaderRegion(2, 1, 2)
+	tc.AddLeaderRegion(3, 1, 2, 4)
+	tc.SetStoreOffline(4)
+
+	region := cluster.getRegion(1)
+	region.DownPeers = []*pdpb.PeerStats{{Peer: region.GetStorePeer(2)}}
+	region.PendingPeers = []*metapb.Peer{region.GetStorePeer(3)}
+	cluster.putRegion(region)
+
+	checkRegionIDs := func(regions []*metapb.Region, ids ...uint64) {
+		c.Assert(regions, HasLen, len(ids))
+		for i, region := range regions {
+			c.Assert(region.GetId(), Equals, ids[i])
+		}
+	}
+	checkRegionIDs(rc.GetMissPeerRegions(), 2)
+	checkRegionIDs(rc.GetDownPeerRegions(), 1)
+	checkRegionIDs(rc.GetPendingPeerRegions(), 1)
+	checkRegionIDs(rc.GetOfflinePeerRegions(), 3)
+
+	tc.AddLeaderRegion(4, 1, 2, 3, 4)
+	c.Assert(rc.isExtraPeerRegion(cluster.getRegion(4)), IsTrue)
+	c.Assert(rc.isExtraPeerRegion(cluster.getRegion(1)), IsFalse)
+}
@@ -30,6 +30,7 @@ func (c *RaftCluster) handleRegionHeartbeat(region *regionInfo) (*pdpb.RegionHea
 		return nil, errors.Errorf("invalid region, zero region peer count - %v", region)
 	}
 
+	c.unavailableRegions.update(region)
 	return c.coordinator.dispatch(region), nil
 }
 
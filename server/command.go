@@ -14,11 +14,14 @@
 package server
 
 import (
+	"time"
+
 	"github.com/golang/protobuf/proto"
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/pkg/failpoint"
 )
 
 func (c *conn) handleTso(req *pdpb.Request) (*pdpb.Response, error) {
@@ -28,7 +31,7 @@ func (c *conn) handleTso(req *pdpb.Request) (*pdpb.Response, error) {
 	}
 
 	count := request.GetCount()
-	ts, err := c.s.getRespTS(count)
+	ts, err := c.s.tsoBatcher.getTS(count)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -174,15 +177,16 @@ func (c *conn) handleRegionHeartbeat(req *pdpb.Request) (*pdpb.Response, error)
 		return nil, errors.Trace(err)
 	}
 
-	region := newRegionInfo(request.GetRegion(), request.GetLeader())
-	region.DownPeers = request.GetDownPeers()
-	region.PendingPeers = request.GetPendingPeers()
+	region := newRegionInfoFromHeartbeat(request)
 	if region.GetId() == 0 {
 		return nil, errors.Errorf("invalid request region, %v", request)
 	}
 	if region.Leader == nil {
 		return nil, errors.Errorf("invalid request leader, %v", request)
 	}
+	if err = failpoint.Error(FailpointRegionHeartbeat); err != nil {
+		return nil, errors.Trace(err)
+	}
 
 	err = cluster.cachedCluster.handleRegionHeartbeat(region)
 	if err != nil {
@@ -199,6 +203,13 @@ func (c *conn) handleRegionHeartbeat(req *pdpb.Request) (*pdpb.Response, error)
 	}, nil
 }
 
+func newRegionInfoFromHeartbeat(request *pdpb.RegionHeartbeatRequest) *regionInfo {
+	region := newRegionInfo(request.GetRegion(), request.GetLeader())
+	region.DownPeers = request.GetDownPeers()
+	region.PendingPeers = request.GetPendingPeers()
+	return region
+}
+
 // checkStore returns an error response if the store exists and is in tombstone state.
 // It returns nil if it can't get the store.
 func checkStore(cluster *RaftCluster, storeID uint64) *pdpb.Response {
@@ -226,11 +237,17 @@ func (c *conn) handleStoreHeartbeat(req *pdpb.Request) (*pdpb.Response, error) {
 	if resp := checkStore(cluster, stats.GetStoreId()); resp != nil {
 		return resp, nil
 	}
+	if err = failpoint.Error(FailpointStoreHeartbeat); err != nil {
+		return nil, errors.Trace(err)
+	}
 
+	start := time.Now()
+	store := cluster.cachedCluster.getStore(stats.GetStoreId())
 	err = cluster.cachedCluster.handleStoreHeartbeat(stats)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
+	collectStoreHeartbeatMetrics(store, start)
 
 	return &pdpb.Response{
 		StoreHeartbeat: &pdpb.StoreHeartbeatResponse{},
@@ -25,8 +25,11 @@ import (
 	"github.com/BurntSushi/toml"
 	"github.com/coreos/etcd/embed"
 	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/pkg/logutil"
 	"github.com/pingcap/pd/pkg/metricutil"
 	"github.com/pingcap/pd/pkg/testutil"
+	"github.com/pingcap/pd/pkg/tlsutil"
 	"github.com/pingcap/pd/pkg/typeutil"
 )
 
@@ -54,15 +57,104 @@ type Config struct {
 	// in etcd after lease time, etcd will expire the leader key
 	// and other servers can campaign the leader again.
 	// Etcd onlys support seoncds TTL, so here is second too.
+	// The lease is kept alive every 1/3 lease and should not be less than
+	// the etcd election timeout.
 	LeaderLease int64 `toml:"lease" json:"lease"`
 
 	// Log level.
 	LogLevel string `toml:"log-level" json:"log-level"`
 	// Log file.
 	LogFile string `toml:"log-file" json:"log-file"`
+	// Log format and rotation.
+	Log logutil.LogConfig `toml:"log" json:"log"`
 
 	// TsoSaveInterval is the interval to save timestamp.
 	TsoSaveInterval typeutil.Duration `toml:"tso-save-interval" json:"tso-save-interval"`
+	// TsoUpdateInterval is the interval to update the physical part of timestamp.
+	TsoUpdateInterval typeutil.Duration `toml:"tso-update-interval" json:"tso-update-interval"`
+
+	// LeaderPriorityCheckInterval is the interval to check whether the
+	// leadership should move to a member with higher leader priority.
+	LeaderPriorityCheckInterval typeutil.Duration `toml:"leader-priority-check-interval" json:"leader-priority-check-interval"`
+	// EnableEtcdLeaderColocation moves the pd leadership to the etcd leader
+	// when they are on different members, which saves a network round trip
+	// for every etcd write of the leader.
+	EnableEtcdLeaderColocation bool `toml:"enable-etcd-leader-colocation" json:"enable-etcd-leader-colocation"`
+
+	// LeaderMaxWriteLatency and LeaderMaxHeartbeatLatency are the max
+	// average latency of etcd writes and heartbeats handled by the leader
+	// within leader-priority-check-interval, the leader resigns if exceeded.
+	LeaderMaxWriteLatency     typeutil.Duration `toml:"leader-max-write-latency" json:"leader-max-write-latency"`
+	LeaderMaxHeartbeatLatency typeutil.Duration `toml:"leader-max-heartbeat-latency" json:"leader-max-heartbeat-latency"`
+	// LeaderMinTenure is the time the leader serves before it may resign
+	// for the latency. A slow etcd quorum is as slow for every member, so
+	// the leadership would churn through the members without it.
+	LeaderMinTenure typeutil.Duration `toml:"leader-min-tenure" json:"leader-min-tenure"`
+
+	// EnableScheduleThrottle enlarges the schedule interval of the leader if
+	// the CPU usage of the process exceeds ScheduleThrottleCPUUsage of all
+	// the cores, or the heartbeats being handled at the same time exceed
+	// ScheduleThrottleHeartbeats within ScheduleThrottleCheckInterval. The
+	// interval is doubled for every overloaded check up to 16 times, and
+	// halved for every check not overloaded.
+	EnableScheduleThrottle        bool              `toml:"enable-schedule-throttle" json:"enable-schedule-throttle"`
+	ScheduleThrottleCheckInterval typeutil.Duration `toml:"schedule-throttle-check-interval" json:"schedule-throttle-check-interval"`
+	ScheduleThrottleCPUUsage      float64           `toml:"schedule-throttle-cpu-usage" json:"schedule-throttle-cpu-usage"`
+	ScheduleThrottleHeartbeats    int64             `toml:"schedule-throttle-heartbeats" json:"schedule-throttle-heartbeats"`
+
+	// EnableFollowerRead makes followers serve store and region reads from
+	// a local cache synced from etcd instead of forwarding them to leader.
+	EnableFollowerRead bool `toml:"enable-follower-read" json:"enable-follower-read"`
+	// FollowerReadMaxStaleness is the max time the follower cache can lag
+	// behind the leader, it should be greater than tso-save-interval.
+	FollowerReadMaxStaleness typeutil.Duration `toml:"follower-read-max-staleness" json:"follower-read-max-staleness"`
+
+	// SlowRequestThreshold is the handling time above which a request is
+	// logged with its key parameters at SlowRequestLogLevel, which can be
+	// different from log-level.
+	SlowRequestThreshold typeutil.Duration `toml:"slow-request-threshold" json:"slow-request-threshold"`
+	SlowRequestLogLevel  string            `toml:"slow-request-log-level" json:"slow-request-log-level"`
+
+	// EnableTrace collects the traces of requests, scheduling and etcd
+	// writes, and serves them on /debug/requests and /debug/events.
+	EnableTrace bool `toml:"enable-trace" json:"enable-trace"`
+	// TraceExporter is the name of the trace exporter, the exporters other
+	// than net-trace are registered by RegisterTraceExporter.
+	TraceExporter string `toml:"trace-exporter" json:"trace-exporter"`
+	// EnablePProf serves the profiles of the member on /debug/pprof, and the
+	// profiles of the leader on /pd/api/v1/debug/pprof.
+	EnablePProf bool `toml:"enable-pprof" json:"enable-pprof"`
+	// EnableFailpoint enables the failpoints in the PD_FAILPOINTS environment
+	// variable, and serves the failpoints of the member on
+	// /pd/api/v1/failpoints. It is for the tests and the chaos tools only.
+	EnableFailpoint bool `toml:"enable-failpoint" json:"enable-failpoint"`
+
+	// DCLocation is the data center of the pd server, servers in the same
+	// dc-location elect a local tso allocator. Leave it empty to disable local tso.
+	DCLocation string `toml:"dc-location" json:"dc-location"`
+
+	// RegionStorage is where the region metadata is saved, etcd or local.
+	// The local storage is a bolt db in data-dir, the followers pull the
+	// regions saved by the leader every region-flush-interval.
+	RegionStorage string `toml:"region-storage" json:"region-storage"`
+	// RegionFlushInterval is the max time the region metadata changes are
+	// kept in memory before being saved in a batch.
+	RegionFlushInterval typeutil.Duration `toml:"region-flush-interval" json:"region-flush-interval"`
+
+	// IDAllocator is the name of the ID allocator, the allocators other than
+	// etcd are registered by RegisterIDAllocator.
+	IDAllocator string `toml:"id-allocator" json:"id-allocator"`
+	// IDAllocStep is the number of IDs reserved in etcd at a time, the IDs
+	// are allocated in memory until the batch is used up.
+	IDAllocStep uint64 `toml:"id-alloc-step" json:"id-alloc-step"`
+
+	// EtcdCompactionInterval is the interval the leader compacts the etcd
+	// revisions, the history of the last interval is kept.
+	EtcdCompactionInterval typeutil.Duration `toml:"etcd-compaction-interval" json:"etcd-compaction-interval"`
+	// EnableEtcdDefrag defragments the etcd members one by one every
+	// etcd-defrag-interval, a member is blocked while defragmenting.
+	EnableEtcdDefrag   bool              `toml:"enable-etcd-defrag" json:"enable-etcd-defrag"`
+	EtcdDefragInterval typeutil.Duration `toml:"etcd-defrag-interval" json:"etcd-defrag-interval"`
 
 	Metric metricutil.MetricConfig `toml:"metric" json:"metric"`
 
@@ -70,6 +162,16 @@ type Config struct {
 
 	Replication ReplicationConfig `toml:"replication" json:"replication"`
 
+	LabelProperty LabelPropertyConfig `toml:"label-property" json:"label-property"`
+
+	// Security enables the TLS of the client, peer and api endpoints, the
+	// urls should be in https then.
+	Security tlsutil.SecurityConfig `toml:"security" json:"security"`
+
+	APIAuth APIAuthConfig `toml:"api-auth" json:"api-auth"`
+
+	Encryption EncryptionConfig `toml:"encryption" json:"encryption"`
+
 	// Only test can change them.
 	nextRetryDelay             time.Duration
 	disableStrictReconfigCheck bool
@@ -77,7 +179,8 @@ type Config struct {
 	tickMs     uint64
 	electionMs uint64
 
-	configFile string
+	configFile  string
+	restoreFile string
 }
 
 // NewConfig creates a new config.
@@ -89,6 +192,7 @@ func NewConfig() *Config {
 	fs.BoolVar(&cfg.Version, "V", false, "print version information and exit")
 	fs.BoolVar(&cfg.Version, "version", false, "print version information and exit")
 	fs.StringVar(&cfg.configFile, "config", "", "Config file")
+	fs.StringVar(&cfg.restoreFile, "restore", "", "restore the metadata from the backup file when starting a fresh cluster")
 
 	fs.StringVar(&cfg.Name, "name", defaultName, "human-readable name for this pd member")
 
@@ -100,6 +204,12 @@ func NewConfig() *Config {
 	fs.StringVar(&cfg.InitialCluster, "initial-cluster", "", "initial cluster configuration for bootstrapping, e,g. pd=http://127.0.0.1:2380")
 	fs.StringVar(&cfg.Join, "join", "", "join to an existing cluster (usage: cluster's '${advertise-client-urls}'")
 
+	fs.StringVar(&cfg.DCLocation, "dc-location", "", "data center of this pd member, used for local tso")
+
+	fs.StringVar(&cfg.Security.CAPath, "cacert", "", "path of file that contains list of trusted TLS CAs")
+	fs.StringVar(&cfg.Security.CertPath, "cert", "", "path of file that contains X509 certificate in PEM format")
+	fs.StringVar(&cfg.Security.KeyPath, "key", "", "path of file that contains X509 key in PEM format")
+
 	fs.StringVar(&cfg.LogLevel, "L", "info", "log level: debug, info, warn, error, fatal")
 	fs.StringVar(&cfg.LogFile, "log-file", "", "log file path")
 
@@ -107,8 +217,26 @@ func NewConfig() *Config {
 }
 
 const (
-	defaultLeaderLease    = int64(3)
-	defaultNextRetryDelay = time.Second
+	defaultLeaderLease       = int64(3)
+	defaultNextRetryDelay    = time.Second
+	defaultTsoUpdateInterval = 50 * time.Millisecond
+
+	defaultLeaderPriorityCheckInterval = time.Minute
+	defaultLeaderMaxWriteLatency       = time.Second
+	defaultLeaderMaxHeartbeatLatency   = time.Second
+	defaultLeaderMinTenure             = 10 * time.Minute
+	defaultScheduleThrottleInterval    = 10 * time.Second
+	defaultScheduleThrottleCPUUsage    = 0.8
+	defaultScheduleThrottleHeartbeats  = int64(256)
+	defaultFollowerReadMaxStaleness    = 10 * time.Second
+	defaultSlowRequestThreshold        = time.Second
+	defaultRegionFlushInterval         = time.Second
+	defaultEtcdCompactionInterval      = time.Hour
+	defaultEtcdDefragInterval          = 24 * time.Hour
+	defaultCertReloadInterval          = time.Minute
+	defaultDataKeyRotationPeriod       = 7 * 24 * time.Hour
+	defaultSlowRequestLogLevel         = "warn"
+	defaultIDAllocStep                 = uint64(1000)
 
 	defaultName                = "pd"
 	defaultClientUrls          = "http://127.0.0.1:2379"
@@ -228,21 +356,117 @@ func (c *Config) adjust() error {
 	adjustString(&c.InitialClusterState, defualtInitialClusterState)
 
 	adjustInt64(&c.LeaderLease, defaultLeaderLease)
+	adjustUint64(&c.tickMs, defaultTickMs)
+	adjustUint64(&c.electionMs, defaultElectionMs)
+	// The leader can't keep its lease alive while etcd is electing a new
+	// leader, so a lease shorter than the election timeout causes spurious
+	// pd leader changes.
+	if c.LeaderLease < 0 || uint64(c.LeaderLease)*1000 < c.electionMs {
+		return errors.Errorf("lease %ds should not be less than etcd election timeout %dms", c.LeaderLease, c.electionMs)
+	}
+
+	adjustDuration(&c.TsoSaveInterval, time.Duration(c.LeaderLease)*time.Second)
+	adjustDuration(&c.TsoUpdateInterval, defaultTsoUpdateInterval)
+	if c.TsoUpdateInterval.Duration >= c.TsoSaveInterval.Duration {
+		return errors.Errorf("tso-update-interval %v should be less than tso-save-interval %v", c.TsoUpdateInterval, c.TsoSaveInterval)
+	}
+
+	adjustDuration(&c.LeaderPriorityCheckInterval, defaultLeaderPriorityCheckInterval)
+	adjustDuration(&c.LeaderMaxWriteLatency, defaultLeaderMaxWriteLatency)
+	adjustDuration(&c.LeaderMaxHeartbeatLatency, defaultLeaderMaxHeartbeatLatency)
+	adjustDuration(&c.LeaderMinTenure, defaultLeaderMinTenure)
+
+	adjustDuration(&c.ScheduleThrottleCheckInterval, defaultScheduleThrottleInterval)
+	adjustFloat64(&c.ScheduleThrottleCPUUsage, defaultScheduleThrottleCPUUsage)
+	adjustInt64(&c.ScheduleThrottleHeartbeats, defaultScheduleThrottleHeartbeats)
+	if c.ScheduleThrottleCPUUsage < 0 || c.ScheduleThrottleCPUUsage > 1 {
+		return errors.Errorf("schedule-throttle-cpu-usage %v should be in [0, 1]", c.ScheduleThrottleCPUUsage)
+	}
+
+	adjustDuration(&c.FollowerReadMaxStaleness, defaultFollowerReadMaxStaleness)
+	if c.EnableFollowerRead && c.FollowerReadMaxStaleness.Duration <= c.TsoSaveInterval.Duration {
+		return errors.Errorf("follower-read-max-staleness %v should be greater than tso-save-interval %v", c.FollowerReadMaxStaleness, c.TsoSaveInterval)
+	}
+
+	adjustString(&c.RegionStorage, RegionStorageEtcd)
+	switch c.RegionStorage {
+	case RegionStorageEtcd:
+	case RegionStorageLocal:
+		// The follower cache is synced from the regions in etcd.
+		if c.EnableFollowerRead {
+			return errors.New("enable-follower-read needs region-storage etcd")
+		}
+	default:
+		return errors.Errorf("invalid region-storage %s", c.RegionStorage)
+	}
+
+	adjustDuration(&c.RegionFlushInterval, defaultRegionFlushInterval)
+	if c.RegionFlushInterval.Duration < 0 {
+		return errors.Errorf("region-flush-interval %v should not be negative", c.RegionFlushInterval)
+	}
 
-	adjustDuration(&c.TsoSaveInterval, time.Duration(defaultLeaderLease)*time.Second)
+	adjustString(&c.IDAllocator, defaultIDAllocator)
+	if _, ok := idAllocatorBuilders[c.IDAllocator]; !ok {
+		return errors.Errorf("unknown id-allocator %s", c.IDAllocator)
+	}
+	adjustUint64(&c.IDAllocStep, defaultIDAllocStep)
+
+	adjustDuration(&c.EtcdCompactionInterval, defaultEtcdCompactionInterval)
+	if c.EtcdCompactionInterval.Duration < 0 {
+		return errors.Errorf("etcd-compaction-interval %v should not be negative", c.EtcdCompactionInterval)
+	}
+	adjustDuration(&c.EtcdDefragInterval, defaultEtcdDefragInterval)
+	if c.EtcdDefragInterval.Duration < 0 {
+		return errors.Errorf("etcd-defrag-interval %v should not be negative", c.EtcdDefragInterval)
+	}
+
+	adjustDuration(&c.SlowRequestThreshold, defaultSlowRequestThreshold)
+	adjustString(&c.SlowRequestLogLevel, defaultSlowRequestLogLevel)
+	switch c.SlowRequestLogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		return errors.Errorf("invalid slow-request-log-level %s", c.SlowRequestLogLevel)
+	}
+
+	adjustString(&c.TraceExporter, defaultTraceExporter)
+	if _, ok := traceExporters[c.TraceExporter]; !ok {
+		return errors.Errorf("unknown trace-exporter %s", c.TraceExporter)
+	}
 
 	if c.nextRetryDelay == 0 {
 		c.nextRetryDelay = defaultNextRetryDelay
 	}
 
-	adjustUint64(&c.tickMs, defaultTickMs)
-	adjustUint64(&c.electionMs, defaultElectionMs)
+	adjustString(&c.Log.Format, logutil.FormatText)
+	if c.Log.Format != logutil.FormatText && c.Log.Format != logutil.FormatJSON {
+		return errors.Errorf("invalid log format %s", c.Log.Format)
+	}
 
 	adjustString(&c.Metric.PushJob, c.Name)
 
+	adjustDuration(&c.Security.CertReloadInterval, defaultCertReloadInterval)
+	if err := c.Security.Validate(); err != nil {
+		return errors.Trace(err)
+	}
+	if err := c.APIAuth.validate(c.Security); err != nil {
+		return errors.Trace(err)
+	}
+	adjustDuration(&c.Encryption.DataKeyRotationPeriod, defaultDataKeyRotationPeriod)
+	// Without api-auth every request passes, even with client-cert-auth any
+	// client with a cert signed by the CA could read the data keys.
+	if c.Encryption.Enabled() && !c.APIAuth.Enabled() {
+		return errors.New("encryption needs api-auth to protect the data keys")
+	}
+
 	c.Schedule.adjust()
+	if err := c.Schedule.Validate(); err != nil {
+		return errors.Trace(err)
+	}
 	c.Replication.adjust()
-	return nil
+	if err := c.Replication.Validate(); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(c.LabelProperty.Validate())
 }
 
 func (c *Config) clone() *Config {
@@ -258,10 +482,26 @@ func (c *Config) String() string {
 	return fmt.Sprintf("Config(%+v)", *c)
 }
 
-// configFromFile loads config from file.
+// configFromFile loads config from file, the unknown items are rejected, so
+// the typos are not ignored silently.
 func (c *Config) configFromFile(path string) error {
-	_, err := toml.DecodeFile(path, c)
-	return errors.Trace(err)
+	md, err := toml.DecodeFile(path, c)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(checkUndecoded(&md))
+}
+
+func checkUndecoded(md *toml.MetaData) error {
+	undecoded := md.Undecoded()
+	if len(undecoded) == 0 {
+		return nil
+	}
+	items := make([]string, 0, len(undecoded))
+	for _, key := range undecoded {
+		items = append(items, key.String())
+	}
+	return errors.Errorf("config contains unknown items: %s", strings.Join(items, ", "))
 }
 
 // ScheduleConfig is the schedule configuration.
@@ -294,19 +534,57 @@ type ScheduleConfig struct {
 	RegionScheduleLimit uint64 `toml:"region-schedule-limit" json:"region-schedule-limit"`
 	// ReplicaScheduleLimit is the max coexist replica schedules.
 	ReplicaScheduleLimit uint64 `toml:"replica-schedule-limit" json:"replica-schedule-limit"`
+	// ReplicaChangeRate is the max number of operators per second which add
+	// or remove replicas to converge the regions after max-replicas changes.
+	ReplicaChangeRate float64 `toml:"replica-change-rate" json:"replica-change-rate"`
+	// OperatorHistoryRetention is the duration to retain the saved records
+	// of the finished operators.
+	OperatorHistoryRetention typeutil.Duration `toml:"operator-history-retention" json:"operator-history-retention"`
+	// TransferLeaderTimeout is the timeout of a step transferring leader.
+	TransferLeaderTimeout typeutil.Duration `toml:"transfer-leader-timeout" json:"transfer-leader-timeout"`
+	// AddPeerTimeout is the timeout of a step adding a peer to a region of
+	// 64MB, it is scaled by the region size estimated from the leader store,
+	// up to 10 times.
+	AddPeerTimeout typeutil.Duration `toml:"add-peer-timeout" json:"add-peer-timeout"`
+	// RemovePeerTimeout is the timeout of a step removing a peer.
+	RemovePeerTimeout typeutil.Duration `toml:"remove-peer-timeout" json:"remove-peer-timeout"`
+	// EngineReplicas are the key ranges which have extra replicas on the
+	// stores of special engines.
+	EngineReplicas []EngineReplicaConfig `toml:"engine-replicas" json:"engine-replicas"`
+}
+
+// EngineReplicaConfig places Count extra replicas of the regions in the key
+// range [StartKey, EndKey) on the stores labeled engine=Engine. The keys are
+// raw keys in hex, an empty key means unbounded. The regions are matched by
+// the start keys. The replicas are voters, the count is capped below the
+// ordinary replicas, so the ordinary replicas still make the quorum.
+type EngineReplicaConfig struct {
+	StartKey string `toml:"start-key" json:"start-key"`
+	EndKey   string `toml:"end-key" json:"end-key"`
+	Engine   string `toml:"engine" json:"engine"`
+	Count    uint64 `toml:"count" json:"count"`
+}
+
+func (c *EngineReplicaConfig) keyRange() *KeyRange {
+	return &KeyRange{StartKey: c.StartKey, EndKey: c.EndKey}
 }
 
 const (
-	defaultMaxReplicas          = uint64(3)
-	defaultMinRegionCount       = uint64(10)
-	defaultMinLeaderCount       = uint64(10)
-	defaultMaxSnapshotCount     = uint64(3)
-	defaultMinBalanceDiffRatio  = float64(0.01)
-	defaultMaxStoreDownDuration = time.Hour
-	defaultScheduleInterval     = time.Minute
-	defaultLeaderScheduleLimit  = 16
-	defaultRegionScheduleLimit  = 12
-	defaultReplicaScheduleLimit = 16
+	defaultMaxReplicas              = uint64(3)
+	defaultMinRegionCount           = uint64(10)
+	defaultMinLeaderCount           = uint64(10)
+	defaultMaxSnapshotCount         = uint64(3)
+	defaultMinBalanceDiffRatio      = float64(0.01)
+	defaultMaxStoreDownDuration     = time.Hour
+	defaultScheduleInterval         = time.Minute
+	defaultLeaderScheduleLimit      = 16
+	defaultRegionScheduleLimit      = 12
+	defaultReplicaScheduleLimit     = 16
+	defaultReplicaChangeRate        = float64(10)
+	defaultOperatorHistoryRetention = 7 * 24 * time.Hour
+	defaultTransferLeaderTimeout    = 30 * time.Second
+	defaultAddPeerTimeout           = time.Minute
+	defaultRemovePeerTimeout        = 30 * time.Second
 )
 
 func (c *ScheduleConfig) adjust() {
@@ -319,6 +597,58 @@ func (c *ScheduleConfig) adjust() {
 	adjustUint64(&c.LeaderScheduleLimit, defaultLeaderScheduleLimit)
 	adjustUint64(&c.RegionScheduleLimit, defaultRegionScheduleLimit)
 	adjustUint64(&c.ReplicaScheduleLimit, defaultReplicaScheduleLimit)
+	adjustFloat64(&c.ReplicaChangeRate, defaultReplicaChangeRate)
+	adjustDuration(&c.OperatorHistoryRetention, defaultOperatorHistoryRetention)
+	adjustDuration(&c.TransferLeaderTimeout, defaultTransferLeaderTimeout)
+	adjustDuration(&c.AddPeerTimeout, defaultAddPeerTimeout)
+	adjustDuration(&c.RemovePeerTimeout, defaultRemovePeerTimeout)
+}
+
+// Validate checks the values of the schedule config are in range.
+func (c *ScheduleConfig) Validate() error {
+	if c.MinBalanceDiffRatio < 0 || c.MinBalanceDiffRatio > 1 {
+		return errors.Errorf("min-balance-diff-ratio %v should be in [0, 1]", c.MinBalanceDiffRatio)
+	}
+	if c.MaxStoreDownDuration.Duration <= 0 {
+		return errors.Errorf("max-store-down-duration %v should be greater than 0", c.MaxStoreDownDuration)
+	}
+	if c.ScheduleInterval.Duration <= 0 {
+		return errors.Errorf("schedule-interval %v should be greater than 0", c.ScheduleInterval)
+	}
+	if c.ReplicaChangeRate < 0 {
+		return errors.Errorf("replica-change-rate %v should not be negative", c.ReplicaChangeRate)
+	}
+	if c.OperatorHistoryRetention.Duration < 0 {
+		return errors.Errorf("operator-history-retention %v should not be negative", c.OperatorHistoryRetention)
+	}
+	if c.TransferLeaderTimeout.Duration <= 0 {
+		return errors.Errorf("transfer-leader-timeout %v should be greater than 0", c.TransferLeaderTimeout)
+	}
+	if c.AddPeerTimeout.Duration <= 0 {
+		return errors.Errorf("add-peer-timeout %v should be greater than 0", c.AddPeerTimeout)
+	}
+	if c.RemovePeerTimeout.Duration <= 0 {
+		return errors.Errorf("remove-peer-timeout %v should be greater than 0", c.RemovePeerTimeout)
+	}
+	for i := range c.EngineReplicas {
+		r := &c.EngineReplicas[i]
+		if r.Engine == "" || r.Engine == defaultEngine {
+			return errors.Errorf("engine replica engine %q should be a special engine", r.Engine)
+		}
+		if r.Count == 0 {
+			return errors.Errorf("engine replica count of [%s, %s) should be greater than 0", r.StartKey, r.EndKey)
+		}
+		if err := r.keyRange().Validate(); err != nil {
+			return errors.Trace(err)
+		}
+		for j := 0; j < i; j++ {
+			if r.keyRange().overlaps(c.EngineReplicas[j].keyRange()) {
+				return errors.Errorf("engine replica ranges [%s, %s) and [%s, %s) overlap",
+					c.EngineReplicas[j].StartKey, c.EngineReplicas[j].EndKey, r.StartKey, r.EndKey)
+			}
+		}
+	}
+	return nil
 }
 
 // ReplicationConfig is the replication configuration.
@@ -337,16 +667,134 @@ func (c *ReplicationConfig) adjust() {
 	adjustUint64(&c.MaxReplicas, defaultMaxReplicas)
 }
 
+// Validate checks the replication config.
+func (c *ReplicationConfig) Validate() error {
+	if c.MaxReplicas == 0 {
+		return errors.New("max-replicas should be greater than 0")
+	}
+	labels := make(map[string]struct{}, len(c.LocationLabels))
+	for _, label := range c.LocationLabels {
+		if label == "" {
+			return errors.New("location-labels should not contain empty label")
+		}
+		if _, ok := labels[label]; ok {
+			return errors.Errorf("location-labels contains duplicated label %s", label)
+		}
+		labels[label] = struct{}{}
+	}
+	return nil
+}
+
+// Types of the label properties.
+const (
+	// RejectLeader makes the stores not receive leaders.
+	RejectLeader = "reject-leader"
+	// SlowStore makes the stores not receive leaders or new peers.
+	SlowStore = "slow-store"
+)
+
+// StoreLabel is a label of the stores which a label property applies to.
+type StoreLabel struct {
+	Key   string `toml:"key" json:"key"`
+	Value string `toml:"value" json:"value"`
+}
+
+// LabelPropertyConfig is the label properties by type, a property applies to
+// the stores with any of its labels.
+type LabelPropertyConfig map[string][]StoreLabel
+
+// Validate checks the types and labels of the label properties.
+func (c LabelPropertyConfig) Validate() error {
+	for typ, labels := range c {
+		if typ != RejectLeader && typ != SlowStore {
+			return errors.Errorf("unknown label property %s", typ)
+		}
+		for _, label := range labels {
+			if label.Key == "" || label.Value == "" {
+				return errors.Errorf("label property %s contains empty label", typ)
+			}
+		}
+	}
+	return nil
+}
+
+func (c LabelPropertyConfig) clone() LabelPropertyConfig {
+	cfg := make(LabelPropertyConfig, len(c))
+	for typ, labels := range c {
+		cfg[typ] = append([]StoreLabel(nil), labels...)
+	}
+	return cfg
+}
+
+// APIAuthConfig is the authentication of the APIs. If any tokens or common
+// names are set, the mutating APIs require a token in the Authorization
+// header as "Bearer <token>", or a client cert with an allowed common name.
+type APIAuthConfig struct {
+	// Tokens are hidden from the config APIs.
+	Tokens []string `toml:"tokens" json:"-"`
+	// AllowedCNs are the common names of the allowed client certs, the
+	// common name of the cert used by the members is always allowed, so the
+	// requests redirected to the leader are accepted.
+	AllowedCNs []string `toml:"allowed-cn" json:"allowed-cn"`
+	// ReadRequireAuth requires the read-only APIs to be authenticated too.
+	ReadRequireAuth bool `toml:"read-require-auth" json:"read-require-auth"`
+}
+
+// Enabled returns true if the APIs require authentication.
+func (c APIAuthConfig) Enabled() bool {
+	return len(c.Tokens) > 0 || len(c.AllowedCNs) > 0
+}
+
+func (c APIAuthConfig) validate(security tlsutil.SecurityConfig) error {
+	for _, token := range c.Tokens {
+		if token == "" {
+			return errors.New("api-auth tokens contain empty token")
+		}
+	}
+	if len(c.AllowedCNs) > 0 && !security.ClientCertAuth {
+		return errors.New("api-auth allowed-cn needs client-cert-auth")
+	}
+	if c.ReadRequireAuth && !c.Enabled() {
+		return errors.New("api-auth read-require-auth needs tokens or allowed-cn")
+	}
+	return nil
+}
+
+// EncryptionConfig is the management of the data keys of the encryption at
+// rest of the stores. The data keys are generated and rotated by the leader,
+// and saved in etcd encrypted by the master key.
+type EncryptionConfig struct {
+	// MasterKeyPath is the file of the master key in hex, 32 bytes for
+	// AES-256. All members should have the same master key. The encryption
+	// is disabled if it is not set.
+	MasterKeyPath string `toml:"master-key-path" json:"master-key-path"`
+	// DataKeyRotationPeriod is the age of the current data key to rotate.
+	DataKeyRotationPeriod typeutil.Duration `toml:"data-key-rotation-period" json:"data-key-rotation-period"`
+}
+
+// Enabled returns true if the encryption is enabled.
+func (c EncryptionConfig) Enabled() bool {
+	return c.MasterKeyPath != ""
+}
+
 // scheduleOption is a wrapper to access the configuration safely.
 type scheduleOption struct {
-	v   atomic.Value
-	rep *Replication
+	v             atomic.Value
+	rep           *Replication
+	labelProperty atomic.Value
+	featureGates  atomic.Value
+	classifier    atomic.Value
+	nsConfigs     atomic.Value
 }
 
 func newScheduleOption(cfg *Config) *scheduleOption {
 	o := &scheduleOption{}
 	o.store(&cfg.Schedule)
 	o.rep = newReplication(&cfg.Replication)
+	o.SetLabelProperty(cfg.LabelProperty)
+	o.SetFeatureGates(newFeatureGates())
+	o.SetClassifier(defaultClassifier{})
+	o.nsConfigs.Store(map[string]NamespaceConfig{})
 	return o
 }
 
@@ -406,6 +854,156 @@ func (o *scheduleOption) GetReplicaScheduleLimit() uint64 {
 	return o.load().ReplicaScheduleLimit
 }
 
+func (o *scheduleOption) GetLabelProperty() LabelPropertyConfig {
+	return o.labelProperty.Load().(LabelPropertyConfig)
+}
+
+func (o *scheduleOption) SetLabelProperty(cfg LabelPropertyConfig) {
+	o.labelProperty.Store(cfg.clone())
+}
+
+// CheckLabelProperty returns true if the store with the labels has the label
+// property, it is always false if the label property feature gate is disabled.
+func (o *scheduleOption) CheckLabelProperty(typ string, labels []*metapb.StoreLabel) bool {
+	if !o.IsFeatureEnabled(FeatureLabelProperty) {
+		return false
+	}
+	for _, l := range o.GetLabelProperty()[typ] {
+		for _, label := range labels {
+			if label.GetKey() == l.Key && label.GetValue() == l.Value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (o *scheduleOption) GetFeatureGates() FeatureGates {
+	return o.featureGates.Load().(FeatureGates).clone()
+}
+
+func (o *scheduleOption) SetFeatureGates(gates FeatureGates) {
+	o.featureGates.Store(gates.clone())
+}
+
+// IsFeatureEnabled returns true if the feature gate is enabled.
+func (o *scheduleOption) IsFeatureEnabled(name string) bool {
+	return o.featureGates.Load().(FeatureGates)[name]
+}
+
+// classifierValue wraps the classifier, the values stored in atomic.Value
+// must have the same concrete type.
+type classifierValue struct {
+	Classifier
+}
+
+func (o *scheduleOption) GetClassifier() Classifier {
+	return o.classifier.Load().(classifierValue).Classifier
+}
+
+func (o *scheduleOption) SetClassifier(classifier Classifier) {
+	o.classifier.Store(classifierValue{classifier})
+}
+
+// SetNamespaces applies the namespaces to the classifier and the namespace
+// configs.
+func (o *scheduleOption) SetNamespaces(namespaces []*Namespace) {
+	configs := make(map[string]NamespaceConfig, len(namespaces))
+	for _, ns := range namespaces {
+		configs[ns.Name] = ns.NamespaceConfig.clone()
+	}
+	o.nsConfigs.Store(configs)
+	o.SetClassifier(newTableNamespaceClassifier(namespaces))
+}
+
+func (o *scheduleOption) getNamespaceConfig(namespace string) NamespaceConfig {
+	return o.nsConfigs.Load().(map[string]NamespaceConfig)[namespace]
+}
+
+// GetRegionMaxReplicas returns the max replicas of the region's namespace.
+func (o *scheduleOption) GetRegionMaxReplicas(region *metapb.Region) int {
+	namespace := o.GetClassifier().GetRegionNamespace(region)
+	if cfg := o.getNamespaceConfig(namespace); cfg.MaxReplicas > 0 {
+		return int(cfg.MaxReplicas)
+	}
+	return o.GetMaxReplicas()
+}
+
+// GetRegionReplication returns the replication of the region's namespace,
+// which isolates the replicas by the location labels of the namespace.
+func (o *scheduleOption) GetRegionReplication(region *metapb.Region) *Replication {
+	namespace := o.GetClassifier().GetRegionNamespace(region)
+	cfg := o.getNamespaceConfig(namespace)
+	if len(cfg.LocationLabels) == 0 {
+		return o.rep
+	}
+	return newReplication(&ReplicationConfig{
+		MaxReplicas:    uint64(o.GetRegionMaxReplicas(region)),
+		LocationLabels: cfg.LocationLabels,
+	})
+}
+
+// GetNamespaceLeaderScheduleLimit returns the leader schedule limit of the
+// namespace.
+func (o *scheduleOption) GetNamespaceLeaderScheduleLimit(namespace string) uint64 {
+	if cfg := o.getNamespaceConfig(namespace); cfg.LeaderScheduleLimit > 0 {
+		return cfg.LeaderScheduleLimit
+	}
+	return o.GetLeaderScheduleLimit()
+}
+
+// GetNamespaceRegionScheduleLimit returns the region schedule limit of the
+// namespace.
+func (o *scheduleOption) GetNamespaceRegionScheduleLimit(namespace string) uint64 {
+	if cfg := o.getNamespaceConfig(namespace); cfg.RegionScheduleLimit > 0 {
+		return cfg.RegionScheduleLimit
+	}
+	return o.GetRegionScheduleLimit()
+}
+
+// GetNamespaceReplicaScheduleLimit returns the replica schedule limit of the
+// namespace.
+func (o *scheduleOption) GetNamespaceReplicaScheduleLimit(namespace string) uint64 {
+	if cfg := o.getNamespaceConfig(namespace); cfg.ReplicaScheduleLimit > 0 {
+		return cfg.ReplicaScheduleLimit
+	}
+	return o.GetReplicaScheduleLimit()
+}
+
+// IsSchedulerDisabled returns true if the scheduler is disabled in the namespace.
+func (o *scheduleOption) IsSchedulerDisabled(namespace, scheduler string) bool {
+	for _, name := range o.getNamespaceConfig(namespace).DisabledSchedulers {
+		if name == scheduler {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *scheduleOption) GetReplicaChangeRate() float64 {
+	return o.load().ReplicaChangeRate
+}
+
+func (o *scheduleOption) GetOperatorHistoryRetention() time.Duration {
+	return o.load().OperatorHistoryRetention.Duration
+}
+
+func (o *scheduleOption) GetTransferLeaderTimeout() time.Duration {
+	return o.load().TransferLeaderTimeout.Duration
+}
+
+func (o *scheduleOption) GetAddPeerTimeout() time.Duration {
+	return o.load().AddPeerTimeout.Duration
+}
+
+func (o *scheduleOption) GetRemovePeerTimeout() time.Duration {
+	return o.load().RemovePeerTimeout.Duration
+}
+
+func (o *scheduleOption) GetEngineReplicas() []EngineReplicaConfig {
+	return o.load().EngineReplicas
+}
+
 // ParseUrls parse a string into multiple urls.
 // Export for api.
 func ParseUrls(s string) ([]url.URL, error) {
@@ -431,10 +1029,14 @@ func (c *Config) genEmbedEtcdConfig() (*embed.Config, error) {
 	cfg.WalDir = ""
 	cfg.InitialCluster = c.InitialCluster
 	cfg.ClusterState = c.InitialClusterState
-	cfg.EnablePprof = true
+	cfg.EnablePprof = c.EnablePProf
 	cfg.StrictReconfigCheck = !c.disableStrictReconfigCheck
 	cfg.TickMs = uint(c.tickMs)
 	cfg.ElectionMs = uint(c.electionMs)
+	if c.Security.Enabled() {
+		cfg.ClientTLSInfo = c.Security.TLSInfo()
+		cfg.PeerTLSInfo = c.Security.TLSInfo()
+	}
 
 	var err error
 
@@ -0,0 +1,131 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/pd/pkg/typeutil"
+)
+
+// memberConfigs are the keys identifying a member, they always differ
+// between members and from the defaults, so they are not diffed.
+var memberConfigs = map[string]struct{}{
+	"client-urls":           {},
+	"peer-urls":             {},
+	"advertise-client-urls": {},
+	"advertise-peer-urls":   {},
+	"name":                  {},
+	"data-dir":              {},
+	"initial-cluster":       {},
+	"initial-cluster-state": {},
+	"join":                  {},
+	"dc-location":           {},
+	"metric.job":            {},
+}
+
+// ConfigDiffItem is a config item whose value differs from the baseline, the
+// key is the dotted path in the config file, e.g. schedule.leader-schedule-limit.
+type ConfigDiffItem struct {
+	Key      string      `json:"key"`
+	Value    interface{} `json:"value"`
+	Baseline interface{} `json:"baseline"`
+}
+
+// DefaultConfig returns the compiled-in default config.
+func DefaultConfig() (*Config, error) {
+	cfg := NewConfig()
+	if err := cfg.adjust(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return cfg, nil
+}
+
+// DiffDefaultConfig returns the items of the config which differ from the
+// compiled-in defaults.
+func DiffDefaultConfig(cfg *Config) ([]*ConfigDiffItem, error) {
+	def, err := DefaultConfig()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return diffConfigItems(def, cfg, func(string) bool { return true }), nil
+}
+
+// DiffMemberConfig returns the items of the member config which differ from
+// the leader config. The schedule, replication and label property config are
+// not diffed, they are loaded from the config versions when a member becomes
+// leader.
+func DiffMemberConfig(leader, member *Config) []*ConfigDiffItem {
+	return diffConfigItems(leader, member, func(key string) bool {
+		return !isVersionedConfig(strings.Split(key, ".")[0])
+	})
+}
+
+// diffConfigItems compares the configs field by field, the structs with
+// toml tags are compared by their fields.
+func diffConfigItems(baseline, cfg *Config, filter func(key string) bool) []*ConfigDiffItem {
+	items := []*ConfigDiffItem{}
+	diffConfigValue("", reflect.ValueOf(baseline).Elem(), reflect.ValueOf(cfg).Elem(), func(key string, baseline, value reflect.Value) {
+		if _, ok := memberConfigs[key]; ok || !filter(key) {
+			return
+		}
+		if reflect.DeepEqual(baseline.Interface(), value.Interface()) {
+			return
+		}
+		items = append(items, &ConfigDiffItem{
+			Key:      key,
+			Value:    configItemValue(value),
+			Baseline: configItemValue(baseline),
+		})
+	})
+	return items
+}
+
+func diffConfigValue(prefix string, baseline, value reflect.Value, fn func(key string, baseline, value reflect.Value)) {
+	typ := value.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		name := strings.Split(typ.Field(i).Tag.Get("toml"), ",")[0]
+		if name == "" {
+			continue
+		}
+		key := prefix + name
+		if hasTOMLFields(typ.Field(i).Type) {
+			diffConfigValue(key+".", baseline.Field(i), value.Field(i), fn)
+			continue
+		}
+		fn(key, baseline.Field(i), value.Field(i))
+	}
+}
+
+func hasTOMLFields(typ reflect.Type) bool {
+	if typ.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		if typ.Field(i).Tag.Get("toml") != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// configItemValue returns the value as it is written in the config file.
+func configItemValue(v reflect.Value) interface{} {
+	if d, ok := v.Interface().(typeutil.Duration); ok {
+		return d.String()
+	}
+	return v.Interface()
+}
@@ -0,0 +1,57 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testConfigDiffSuite{})
+
+type testConfigDiffSuite struct{}
+
+func (s *testConfigDiffSuite) TestDiffConfig(c *C) {
+	cfg, err := DefaultConfig()
+	c.Assert(err, IsNil)
+	items, err := DiffDefaultConfig(cfg)
+	c.Assert(err, IsNil)
+	c.Assert(items, HasLen, 0)
+
+	// The member configs are not diffed.
+	cfg.Name = "pd1"
+	cfg.DataDir = "/tmp/pd1"
+	cfg.LogLevel = "debug"
+	cfg.Log.MaxDays = 7
+	cfg.Schedule.LeaderScheduleLimit = 32
+	cfg.Schedule.MaxStoreDownDuration.Duration = time.Minute
+	items, err = DiffDefaultConfig(cfg)
+	c.Assert(err, IsNil)
+	c.Assert(items, DeepEquals, []*ConfigDiffItem{
+		{Key: "log-level", Value: "debug", Baseline: "info"},
+		{Key: "log.max-days", Value: 7, Baseline: 0},
+		{Key: "schedule.max-store-down-duration", Value: "1m0s", Baseline: "1h0m0s"},
+		{Key: "schedule.leader-schedule-limit", Value: uint64(32), Baseline: uint64(16)},
+	})
+
+	// The versioned configs are not diffed between members.
+	member := cfg.clone()
+	member.LogLevel = "info"
+	member.Schedule.LeaderScheduleLimit = 16
+	member.Replication.MaxReplicas = 5
+	c.Assert(DiffMemberConfig(cfg, member), DeepEquals, []*ConfigDiffItem{
+		{Key: "log-level", Value: "info", Baseline: "debug"},
+	})
+}
@@ -0,0 +1,167 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+)
+
+// reloadableConfigs are the top level keys of the config file which can be
+// changed without restarting the server.
+// configReloadAuthor is the author of the config versions saved by reloading.
+const configReloadAuthor = "config-reload"
+
+var reloadableConfigs = map[string]struct{}{
+	"log-level":              {},
+	"slow-request-log-level": {},
+	"slow-request-threshold": {},
+	"schedule":               {},
+	"replication":            {},
+	"label-property":         {},
+}
+
+// ConfigReloadResult is the result of reloading the config file, Applied are
+// the changed keys which take effect, Rejected are the changed keys which
+// need a restart to take effect.
+type ConfigReloadResult struct {
+	Applied  []string `json:"applied"`
+	Rejected []string `json:"rejected"`
+}
+
+// ReloadConfig reads the config file again and applies the changes of the
+// reloadable keys. The schedule, replication and label property config are
+// saved as a new version if the server is leader.
+func (s *Server) ReloadConfig() (*ConfigReloadResult, error) {
+	configLock.Lock()
+	defer configLock.Unlock()
+
+	if s.cfg.configFile == "" {
+		return nil, errors.New("no config file to reload")
+	}
+
+	cfg := s.cfg.clone()
+	md, err := toml.DecodeFile(s.cfg.configFile, cfg)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err = checkUndecoded(&md); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err = validateReloadedConfig(cfg, &md); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	result := diffConfig(s.cfg, cfg, &md)
+	// The versioned config is saved first, so nothing is applied if it fails.
+	versioned := false
+	for _, key := range result.Applied {
+		versioned = versioned || (isVersionedConfig(key) && s.IsLeader())
+	}
+	if versioned {
+		v := s.newConfigVersion(configReloadAuthor)
+		v.Schedule, v.Replication, v.LabelProperty = cfg.Schedule, cfg.Replication, cfg.LabelProperty
+		if _, err = s.commitConfig(v); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	for _, key := range result.Applied {
+		if versioned && isVersionedConfig(key) {
+			continue
+		}
+		s.applyConfig(key, cfg)
+	}
+	log.Infof("config file %s is reloaded, applied %v, rejected %v", s.cfg.configFile, result.Applied, result.Rejected)
+	return result, nil
+}
+
+func validateReloadedConfig(cfg *Config, md *toml.MetaData) error {
+	if md.IsDefined("log-level") {
+		switch cfg.LogLevel {
+		case "debug", "info", "warn", "error", "fatal":
+		default:
+			return errors.Errorf("invalid log-level %s", cfg.LogLevel)
+		}
+	}
+	switch cfg.SlowRequestLogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		return errors.Errorf("invalid slow-request-log-level %s", cfg.SlowRequestLogLevel)
+	}
+	cfg.Schedule.adjust()
+	if err := cfg.Schedule.Validate(); err != nil {
+		return errors.Trace(err)
+	}
+	cfg.Replication.adjust()
+	if err := cfg.Replication.Validate(); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(cfg.LabelProperty.Validate())
+}
+
+// diffConfig returns the top level keys which are defined in the config file
+// and changed from the current config.
+func diffConfig(old, cfg *Config, md *toml.MetaData) *ConfigReloadResult {
+	result := &ConfigReloadResult{}
+
+	oldValue, newValue := reflect.ValueOf(old).Elem(), reflect.ValueOf(cfg).Elem()
+	typ := oldValue.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		key := strings.Split(typ.Field(i).Tag.Get("toml"), ",")[0]
+		if key == "" || !md.IsDefined(key) {
+			continue
+		}
+		if reflect.DeepEqual(oldValue.Field(i).Interface(), newValue.Field(i).Interface()) {
+			continue
+		}
+		if _, ok := reloadableConfigs[key]; ok {
+			result.Applied = append(result.Applied, key)
+		} else {
+			result.Rejected = append(result.Rejected, key)
+		}
+	}
+
+	sort.Strings(result.Applied)
+	sort.Strings(result.Rejected)
+	return result
+}
+
+func (s *Server) applyConfig(key string, cfg *Config) {
+	switch key {
+	case "log-level":
+		log.SetLevelByString(cfg.LogLevel)
+		s.cfg.LogLevel = cfg.LogLevel
+	case "slow-request-log-level":
+		s.cfg.SlowRequestLogLevel = cfg.SlowRequestLogLevel
+	case "slow-request-threshold":
+		s.cfg.SlowRequestThreshold = cfg.SlowRequestThreshold
+	case "schedule":
+		s.SetScheduleConfig(cfg.Schedule)
+	case "replication":
+		s.cfg.Replication = cfg.Replication
+	case "label-property":
+		s.setLabelProperty(cfg.LabelProperty)
+	}
+}
+
+// isVersionedConfig returns true if the changes of the key are saved as
+// config versions.
+func isVersionedConfig(key string) bool {
+	return key == "schedule" || key == "replication" || key == "label-property"
+}
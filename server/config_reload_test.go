@@ -0,0 +1,80 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"io/ioutil"
+	"os"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testConfigReloadSuite{})
+
+type testConfigReloadSuite struct{}
+
+func (s *testConfigReloadSuite) TestReloadConfig(c *C) {
+	svr, cleanup := mustRunTestServer(c)
+	defer cleanup()
+
+	_, err := svr.ReloadConfig()
+	c.Assert(err, NotNil)
+
+	f, err := ioutil.TempFile("", "pd_config")
+	c.Assert(err, IsNil)
+	defer os.Remove(f.Name())
+	svr.cfg.configFile = f.Name()
+
+	// Nothing is changed.
+	result, err := svr.ReloadConfig()
+	c.Assert(err, IsNil)
+	c.Assert(result.Applied, HasLen, 0)
+	c.Assert(result.Rejected, HasLen, 0)
+
+	data := `
+log-level = "warn"
+lease = 10
+
+[schedule]
+leader-schedule-limit = 32
+
+[replication]
+max-replicas = 5
+`
+	c.Assert(ioutil.WriteFile(f.Name(), []byte(data), 0644), IsNil)
+	result, err = svr.ReloadConfig()
+	c.Assert(err, IsNil)
+	c.Assert(result.Applied, DeepEquals, []string{"log-level", "replication", "schedule"})
+	c.Assert(result.Rejected, DeepEquals, []string{"lease"})
+	defer svr.applyConfig("log-level", &Config{LogLevel: "info"})
+
+	cfg := svr.GetConfig()
+	c.Assert(cfg.LogLevel, Equals, "warn")
+	c.Assert(cfg.LeaderLease, Equals, int64(1))
+	c.Assert(svr.scheduleOpt.GetLeaderScheduleLimit(), Equals, uint64(32))
+	c.Assert(svr.scheduleOpt.GetMaxReplicas(), Equals, 5)
+
+	// The schedule and replication config are saved as a version.
+	versions, err := svr.GetConfigHistory()
+	c.Assert(err, IsNil)
+	c.Assert(versions, HasLen, 1)
+	c.Assert(versions[0].Author, Equals, configReloadAuthor)
+	c.Assert(versions[0].Replication.MaxReplicas, Equals, uint64(5))
+
+	// Invalid config is not applied.
+	c.Assert(ioutil.WriteFile(f.Name(), []byte(`log-level = "none"`), 0644), IsNil)
+	_, err = svr.ReloadConfig()
+	c.Assert(err, NotNil)
+	c.Assert(svr.GetConfig().LogLevel, Equals, "warn")
+}
@@ -0,0 +1,79 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"io/ioutil"
+	"os"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testConfigSuite{})
+
+type testConfigSuite struct{}
+
+func (s *testConfigSuite) TestConfigFile(c *C) {
+	cfg := NewConfig()
+	c.Assert(cfg.Parse([]string{"-config", "../conf/config.toml"}), IsNil)
+
+	f, err := ioutil.TempFile("", "pd_config")
+	c.Assert(err, IsNil)
+	defer os.Remove(f.Name())
+
+	tests := []struct {
+		data string
+		err  bool
+	}{
+		{"name = \"pd\"\n[schedule]\nmax-snapshot-count = 5", false},
+		// Typos are rejected.
+		{"nam = \"pd\"", true},
+		{"[schedule]\nmax-snapshots-count = 5", true},
+		{"[replicaton]\nmax-replicas = 5", true},
+		// Out of range values are rejected.
+		{"[schedule]\nmin-balance-diff-ratio = 1.5", true},
+		{"[schedule]\nschedule-interval = \"-1s\"", true},
+		{"[schedule]\nadd-peer-timeout = \"5m\"", false},
+		{"[schedule]\ntransfer-leader-timeout = \"-1s\"", true},
+		{"[[schedule.engine-replicas]]\nengine = \"tiflash\"\ncount = 1", false},
+		{"[[schedule.engine-replicas]]\nengine = \"tikv\"\ncount = 1", true},
+		{"[[schedule.engine-replicas]]\nengine = \"tiflash\"\ncount = 1\n[[schedule.engine-replicas]]\nstart-key = \"00\"\nengine = \"tiflash\"\ncount = 1", true},
+		{"[replication]\nlocation-labels = [\"zone\", \"zone\"]", true},
+		{"[replication]\nlocation-labels = [\"\"]", true},
+		{"region-storage = \"local\"", false},
+		{"region-storage = \"leveldb\"", true},
+		{"region-storage = \"local\"\nenable-follower-read = true", true},
+		{"etcd-compaction-interval = \"-1h\"", true},
+		{"enable-trace = true\ntrace-exporter = \"net-trace\"", false},
+		{"trace-exporter = \"jaeger\"", true},
+		{"enable-schedule-throttle = true\nschedule-throttle-cpu-usage = 0.5", false},
+		{"schedule-throttle-cpu-usage = 1.5", true},
+		{"enable-etcd-defrag = true\netcd-defrag-interval = \"12h\"", false},
+		{"[security]\ncert-path = \"pd.pem\"", true},
+		{"[api-auth]\ntokens = [\"t1\"]\nread-require-auth = true", false},
+		{"[api-auth]\ntokens = [\"\"]", true},
+		{"[api-auth]\nread-require-auth = true", true},
+		{"[api-auth]\nallowed-cn = [\"admin\"]", true},
+		{"[encryption]\nmaster-key-path = \"master.key\"", true},
+		{"[api-auth]\ntokens = [\"t1\"]\n[encryption]\nmaster-key-path = \"master.key\"", false},
+		// The client certs alone don't protect the data keys.
+		{"[security]\ncacert-path = \"ca.pem\"\ncert-path = \"pd.pem\"\nkey-path = \"pd-key.pem\"\nclient-cert-auth = true\n[encryption]\nmaster-key-path = \"master.key\"", true},
+		{"[security]\ncacert-path = \"ca.pem\"\ncert-path = \"pd.pem\"\nkey-path = \"pd-key.pem\"\nclient-cert-auth = true\n[api-auth]\nallowed-cn = [\"admin\"]\n[encryption]\nmaster-key-path = \"master.key\"", false},
+	}
+	for _, t := range tests {
+		c.Assert(ioutil.WriteFile(f.Name(), []byte(t.data), 0644), IsNil)
+		err = NewConfig().Parse([]string{"-config", f.Name()})
+		c.Assert(err != nil, Equals, t.err, Commentf("%s: %v", t.data, err))
+	}
+}
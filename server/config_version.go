@@ -0,0 +1,198 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+)
+
+// ErrConfigVersionNotFound is returned when rolling back to a config version
+// which does not exist.
+var ErrConfigVersionNotFound = errors.New("config version is not found")
+
+// configLock serializes the changes of the config.
+var configLock sync.Mutex
+
+// ConfigVersion is a change of the schedule, replication and label property
+// config saved in etcd, the versions start from 1.
+type ConfigVersion struct {
+	Version       uint64              `json:"version"`
+	Author        string              `json:"author"`
+	Time          time.Time           `json:"time"`
+	Schedule      ScheduleConfig      `json:"schedule"`
+	Replication   ReplicationConfig   `json:"replication"`
+	LabelProperty LabelPropertyConfig `json:"label-property"`
+	// RollbackVersion is the version rolled back to, 0 if it is not a rollback.
+	RollbackVersion uint64 `json:"rollback-version,omitempty"`
+}
+
+// UpdateScheduleConfig saves the schedule config as a new version and applies it.
+func (s *Server) UpdateScheduleConfig(cfg ScheduleConfig, author string) (*ConfigVersion, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	configLock.Lock()
+	defer configLock.Unlock()
+
+	v := s.newConfigVersion(author)
+	v.Schedule = cfg
+	v, err := s.commitConfig(v)
+	return v, errors.Trace(err)
+}
+
+// UpdateReplicationConfig saves the replication config as a new version and
+// applies it.
+func (s *Server) UpdateReplicationConfig(cfg ReplicationConfig, author string) (*ConfigVersion, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	configLock.Lock()
+	defer configLock.Unlock()
+
+	v := s.newConfigVersion(author)
+	v.Replication = cfg
+	v, err := s.commitConfig(v)
+	return v, errors.Trace(err)
+}
+
+// RollbackConfig applies the config of the version, the rollback is saved as
+// a new version.
+func (s *Server) RollbackConfig(version uint64, author string) (*ConfigVersion, error) {
+	configLock.Lock()
+	defer configLock.Unlock()
+
+	old, err := s.kv.loadConfigVersion(version)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if old == nil {
+		return nil, errors.Trace(ErrConfigVersionNotFound)
+	}
+	v := s.newConfigVersion(author)
+	v.Schedule, v.Replication, v.LabelProperty = old.Schedule, old.Replication, old.LabelProperty
+	v.RollbackVersion = version
+	v, err = s.commitConfig(v)
+	return v, errors.Trace(err)
+}
+
+// SetLabelProperty adds the label to the label property and saves it as a new
+// version.
+func (s *Server) SetLabelProperty(typ string, label StoreLabel, author string) (*ConfigVersion, error) {
+	configLock.Lock()
+	defer configLock.Unlock()
+
+	v := s.newConfigVersion(author)
+	for _, l := range v.LabelProperty[typ] {
+		if l == label {
+			return v, nil
+		}
+	}
+	v.LabelProperty[typ] = append(v.LabelProperty[typ], label)
+	if err := v.LabelProperty.Validate(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	v, err := s.commitConfig(v)
+	return v, errors.Trace(err)
+}
+
+// DeleteLabelProperty deletes the label from the label property and saves it
+// as a new version.
+func (s *Server) DeleteLabelProperty(typ string, label StoreLabel, author string) (*ConfigVersion, error) {
+	configLock.Lock()
+	defer configLock.Unlock()
+
+	v := s.newConfigVersion(author)
+	labels := v.LabelProperty[typ][:0]
+	for _, l := range v.LabelProperty[typ] {
+		if l != label {
+			labels = append(labels, l)
+		}
+	}
+	if len(labels) == len(v.LabelProperty[typ]) {
+		return v, nil
+	}
+	if len(labels) == 0 {
+		delete(v.LabelProperty, typ)
+	} else {
+		v.LabelProperty[typ] = labels
+	}
+	v, err := s.commitConfig(v)
+	return v, errors.Trace(err)
+}
+
+// GetConfigHistory returns the config versions in order.
+func (s *Server) GetConfigHistory() ([]*ConfigVersion, error) {
+	versions, err := s.kv.loadConfigVersions()
+	return versions, errors.Trace(err)
+}
+
+// newConfigVersion returns a version of the current config to be changed.
+func (s *Server) newConfigVersion(author string) *ConfigVersion {
+	return &ConfigVersion{
+		Author:        author,
+		Schedule:      s.cfg.Schedule,
+		Replication:   s.cfg.Replication,
+		LabelProperty: s.scheduleOpt.GetLabelProperty().clone(),
+	}
+}
+
+// commitConfig saves the config as the next version and then applies it, so
+// the config is not changed if saving fails. It must be called with configLock.
+func (s *Server) commitConfig(v *ConfigVersion) (*ConfigVersion, error) {
+	latest, err := s.kv.loadLatestConfigVersion()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	v.Version = 1
+	if latest != nil {
+		v.Version = latest.Version + 1
+	}
+	v.Time = time.Now()
+	if err = s.kv.saveConfigVersion(v); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	s.applyConfigVersion(v)
+	log.Infof("config version %d is saved by %s", v.Version, v.Author)
+	return v, nil
+}
+
+// loadConfig applies the latest config version after becoming leader, so the
+// changes are kept across leader changes and restarts.
+func (s *Server) loadConfig() error {
+	configLock.Lock()
+	defer configLock.Unlock()
+
+	v, err := s.kv.loadLatestConfigVersion()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if v != nil {
+		s.applyConfigVersion(v)
+		log.Infof("config version %d is loaded", v.Version)
+	}
+	return nil
+}
+
+func (s *Server) applyConfigVersion(v *ConfigVersion) {
+	s.SetScheduleConfig(v.Schedule)
+	s.cfg.Replication = v.Replication
+	s.setLabelProperty(v.LabelProperty)
+}
@@ -15,9 +15,12 @@ package server
 
 import (
 	"bufio"
+	"fmt"
 	"io"
 	"net"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/juju/errors"
@@ -26,11 +29,15 @@ import (
 	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/pingcap/kvproto/pkg/util"
 	"github.com/pingcap/pd/pkg/metricutil"
+	"github.com/pingcap/pd/pkg/rpcutil"
 )
 
 const (
 	readBufferSize  = 8 * 1024
 	writeBufferSize = 8 * 1024
+	// tsoProxyTimeout is the timeout of connecting leader and forwarding a
+	// tso request to it.
+	tsoProxyTimeout = 3 * time.Second
 )
 
 type conn struct {
@@ -39,6 +46,11 @@ type conn struct {
 	rb   *bufio.Reader
 	wb   *bufio.Writer
 	conn net.Conn
+	// caller is the host of the remote address, it labels the cmd metrics.
+	caller string
+
+	// 1 if a request is being handled.
+	busy int64
 }
 
 func newConn(s *Server, netConn net.Conn, bufrw *bufio.ReadWriter) (*conn, error) {
@@ -46,10 +58,11 @@ func newConn(s *Server, netConn net.Conn, bufrw *bufio.ReadWriter) (*conn, error
 	defer s.connsLock.Unlock()
 
 	c := &conn{
-		s:    s,
-		rb:   bufrw.Reader,
-		wb:   bufrw.Writer,
-		conn: netConn,
+		s:      s,
+		rb:     bufrw.Reader,
+		wb:     bufrw.Writer,
+		conn:   netConn,
+		caller: remoteHost(netConn),
 	}
 
 	s.conns[c] = struct{}{}
@@ -71,6 +84,8 @@ func (c *conn) run() {
 	defer p.close()
 
 	for {
+		atomic.StoreInt64(&c.busy, 0)
+
 		msg := &msgpb.Message{}
 		msgID, err := util.ReadMessage(c.rb, msg)
 		if err != nil {
@@ -79,6 +94,7 @@ func (c *conn) run() {
 			}
 			return
 		}
+		atomic.StoreInt64(&c.busy, 1)
 
 		if msg.GetMsgType() != msgpb.MessageType_PdReq {
 			log.Errorf("invalid request message %v", msg)
@@ -88,6 +104,8 @@ func (c *conn) run() {
 		start := time.Now()
 		request := msg.GetPdReq()
 		label := metricutil.GetCmdLabel(request)
+		tr := newTrace(traceFamilyCmd, label)
+		tr.LazyLog(&requestTraceInfo{request: request, caller: c.caller}, false)
 
 		var response *pdpb.Response
 
@@ -95,6 +113,7 @@ func (c *conn) run() {
 			log.Errorf("check request %s err %v", request, errors.ErrorStack(err))
 			response = newError(err)
 		} else if !c.s.IsLeader() {
+			tr.LazyPrintf("proxy to leader")
 			response, err = p.handleRequest(msgID, request)
 			if err != nil {
 				if isUnexpectedConnError(err) {
@@ -103,6 +122,9 @@ func (c *conn) run() {
 				response = newError(err)
 			}
 		} else {
+			if isHeartbeat(request) {
+				c.s.scheduleThrottle.startHeartbeat()
+			}
 			response, err = c.handleRequest(request)
 			if err != nil {
 				if isUnexpectedConnError(err) {
@@ -110,14 +132,28 @@ func (c *conn) run() {
 				}
 				response = newError(err)
 			}
+			if isHeartbeat(request) {
+				c.s.scheduleThrottle.finishHeartbeat()
+				c.s.heartbeatLatency.observe(time.Since(start))
+				c.s.captures.record(request, response)
+			}
 		}
 
 		if err == nil {
-			cmdCounter.WithLabelValues(label).Inc()
-			cmdDuration.WithLabelValues(label).Observe(time.Since(start).Seconds())
+			cmdCounter.WithLabelValues(label, c.caller).Inc()
+			cmdDuration.WithLabelValues(label, c.caller).Observe(time.Since(start).Seconds())
 		} else {
-			cmdFailedCounter.WithLabelValues(label).Inc()
-			cmdFailedDuration.WithLabelValues(label).Observe(time.Since(start).Seconds())
+			cmdFailedCounter.WithLabelValues(label, c.caller).Inc()
+			cmdFailedDuration.WithLabelValues(label, c.caller).Observe(time.Since(start).Seconds())
+		}
+		if err != nil {
+			tr.LazyPrintf("err %v", err)
+			tr.SetError()
+		}
+		tr.Finish()
+		if cost := time.Since(start); cost > c.s.cfg.SlowRequestThreshold.Duration {
+			logSlowRequest(c.s.cfg.SlowRequestLogLevel, "slow request %s from %s cost %v, %s, err %v",
+				label, c.caller, cost, requestParams(request), err)
 		}
 
 		if response == nil {
@@ -148,7 +184,73 @@ func (c *conn) run() {
 			return
 		}
 
-		cmdCompletedDuration.WithLabelValues(label).Observe(time.Since(start).Seconds())
+		cmdCompletedDuration.WithLabelValues(label, c.caller).Observe(time.Since(start).Seconds())
+	}
+}
+
+// remoteHost returns the host of the remote address without the port, so
+// the reconnected clients share the same metrics.
+func remoteHost(netConn net.Conn) string {
+	addr := netConn.RemoteAddr()
+	if addr == nil || addr.String() == "" {
+		return "unknown"
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil || host == "" {
+		// Unix socket addresses have no port.
+		return addr.String()
+	}
+	return host
+}
+
+// requestParams returns the key parameters of the request, it is much shorter
+// than the request, e.g. a region heartbeat has all the peers of the region.
+func requestParams(req *pdpb.Request) string {
+	switch req.GetCmdType() {
+	case pdpb.CommandType_Tso:
+		return fmt.Sprintf("count %d", req.GetTso().GetCount())
+	case pdpb.CommandType_GetStore:
+		return fmt.Sprintf("store %d", req.GetGetStore().GetStoreId())
+	case pdpb.CommandType_PutStore:
+		return fmt.Sprintf("store %d", req.GetPutStore().GetStore().GetId())
+	case pdpb.CommandType_StoreHeartbeat:
+		return fmt.Sprintf("store %d", req.GetStoreHeartbeat().GetStats().GetStoreId())
+	case pdpb.CommandType_GetRegion:
+		return fmt.Sprintf("key %q", req.GetGetRegion().GetRegionKey())
+	case pdpb.CommandType_GetRegionByID:
+		return fmt.Sprintf("region %d", req.GetGetRegionById().GetRegionId())
+	case pdpb.CommandType_RegionHeartbeat:
+		heartbeat := req.GetRegionHeartbeat()
+		return fmt.Sprintf("region %d, leader store %d", heartbeat.GetRegion().GetId(), heartbeat.GetLeader().GetStoreId())
+	case pdpb.CommandType_AskSplit:
+		return fmt.Sprintf("region %d", req.GetAskSplit().GetRegion().GetId())
+	case pdpb.CommandType_ReportSplit:
+		split := req.GetReportSplit()
+		return fmt.Sprintf("left region %d, right region %d", split.GetLeft().GetId(), split.GetRight().GetId())
+	}
+	return "no params"
+}
+
+// requestTraceInfo formats the request only when the trace is rendered.
+type requestTraceInfo struct {
+	request *pdpb.Request
+	caller  string
+}
+
+func (i *requestTraceInfo) String() string {
+	return fmt.Sprintf("%s from %s", requestParams(i.request), i.caller)
+}
+
+func logSlowRequest(level string, format string, args ...interface{}) {
+	switch level {
+	case "debug":
+		log.Debugf(format, args...)
+	case "info":
+		log.Infof(format, args...)
+	case "error":
+		log.Errorf(format, args...)
+	default:
+		log.Warnf(format, args...)
 	}
 }
 
@@ -168,6 +270,18 @@ func updateResponse(req *pdpb.Request, resp *pdpb.Response) {
 	resp.Header.ClusterId = req.Header.ClusterId
 }
 
+func isHeartbeat(req *pdpb.Request) bool {
+	switch req.GetCmdType() {
+	case pdpb.CommandType_RegionHeartbeat, pdpb.CommandType_StoreHeartbeat:
+		return true
+	}
+	return false
+}
+
+func (c *conn) isBusy() bool {
+	return atomic.LoadInt64(&c.busy) == 1
+}
+
 func (c *conn) close() error {
 	if err := c.conn.Close(); isUnexpectedConnError(err) {
 		return errors.Trace(err)
@@ -240,13 +354,24 @@ func (p *leaderProxy) close() {
 }
 
 func (p *leaderProxy) handleRequest(msgID uint64, req *pdpb.Request) (*pdpb.Response, error) {
+	// Tso requests of all connections are forwarded together.
+	if req.GetCmdType() == pdpb.CommandType_Tso {
+		return p.s.tsoProxy.handleTso(req)
+	}
+
+	if p.s.followerCache != nil {
+		if resp, ok := p.s.followerCache.handleRequest(req); ok {
+			return resp, nil
+		}
+	}
+
 	// Create a connection to leader.
 	if p.conn == nil {
 		leader, err := p.s.GetLeader()
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
-		conn, err := rpcConnect(leader.GetAddr())
+		conn, err := rpcConnectWithTLS(leader.GetAddr(), p.s.GetTLSConfig())
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -261,6 +386,119 @@ func (p *leaderProxy) handleRequest(msgID uint64, req *pdpb.Request) (*pdpb.Resp
 	return resp, errors.Trace(err)
 }
 
+// tsoProxy forwards the tso requests of all connections on a follower
+// to leader through one shared connection, concurrent requests are
+// batched into one request.
+type tsoProxy struct {
+	s *Server
+
+	sync.Mutex
+	conn  net.Conn
+	msgID uint64
+
+	batcher *tsoBatcher
+}
+
+func newTSOProxy(s *Server) *tsoProxy {
+	p := &tsoProxy{s: s}
+	p.batcher = newTSOBatcher("proxy", p.forward)
+	return p
+}
+
+func (p *tsoProxy) close() {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn = nil
+	}
+}
+
+func (p *tsoProxy) handleTso(req *pdpb.Request) (*pdpb.Response, error) {
+	request := req.GetTso()
+	if request == nil {
+		return nil, errors.Errorf("invalid tso command, but %v", req)
+	}
+
+	count := request.GetCount()
+	ts, err := p.batcher.getTS(count)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	return &pdpb.Response{
+		Tso: &pdpb.TsoResponse{Timestamp: ts, Count: count},
+	}, nil
+}
+
+func (p *tsoProxy) forward(count uint32) (pdpb.Timestamp, error) {
+	var ts pdpb.Timestamp
+
+	conn, msgID, err := p.getConn()
+	if err != nil {
+		return ts, errors.Trace(err)
+	}
+
+	req := &pdpb.Request{
+		Header:  &pdpb.RequestHeader{ClusterId: p.s.clusterID},
+		CmdType: pdpb.CommandType_Tso,
+		Tso:     &pdpb.TsoRequest{Count: count},
+	}
+	// All the batched requests wait for the leader, so they fail in time if
+	// the leader hangs.
+	if err = conn.SetDeadline(time.Now().Add(tsoProxyTimeout)); err != nil {
+		p.resetConn(conn)
+		return ts, errors.Trace(err)
+	}
+	resp, err := rpcCall(conn, msgID, req)
+	if err != nil {
+		p.resetConn(conn)
+		return ts, errors.Trace(err)
+	}
+	if resp.GetHeader().GetError() != nil {
+		return ts, errors.New(resp.GetHeader().GetError().GetMessage())
+	}
+	if resp.GetTso() == nil {
+		return ts, errors.Errorf("invalid tso response %v", resp)
+	}
+	return resp.GetTso().GetTimestamp(), nil
+}
+
+// getConn returns the connection to leader and the next message ID, the
+// connection is created if there is none. The lock is not held during the
+// rpc, so closing the proxy doesn't wait for the leader.
+func (p *tsoProxy) getConn() (net.Conn, uint64, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.conn == nil {
+		leader, err := p.s.GetLeader()
+		if err != nil {
+			return nil, 0, errors.Trace(err)
+		}
+		conn, err := rpcutil.ConnectUrls(leader.GetAddr(), tsoProxyTimeout, p.s.GetTLSConfig())
+		if err != nil {
+			return nil, 0, errors.Trace(err)
+		}
+		p.conn = conn
+	}
+	p.msgID++
+	return p.conn, p.msgID, nil
+}
+
+// resetConn closes the failed connection, a new one is created for the next
+// request.
+func (p *tsoProxy) resetConn(conn net.Conn) {
+	p.Lock()
+	defer p.Unlock()
+
+	conn.Close()
+	if p.conn == conn {
+		p.conn = nil
+	}
+}
+
 var errClosed = errors.New("use of closed network connection")
 
 func isUnexpectedConnError(err error) bool {
@@ -15,10 +15,13 @@ package server
 
 import (
 	"io"
+	"io/ioutil"
+	"net"
 	"time"
 
 	"github.com/juju/errors"
 	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/pingcap/pd/pkg/testutil"
 )
@@ -92,6 +95,73 @@ func (s *testConnSuite) TestReconnect(c *C) {
 	}
 }
 
+func (s *testConnSuite) TestProxyTso(c *C) {
+	svrs, cleanup := newMultiTestServers(c, 3)
+	defer cleanup()
+
+	leader := mustWaitLeader(c, svrs)
+	var last pdpb.Timestamp
+	for _, svr := range svrs {
+		if svr == leader {
+			continue
+		}
+
+		conn, err := rpcConnect(svr.GetAddr())
+		c.Assert(err, IsNil)
+		for i := 0; i < 10; i++ {
+			req := &pdpb.Request{
+				Header:  newRequestHeader(svr.clusterID),
+				CmdType: pdpb.CommandType_Tso,
+				Tso:     &pdpb.TsoRequest{Count: 10},
+			}
+			resp, err := rpcCall(conn, uint64(i), req)
+			c.Assert(err, IsNil)
+			c.Assert(resp.GetHeader().GetError(), IsNil)
+			c.Assert(resp.GetTso().GetCount(), Equals, uint32(10))
+
+			ts := resp.GetTso().GetTimestamp()
+			c.Assert(ts.GetPhysical(), Not(Less), last.GetPhysical())
+			if ts.GetPhysical() == last.GetPhysical() {
+				c.Assert(ts.GetLogical(), Greater, last.GetLogical())
+			}
+			last = ts
+		}
+		conn.Close()
+
+		// The follower keeps one connection to leader for tso.
+		svr.tsoProxy.Lock()
+		c.Assert(svr.tsoProxy.conn, NotNil)
+		svr.tsoProxy.Unlock()
+	}
+}
+
+func (s *testConnSuite) TestTsoProxyTimeout(c *C) {
+	// The leader accepts the request but never responds.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go io.Copy(ioutil.Discard, conn)
+		}
+	}()
+	conn, err := net.Dial("tcp", l.Addr().String())
+	c.Assert(err, IsNil)
+
+	p := &tsoProxy{s: &Server{}, conn: conn}
+	start := time.Now()
+	_, err = p.forward(1)
+	c.Assert(err, NotNil)
+	c.Assert(time.Since(start), Less, 2*tsoProxyTimeout)
+	p.Lock()
+	c.Assert(p.conn, IsNil)
+	p.Unlock()
+}
+
 func (s *testConnSuite) TestUnexpectedError(c *C) {
 	c.Assert(isUnexpectedConnError(nil), IsFalse)
 	c.Assert(isUnexpectedConnError(errors.Trace(io.EOF)), IsFalse)
@@ -99,6 +169,41 @@ func (s *testConnSuite) TestUnexpectedError(c *C) {
 	c.Assert(isUnexpectedConnError(errors.Trace(io.ErrClosedPipe)), IsTrue)
 }
 
+type testAddrConn struct {
+	net.Conn
+	addr net.Addr
+}
+
+func (c *testAddrConn) RemoteAddr() net.Addr { return c.addr }
+
+func (s *testConnSuite) TestRemoteHost(c *C) {
+	tcpAddr := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 20160}
+	c.Assert(remoteHost(&testAddrConn{addr: tcpAddr}), Equals, "10.0.0.1")
+	unixAddr := &net.UnixAddr{Name: "/tmp/pd.sock", Net: "unix"}
+	c.Assert(remoteHost(&testAddrConn{addr: unixAddr}), Equals, "/tmp/pd.sock")
+	c.Assert(remoteHost(&testAddrConn{}), Equals, "unknown")
+}
+
+func (s *testConnSuite) TestRequestParams(c *C) {
+	req := &pdpb.Request{
+		CmdType: pdpb.CommandType_RegionHeartbeat,
+		RegionHeartbeat: &pdpb.RegionHeartbeatRequest{
+			Region: &metapb.Region{Id: 2},
+			Leader: &metapb.Peer{Id: 3, StoreId: 1},
+		},
+	}
+	c.Assert(requestParams(req), Equals, "region 2, leader store 1")
+
+	req = &pdpb.Request{
+		CmdType:   pdpb.CommandType_GetRegion,
+		GetRegion: &pdpb.GetRegionRequest{RegionKey: []byte("a")},
+	}
+	c.Assert(requestParams(req), Equals, `key "a"`)
+
+	req = &pdpb.Request{CmdType: pdpb.CommandType_AllocId}
+	c.Assert(requestParams(req), Equals, "no params")
+}
+
 func mustRequest(c *C, s *Server) *pdpb.Response {
 	req := &pdpb.Request{
 		Header:  newRequestHeader(s.clusterID),
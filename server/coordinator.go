@@ -14,19 +14,32 @@
 package server
 
 import (
+	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
+	raftpb "github.com/pingcap/kvproto/pkg/eraftpb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/pkg/failpoint"
+	"github.com/pingcap/pd/pkg/typeutil"
 	"golang.org/x/net/context"
+	"golang.org/x/net/trace"
 )
 
 const (
 	historiesCacheSize = 1000
+	recordsChanSize    = 1000
 	eventsCacheSize    = 1000
 	maxScheduleRetries = 10
+
+	// baseRegionSize is the region size the add peer timeout is for.
+	baseRegionSize = 64 * 1024 * 1024
+	// maxAddPeerTimeoutScale is the max times the add peer timeout is
+	// scaled by for the large regions.
+	maxAddPeerTimeoutScale = 10
 )
 
 var (
@@ -45,26 +58,38 @@ type coordinator struct {
 	opt        *scheduleOption
 	limiter    *scheduleLimiter
 	checker    *replicaChecker
+	nsChecker  *namespaceChecker
+	engChecker *engineReplicaChecker
 	operators  map[uint64]Operator
 	schedulers map[string]*scheduleController
 
 	histories *lruCache
 	events    *fifoCache
+	records   chan *OperatorRecord
+	opLog     trace.EventLog
+	bus       *eventBus
+	captures  *heartbeatCaptures
+	throttle  *scheduleThrottle
 }
 
 func newCoordinator(cluster *clusterInfo, opt *scheduleOption) *coordinator {
 	ctx, cancel := context.WithCancel(context.Background())
+	checker := newReplicaChecker(opt, cluster)
 	return &coordinator{
 		ctx:        ctx,
 		cancel:     cancel,
 		cluster:    cluster,
 		opt:        opt,
 		limiter:    newScheduleLimiter(),
-		checker:    newReplicaChecker(opt, cluster),
+		checker:    checker,
+		nsChecker:  newNamespaceChecker(opt, cluster, checker),
+		engChecker: newEngineReplicaChecker(opt, cluster),
 		operators:  make(map[uint64]Operator),
 		schedulers: make(map[string]*scheduleController),
 		histories:  newLRUCache(historiesCacheSize),
 		events:     newFifoCache(eventsCacheSize),
+		records:    make(chan *OperatorRecord, recordsChanSize),
+		opLog:      newEventLog(traceFamilyOperator, "coordinator"),
 	}
 }
 
@@ -73,17 +98,39 @@ func (c *coordinator) dispatch(region *regionInfo) *pdpb.RegionHeartbeatResponse
 	if op := c.getOperator(region.GetId()); op != nil {
 		res, finished := op.Do(region)
 		if !finished {
+			if _, ok := failpoint.Eval(FailpointDispatchOperator); ok {
+				return nil
+			}
+			if res != nil {
+				c.opLog.Printf("dispatch %v to region %d", res, region.GetId())
+			}
 			return res
 		}
-		c.removeOperator(op)
+		c.removeOperator(op, op.(*regionOperator).finishStatus())
 	}
 
 	// Check replica operator.
 	if c.limiter.operatorCount(regionKind) >= c.opt.GetReplicaScheduleLimit() {
 		return nil
 	}
+	namespace := c.opt.GetClassifier().GetRegionNamespace(region.Region)
+	if c.limiter.namespaceOperatorCount(namespace, regionKind) >= c.opt.GetNamespaceReplicaScheduleLimit(namespace) {
+		return nil
+	}
 	if op := c.checker.Check(region); op != nil {
-		if c.addOperator(op) {
+		if c.addOperator(op, operatorSourceChecker) {
+			res, _ := op.Do(region)
+			return res
+		}
+	}
+	if op := c.nsChecker.Check(region); op != nil {
+		if c.addOperator(op, operatorSourceChecker) {
+			res, _ := op.Do(region)
+			return res
+		}
+	}
+	if op := c.engChecker.Check(region); op != nil {
+		if c.addOperator(op, operatorSourceChecker) {
 			res, _ := op.Do(region)
 			return res
 		}
@@ -93,13 +140,35 @@ func (c *coordinator) dispatch(region *regionInfo) *pdpb.RegionHeartbeatResponse
 }
 
 func (c *coordinator) run() {
+	if c.cluster.kv != nil {
+		c.wg.Add(1)
+		go c.saveOperatorRecords()
+	}
 	c.addScheduler(newBalanceLeaderScheduler(c.opt))
 	c.addScheduler(newBalanceStorageScheduler(c.opt))
 }
 
+// saveOperatorRecords saves the records of the finished operators to etcd,
+// it is done in background to not block the heartbeats.
+func (c *coordinator) saveOperatorRecords() {
+	defer c.wg.Done()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case record := <-c.records:
+			if err := c.cluster.kv.saveOperatorRecord(record); err != nil {
+				log.Errorf("save operator record of region %d error: %v", record.RegionID, err)
+			}
+		}
+	}
+}
+
 func (c *coordinator) stop() {
 	c.cancel()
 	c.wg.Wait()
+	c.opLog.Finish()
 }
 
 func (c *coordinator) getSchedulers() []string {
@@ -146,6 +215,35 @@ func (c *coordinator) removeScheduler(name string) error {
 	return nil
 }
 
+func (c *coordinator) getScheduler(name string) (*scheduleController, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	s, ok := c.schedulers[name]
+	if !ok {
+		return nil, errSchedulerNotFound
+	}
+	return s, nil
+}
+
+func (c *coordinator) pauseScheduler(name string) error {
+	s, err := c.getScheduler(name)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	s.Pause()
+	return nil
+}
+
+func (c *coordinator) resumeScheduler(name string) error {
+	s, err := c.getScheduler(name)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	s.Resume()
+	return nil
+}
+
 func (c *coordinator) runScheduler(s *scheduleController) {
 	defer c.wg.Done()
 	defer s.Cleanup(c.cluster)
@@ -157,18 +255,38 @@ func (c *coordinator) runScheduler(s *scheduleController) {
 		select {
 		case <-timer.C:
 			timer.Reset(s.GetInterval())
+			if s.IsPaused() {
+				recordSchedule(s.GetName(), schedulePaused)
+				continue
+			}
 			if !s.AllowSchedule() {
+				recordSchedule(s.GetName(), scheduleLimitExceeded)
 				continue
 			}
+			tr := newTrace(traceFamilySchedule, s.GetName())
 			for i := 0; i < maxScheduleRetries; i++ {
 				op := s.Schedule(c.cluster)
 				if op == nil {
 					continue
 				}
-				if c.addOperator(op) {
+				if c.isSchedulerDisabled(s.GetName(), op) {
+					recordSchedule(s.GetName(), scheduleNamespaceDisabled)
+					continue
+				}
+				if !c.allowNamespaceSchedule(s, op) {
+					recordSchedule(s.GetName(), scheduleNamespaceLimitExceeded)
+					continue
+				}
+				if c.addOperator(op, operatorSourceScheduler) {
+					recordSchedule(s.GetName(), scheduleOperatorCreated)
+					// The operator changes later, format it now.
+					tr.LazyPrintf("add operator %s", fmt.Sprint(op))
 					break
 				}
+				recordSchedule(s.GetName(), scheduleOperatorExists)
+				tr.LazyPrintf("region %d has an operator already", op.GetRegionID())
 			}
+			tr.Finish()
 		case <-s.Ctx().Done():
 			log.Infof("%v stopped: %v", s.GetName(), s.Ctx().Err())
 			return
@@ -176,7 +294,31 @@ func (c *coordinator) runScheduler(s *scheduleController) {
 	}
 }
 
-func (c *coordinator) addOperator(op Operator) bool {
+// isSchedulerDisabled returns true if the scheduler is disabled in the
+// namespace of the operator's region.
+func (c *coordinator) isSchedulerDisabled(name string, op Operator) bool {
+	regionOp, ok := op.(*regionOperator)
+	if !ok {
+		return false
+	}
+	namespace := c.opt.GetClassifier().GetRegionNamespace(regionOp.Region.Region)
+	return c.opt.IsSchedulerDisabled(namespace, name)
+}
+
+// allowNamespaceSchedule returns true if the operators of the scheduler in
+// the namespace of the operator's region do not exceed the namespace limit.
+func (c *coordinator) allowNamespaceSchedule(s *scheduleController, op Operator) bool {
+	regionOp, ok := op.(*regionOperator)
+	if !ok {
+		return true
+	}
+	namespace := c.opt.GetClassifier().GetRegionNamespace(regionOp.Region.Region)
+	return s.AllowNamespaceSchedule(namespace)
+}
+
+// addOperator adds the operator generated by the source, returns false if
+// the region has an operator already.
+func (c *coordinator) addOperator(op Operator, source string) bool {
 	c.Lock()
 	defer c.Unlock()
 
@@ -185,21 +327,107 @@ func (c *coordinator) addOperator(op Operator) bool {
 		return false
 	}
 
-	c.limiter.addOperator(op)
+	op.(*regionOperator).Source = source
+	// The timeouts of a replayed operator are recorded already.
+	if regionOp := op.(*regionOperator); len(regionOp.StepTimeouts) == 0 {
+		regionOp.StepTimeouts = c.stepTimeouts(regionOp)
+	}
+	c.limiter.addOperator(op, c.opt.GetClassifier().GetRegionNamespace(op.(*regionOperator).Region.Region))
 	c.operators[regionID] = op
 	collectOperatorCounterMetrics(op)
+	c.opLog.Printf("add operator %v from %s", op, source)
+	// The operators of the checker are added by the heartbeats again when
+	// replaying.
+	if source != operatorSourceChecker {
+		c.captures.recordOperator(op.(*regionOperator), false)
+	}
 	return true
 }
 
-func (c *coordinator) removeOperator(op Operator) {
+// stepTimeouts returns the timeouts of the steps of the operator. Adding a
+// peer sends a snapshot of the region, so its timeout is scaled by the
+// region size, which is estimated by the average region size of the leader
+// store because the heartbeats don't report it.
+func (c *coordinator) stepTimeouts(op *regionOperator) []typeutil.Duration {
+	timeouts := make([]typeutil.Duration, len(op.Ops))
+	for i, o := range op.Ops {
+		switch o := o.(type) {
+		case *transferLeaderOperator:
+			timeouts[i] = typeutil.NewDuration(c.opt.GetTransferLeaderTimeout())
+		case *changePeerOperator:
+			if o.ChangePeer.GetChangeType() == raftpb.ConfChangeType_AddNode {
+				timeouts[i] = typeutil.NewDuration(time.Duration(float64(c.opt.GetAddPeerTimeout()) * c.regionSizeScale(op.Region)))
+			} else {
+				timeouts[i] = typeutil.NewDuration(c.opt.GetRemovePeerTimeout())
+			}
+		}
+	}
+	return timeouts
+}
+
+// regionSizeScale returns the estimated size of the region divided by
+// baseRegionSize, in [1, maxAddPeerTimeoutScale].
+func (c *coordinator) regionSizeScale(region *regionInfo) float64 {
+	store := c.cluster.getStore(region.Leader.GetStoreId())
+	if store == nil || store.stats.GetRegionCount() == 0 {
+		return 1
+	}
+	scale := float64(store.stats.GetUsedSize()) / float64(store.stats.GetRegionCount()) / baseRegionSize
+	if scale < 1 {
+		return 1
+	}
+	if scale > maxAddPeerTimeoutScale {
+		return maxAddPeerTimeoutScale
+	}
+	return scale
+}
+
+// removeOperator removes the operator which is finished with the status,
+// returns false if it is not the running operator of the region, e.g. it is
+// canceled and finished at the same time.
+func (c *coordinator) removeOperator(op Operator, status string) bool {
 	c.Lock()
 	defer c.Unlock()
 
 	regionID := op.GetRegionID()
+	if c.operators[regionID] != op {
+		return false
+	}
 	c.limiter.removeOperator(op)
 	delete(c.operators, regionID)
+	collectOperatorFinishMetrics(op, status)
+	c.opLog.Printf("remove operator %v, %s", op, status)
 
 	c.histories.add(regionID, op)
+	c.addOperatorRecord(op, status)
+	if status == operatorStatusCancel {
+		c.captures.recordOperator(op.(*regionOperator), true)
+	}
+	if status == operatorStatusTimeout {
+		c.bus.publish(&ClusterEvent{
+			Type:     ClusterEventOperatorTimeout,
+			RegionID: regionID,
+			Message:  fmt.Sprintf("operator %s of region %d is timeout", op.(*regionOperator).typeName(), regionID),
+		})
+	}
+	return true
+}
+
+func (c *coordinator) addOperatorRecord(op Operator, status string) {
+	regionOp, ok := op.(*regionOperator)
+	if !ok || c.cluster.kv == nil {
+		return
+	}
+	record, err := newOperatorRecord(regionOp, status)
+	if err != nil {
+		log.Errorf("create operator record for %v error: %v", op, err)
+		return
+	}
+	select {
+	case c.records <- record:
+	default:
+		log.Warnf("too many operator records to save, drop the record of region %d", record.RegionID)
+	}
 }
 
 func (c *coordinator) getOperator(regionID uint64) Operator {
@@ -234,25 +462,42 @@ func (c *coordinator) getHistories() []Operator {
 
 type scheduleLimiter struct {
 	sync.RWMutex
-	counts map[ResourceKind]uint64
+	counts          map[ResourceKind]uint64
+	namespaceCounts map[string]map[ResourceKind]uint64
+	// namespaces are the namespaces which the operators are counted in by
+	// region ID, as the namespace of a region changes with the namespaces.
+	namespaces map[uint64]string
 }
 
 func newScheduleLimiter() *scheduleLimiter {
 	return &scheduleLimiter{
-		counts: make(map[ResourceKind]uint64),
+		counts:          make(map[ResourceKind]uint64),
+		namespaceCounts: make(map[string]map[ResourceKind]uint64),
+		namespaces:      make(map[uint64]string),
 	}
 }
 
-func (l *scheduleLimiter) addOperator(op Operator) {
+func (l *scheduleLimiter) addOperator(op Operator, namespace string) {
 	l.Lock()
 	defer l.Unlock()
 	l.counts[op.GetResourceKind()]++
+	counts, ok := l.namespaceCounts[namespace]
+	if !ok {
+		counts = make(map[ResourceKind]uint64)
+		l.namespaceCounts[namespace] = counts
+	}
+	counts[op.GetResourceKind()]++
+	l.namespaces[op.GetRegionID()] = namespace
 }
 
 func (l *scheduleLimiter) removeOperator(op Operator) {
 	l.Lock()
 	defer l.Unlock()
 	l.counts[op.GetResourceKind()]--
+	if namespace, ok := l.namespaces[op.GetRegionID()]; ok {
+		l.namespaceCounts[namespace][op.GetResourceKind()]--
+		delete(l.namespaces, op.GetRegionID())
+	}
 }
 
 func (l *scheduleLimiter) operatorCount(kind ResourceKind) uint64 {
@@ -261,12 +506,20 @@ func (l *scheduleLimiter) operatorCount(kind ResourceKind) uint64 {
 	return l.counts[kind]
 }
 
+func (l *scheduleLimiter) namespaceOperatorCount(namespace string, kind ResourceKind) uint64 {
+	l.RLock()
+	defer l.RUnlock()
+	return l.namespaceCounts[namespace][kind]
+}
+
 type scheduleController struct {
 	Scheduler
-	opt     *scheduleOption
-	limiter *scheduleLimiter
-	ctx     context.Context
-	cancel  context.CancelFunc
+	opt      *scheduleOption
+	limiter  *scheduleLimiter
+	throttle *scheduleThrottle
+	ctx      context.Context
+	cancel   context.CancelFunc
+	paused   int32
 }
 
 func newScheduleController(c *coordinator, s Scheduler) *scheduleController {
@@ -275,6 +528,7 @@ func newScheduleController(c *coordinator, s Scheduler) *scheduleController {
 		Scheduler: s,
 		opt:       c.opt,
 		limiter:   c.limiter,
+		throttle:  c.throttle,
 		ctx:       ctx,
 		cancel:    cancel,
 	}
@@ -288,9 +542,25 @@ func (s *scheduleController) Stop() {
 	s.cancel()
 }
 
+// Pause stops generating operators until Resume is called, the scheduler
+// keeps its state, e.g. the blocked store of grant-leader-scheduler.
+func (s *scheduleController) Pause() {
+	atomic.StoreInt32(&s.paused, 1)
+}
+
+func (s *scheduleController) Resume() {
+	atomic.StoreInt32(&s.paused, 0)
+}
+
+func (s *scheduleController) IsPaused() bool {
+	return atomic.LoadInt32(&s.paused) == 1
+}
+
+// GetInterval returns the schedule interval divided by the resource limit,
+// and enlarged by the throttle factor if the leader is overloaded.
 func (s *scheduleController) GetInterval() time.Duration {
 	limit := s.GetResourceLimit()
-	interval := s.opt.GetScheduleInterval()
+	interval := s.opt.GetScheduleInterval() * time.Duration(s.throttle.getFactor())
 	if limit == 0 {
 		return interval
 	}
@@ -301,6 +571,16 @@ func (s *scheduleController) AllowSchedule() bool {
 	return s.limiter.operatorCount(s.GetResourceKind()) < s.GetResourceLimit()
 }
 
+// AllowNamespaceSchedule returns true if the operators of the resource kind
+// in the namespace are fewer than the schedule limit of the namespace.
+func (s *scheduleController) AllowNamespaceSchedule(namespace string) bool {
+	limit := s.opt.GetNamespaceRegionScheduleLimit(namespace)
+	if s.GetResourceKind() == leaderKind {
+		limit = s.opt.GetNamespaceLeaderScheduleLimit(namespace)
+	}
+	return s.limiter.namespaceOperatorCount(namespace, s.GetResourceKind()) < limit
+}
+
 func collectOperatorCounterMetrics(op Operator) {
 	metrics := make(map[string]uint64)
 	for _, op := range op.(*regionOperator).Ops {
@@ -315,4 +595,14 @@ func collectOperatorCounterMetrics(op Operator) {
 	for label, value := range metrics {
 		operatorCounter.WithLabelValues(label).Add(float64(value))
 	}
+
+	o := op.(*regionOperator)
+	operatorCreatedCounter.WithLabelValues(o.typeName(), o.Source).Inc()
+}
+
+func collectOperatorFinishMetrics(op Operator, status string) {
+	o := op.(*regionOperator)
+	typeName := o.typeName()
+	operatorFinishedCounter.WithLabelValues(typeName, o.Source, status).Inc()
+	operatorDuration.WithLabelValues(typeName, status).Observe(time.Since(o.Start).Seconds())
 }
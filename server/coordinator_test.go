@@ -14,6 +14,9 @@
 package server
 
 import (
+	"bytes"
+	"encoding/gob"
+	"testing"
 	"time"
 
 	"github.com/gogo/protobuf/proto"
@@ -21,6 +24,7 @@ import (
 	raftpb "github.com/pingcap/kvproto/pkg/eraftpb"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/pkg/typeutil"
 )
 
 type testOperator struct {
@@ -45,30 +49,112 @@ var _ = Suite(&testCoordinatorSuite{})
 type testCoordinatorSuite struct{}
 
 func (s *testCoordinatorSuite) TestBasic(c *C) {
-	cluster := newClusterInfo(newMockIDAllocator())
+	cluster := newClusterInfo(NewMockIDAllocator())
 	_, opt := newTestScheduleConfig()
 	co := newCoordinator(cluster, opt)
 	l := co.limiter
 
 	op1 := newTestOperator(1, leaderKind)
-	co.addOperator(op1)
+	co.addOperator(op1, operatorSourceManual)
 	c.Assert(l.operatorCount(op1.GetResourceKind()), Equals, uint64(1))
 	c.Assert(co.getOperator(1).GetRegionID(), Equals, op1.GetRegionID())
+	c.Assert(op1.(*regionOperator).Source, Equals, operatorSourceManual)
 
 	// Region 1 already has an operator, cannot add another one.
 	op2 := newTestOperator(1, regionKind)
-	co.addOperator(op2)
+	co.addOperator(op2, operatorSourceManual)
 	c.Assert(l.operatorCount(op2.GetResourceKind()), Equals, uint64(0))
 
 	// Remove the operator manually, then we can add a new operator.
-	co.removeOperator(op1)
-	co.addOperator(op2)
+	c.Assert(co.removeOperator(op1, operatorStatusCancel), IsTrue)
+	co.addOperator(op2, operatorSourceManual)
 	c.Assert(l.operatorCount(op2.GetResourceKind()), Equals, uint64(1))
 	c.Assert(co.getOperator(1).GetRegionID(), Equals, op2.GetRegionID())
+
+	// The removed operator is not removed again, e.g. when it is canceled
+	// and finished at the same time, nor does it remove the new one.
+	c.Assert(co.removeOperator(op1, operatorStatusCancel), IsFalse)
+	c.Assert(l.operatorCount(op1.GetResourceKind()), Equals, uint64(0))
+	c.Assert(l.operatorCount(op2.GetResourceKind()), Equals, uint64(1))
+	c.Assert(co.getOperator(1), Equals, op2)
+
+	// The operator times out.
+	op := op2.(*regionOperator)
+	op.StepStart = time.Now().Add(-maxOperatorWaitTime - time.Second)
+	_, finished := op.Do(op.Region)
+	c.Assert(finished, IsTrue)
+	c.Assert(op.finishStatus(), Equals, operatorStatusTimeout)
+}
+
+func (s *testCoordinatorSuite) TestStepTimeout(c *C) {
+	cluster := newClusterInfo(NewMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+	cfg, opt := newTestScheduleConfig()
+	co := newCoordinator(cluster, opt)
+
+	tc.AddLeaderStore(1, 1, 1)
+	tc.AddLeaderStore(2, 0, 0)
+	tc.AddLeaderStore(3, 0, 0)
+	tc.AddLeaderRegion(1, 1, 2)
+	region := cluster.getRegion(1)
+	newPeer, _ := cluster.allocPeer(3)
+	op := newRegionOperator(region, newAddPeerOperator(1, newPeer), newRemovePeerOperator(1, region.GetStorePeer(2)))
+	c.Assert(co.addOperator(op, operatorSourceManual), IsTrue)
+	c.Assert(op.StepTimeouts, DeepEquals, []typeutil.Duration{cfg.AddPeerTimeout, cfg.RemovePeerTimeout})
+
+	// The add peer timeout is scaled by the region size of the leader store.
+	co.removeOperator(op, operatorStatusCancel)
+	store := cluster.getStore(1)
+	store.stats.Capacity = 1000 * baseRegionSize
+	store.stats.Available = 900 * baseRegionSize
+	store.stats.RegionCount = 50
+	cluster.putStore(store)
+	op.StepTimeouts = nil
+	c.Assert(co.addOperator(op, operatorSourceManual), IsTrue)
+	c.Assert(op.StepTimeouts[0].Duration, Equals, 2*cfg.AddPeerTimeout.Duration)
+	store.stats.RegionCount = 1
+	cluster.putStore(store)
+	c.Assert(co.stepTimeouts(op)[0].Duration, Equals, maxAddPeerTimeoutScale*cfg.AddPeerTimeout.Duration)
+
+	// The add peer step is still in time.
+	op.StepStart = time.Now().Add(-cfg.AddPeerTimeout.Duration - time.Second)
+	resp, finished := op.Do(region)
+	c.Assert(finished, IsFalse)
+	c.Assert(resp.GetChangePeer(), NotNil)
+
+	// The peer is added, the remove peer step starts.
+	region = region.clone()
+	region.Peers = append(region.Peers, newPeer)
+	resp, finished = op.Do(region)
+	c.Assert(finished, IsFalse)
+	c.Assert(resp.GetChangePeer().GetPeer().GetStoreId(), Equals, uint64(2))
+	c.Assert(time.Since(op.StepStart), Less, time.Second)
+
+	// The remove peer step times out.
+	op.StepStart = time.Now().Add(-cfg.RemovePeerTimeout.Duration - time.Second)
+	_, finished = op.Do(region)
+	c.Assert(finished, IsTrue)
+	c.Assert(op.finishStatus(), Equals, operatorStatusTimeout)
+	co.removeOperator(op, operatorStatusTimeout)
+
+	op = newRegionOperator(region, newTransferLeaderOperator(1, region.Leader, region.GetStorePeer(3)))
+	c.Assert(co.addOperator(op, operatorSourceManual), IsTrue)
+	c.Assert(op.StepTimeouts, DeepEquals, []typeutil.Duration{cfg.TransferLeaderTimeout})
+
+	// The timeouts are kept with the operator, e.g. in a capture, and not
+	// computed again when it is replayed.
+	co.removeOperator(op, operatorStatusCancel)
+	op.StepTimeouts[0] = typeutil.NewDuration(time.Hour)
+	var buf bytes.Buffer
+	c.Assert(gob.NewEncoder(&buf).Encode(op), IsNil)
+	replayed := &regionOperator{}
+	c.Assert(gob.NewDecoder(&buf).Decode(replayed), IsNil)
+	c.Assert(co.addOperator(replayed, operatorSourceManual), IsTrue)
+	c.Assert(replayed.stepTimeout(), Equals, time.Hour)
 }
 
 func (s *testCoordinatorSuite) TestDispatch(c *C) {
-	cluster := newClusterInfo(newMockIDAllocator())
+	cluster := newClusterInfo(NewMockIDAllocator())
 	tc := newTestClusterInfo(cluster)
 
 	_, opt := newTestScheduleConfig()
@@ -77,18 +163,18 @@ func (s *testCoordinatorSuite) TestDispatch(c *C) {
 	defer co.stop()
 
 	// Transfer peer from store 4 to store 1.
-	tc.addRegionStore(4, 4, 0.4)
-	tc.addRegionStore(3, 3, 0.3)
-	tc.addRegionStore(2, 2, 0.2)
-	tc.addRegionStore(1, 1, 0.1)
-	tc.addLeaderRegion(1, 2, 3, 4)
+	tc.AddRegionStore(4, 4, 0.4)
+	tc.AddRegionStore(3, 3, 0.3)
+	tc.AddRegionStore(2, 2, 0.2)
+	tc.AddRegionStore(1, 1, 0.1)
+	tc.AddLeaderRegion(1, 2, 3, 4)
 
 	// Transfer leader from store 4 to store 2.
-	tc.updateLeaderCount(4, 4, 10)
-	tc.updateLeaderCount(3, 3, 10)
-	tc.updateLeaderCount(2, 2, 10)
-	tc.updateLeaderCount(1, 1, 10)
-	tc.addLeaderRegion(2, 4, 3, 2)
+	tc.UpdateLeaderCount(4, 4, 10)
+	tc.UpdateLeaderCount(3, 3, 10)
+	tc.UpdateLeaderCount(2, 2, 10)
+	tc.UpdateLeaderCount(1, 1, 10)
+	tc.AddLeaderRegion(2, 4, 3, 2)
 
 	// Wait for schedule and turn off balance.
 	time.Sleep(time.Second)
@@ -96,6 +182,9 @@ func (s *testCoordinatorSuite) TestDispatch(c *C) {
 	c.Assert(co.removeScheduler("balance-storage-scheduler"), IsNil)
 	checkTransferPeer(c, co.getOperator(1), 4, 1)
 	checkTransferLeader(c, co.getOperator(2), 4, 2)
+	op := co.getOperator(1).(*regionOperator)
+	c.Assert(op.Source, Equals, operatorSourceScheduler)
+	c.Assert(op.typeName(), Equals, "add_peer,remove_peer")
 
 	// Transfer peer.
 	region := cluster.getRegion(1)
@@ -108,6 +197,7 @@ func (s *testCoordinatorSuite) TestDispatch(c *C) {
 	region.RemoveStorePeer(4)
 	cluster.putRegion(region)
 	c.Assert(co.dispatch(region), IsNil)
+	c.Assert(op.finishStatus(), Equals, operatorStatusSuccess)
 	c.Assert(co.getOperator(region.GetId()), IsNil)
 
 	// Transfer leader.
@@ -120,7 +210,7 @@ func (s *testCoordinatorSuite) TestDispatch(c *C) {
 }
 
 func (s *testCoordinatorSuite) TestReplica(c *C) {
-	cluster := newClusterInfo(newMockIDAllocator())
+	cluster := newClusterInfo(NewMockIDAllocator())
 	tc := newTestClusterInfo(cluster)
 
 	// Turn off balance.
@@ -132,13 +222,13 @@ func (s *testCoordinatorSuite) TestReplica(c *C) {
 	co.run()
 	defer co.stop()
 
-	tc.addRegionStore(1, 1, 0.1)
-	tc.addRegionStore(2, 2, 0.2)
-	tc.addRegionStore(3, 3, 0.3)
-	tc.addRegionStore(4, 4, 0.4)
+	tc.AddRegionStore(1, 1, 0.1)
+	tc.AddRegionStore(2, 2, 0.2)
+	tc.AddRegionStore(3, 3, 0.3)
+	tc.AddRegionStore(4, 4, 0.4)
 
 	// Add peer to store 1.
-	tc.addLeaderRegion(1, 2, 3)
+	tc.AddLeaderRegion(1, 2, 3)
 	region := cluster.getRegion(1)
 	resp := co.dispatch(region)
 	checkAddPeerResp(c, resp, 1)
@@ -146,7 +236,7 @@ func (s *testCoordinatorSuite) TestReplica(c *C) {
 	c.Assert(co.dispatch(region), IsNil)
 
 	// Peer in store 3 is down, remove peer in store 3 and add peer to store 4.
-	tc.setStoreDown(3)
+	tc.SetStoreDown(3)
 	downPeer := &pdpb.PeerStats{
 		Peer:        region.GetStorePeer(3),
 		DownSeconds: proto.Uint64(24 * 60 * 60),
@@ -162,7 +252,7 @@ func (s *testCoordinatorSuite) TestReplica(c *C) {
 	c.Assert(co.dispatch(region), IsNil)
 
 	// Remove peer from store 4.
-	tc.addLeaderRegion(2, 1, 2, 3, 4)
+	tc.AddLeaderRegion(2, 1, 2, 3, 4)
 	region = cluster.getRegion(2)
 	resp = co.dispatch(region)
 	checkRemovePeerResp(c, resp, 4)
@@ -171,7 +261,7 @@ func (s *testCoordinatorSuite) TestReplica(c *C) {
 }
 
 func (s *testCoordinatorSuite) TestPeerState(c *C) {
-	cluster := newClusterInfo(newMockIDAllocator())
+	cluster := newClusterInfo(NewMockIDAllocator())
 	tc := newTestClusterInfo(cluster)
 
 	_, opt := newTestScheduleConfig()
@@ -180,11 +270,11 @@ func (s *testCoordinatorSuite) TestPeerState(c *C) {
 	defer co.stop()
 
 	// Transfer peer from store 4 to store 1.
-	tc.addRegionStore(4, 4, 0.4)
-	tc.addRegionStore(3, 3, 0.3)
-	tc.addRegionStore(2, 2, 0.2)
-	tc.addRegionStore(1, 1, 0.1)
-	tc.addLeaderRegion(1, 2, 3, 4)
+	tc.AddRegionStore(4, 4, 0.4)
+	tc.AddRegionStore(3, 3, 0.3)
+	tc.AddRegionStore(2, 2, 0.2)
+	tc.AddRegionStore(1, 1, 0.1)
+	tc.AddLeaderRegion(1, 2, 3, 4)
 
 	// Wait for schedule.
 	time.Sleep(time.Second)
@@ -208,13 +298,13 @@ func (s *testCoordinatorSuite) TestPeerState(c *C) {
 	region.PendingPeers = nil
 	resp = co.dispatch(region)
 	checkRemovePeerResp(c, resp, 4)
-	tc.addLeaderRegion(1, 1, 2, 3)
+	tc.AddLeaderRegion(1, 1, 2, 3)
 	region = cluster.getRegion(1)
 	c.Assert(co.dispatch(region), IsNil)
 }
 
 func (s *testCoordinatorSuite) TestAddScheduler(c *C) {
-	cluster := newClusterInfo(newMockIDAllocator())
+	cluster := newClusterInfo(NewMockIDAllocator())
 	tc := newTestClusterInfo(cluster)
 
 	cfg, opt := newTestScheduleConfig()
@@ -230,15 +320,15 @@ func (s *testCoordinatorSuite) TestAddScheduler(c *C) {
 	c.Assert(co.schedulers, HasLen, 0)
 
 	// Add stores 1,2,3
-	tc.addLeaderStore(1, 1, 1)
-	tc.addLeaderStore(2, 1, 1)
-	tc.addLeaderStore(3, 1, 1)
+	tc.AddLeaderStore(1, 1, 1)
+	tc.AddLeaderStore(2, 1, 1)
+	tc.AddLeaderStore(3, 1, 1)
 	// Add regions 1 with leader in store 1 and followers in stores 2,3
-	tc.addLeaderRegion(1, 1, 2, 3)
+	tc.AddLeaderRegion(1, 1, 2, 3)
 	// Add regions 2 with leader in store 2 and followers in stores 1,3
-	tc.addLeaderRegion(2, 2, 1, 3)
+	tc.AddLeaderRegion(2, 2, 1, 3)
 	// Add regions 3 with leader in store 3 and followers in stores 1,2
-	tc.addLeaderRegion(3, 3, 1, 2)
+	tc.AddLeaderRegion(3, 3, 1, 2)
 
 	gls := newGrantLeaderScheduler(opt, 0)
 	c.Assert(co.addScheduler(gls), NotNil)
@@ -263,6 +353,36 @@ func (s *testCoordinatorSuite) TestAddScheduler(c *C) {
 	c.Assert(co.dispatch(region3), IsNil)
 }
 
+func (s *testCoordinatorSuite) TestPauseScheduler(c *C) {
+	cluster := newClusterInfo(NewMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	cfg, opt := newTestScheduleConfig()
+	cfg.ReplicaScheduleLimit = 0
+
+	co := newCoordinator(cluster, opt)
+	co.run()
+	defer co.stop()
+
+	c.Assert(co.pauseScheduler("balance-leader-scheduler"), IsNil)
+	c.Assert(co.removeScheduler("balance-storage-scheduler"), IsNil)
+	c.Assert(co.pauseScheduler("balance-storage-scheduler"), NotNil)
+	c.Assert(co.resumeScheduler("balance-storage-scheduler"), NotNil)
+
+	// Leader of region 1 should be transferred from store 1 to store 2.
+	tc.AddLeaderStore(1, 10, 10)
+	tc.AddLeaderStore(2, 0, 10)
+	tc.AddLeaderRegion(1, 1, 2)
+
+	// Paused scheduler doesn't generate operators.
+	time.Sleep(100 * time.Millisecond)
+	c.Assert(co.getOperator(1), IsNil)
+
+	c.Assert(co.resumeScheduler("balance-leader-scheduler"), IsNil)
+	time.Sleep(100 * time.Millisecond)
+	checkTransferLeader(c, co.getOperator(1), 1, 2)
+}
+
 var _ = Suite(&testScheduleLimiterSuite{})
 
 type testScheduleLimiterSuite struct{}
@@ -273,20 +393,31 @@ func (s *testScheduleLimiterSuite) TestOperatorCount(c *C) {
 	c.Assert(l.operatorCount(regionKind), Equals, uint64(0))
 
 	leaderOP := newTestOperator(1, leaderKind)
-	l.addOperator(leaderOP)
+	l.addOperator(leaderOP, defaultNamespace)
 	c.Assert(l.operatorCount(leaderKind), Equals, uint64(1))
-	l.addOperator(leaderOP)
+	l.addOperator(leaderOP, defaultNamespace)
 	c.Assert(l.operatorCount(leaderKind), Equals, uint64(2))
 	l.removeOperator(leaderOP)
 	c.Assert(l.operatorCount(leaderKind), Equals, uint64(1))
 
 	regionOP := newTestOperator(1, regionKind)
-	l.addOperator(regionOP)
+	l.addOperator(regionOP, defaultNamespace)
 	c.Assert(l.operatorCount(regionKind), Equals, uint64(1))
-	l.addOperator(regionOP)
+	l.addOperator(regionOP, defaultNamespace)
 	c.Assert(l.operatorCount(regionKind), Equals, uint64(2))
 	l.removeOperator(regionOP)
 	c.Assert(l.operatorCount(regionKind), Equals, uint64(1))
+
+	// The operators are counted by namespace too.
+	c.Assert(l.namespaceOperatorCount(defaultNamespace, regionKind), Equals, uint64(1))
+	nsOP := newTestOperator(2, regionKind)
+	l.addOperator(nsOP, "ns1")
+	c.Assert(l.operatorCount(regionKind), Equals, uint64(2))
+	c.Assert(l.namespaceOperatorCount("ns1", regionKind), Equals, uint64(1))
+	c.Assert(l.namespaceOperatorCount("ns1", leaderKind), Equals, uint64(0))
+	l.removeOperator(nsOP)
+	c.Assert(l.namespaceOperatorCount("ns1", regionKind), Equals, uint64(0))
+	c.Assert(l.namespaceOperatorCount(defaultNamespace, regionKind), Equals, uint64(1))
 }
 
 var _ = Suite(&testScheduleControllerSuite{})
@@ -294,7 +425,7 @@ var _ = Suite(&testScheduleControllerSuite{})
 type testScheduleControllerSuite struct{}
 
 func (s *testScheduleControllerSuite) TestController(c *C) {
-	cluster := newClusterInfo(newMockIDAllocator())
+	cluster := newClusterInfo(NewMockIDAllocator())
 	cfg, opt := newTestScheduleConfig()
 	cfg.ScheduleInterval.Duration = time.Minute
 	co := newCoordinator(cluster, opt)
@@ -310,10 +441,10 @@ func (s *testScheduleControllerSuite) TestController(c *C) {
 	op := newTestOperator(1, leaderKind)
 	// count = 0
 	c.Assert(sc.AllowSchedule(), IsTrue)
-	sc.limiter.addOperator(op)
+	sc.limiter.addOperator(op, defaultNamespace)
 	// count = 1
 	c.Assert(sc.AllowSchedule(), IsTrue)
-	sc.limiter.addOperator(op)
+	sc.limiter.addOperator(op, defaultNamespace)
 	// count = 2
 	c.Assert(sc.AllowSchedule(), IsFalse)
 	sc.limiter.removeOperator(op)
@@ -341,3 +472,55 @@ func checkTransferPeerResp(c *C, resp *pdpb.RegionHeartbeatResponse, sourceID, t
 	checkAddPeerResp(c, resp, targetID)
 	checkRemovePeerResp(c, resp, sourceID)
 }
+
+// newBenchmarkCoordinator returns a coordinator of 3 stores and the regions
+// with 3 peers, so the replica checker finds nothing to do.
+func newBenchmarkCoordinator(regionCount int) (*MockCluster, *coordinator) {
+	cluster := newClusterInfo(NewMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+	_, opt := newTestScheduleConfig()
+	co := newCoordinator(cluster, opt)
+
+	for i := uint64(1); i <= 3; i++ {
+		tc.AddLeaderStore(i, regionCount/3, regionCount)
+	}
+	for i := 0; i < regionCount; i++ {
+		leader := uint64(i%3 + 1)
+		tc.AddLeaderRegion(uint64(i+1), leader, leader%3+1, (leader+1)%3+1)
+	}
+	return tc, co
+}
+
+func BenchmarkRegionHeartbeat(b *testing.B) {
+	tc, co := newBenchmarkCoordinator(1000)
+	regions := tc.getRegions()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		region := regions[i%len(regions)]
+		if err := tc.handleRegionHeartbeat(region); err != nil {
+			b.Fatal(err)
+		}
+		co.dispatch(region)
+	}
+}
+
+func BenchmarkStoreHeartbeat(b *testing.B) {
+	tc, _ := newBenchmarkCoordinator(1000)
+	var stats []*pdpb.StoreStats
+	for _, store := range tc.getStores() {
+		stats = append(stats, &pdpb.StoreStats{
+			StoreId:     store.GetId(),
+			Capacity:    100,
+			Available:   50,
+			RegionCount: uint32(store.stats.TotalRegionCount),
+		})
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := tc.handleStoreHeartbeat(stats[i%len(stats)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// processCPUTime is not supported on windows, the schedule throttle only
+// checks the heartbeats then.
+func processCPUTime() (time.Duration, error) {
+	return 0, errors.New("process cpu time is not supported on windows")
+}
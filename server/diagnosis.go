@@ -0,0 +1,136 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Results of a schedule, all except scheduleOperatorCreated are the reasons
+// why no operator is created.
+const (
+	scheduleOperatorCreated        = "operator_created"
+	scheduleOperatorExists         = "operator_exists"
+	schedulePaused                 = "paused"
+	scheduleLimitExceeded          = "limit_exceeded"
+	scheduleNoRegion               = "no_region"
+	scheduleNoSource               = "no_source"
+	scheduleNoTarget               = "no_target"
+	scheduleSmallDiff              = "small_diff"
+	scheduleAbnormalReplicas       = "abnormal_replicas"
+	scheduleOtherNamespace         = "other_namespace"
+	scheduleNamespaceDisabled      = "namespace_disabled"
+	scheduleNamespaceLimitExceeded = "namespace_limit_exceeded"
+)
+
+// scheduleDiagnoses counts the results of the schedules by scheduler name,
+// the counts are kept after the schedulers are removed, like the metrics.
+var scheduleDiagnoses = &scheduleDiagnosis{counts: make(map[string]map[string]uint64)}
+
+type scheduleDiagnosis struct {
+	sync.RWMutex
+	counts map[string]map[string]uint64
+}
+
+// recordSchedule records a result of the schedule by the scheduler.
+func recordSchedule(scheduler, result string) {
+	scheduleDiagnoses.Lock()
+	counts, ok := scheduleDiagnoses.counts[scheduler]
+	if !ok {
+		counts = make(map[string]uint64)
+		scheduleDiagnoses.counts[scheduler] = counts
+	}
+	counts[result]++
+	scheduleDiagnoses.Unlock()
+
+	scheduleResultCounter.WithLabelValues(scheduler, result).Inc()
+}
+
+// getScheduleDiagnosis returns the counts of the results of the scheduler.
+func getScheduleDiagnosis(scheduler string) map[string]uint64 {
+	scheduleDiagnoses.RLock()
+	defer scheduleDiagnoses.RUnlock()
+
+	counts := make(map[string]uint64)
+	for result, count := range scheduleDiagnoses.counts[scheduler] {
+		counts[result] = count
+	}
+	return counts
+}
+
+// diagnoseSelect returns why no source or target is selected from the stores,
+// e.g. "no_target_filtered_by_health" if the health filter rejects the most
+// stores.
+func diagnoseSelect(stores []*storeInfo, s Selector, filters []Filter, isSource bool) string {
+	result := scheduleNoTarget
+	if isSource {
+		result = scheduleNoSource
+	}
+	if len(stores) == 0 {
+		return result + "_no_store"
+	}
+
+	filters = append(filters, selectorFilters(s)...)
+	counts := make([]int, len(filters))
+	for _, store := range stores {
+		for i, f := range filters {
+			if (isSource && f.FilterSource(store)) || (!isSource && f.FilterTarget(store)) {
+				counts[i]++
+				break
+			}
+		}
+	}
+
+	best := -1
+	for i, count := range counts {
+		if count > 0 && (best < 0 || count > counts[best]) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return result
+	}
+	return result + "_filtered_by_" + filterName(filters[best])
+}
+
+func selectorFilters(s Selector) []Filter {
+	switch s := s.(type) {
+	case *balanceSelector:
+		return s.filters
+	case *randomSelector:
+		return s.filters
+	}
+	return nil
+}
+
+// filterName returns the name of the filter in snake case, e.g. "region_count"
+// for regionCountFilter.
+func filterName(f Filter) string {
+	name := fmt.Sprintf("%T", f)
+	name = name[strings.LastIndex(name, ".")+1:]
+	name = strings.TrimSuffix(name, "Filter")
+
+	var buf bytes.Buffer
+	for _, c := range name {
+		if c >= 'A' && c <= 'Z' {
+			buf.WriteByte('_')
+			c += 'a' - 'A'
+		}
+		buf.WriteRune(c)
+	}
+	return buf.String()
+}
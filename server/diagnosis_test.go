@@ -0,0 +1,75 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testDiagnosisSuite{})
+
+type testDiagnosisSuite struct{}
+
+func (s *testDiagnosisSuite) TestFilterName(c *C) {
+	c.Assert(filterName(newHealthFilter(nil)), Equals, "health")
+	c.Assert(filterName(newRegionCountFilter(nil)), Equals, "region_count")
+	c.Assert(filterName(newStorageThresholdFilter(nil)), Equals, "storage_threshold")
+}
+
+func (s *testDiagnosisSuite) TestBalanceLeaderDiagnosis(c *C) {
+	cluster := newClusterInfo(NewMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	cfg, opt := newTestScheduleConfig()
+	lb := newBalanceLeaderScheduler(opt)
+	name := lb.GetName()
+	cfg.MinLeaderCount = 10
+	cfg.MinBalanceDiffRatio = 0.1
+
+	// No stores.
+	before := getScheduleDiagnosis(name)
+	c.Assert(lb.Schedule(cluster), IsNil)
+	after := getScheduleDiagnosis(name)
+	c.Assert(after["no_source_no_store"]-before["no_source_no_store"], Equals, uint64(1))
+
+	tc.AddLeaderStore(1, 6, 30)
+	tc.AddLeaderStore(2, 7, 30)
+	tc.AddLeaderStore(3, 8, 30)
+	tc.AddLeaderRegion(1, 3, 1, 2)
+
+	// All the stores have less than 10 leaders.
+	reason := "no_source_filtered_by_leader_count"
+	before = getScheduleDiagnosis(name)
+	c.Assert(lb.Schedule(cluster), IsNil)
+	after = getScheduleDiagnosis(name)
+	c.Assert(after[reason]-before[reason], Equals, uint64(1))
+
+	// All the followers are down.
+	tc.UpdateLeaderCount(3, 12, 30)
+	tc.SetStoreDown(1)
+	tc.SetStoreDown(2)
+	reason = "no_target_filtered_by_health"
+	before = getScheduleDiagnosis(name)
+	c.Assert(lb.Schedule(cluster), IsNil)
+	after = getScheduleDiagnosis(name)
+	c.Assert(after[reason]-before[reason], Equals, uint64(1))
+
+	// The diff of leader ratio is too small.
+	tc.SetStoreUp(1)
+	tc.UpdateLeaderCount(1, 11, 30)
+	before = getScheduleDiagnosis(name)
+	c.Assert(lb.Schedule(cluster), IsNil)
+	after = getScheduleDiagnosis(name)
+	c.Assert(after[scheduleSmallDiff]-before[scheduleSmallDiff], Equals, uint64(1))
+}
@@ -0,0 +1,253 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+)
+
+const (
+	// EncryptionMethodAES256CTR is the method of the data keys, the stores
+	// encrypt the data in AES-256 in CTR mode.
+	EncryptionMethodAES256CTR = "aes256-ctr"
+
+	masterKeyLen = 32
+	dataKeyLen   = 32
+	// encryptionKeyCheckInterval is the interval the leader checks whether
+	// the current data key should be rotated.
+	encryptionKeyCheckInterval = time.Minute
+)
+
+var (
+	// ErrEncryptionKeyNotFound is returned when getting a data key which does
+	// not exist.
+	ErrEncryptionKeyNotFound = errors.New("encryption key is not found")
+
+	errEncryptionDisabled = errors.New("encryption is not enabled")
+)
+
+// EncryptionKey is a data key used by the stores to encrypt the data at rest.
+// The stores encrypt the new data by the current key, and the old keys are
+// kept to decrypt the data written before rotating.
+type EncryptionKey struct {
+	ID     uint64 `json:"id"`
+	Method string `json:"method"`
+	// Key is the plaintext of the key, it is omitted in the listings.
+	Key []byte `json:"key,omitempty"`
+	// CreatedAt is the unix time in seconds.
+	CreatedAt int64 `json:"created_at"`
+}
+
+// EncryptedKey is a data key saved in etcd, the key is encrypted by the
+// master key in AES-GCM, with the ID as the additional data.
+type EncryptedKey struct {
+	ID         uint64 `json:"id"`
+	Method     string `json:"method"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+	CreatedAt  int64  `json:"created_at"`
+}
+
+// loadMasterKey reads the master key in hex from the file.
+func loadMasterKey(name string) (cipher.AEAD, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, errors.Annotatef(err, "invalid master key in %s", name)
+	}
+	if len(key) != masterKeyLen {
+		return nil, errors.Errorf("master key in %s should be %d bytes, got %d", name, masterKeyLen, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	return aead, errors.Trace(err)
+}
+
+func (s *Server) encryptKey(key *EncryptionKey) (*EncryptedKey, error) {
+	nonce := make([]byte, s.masterKey.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &EncryptedKey{
+		ID:         key.ID,
+		Method:     key.Method,
+		Nonce:      nonce,
+		Ciphertext: s.masterKey.Seal(nil, nonce, key.Key, encryptionKeyAD(key.ID)),
+		CreatedAt:  key.CreatedAt,
+	}, nil
+}
+
+func (s *Server) decryptKey(ek *EncryptedKey) (*EncryptionKey, error) {
+	key, err := s.masterKey.Open(nil, ek.Nonce, ek.Ciphertext, encryptionKeyAD(ek.ID))
+	if err != nil {
+		return nil, errors.Annotatef(err, "decrypt encryption key %d", ek.ID)
+	}
+	return &EncryptionKey{
+		ID:        ek.ID,
+		Method:    ek.Method,
+		Key:       key,
+		CreatedAt: ek.CreatedAt,
+	}, nil
+}
+
+func encryptionKeyAD(id uint64) []byte {
+	return []byte(strconv.FormatUint(id, 10))
+}
+
+// GetEncryptionKeys returns the data keys ordered by ID, without the
+// plaintext of the keys.
+func (s *Server) GetEncryptionKeys() ([]*EncryptionKey, error) {
+	if s.masterKey == nil {
+		return nil, errors.Trace(errEncryptionDisabled)
+	}
+	eks, err := s.kv.loadEncryptedKeys()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	keys := make([]*EncryptionKey, 0, len(eks))
+	for _, ek := range eks {
+		keys = append(keys, &EncryptionKey{ID: ek.ID, Method: ek.Method, CreatedAt: ek.CreatedAt})
+	}
+	return keys, nil
+}
+
+// GetEncryptionKey returns the data key by ID.
+func (s *Server) GetEncryptionKey(id uint64) (*EncryptionKey, error) {
+	if s.masterKey == nil {
+		return nil, errors.Trace(errEncryptionDisabled)
+	}
+	ek, err := s.kv.loadEncryptedKey(id)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if ek == nil {
+		return nil, errors.Trace(ErrEncryptionKeyNotFound)
+	}
+	key, err := s.decryptKey(ek)
+	return key, errors.Trace(err)
+}
+
+// GetCurrentEncryptionKey returns the data key to encrypt the new data.
+func (s *Server) GetCurrentEncryptionKey() (*EncryptionKey, error) {
+	if s.masterKey == nil {
+		return nil, errors.Trace(errEncryptionDisabled)
+	}
+	id, err := s.kv.loadCurrentEncryptionKeyID()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if id == 0 {
+		return nil, errors.Trace(ErrEncryptionKeyNotFound)
+	}
+	key, err := s.GetEncryptionKey(id)
+	return key, errors.Trace(err)
+}
+
+// RotateEncryptionKey generates a data key and makes it the current key, it
+// returns the new key without the plaintext.
+func (s *Server) RotateEncryptionKey() (*EncryptionKey, error) {
+	if s.masterKey == nil {
+		return nil, errors.Trace(errEncryptionDisabled)
+	}
+	if !s.IsLeader() {
+		return nil, errors.New("rotate encryption key on non-leader")
+	}
+
+	s.encryptionLock.Lock()
+	defer s.encryptionLock.Unlock()
+
+	prevID, err := s.kv.loadCurrentEncryptionKeyID()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	id, err := s.idAlloc.Alloc()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	key := &EncryptionKey{
+		ID:        id,
+		Method:    EncryptionMethodAES256CTR,
+		Key:       make([]byte, dataKeyLen),
+		CreatedAt: time.Now().Unix(),
+	}
+	if _, err = io.ReadFull(rand.Reader, key.Key); err != nil {
+		return nil, errors.Trace(err)
+	}
+	ek, err := s.encryptKey(key)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	rotated, err := s.kv.rotateEncryptionKey(prevID, ek)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if !rotated {
+		return nil, errors.Errorf("current encryption key is changed from %d", prevID)
+	}
+	log.Infof("encryption key is rotated from %d to %d", prevID, id)
+	key.Key = nil
+	return key, nil
+}
+
+// runEncryptionKeyRotation rotates the current data key once it is older
+// than data-key-rotation-period, or creates the first one, until quit. It
+// only runs on the leader.
+func (s *Server) runEncryptionKeyRotation(quit <-chan struct{}) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(encryptionKeyCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.rotateExpiredEncryptionKey(); err != nil {
+			log.Errorf("rotate encryption key err %v", errors.ErrorStack(err))
+		}
+		select {
+		case <-ticker.C:
+		case <-quit:
+			return
+		case <-s.client.Ctx().Done():
+			return
+		}
+	}
+}
+
+func (s *Server) rotateExpiredEncryptionKey() error {
+	key, err := s.GetCurrentEncryptionKey()
+	if err != nil && errors.Cause(err) != ErrEncryptionKeyNotFound {
+		return errors.Trace(err)
+	}
+	if key != nil && time.Since(time.Unix(key.CreatedAt, 0)) < s.cfg.Encryption.DataKeyRotationPeriod.Duration {
+		return nil
+	}
+	_, err = s.RotateEncryptionKey()
+	return errors.Trace(err)
+}
@@ -0,0 +1,144 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testEncryptionSuite{})
+
+type testEncryptionSuite struct{}
+
+func mustWriteMasterKey(c *C, key string) string {
+	f, err := ioutil.TempFile("", "pd_master_key")
+	c.Assert(err, IsNil)
+	defer f.Close()
+	_, err = f.WriteString(key + "\n")
+	c.Assert(err, IsNil)
+	return f.Name()
+}
+
+func (s *testEncryptionSuite) TestLoadMasterKey(c *C) {
+	name := mustWriteMasterKey(c, strings.Repeat("ab", masterKeyLen))
+	defer os.Remove(name)
+	_, err := loadMasterKey(name)
+	c.Assert(err, IsNil)
+
+	for _, key := range []string{"", "xyz", strings.Repeat("ab", masterKeyLen-1)} {
+		name := mustWriteMasterKey(c, key)
+		defer os.Remove(name)
+		_, err = loadMasterKey(name)
+		c.Assert(err, NotNil)
+	}
+}
+
+func (s *testEncryptionSuite) TestManageEncryptionKeys(c *C) {
+	cfg := NewTestSingleConfig()
+	cfg.Encryption.MasterKeyPath = mustWriteMasterKey(c, strings.Repeat("ab", masterKeyLen))
+	defer os.Remove(cfg.Encryption.MasterKeyPath)
+	cfg.Encryption.DataKeyRotationPeriod.Duration = time.Hour
+	svr, err := NewServer(cfg)
+	c.Assert(err, IsNil)
+	defer func() {
+		svr.Close()
+		cleanServer(cfg)
+	}()
+	go svr.Run()
+	mustWaitLeader(c, []*Server{svr})
+
+	// The leader creates the first key.
+	var first *EncryptionKey
+	for i := 0; i < 50 && first == nil; i++ {
+		time.Sleep(100 * time.Millisecond)
+		first, err = svr.GetCurrentEncryptionKey()
+		if err != nil {
+			c.Assert(errors.Cause(err), Equals, ErrEncryptionKeyNotFound)
+		}
+	}
+	c.Assert(first, NotNil)
+	c.Assert(first.Method, Equals, EncryptionMethodAES256CTR)
+	c.Assert(first.Key, HasLen, dataKeyLen)
+
+	// The key is not expired yet.
+	c.Assert(svr.rotateExpiredEncryptionKey(), IsNil)
+	current, err := svr.GetCurrentEncryptionKey()
+	c.Assert(err, IsNil)
+	c.Assert(current.ID, Equals, first.ID)
+
+	rotated, err := svr.RotateEncryptionKey()
+	c.Assert(err, IsNil)
+	c.Assert(rotated.ID, Greater, first.ID)
+	c.Assert(rotated.Key, IsNil)
+	current, err = svr.GetCurrentEncryptionKey()
+	c.Assert(err, IsNil)
+	c.Assert(current.ID, Equals, rotated.ID)
+	c.Assert(bytes.Equal(current.Key, first.Key), IsFalse)
+
+	// The old key is kept.
+	old, err := svr.GetEncryptionKey(first.ID)
+	c.Assert(err, IsNil)
+	c.Assert(old, DeepEquals, first)
+	_, err = svr.GetEncryptionKey(rotated.ID + 1)
+	c.Assert(errors.Cause(err), Equals, ErrEncryptionKeyNotFound)
+
+	keys, err := svr.GetEncryptionKeys()
+	c.Assert(err, IsNil)
+	c.Assert(keys, HasLen, 2)
+	c.Assert(keys[0].ID, Equals, first.ID)
+	c.Assert(keys[1].ID, Equals, rotated.ID)
+	c.Assert(keys[1].Key, IsNil)
+
+	// The keys are saved encrypted, and can't be decrypted by another
+	// master key or as another key.
+	ek, err := svr.kv.loadEncryptedKey(first.ID)
+	c.Assert(err, IsNil)
+	c.Assert(bytes.Contains(ek.Ciphertext, first.Key), IsFalse)
+	ek.ID = rotated.ID
+	_, err = svr.decryptKey(ek)
+	c.Assert(err, NotNil)
+	otherKey := mustWriteMasterKey(c, strings.Repeat("cd", masterKeyLen))
+	defer os.Remove(otherKey)
+	other, err := loadMasterKey(otherKey)
+	c.Assert(err, IsNil)
+	ek.ID = first.ID
+	svr.masterKey, other = other, svr.masterKey
+	_, err = svr.decryptKey(ek)
+	c.Assert(err, NotNil)
+	svr.masterKey = other
+
+	// The expired key is rotated.
+	svr.cfg.Encryption.DataKeyRotationPeriod.Duration = 0
+	c.Assert(svr.rotateExpiredEncryptionKey(), IsNil)
+	current, err = svr.GetCurrentEncryptionKey()
+	c.Assert(err, IsNil)
+	c.Assert(current.ID, Greater, rotated.ID)
+}
+
+func (s *testEncryptionSuite) TestEncryptionDisabled(c *C) {
+	svr, cleanup := mustRunTestServer(c)
+	defer cleanup()
+
+	_, err := svr.GetCurrentEncryptionKey()
+	c.Assert(errors.Cause(err), Equals, errEncryptionDisabled)
+	_, err = svr.RotateEncryptionKey()
+	c.Assert(errors.Cause(err), Equals, errEncryptionDisabled)
+}
@@ -0,0 +1,286 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+// isEngineStore returns true if the store runs a special engine and is not
+// put in a namespace explicitly. The replicas on such stores are managed by
+// the engine replica checker only, the other schedulers leave them alone.
+func isEngineStore(opt *scheduleOption, store *storeInfo) bool {
+	return store.isSpecialEngine() && opt.GetClassifier().GetStoreNamespace(store.Store) == defaultNamespace
+}
+
+// withoutEnginePeers returns the region without the peers on the engine
+// stores, which is what the replica checker and the balancers see. The region
+// itself is returned if it has no such peers.
+func withoutEnginePeers(cluster *clusterInfo, opt *scheduleOption, region *regionInfo) *regionInfo {
+	engineStores := make(map[uint64]struct{})
+	for _, peer := range region.GetPeers() {
+		if store := cluster.getStore(peer.GetStoreId()); store != nil && isEngineStore(opt, store) {
+			engineStores[store.GetId()] = struct{}{}
+		}
+	}
+	if len(engineStores) == 0 {
+		return region
+	}
+
+	newRegion := region.clone()
+	for storeID := range engineStores {
+		newRegion.RemoveStorePeer(storeID)
+	}
+	downPeers := newRegion.DownPeers[:0]
+	for _, stats := range newRegion.DownPeers {
+		if _, ok := engineStores[stats.GetPeer().GetStoreId()]; !ok {
+			downPeers = append(downPeers, stats)
+		}
+	}
+	newRegion.DownPeers = downPeers
+	pendingPeers := newRegion.PendingPeers[:0]
+	for _, peer := range newRegion.PendingPeers {
+		if _, ok := engineStores[peer.GetStoreId()]; !ok {
+			pendingPeers = append(pendingPeers, peer)
+		}
+	}
+	newRegion.PendingPeers = pendingPeers
+	return newRegion
+}
+
+// findEngineReplica returns the index of the engine replica config of the
+// range containing the start key of the region, -1 if there is none.
+func findEngineReplica(replicas []EngineReplicaConfig, region *regionInfo) int {
+	key := region.GetStartKey()
+	for i := range replicas {
+		start, end := replicas[i].keyRange().encode()
+		if bytes.Compare(key, start) >= 0 && (len(end) == 0 || bytes.Compare(key, end) < 0) {
+			return i
+		}
+	}
+	return -1
+}
+
+// enginePeers returns the peers of the region on the engine stores of the
+// engine, the peers on the stores which are up and not down are healthy.
+func enginePeers(cluster *clusterInfo, opt *scheduleOption, region *regionInfo, engine string) (healthy, unhealthy []*metapb.Peer) {
+	for _, peer := range region.GetPeers() {
+		store := cluster.getStore(peer.GetStoreId())
+		if store == nil {
+			continue
+		}
+		if store.getLabelValue(engineLabelKey) != engine || !isEngineStore(opt, store) {
+			continue
+		}
+		if store.isUp() && store.downTime() < opt.GetMaxStoreDownTime() {
+			healthy = append(healthy, peer)
+		} else {
+			unhealthy = append(unhealthy, peer)
+		}
+	}
+	return healthy, unhealthy
+}
+
+// engineReplicaChecker keeps the configured count of extra replicas of the
+// regions on the engine stores. A replica is added if the healthy replicas
+// are fewer than the count, an unhealthy one or the one on the store with the
+// largest storage ratio is removed if there are more replicas.
+//
+// The extra replicas are voters, as there are no learners, so they are in
+// the quorum of the region. To keep the region writable by the ordinary
+// replicas alone, a region has fewer engine replicas than ordinary ones, e.g.
+// at most 2 for 3 ordinary replicas, and the leaders are moved off the
+// engine stores.
+type engineReplicaChecker struct {
+	opt           *scheduleOption
+	cluster       *clusterInfo
+	filters       []Filter
+	leaderFilters []Filter
+}
+
+func newEngineReplicaChecker(opt *scheduleOption, cluster *clusterInfo) *engineReplicaChecker {
+	var filters []Filter
+	filters = append(filters, newStateFilter(opt))
+	filters = append(filters, newHealthFilter(opt))
+	filters = append(filters, newSnapshotCountFilter(opt))
+	filters = append(filters, newStorageThresholdFilter(opt))
+
+	var leaderFilters []Filter
+	leaderFilters = append(leaderFilters, newStateFilter(opt))
+	leaderFilters = append(leaderFilters, newHealthFilter(opt))
+	leaderFilters = append(leaderFilters, newRejectLeaderFilter(opt))
+	leaderFilters = append(leaderFilters, newEngineFilter(opt))
+
+	return &engineReplicaChecker{
+		opt:           opt,
+		cluster:       cluster,
+		filters:       filters,
+		leaderFilters: leaderFilters,
+	}
+}
+
+func (e *engineReplicaChecker) Check(region *regionInfo) Operator {
+	if op := e.checkLeader(region); op != nil {
+		return op
+	}
+
+	replicas := e.opt.GetEngineReplicas()
+	i := findEngineReplica(replicas, region)
+	if i < 0 {
+		return nil
+	}
+	cfg := &replicas[i]
+
+	// The engine replicas are fewer than the ordinary ones, so the engine
+	// stores are not needed by the quorum.
+	ordinary := len(withoutEnginePeers(e.cluster, e.opt, region).GetPeers())
+	if ordinary == 0 {
+		return nil
+	}
+	count := int(cfg.Count)
+	if count >= ordinary {
+		count = ordinary - 1
+	}
+
+	healthy, unhealthy := enginePeers(e.cluster, e.opt, region, cfg.Engine)
+	if len(healthy) < count {
+		newPeer := e.selectTarget(region, cfg.Engine)
+		if newPeer == nil {
+			return nil
+		}
+		return newAddPeer(region, newPeer)
+	}
+	if len(healthy)+len(unhealthy) > count {
+		if len(unhealthy) > 0 {
+			return newRemovePeer(region, unhealthy[0])
+		}
+		return newRemovePeer(region, e.selectSource(healthy))
+	}
+	return nil
+}
+
+// checkLeader transfers the leader on an engine store to the follower on the
+// ordinary store with the least leader ratio.
+func (e *engineReplicaChecker) checkLeader(region *regionInfo) Operator {
+	store := e.cluster.getStore(region.Leader.GetStoreId())
+	if store == nil || !isEngineStore(e.opt, store) {
+		return nil
+	}
+	var target *storeInfo
+	for _, store := range e.cluster.getFollowerStores(region) {
+		peer := region.GetStorePeer(store.GetId())
+		if region.GetDownPeer(peer.GetId()) != nil || region.GetPendingPeer(peer.GetId()) != nil {
+			continue
+		}
+		if filterTarget(store, e.leaderFilters) {
+			continue
+		}
+		if target == nil || store.leaderRatio() < target.leaderRatio() {
+			target = store
+		}
+	}
+	if target == nil {
+		return nil
+	}
+	return newTransferLeader(region, region.GetStorePeer(target.GetId()))
+}
+
+// selectTarget returns a new peer on the store of the engine with the least
+// storage ratio, which has no peer of the region.
+func (e *engineReplicaChecker) selectTarget(region *regionInfo, engine string) *metapb.Peer {
+	var target *storeInfo
+	for _, store := range e.cluster.getStores() {
+		if store.getLabelValue(engineLabelKey) != engine || !isEngineStore(e.opt, store) {
+			continue
+		}
+		if region.GetStorePeer(store.GetId()) != nil || filterTarget(store, e.filters) {
+			continue
+		}
+		if target == nil || store.storageRatio() < target.storageRatio() {
+			target = store
+		}
+	}
+	if target == nil {
+		return nil
+	}
+	newPeer, err := e.cluster.allocPeer(target.GetId())
+	if err != nil {
+		return nil
+	}
+	return newPeer
+}
+
+// selectSource returns the peer on the store with the largest storage ratio.
+func (e *engineReplicaChecker) selectSource(peers []*metapb.Peer) *metapb.Peer {
+	var (
+		source      *metapb.Peer
+		sourceRatio float64
+	)
+	for _, peer := range peers {
+		ratio := e.cluster.getStore(peer.GetStoreId()).storageRatio()
+		if source == nil || ratio > sourceRatio {
+			source, sourceRatio = peer, ratio
+		}
+	}
+	return source
+}
+
+// EngineReplicaProgress is the progress of placing the engine replicas of a
+// key range. The regions with Count healthy replicas are replicated, and the
+// regions with at least one healthy replica are available on the engine.
+type EngineReplicaProgress struct {
+	EngineReplicaConfig
+	RegionCount     int     `json:"region-count"`
+	ReplicatedCount int     `json:"replicated-count"`
+	AvailableCount  int     `json:"available-count"`
+	ReplicatedRatio float64 `json:"replicated-ratio"`
+}
+
+// GetEngineReplicaProgress returns the progress of the engine replicas of
+// every configured key range.
+func (c *RaftCluster) GetEngineReplicaProgress() []*EngineReplicaProgress {
+	opt := c.s.scheduleOpt
+	replicas := opt.GetEngineReplicas()
+	progresses := make([]*EngineReplicaProgress, 0, len(replicas))
+	for _, cfg := range replicas {
+		progresses = append(progresses, &EngineReplicaProgress{EngineReplicaConfig: cfg, ReplicatedRatio: 1})
+	}
+	if len(replicas) == 0 {
+		return progresses
+	}
+
+	for _, region := range c.cachedCluster.getRegions() {
+		i := findEngineReplica(replicas, region)
+		if i < 0 {
+			continue
+		}
+		cfg, progress := &replicas[i], progresses[i]
+		progress.RegionCount++
+		healthy, _ := enginePeers(c.cachedCluster, opt, region, cfg.Engine)
+		if uint64(len(healthy)) >= cfg.Count {
+			progress.ReplicatedCount++
+		}
+		if len(healthy) > 0 {
+			progress.AvailableCount++
+		}
+	}
+	for _, progress := range progresses {
+		if progress.RegionCount > 0 {
+			progress.ReplicatedRatio = float64(progress.ReplicatedCount) / float64(progress.RegionCount)
+		}
+	}
+	return progresses
+}
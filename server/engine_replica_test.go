@@ -0,0 +1,137 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+var _ = Suite(&testEngineReplicaSuite{})
+
+type testEngineReplicaSuite struct{}
+
+func (s *testEngineReplicaSuite) TestChecker(c *C) {
+	cluster := newClusterInfo(NewMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	cfg, opt := newTestScheduleConfig()
+	rc := newReplicaChecker(opt, cluster)
+	ec := newEngineReplicaChecker(opt, cluster)
+
+	tc.AddRegionStore(1, 1, 0.1)
+	tc.AddRegionStore(2, 1, 0.1)
+	tc.AddRegionStore(3, 1, 0.1)
+	tc.AddLabelsStore(4, 0, 0.2, map[string]string{engineLabelKey: "tiflash"})
+	tc.AddLabelsStore(5, 0, 0.3, map[string]string{engineLabelKey: "tiflash"})
+	tc.AddLeaderRegion(1, 1, 2, 3)
+	region := cluster.getRegion(1)
+
+	// No extra replicas are configured.
+	c.Assert(ec.Check(region), IsNil)
+
+	cfg.EngineReplicas = []EngineReplicaConfig{{Engine: "tiflash", Count: 1}}
+	checkAddPeer(c, ec.Check(region), 4)
+
+	// The replica on the engine store is not counted by the replica checker.
+	peer4, _ := cluster.allocPeer(4)
+	region.Peers = append(region.Peers, peer4)
+	c.Assert(rc.Check(region), IsNil)
+	c.Assert(ec.Check(region), IsNil)
+
+	// The redundant replica on the store with larger storage ratio is removed.
+	peer5, _ := cluster.allocPeer(5)
+	region.Peers = append(region.Peers, peer5)
+	c.Assert(rc.Check(region), IsNil)
+	checkRemovePeer(c, ec.Check(region), 5)
+
+	// The replica on the offline store is replaced.
+	region.RemoveStorePeer(5)
+	tc.SetStoreOffline(4)
+	checkAddPeer(c, ec.Check(region), 5)
+	region.Peers = append(region.Peers, peer5)
+	checkRemovePeer(c, ec.Check(region), 4)
+
+	// The engine replicas are fewer than the ordinary ones.
+	region.RemoveStorePeer(4)
+	tc.SetStoreUp(4)
+	cfg.EngineReplicas = []EngineReplicaConfig{{Engine: "tiflash", Count: 3}}
+	tc.AddLabelsStore(6, 0, 0.1, map[string]string{engineLabelKey: "tiflash"})
+	checkAddPeer(c, ec.Check(region), 6)
+	peer6, _ := cluster.allocPeer(6)
+	region.Peers = append(region.Peers, peer6)
+	c.Assert(ec.Check(region), IsNil)
+	region.RemoveStorePeer(3)
+	checkRemovePeer(c, ec.Check(region), 5)
+
+	// The range does not contain the region.
+	cfg.EngineReplicas = []EngineReplicaConfig{{StartKey: "00", EndKey: "01", Engine: "tiflash", Count: 1}}
+	c.Assert(ec.Check(region), IsNil)
+}
+
+func (s *testEngineReplicaSuite) TestLeader(c *C) {
+	cluster := newClusterInfo(NewMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	_, opt := newTestScheduleConfig()
+	ec := newEngineReplicaChecker(opt, cluster)
+	lb := newBalanceLeaderScheduler(opt)
+
+	tc.AddLeaderStore(1, 0, 2)
+	tc.AddLeaderStore(2, 1, 2)
+	tc.AddLeaderStore(3, 2, 2)
+	store := cluster.getStore(3)
+	store.Labels = []*metapb.StoreLabel{{Key: engineLabelKey, Value: "tiflash"}}
+	cluster.putStore(store)
+	tc.AddLeaderRegion(1, 3, 1, 2)
+	region := cluster.getRegion(1)
+
+	// The leader is moved off the engine store, but not to another one.
+	checkTransferLeader(c, ec.Check(region), 3, 1)
+	checkTransferLeader(c, lb.Schedule(cluster), 3, 1)
+	store = cluster.getStore(1)
+	store.Labels = []*metapb.StoreLabel{{Key: engineLabelKey, Value: "tiflash"}}
+	cluster.putStore(store)
+	checkTransferLeader(c, ec.Check(region), 3, 2)
+}
+
+func (s *testEngineReplicaSuite) TestProgress(c *C) {
+	cfg, opt := newTestScheduleConfig()
+	cluster := newClusterInfo(NewMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+	rc := &RaftCluster{s: &Server{scheduleOpt: opt}, cachedCluster: cluster}
+	c.Assert(rc.GetEngineReplicaProgress(), HasLen, 0)
+
+	tc.AddRegionStore(1, 1, 0.1)
+	tc.AddRegionStore(2, 1, 0.1)
+	tc.AddRegionStore(3, 1, 0.1)
+	tc.AddLabelsStore(4, 0, 0.1, map[string]string{engineLabelKey: "tiflash"})
+	tc.AddLabelsStore(5, 0, 0.1, map[string]string{engineLabelKey: "tiflash"})
+	tc.AddLeaderRegion(1, 1, 2, 3, 4, 5)
+	tc.AddLeaderRegion(2, 1, 2, 3, 4)
+	tc.AddLeaderRegion(3, 1, 2, 3)
+	cfg.EngineReplicas = []EngineReplicaConfig{{Engine: "tiflash", Count: 2}}
+
+	progress := rc.GetEngineReplicaProgress()
+	c.Assert(progress, HasLen, 1)
+	c.Assert(progress[0].Engine, Equals, "tiflash")
+	c.Assert(progress[0].RegionCount, Equals, 3)
+	c.Assert(progress[0].ReplicatedCount, Equals, 1)
+	c.Assert(progress[0].AvailableCount, Equals, 2)
+	c.Assert(progress[0].ReplicatedRatio, Equals, 1.0/3)
+
+	// The replicas on the engine stores are not extra peers.
+	c.Assert(rc.isExtraPeerRegion(cluster.getRegion(1)), IsFalse)
+	c.Assert(rc.isMissPeerRegion(cluster.getRegion(3)), IsFalse)
+}
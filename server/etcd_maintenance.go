@@ -0,0 +1,161 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/tls"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/etcdserver/api/v3rpc/rpctypes"
+	"github.com/coreos/etcd/etcdserver/etcdserverpb"
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/pd/pkg/etcdutil"
+	"golang.org/x/net/context"
+)
+
+// defragTimeout is the max time to defragment one member, the member is
+// blocked while defragmenting.
+const defragTimeout = time.Minute
+
+// runEtcdMaintenance compacts the etcd revisions every etcd-compaction-interval,
+// and defragments the members every etcd-defrag-interval if enabled, until
+// quit. It only runs on the leader.
+func (s *Server) runEtcdMaintenance(quit <-chan struct{}) {
+	defer s.wg.Done()
+
+	var compactC, defragC <-chan time.Time
+	if interval := s.cfg.EtcdCompactionInterval.Duration; interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		compactC = ticker.C
+	}
+	if s.cfg.EnableEtcdDefrag {
+		ticker := time.NewTicker(s.cfg.EtcdDefragInterval.Duration)
+		defer ticker.Stop()
+		defragC = ticker.C
+	}
+
+	// The revisions are compacted one interval after they are seen, so the
+	// watchers always have the history of the last interval.
+	var rev int64
+	for {
+		select {
+		case <-compactC:
+			current, err := s.compactEtcd(rev)
+			if err != nil {
+				log.Errorf("compact etcd to revision %d err %v", rev, errors.ErrorStack(err))
+				etcdMaintenanceCounter.WithLabelValues("compact", "failed").Inc()
+				continue
+			}
+			etcdMaintenanceCounter.WithLabelValues("compact", "success").Inc()
+			rev = current
+		case <-defragC:
+			if err := s.defragEtcd(); err != nil {
+				log.Errorf("defragment etcd err %v", errors.ErrorStack(err))
+				etcdMaintenanceCounter.WithLabelValues("defrag", "failed").Inc()
+				continue
+			}
+			etcdMaintenanceCounter.WithLabelValues("defrag", "success").Inc()
+		case <-quit:
+			return
+		case <-s.client.Ctx().Done():
+			return
+		}
+	}
+}
+
+// compactEtcd compacts the revisions before rev if it is not 0, and returns
+// the current revision.
+func (s *Server) compactEtcd(rev int64) (int64, error) {
+	// The header of any read has the current revision.
+	resp, err := kvGet(s.client, pdClusterIDPath)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	current := resp.Header.Revision
+	if rev == 0 {
+		return current, nil
+	}
+
+	ctx, cancel := context.WithTimeout(s.client.Ctx(), requestTimeout)
+	defer cancel()
+	if _, err = s.client.Compact(ctx, rev); err != nil && err != rpctypes.ErrCompacted {
+		return 0, errors.Trace(err)
+	}
+	log.Infof("etcd is compacted to revision %d", rev)
+	return current, nil
+}
+
+// defragEtcd defragments the members one by one. It stops if any member is
+// unhealthy before or after defragmenting a member, so at most one member is
+// blocked at the same time.
+func (s *Server) defragEtcd() error {
+	resp, err := etcdutil.ListEtcdMembers(s.client)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, m := range resp.Members {
+		if err = s.checkEtcdMembers(resp.Members); err != nil {
+			return errors.Trace(err)
+		}
+		start := time.Now()
+		err = withMemberClient(m, s.GetTLSConfig(), func(c *clientv3.Client) error {
+			ctx, cancel := context.WithTimeout(c.Ctx(), defragTimeout)
+			defer cancel()
+			_, err := c.Defragment(ctx, m.ClientURLs[0])
+			return errors.Trace(err)
+		})
+		if err != nil {
+			return errors.Annotatef(err, "defragment member %s", m.Name)
+		}
+		log.Infof("etcd member %s is defragmented in %v", m.Name, time.Since(start))
+	}
+	return errors.Trace(s.checkEtcdMembers(resp.Members))
+}
+
+// checkEtcdMembers returns an error if any member does not respond.
+func (s *Server) checkEtcdMembers(members []*etcdserverpb.Member) error {
+	for _, m := range members {
+		if len(m.ClientURLs) == 0 {
+			return errors.Errorf("etcd member %s has no client urls", m.Name)
+		}
+		err := withMemberClient(m, s.GetTLSConfig(), func(c *clientv3.Client) error {
+			ctx, cancel := context.WithTimeout(c.Ctx(), requestTimeout)
+			defer cancel()
+			_, err := c.Status(ctx, m.ClientURLs[0])
+			return errors.Trace(err)
+		})
+		if err != nil {
+			return errors.Annotatef(err, "etcd member %s is unhealthy", m.Name)
+		}
+	}
+	return nil
+}
+
+// withMemberClient calls fn with a client connected to the member, the client
+// of the server can only dial its own endpoint.
+func withMemberClient(m *etcdserverpb.Member, tlsCfg *tls.Config, fn func(c *clientv3.Client) error) error {
+	c, err := clientv3.New(clientv3.Config{
+		Endpoints:   m.ClientURLs,
+		DialTimeout: etcdTimeout,
+		TLS:         tlsCfg,
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer c.Close()
+	return errors.Trace(fn(c))
+}
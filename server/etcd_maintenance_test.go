@@ -0,0 +1,68 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/etcdserver/api/v3rpc/rpctypes"
+	"github.com/juju/errors"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testEtcdMaintenanceSuite{})
+
+type testEtcdMaintenanceSuite struct{}
+
+func (s *testEtcdMaintenanceSuite) TestCompact(c *C) {
+	svr, cleanup := mustRunTestServer(c)
+	defer cleanup()
+
+	key := "/test/compact"
+	c.Assert(svr.kv.save(key, "1"), IsNil)
+	rev, err := svr.compactEtcd(0)
+	c.Assert(err, IsNil)
+	c.Assert(svr.kv.save(key, "2"), IsNil)
+
+	// The revisions seen last time are kept.
+	_, err = kvGet(svr.client, key, clientv3.WithRev(rev))
+	c.Assert(err, IsNil)
+	current, err := svr.compactEtcd(rev)
+	c.Assert(err, IsNil)
+	c.Assert(current, Greater, rev)
+	_, err = kvGet(svr.client, key, clientv3.WithRev(rev))
+	c.Assert(err, IsNil)
+	_, err = kvGet(svr.client, key, clientv3.WithRev(rev-1))
+	c.Assert(errors.Cause(err), Equals, rpctypes.ErrCompacted)
+
+	// Compacting to a compacted revision is not an error.
+	_, err = svr.compactEtcd(rev)
+	c.Assert(err, IsNil)
+}
+
+func (s *testEtcdMaintenanceSuite) TestDefrag(c *C) {
+	svrs, cleanup := newMultiTestServers(c, 3)
+	defer cleanup()
+	leader := mustWaitLeader(c, svrs)
+
+	c.Assert(leader.defragEtcd(), IsNil)
+
+	// It stops if any member is unhealthy.
+	for _, svr := range svrs {
+		if svr != leader {
+			svr.Close()
+			break
+		}
+	}
+	c.Assert(leader.defragEtcd(), NotNil)
+}
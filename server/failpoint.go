@@ -0,0 +1,34 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+// The failpoints of the server, they are enabled by pkg/failpoint. The
+// return term makes the code fail there, and the sleep term delays it.
+const (
+	// FailpointCampaignLeader fails the campaign before granting the lease.
+	FailpointCampaignLeader = "campaign-leader"
+	// FailpointLeaderResign makes the leader resign in next tso update.
+	FailpointLeaderResign = "leader-resign"
+	// FailpointRegionHeartbeat fails the region heartbeats before they are
+	// handled.
+	FailpointRegionHeartbeat = "region-heartbeat"
+	// FailpointStoreHeartbeat fails the store heartbeats before they are
+	// handled.
+	FailpointStoreHeartbeat = "store-heartbeat"
+	// FailpointDispatchOperator drops the operator step dispatched to the
+	// region, as if the heartbeat response is lost.
+	FailpointDispatchOperator = "dispatch-operator"
+	// FailpointEtcdTxn fails the etcd txns without committing them.
+	FailpointEtcdTxn = "etcd-txn"
+)
@@ -0,0 +1,58 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/pkg/failpoint"
+)
+
+var _ = Suite(&testFailpointSuite{})
+
+type testFailpointSuite struct{}
+
+func (s *testFailpointSuite) TestLeader(c *C) {
+	c.Assert(failpoint.Enable(FailpointCampaignLeader, "return(test)"), IsNil)
+	defer failpoint.Disable(FailpointCampaignLeader)
+
+	svr, cleanup := newTestServer(c)
+	defer cleanup()
+	go svr.Run()
+	time.Sleep(time.Second)
+	c.Assert(svr.IsLeader(), IsFalse)
+
+	failpoint.Disable(FailpointCampaignLeader)
+	mustWaitLeader(c, []*Server{svr})
+
+	// The leader resigns and campaigns again.
+	c.Assert(failpoint.Enable(FailpointLeaderResign, "1*return(test)"), IsNil)
+	defer failpoint.Disable(FailpointLeaderResign)
+	for i := 0; i < 100 && failpoint.All()[0].Count != 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(failpoint.All()[0].Count, Equals, 0)
+	mustWaitLeader(c, []*Server{svr})
+}
+
+func (s *testFailpointSuite) TestEtcdTxn(c *C) {
+	svr, cleanup := mustRunTestServer(c)
+	defer cleanup()
+
+	c.Assert(failpoint.Enable(FailpointEtcdTxn, "1*return(test)"), IsNil)
+	defer failpoint.Disable(FailpointEtcdTxn)
+	c.Assert(svr.SetFeatureGate(FeatureLabelProperty, true), NotNil)
+	c.Assert(svr.SetFeatureGate(FeatureLabelProperty, true), IsNil)
+}
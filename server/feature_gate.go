@@ -0,0 +1,104 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+)
+
+// Feature gates of the scheduling behaviors.
+const (
+	// FeatureLocationReplacement moves replicas to the stores with better
+	// location isolation when the replica count is satisfied.
+	FeatureLocationReplacement = "enable-location-replacement"
+	// FeatureLabelProperty makes the schedulers respect the label property.
+	FeatureLabelProperty = "enable-label-property"
+)
+
+// featureGateDefaults are the known feature gates and their defaults.
+var featureGateDefaults = map[string]bool{
+	FeatureLocationReplacement: true,
+	FeatureLabelProperty:       true,
+}
+
+// ErrUnknownFeatureGate is returned when setting a feature gate which is not
+// defined.
+var ErrUnknownFeatureGate = errors.New("unknown feature gate")
+
+// FeatureGates maps the name of the feature gates to whether they are enabled.
+type FeatureGates map[string]bool
+
+func newFeatureGates() FeatureGates {
+	gates := make(FeatureGates, len(featureGateDefaults))
+	for name, enabled := range featureGateDefaults {
+		gates[name] = enabled
+	}
+	return gates
+}
+
+func (g FeatureGates) clone() FeatureGates {
+	gates := make(FeatureGates, len(g))
+	for name, enabled := range g {
+		gates[name] = enabled
+	}
+	return gates
+}
+
+// GetFeatureGates returns all the feature gates.
+func (s *Server) GetFeatureGates() FeatureGates {
+	return s.scheduleOpt.GetFeatureGates()
+}
+
+// SetFeatureGate saves the feature gate in etcd and then applies it, the
+// change takes effect at once and is kept across leader changes.
+func (s *Server) SetFeatureGate(name string, enabled bool) error {
+	if _, ok := featureGateDefaults[name]; !ok {
+		return errors.Trace(ErrUnknownFeatureGate)
+	}
+
+	configLock.Lock()
+	defer configLock.Unlock()
+
+	if err := s.kv.saveFeatureGate(name, enabled); err != nil {
+		return errors.Trace(err)
+	}
+	gates := s.scheduleOpt.GetFeatureGates()
+	gates[name] = enabled
+	s.scheduleOpt.SetFeatureGates(gates)
+	log.Infof("feature gate %s is set to %v", name, enabled)
+	return nil
+}
+
+// loadFeatureGates applies the feature gates saved in etcd after becoming
+// leader, the gates not saved use the defaults.
+func (s *Server) loadFeatureGates() error {
+	configLock.Lock()
+	defer configLock.Unlock()
+
+	saved, err := s.kv.loadFeatureGates()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	gates := newFeatureGates()
+	for name, enabled := range saved {
+		if _, ok := gates[name]; !ok {
+			log.Warnf("unknown feature gate %s is ignored", name)
+			continue
+		}
+		gates[name] = enabled
+	}
+	s.scheduleOpt.SetFeatureGates(gates)
+	return nil
+}
@@ -0,0 +1,47 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"github.com/juju/errors"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testFeatureGateSuite{})
+
+type testFeatureGateSuite struct{}
+
+func (s *testFeatureGateSuite) TestFeatureGates(c *C) {
+	svr, cleanup := mustRunTestServer(c)
+	defer cleanup()
+
+	c.Assert(svr.GetFeatureGates(), DeepEquals, FeatureGates{
+		FeatureLocationReplacement: true,
+		FeatureLabelProperty:       true,
+	})
+
+	err := svr.SetFeatureGate("unknown", true)
+	c.Assert(errors.Cause(err), Equals, ErrUnknownFeatureGate)
+
+	c.Assert(svr.SetFeatureGate(FeatureLocationReplacement, false), IsNil)
+	c.Assert(svr.scheduleOpt.IsFeatureEnabled(FeatureLocationReplacement), IsFalse)
+
+	// The saved feature gates are loaded after becoming leader.
+	svr.scheduleOpt.SetFeatureGates(newFeatureGates())
+	c.Assert(svr.loadFeatureGates(), IsNil)
+	c.Assert(svr.GetFeatureGates(), DeepEquals, FeatureGates{
+		FeatureLocationReplacement: false,
+		FeatureLabelProperty:       true,
+	})
+}
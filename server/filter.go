@@ -134,6 +134,80 @@ func (f *healthFilter) FilterTarget(store *storeInfo) bool {
 	return f.filter(store)
 }
 
+// rejectLeaderFilter filters the stores with the reject-leader or slow-store
+// label property as leader targets.
+type rejectLeaderFilter struct {
+	opt *scheduleOption
+}
+
+func newRejectLeaderFilter(opt *scheduleOption) *rejectLeaderFilter {
+	return &rejectLeaderFilter{opt: opt}
+}
+
+func (f *rejectLeaderFilter) FilterSource(store *storeInfo) bool {
+	return false
+}
+
+func (f *rejectLeaderFilter) FilterTarget(store *storeInfo) bool {
+	return f.opt.CheckLabelProperty(RejectLeader, store.GetLabels()) ||
+		f.opt.CheckLabelProperty(SlowStore, store.GetLabels())
+}
+
+// slowStoreFilter filters the stores with the slow-store label property as
+// targets of new peers.
+type slowStoreFilter struct {
+	opt *scheduleOption
+}
+
+func newSlowStoreFilter(opt *scheduleOption) *slowStoreFilter {
+	return &slowStoreFilter{opt: opt}
+}
+
+func (f *slowStoreFilter) FilterSource(store *storeInfo) bool {
+	return false
+}
+
+func (f *slowStoreFilter) FilterTarget(store *storeInfo) bool {
+	return f.opt.CheckLabelProperty(SlowStore, store.GetLabels())
+}
+
+// engineFilter filters the engine stores as targets, the replicas on them are
+// added by the engine replica checker only. They are still sources, so the
+// leaders can be moved off them.
+type engineFilter struct {
+	opt *scheduleOption
+}
+
+func newEngineFilter(opt *scheduleOption) *engineFilter {
+	return &engineFilter{opt: opt}
+}
+
+func (f *engineFilter) FilterSource(store *storeInfo) bool {
+	return false
+}
+
+func (f *engineFilter) FilterTarget(store *storeInfo) bool {
+	return isEngineStore(f.opt, store)
+}
+
+// enginePeerFilter filters the engine stores as sources of moving peers, so
+// the engine replicas are not moved to the ordinary stores.
+type enginePeerFilter struct {
+	opt *scheduleOption
+}
+
+func newEnginePeerFilter(opt *scheduleOption) *enginePeerFilter {
+	return &enginePeerFilter{opt: opt}
+}
+
+func (f *enginePeerFilter) FilterSource(store *storeInfo) bool {
+	return isEngineStore(f.opt, store)
+}
+
+func (f *enginePeerFilter) FilterTarget(store *storeInfo) bool {
+	return false
+}
+
 type regionCountFilter struct {
 	opt *scheduleOption
 }
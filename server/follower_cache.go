@@ -0,0 +1,216 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"golang.org/x/net/context"
+)
+
+// followerCache is a copy of the stores and regions saved in etcd, followers
+// use it to serve metadata reads without forwarding them to the leader.
+//
+// The cache is synced by watching the local etcd member. The leader saves the
+// timestamp every tso-save-interval, and the watch delivers events in order,
+// so receiving a timestamp event means all changes saved before it are
+// applied. The cache is stale if no timestamp event is received within
+// follower-read-max-staleness, then requests are forwarded to the leader.
+//
+// Region leaders are not saved in etcd, so regions read from the cache have
+// no leader.
+type followerCache struct {
+	s *Server
+
+	sync.RWMutex
+	cluster  *clusterInfo
+	lastSync time.Time
+}
+
+func newFollowerCache(s *Server) *followerCache {
+	return &followerCache{s: s}
+}
+
+func (c *followerCache) loop() {
+	defer c.s.wg.Done()
+
+	for {
+		if c.s.isClosed() {
+			log.Infof("server is closed, return follower cache loop")
+			return
+		}
+
+		if !c.s.IsLeader() {
+			if err := c.sync(); err != nil {
+				log.Errorf("sync follower cache err %v", errors.ErrorStack(err))
+			}
+			c.reset(nil)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+// sync loads the cluster and applies the changes until the server
+// becomes leader or the watch fails.
+func (c *followerCache) sync() error {
+	resp, err := kvGet(c.s.client, c.s.rootPath)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	cluster, err := loadClusterInfo(c.s.idAlloc, c.s.kv)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if cluster == nil {
+		return nil
+	}
+	c.reset(cluster)
+
+	watcher := clientv3.NewWatcher(c.s.client)
+	defer watcher.Close()
+
+	ctx, cancel := context.WithCancel(c.s.client.Ctx())
+	defer cancel()
+
+	// Changes after the revision may be applied twice, it is fine because
+	// the events are in order and they are all full values.
+	rch := watcher.Watch(ctx, c.s.rootPath, clientv3.WithPrefix(), clientv3.WithRev(resp.Header.Revision+1))
+
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case wresp, ok := <-rch:
+			if !ok {
+				return errors.New("follower cache watch channel is closed")
+			}
+			if err = wresp.Err(); err != nil {
+				return errors.Trace(err)
+			}
+			for _, ev := range wresp.Events {
+				if err = c.apply(ev); err != nil {
+					return errors.Trace(err)
+				}
+			}
+		case <-ticker.C:
+			if c.s.IsLeader() {
+				return nil
+			}
+		}
+	}
+}
+
+func (c *followerCache) apply(ev *clientv3.Event) error {
+	key := string(ev.Kv.Key)
+	storePrefix := path.Join(c.s.kv.clusterPath, "s") + "/"
+	regionPrefix := path.Join(c.s.kv.clusterPath, "r") + "/"
+
+	c.Lock()
+	defer c.Unlock()
+
+	switch {
+	case key == c.s.getTimestampPath():
+		c.lastSync = time.Now()
+	case strings.HasPrefix(key, storePrefix):
+		if ev.Type != mvccpb.PUT {
+			return nil
+		}
+		store := &metapb.Store{}
+		if err := store.Unmarshal(ev.Kv.Value); err != nil {
+			return errors.Trace(err)
+		}
+		c.cluster.stores.setStore(newStoreInfo(store))
+	case strings.HasPrefix(key, regionPrefix):
+		if ev.Type != mvccpb.PUT {
+			return nil
+		}
+		region := &metapb.Region{}
+		if err := region.Unmarshal(ev.Kv.Value); err != nil {
+			return errors.Trace(err)
+		}
+		c.cluster.regions.setRegion(newRegionInfo(region, nil))
+	}
+	return nil
+}
+
+func (c *followerCache) reset(cluster *clusterInfo) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.cluster = cluster
+	c.lastSync = time.Now()
+}
+
+func (c *followerCache) getCluster() *clusterInfo {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.cluster
+}
+
+// handleRequest serves the metadata read request, returns false if the
+// request should be forwarded to the leader.
+func (c *followerCache) handleRequest(req *pdpb.Request) (*pdpb.Response, bool) {
+	c.RLock()
+	defer c.RUnlock()
+
+	cluster := c.cluster
+	if cluster == nil || time.Since(c.lastSync) > c.s.cfg.FollowerReadMaxStaleness.Duration {
+		return nil, false
+	}
+
+	switch req.GetCmdType() {
+	case pdpb.CommandType_GetStore:
+		store := cluster.stores.getStore(req.GetGetStore().GetStoreId())
+		if store == nil {
+			return nil, false
+		}
+		return &pdpb.Response{
+			GetStore: &pdpb.GetStoreResponse{
+				Store: store.Store,
+			},
+		}, true
+	case pdpb.CommandType_GetRegion:
+		region := cluster.regions.searchRegion(req.GetGetRegion().GetRegionKey())
+		if region == nil {
+			return nil, false
+		}
+		return &pdpb.Response{
+			GetRegion: &pdpb.GetRegionResponse{
+				Region: region.Region,
+			},
+		}, true
+	case pdpb.CommandType_GetRegionByID:
+		region := cluster.regions.getRegion(req.GetGetRegionById().GetRegionId())
+		if region == nil {
+			return nil, false
+		}
+		return &pdpb.Response{
+			GetRegionById: &pdpb.GetRegionResponse{
+				Region: region.Region,
+			},
+		}, true
+	}
+	return nil, false
+}
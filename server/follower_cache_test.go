@@ -0,0 +1,112 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+)
+
+var _ = Suite(&testFollowerCacheSuite{})
+
+type testFollowerCacheSuite struct {
+	testClusterBaseSuite
+}
+
+func (s *testFollowerCacheSuite) TestFollowerRead(c *C) {
+	cfgs := NewTestMultiConfig(3)
+	for _, cfg := range cfgs {
+		cfg.EnableFollowerRead = true
+	}
+	svrs, cleanup := newMultiTestServersWithCfgs(c, cfgs)
+	defer cleanup()
+
+	leader := mustWaitLeader(c, svrs)
+	s.svr = leader
+	var follower *Server
+	for _, svr := range svrs {
+		if svr != leader {
+			follower = svr
+			break
+		}
+	}
+	c.Assert(leader.followerCache.getCluster(), IsNil)
+
+	conn, err := rpcConnect(leader.GetAddr())
+	c.Assert(err, IsNil)
+	defer conn.Close()
+
+	req := s.newBootstrapRequest(c, leader.clusterID, "127.0.0.1:0")
+	sendRequest(c, conn, 0, req)
+	_, resp := recvResponse(c, conn)
+	c.Assert(resp.GetBootstrap(), NotNil)
+	store := req.GetBootstrap().GetStore()
+	region := req.GetBootstrap().GetRegion()
+
+	getStore := &pdpb.Request{
+		Header:   newRequestHeader(leader.clusterID),
+		CmdType:  pdpb.CommandType_GetStore,
+		GetStore: &pdpb.GetStoreRequest{StoreId: store.GetId()},
+	}
+	getRegion := &pdpb.Request{
+		Header:    newRequestHeader(leader.clusterID),
+		CmdType:   pdpb.CommandType_GetRegion,
+		GetRegion: &pdpb.GetRegionRequest{RegionKey: []byte("a")},
+	}
+	getRegionByID := &pdpb.Request{
+		Header:        newRequestHeader(leader.clusterID),
+		CmdType:       pdpb.CommandType_GetRegionByID,
+		GetRegionById: &pdpb.GetRegionByIDRequest{RegionId: region.GetId()},
+	}
+
+	cache := follower.followerCache
+	for i := 0; i < 100; i++ {
+		if _, ok := cache.handleRequest(getStore); ok {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	resp, ok := cache.handleRequest(getStore)
+	c.Assert(ok, IsTrue)
+	c.Assert(resp.GetGetStore().GetStore(), DeepEquals, store)
+	resp, ok = cache.handleRequest(getRegion)
+	c.Assert(ok, IsTrue)
+	c.Assert(resp.GetGetRegion().GetRegion(), DeepEquals, region)
+	resp, ok = cache.handleRequest(getRegionByID)
+	c.Assert(ok, IsTrue)
+	c.Assert(resp.GetGetRegionById().GetRegion(), DeepEquals, region)
+
+	// Unknown stores are forwarded to leader.
+	_, ok = cache.handleRequest(&pdpb.Request{
+		Header:   newRequestHeader(leader.clusterID),
+		CmdType:  pdpb.CommandType_GetStore,
+		GetStore: &pdpb.GetStoreRequest{StoreId: store.GetId() + 1000},
+	})
+	c.Assert(ok, IsFalse)
+
+	// Stale cache is not used.
+	cache.Lock()
+	cache.lastSync = time.Now().Add(-time.Hour)
+	cache.Unlock()
+	_, ok = cache.handleRequest(getStore)
+	c.Assert(ok, IsFalse)
+
+	// The cache catches up when the leader saves timestamp.
+	time.Sleep(2 * cfgs[0].TsoSaveInterval.Duration)
+	_, ok = cache.handleRequest(getStore)
+	c.Assert(ok, IsTrue)
+}
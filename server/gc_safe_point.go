@@ -0,0 +1,170 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"math"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+)
+
+// ServiceSafePoint is the safe point of a service reading the old data, e.g.
+// backup or CDC. The gc safe point can't go beyond it before it expires.
+type ServiceSafePoint struct {
+	ServiceID string `json:"service_id"`
+	SafePoint uint64 `json:"safe_point"`
+	// ExpiredAt is the unix time in seconds.
+	ExpiredAt int64 `json:"expired_at"`
+}
+
+func (ssp *ServiceSafePoint) isExpired(now time.Time) bool {
+	return now.Unix() >= ssp.ExpiredAt
+}
+
+// GetGCSafePoint returns the GC safe point of the cluster, 0 means it is not
+// set. The data before the safe point may be removed by GC.
+func (s *Server) GetGCSafePoint() (uint64, error) {
+	safePoint, err := s.kv.loadGCSafePoint()
+	return safePoint, errors.Trace(err)
+}
+
+// UpdateGCSafePoint updates the GC safe point, it can't go backward since the
+// data before the old one may be removed already, and it can't go beyond any
+// unexpired service safe point.
+func (s *Server) UpdateGCSafePoint(safePoint uint64) error {
+	if !s.IsLeader() {
+		return errors.New("update gc safe point on non-leader")
+	}
+
+	s.gcSafePointLock.Lock()
+	defer s.gcSafePointLock.Unlock()
+
+	prev, err := s.kv.loadGCSafePoint()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if safePoint < prev {
+		return errors.Errorf("gc safe point %d is less than the previous one %d", safePoint, prev)
+	}
+	if safePoint == prev {
+		return nil
+	}
+	min, err := s.loadMinServiceSafePoint()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if min != nil && safePoint > min.SafePoint {
+		return errors.Errorf("gc safe point %d is greater than the safe point %d of service %s", safePoint, min.SafePoint, min.ServiceID)
+	}
+	if err = s.kv.saveGCSafePoint(prev, safePoint); err != nil {
+		return errors.Trace(err)
+	}
+	log.Infof("gc safe point is updated from %d to %d", prev, safePoint)
+	return nil
+}
+
+// GetServiceSafePoints returns the unexpired service safe points.
+func (s *Server) GetServiceSafePoints() ([]*ServiceSafePoint, error) {
+	ssps, err := s.kv.loadServiceSafePoints()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	now := time.Now()
+	unexpired := ssps[:0]
+	for _, ssp := range ssps {
+		if !ssp.isExpired(now) {
+			unexpired = append(unexpired, ssp)
+		}
+	}
+	return unexpired, nil
+}
+
+// UpdateServiceSafePoint sets the safe point of the service which expires
+// after ttl seconds, or removes it if ttl is not positive. The safe point
+// can't be less than the gc safe point. It returns the min safe point of the
+// unexpired services after updating, which is the max gc safe point allowed,
+// or the gc safe point if there is no service.
+func (s *Server) UpdateServiceSafePoint(serviceID string, ttl int64, safePoint uint64) (uint64, error) {
+	if !s.IsLeader() {
+		return 0, errors.New("update service safe point on non-leader")
+	}
+	if serviceID == "" {
+		return 0, errors.New("service ID is empty")
+	}
+
+	s.gcSafePointLock.Lock()
+	defer s.gcSafePointLock.Unlock()
+
+	gcSafePoint, err := s.kv.loadGCSafePoint()
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if ttl <= 0 {
+		if err = s.kv.deleteServiceSafePoint(serviceID); err != nil {
+			return 0, errors.Trace(err)
+		}
+		log.Infof("safe point of service %s is removed", serviceID)
+	} else {
+		if safePoint < gcSafePoint {
+			return 0, errors.Errorf("safe point %d of service %s is less than the gc safe point %d", safePoint, serviceID, gcSafePoint)
+		}
+		ssp := &ServiceSafePoint{
+			ServiceID: serviceID,
+			SafePoint: safePoint,
+			ExpiredAt: math.MaxInt64,
+		}
+		if now := time.Now().Unix(); ttl < math.MaxInt64-now {
+			ssp.ExpiredAt = now + ttl
+		}
+		if err = s.kv.saveServiceSafePoint(ssp); err != nil {
+			return 0, errors.Trace(err)
+		}
+	}
+
+	min, err := s.loadMinServiceSafePoint()
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if min == nil {
+		return gcSafePoint, nil
+	}
+	return min.SafePoint, nil
+}
+
+// loadMinServiceSafePoint returns the unexpired service safe point which is
+// the min, or nil if there is none. The expired ones are removed.
+func (s *Server) loadMinServiceSafePoint() (*ServiceSafePoint, error) {
+	ssps, err := s.kv.loadServiceSafePoints()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	var min *ServiceSafePoint
+	now := time.Now()
+	for _, ssp := range ssps {
+		if ssp.isExpired(now) {
+			if err = s.kv.deleteServiceSafePoint(ssp.ServiceID); err != nil {
+				return nil, errors.Trace(err)
+			}
+			log.Infof("safe point %d of service %s is expired", ssp.SafePoint, ssp.ServiceID)
+			continue
+		}
+		if min == nil || ssp.SafePoint < min.SafePoint {
+			min = ssp
+		}
+	}
+	return min, nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testGCSafePointSuite{})
+
+type testGCSafePointSuite struct{}
+
+func (s *testGCSafePointSuite) TestGCSafePoint(c *C) {
+	svrs, clean := newMultiTestServers(c, 3)
+	defer clean()
+
+	leader := mustWaitLeader(c, svrs)
+	safePoint, err := leader.GetGCSafePoint()
+	c.Assert(err, IsNil)
+	c.Assert(safePoint, Equals, uint64(0))
+
+	c.Assert(leader.UpdateGCSafePoint(100), IsNil)
+	c.Assert(leader.UpdateGCSafePoint(100), IsNil)
+	c.Assert(leader.UpdateGCSafePoint(99), NotNil)
+
+	// The safe point is kept by the next leader.
+	var follower *Server
+	for _, svr := range svrs {
+		if svr != leader {
+			follower = svr
+		}
+	}
+	c.Assert(follower.UpdateGCSafePoint(200), NotNil)
+	c.Assert(leader.ResignLeader(follower.Name()), IsNil)
+	mustWaitLeaderChanged(c, svrs, follower)
+	leader = follower
+	safePoint, err = leader.GetGCSafePoint()
+	c.Assert(err, IsNil)
+	c.Assert(safePoint, Equals, uint64(100))
+	c.Assert(leader.UpdateGCSafePoint(99), NotNil)
+	c.Assert(leader.UpdateGCSafePoint(200), IsNil)
+}
+
+func (s *testGCSafePointSuite) TestServiceSafePoint(c *C) {
+	svr, cleanup := mustRunTestServer(c)
+	defer cleanup()
+	mustWaitLeader(c, []*Server{svr})
+
+	c.Assert(svr.UpdateGCSafePoint(100), IsNil)
+	// No service, the gc safe point is returned.
+	min, err := svr.UpdateServiceSafePoint("a", 0, 0)
+	c.Assert(err, IsNil)
+	c.Assert(min, Equals, uint64(100))
+
+	_, err = svr.UpdateServiceSafePoint("", 10, 200)
+	c.Assert(err, NotNil)
+	// Service safe point can't be less than the gc safe point.
+	_, err = svr.UpdateServiceSafePoint("a", 10, 99)
+	c.Assert(err, NotNil)
+
+	min, err = svr.UpdateServiceSafePoint("a", 10, 300)
+	c.Assert(err, IsNil)
+	c.Assert(min, Equals, uint64(300))
+	min, err = svr.UpdateServiceSafePoint("b", 10, 200)
+	c.Assert(err, IsNil)
+	c.Assert(min, Equals, uint64(200))
+	ssps, err := svr.GetServiceSafePoints()
+	c.Assert(err, IsNil)
+	c.Assert(ssps, HasLen, 2)
+
+	// The gc safe point can't go beyond the service safe points.
+	c.Assert(svr.UpdateGCSafePoint(201), NotNil)
+	c.Assert(svr.UpdateGCSafePoint(200), IsNil)
+
+	min, err = svr.UpdateServiceSafePoint("b", 0, 0)
+	c.Assert(err, IsNil)
+	c.Assert(min, Equals, uint64(300))
+	c.Assert(svr.UpdateGCSafePoint(300), IsNil)
+
+	// The expired service safe point is ignored.
+	c.Assert(svr.kv.saveServiceSafePoint(&ServiceSafePoint{
+		ServiceID: "a",
+		SafePoint: 300,
+		ExpiredAt: time.Now().Unix() - 1,
+	}), IsNil)
+	ssps, err = svr.GetServiceSafePoints()
+	c.Assert(err, IsNil)
+	c.Assert(ssps, HasLen, 0)
+	c.Assert(svr.UpdateGCSafePoint(400), IsNil)
+	ssps, err = svr.kv.loadServiceSafePoints()
+	c.Assert(err, IsNil)
+	c.Assert(ssps, HasLen, 0)
+}
@@ -13,7 +13,10 @@
 
 package server
 
-import "github.com/juju/errors"
+import (
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
 
 var (
 	errNotBootstrapped = errors.New("TiKV cluster not bootstrapped, please start TiKV first")
@@ -64,6 +67,70 @@ func (h *Handler) RemoveScheduler(name string) error {
 	return errors.Trace(c.removeScheduler(name))
 }
 
+// SchedulerConfig is the running config of a scheduler.
+type SchedulerConfig struct {
+	Name          string `json:"name"`
+	ResourceKind  string `json:"resource_kind"`
+	ResourceLimit uint64 `json:"resource_limit"`
+	Interval      string `json:"interval"`
+	Paused        bool   `json:"paused"`
+}
+
+// GetSchedulerConfig returns the config of a scheduler by name.
+func (h *Handler) GetSchedulerConfig(name string) (*SchedulerConfig, error) {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	s, err := c.getScheduler(name)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	kind := "leader"
+	if s.GetResourceKind() == regionKind {
+		kind = "region"
+	}
+	return &SchedulerConfig{
+		Name:          s.GetName(),
+		ResourceKind:  kind,
+		ResourceLimit: s.GetResourceLimit(),
+		Interval:      s.GetInterval().String(),
+		Paused:        s.IsPaused(),
+	}, nil
+}
+
+// GetSchedulerDiagnosis returns the counts of the schedule results of a
+// scheduler by name, e.g. "no_source_filtered_by_health" if no source store is
+// selected because most stores are unhealthy.
+func (h *Handler) GetSchedulerDiagnosis(name string) (map[string]uint64, error) {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if _, err = c.getScheduler(name); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return getScheduleDiagnosis(name), nil
+}
+
+// PauseScheduler pauses a scheduler by name.
+func (h *Handler) PauseScheduler(name string) error {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(c.pauseScheduler(name))
+}
+
+// ResumeScheduler resumes a paused scheduler by name.
+func (h *Handler) ResumeScheduler(name string) error {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(c.resumeScheduler(name))
+}
+
 // AddBalanceLeaderScheduler adds a balance-leader-scheduler.
 func (h *Handler) AddBalanceLeaderScheduler() error {
 	return h.AddScheduler(newBalanceLeaderScheduler(h.opt))
@@ -83,3 +150,174 @@ func (h *Handler) AddEvictLeaderScheduler(storeID uint64) error {
 func (h *Handler) AddShuffleLeaderScheduler() error {
 	return h.AddScheduler(newShuffleLeaderScheduler(h.opt))
 }
+
+// GetOperator returns the operator of the region.
+func (h *Handler) GetOperator(regionID uint64) (Operator, error) {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	op := c.getOperator(regionID)
+	if op == nil {
+		return nil, errors.Errorf("region %v has no operator", regionID)
+	}
+	return op, nil
+}
+
+// GetOperators returns the running operators.
+func (h *Handler) GetOperators() ([]Operator, error) {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var ops []Operator
+	for _, op := range c.getOperators() {
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// RemoveOperator removes the operator of the region.
+func (h *Handler) RemoveOperator(regionID uint64) error {
+	c, err := h.getCoordinator()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	op := c.getOperator(regionID)
+	if op == nil || !c.removeOperator(op, operatorStatusCancel) {
+		return errors.Errorf("region %v has no operator", regionID)
+	}
+	return nil
+}
+
+// AddTransferLeaderOperator adds an operator to transfer the leader of the
+// region to the store.
+func (h *Handler) AddTransferLeaderOperator(regionID uint64, storeID uint64) error {
+	cluster, region, err := h.getClusterRegion(regionID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	newLeader := region.GetStorePeer(storeID)
+	if newLeader == nil {
+		return errors.Errorf("region %v has no peer in store %v", regionID, storeID)
+	}
+	return errors.Trace(h.addOperator(cluster, newTransferLeader(region, newLeader)))
+}
+
+// AddTransferPeerOperator adds an operator to move a peer of the region
+// from one store to another.
+func (h *Handler) AddTransferPeerOperator(regionID uint64, fromStoreID, toStoreID uint64) error {
+	cluster, region, err := h.getClusterRegion(regionID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	oldPeer := region.GetStorePeer(fromStoreID)
+	if oldPeer == nil {
+		return errors.Errorf("region %v has no peer in store %v", regionID, fromStoreID)
+	}
+	newPeer, err := h.allocPeer(cluster, region, toStoreID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(h.addOperator(cluster, newTransferPeer(region, oldPeer, newPeer)))
+}
+
+// AddAddPeerOperator adds an operator to add a peer of the region to the store.
+func (h *Handler) AddAddPeerOperator(regionID uint64, toStoreID uint64) error {
+	cluster, region, err := h.getClusterRegion(regionID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	newPeer, err := h.allocPeer(cluster, region, toStoreID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(h.addOperator(cluster, newAddPeer(region, newPeer)))
+}
+
+// AddRemovePeerOperator adds an operator to remove the peer of the region
+// in the store.
+func (h *Handler) AddRemovePeerOperator(regionID uint64, fromStoreID uint64) error {
+	cluster, region, err := h.getClusterRegion(regionID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	oldPeer := region.GetStorePeer(fromStoreID)
+	if oldPeer == nil {
+		return errors.Errorf("region %v has no peer in store %v", regionID, fromStoreID)
+	}
+	if oldPeer.GetId() == region.Leader.GetId() {
+		return errors.Errorf("can't remove the leader of region %v, transfer leader first", regionID)
+	}
+	return errors.Trace(h.addOperator(cluster, newRemovePeer(region, oldPeer)))
+}
+
+// AddMoveRegionOperator adds an operator to move the peers of the region to
+// the stores. The leader must be in one of the stores.
+func (h *Handler) AddMoveRegionOperator(regionID uint64, storeIDs []uint64) error {
+	cluster, region, err := h.getClusterRegion(regionID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	targets := make(map[uint64]struct{}, len(storeIDs))
+	var ops []Operator
+	for _, storeID := range storeIDs {
+		targets[storeID] = struct{}{}
+		if region.GetStorePeer(storeID) != nil {
+			continue
+		}
+		newPeer, err := h.allocPeer(cluster, region, storeID)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		ops = append(ops, newAddPeerOperator(regionID, newPeer))
+	}
+	for _, peer := range region.GetPeers() {
+		if _, ok := targets[peer.GetStoreId()]; ok {
+			continue
+		}
+		if peer.GetId() == region.Leader.GetId() {
+			return errors.Errorf("can't remove the leader of region %v, transfer leader first", regionID)
+		}
+		ops = append(ops, newRemovePeerOperator(regionID, peer))
+	}
+	if len(ops) == 0 {
+		return errors.Errorf("region %v is already in stores %v", regionID, storeIDs)
+	}
+	return errors.Trace(h.addOperator(cluster, newRegionOperator(region, ops...)))
+}
+
+func (h *Handler) getClusterRegion(regionID uint64) (*RaftCluster, *regionInfo, error) {
+	cluster := h.s.GetRaftCluster()
+	if cluster == nil {
+		return nil, nil, errors.Trace(errNotBootstrapped)
+	}
+	region := cluster.cachedCluster.getRegion(regionID)
+	if region == nil {
+		return nil, nil, errors.Errorf("region %v not found", regionID)
+	}
+	return cluster, region, nil
+}
+
+func (h *Handler) allocPeer(cluster *RaftCluster, region *regionInfo, storeID uint64) (*metapb.Peer, error) {
+	if region.GetStorePeer(storeID) != nil {
+		return nil, errors.Errorf("region %v already has a peer in store %v", region.GetId(), storeID)
+	}
+	store := cluster.cachedCluster.getStore(storeID)
+	if store == nil {
+		return nil, errors.Trace(errStoreNotFound(storeID))
+	}
+	if !store.isUp() {
+		return nil, errors.Errorf("store %v is not up", storeID)
+	}
+	peer, err := cluster.cachedCluster.allocPeer(storeID)
+	return peer, errors.Trace(err)
+}
+
+func (h *Handler) addOperator(cluster *RaftCluster, op Operator) error {
+	if !cluster.coordinator.addOperator(op, operatorSourceManual) {
+		return errors.Errorf("region %v has an operator already", op.GetRegionID())
+	}
+	return nil
+}
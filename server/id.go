@@ -16,28 +16,72 @@ package server
 import (
 	"path"
 	"sync"
+	"time"
 
 	"github.com/coreos/etcd/clientv3"
 	"github.com/juju/errors"
 )
 
-const (
-	allocStep = uint64(1000)
-)
+// defaultIDAllocator is the name of the allocator reserving the IDs in etcd.
+const defaultIDAllocator = "etcd"
 
 // IDAllocator is the allocator to generate unique ID.
 type IDAllocator interface {
+	// Alloc returns an ID which is never allocated by any server of the
+	// cluster before, including the previous leaders.
 	Alloc() (uint64, error)
+	// Status returns the status of the allocator, Allocator is filled by
+	// the server.
+	Status() (*IDAllocStatus, error)
+}
+
+// IDAllocatorBuilder creates the ID allocator of the server. It is called
+// after the cluster ID is initialized, the allocator can keep its state by
+// the etcd client of the server.
+type IDAllocatorBuilder func(s *Server) (IDAllocator, error)
+
+var idAllocatorBuilders = map[string]IDAllocatorBuilder{
+	defaultIDAllocator: func(s *Server) (IDAllocator, error) {
+		return newIDAllocator(s, s.cfg.IDAllocStep), nil
+	},
 }
 
+// RegisterIDAllocator registers an ID allocator, which is used by setting
+// id-allocator to the name, e.g. an allocator reserving a range for every
+// member. It should be called in init, and panics if the name is registered
+// already.
+func RegisterIDAllocator(name string, builder IDAllocatorBuilder) {
+	if _, ok := idAllocatorBuilders[name]; ok {
+		panic("id allocator " + name + " is registered already")
+	}
+	idAllocatorBuilders[name] = builder
+}
+
+func newIDAllocatorByName(name string, s *Server) (IDAllocator, error) {
+	builder, ok := idAllocatorBuilders[name]
+	if !ok {
+		return nil, errors.Errorf("unknown id allocator %s", name)
+	}
+	alloc, err := builder(s)
+	return alloc, errors.Trace(err)
+}
+
+// idAllocator reserves a batch of step IDs in etcd at a time, and allocates
+// them in memory. A larger step makes fewer etcd writes, and more IDs are
+// skipped when the leader changes.
 type idAllocator struct {
 	mu   sync.Mutex
 	base uint64
 	end  uint64
+	step uint64
 
 	s *Server
 }
 
+func newIDAllocator(s *Server, step uint64) *idAllocator {
+	return &idAllocator{s: s, step: step}
+}
+
 func (alloc *idAllocator) Alloc() (uint64, error) {
 	alloc.mu.Lock()
 	defer alloc.mu.Unlock()
@@ -49,15 +93,21 @@ func (alloc *idAllocator) Alloc() (uint64, error) {
 		}
 
 		alloc.end = end
-		alloc.base = alloc.end - allocStep
+		alloc.base = alloc.end - alloc.step
 	}
 
 	alloc.base++
+	idAllocCounter.Inc()
 
 	return alloc.base, nil
 }
 
 func (alloc *idAllocator) generate() (uint64, error) {
+	start := time.Now()
+	defer func() {
+		idGenerateDuration.Observe(time.Since(start).Seconds())
+	}()
+
 	key := alloc.s.getAllocIDPath()
 	value, err := getValue(alloc.s.client, key)
 	if err != nil {
@@ -82,7 +132,7 @@ func (alloc *idAllocator) generate() (uint64, error) {
 		cmp = clientv3.Compare(clientv3.Value(key), "=", string(value))
 	}
 
-	end += allocStep
+	end += alloc.step
 	value = uint64ToBytes(end)
 	resp, err := alloc.s.leaderTxn(cmp).Then(clientv3.OpPut(key, string(value))).Commit()
 	if err != nil {
@@ -95,6 +145,53 @@ func (alloc *idAllocator) generate() (uint64, error) {
 	return end, nil
 }
 
+// lastID returns the last allocated ID, 0 if no ID is allocated.
+func (alloc *idAllocator) lastID() uint64 {
+	alloc.mu.Lock()
+	defer alloc.mu.Unlock()
+	return alloc.base
+}
+
+func (alloc *idAllocator) Status() (*IDAllocStatus, error) {
+	status := &IDAllocStatus{
+		Step: alloc.step,
+		Last: alloc.lastID(),
+	}
+	value, err := getValue(alloc.s.client, alloc.s.getAllocIDPath())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if value != nil {
+		if status.End, err = bytesToUint64(value); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return status, nil
+}
+
+// IDAllocStatus is the status of the ID allocator.
+type IDAllocStatus struct {
+	Allocator string `json:"allocator"`
+	// Step is the number of IDs reserved at a time.
+	Step uint64 `json:"step"`
+	// Last is the last ID allocated by the server, 0 if the server allocates
+	// no ID since it starts.
+	Last uint64 `json:"last"`
+	// End is the end of the reserved IDs, the IDs after it are never
+	// allocated.
+	End uint64 `json:"end"`
+}
+
+// GetIDAllocStatus returns the status of the ID allocator.
+func (s *Server) GetIDAllocStatus() (*IDAllocStatus, error) {
+	status, err := s.idAlloc.Status()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	status.Allocator = s.cfg.IDAllocator
+	return status, nil
+}
+
 func (s *Server) getAllocIDPath() string {
 	return path.Join(s.rootPath, "alloc_id")
 }
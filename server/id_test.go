@@ -34,7 +34,7 @@ type testAllocIDSuite struct {
 func (s *testAllocIDSuite) SetUpSuite(c *C) {
 	s.svr, s.cleanup = newTestServer(c)
 	s.client = s.svr.client
-	s.alloc = s.svr.idAlloc
+	s.alloc = s.svr.idAlloc.(*idAllocator)
 
 	go s.svr.Run()
 }
@@ -47,7 +47,7 @@ func (s *testAllocIDSuite) TestID(c *C) {
 	mustGetLeader(c, s.client, s.svr.getLeaderPath())
 
 	var last uint64
-	for i := uint64(0); i < allocStep; i++ {
+	for i := uint64(0); i < defaultIDAllocStep; i++ {
 		id, err := s.alloc.Alloc()
 		c.Assert(err, IsNil)
 		c.Assert(id, Greater, last)
@@ -95,7 +95,7 @@ func (s *testAllocIDSuite) TestCommand(c *C) {
 	}
 
 	var last uint64
-	for i := uint64(0); i < 2*allocStep; i++ {
+	for i := uint64(0); i < 2*defaultIDAllocStep; i++ {
 		rawMsgID := uint64(rand.Int63())
 		sendRequest(c, conn, rawMsgID, req)
 		msgID, resp := recvResponse(c, conn)
@@ -105,3 +105,54 @@ func (s *testAllocIDSuite) TestCommand(c *C) {
 		last = resp.AllocId.GetId()
 	}
 }
+
+func (s *testAllocIDSuite) TestStep(c *C) {
+	mustGetLeader(c, s.client, s.svr.getLeaderPath())
+
+	status, err := s.svr.GetIDAllocStatus()
+	c.Assert(err, IsNil)
+	c.Assert(status.Allocator, Equals, defaultIDAllocator)
+	c.Assert(status.Step, Equals, defaultIDAllocStep)
+
+	alloc := newIDAllocator(s.svr, 10)
+	var last uint64
+	for i := 0; i < 11; i++ {
+		last, err = alloc.Alloc()
+		c.Assert(err, IsNil)
+	}
+	// The IDs are reserved twice.
+	c.Assert(last, Equals, status.End+11)
+	c.Assert(alloc.lastID(), Equals, last)
+
+	status, err = s.svr.GetIDAllocStatus()
+	c.Assert(err, IsNil)
+	c.Assert(status.End, Equals, last+9)
+}
+
+func (s *testAllocIDSuite) TestRegister(c *C) {
+	RegisterIDAllocator("mock", func(*Server) (IDAllocator, error) {
+		return NewMockIDAllocator(), nil
+	})
+	c.Assert(func() { RegisterIDAllocator("mock", nil) }, PanicMatches, ".*registered already")
+
+	cfg := NewTestSingleConfig()
+	defer cleanServer(cfg)
+	cfg.IDAllocator = "unknown"
+	c.Assert(cfg.adjust(), NotNil)
+	cfg.IDAllocator = "mock"
+	c.Assert(cfg.adjust(), IsNil)
+
+	svr, err := NewServer(cfg)
+	c.Assert(err, IsNil)
+	defer svr.Close()
+	go svr.Run()
+	mustWaitLeader(c, []*Server{svr})
+
+	id, err := svr.idAlloc.Alloc()
+	c.Assert(err, IsNil)
+	c.Assert(id, Equals, uint64(1))
+	status, err := svr.GetIDAllocStatus()
+	c.Assert(err, IsNil)
+	c.Assert(status.Allocator, Equals, "mock")
+	c.Assert(status.Last, Equals, uint64(1))
+}
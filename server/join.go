@@ -24,13 +24,17 @@ import (
 	"github.com/pingcap/pd/pkg/etcdutil"
 )
 
-// TODO: support HTTPS
-func genClientV3Config(cfg *Config) clientv3.Config {
+func genClientV3Config(cfg *Config) (clientv3.Config, error) {
 	endpoints := strings.Split(cfg.Join, ",")
+	tlsCfg, err := cfg.Security.ToClientTLSConfig()
+	if err != nil {
+		return clientv3.Config{}, errors.Trace(err)
+	}
 	return clientv3.Config{
 		Endpoints:   endpoints,
 		DialTimeout: etcdutil.DefaultDialTimeout,
-	}
+		TLS:         tlsCfg,
+	}, nil
 }
 
 // prepareJoinCluster sends MemberAdd command to PD cluster,
@@ -72,7 +76,7 @@ func genClientV3Config(cfg *Config) clientv3.Config {
 //                      is fine.)
 func prepareJoinCluster(cfg *Config) (string, string, error) {
 	// - A PD tries to join itself.
-	if cfg.Join == cfg.AdvertiseClientUrls {
+	if isJoinSelf(cfg) {
 		return "", "", errors.New("join self is forbidden")
 	}
 
@@ -85,7 +89,11 @@ func prepareJoinCluster(cfg *Config) (string, string, error) {
 
 	// Below are cases without data directory.
 
-	client, err := clientv3.New(genClientV3Config(cfg))
+	clientCfg, err := genClientV3Config(cfg)
+	if err != nil {
+		return "", "", errors.Trace(err)
+	}
+	client, err := clientv3.New(clientCfg)
 	if err != nil {
 		return "", "", errors.Trace(err)
 	}
@@ -110,7 +118,7 @@ func prepareJoinCluster(cfg *Config) (string, string, error) {
 
 	// - A new PD joins an existing cluster.
 	// - A deleted PD joins to previous cluster.
-	addResp, err := etcdutil.AddEtcdMember(client, []string{cfg.AdvertisePeerUrls})
+	addResp, err := etcdutil.AddEtcdMember(client, strings.Split(cfg.AdvertisePeerUrls, ","))
 	if err != nil {
 		return "", "", errors.Trace(err)
 	}
@@ -134,3 +142,16 @@ func prepareJoinCluster(cfg *Config) (string, string, error) {
 
 	return initialCluster, embed.ClusterStateFlagExisting, nil
 }
+
+// isJoinSelf returns true if any join endpoint is an advertise client url of
+// the PD itself.
+func isJoinSelf(cfg *Config) bool {
+	for _, join := range strings.Split(cfg.Join, ",") {
+		for _, u := range strings.Split(cfg.AdvertiseClientUrls, ",") {
+			if strings.TrimSpace(join) == strings.TrimSpace(u) {
+				return true
+			}
+		}
+	}
+	return false
+}
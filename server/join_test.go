@@ -17,11 +17,13 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/juju/errors"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/pd/pkg/etcdutil"
+	"github.com/pingcap/pd/pkg/testutil"
 	"golang.org/x/net/context"
 )
 
@@ -213,6 +215,12 @@ func (s *testJoinServerSuite) TestPDJoinsItself(c *C) {
 
 	_, err := startPdWith(cfg)
 	c.Assert(err, NotNil)
+
+	// One of the join endpoints is itself.
+	cfg.Join = strings.Join([]string{testutil.UnixURL(), cfg.AdvertiseClientUrls}, ",")
+	c.Assert(isJoinSelf(cfg), IsTrue)
+	cfg.Join = testutil.UnixURL()
+	c.Assert(isJoinSelf(cfg), IsFalse)
 }
 
 // A failed PD re-joins the previous cluster.
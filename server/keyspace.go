@@ -0,0 +1,174 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/hex"
+	"regexp"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+)
+
+// The states of a keyspace. A keyspace is enabled after creating, it can be
+// disabled and enabled again, and a disabled keyspace can be archived, which
+// is the final state.
+const (
+	KeyspaceEnabled  = "enabled"
+	KeyspaceDisabled = "disabled"
+	KeyspaceArchived = "archived"
+)
+
+const (
+	// keyspacePrefixMode is the first byte of the key prefix of keyspaces,
+	// the ID follows in 3 bytes.
+	keyspacePrefixMode = 'x'
+	maxKeyspaceID      = 1<<24 - 1
+)
+
+var (
+	// ErrKeyspaceNotFound is returned when getting or changing a keyspace
+	// which does not exist.
+	ErrKeyspaceNotFound = errors.New("keyspace is not found")
+	// ErrKeyspaceExists is returned when creating a keyspace which exists.
+	ErrKeyspaceExists = errors.New("keyspace exists")
+
+	keyspaceNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_\-]{1,64}$`)
+)
+
+// keyspaceStateTransitions are the states a keyspace can change to from
+// each state.
+var keyspaceStateTransitions = map[string][]string{
+	KeyspaceEnabled:  {KeyspaceDisabled},
+	KeyspaceDisabled: {KeyspaceEnabled, KeyspaceArchived},
+	KeyspaceArchived: {},
+}
+
+// Keyspace is a logical tenant of the cluster, the keys of a keyspace are
+// prefixed by the key prefix allocated with its ID. The IDs are never
+// reused, the archived keyspaces are kept.
+type Keyspace struct {
+	ID   uint32 `json:"id"`
+	Name string `json:"name"`
+	// Prefix is the key prefix in hex.
+	Prefix string `json:"prefix"`
+	State  string `json:"state"`
+	// CreatedAt and StateChangedAt are the unix time in seconds.
+	CreatedAt      int64 `json:"created_at"`
+	StateChangedAt int64 `json:"state_changed_at"`
+}
+
+func keyspacePrefix(id uint32) []byte {
+	return []byte{keyspacePrefixMode, byte(id >> 16), byte(id >> 8), byte(id)}
+}
+
+func canChangeKeyspaceState(from, to string) bool {
+	for _, state := range keyspaceStateTransitions[from] {
+		if state == to {
+			return true
+		}
+	}
+	return false
+}
+
+// GetKeyspaces returns the keyspaces ordered by name.
+func (s *Server) GetKeyspaces() ([]*Keyspace, error) {
+	keyspaces, err := s.kv.loadKeyspaces()
+	return keyspaces, errors.Trace(err)
+}
+
+// GetKeyspace returns the keyspace by name.
+func (s *Server) GetKeyspace(name string) (*Keyspace, error) {
+	keyspace, err := s.kv.loadKeyspace(name)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if keyspace == nil {
+		return nil, errors.Trace(ErrKeyspaceNotFound)
+	}
+	return keyspace, nil
+}
+
+// CreateKeyspace creates an enabled keyspace with a new ID.
+func (s *Server) CreateKeyspace(name string) (*Keyspace, error) {
+	if !keyspaceNameRegexp.MatchString(name) {
+		return nil, errors.Errorf("invalid keyspace name %q", name)
+	}
+	if !s.IsLeader() {
+		return nil, errors.New("create keyspace on non-leader")
+	}
+
+	s.keyspaceLock.Lock()
+	defer s.keyspaceLock.Unlock()
+
+	prevID, err := s.kv.loadKeyspaceAllocID()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if prevID >= maxKeyspaceID {
+		return nil, errors.Errorf("keyspace ID is exhausted")
+	}
+	id := uint32(prevID + 1)
+	now := time.Now().Unix()
+	keyspace := &Keyspace{
+		ID:             id,
+		Name:           name,
+		Prefix:         hex.EncodeToString(keyspacePrefix(id)),
+		State:          KeyspaceEnabled,
+		CreatedAt:      now,
+		StateChangedAt: now,
+	}
+	created, err := s.kv.createKeyspace(prevID, keyspace)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if !created {
+		return nil, errors.Trace(ErrKeyspaceExists)
+	}
+	log.Infof("keyspace %s is created with ID %d", name, id)
+	return keyspace, nil
+}
+
+// UpdateKeyspaceState changes the state of the keyspace, it does nothing if
+// the keyspace is in the state already.
+func (s *Server) UpdateKeyspaceState(name string, state string) (*Keyspace, error) {
+	if _, ok := keyspaceStateTransitions[state]; !ok {
+		return nil, errors.Errorf("invalid keyspace state %q", state)
+	}
+	if !s.IsLeader() {
+		return nil, errors.New("update keyspace on non-leader")
+	}
+
+	s.keyspaceLock.Lock()
+	defer s.keyspaceLock.Unlock()
+
+	keyspace, err := s.GetKeyspace(name)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if keyspace.State == state {
+		return keyspace, nil
+	}
+	if !canChangeKeyspaceState(keyspace.State, state) {
+		return nil, errors.Errorf("keyspace %s can't change from %s to %s", name, keyspace.State, state)
+	}
+	prev := keyspace.State
+	keyspace.State, keyspace.StateChangedAt = state, time.Now().Unix()
+	if err = s.kv.saveKeyspace(keyspace); err != nil {
+		return nil, errors.Trace(err)
+	}
+	log.Infof("keyspace %s is changed from %s to %s", name, prev, state)
+	return keyspace, nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"github.com/juju/errors"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testKeyspaceSuite{})
+
+type testKeyspaceSuite struct{}
+
+func (s *testKeyspaceSuite) TestKeyspacePrefix(c *C) {
+	c.Assert(keyspacePrefix(1), DeepEquals, []byte{'x', 0, 0, 1})
+	c.Assert(keyspacePrefix(0x123456), DeepEquals, []byte{'x', 0x12, 0x34, 0x56})
+}
+
+func (s *testKeyspaceSuite) TestKeyspaceState(c *C) {
+	c.Assert(canChangeKeyspaceState(KeyspaceEnabled, KeyspaceDisabled), IsTrue)
+	c.Assert(canChangeKeyspaceState(KeyspaceEnabled, KeyspaceArchived), IsFalse)
+	c.Assert(canChangeKeyspaceState(KeyspaceDisabled, KeyspaceEnabled), IsTrue)
+	c.Assert(canChangeKeyspaceState(KeyspaceDisabled, KeyspaceArchived), IsTrue)
+	c.Assert(canChangeKeyspaceState(KeyspaceArchived, KeyspaceEnabled), IsFalse)
+	c.Assert(canChangeKeyspaceState(KeyspaceArchived, KeyspaceDisabled), IsFalse)
+}
+
+func (s *testKeyspaceSuite) TestManageKeyspaces(c *C) {
+	svr, cleanup := mustRunTestServer(c)
+	defer cleanup()
+	mustWaitLeader(c, []*Server{svr})
+
+	_, err := svr.CreateKeyspace("")
+	c.Assert(err, NotNil)
+	_, err = svr.CreateKeyspace("ks/1")
+	c.Assert(err, NotNil)
+	ks1, err := svr.CreateKeyspace("ks1")
+	c.Assert(err, IsNil)
+	c.Assert(ks1.ID, Equals, uint32(1))
+	c.Assert(ks1.Prefix, Equals, "78000001")
+	c.Assert(ks1.State, Equals, KeyspaceEnabled)
+	_, err = svr.CreateKeyspace("ks1")
+	c.Assert(errors.Cause(err), Equals, ErrKeyspaceExists)
+	ks2, err := svr.CreateKeyspace("ks2")
+	c.Assert(err, IsNil)
+	c.Assert(ks2.ID, Equals, uint32(2))
+
+	keyspaces, err := svr.GetKeyspaces()
+	c.Assert(err, IsNil)
+	c.Assert(keyspaces, DeepEquals, []*Keyspace{ks1, ks2})
+	_, err = svr.GetKeyspace("ks3")
+	c.Assert(errors.Cause(err), Equals, ErrKeyspaceNotFound)
+
+	_, err = svr.UpdateKeyspaceState("ks1", "unknown")
+	c.Assert(err, NotNil)
+	_, err = svr.UpdateKeyspaceState("ks1", KeyspaceArchived)
+	c.Assert(err, NotNil)
+	_, err = svr.UpdateKeyspaceState("ks3", KeyspaceDisabled)
+	c.Assert(errors.Cause(err), Equals, ErrKeyspaceNotFound)
+	ks1, err = svr.UpdateKeyspaceState("ks1", KeyspaceDisabled)
+	c.Assert(err, IsNil)
+	c.Assert(ks1.State, Equals, KeyspaceDisabled)
+	ks1, err = svr.UpdateKeyspaceState("ks1", KeyspaceArchived)
+	c.Assert(err, IsNil)
+	c.Assert(ks1.State, Equals, KeyspaceArchived)
+	_, err = svr.UpdateKeyspaceState("ks1", KeyspaceEnabled)
+	c.Assert(err, NotNil)
+	keyspace, err := svr.GetKeyspace("ks1")
+	c.Assert(err, IsNil)
+	c.Assert(keyspace, DeepEquals, ks1)
+
+	// The IDs of the archived keyspaces are not reused.
+	ks3, err := svr.CreateKeyspace("ks3")
+	c.Assert(err, IsNil)
+	c.Assert(ks3.ID, Equals, uint32(3))
+}
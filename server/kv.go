@@ -14,9 +14,11 @@
 package server
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
 	"path"
+	"strconv"
 	"time"
 
 	"github.com/coreos/etcd/clientv3"
@@ -37,21 +39,41 @@ var (
 	errTxnFailed = errors.New("failed to commit transaction")
 )
 
-// kv wraps all kv operations, keep it stateless.
+// kv wraps all kv operations, keep it stateless. The metadata is saved in
+// the base storage, except that the regions are saved in the region storage,
+// which is etcd too unless region-storage is local. The conditional writes
+// need etcd and use the txn directly.
 type kv struct {
-	s           *Server
-	client      *clientv3.Client
-	clusterPath string
+	s             *Server
+	client        *clientv3.Client
+	clusterPath   string
+	base          Storage
+	regionStorage Storage
 }
 
 func newKV(s *Server) *kv {
+	base := newEtcdStorage(s.client, s.leaderTxn)
 	return &kv{
-		s:           s,
-		client:      s.client,
-		clusterPath: path.Join(s.rootPath, "raft"),
+		s:             s,
+		client:        s.client,
+		clusterPath:   path.Join(s.rootPath, "raft"),
+		base:          base,
+		regionStorage: base,
 	}
 }
 
+// setRegionStorage saves the regions in the storage instead of etcd.
+func (kv *kv) setRegionStorage(storage Storage) {
+	kv.regionStorage = storage
+}
+
+func (kv *kv) close() error {
+	if kv.regionStorage != kv.base {
+		return errors.Trace(kv.regionStorage.Close())
+	}
+	return nil
+}
+
 func (kv *kv) txn(cs ...clientv3.Cmp) clientv3.Txn { return kv.s.leaderTxn(cs...) }
 
 func (kv *kv) storePath(storeID uint64) string {
@@ -62,46 +84,228 @@ func (kv *kv) regionPath(regionID uint64) string {
 	return path.Join(kv.clusterPath, "r", fmt.Sprintf("%020d", regionID))
 }
 
+func (kv *kv) externalTimestampPath() string {
+	return path.Join(kv.s.rootPath, "external_timestamp")
+}
+
+func (kv *kv) gcSafePointPath() string {
+	return path.Join(kv.s.rootPath, "gc", "safe_point")
+}
+
+func (kv *kv) serviceSafePointPath(serviceID string) string {
+	return path.Join(kv.gcSafePointPath(), "service", serviceID)
+}
+
+func (kv *kv) namespacePath(name string) string {
+	return path.Join(kv.s.rootPath, "namespace", name)
+}
+
+func (kv *kv) keyspacePath(name string) string {
+	return path.Join(kv.s.rootPath, "keyspace", "meta", name)
+}
+
+func (kv *kv) keyspaceAllocIDPath() string {
+	return path.Join(kv.s.rootPath, "keyspace", "alloc_id")
+}
+
+func (kv *kv) encryptionKeysPath() string {
+	return path.Join(kv.s.rootPath, "encryption", "keys")
+}
+
+// encryptionKeyPath orders the data keys by ID.
+func (kv *kv) encryptionKeyPath(id uint64) string {
+	return path.Join(kv.encryptionKeysPath(), fmt.Sprintf("%020d", id))
+}
+
+func (kv *kv) currentEncryptionKeyPath() string {
+	return path.Join(kv.s.rootPath, "encryption", "current")
+}
+
+func (kv *kv) userPath(name string) string {
+	return path.Join(kv.s.rootPath, "rbac", "users", name)
+}
+
+func (kv *kv) webhookPath(name string) string {
+	return path.Join(kv.s.rootPath, "webhook", name)
+}
+
+func (kv *kv) configPath() string {
+	return path.Join(kv.s.rootPath, "config")
+}
+
+func (kv *kv) configVersionPath(version uint64) string {
+	return path.Join(kv.configPath(), fmt.Sprintf("%020d", version))
+}
+
+func (kv *kv) featureGatePath(name string) string {
+	return path.Join(kv.s.rootPath, "feature_gate", name)
+}
+
+// operatorRecordPath orders the records by the end time.
+func (kv *kv) operatorRecordPath(end time.Time, regionID uint64) string {
+	return path.Join(kv.clusterPath, "h", fmt.Sprintf("%020d_%020d", end.UnixNano(), regionID))
+}
+
 func (kv *kv) loadMeta(meta *metapb.Cluster) (bool, error) {
-	return kv.loadProto(kv.clusterPath, meta)
+	return loadProto(kv.base, kv.clusterPath, meta)
 }
 
 func (kv *kv) saveMeta(meta *metapb.Cluster) error {
-	return kv.saveProto(kv.clusterPath, meta)
+	return saveProto(kv.base, kv.clusterPath, meta)
 }
 
 func (kv *kv) loadStore(storeID uint64, store *metapb.Store) (bool, error) {
-	return kv.loadProto(kv.storePath(storeID), store)
+	return loadProto(kv.base, kv.storePath(storeID), store)
 }
 
 func (kv *kv) saveStore(store *metapb.Store) error {
-	return kv.saveProto(kv.storePath(store.GetId()), store)
+	return saveProto(kv.base, kv.storePath(store.GetId()), store)
+}
+
+// saveStores saves the stores in one batch.
+func (kv *kv) saveStores(stores []*metapb.Store) error {
+	kvs := make(map[string]string, len(stores))
+	for _, store := range stores {
+		value, err := proto.Marshal(store)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		kvs[kv.storePath(store.GetId())] = string(value)
+	}
+	return errors.Trace(kv.base.SaveBatch(kvs))
+}
+
+// loadExternalTimestamp returns 0 if the external timestamp is not set.
+func (kv *kv) loadExternalTimestamp() (uint64, error) {
+	value, err := kv.load(kv.externalTimestampPath())
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if value == nil {
+		return 0, nil
+	}
+	ts, err := bytesToUint64(value)
+	return ts, errors.Trace(err)
+}
+
+// saveExternalTimestamp saves the external timestamp only if the saved one is still prev.
+func (kv *kv) saveExternalTimestamp(prev, ts uint64) error {
+	key := kv.externalTimestampPath()
+	cmp := clientv3.Compare(clientv3.CreateRevision(key), "=", 0)
+	if prev != 0 {
+		cmp = clientv3.Compare(clientv3.Value(key), "=", string(uint64ToBytes(prev)))
+	}
+	resp, err := kv.txn(cmp).Then(clientv3.OpPut(key, string(uint64ToBytes(ts)))).Commit()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !resp.Succeeded {
+		return errors.Trace(errTxnFailed)
+	}
+	return nil
+}
+
+// loadGCSafePoint returns 0 if the GC safe point is not set.
+func (kv *kv) loadGCSafePoint() (uint64, error) {
+	value, err := kv.load(kv.gcSafePointPath())
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if value == nil {
+		return 0, nil
+	}
+	safePoint, err := bytesToUint64(value)
+	return safePoint, errors.Trace(err)
+}
+
+// saveGCSafePoint saves the GC safe point only if the saved one is still prev.
+func (kv *kv) saveGCSafePoint(prev, safePoint uint64) error {
+	key := kv.gcSafePointPath()
+	cmp := clientv3.Compare(clientv3.CreateRevision(key), "=", 0)
+	if prev != 0 {
+		cmp = clientv3.Compare(clientv3.Value(key), "=", string(uint64ToBytes(prev)))
+	}
+	resp, err := kv.txn(cmp).Then(clientv3.OpPut(key, string(uint64ToBytes(safePoint)))).Commit()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !resp.Succeeded {
+		return errors.Trace(errTxnFailed)
+	}
+	return nil
+}
+
+func (kv *kv) saveServiceSafePoint(ssp *ServiceSafePoint) error {
+	value, err := json.Marshal(ssp)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return kv.save(kv.serviceSafePointPath(ssp.ServiceID), string(value))
+}
+
+func (kv *kv) loadServiceSafePoints() ([]*ServiceSafePoint, error) {
+	resp, err := kvGet(kv.client, kv.serviceSafePointPath("")+"/", clientv3.WithPrefix())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	ssps := make([]*ServiceSafePoint, 0, len(resp.Kvs))
+	for _, item := range resp.Kvs {
+		ssp := &ServiceSafePoint{}
+		if err := json.Unmarshal(item.Value, ssp); err != nil {
+			return nil, errors.Trace(err)
+		}
+		ssps = append(ssps, ssp)
+	}
+	return ssps, nil
+}
+
+func (kv *kv) deleteServiceSafePoint(serviceID string) error {
+	resp, err := kv.txn().Then(clientv3.OpDelete(kv.serviceSafePointPath(serviceID))).Commit()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !resp.Succeeded {
+		return errors.Trace(errTxnFailed)
+	}
+	return nil
 }
 
 func (kv *kv) loadRegion(regionID uint64, region *metapb.Region) (bool, error) {
-	return kv.loadProto(kv.regionPath(regionID), region)
+	return loadProto(kv.regionStorage, kv.regionPath(regionID), region)
 }
 
 func (kv *kv) saveRegion(region *metapb.Region) error {
-	return kv.saveProto(kv.regionPath(region.GetId()), region)
+	return saveProto(kv.regionStorage, kv.regionPath(region.GetId()), region)
+}
+
+// saveRegions saves the regions in one batch.
+func (kv *kv) saveRegions(regions []*metapb.Region) error {
+	kvs := make(map[string]string, len(regions))
+	for _, region := range regions {
+		value, err := proto.Marshal(region)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		kvs[kv.regionPath(region.GetId())] = string(value)
+	}
+	return errors.Trace(kv.regionStorage.SaveBatch(kvs))
 }
 
 func (kv *kv) loadStores(stores *storesInfo, rangeLimit int64) error {
 	nextID := uint64(0)
 	endStore := kv.storePath(math.MaxUint64)
-	withRange := clientv3.WithRange(endStore)
-	withLimit := clientv3.WithLimit(rangeLimit)
 
 	for {
 		key := kv.storePath(nextID)
-		resp, err := kvGet(kv.client, key, withRange, withLimit)
+		_, values, err := kv.base.LoadRange(key, endStore, int(rangeLimit))
 		if err != nil {
 			return errors.Trace(err)
 		}
 
-		for _, item := range resp.Kvs {
+		for _, value := range values {
 			store := &metapb.Store{}
-			if err := store.Unmarshal(item.Value); err != nil {
+			if err := store.Unmarshal(value); err != nil {
 				return errors.Trace(err)
 			}
 
@@ -109,7 +313,7 @@ func (kv *kv) loadStores(stores *storesInfo, rangeLimit int64) error {
 			stores.setStore(newStoreInfo(store))
 		}
 
-		if len(resp.Kvs) < int(rangeLimit) {
+		if len(values) < int(rangeLimit) {
 			return nil
 		}
 	}
@@ -118,19 +322,17 @@ func (kv *kv) loadStores(stores *storesInfo, rangeLimit int64) error {
 func (kv *kv) loadRegions(regions *regionsInfo, rangeLimit int64) error {
 	nextID := uint64(0)
 	endRegion := kv.regionPath(math.MaxUint64)
-	withRange := clientv3.WithRange(endRegion)
-	withLimit := clientv3.WithLimit(rangeLimit)
 
 	for {
 		key := kv.regionPath(nextID)
-		resp, err := kvGet(kv.client, key, withRange, withLimit)
+		_, values, err := kv.regionStorage.LoadRange(key, endRegion, int(rangeLimit))
 		if err != nil {
 			return errors.Trace(err)
 		}
 
-		for _, item := range resp.Kvs {
+		for _, value := range values {
 			region := &metapb.Region{}
-			if err := region.Unmarshal(item.Value); err != nil {
+			if err := region.Unmarshal(value); err != nil {
 				return errors.Trace(err)
 			}
 
@@ -138,46 +340,367 @@ func (kv *kv) loadRegions(regions *regionsInfo, rangeLimit int64) error {
 			regions.setRegion(newRegionInfo(region, nil))
 		}
 
-		if len(resp.Kvs) < int(rangeLimit) {
+		if len(values) < int(rangeLimit) {
 			return nil
 		}
 	}
 }
 
-func (kv *kv) loadProto(key string, msg proto.Message) (bool, error) {
-	value, err := kv.load(key)
+func (kv *kv) saveOperatorRecord(record *OperatorRecord) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return kv.save(kv.operatorRecordPath(record.End, record.RegionID), string(value))
+}
+
+// loadOperatorRecords loads at most limit records which end in [start, end),
+// only the records of the region are returned if regionID is not 0.
+func (kv *kv) loadOperatorRecords(regionID uint64, start, end time.Time, limit int) ([]*OperatorRecord, error) {
+	var records []*OperatorRecord
+	key := kv.operatorRecordPath(start, 0)
+	endKey := kv.operatorRecordPath(end, 0)
+
+	for {
+		keys, values, err := kv.base.LoadRange(key, endKey, kvRangeLimit)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+
+		for i, value := range values {
+			record := &OperatorRecord{}
+			if err := json.Unmarshal(value, record); err != nil {
+				return nil, errors.Trace(err)
+			}
+
+			key = keys[i] + "\x00"
+			if regionID != 0 && record.RegionID != regionID {
+				continue
+			}
+			records = append(records, record)
+			if len(records) >= limit {
+				return records, nil
+			}
+		}
+
+		if len(values) < kvRangeLimit {
+			return records, nil
+		}
+	}
+}
+
+// deleteOperatorRecords deletes the records which end before the deadline.
+func (kv *kv) deleteOperatorRecords(deadline time.Time) error {
+	key := kv.operatorRecordPath(time.Unix(0, 0), 0)
+	withRange := clientv3.WithRange(kv.operatorRecordPath(deadline, 0))
+	resp, err := kv.txn().Then(clientv3.OpDelete(key, withRange)).Commit()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !resp.Succeeded {
+		return errors.Trace(errTxnFailed)
+	}
+	return nil
+}
+
+func (kv *kv) saveWebhook(hook *Webhook) error {
+	value, err := json.Marshal(hook)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return kv.save(kv.webhookPath(hook.Name), string(value))
+}
+
+func (kv *kv) loadWebhooks() ([]*Webhook, error) {
+	resp, err := kvGet(kv.client, kv.webhookPath("")+"/", clientv3.WithPrefix())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	hooks := make([]*Webhook, 0, len(resp.Kvs))
+	for _, item := range resp.Kvs {
+		hook := &Webhook{}
+		if err := json.Unmarshal(item.Value, hook); err != nil {
+			return nil, errors.Trace(err)
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, nil
+}
+
+// deleteWebhook returns false if the webhook does not exist.
+func (kv *kv) deleteWebhook(name string) (bool, error) {
+	resp, err := kv.txn().Then(clientv3.OpDelete(kv.webhookPath(name))).Commit()
 	if err != nil {
 		return false, errors.Trace(err)
 	}
+	if !resp.Succeeded {
+		return false, errors.Trace(errTxnFailed)
+	}
+	return resp.Responses[0].GetResponseDeleteRange().Deleted > 0, nil
+}
+
+func (kv *kv) saveNamespace(ns *Namespace) error {
+	value, err := json.Marshal(ns)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return kv.save(kv.namespacePath(ns.Name), string(value))
+}
+
+// loadNamespaces returns the namespaces ordered by name.
+func (kv *kv) loadNamespaces() ([]*Namespace, error) {
+	resp, err := kvGet(kv.client, kv.namespacePath("")+"/", clientv3.WithPrefix())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	namespaces := make([]*Namespace, 0, len(resp.Kvs))
+	for _, item := range resp.Kvs {
+		ns := &Namespace{}
+		if err := json.Unmarshal(item.Value, ns); err != nil {
+			return nil, errors.Trace(err)
+		}
+		namespaces = append(namespaces, ns)
+	}
+	return namespaces, nil
+}
+
+func (kv *kv) deleteNamespace(name string) error {
+	resp, err := kv.txn().Then(clientv3.OpDelete(kv.namespacePath(name))).Commit()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !resp.Succeeded {
+		return errors.Trace(errTxnFailed)
+	}
+	return nil
+}
+
+// loadKeyspaceAllocID returns the last allocated keyspace ID, 0 if none.
+func (kv *kv) loadKeyspaceAllocID() (uint64, error) {
+	value, err := kv.load(kv.keyspaceAllocIDPath())
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
 	if value == nil {
+		return 0, nil
+	}
+	id, err := bytesToUint64(value)
+	return id, errors.Trace(err)
+}
+
+// createKeyspace saves the keyspace and its ID as the last allocated one,
+// only if the last allocated ID is still prevID. It returns false if the
+// keyspace exists already.
+func (kv *kv) createKeyspace(prevID uint64, keyspace *Keyspace) (bool, error) {
+	value, err := json.Marshal(keyspace)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	key, idKey := kv.keyspacePath(keyspace.Name), kv.keyspaceAllocIDPath()
+	idCmp := clientv3.Compare(clientv3.CreateRevision(idKey), "=", 0)
+	if prevID != 0 {
+		idCmp = clientv3.Compare(clientv3.Value(idKey), "=", string(uint64ToBytes(prevID)))
+	}
+	resp, err := kv.txn(idCmp, clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).Then(
+		clientv3.OpPut(key, string(value)),
+		clientv3.OpPut(idKey, string(uint64ToBytes(uint64(keyspace.ID)))),
+	).Commit()
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	if resp.Succeeded {
+		return true, nil
+	}
+	// Check which condition fails.
+	existing, err := kv.loadKeyspace(keyspace.Name)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	if existing != nil {
 		return false, nil
 	}
-	return true, proto.Unmarshal(value, msg)
+	return false, errors.Trace(errTxnFailed)
 }
 
-func (kv *kv) saveProto(key string, msg proto.Message) error {
-	value, err := proto.Marshal(msg)
+func (kv *kv) saveKeyspace(keyspace *Keyspace) error {
+	value, err := json.Marshal(keyspace)
 	if err != nil {
 		return errors.Trace(err)
 	}
-	return kv.save(key, string(value))
+	return kv.save(kv.keyspacePath(keyspace.Name), string(value))
 }
 
-func (kv *kv) load(key string) ([]byte, error) {
-	resp, err := kvGet(kv.client, key)
+// loadKeyspace returns nil if the keyspace does not exist.
+func (kv *kv) loadKeyspace(name string) (*Keyspace, error) {
+	value, err := kv.load(kv.keyspacePath(name))
+	if err != nil || value == nil {
+		return nil, errors.Trace(err)
+	}
+	keyspace := &Keyspace{}
+	if err = json.Unmarshal(value, keyspace); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return keyspace, nil
+}
+
+// loadKeyspaces returns the keyspaces ordered by name.
+func (kv *kv) loadKeyspaces() ([]*Keyspace, error) {
+	resp, err := kvGet(kv.client, kv.keyspacePath("")+"/", clientv3.WithPrefix())
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	if n := len(resp.Kvs); n == 0 {
-		return nil, nil
-	} else if n > 1 {
-		return nil, errors.Errorf("load more than one kvs: key %v kvs %v", key, n)
+
+	keyspaces := make([]*Keyspace, 0, len(resp.Kvs))
+	for _, item := range resp.Kvs {
+		keyspace := &Keyspace{}
+		if err := json.Unmarshal(item.Value, keyspace); err != nil {
+			return nil, errors.Trace(err)
+		}
+		keyspaces = append(keyspaces, keyspace)
 	}
-	return resp.Kvs[0].Value, nil
+	return keyspaces, nil
 }
 
-func (kv *kv) save(key, value string) error {
-	resp, err := kv.txn().Then(clientv3.OpPut(key, value)).Commit()
+// loadCurrentEncryptionKeyID returns 0 if there is no data key.
+func (kv *kv) loadCurrentEncryptionKeyID() (uint64, error) {
+	value, err := kv.load(kv.currentEncryptionKeyPath())
+	if err != nil || value == nil {
+		return 0, errors.Trace(err)
+	}
+	id, err := bytesToUint64(value)
+	return id, errors.Trace(err)
+}
+
+// loadEncryptedKey returns nil if the data key does not exist.
+func (kv *kv) loadEncryptedKey(id uint64) (*EncryptedKey, error) {
+	value, err := kv.load(kv.encryptionKeyPath(id))
+	if err != nil || value == nil {
+		return nil, errors.Trace(err)
+	}
+	ek := &EncryptedKey{}
+	if err = json.Unmarshal(value, ek); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return ek, nil
+}
+
+func (kv *kv) loadEncryptedKeys() ([]*EncryptedKey, error) {
+	resp, err := kvGet(kv.client, kv.encryptionKeysPath()+"/", clientv3.WithPrefix())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	eks := make([]*EncryptedKey, 0, len(resp.Kvs))
+	for _, item := range resp.Kvs {
+		ek := &EncryptedKey{}
+		if err := json.Unmarshal(item.Value, ek); err != nil {
+			return nil, errors.Trace(err)
+		}
+		eks = append(eks, ek)
+	}
+	return eks, nil
+}
+
+// rotateEncryptionKey saves the data key and makes it the current key, only
+// if the current key is still prevID.
+func (kv *kv) rotateEncryptionKey(prevID uint64, ek *EncryptedKey) (bool, error) {
+	value, err := json.Marshal(ek)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	currentKey := kv.currentEncryptionKeyPath()
+	cmp := clientv3.Compare(clientv3.CreateRevision(currentKey), "=", 0)
+	if prevID != 0 {
+		cmp = clientv3.Compare(clientv3.Value(currentKey), "=", string(uint64ToBytes(prevID)))
+	}
+	resp, err := kv.txn(cmp).Then(
+		clientv3.OpPut(kv.encryptionKeyPath(ek.ID), string(value)),
+		clientv3.OpPut(currentKey, string(uint64ToBytes(ek.ID))),
+	).Commit()
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	return resp.Succeeded, nil
+}
+
+// createUser returns false if the user exists already.
+func (kv *kv) createUser(user *User) (bool, error) {
+	value, err := json.Marshal(user)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	key := kv.userPath(user.Name)
+	resp, err := kv.txn(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+		Then(clientv3.OpPut(key, string(value))).
+		Commit()
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	return resp.Succeeded, nil
+}
+
+func (kv *kv) saveUser(user *User) error {
+	value, err := json.Marshal(user)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return kv.save(kv.userPath(user.Name), string(value))
+}
+
+// loadUser returns nil if the user does not exist.
+func (kv *kv) loadUser(name string) (*User, error) {
+	value, err := kv.load(kv.userPath(name))
+	if err != nil || value == nil {
+		return nil, errors.Trace(err)
+	}
+	user := &User{}
+	if err = json.Unmarshal(value, user); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return user, nil
+}
+
+func (kv *kv) loadUsers() ([]*User, error) {
+	resp, err := kvGet(kv.client, kv.userPath("")+"/", clientv3.WithPrefix())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	users := make([]*User, 0, len(resp.Kvs))
+	for _, item := range resp.Kvs {
+		user := &User{}
+		if err := json.Unmarshal(item.Value, user); err != nil {
+			return nil, errors.Trace(err)
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+func (kv *kv) deleteUser(name string) error {
+	resp, err := kv.txn().Then(clientv3.OpDelete(kv.userPath(name))).Commit()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !resp.Succeeded {
+		return errors.Trace(errTxnFailed)
+	}
+	return nil
+}
+
+// saveConfigVersion fails if the version exists already, e.g. another
+// change is saved concurrently.
+func (kv *kv) saveConfigVersion(v *ConfigVersion) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	key := kv.configVersionPath(v.Version)
+	resp, err := kv.txn(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+		Then(clientv3.OpPut(key, string(value))).
+		Commit()
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -187,6 +710,104 @@ func (kv *kv) save(key, value string) error {
 	return nil
 }
 
+func (kv *kv) loadConfigVersion(version uint64) (*ConfigVersion, error) {
+	value, err := kv.load(kv.configVersionPath(version))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if value == nil {
+		return nil, nil
+	}
+	v := &ConfigVersion{}
+	if err = json.Unmarshal(value, v); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return v, nil
+}
+
+// loadLatestConfigVersion returns nil if the config is never changed.
+func (kv *kv) loadLatestConfigVersion() (*ConfigVersion, error) {
+	resp, err := kvGet(kv.client, kv.configPath()+"/", clientv3.WithLastKey()...)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+	v := &ConfigVersion{}
+	if err = json.Unmarshal(resp.Kvs[0].Value, v); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return v, nil
+}
+
+func (kv *kv) loadConfigVersions() ([]*ConfigVersion, error) {
+	resp, err := kvGet(kv.client, kv.configPath()+"/", clientv3.WithPrefix())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	versions := make([]*ConfigVersion, 0, len(resp.Kvs))
+	for _, item := range resp.Kvs {
+		v := &ConfigVersion{}
+		if err := json.Unmarshal(item.Value, v); err != nil {
+			return nil, errors.Trace(err)
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+func (kv *kv) saveFeatureGate(name string, enabled bool) error {
+	return kv.save(kv.featureGatePath(name), strconv.FormatBool(enabled))
+}
+
+func (kv *kv) loadFeatureGates() (map[string]bool, error) {
+	prefix := kv.featureGatePath("") + "/"
+	resp, err := kvGet(kv.client, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	gates := make(map[string]bool, len(resp.Kvs))
+	for _, item := range resp.Kvs {
+		enabled, err := strconv.ParseBool(string(item.Value))
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		gates[string(item.Key)[len(prefix):]] = enabled
+	}
+	return gates, nil
+}
+
+func loadProto(s Storage, key string, msg proto.Message) (bool, error) {
+	value, err := s.Load(key)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	if value == nil {
+		return false, nil
+	}
+	return true, proto.Unmarshal(value, msg)
+}
+
+func saveProto(s Storage, key string, msg proto.Message) error {
+	value, err := proto.Marshal(msg)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(s.Save(key, string(value)))
+}
+
+func (kv *kv) load(key string) ([]byte, error) {
+	value, err := kv.base.Load(key)
+	return value, errors.Trace(err)
+}
+
+func (kv *kv) save(key, value string) error {
+	return errors.Trace(kv.base.Save(key, value))
+}
+
 func kvGet(c *clientv3.Client, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
 	ctx, cancel := context.WithTimeout(c.Ctx(), kvRequestTimeout)
 	defer cancel()
@@ -15,6 +15,7 @@ package server
 
 import (
 	"fmt"
+	"time"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
@@ -133,3 +134,44 @@ func (s *testKVSuite) TestLoadRegions(c *C) {
 		c.Assert(region, DeepEquals, regions[region.GetId()])
 	}
 }
+
+func (s *testKVSuite) TestOperatorRecords(c *C) {
+	kv := newKV(s.server)
+
+	now := time.Now()
+	for i := 0; i < 6; i++ {
+		record := &OperatorRecord{
+			RegionID: uint64(i%2 + 1),
+			Type:     "transfer_leader",
+			End:      now.Add(time.Duration(i) * time.Second),
+			Steps:    []byte("[]"),
+		}
+		c.Assert(kv.saveOperatorRecord(record), IsNil)
+	}
+
+	records, err := kv.loadOperatorRecords(0, now, now.Add(time.Minute), 100)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 6)
+	for i, record := range records {
+		c.Assert(record.End.Equal(now.Add(time.Duration(i)*time.Second)), IsTrue)
+	}
+
+	records, err = kv.loadOperatorRecords(2, now, now.Add(time.Minute), 100)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 3)
+	for _, record := range records {
+		c.Assert(record.RegionID, Equals, uint64(2))
+	}
+
+	records, err = kv.loadOperatorRecords(0, now.Add(time.Second), now.Add(3*time.Second), 100)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 2)
+	records, err = kv.loadOperatorRecords(0, now, now.Add(time.Minute), 4)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 4)
+
+	c.Assert(kv.deleteOperatorRecords(now.Add(3*time.Second)), IsNil)
+	records, err = kv.loadOperatorRecords(0, now, now.Add(time.Minute), 100)
+	c.Assert(err, IsNil)
+	c.Assert(records, HasLen, 3)
+}
@@ -14,6 +14,7 @@
 package server
 
 import (
+	"fmt"
 	"path"
 	"sync/atomic"
 	"time"
@@ -23,6 +24,7 @@ import (
 	"github.com/juju/errors"
 	"github.com/ngaut/log"
 	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/pkg/failpoint"
 	"golang.org/x/net/context"
 )
 
@@ -79,11 +81,19 @@ func (s *Server) leaderLoop() {
 				}
 			} else {
 				log.Infof("leader is %s, watch it", leader)
-				s.watchLeader()
+				s.watchLeader(s.getLeaderPath())
 				log.Info("leader changed, try to campaign leader")
+				// Forwarded tso requests should go to the new leader.
+				s.tsoProxy.close()
 			}
 		}
 
+		// Leave the leadership to the member it is transferring to.
+		if s.shouldResignLeader() {
+			time.Sleep(200 * time.Millisecond)
+			continue
+		}
+
 		if err = s.campaignLeader(); err != nil {
 			log.Errorf("campaign leader err %s", errors.ErrorStack(err))
 		}
@@ -119,6 +129,47 @@ func (s *Server) GetLeader() (*pdpb.Leader, error) {
 	return leader, nil
 }
 
+// LeaderWatchResult is the leader and the members when the leader changes.
+// Revision is the etcd revision to watch the next change from.
+type LeaderWatchResult struct {
+	Revision int64            `json:"revision"`
+	Leader   *pdpb.Leader     `json:"leader"`
+	Members  []*pdpb.PDMember `json:"members"`
+}
+
+// WatchLeader waits until the leader is changed after the revision, or ctx
+// is done, then it returns the current leader and members. It returns at
+// once if the revision is 0. The leader is nil if it is being elected.
+func (s *Server) WatchLeader(ctx context.Context, revision int64) (*LeaderWatchResult, error) {
+	if revision > 0 {
+		watchCtx, cancel := context.WithCancel(ctx)
+		// The events after the revision are sent at once if there are any,
+		// and the compacted revision is returned as an error.
+		rch := s.client.Watch(watchCtx, s.getLeaderPath(), clientv3.WithRev(revision+1))
+		select {
+		case <-rch:
+		case <-watchCtx.Done():
+		}
+		cancel()
+	}
+
+	resp, err := kvGet(s.client, s.getLeaderPath())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	result := &LeaderWatchResult{Revision: resp.Header.Revision}
+	if len(resp.Kvs) == 1 {
+		result.Leader = &pdpb.Leader{}
+		if err = result.Leader.Unmarshal(resp.Kvs[0].Value); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	if result.Members, err = GetPDMembers(s.client); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return result, nil
+}
+
 func (s *Server) isSameLeader(leader *pdpb.Leader) bool {
 	return leader.GetAddr() == s.GetAddr() && leader.GetId() == s.ID()
 }
@@ -140,6 +191,9 @@ func (s *Server) marshalLeader() string {
 
 func (s *Server) campaignLeader() error {
 	log.Debugf("begin to campaign leader %s", s.Name())
+	if err := failpoint.Error(FailpointCampaignLeader); err != nil {
+		return errors.Trace(err)
+	}
 
 	lessor := clientv3.NewLease(s.client)
 	defer lessor.Close()
@@ -177,25 +231,80 @@ func (s *Server) campaignLeader() error {
 	}
 
 	log.Debugf("campaign leader ok %s", s.Name())
+
+	// The leadership transfer is done.
+	if err = s.deleteNextLeader(); err != nil {
+		return errors.Trace(err)
+	}
+
 	s.enableLeader(true)
 	defer s.enableLeader(false)
 
+	if err = s.loadConfig(); err != nil {
+		return errors.Trace(err)
+	}
+	if err = s.loadFeatureGates(); err != nil {
+		return errors.Trace(err)
+	}
+	if err = s.loadNamespaces(); err != nil {
+		return errors.Trace(err)
+	}
+
+	if s.regionSyncer != nil {
+		s.regionSyncer.reset()
+	}
+
 	// Try to create raft cluster.
 	err = s.createRaftCluster()
 	if err != nil {
 		return errors.Trace(err)
 	}
 
+	if err = s.startWebhooks(); err != nil {
+		return errors.Trace(err)
+	}
+	defer s.webhooks.stop()
+	maintenanceQuit := make(chan struct{})
+	defer close(maintenanceQuit)
+	s.wg.Add(1)
+	go s.runEtcdMaintenance(maintenanceQuit)
+	if s.masterKey != nil {
+		s.wg.Add(1)
+		go s.runEncryptionKeyRotation(maintenanceQuit)
+	}
+	s.eventBus.publish(&ClusterEvent{
+		Type:    ClusterEventLeaderChange,
+		Leader:  s.Name(),
+		Message: fmt.Sprintf("%s becomes pd leader", s.Name()),
+	})
+
 	log.Debug("sync timestamp for tso")
-	if err = s.syncTimestamp(); err != nil {
+	defer s.tso.resetTimestamp()
+	if err = s.tso.syncTimestamp(zeroTime); err != nil {
 		return errors.Trace(err)
 	}
 
 	log.Infof("PD cluster leader %s is ready to serve", s.Name())
 
-	tsTicker := time.NewTicker(updateTimestampStep)
+	tsTicker := time.NewTicker(s.cfg.TsoUpdateInterval.Duration)
 	defer tsTicker.Stop()
 
+	priorityTicker := time.NewTicker(s.cfg.LeaderPriorityCheckInterval.Duration)
+	defer priorityTicker.Stop()
+
+	// Only check the latency after becoming leader.
+	s.writeLatency.takeAverage()
+	s.heartbeatLatency.takeAverage()
+	atomic.StoreInt64(&s.leaderSince, time.Now().UnixNano())
+
+	var throttleCh <-chan time.Time
+	if s.cfg.EnableScheduleThrottle {
+		s.scheduleThrottle.reset()
+		throttleTicker := time.NewTicker(s.cfg.ScheduleThrottleCheckInterval.Duration)
+		defer throttleTicker.Stop()
+		throttleCh = throttleTicker.C
+	}
+
 	for {
 		select {
 		case _, ok := <-ch:
@@ -204,22 +313,43 @@ func (s *Server) campaignLeader() error {
 				return nil
 			}
 		case <-tsTicker.C:
-			if err = s.updateTimestamp(); err != nil {
+			if err = s.tso.updateTimestamp(); err != nil {
 				return errors.Trace(err)
 			}
+			if err = failpoint.Error(FailpointLeaderResign); err != nil {
+				log.Infof("%s resigns leadership: %v", s.Name(), err)
+				return nil
+			}
+		case <-priorityTicker.C:
+			if s.shouldResignLeader() {
+				log.Infof("leadership is transferring, %s resigns", s.Name())
+				return nil
+			}
+			if err = s.checkEtcdLeader(); err != nil {
+				log.Errorf("check etcd leader err %v", errors.ErrorStack(err))
+			}
+			if err = s.checkLeaderHealth(); err != nil {
+				log.Errorf("check leader health err %v", errors.ErrorStack(err))
+			}
+		case <-throttleCh:
+			s.scheduleThrottle.check(s.cfg.ScheduleThrottleCPUUsage, s.cfg.ScheduleThrottleHeartbeats)
+		case <-s.resignCh:
+			log.Infof("%s resigns leadership", s.Name())
+			return nil
 		case <-s.client.Ctx().Done():
 			return errors.New("server closed")
 		}
 	}
 }
 
-func (s *Server) watchLeader() {
+// watchLeader waits until the leader key is deleted.
+func (s *Server) watchLeader(leaderPath string) {
 	watcher := clientv3.NewWatcher(s.client)
 	defer watcher.Close()
 
 	ctx := s.client.Ctx()
 	for {
-		rch := watcher.Watch(ctx, s.getLeaderPath())
+		rch := watcher.Watch(ctx, leaderPath)
 		for wresp := range rch {
 			if wresp.Canceled {
 				return
@@ -0,0 +1,72 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+)
+
+// latencyStat records the average latency of a period.
+type latencyStat struct {
+	sync.Mutex
+	total time.Duration
+	count int64
+}
+
+func (l *latencyStat) observe(d time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.total += d
+	l.count++
+}
+
+// takeAverage returns the average latency since last call.
+func (l *latencyStat) takeAverage() time.Duration {
+	l.Lock()
+	defer l.Unlock()
+
+	var avg time.Duration
+	if l.count > 0 {
+		avg = l.total / time.Duration(l.count)
+	}
+	l.total, l.count = 0, 0
+	return avg
+}
+
+// checkLeaderHealth resigns the leadership if the etcd writes or heartbeats
+// handled by the leader are too slow, so a sick leader doesn't block the
+// cluster until its lease expires. The leader serves leader-min-tenure at
+// least, so every handoff, which pauses the TSO, is spaced out when all the
+// members are slow.
+func (s *Server) checkLeaderHealth() error {
+	write := s.writeLatency.takeAverage()
+	heartbeat := s.heartbeatLatency.takeAverage()
+	if write <= s.cfg.LeaderMaxWriteLatency.Duration && heartbeat <= s.cfg.LeaderMaxHeartbeatLatency.Duration {
+		return nil
+	}
+	since := time.Unix(0, atomic.LoadInt64(&s.leaderSince))
+	if time.Since(since) < s.cfg.LeaderMinTenure.Duration {
+		log.Warnf("leader %s is unhealthy, write latency %v, heartbeat latency %v, keep it within the tenure", s.Name(), write, heartbeat)
+		return nil
+	}
+
+	log.Warnf("leader %s is unhealthy, write latency %v, heartbeat latency %v, resign", s.Name(), write, heartbeat)
+	return errors.Trace(s.ResignLeader(""))
+}
@@ -0,0 +1,223 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"path"
+	"sync/atomic"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"golang.org/x/net/context"
+)
+
+// localTSOAllocator allocates timestamps for a dc-location. One of the
+// servers in the dc-location is elected as the allocator, and it syncs
+// with the global timestamp when elected, so the local timestamps are
+// greater than the global ones allocated before.
+type localTSOAllocator struct {
+	s          *Server
+	dcLocation string
+
+	isLeaderValue int64
+
+	tso     *timestampOracle
+	batcher *tsoBatcher
+}
+
+func newLocalTSOAllocator(s *Server, dcLocation string) *localTSOAllocator {
+	a := &localTSOAllocator{
+		s:          s,
+		dcLocation: dcLocation,
+	}
+	a.tso = newTimestampOracle(s, "local", path.Join(a.getRootPath(), "timestamp"), a.leaderTxn)
+	a.batcher = newTSOBatcher("local", a.tso.getRespTS)
+	return a
+}
+
+func (a *localTSOAllocator) getRootPath() string {
+	return path.Join(a.s.rootPath, "dc-location", a.dcLocation)
+}
+
+func (a *localTSOAllocator) getLeaderPath() string {
+	return path.Join(a.getRootPath(), "leader")
+}
+
+func (a *localTSOAllocator) isLeader() bool {
+	return atomic.LoadInt64(&a.isLeaderValue) == 1
+}
+
+func (a *localTSOAllocator) enableLeader(b bool) {
+	value := int64(0)
+	if b {
+		value = 1
+	}
+
+	atomic.StoreInt64(&a.isLeaderValue, value)
+}
+
+// leaderTxn returns txn() with a comparison to guarantee that the
+// transaction can be executed only if the server is the allocator.
+func (a *localTSOAllocator) leaderTxn(cs ...clientv3.Cmp) clientv3.Txn {
+	cmp := clientv3.Compare(clientv3.Value(a.getLeaderPath()), "=", a.s.leaderValue)
+	return a.s.txn().If(append(cs, cmp)...)
+}
+
+func (a *localTSOAllocator) loop() {
+	defer a.s.wg.Done()
+
+	for {
+		if a.s.isClosed() {
+			log.Infof("server is closed, return local tso allocator loop")
+			return
+		}
+
+		leader, err := getLeader(a.s.client, a.getLeaderPath())
+		if err != nil {
+			log.Errorf("get local tso allocator of dc-location %s err %v", a.dcLocation, err)
+			time.Sleep(200 * time.Millisecond)
+			continue
+		}
+		if leader != nil {
+			if a.s.isSameLeader(leader) {
+				// We may meet something wrong in previous campaign,
+				// resign and campaign again.
+				log.Warnf("local tso allocator is still %s, resign and campaign again", leader)
+				if err = a.resign(); err != nil {
+					log.Errorf("resign local tso allocator err %s", err)
+					time.Sleep(200 * time.Millisecond)
+					continue
+				}
+			} else {
+				log.Infof("local tso allocator of dc-location %s is %s, watch it", a.dcLocation, leader)
+				a.s.watchLeader(a.getLeaderPath())
+				log.Info("local tso allocator changed, try to campaign")
+			}
+		}
+
+		if err = a.campaign(); err != nil {
+			log.Errorf("campaign local tso allocator err %s", errors.ErrorStack(err))
+		}
+	}
+}
+
+func (a *localTSOAllocator) campaign() error {
+	log.Debugf("begin to campaign local tso allocator of dc-location %s", a.dcLocation)
+
+	lessor := clientv3.NewLease(a.s.client)
+	defer lessor.Close()
+
+	ctx, cancel := context.WithTimeout(a.s.client.Ctx(), requestTimeout)
+	leaseResp, err := lessor.Grant(ctx, a.s.cfg.LeaderLease)
+	cancel()
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	leaderKey := a.getLeaderPath()
+	// The leader key must not exist, so the CreateRevision is 0.
+	resp, err := a.s.txn().
+		If(clientv3.Compare(clientv3.CreateRevision(leaderKey), "=", 0)).
+		Then(clientv3.OpPut(leaderKey, a.s.leaderValue, clientv3.WithLease(clientv3.LeaseID(leaseResp.ID)))).
+		Commit()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !resp.Succeeded {
+		return errors.New("campaign local tso allocator failed, other server may campaign ok")
+	}
+
+	ch, err := lessor.KeepAlive(a.s.client.Ctx(), clientv3.LeaseID(leaseResp.ID))
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	// Sync with the global timestamp.
+	global, _, err := loadTimestamp(a.s.client, a.s.getTimestampPath())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err = a.tso.syncTimestamp(global); err != nil {
+		return errors.Trace(err)
+	}
+
+	a.enableLeader(true)
+	defer a.enableLeader(false)
+	defer a.tso.resetTimestamp()
+
+	log.Infof("local tso allocator %s of dc-location %s is ready to serve", a.s.Name(), a.dcLocation)
+
+	tsTicker := time.NewTicker(a.s.cfg.TsoUpdateInterval.Duration)
+	defer tsTicker.Stop()
+
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				log.Info("keep alive channel is closed")
+				return nil
+			}
+		case <-tsTicker.C:
+			if err = a.tso.updateTimestamp(); err != nil {
+				return errors.Trace(err)
+			}
+		case <-a.s.client.Ctx().Done():
+			return errors.New("server closed")
+		}
+	}
+}
+
+func (a *localTSOAllocator) resign() error {
+	resp, err := a.leaderTxn().Then(clientv3.OpDelete(a.getLeaderPath())).Commit()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !resp.Succeeded {
+		return errors.New("resign local tso allocator failed, we are not allocator already")
+	}
+	return nil
+}
+
+// GetLocalTSOLeader returns the local tso allocator of the server's dc-location.
+func (s *Server) GetLocalTSOLeader() (*pdpb.Leader, error) {
+	if s.localTSO == nil {
+		return nil, errors.New("dc-location is not configured")
+	}
+	leader, err := getLeader(s.client, s.localTSO.getLeaderPath())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if leader == nil {
+		return nil, errors.Trace(errNoLeader)
+	}
+	return leader, nil
+}
+
+// IsLocalTSOLeader returns whether the server is the local tso allocator.
+func (s *Server) IsLocalTSOLeader() bool {
+	return s.localTSO != nil && s.localTSO.isLeader()
+}
+
+// GetLocalTS allocates count timestamps from the local tso allocator,
+// only the allocator of the dc-location can serve it.
+func (s *Server) GetLocalTS(count uint32) (pdpb.Timestamp, error) {
+	if !s.IsLocalTSOLeader() {
+		return pdpb.Timestamp{}, errors.Errorf("%s is not the local tso allocator", s.Name())
+	}
+	ts, err := s.localTSO.batcher.getTS(count)
+	return ts, errors.Trace(err)
+}
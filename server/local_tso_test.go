@@ -0,0 +1,82 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+)
+
+var _ = Suite(&testLocalTSOSuite{})
+
+type testLocalTSOSuite struct{}
+
+func mustWaitLocalTSOLeader(c *C, svrs []*Server) *Server {
+	for i := 0; i < 500; i++ {
+		for _, s := range svrs {
+			if s.IsLocalTSOLeader() {
+				return s
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	c.Fatal("no local tso allocator")
+	return nil
+}
+
+func mustGetLocalTS(c *C, s *Server, last pdpb.Timestamp) pdpb.Timestamp {
+	ts, err := s.GetLocalTS(10)
+	c.Assert(err, IsNil)
+	c.Assert(ts.GetPhysical(), Not(Less), last.GetPhysical())
+	if ts.GetPhysical() == last.GetPhysical() {
+		c.Assert(ts.GetLogical(), Greater, last.GetLogical())
+	}
+	return ts
+}
+
+func (s *testLocalTSOSuite) TestLocalTSO(c *C) {
+	cfgs := NewTestMultiConfig(3)
+	for _, cfg := range cfgs {
+		cfg.DCLocation = "dc1"
+	}
+	svrs, cleanup := newMultiTestServersWithCfgs(c, cfgs)
+	defer cleanup()
+
+	allocator := mustWaitLocalTSOLeader(c, svrs)
+	leader, err := allocator.GetLocalTSOLeader()
+	c.Assert(err, IsNil)
+	c.Assert(allocator.isSameLeader(leader), IsTrue)
+
+	var last pdpb.Timestamp
+	for i := 0; i < 10; i++ {
+		last = mustGetLocalTS(c, allocator, last)
+	}
+
+	// Only the allocator can serve local tso.
+	var others []*Server
+	for _, svr := range svrs {
+		if svr != allocator {
+			_, err = svr.GetLocalTS(1)
+			c.Assert(err, NotNil)
+			others = append(others, svr)
+		}
+	}
+
+	// A new allocator should be elected and continue the timestamps.
+	allocator.Close()
+	allocator = mustWaitLocalTSOLeader(c, others)
+	mustGetLocalTS(c, allocator, last)
+}
@@ -0,0 +1,263 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"math/rand"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/pd/pkg/etcdutil"
+	"golang.org/x/net/context"
+)
+
+func (s *Server) getMemberPath(id uint64) string {
+	return path.Join(s.rootPath, "member", fmt.Sprintf("%d", id))
+}
+
+func (s *Server) getMemberLeaderPriorityPath(id uint64) string {
+	return path.Join(s.getMemberPath(id), "leader_priority")
+}
+
+// getNextLeaderPath returns the path of the member the leadership is
+// transferring to. Other members don't campaign until the key expires.
+func (s *Server) getNextLeaderPath() string {
+	return path.Join(s.rootPath, "next_leader")
+}
+
+// SetMemberLeaderPriority saves the leader priority of a member. When
+// priorities differ, leadership moves toward the member with higher priority.
+func (s *Server) SetMemberLeaderPriority(id uint64, priority int) error {
+	key := s.getMemberLeaderPriorityPath(id)
+	resp, err := s.leaderTxn().Then(clientv3.OpPut(key, strconv.Itoa(priority))).Commit()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !resp.Succeeded {
+		return errors.New("save leader priority failed, maybe not leader")
+	}
+	return nil
+}
+
+// GetMemberLeaderPriority returns the leader priority of a member, 0 if not set.
+func (s *Server) GetMemberLeaderPriority(id uint64) (int, error) {
+	data, err := getValue(s.client, s.getMemberLeaderPriorityPath(id))
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if data == nil {
+		return 0, nil
+	}
+	priority, err := strconv.Atoi(string(data))
+	return priority, errors.Trace(err)
+}
+
+// getNextLeader returns the member the leadership is transferring to, 0 if none.
+func (s *Server) getNextLeader() (uint64, error) {
+	data, err := getValue(s.client, s.getNextLeaderPath())
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if data == nil {
+		return 0, nil
+	}
+	id, err := bytesToUint64(data)
+	return id, errors.Trace(err)
+}
+
+// setNextLeader asks the current leader to transfer leadership to the member.
+// The request expires after a few leases in case the member fails to campaign.
+func (s *Server) setNextLeader(id uint64) error {
+	lessor := clientv3.NewLease(s.client)
+	defer lessor.Close()
+
+	ctx, cancel := context.WithTimeout(s.client.Ctx(), requestTimeout)
+	leaseResp, err := lessor.Grant(ctx, 3*s.cfg.LeaderLease)
+	cancel()
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	key := s.getNextLeaderPath()
+	resp, err := s.txn().
+		If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+		Then(clientv3.OpPut(key, string(uint64ToBytes(id)), clientv3.WithLease(clientv3.LeaseID(leaseResp.ID)))).
+		Commit()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !resp.Succeeded {
+		return errors.New("leadership is already transferring")
+	}
+	return nil
+}
+
+// deleteNextLeader deletes the transfer request once the server becomes leader.
+func (s *Server) deleteNextLeader() error {
+	key := s.getNextLeaderPath()
+	_, err := s.txn().
+		If(clientv3.Compare(clientv3.Value(key), "=", string(uint64ToBytes(s.ID())))).
+		Then(clientv3.OpDelete(key)).
+		Commit()
+	return errors.Trace(err)
+}
+
+// shouldResignLeader returns true if the leadership is transferring to another member.
+func (s *Server) shouldResignLeader() bool {
+	next, err := s.getNextLeader()
+	if err != nil {
+		log.Errorf("get next leader err %v", err)
+		return false
+	}
+	return next != 0 && next != s.ID()
+}
+
+// ResignLeader resigns the leadership and transfers it to the member named
+// nextLeader. If nextLeader is empty, a member is picked randomly.
+func (s *Server) ResignLeader(nextLeader string) error {
+	if !s.IsLeader() {
+		return errors.New("server is not leader")
+	}
+
+	listResp, err := etcdutil.ListEtcdMembers(s.client)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	var ids []uint64
+	for _, m := range listResp.Members {
+		// Skip the members not started yet, they have no name.
+		if m.ID == s.ID() || len(m.Name) == 0 {
+			continue
+		}
+		if len(nextLeader) == 0 || m.Name == nextLeader {
+			ids = append(ids, m.ID)
+		}
+	}
+	if len(ids) == 0 {
+		if len(nextLeader) == 0 {
+			return errors.New("no other member to transfer leadership")
+		}
+		return errors.Errorf("member %s not found", nextLeader)
+	}
+
+	next := ids[rand.Intn(len(ids))]
+	log.Infof("%s resigns leadership, next leader %x", s.Name(), next)
+	if err = s.setNextLeader(next); err != nil {
+		return errors.Trace(err)
+	}
+
+	select {
+	case s.resignCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// leaderPriorityLoop checks whether the server has higher leader priority
+// than the current leader, and asks the leader to transfer leadership if so.
+func (s *Server) leaderPriorityLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.cfg.LeaderPriorityCheckInterval.Duration)
+	defer ticker.Stop()
+
+	ctx := s.client.Ctx()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.checkLeaderPriority(); err != nil {
+				log.Errorf("check leader priority err %v", errors.ErrorStack(err))
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Server) checkLeaderPriority() error {
+	if s.IsLeader() {
+		return nil
+	}
+
+	leader, err := s.GetLeader()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	myPriority, err := s.GetMemberLeaderPriority(s.ID())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	leaderPriority, err := s.GetMemberLeaderPriority(leader.GetId())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if myPriority <= leaderPriority {
+		return nil
+	}
+
+	log.Infof("%s has higher leader priority %d than leader %d, transfer leadership", s.Name(), myPriority, leaderPriority)
+	return errors.Trace(s.setNextLeader(s.ID()))
+}
+
+// GetEtcdLeader returns the member id of the etcd leader, 0 if unknown.
+func (s *Server) GetEtcdLeader() uint64 {
+	return s.etcd.Server.Lead()
+}
+
+// checkEtcdLeader transfers the leadership to the etcd leader if they are on
+// different members. The embed etcd can't transfer its leadership to a chosen
+// member, so the pd leadership follows the etcd leader instead.
+func (s *Server) checkEtcdLeader() error {
+	etcdLeader := s.GetEtcdLeader()
+	if etcdLeader == 0 || etcdLeader == s.ID() {
+		etcdLeaderColocatedGauge.Set(1)
+		return nil
+	}
+	etcdLeaderColocatedGauge.Set(0)
+
+	if !s.cfg.EnableEtcdLeaderColocation {
+		return nil
+	}
+
+	// Don't move the leadership to a member with lower leader priority,
+	// otherwise it will be moved back soon.
+	myPriority, err := s.GetMemberLeaderPriority(s.ID())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	etcdLeaderPriority, err := s.GetMemberLeaderPriority(etcdLeader)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if etcdLeaderPriority < myPriority {
+		return nil
+	}
+
+	listResp, err := etcdutil.ListEtcdMembers(s.client)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, m := range listResp.Members {
+		if m.ID == etcdLeader {
+			log.Infof("etcd leader %s is not pd leader, transfer leadership", m.Name)
+			return errors.Trace(s.ResignLeader(m.Name))
+		}
+	}
+	return nil
+}
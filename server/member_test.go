@@ -0,0 +1,172 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"sync/atomic"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/pkg/typeutil"
+)
+
+var _ = Suite(&testMemberSuite{})
+
+type testMemberSuite struct{}
+
+func mustWaitLeaderChanged(c *C, svrs []*Server, target *Server) {
+	for i := 0; i < 100; i++ {
+		if target.IsLeader() {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	c.Fatalf("leader is not changed to %s", target.Name())
+}
+
+func (s *testMemberSuite) TestLeaderPriority(c *C) {
+	cfgs := NewTestMultiConfig(3)
+	for _, cfg := range cfgs {
+		cfg.LeaderPriorityCheckInterval = typeutil.NewDuration(100 * time.Millisecond)
+	}
+	svrs, cleanup := newMultiTestServersWithCfgs(c, cfgs)
+	defer cleanup()
+
+	leader := mustWaitLeader(c, svrs)
+
+	// Default priority is 0.
+	priority, err := leader.GetMemberLeaderPriority(leader.ID())
+	c.Assert(err, IsNil)
+	c.Assert(priority, Equals, 0)
+
+	var follower *Server
+	for _, svr := range svrs {
+		if svr != leader {
+			follower = svr
+			break
+		}
+	}
+
+	// Leadership moves to the member with higher priority.
+	c.Assert(leader.SetMemberLeaderPriority(follower.ID(), 10), IsNil)
+	priority, err = leader.GetMemberLeaderPriority(follower.ID())
+	c.Assert(err, IsNil)
+	c.Assert(priority, Equals, 10)
+	mustWaitLeaderChanged(c, svrs, follower)
+
+	// The transfer request is deleted after the new leader is elected.
+	next, err := follower.getNextLeader()
+	c.Assert(err, IsNil)
+	c.Assert(next, Equals, uint64(0))
+}
+
+func (s *testMemberSuite) TestResignLeader(c *C) {
+	svrs, cleanup := newMultiTestServers(c, 3)
+	defer cleanup()
+
+	leader := mustWaitLeader(c, svrs)
+	var follower *Server
+	for _, svr := range svrs {
+		if svr != leader {
+			follower = svr
+			break
+		}
+	}
+
+	// Only leader can resign.
+	c.Assert(follower.ResignLeader(""), NotNil)
+	c.Assert(leader.ResignLeader("unknown"), NotNil)
+
+	// Transfer to the named member.
+	c.Assert(leader.ResignLeader(follower.Name()), IsNil)
+	mustWaitLeaderChanged(c, svrs, follower)
+
+	// Transfer to a random member.
+	c.Assert(follower.ResignLeader(""), IsNil)
+	for i := 0; i < 100; i++ {
+		if !follower.IsLeader() {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	c.Assert(mustWaitLeader(c, svrs), Not(Equals), follower)
+}
+
+func (s *testMemberSuite) TestEtcdLeaderColocation(c *C) {
+	cfgs := NewTestMultiConfig(3)
+	for _, cfg := range cfgs {
+		cfg.LeaderPriorityCheckInterval = typeutil.NewDuration(100 * time.Millisecond)
+		cfg.EnableEtcdLeaderColocation = true
+	}
+	svrs, cleanup := newMultiTestServersWithCfgs(c, cfgs)
+	defer cleanup()
+
+	for i := 0; i < 100; i++ {
+		leader := mustWaitLeader(c, svrs)
+		if leader.GetEtcdLeader() == leader.ID() {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	c.Fatal("pd leader is not etcd leader")
+}
+
+func (s *testMemberSuite) TestGracefulClose(c *C) {
+	svrs, cleanup := newMultiTestServers(c, 3)
+	defer cleanup()
+
+	leader := mustWaitLeader(c, svrs)
+	leader.GracefulClose(10 * time.Second)
+	c.Assert(leader.isClosed(), IsTrue)
+
+	var others []*Server
+	for _, svr := range svrs {
+		if svr != leader {
+			others = append(others, svr)
+		}
+	}
+	// The leadership is transferred before the leader is closed.
+	newLeader, err := others[0].GetLeader()
+	c.Assert(err, IsNil)
+	c.Assert(newLeader.GetId(), Not(Equals), leader.ID())
+	mustWaitLeader(c, others)
+}
+
+func (s *testMemberSuite) TestLeaderHealth(c *C) {
+	cfgs := NewTestMultiConfig(3)
+	for _, cfg := range cfgs {
+		cfg.LeaderPriorityCheckInterval = typeutil.NewDuration(100 * time.Millisecond)
+		cfg.LeaderMaxWriteLatency = typeutil.NewDuration(time.Nanosecond)
+		cfg.LeaderMinTenure = typeutil.NewDuration(time.Hour)
+	}
+	svrs, cleanup := newMultiTestServersWithCfgs(c, cfgs)
+	defer cleanup()
+
+	leader := mustWaitLeader(c, svrs)
+
+	// The etcd writes are too slow, but the leader keeps the leadership
+	// within its tenure.
+	time.Sleep(time.Second)
+	c.Assert(leader.IsLeader(), IsTrue)
+
+	// The leader resigns once its tenure is over.
+	atomic.StoreInt64(&leader.leaderSince, time.Now().Add(-2*time.Hour).UnixNano())
+	for i := 0; i < 100; i++ {
+		if !leader.IsLeader() {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	c.Assert(mustWaitLeader(c, svrs), Not(Equals), leader)
+}
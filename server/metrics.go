@@ -22,7 +22,7 @@ var (
 			Subsystem: "cmd",
 			Name:      "cmds_total",
 			Help:      "Counter of cmds.",
-		}, []string{"type"})
+		}, []string{"type", "caller"})
 
 	cmdFailedCounter = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
@@ -30,7 +30,7 @@ var (
 			Subsystem: "cmd",
 			Name:      "cmds_failed_total",
 			Help:      "Counter of failed cmds.",
-		}, []string{"type"})
+		}, []string{"type", "caller"})
 
 	cmdDuration = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
@@ -39,7 +39,7 @@ var (
 			Name:      "handle_cmds_duration_seconds",
 			Help:      "Bucketed histogram of processing time (s) of handled success cmds.",
 			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 13),
-		}, []string{"type"})
+		}, []string{"type", "caller"})
 
 	cmdFailedDuration = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
@@ -48,7 +48,7 @@ var (
 			Name:      "handle_failed_cmds_duration_seconds",
 			Help:      "Bucketed histogram of processing time (s) of failed handled cmds.",
 			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 13),
-		}, []string{"type"})
+		}, []string{"type", "caller"})
 
 	cmdCompletedDuration = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
@@ -57,7 +57,7 @@ var (
 			Name:      "handle_completed_cmds_duration_seconds",
 			Help:      "Bucketed histogram of processing time (s) of completed cmds.",
 			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 13),
-		}, []string{"type"})
+		}, []string{"type", "caller"})
 
 	txnCounter = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
@@ -84,6 +84,39 @@ var (
 			Help:      "Counter of schedule operators.",
 		}, []string{"type"})
 
+	operatorCreatedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "schedule",
+			Name:      "operators_created_total",
+			Help:      "Counter of created operators by type and source.",
+		}, []string{"type", "source"})
+
+	operatorFinishedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "schedule",
+			Name:      "operators_finished_total",
+			Help:      "Counter of finished operators by type, source and status.",
+		}, []string{"type", "source", "status"})
+
+	scheduleResultCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "schedule",
+			Name:      "result_total",
+			Help:      "Counter of schedule results by scheduler, e.g. why no operator is created.",
+		}, []string{"scheduler", "result"})
+
+	operatorDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "pd",
+			Subsystem: "schedule",
+			Name:      "operator_duration_seconds",
+			Help:      "Bucketed histogram of processing time (s) of finished operators.",
+			Buckets:   prometheus.ExponentialBuckets(0.5, 2, 12),
+		}, []string{"type", "status"})
+
 	clusterStatusGauge = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: "pd",
@@ -92,6 +125,117 @@ var (
 			Help:      "Status of the cluster.",
 		}, []string{"type"})
 
+	storeStatusGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "store_status",
+			Help:      "Status of the stores.",
+		}, []string{"address", "store", "type"})
+
+	storeHeartbeatInterval = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "store_heartbeat_interval_seconds",
+			Help:      "Bucketed histogram of the interval (s) between the heartbeats of the stores.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+		}, []string{"address", "store"})
+
+	storeHeartbeatDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "store_heartbeat_duration_seconds",
+			Help:      "Bucketed histogram of processing time (s) of the heartbeats of the stores.",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 13),
+		}, []string{"address", "store"})
+
+	regionStatusGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "region_status",
+			Help:      "Count of the unhealthy regions.",
+		}, []string{"type"})
+
+	unavailableRegionGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "unavailable_regions",
+			Help:      "Count of the unavailable regions by reason.",
+		}, []string{"reason"})
+
+	tsoBatchDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "pd",
+			Subsystem: "tso",
+			Name:      "handle_batch_duration_seconds",
+			Help:      "Bucketed histogram of processing time (s) of tso batches.",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 13),
+		}, []string{"type"})
+
+	regionSaveDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "region_save_duration_seconds",
+			Help:      "Bucketed histogram of processing time (s) of saving region batches.",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 13),
+		})
+
+	regionSaveBatchSize = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "region_save_batch_size",
+			Help:      "Bucketed histogram of the region count of saved region batches.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 8),
+		})
+
+	tsoBatchSize = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "pd",
+			Subsystem: "tso",
+			Name:      "batch_size",
+			Help:      "Bucketed histogram of the request count of tso batches.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 13),
+		}, []string{"type"})
+
+	tsoLogicalUsage = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "pd",
+			Subsystem: "tso",
+			Name:      "logical_usage",
+			Help:      "Bucketed histogram of the logical part used in each physical time.",
+			Buckets:   prometheus.ExponentialBuckets(1, 4, 10),
+		}, []string{"type"})
+
+	tsoCheckCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "tso",
+			Name:      "check_total",
+			Help:      "Counter of timestamp checks after becoming leader.",
+		}, []string{"result"})
+
+	etcdLeaderColocatedGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "server",
+			Name:      "etcd_leader_colocated",
+			Help:      "Whether the pd leader is also the etcd leader.",
+		})
+
+	scheduleThrottleGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "schedule",
+			Name:      "throttle_factor",
+			Help:      "Times the schedule interval is enlarged by for the overloaded leader.",
+		})
+
 	timeJumpBackCounter = prometheus.NewCounter(
 		prometheus.CounterOpts{
 			Namespace: "pd",
@@ -99,6 +243,31 @@ var (
 			Name:      "time_jump_back_total",
 			Help:      "Counter of system time jumps backward.",
 		})
+
+	etcdMaintenanceCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "server",
+			Name:      "etcd_maintenance_total",
+			Help:      "Counter of etcd compactions and defragmentations.",
+		}, []string{"type", "result"})
+
+	idAllocCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "id",
+			Name:      "alloc_total",
+			Help:      "Counter of allocated IDs.",
+		})
+
+	idGenerateDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: "pd",
+			Subsystem: "id",
+			Name:      "generate_duration_seconds",
+			Help:      "Bucketed histogram of processing time (s) of reserving the ID batches in etcd.",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 13),
+		})
 )
 
 func init() {
@@ -110,6 +279,26 @@ func init() {
 	prometheus.MustRegister(txnCounter)
 	prometheus.MustRegister(txnDuration)
 	prometheus.MustRegister(operatorCounter)
+	prometheus.MustRegister(operatorCreatedCounter)
+	prometheus.MustRegister(operatorFinishedCounter)
+	prometheus.MustRegister(operatorDuration)
+	prometheus.MustRegister(scheduleResultCounter)
 	prometheus.MustRegister(clusterStatusGauge)
+	prometheus.MustRegister(storeStatusGauge)
+	prometheus.MustRegister(storeHeartbeatInterval)
+	prometheus.MustRegister(storeHeartbeatDuration)
+	prometheus.MustRegister(regionStatusGauge)
+	prometheus.MustRegister(unavailableRegionGauge)
+	prometheus.MustRegister(regionSaveDuration)
+	prometheus.MustRegister(regionSaveBatchSize)
+	prometheus.MustRegister(tsoBatchDuration)
+	prometheus.MustRegister(tsoBatchSize)
+	prometheus.MustRegister(tsoLogicalUsage)
+	prometheus.MustRegister(tsoCheckCounter)
+	prometheus.MustRegister(etcdLeaderColocatedGauge)
+	prometheus.MustRegister(scheduleThrottleGauge)
 	prometheus.MustRegister(timeJumpBackCounter)
+	prometheus.MustRegister(etcdMaintenanceCounter)
+	prometheus.MustRegister(idAllocCounter)
+	prometheus.MustRegister(idGenerateDuration)
 }
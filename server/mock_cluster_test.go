@@ -0,0 +1,288 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/juju/errors"
+	. "github.com/pingcap/check"
+	raftpb "github.com/pingcap/kvproto/pkg/eraftpb"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+)
+
+var _ = Suite(&testMockClusterSuite{})
+
+type testMockClusterSuite struct{}
+
+func (s *testMockClusterSuite) TestHeartbeat(c *C) {
+	mc, err := NewMockCluster(nil)
+	c.Assert(err, IsNil)
+	mc.AddLabelsStore(1, 1, 0.1, map[string]string{"zone": "z1"})
+	mc.AddLabelsStore(2, 1, 0.1, map[string]string{"zone": "z2"})
+	mc.AddLabelsStore(3, 0, 0.1, map[string]string{"zone": "z3"})
+	c.Assert(mc.GetStoreMeta(1).GetLabels(), HasLen, 1)
+	c.Assert(mc.GetStoreMeta(4), IsNil)
+
+	// The region lacks a replica, the peer is added to store 3.
+	mc.AddLeaderRegion(1, 1, 2)
+	region, leader := mc.GetRegionMeta(1)
+	c.Assert(region.GetPeers(), HasLen, 2)
+	c.Assert(leader.GetStoreId(), Equals, uint64(1))
+	region = proto.Clone(region).(*metapb.Region)
+	resp, err := mc.HandleRegionHeartbeat(region, leader)
+	c.Assert(err, IsNil)
+	c.Assert(resp.GetChangePeer().GetChangeType(), Equals, raftpb.ConfChangeType_AddNode)
+	peer := resp.GetChangePeer().GetPeer()
+	c.Assert(peer.GetStoreId(), Equals, uint64(3))
+
+	// The operator is finished by the heartbeat with the new peer.
+	region.Peers = append(region.Peers, peer)
+	region.RegionEpoch = &metapb.RegionEpoch{ConfVer: 1}
+	resp, err = mc.HandleRegionHeartbeat(region, leader)
+	c.Assert(err, IsNil)
+	c.Assert(resp, IsNil)
+	c.Assert(mc.co.getOperator(1), IsNil)
+
+	// The stale heartbeat is rejected.
+	region.RegionEpoch = &metapb.RegionEpoch{}
+	_, err = mc.HandleRegionHeartbeat(region, leader)
+	c.Assert(err, NotNil)
+}
+
+func (s *testMockClusterSuite) TestRunScheduler(c *C) {
+	mc, err := NewMockCluster(nil)
+	c.Assert(err, IsNil)
+	mc.AddLeaderStore(1, 1, 1)
+	mc.AddLeaderStore(2, 0, 1)
+	mc.AddLeaderRegion(1, 1, 2)
+
+	regionID, resp, err := mc.RunScheduler(newGrantLeaderScheduler(mc.opt, 2))
+	c.Assert(err, IsNil)
+	c.Assert(regionID, Equals, uint64(1))
+	c.Assert(resp.GetTransferLeader().GetPeer().GetStoreId(), Equals, uint64(2))
+	c.Assert(mc.co.getOperator(1), NotNil)
+
+	// The store is unblocked after running.
+	mc.AddLeaderStore(3, 0, 0)
+	mc.SetStoreOffline(3)
+	_, _, err = mc.RunScheduler(newGrantLeaderScheduler(mc.opt, 2))
+	c.Assert(err, IsNil)
+	regionID, resp, err = mc.RunScheduler(newGrantLeaderScheduler(mc.opt, 3))
+	c.Assert(err, IsNil)
+	c.Assert(regionID, Equals, uint64(0))
+	c.Assert(resp, IsNil)
+}
+
+// MockIDAllocator allocates the IDs in memory from 1, it is only used for
+// test.
+type MockIDAllocator struct {
+	base uint64
+}
+
+// NewMockIDAllocator returns a MockIDAllocator.
+func NewMockIDAllocator() *MockIDAllocator {
+	return &MockIDAllocator{base: 0}
+}
+
+// Alloc returns the next ID.
+func (alloc *MockIDAllocator) Alloc() (uint64, error) {
+	return atomic.AddUint64(&alloc.base, 1), nil
+}
+
+// Status returns the last allocated ID.
+func (alloc *MockIDAllocator) Status() (*IDAllocStatus, error) {
+	base := atomic.LoadUint64(&alloc.base)
+	return &IDAllocStatus{Step: 1, Last: base, End: base}, nil
+}
+
+// MockCluster is a cluster cache filled by the tests, it handles the region
+// heartbeats and runs the schedulers like the leader without a PD server or
+// any store. It is only used for test, e.g. testing a scheduler.
+type MockCluster struct {
+	*clusterInfo
+	opt *scheduleOption
+	co  *coordinator
+}
+
+// NewMockCluster returns an empty mock cluster with the schedule and
+// replication config of cfg, the default config is used if cfg is nil.
+func NewMockCluster(cfg *Config) (*MockCluster, error) {
+	if cfg == nil {
+		cfg = NewConfig()
+		if err := cfg.adjust(); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	return newMockCluster(newClusterInfo(NewMockIDAllocator()), newScheduleOption(cfg)), nil
+}
+
+func newMockCluster(cluster *clusterInfo, opt *scheduleOption) *MockCluster {
+	return &MockCluster{
+		clusterInfo: cluster,
+		opt:         opt,
+		co:          newCoordinator(cluster, opt),
+	}
+}
+
+// SetStoreUp marks the store up and heartbeating.
+func (c *MockCluster) SetStoreUp(storeID uint64) {
+	store := c.getStore(storeID)
+	store.State = metapb.StoreState_Up
+	store.stats.LastHeartbeatTS = time.Now()
+	c.putStore(store)
+}
+
+// SetStoreDown marks the store up but not heartbeating since long ago.
+func (c *MockCluster) SetStoreDown(storeID uint64) {
+	store := c.getStore(storeID)
+	store.State = metapb.StoreState_Up
+	store.stats.LastHeartbeatTS = time.Time{}
+	c.putStore(store)
+}
+
+// SetStoreOffline marks the store offline.
+func (c *MockCluster) SetStoreOffline(storeID uint64) {
+	store := c.getStore(storeID)
+	store.State = metapb.StoreState_Offline
+	c.putStore(store)
+}
+
+// SetStoreBusy sets whether the store reports busy.
+func (c *MockCluster) SetStoreBusy(storeID uint64, busy bool) {
+	store := c.getStore(storeID)
+	store.stats.IsBusy = busy
+	store.stats.LastHeartbeatTS = time.Now()
+	c.putStore(store)
+}
+
+// AddLeaderStore adds an up store with the leader and region counts.
+func (c *MockCluster) AddLeaderStore(storeID uint64, leaderCount, regionCount int) {
+	store := newStoreInfo(&metapb.Store{Id: storeID})
+	store.stats.LastHeartbeatTS = time.Now()
+	store.stats.TotalRegionCount = regionCount
+	store.stats.LeaderRegionCount = leaderCount
+	c.putStore(store)
+}
+
+// AddRegionStore adds an up store with the region count, the capacity is 100
+// and the used is storageRatio of it.
+func (c *MockCluster) AddRegionStore(storeID uint64, regionCount int, storageRatio float64) {
+	store := newStoreInfo(&metapb.Store{Id: storeID})
+	store.stats.LastHeartbeatTS = time.Now()
+	store.stats.RegionCount = uint32(regionCount)
+	store.stats.Capacity = 100
+	store.stats.Available = uint64((1 - storageRatio) * float64(store.stats.Capacity))
+	c.putStore(store)
+}
+
+// AddLabelsStore adds a store like AddRegionStore with the labels.
+func (c *MockCluster) AddLabelsStore(storeID uint64, regionCount int, storageRatio float64, labels map[string]string) {
+	c.AddRegionStore(storeID, regionCount, storageRatio)
+	store := c.getStore(storeID)
+	for k, v := range labels {
+		store.Labels = append(store.Labels, &metapb.StoreLabel{Key: k, Value: v})
+	}
+	c.putStore(store)
+}
+
+// AddLeaderRegion adds a region with the leader on leaderID and the
+// followers on followerIds, the peer IDs are allocated.
+func (c *MockCluster) AddLeaderRegion(regionID uint64, leaderID uint64, followerIds ...uint64) {
+	region := &metapb.Region{Id: regionID}
+	leader, _ := c.allocPeer(leaderID)
+	region.Peers = []*metapb.Peer{leader}
+	for _, id := range followerIds {
+		peer, _ := c.allocPeer(id)
+		region.Peers = append(region.Peers, peer)
+	}
+	c.putRegion(newRegionInfo(region, leader))
+}
+
+// UpdateLeaderCount sets the leader and region counts of the store.
+func (c *MockCluster) UpdateLeaderCount(storeID uint64, leaderCount, regionCount int) {
+	store := c.getStore(storeID)
+	store.stats.TotalRegionCount = regionCount
+	store.stats.LeaderRegionCount = leaderCount
+	c.putStore(store)
+}
+
+// UpdateRegionCount sets the region count and the storage ratio of the store.
+func (c *MockCluster) UpdateRegionCount(storeID uint64, regionCount int, storageRatio float64) {
+	store := c.getStore(storeID)
+	store.stats.RegionCount = uint32(regionCount)
+	store.stats.Capacity = 100
+	store.stats.Available = uint64((1 - storageRatio) * float64(store.stats.Capacity))
+	c.putStore(store)
+}
+
+// UpdateSnapshotCount sets the applying snapshots of the store.
+func (c *MockCluster) UpdateSnapshotCount(storeID uint64, snapshotCount int) {
+	store := c.getStore(storeID)
+	store.stats.ApplyingSnapCount = uint32(snapshotCount)
+	c.putStore(store)
+}
+
+// GetStoreMeta returns the store, nil if it is not found.
+func (c *MockCluster) GetStoreMeta(storeID uint64) *metapb.Store {
+	store := c.getStore(storeID)
+	if store == nil {
+		return nil
+	}
+	return store.Store
+}
+
+// GetRegionMeta returns the region and its leader, nil if it is not found.
+func (c *MockCluster) GetRegionMeta(regionID uint64) (*metapb.Region, *metapb.Peer) {
+	region := c.getRegion(regionID)
+	if region == nil {
+		return nil, nil
+	}
+	return region.Region, region.Leader
+}
+
+// HandleRegionHeartbeat handles the heartbeat of the region like the leader,
+// and returns the operator step to the region if there is any. The steps of
+// the operators are finished by the heartbeats of the changed region.
+func (c *MockCluster) HandleRegionHeartbeat(region *metapb.Region, leader *metapb.Peer) (*pdpb.RegionHeartbeatResponse, error) {
+	r := newRegionInfo(region, leader)
+	if err := c.handleRegionHeartbeat(r); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return c.co.dispatch(r), nil
+}
+
+// RunScheduler runs the scheduler once, and adds the operator it creates. It
+// returns the region scheduled and the first step of the operator, 0 and
+// nil if the scheduler creates nothing.
+func (c *MockCluster) RunScheduler(s Scheduler) (uint64, *pdpb.RegionHeartbeatResponse, error) {
+	if err := s.Prepare(c.clusterInfo); err != nil {
+		return 0, nil, errors.Trace(err)
+	}
+	defer s.Cleanup(c.clusterInfo)
+
+	op := s.Schedule(c.clusterInfo)
+	if op == nil || !c.co.addOperator(op, operatorSourceScheduler) {
+		return 0, nil, nil
+	}
+	region := c.getRegion(op.GetRegionID())
+	if region == nil {
+		return op.GetRegionID(), nil, nil
+	}
+	resp, _ := op.Do(region)
+	return op.GetRegionID(), resp, nil
+}
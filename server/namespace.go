@@ -0,0 +1,468 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/pkg/codec"
+)
+
+// defaultNamespace is the namespace of the stores and regions which are not
+// classified into any other namespace.
+const defaultNamespace = "global"
+
+// Classifier classifies the stores and regions into namespaces. The regions
+// of a namespace are placed and balanced only among the stores of the same
+// namespace.
+type Classifier interface {
+	GetAllNamespaces() []string
+	GetStoreNamespace(store *metapb.Store) string
+	GetRegionNamespace(region *metapb.Region) string
+}
+
+// defaultClassifier puts everything into the default namespace.
+type defaultClassifier struct{}
+
+func (c defaultClassifier) GetAllNamespaces() []string {
+	return []string{defaultNamespace}
+}
+
+func (c defaultClassifier) GetStoreNamespace(store *metapb.Store) string {
+	return defaultNamespace
+}
+
+func (c defaultClassifier) GetRegionNamespace(region *metapb.Region) string {
+	return defaultNamespace
+}
+
+var (
+	// ErrNamespaceNotFound is returned when changing a namespace which does
+	// not exist.
+	ErrNamespaceNotFound = errors.New("namespace is not found")
+	// ErrNamespaceExists is returned when creating a namespace which exists.
+	ErrNamespaceExists = errors.New("namespace exists")
+)
+
+// Namespace is a set of tables and key ranges, and the stores which the
+// regions of them are placed on.
+type Namespace struct {
+	Name      string      `json:"name"`
+	TableIDs  []int64     `json:"table_ids"`
+	KeyRanges []*KeyRange `json:"key_ranges"`
+	StoreIDs  []uint64    `json:"store_ids"`
+	NamespaceConfig
+}
+
+// NamespaceConfig overrides the scheduling config for the regions of a
+// namespace.
+type NamespaceConfig struct {
+	// MaxReplicas is the number of replicas of the regions, 0 means using
+	// the global max-replicas.
+	MaxReplicas uint64 `json:"max_replicas,omitempty"`
+	// LocationLabels are the labels to isolate the replicas of the regions,
+	// empty means using the global location-labels.
+	LocationLabels []string `json:"location_labels,omitempty"`
+	// LeaderScheduleLimit, RegionScheduleLimit and ReplicaScheduleLimit cap
+	// the operators of the regions running at the same time, 0 means using
+	// the global limits. The global limits still apply to all namespaces.
+	LeaderScheduleLimit  uint64 `json:"leader_schedule_limit,omitempty"`
+	RegionScheduleLimit  uint64 `json:"region_schedule_limit,omitempty"`
+	ReplicaScheduleLimit uint64 `json:"replica_schedule_limit,omitempty"`
+	// DisabledSchedulers are the names of the schedulers which do not
+	// schedule the regions.
+	DisabledSchedulers []string `json:"disabled_schedulers,omitempty"`
+}
+
+func (c NamespaceConfig) clone() NamespaceConfig {
+	c.LocationLabels = append([]string(nil), c.LocationLabels...)
+	c.DisabledSchedulers = append([]string(nil), c.DisabledSchedulers...)
+	return c
+}
+
+// KeyRange is the range [StartKey, EndKey) of the raw keys in hex, an empty
+// key means unbounded.
+type KeyRange struct {
+	StartKey string `json:"start_key"`
+	EndKey   string `json:"end_key"`
+}
+
+// Validate checks the keys are in hex and the range is not empty.
+func (r *KeyRange) Validate() error {
+	start, err := hex.DecodeString(r.StartKey)
+	if err != nil {
+		return errors.Annotatef(err, "invalid start key %s", r.StartKey)
+	}
+	end, err := hex.DecodeString(r.EndKey)
+	if err != nil {
+		return errors.Annotatef(err, "invalid end key %s", r.EndKey)
+	}
+	if len(end) > 0 && bytes.Compare(start, end) >= 0 {
+		return errors.Errorf("start key %s is not less than end key %s", r.StartKey, r.EndKey)
+	}
+	return nil
+}
+
+// encode returns the range of the region keys, the keys of a valid range can
+// always be decoded.
+func (r *KeyRange) encode() (start, end []byte) {
+	if key, _ := hex.DecodeString(r.StartKey); len(key) > 0 {
+		start = codec.EncodeBytes(key)
+	}
+	if key, _ := hex.DecodeString(r.EndKey); len(key) > 0 {
+		end = codec.EncodeBytes(key)
+	}
+	return start, end
+}
+
+func (r *KeyRange) overlaps(other *KeyRange) bool {
+	start, end := r.encode()
+	otherStart, otherEnd := other.encode()
+	return (len(otherEnd) == 0 || bytes.Compare(start, otherEnd) < 0) &&
+		(len(end) == 0 || bytes.Compare(otherStart, end) < 0)
+}
+
+type namespaceKeyRange struct {
+	start []byte
+	end   []byte
+	name  string
+}
+
+// tableNamespaceClassifier classifies the regions by the table ID or the key
+// range of the start key. The stores, tables and ranges not in any namespace
+// are in the default namespace. It is not changed after created.
+type tableNamespaceClassifier struct {
+	names  []string
+	tables map[int64]string
+	ranges []*namespaceKeyRange
+	stores map[uint64]string
+}
+
+func newTableNamespaceClassifier(namespaces []*Namespace) *tableNamespaceClassifier {
+	c := &tableNamespaceClassifier{
+		names:  []string{defaultNamespace},
+		tables: make(map[int64]string),
+		stores: make(map[uint64]string),
+	}
+	for _, ns := range namespaces {
+		c.names = append(c.names, ns.Name)
+		for _, id := range ns.TableIDs {
+			c.tables[id] = ns.Name
+		}
+		for _, r := range ns.KeyRanges {
+			start, end := r.encode()
+			c.ranges = append(c.ranges, &namespaceKeyRange{start: start, end: end, name: ns.Name})
+		}
+		for _, id := range ns.StoreIDs {
+			c.stores[id] = ns.Name
+		}
+	}
+	sort.Strings(c.names[1:])
+	return c
+}
+
+func (c *tableNamespaceClassifier) GetAllNamespaces() []string {
+	return append([]string(nil), c.names...)
+}
+
+func (c *tableNamespaceClassifier) GetStoreNamespace(store *metapb.Store) string {
+	if name, ok := c.stores[store.GetId()]; ok {
+		return name
+	}
+	return defaultNamespace
+}
+
+func (c *tableNamespaceClassifier) GetRegionNamespace(region *metapb.Region) string {
+	key := region.GetStartKey()
+	if name, ok := c.tables[codec.DecodeTableID(key)]; ok {
+		return name
+	}
+	for _, r := range c.ranges {
+		if bytes.Compare(key, r.start) >= 0 && (len(r.end) == 0 || bytes.Compare(key, r.end) < 0) {
+			return r.name
+		}
+	}
+	return defaultNamespace
+}
+
+// namespaceFilter filters the stores not in the namespace.
+type namespaceFilter struct {
+	classifier Classifier
+	namespace  string
+}
+
+func newNamespaceFilter(classifier Classifier, namespace string) *namespaceFilter {
+	return &namespaceFilter{
+		classifier: classifier,
+		namespace:  namespace,
+	}
+}
+
+func (f *namespaceFilter) filter(store *storeInfo) bool {
+	return f.classifier.GetStoreNamespace(store.Store) != f.namespace
+}
+
+func (f *namespaceFilter) FilterSource(store *storeInfo) bool {
+	return f.filter(store)
+}
+
+func (f *namespaceFilter) FilterTarget(store *storeInfo) bool {
+	return f.filter(store)
+}
+
+// namespaceChecker moves the replicas on the stores of other namespaces to
+// the stores of the region's namespace.
+type namespaceChecker struct {
+	opt     *scheduleOption
+	cluster *clusterInfo
+	checker *replicaChecker
+}
+
+func newNamespaceChecker(opt *scheduleOption, cluster *clusterInfo, checker *replicaChecker) *namespaceChecker {
+	return &namespaceChecker{
+		opt:     opt,
+		cluster: cluster,
+		checker: checker,
+	}
+}
+
+func (n *namespaceChecker) Check(region *regionInfo) Operator {
+	classifier := n.opt.GetClassifier()
+	namespace := classifier.GetRegionNamespace(region.Region)
+	region = withoutEnginePeers(n.cluster, n.opt, region)
+	for _, peer := range region.GetPeers() {
+		store := n.cluster.getStore(peer.GetStoreId())
+		if store == nil || classifier.GetStoreNamespace(store.Store) == namespace {
+			continue
+		}
+		// The best peer is selected in the stores of the region's namespace.
+		newPeer, _ := n.checker.selectBestReplacement(region, peer)
+		if newPeer == nil {
+			return nil
+		}
+		return newTransferPeer(region, peer, newPeer)
+	}
+	return nil
+}
+
+// GetNamespaces returns the namespaces ordered by name.
+func (s *Server) GetNamespaces() ([]*Namespace, error) {
+	namespaces, err := s.kv.loadNamespaces()
+	return namespaces, errors.Trace(err)
+}
+
+// CreateNamespace creates an empty namespace.
+func (s *Server) CreateNamespace(name string) error {
+	if name == "" || name == defaultNamespace || strings.Contains(name, "/") {
+		return errors.Errorf("invalid namespace name %q", name)
+	}
+	return s.updateNamespaces(func(namespaces []*Namespace) ([]*Namespace, *Namespace, error) {
+		if findNamespace(namespaces, name) != nil {
+			return nil, nil, errors.Trace(ErrNamespaceExists)
+		}
+		ns := &Namespace{Name: name}
+		return append(namespaces, ns), ns, nil
+	})
+}
+
+// DeleteNamespace deletes the namespace, its stores, tables and key ranges
+// go back to the default namespace.
+func (s *Server) DeleteNamespace(name string) error {
+	configLock.Lock()
+	defer configLock.Unlock()
+
+	namespaces, err := s.kv.loadNamespaces()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if findNamespace(namespaces, name) == nil {
+		return errors.Trace(ErrNamespaceNotFound)
+	}
+	if err = s.kv.deleteNamespace(name); err != nil {
+		return errors.Trace(err)
+	}
+	var left []*Namespace
+	for _, ns := range namespaces {
+		if ns.Name != name {
+			left = append(left, ns)
+		}
+	}
+	s.scheduleOpt.SetNamespaces(left)
+	log.Infof("namespace %s is deleted", name)
+	return nil
+}
+
+// AddNamespaceTableID adds the table to the namespace, a table can only be
+// in one namespace.
+func (s *Server) AddNamespaceTableID(name string, tableID int64) error {
+	return s.updateNamespace(name, func(ns *Namespace, namespaces []*Namespace) error {
+		for _, other := range namespaces {
+			for _, id := range other.TableIDs {
+				if id == tableID {
+					return errors.Errorf("table %d is in namespace %s already", tableID, other.Name)
+				}
+			}
+		}
+		ns.TableIDs = append(ns.TableIDs, tableID)
+		return nil
+	})
+}
+
+// RemoveNamespaceTableID removes the table from the namespace.
+func (s *Server) RemoveNamespaceTableID(name string, tableID int64) error {
+	return s.updateNamespace(name, func(ns *Namespace, namespaces []*Namespace) error {
+		for i, id := range ns.TableIDs {
+			if id == tableID {
+				ns.TableIDs = append(ns.TableIDs[:i], ns.TableIDs[i+1:]...)
+				return nil
+			}
+		}
+		return errors.Errorf("table %d is not in namespace %s", tableID, name)
+	})
+}
+
+// AddNamespaceKeyRange adds the key range to the namespace, the key ranges of
+// all namespaces can't overlap.
+func (s *Server) AddNamespaceKeyRange(name string, r *KeyRange) error {
+	if err := r.Validate(); err != nil {
+		return errors.Trace(err)
+	}
+	return s.updateNamespace(name, func(ns *Namespace, namespaces []*Namespace) error {
+		for _, other := range namespaces {
+			for _, kr := range other.KeyRanges {
+				if kr.overlaps(r) {
+					return errors.Errorf("key range [%s, %s) overlaps [%s, %s) of namespace %s", r.StartKey, r.EndKey, kr.StartKey, kr.EndKey, other.Name)
+				}
+			}
+		}
+		ns.KeyRanges = append(ns.KeyRanges, r)
+		return nil
+	})
+}
+
+// RemoveNamespaceKeyRange removes the key range starting from the start key
+// from the namespace.
+func (s *Server) RemoveNamespaceKeyRange(name string, startKey string) error {
+	return s.updateNamespace(name, func(ns *Namespace, namespaces []*Namespace) error {
+		for i, r := range ns.KeyRanges {
+			if r.StartKey == startKey {
+				ns.KeyRanges = append(ns.KeyRanges[:i], ns.KeyRanges[i+1:]...)
+				return nil
+			}
+		}
+		return errors.Errorf("key range starting from %q is not in namespace %s", startKey, name)
+	})
+}
+
+// AddNamespaceStoreID assigns the store to the namespace, a store can only be
+// in one namespace.
+func (s *Server) AddNamespaceStoreID(name string, storeID uint64) error {
+	return s.updateNamespace(name, func(ns *Namespace, namespaces []*Namespace) error {
+		for _, other := range namespaces {
+			for _, id := range other.StoreIDs {
+				if id == storeID {
+					return errors.Errorf("store %d is in namespace %s already", storeID, other.Name)
+				}
+			}
+		}
+		ns.StoreIDs = append(ns.StoreIDs, storeID)
+		return nil
+	})
+}
+
+// RemoveNamespaceStoreID removes the store from the namespace, it goes back
+// to the default namespace.
+func (s *Server) RemoveNamespaceStoreID(name string, storeID uint64) error {
+	return s.updateNamespace(name, func(ns *Namespace, namespaces []*Namespace) error {
+		for i, id := range ns.StoreIDs {
+			if id == storeID {
+				ns.StoreIDs = append(ns.StoreIDs[:i], ns.StoreIDs[i+1:]...)
+				return nil
+			}
+		}
+		return errors.Errorf("store %d is not in namespace %s", storeID, name)
+	})
+}
+
+// SetNamespaceConfig overrides the scheduling config of the namespace.
+func (s *Server) SetNamespaceConfig(name string, cfg *NamespaceConfig) error {
+	return s.updateNamespace(name, func(ns *Namespace, namespaces []*Namespace) error {
+		ns.NamespaceConfig = cfg.clone()
+		return nil
+	})
+}
+
+// updateNamespace changes the existing namespace by fn.
+func (s *Server) updateNamespace(name string, fn func(ns *Namespace, namespaces []*Namespace) error) error {
+	return s.updateNamespaces(func(namespaces []*Namespace) ([]*Namespace, *Namespace, error) {
+		ns := findNamespace(namespaces, name)
+		if ns == nil {
+			return nil, nil, errors.Trace(ErrNamespaceNotFound)
+		}
+		if err := fn(ns, namespaces); err != nil {
+			return nil, nil, errors.Trace(err)
+		}
+		return namespaces, ns, nil
+	})
+}
+
+// updateNamespaces saves the namespace changed by fn in etcd, and then
+// applies the namespaces returned by fn to the scheduling.
+func (s *Server) updateNamespaces(fn func(namespaces []*Namespace) ([]*Namespace, *Namespace, error)) error {
+	configLock.Lock()
+	defer configLock.Unlock()
+
+	namespaces, err := s.kv.loadNamespaces()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	namespaces, changed, err := fn(namespaces)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err = s.kv.saveNamespace(changed); err != nil {
+		return errors.Trace(err)
+	}
+	s.scheduleOpt.SetNamespaces(namespaces)
+	log.Infof("namespace %s is updated: %+v", changed.Name, changed)
+	return nil
+}
+
+// loadNamespaces applies the namespaces saved in etcd after becoming leader.
+func (s *Server) loadNamespaces() error {
+	configLock.Lock()
+	defer configLock.Unlock()
+
+	namespaces, err := s.kv.loadNamespaces()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	s.scheduleOpt.SetNamespaces(namespaces)
+	return nil
+}
+
+func findNamespace(namespaces []*Namespace, name string) *Namespace {
+	for _, ns := range namespaces {
+		if ns.Name == name {
+			return ns
+		}
+	}
+	return nil
+}
@@ -0,0 +1,336 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"github.com/juju/errors"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/pkg/codec"
+)
+
+var _ = Suite(&testNamespaceSuite{})
+
+type testNamespaceSuite struct{}
+
+func newTestNamespaceClassifier() *tableNamespaceClassifier {
+	return newTableNamespaceClassifier([]*Namespace{
+		{Name: "ns2", TableIDs: []int64{3}, StoreIDs: []uint64{3}},
+		{Name: "ns1", TableIDs: []int64{1, 2}, StoreIDs: []uint64{1, 2}},
+	})
+}
+
+// setRegionTable sets the start key of the region to the first key of the table.
+func (c *MockCluster) setRegionTable(regionID uint64, tableID int64) {
+	region := c.getRegion(regionID)
+	region.StartKey = codec.GenerateTableKey(tableID)
+	c.putRegion(region)
+}
+
+func (s *testNamespaceSuite) TestTableClassifier(c *C) {
+	classifier := newTestNamespaceClassifier()
+	c.Assert(classifier.GetAllNamespaces(), DeepEquals, []string{defaultNamespace, "ns1", "ns2"})
+
+	c.Assert(classifier.GetStoreNamespace(&metapb.Store{Id: 1}), Equals, "ns1")
+	c.Assert(classifier.GetStoreNamespace(&metapb.Store{Id: 3}), Equals, "ns2")
+	c.Assert(classifier.GetStoreNamespace(&metapb.Store{Id: 4}), Equals, defaultNamespace)
+
+	c.Assert(classifier.GetRegionNamespace(&metapb.Region{StartKey: codec.GenerateTableKey(2)}), Equals, "ns1")
+	c.Assert(classifier.GetRegionNamespace(&metapb.Region{StartKey: codec.GenerateTableKey(3)}), Equals, "ns2")
+	c.Assert(classifier.GetRegionNamespace(&metapb.Region{StartKey: codec.GenerateTableKey(4)}), Equals, defaultNamespace)
+	c.Assert(classifier.GetRegionNamespace(&metapb.Region{}), Equals, defaultNamespace)
+}
+
+func (s *testNamespaceSuite) TestReplicaChecker(c *C) {
+	cluster := newClusterInfo(NewMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	_, opt := newTestScheduleConfig()
+	opt.SetClassifier(newTestNamespaceClassifier())
+	rc := newReplicaChecker(opt, cluster)
+
+	tc.AddRegionStore(1, 4, 0.4)
+	tc.AddRegionStore(2, 3, 0.3)
+	tc.AddRegionStore(3, 1, 0.1)
+	tc.AddRegionStore(4, 1, 0.1)
+	tc.AddLeaderRegion(1, 1)
+
+	// The region of the default namespace is added to store 4.
+	checkAddPeer(c, rc.Check(cluster.getRegion(1)), 4)
+	// The region of table 1 is added to store 2 in ns1.
+	tc.setRegionTable(1, 1)
+	checkAddPeer(c, rc.Check(cluster.getRegion(1)), 2)
+	// No store of ns2 is available besides store 3.
+	tc.AddLeaderRegion(2, 3)
+	tc.setRegionTable(2, 3)
+	c.Assert(rc.Check(cluster.getRegion(2)), IsNil)
+}
+
+func (s *testNamespaceSuite) TestNamespaceChecker(c *C) {
+	cluster := newClusterInfo(NewMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	_, opt := newTestScheduleConfig()
+	opt.SetClassifier(newTestNamespaceClassifier())
+	rc := newReplicaChecker(opt, cluster)
+	nc := newNamespaceChecker(opt, cluster, rc)
+
+	tc.AddRegionStore(1, 1, 0.1)
+	tc.AddRegionStore(2, 1, 0.1)
+	tc.AddRegionStore(3, 1, 0.1)
+	tc.AddRegionStore(4, 1, 0.1)
+	tc.AddRegionStore(5, 1, 0.1)
+	tc.AddLeaderRegion(1, 1, 4, 5)
+	c.Assert(nc.Check(cluster.getRegion(1)), IsNil)
+
+	// The peers of table 1 on the default stores are moved to ns1.
+	tc.setRegionTable(1, 1)
+	checkTransferPeer(c, nc.Check(cluster.getRegion(1)), 4, 2)
+
+	// Nothing to do if ns1 has no more stores.
+	tc.AddLeaderRegion(2, 1, 2, 4)
+	tc.setRegionTable(2, 2)
+	c.Assert(nc.Check(cluster.getRegion(2)), IsNil)
+}
+
+func (s *testNamespaceSuite) TestBalanceLeader(c *C) {
+	cluster := newClusterInfo(NewMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	cfg, opt := newTestScheduleConfig()
+	cfg.MinLeaderCount = 10
+	cfg.MinBalanceDiffRatio = 0.1
+	opt.SetClassifier(newTestNamespaceClassifier())
+	lb := newBalanceLeaderScheduler(opt)
+
+	tc.AddLeaderStore(1, 20, 30)
+	tc.AddLeaderStore(2, 15, 30)
+	tc.AddLeaderStore(4, 5, 30)
+	tc.AddLeaderRegion(1, 1, 2, 4)
+	tc.setRegionTable(1, 1)
+
+	// The leader is transferred within ns1 though store 4 has less leaders.
+	checkTransferLeader(c, lb.Schedule(cluster), 1, 2)
+
+	// The region of the default namespace on the stores of ns1 is not
+	// balanced in ns1.
+	tc.setRegionTable(1, 4)
+	c.Assert(lb.Schedule(cluster), IsNil)
+}
+
+func (s *testNamespaceSuite) TestBalanceStorage(c *C) {
+	cluster := newClusterInfo(NewMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	cfg, opt := newTestScheduleConfig()
+	cfg.MinBalanceDiffRatio = 0.1
+	opt.SetClassifier(newTestNamespaceClassifier())
+	opt.rep = newReplication(&ReplicationConfig{MaxReplicas: 1})
+	sb := newBalanceStorageScheduler(opt)
+
+	tc.AddRegionStore(1, 10, 0.5)
+	tc.AddRegionStore(2, 10, 0.3)
+	tc.AddRegionStore(4, 10, 0.1)
+	tc.AddLeaderRegion(1, 1)
+	tc.setRegionTable(1, 1)
+
+	// The region is moved within ns1 though store 4 has less storage used.
+	checkTransferPeer(c, sb.Schedule(cluster), 1, 2)
+}
+
+func (s *testNamespaceSuite) TestNamespaceConfig(c *C) {
+	cluster := newClusterInfo(NewMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	cfg, opt := newTestScheduleConfig()
+	cfg.MinLeaderCount = 10
+	cfg.MinBalanceDiffRatio = 0.1
+	opt.SetNamespaces([]*Namespace{
+		{Name: "ns1", TableIDs: []int64{1}, StoreIDs: []uint64{1, 2, 3, 4}, NamespaceConfig: NamespaceConfig{
+			MaxReplicas:        4,
+			DisabledSchedulers: []string{"balance-leader-scheduler"},
+		}},
+	})
+	rc := newReplicaChecker(opt, cluster)
+	lb := newBalanceLeaderScheduler(opt)
+	co := newCoordinator(cluster, opt)
+
+	tc.AddLeaderStore(1, 20, 30)
+	tc.AddLeaderStore(2, 5, 30)
+	tc.AddLeaderStore(3, 5, 30)
+	tc.AddLeaderStore(4, 5, 30)
+	tc.AddLeaderRegion(1, 1, 2, 3)
+
+	// The region of the default namespace has enough replicas.
+	region := cluster.getRegion(1)
+	c.Assert(opt.GetRegionMaxReplicas(region.Region), Equals, 3)
+	c.Assert(rc.Check(region), IsNil)
+	c.Assert(co.isSchedulerDisabled(lb.GetName(), newTransferLeader(region, region.GetStorePeer(2))), IsFalse)
+
+	// The region of ns1 needs 4 replicas, and its leader is not balanced.
+	tc.setRegionTable(1, 1)
+	region = cluster.getRegion(1)
+	c.Assert(opt.GetRegionMaxReplicas(region.Region), Equals, 4)
+	checkAddPeer(c, rc.Check(region), 4)
+	c.Assert(lb.Schedule(cluster), IsNil)
+	op := newTransferLeader(region, region.GetStorePeer(2))
+	c.Assert(co.isSchedulerDisabled(lb.GetName(), op), IsTrue)
+	c.Assert(co.isSchedulerDisabled("balance-storage-scheduler", op), IsFalse)
+}
+
+func (s *testNamespaceSuite) TestNamespaceLocationLabels(c *C) {
+	cluster := newClusterInfo(NewMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	_, opt := newTestScheduleConfig()
+	tc.AddLabelsStore(1, 1, 0.1, map[string]string{"zone": "z1"})
+	tc.AddLabelsStore(2, 1, 0.1, map[string]string{"zone": "z1"})
+	tc.AddLabelsStore(3, 1, 0.1, map[string]string{"zone": "z2"})
+	tc.AddLabelsStore(4, 1, 0.3, map[string]string{"zone": "z3"})
+	tc.AddLeaderRegion(1, 1, 3)
+	tc.setRegionTable(1, 1)
+	region := cluster.getRegion(1)
+
+	// Without location labels, the replica is added to the store with less
+	// storage used.
+	opt.SetNamespaces([]*Namespace{{Name: "ns1", TableIDs: []int64{1}, StoreIDs: []uint64{1, 2, 3, 4}}})
+	c.Assert(opt.GetRegionReplication(region.Region), Equals, opt.GetReplication())
+	checkAddPeer(c, newReplicaChecker(opt, cluster).Check(region), 2)
+
+	// The location labels of ns1 isolate the replicas by zone.
+	opt.SetNamespaces([]*Namespace{
+		{Name: "ns1", TableIDs: []int64{1}, StoreIDs: []uint64{1, 2, 3, 4}, NamespaceConfig: NamespaceConfig{
+			LocationLabels: []string{"zone"},
+		}},
+	})
+	checkAddPeer(c, newReplicaChecker(opt, cluster).Check(region), 4)
+}
+
+func (s *testNamespaceSuite) TestNamespaceScheduleLimit(c *C) {
+	cluster := newClusterInfo(NewMockIDAllocator())
+	tc := newTestClusterInfo(cluster)
+
+	_, opt := newTestScheduleConfig()
+	opt.SetNamespaces([]*Namespace{
+		{Name: "ns1", TableIDs: []int64{1}, StoreIDs: []uint64{1, 2, 3}, NamespaceConfig: NamespaceConfig{
+			LeaderScheduleLimit:  1,
+			ReplicaScheduleLimit: 1,
+		}},
+	})
+	co := newCoordinator(cluster, opt)
+	sc := newScheduleController(co, newBalanceLeaderScheduler(opt))
+
+	tc.AddRegionStore(1, 3, 0.1)
+	tc.AddRegionStore(2, 3, 0.1)
+	tc.AddRegionStore(3, 0, 0.1)
+	for id := uint64(1); id <= 3; id++ {
+		tc.AddLeaderRegion(id, 1, 2)
+		tc.setRegionTable(id, 1)
+	}
+	c.Assert(opt.GetNamespaceLeaderScheduleLimit("ns1"), Equals, uint64(1))
+	c.Assert(opt.GetNamespaceRegionScheduleLimit("ns1"), Equals, opt.GetRegionScheduleLimit())
+	c.Assert(opt.GetNamespaceLeaderScheduleLimit(defaultNamespace), Equals, opt.GetLeaderScheduleLimit())
+
+	// The leader operators of ns1 are limited by the limit of ns1.
+	region := cluster.getRegion(1)
+	op := newTransferLeader(region, region.GetStorePeer(2))
+	c.Assert(co.allowNamespaceSchedule(sc, op), IsTrue)
+	c.Assert(co.addOperator(op, operatorSourceScheduler), IsTrue)
+	c.Assert(co.allowNamespaceSchedule(sc, op), IsFalse)
+	c.Assert(sc.AllowNamespaceSchedule(defaultNamespace), IsTrue)
+	c.Assert(sc.AllowSchedule(), IsTrue)
+	co.removeOperator(op, operatorStatusCancel)
+	c.Assert(sc.AllowNamespaceSchedule("ns1"), IsTrue)
+
+	// The replica checker is limited by the limit of ns1.
+	checkAddPeerResp(c, co.dispatch(cluster.getRegion(2)), 3)
+	c.Assert(co.dispatch(cluster.getRegion(3)), IsNil)
+	co.removeOperator(co.getOperator(2), operatorStatusCancel)
+	checkAddPeerResp(c, co.dispatch(cluster.getRegion(3)), 3)
+}
+
+func (s *testNamespaceSuite) TestKeyRangeClassifier(c *C) {
+	classifier := newTableNamespaceClassifier([]*Namespace{
+		{Name: "ns1", TableIDs: []int64{1}, KeyRanges: []*KeyRange{{StartKey: "6d", EndKey: "6e"}}},
+		{Name: "ns2", KeyRanges: []*KeyRange{{StartKey: "7a"}}},
+	})
+	region := func(key string) *metapb.Region {
+		return &metapb.Region{StartKey: codec.EncodeBytes([]byte(key))}
+	}
+	c.Assert(classifier.GetRegionNamespace(region("m")), Equals, "ns1")
+	c.Assert(classifier.GetRegionNamespace(region("m_meta")), Equals, "ns1")
+	c.Assert(classifier.GetRegionNamespace(region("n")), Equals, defaultNamespace)
+	c.Assert(classifier.GetRegionNamespace(region("z")), Equals, "ns2")
+	c.Assert(classifier.GetRegionNamespace(region("zzz")), Equals, "ns2")
+	c.Assert(classifier.GetRegionNamespace(&metapb.Region{StartKey: codec.GenerateTableKey(1)}), Equals, "ns1")
+
+	c.Assert((&KeyRange{StartKey: "zz"}).Validate(), NotNil)
+	c.Assert((&KeyRange{StartKey: "6e", EndKey: "6d"}).Validate(), NotNil)
+	c.Assert((&KeyRange{EndKey: "6d"}).Validate(), IsNil)
+	c.Assert((&KeyRange{StartKey: "6d", EndKey: "6f"}).overlaps(&KeyRange{StartKey: "6e"}), IsTrue)
+	c.Assert((&KeyRange{EndKey: "6e"}).overlaps(&KeyRange{StartKey: "6e"}), IsFalse)
+}
+
+func (s *testNamespaceSuite) TestManageNamespaces(c *C) {
+	svr, cleanup := mustRunTestServer(c)
+	defer cleanup()
+	mustWaitLeader(c, []*Server{svr})
+
+	c.Assert(svr.CreateNamespace(defaultNamespace), NotNil)
+	c.Assert(svr.CreateNamespace("ns1"), IsNil)
+	c.Assert(errors.Cause(svr.CreateNamespace("ns1")), Equals, ErrNamespaceExists)
+	c.Assert(svr.CreateNamespace("ns2"), IsNil)
+	c.Assert(errors.Cause(svr.AddNamespaceTableID("ns3", 1)), Equals, ErrNamespaceNotFound)
+
+	c.Assert(svr.AddNamespaceTableID("ns1", 1), IsNil)
+	c.Assert(svr.AddNamespaceTableID("ns2", 1), NotNil)
+	c.Assert(svr.AddNamespaceKeyRange("ns1", &KeyRange{StartKey: "6d", EndKey: "6e"}), IsNil)
+	c.Assert(svr.AddNamespaceKeyRange("ns2", &KeyRange{StartKey: "6d00"}), NotNil)
+	c.Assert(svr.AddNamespaceStoreID("ns1", 1), IsNil)
+	c.Assert(svr.AddNamespaceStoreID("ns2", 1), NotNil)
+	c.Assert(svr.AddNamespaceStoreID("ns2", 2), IsNil)
+
+	namespaces, err := svr.GetNamespaces()
+	c.Assert(err, IsNil)
+	c.Assert(namespaces, DeepEquals, []*Namespace{
+		{Name: "ns1", TableIDs: []int64{1}, KeyRanges: []*KeyRange{{StartKey: "6d", EndKey: "6e"}}, StoreIDs: []uint64{1}},
+		{Name: "ns2", StoreIDs: []uint64{2}},
+	})
+	classifier := svr.scheduleOpt.GetClassifier()
+	c.Assert(classifier.GetAllNamespaces(), DeepEquals, []string{defaultNamespace, "ns1", "ns2"})
+	c.Assert(classifier.GetStoreNamespace(&metapb.Store{Id: 2}), Equals, "ns2")
+	c.Assert(classifier.GetRegionNamespace(&metapb.Region{StartKey: codec.GenerateTableKey(1)}), Equals, "ns1")
+
+	c.Assert(svr.RemoveNamespaceTableID("ns1", 1), IsNil)
+	c.Assert(svr.RemoveNamespaceTableID("ns1", 1), NotNil)
+	c.Assert(svr.RemoveNamespaceKeyRange("ns1", "6d"), IsNil)
+	c.Assert(svr.RemoveNamespaceStoreID("ns1", 1), IsNil)
+	c.Assert(svr.DeleteNamespace("ns2"), IsNil)
+	c.Assert(errors.Cause(svr.DeleteNamespace("ns2")), Equals, ErrNamespaceNotFound)
+	classifier = svr.scheduleOpt.GetClassifier()
+	c.Assert(classifier.GetStoreNamespace(&metapb.Store{Id: 2}), Equals, defaultNamespace)
+	c.Assert(classifier.GetRegionNamespace(&metapb.Region{StartKey: codec.GenerateTableKey(1)}), Equals, defaultNamespace)
+
+	c.Assert(svr.SetNamespaceConfig("ns1", &NamespaceConfig{MaxReplicas: 5}), IsNil)
+	c.Assert(errors.Cause(svr.SetNamespaceConfig("ns2", &NamespaceConfig{})), Equals, ErrNamespaceNotFound)
+	c.Assert(svr.scheduleOpt.GetRegionMaxReplicas(&metapb.Region{}), Equals, int(svr.cfg.Replication.MaxReplicas))
+	namespaces, err = svr.GetNamespaces()
+	c.Assert(err, IsNil)
+	c.Assert(namespaces[0].MaxReplicas, Equals, uint64(5))
+
+	// The namespaces are applied again after becoming leader.
+	svr.scheduleOpt.SetClassifier(defaultClassifier{})
+	c.Assert(svr.loadNamespaces(), IsNil)
+	c.Assert(svr.scheduleOpt.GetClassifier().GetAllNamespaces(), DeepEquals, []string{defaultNamespace, "ns1"})
+}
@@ -0,0 +1,339 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+)
+
+// Types of the cluster events.
+const (
+	ClusterEventStoreDown         = "store_down"
+	ClusterEventStoreTombstone    = "store_tombstone"
+	ClusterEventLeaderChange      = "leader_change"
+	ClusterEventRegionUnavailable = "region_unavailable"
+	ClusterEventOperatorTimeout   = "operator_timeout"
+)
+
+const (
+	webhookEventsChanSize = 1000
+	webhookRequestTimeout = 5 * time.Second
+)
+
+var (
+	// ErrWebhookNotFound is returned when deleting a webhook which does not exist.
+	ErrWebhookNotFound = errors.New("webhook is not found")
+	errNotLeader       = errors.New("server is not leader")
+)
+
+var clusterEventTypes = map[string]struct{}{
+	ClusterEventStoreDown:         {},
+	ClusterEventStoreTombstone:    {},
+	ClusterEventLeaderChange:      {},
+	ClusterEventRegionUnavailable: {},
+	ClusterEventOperatorTimeout:   {},
+}
+
+// ClusterEvent is a change of the cluster, it is published on the event bus
+// of the leader and sent to the webhooks.
+type ClusterEvent struct {
+	Type     string    `json:"type"`
+	Time     time.Time `json:"time"`
+	StoreID  uint64    `json:"store_id,omitempty"`
+	RegionID uint64    `json:"region_id,omitempty"`
+	Leader   string    `json:"leader,omitempty"`
+	Message  string    `json:"message"`
+}
+
+// eventBus publishes the cluster events to the subscribers. The events are
+// dropped for the subscribers which are too slow to receive them.
+type eventBus struct {
+	sync.RWMutex
+	nextID      uint64
+	subscribers map[uint64]chan *ClusterEvent
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{
+		subscribers: make(map[uint64]chan *ClusterEvent),
+	}
+}
+
+func (b *eventBus) subscribe(size int) (uint64, <-chan *ClusterEvent) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.nextID++
+	ch := make(chan *ClusterEvent, size)
+	b.subscribers[b.nextID] = ch
+	return b.nextID, ch
+}
+
+// unsubscribe closes the channel of the subscriber.
+func (b *eventBus) unsubscribe(id uint64) {
+	b.Lock()
+	defer b.Unlock()
+
+	if ch, ok := b.subscribers[id]; ok {
+		close(ch)
+		delete(b.subscribers, id)
+	}
+}
+
+// publish does nothing if the bus is nil, e.g. the coordinator in tests.
+func (b *eventBus) publish(evt *ClusterEvent) {
+	if b == nil {
+		return
+	}
+	if evt.Time.IsZero() {
+		evt.Time = time.Now()
+	}
+	log.Infof("cluster event %s: %s", evt.Type, evt.Message)
+
+	b.RLock()
+	defer b.RUnlock()
+
+	for id, ch := range b.subscribers {
+		select {
+		case ch <- evt:
+		default:
+			log.Warnf("subscriber %d is too slow, drop cluster event %s", id, evt.Type)
+		}
+	}
+}
+
+// Webhook is an HTTP target which the cluster events are posted to in json.
+type Webhook struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Types are the types of the events to receive, all types if it is empty.
+	Types []string `json:"types,omitempty"`
+}
+
+// Validate checks the name, url and event types of the webhook.
+func (h *Webhook) Validate() error {
+	if len(h.Name) == 0 || strings.Contains(h.Name, "/") {
+		return errors.New("invalid webhook name")
+	}
+	u, err := url.Parse(h.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return errors.New("invalid webhook url")
+	}
+	for _, t := range h.Types {
+		if _, ok := clusterEventTypes[t]; !ok {
+			return errors.Errorf("invalid event type %s", t)
+		}
+	}
+	return nil
+}
+
+func (h *Webhook) accept(evt *ClusterEvent) bool {
+	if len(h.Types) == 0 {
+		return true
+	}
+	for _, t := range h.Types {
+		if t == evt.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookSender posts the events it subscribed to the webhook until it is
+// unsubscribed.
+type webhookSender struct {
+	hook   *Webhook
+	id     uint64
+	events <-chan *ClusterEvent
+	client *http.Client
+}
+
+func newWebhookSender(hook *Webhook, bus *eventBus) *webhookSender {
+	id, events := bus.subscribe(webhookEventsChanSize)
+	return &webhookSender{
+		hook:   hook,
+		id:     id,
+		events: events,
+		client: &http.Client{Timeout: webhookRequestTimeout},
+	}
+}
+
+func (w *webhookSender) run() {
+	for evt := range w.events {
+		if !w.hook.accept(evt) {
+			continue
+		}
+		if err := w.send(evt); err != nil {
+			log.Warnf("send cluster event %s to webhook %s error: %v", evt.Type, w.hook.Name, err)
+		}
+	}
+}
+
+func (w *webhookSender) send(evt *ClusterEvent) error {
+	data, err := json.Marshal(evt)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	resp, err := w.client.Post(w.hook.URL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return errors.Errorf("webhook responds with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// webhookManager runs the senders of the webhooks which are saved in etcd,
+// the senders only run on the leader.
+type webhookManager struct {
+	sync.RWMutex
+	bus     *eventBus
+	senders map[string]*webhookSender
+}
+
+func newWebhookManager(bus *eventBus) *webhookManager {
+	return &webhookManager{
+		bus:     bus,
+		senders: make(map[string]*webhookSender),
+	}
+}
+
+// start starts the senders of the webhooks.
+func (m *webhookManager) start(hooks []*Webhook) {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, hook := range hooks {
+		m.startSender(hook)
+	}
+}
+
+// stop stops all the senders.
+func (m *webhookManager) stop() {
+	m.Lock()
+	defer m.Unlock()
+
+	for name := range m.senders {
+		m.stopSender(name)
+	}
+}
+
+func (m *webhookManager) add(hook *Webhook) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.stopSender(hook.Name)
+	m.startSender(hook)
+}
+
+func (m *webhookManager) remove(name string) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.stopSender(name)
+}
+
+func (m *webhookManager) startSender(hook *Webhook) {
+	sender := newWebhookSender(hook, m.bus)
+	m.senders[hook.Name] = sender
+	go sender.run()
+}
+
+func (m *webhookManager) stopSender(name string) {
+	if sender, ok := m.senders[name]; ok {
+		m.bus.unsubscribe(sender.id)
+		delete(m.senders, name)
+	}
+}
+
+// webhooks returns the running webhooks sorted by name.
+func (m *webhookManager) webhooks() []*Webhook {
+	m.RLock()
+	defer m.RUnlock()
+
+	names := make([]string, 0, len(m.senders))
+	for name := range m.senders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hooks := make([]*Webhook, 0, len(names))
+	for _, name := range names {
+		hooks = append(hooks, m.senders[name].hook)
+	}
+	return hooks
+}
+
+// startWebhooks starts the webhooks after becoming leader.
+func (s *Server) startWebhooks() error {
+	hooks, err := s.kv.loadWebhooks()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	s.webhooks.start(hooks)
+	return nil
+}
+
+// GetWebhooks returns the webhooks.
+func (s *Server) GetWebhooks() ([]*Webhook, error) {
+	if !s.IsLeader() {
+		return nil, errors.Trace(errNotLeader)
+	}
+	return s.webhooks.webhooks(), nil
+}
+
+// AddWebhook adds or updates the webhook.
+func (s *Server) AddWebhook(hook *Webhook) error {
+	if !s.IsLeader() {
+		return errors.Trace(errNotLeader)
+	}
+	if err := hook.Validate(); err != nil {
+		return errors.Trace(err)
+	}
+	if err := s.kv.saveWebhook(hook); err != nil {
+		return errors.Trace(err)
+	}
+	s.webhooks.add(hook)
+	log.Infof("webhook %s is added, url %s types %v", hook.Name, hook.URL, hook.Types)
+	return nil
+}
+
+// DeleteWebhook deletes the webhook.
+func (s *Server) DeleteWebhook(name string) error {
+	if !s.IsLeader() {
+		return errors.Trace(errNotLeader)
+	}
+	ok, err := s.kv.deleteWebhook(name)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !ok {
+		return errors.Trace(ErrWebhookNotFound)
+	}
+	s.webhooks.remove(name)
+	log.Infof("webhook %s is deleted", name)
+	return nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+)
+
+var _ = Suite(&testNotifySuite{})
+
+type testNotifySuite struct{}
+
+func (s *testNotifySuite) TestEventBus(c *C) {
+	bus := newEventBus()
+	id1, ch1 := bus.subscribe(1)
+	_, ch2 := bus.subscribe(2)
+
+	bus.publish(&ClusterEvent{Type: ClusterEventStoreDown, StoreID: 1})
+	bus.publish(&ClusterEvent{Type: ClusterEventStoreDown, StoreID: 2})
+	// The second event is dropped for the first subscriber.
+	evt := <-ch1
+	c.Assert(evt.StoreID, Equals, uint64(1))
+	c.Assert(evt.Time.IsZero(), IsFalse)
+	c.Assert((<-ch2).StoreID, Equals, uint64(1))
+	c.Assert((<-ch2).StoreID, Equals, uint64(2))
+
+	bus.unsubscribe(id1)
+	_, ok := <-ch1
+	c.Assert(ok, IsFalse)
+
+	// Publish on a nil bus.
+	var nilBus *eventBus
+	nilBus.publish(&ClusterEvent{Type: ClusterEventStoreDown})
+}
+
+func (s *testNotifySuite) TestWebhookValidate(c *C) {
+	hook := &Webhook{Name: "test", URL: "http://127.0.0.1:8080/events"}
+	c.Assert(hook.Validate(), IsNil)
+	hook.Types = []string{ClusterEventStoreDown, ClusterEventLeaderChange}
+	c.Assert(hook.Validate(), IsNil)
+
+	c.Assert((&Webhook{Name: "", URL: hook.URL}).Validate(), NotNil)
+	c.Assert((&Webhook{Name: "a/b", URL: hook.URL}).Validate(), NotNil)
+	c.Assert((&Webhook{Name: "test", URL: "127.0.0.1:8080"}).Validate(), NotNil)
+	c.Assert((&Webhook{Name: "test", URL: "ftp://127.0.0.1"}).Validate(), NotNil)
+	c.Assert((&Webhook{Name: "test", URL: hook.URL, Types: []string{"unknown"}}).Validate(), NotNil)
+}
+
+func (s *testNotifySuite) TestWebhookManager(c *C) {
+	received := make(chan *ClusterEvent, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		evt := &ClusterEvent{}
+		c.Assert(json.NewDecoder(r.Body).Decode(evt), IsNil)
+		received <- evt
+	}))
+	defer ts.Close()
+
+	bus := newEventBus()
+	m := newWebhookManager(bus)
+	m.start([]*Webhook{{Name: "test", URL: ts.URL, Types: []string{ClusterEventStoreDown}}})
+	defer m.stop()
+	c.Assert(m.webhooks(), HasLen, 1)
+
+	bus.publish(&ClusterEvent{Type: ClusterEventLeaderChange, Leader: "pd1"})
+	bus.publish(&ClusterEvent{Type: ClusterEventStoreDown, StoreID: 1})
+	select {
+	case evt := <-received:
+		c.Assert(evt.Type, Equals, ClusterEventStoreDown)
+		c.Assert(evt.StoreID, Equals, uint64(1))
+	case <-time.After(5 * time.Second):
+		c.Fatal("webhook does not receive the event")
+	}
+
+	// Update the webhook to receive all the events.
+	m.add(&Webhook{Name: "test", URL: ts.URL})
+	c.Assert(m.webhooks(), HasLen, 1)
+	bus.publish(&ClusterEvent{Type: ClusterEventLeaderChange, Leader: "pd1"})
+	select {
+	case evt := <-received:
+		c.Assert(evt.Leader, Equals, "pd1")
+	case <-time.After(5 * time.Second):
+		c.Fatal("webhook does not receive the event")
+	}
+
+	m.remove("test")
+	c.Assert(m.webhooks(), HasLen, 0)
+	c.Assert(bus.subscribers, HasLen, 0)
+	c.Assert(received, HasLen, 0)
+}
+
+func (s *testNotifySuite) TestUnavailableRegion(c *C) {
+	peers := []*metapb.Peer{{Id: 1}, {Id: 2}, {Id: 3}}
+	region := newRegionInfo(&metapb.Region{Id: 1, Peers: peers}, peers[0])
+	c.Assert(isUnavailableRegion(region), IsFalse)
+	region.DownPeers = []*pdpb.PeerStats{{Peer: peers[1]}}
+	c.Assert(isUnavailableRegion(region), IsFalse)
+	region.DownPeers = append(region.DownPeers, &pdpb.PeerStats{Peer: peers[2]})
+	c.Assert(isUnavailableRegion(region), IsTrue)
+}
@@ -14,17 +14,35 @@
 package server
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/juju/errors"
 	"github.com/ngaut/log"
 	raftpb "github.com/pingcap/kvproto/pkg/eraftpb"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/pkg/typeutil"
 )
 
+// maxOperatorWaitTime is the timeout of the steps which have no timeout
+// configured.
+const maxOperatorWaitTime = 5 * time.Minute
+
+// Sources of operators.
+const (
+	operatorSourceScheduler = "scheduler"
+	operatorSourceChecker   = "checker"
+	operatorSourceManual    = "manual"
+)
+
+// Finish status of operators.
 const (
-	maxOperatorWaitTime = 5 * time.Minute
+	operatorStatusSuccess = "success"
+	operatorStatusTimeout = "timeout"
+	operatorStatusCancel  = "cancel"
 )
 
 // Operator is an interface to schedule region.
@@ -36,10 +54,18 @@ type Operator interface {
 
 type regionOperator struct {
 	Region *regionInfo `json:"region"`
+	Source string      `json:"source"`
 	Start  time.Time   `json:"start"`
 	End    time.Time   `json:"end"`
 	Index  int         `json:"index"`
 	Ops    []Operator  `json:"operators"`
+	// StepStart is the time the current step starts.
+	StepStart time.Time `json:"step_start"`
+
+	// StepTimeouts are the timeouts of the steps, set when the operator is
+	// added to the coordinator. They are kept with the operator, so a
+	// replayed operator times out the same as the recorded one.
+	StepTimeouts []typeutil.Duration `json:"step_timeouts"`
 }
 
 func newRegionOperator(region *regionInfo, ops ...Operator) *regionOperator {
@@ -54,10 +80,12 @@ func newRegionOperator(region *regionInfo, ops ...Operator) *regionOperator {
 		}
 	}
 
+	now := time.Now()
 	return &regionOperator{
-		Region: region,
-		Start:  time.Now(),
-		Ops:    ops,
+		Region:    region,
+		Start:     now,
+		StepStart: now,
+		Ops:       ops,
 	}
 }
 
@@ -73,9 +101,79 @@ func (op *regionOperator) GetResourceKind() ResourceKind {
 	return op.Ops[0].GetResourceKind()
 }
 
+// typeName returns the names of the steps joined by ",", e.g.
+// "add_peer,remove_peer" for moving a peer.
+func (op *regionOperator) typeName() string {
+	names := make([]string, 0, len(op.Ops))
+	for _, o := range op.Ops {
+		switch o := o.(type) {
+		case *changePeerOperator:
+			names = append(names, o.Name)
+		case *transferLeaderOperator:
+			names = append(names, o.Name)
+		default:
+			names = append(names, "unknown")
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// finishStatus returns the status of the operator after Do reports it is
+// finished, the end time is only set if all the steps are done.
+func (op *regionOperator) finishStatus() string {
+	if op.End.IsZero() {
+		return operatorStatusTimeout
+	}
+	return operatorStatusSuccess
+}
+
+// OperatorRecord is the persisted record of a finished region operator.
+type OperatorRecord struct {
+	RegionID uint64            `json:"region_id"`
+	Type     string            `json:"type"`
+	Source   string            `json:"source"`
+	Status   string            `json:"status"`
+	Start    time.Time         `json:"start"`
+	End      time.Time         `json:"end"`
+	Duration typeutil.Duration `json:"duration"`
+	Steps    json.RawMessage   `json:"steps"`
+}
+
+func newOperatorRecord(op *regionOperator, status string) (*OperatorRecord, error) {
+	steps, err := json.Marshal(op.Ops)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	end := time.Now()
+	return &OperatorRecord{
+		RegionID: op.GetRegionID(),
+		Type:     op.typeName(),
+		Source:   op.Source,
+		Status:   status,
+		Start:    op.Start,
+		End:      end,
+		Duration: typeutil.NewDuration(end.Sub(op.Start)),
+		Steps:    steps,
+	}, nil
+}
+
+// stepTimeout returns the timeout of the current step, maxOperatorWaitTime
+// if it is not set.
+func (op *regionOperator) stepTimeout() time.Duration {
+	if op.Index < len(op.StepTimeouts) && op.StepTimeouts[op.Index].Duration > 0 {
+		return op.StepTimeouts[op.Index].Duration
+	}
+	return maxOperatorWaitTime
+}
+
 func (op *regionOperator) Do(region *regionInfo) (*pdpb.RegionHeartbeatResponse, bool) {
-	if time.Since(op.Start) > maxOperatorWaitTime {
-		log.Errorf("%s : operator timeout", op)
+	// The operators saved before the steps are timed separately start the
+	// current step at the start of the operator.
+	if op.StepStart.IsZero() {
+		op.StepStart = op.Start
+	}
+	if time.Since(op.StepStart) > op.stepTimeout() {
+		log.Errorf("%s : operator step %d timeout", op, op.Index)
 		return nil, true
 	}
 
@@ -87,6 +185,7 @@ func (op *regionOperator) Do(region *regionInfo) (*pdpb.RegionHeartbeatResponse,
 		if res, finished := op.Ops[op.Index].Do(region); !finished {
 			return res, false
 		}
+		op.StepStart = time.Now()
 	}
 
 	op.End = time.Now()
@@ -0,0 +1,176 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+)
+
+// The roles of the api users, a role has the permissions of the lower roles.
+// A viewer can read the cluster, an operator can also schedule the cluster,
+// and an admin can also change the config, the members and the users.
+const (
+	RoleViewer   = "viewer"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
+var roleLevels = map[string]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+const userTokenLen = 32
+
+var (
+	// ErrUserNotFound is returned when changing a user which does not exist.
+	ErrUserNotFound = errors.New("user is not found")
+	// ErrUserExists is returned when creating a user which exists.
+	ErrUserExists = errors.New("user exists")
+
+	userNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_\-.@]{1,64}$`)
+)
+
+// User is an api user, the user is authenticated by its token. Only the hash
+// of the token is saved, the token is returned once when creating the user.
+type User struct {
+	Name      string `json:"name"`
+	Role      string `json:"role"`
+	TokenHash string `json:"token_hash,omitempty"`
+	// CreatedAt is the unix time in seconds.
+	CreatedAt int64 `json:"created_at"`
+}
+
+// RoleAllows returns true if the role has the permissions of the required
+// role.
+func RoleAllows(role, required string) bool {
+	level, ok := roleLevels[role]
+	return ok && level >= roleLevels[required]
+}
+
+func hashUserToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newUserToken() (string, error) {
+	b := make([]byte, userTokenLen)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", errors.Trace(err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// GetUsers returns the users ordered by name, without the token hashes.
+func (s *Server) GetUsers() ([]*User, error) {
+	users, err := s.kv.loadUsers()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	for _, user := range users {
+		user.TokenHash = ""
+	}
+	return users, nil
+}
+
+// CreateUser creates a user with a new token, it returns the token.
+func (s *Server) CreateUser(name, role string) (string, error) {
+	if !userNameRegexp.MatchString(name) {
+		return "", errors.Errorf("invalid user name %q", name)
+	}
+	if _, ok := roleLevels[role]; !ok {
+		return "", errors.Errorf("invalid role %q", role)
+	}
+	token, err := newUserToken()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	user := &User{
+		Name:      name,
+		Role:      role,
+		TokenHash: hashUserToken(token),
+		CreatedAt: time.Now().Unix(),
+	}
+	created, err := s.kv.createUser(user)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if !created {
+		return "", errors.Trace(ErrUserExists)
+	}
+	log.Infof("user %s is created with role %s", name, role)
+	return token, nil
+}
+
+// SetUserRole changes the role of the user.
+func (s *Server) SetUserRole(name, role string) error {
+	if _, ok := roleLevels[role]; !ok {
+		return errors.Errorf("invalid role %q", role)
+	}
+	user, err := s.kv.loadUser(name)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if user == nil {
+		return errors.Trace(ErrUserNotFound)
+	}
+	prev := user.Role
+	user.Role = role
+	if err = s.kv.saveUser(user); err != nil {
+		return errors.Trace(err)
+	}
+	log.Infof("role of user %s is changed from %s to %s", name, prev, role)
+	return nil
+}
+
+// DeleteUser deletes the user, its token is rejected then.
+func (s *Server) DeleteUser(name string) error {
+	user, err := s.kv.loadUser(name)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if user == nil {
+		return errors.Trace(ErrUserNotFound)
+	}
+	if err = s.kv.deleteUser(name); err != nil {
+		return errors.Trace(err)
+	}
+	log.Infof("user %s is deleted", name)
+	return nil
+}
+
+// AuthenticateUser returns the user of the token, nil if no user has it.
+func (s *Server) AuthenticateUser(token string) (*User, error) {
+	users, err := s.kv.loadUsers()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	hash := []byte(hashUserToken(token))
+	for _, user := range users {
+		if subtle.ConstantTimeCompare(hash, []byte(user.TokenHash)) == 1 {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
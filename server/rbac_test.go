@@ -0,0 +1,80 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"github.com/juju/errors"
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testRBACSuite{})
+
+type testRBACSuite struct{}
+
+func (s *testRBACSuite) TestRoleAllows(c *C) {
+	c.Assert(RoleAllows(RoleAdmin, RoleViewer), IsTrue)
+	c.Assert(RoleAllows(RoleAdmin, RoleAdmin), IsTrue)
+	c.Assert(RoleAllows(RoleOperator, RoleViewer), IsTrue)
+	c.Assert(RoleAllows(RoleOperator, RoleOperator), IsTrue)
+	c.Assert(RoleAllows(RoleOperator, RoleAdmin), IsFalse)
+	c.Assert(RoleAllows(RoleViewer, RoleOperator), IsFalse)
+	c.Assert(RoleAllows("unknown", RoleViewer), IsFalse)
+}
+
+func (s *testRBACSuite) TestManageUsers(c *C) {
+	svr, cleanup := mustRunTestServer(c)
+	defer cleanup()
+	mustWaitLeader(c, []*Server{svr})
+
+	_, err := svr.CreateUser("u/1", RoleViewer)
+	c.Assert(err, NotNil)
+	_, err = svr.CreateUser("u1", "unknown")
+	c.Assert(err, NotNil)
+	token1, err := svr.CreateUser("u1", RoleViewer)
+	c.Assert(err, IsNil)
+	_, err = svr.CreateUser("u1", RoleAdmin)
+	c.Assert(errors.Cause(err), Equals, ErrUserExists)
+	token2, err := svr.CreateUser("u2", RoleOperator)
+	c.Assert(err, IsNil)
+	c.Assert(token1, Not(Equals), token2)
+
+	user, err := svr.AuthenticateUser(token1)
+	c.Assert(err, IsNil)
+	c.Assert(user.Name, Equals, "u1")
+	c.Assert(user.Role, Equals, RoleViewer)
+	user, err = svr.AuthenticateUser("unknown")
+	c.Assert(err, IsNil)
+	c.Assert(user, IsNil)
+
+	// The token hashes are hidden.
+	users, err := svr.GetUsers()
+	c.Assert(err, IsNil)
+	c.Assert(users, HasLen, 2)
+	c.Assert(users[0].Name, Equals, "u1")
+	c.Assert(users[1].Name, Equals, "u2")
+	c.Assert(users[0].TokenHash, Equals, "")
+
+	c.Assert(svr.SetUserRole("u1", "unknown"), NotNil)
+	c.Assert(errors.Cause(svr.SetUserRole("u3", RoleAdmin)), Equals, ErrUserNotFound)
+	c.Assert(svr.SetUserRole("u1", RoleAdmin), IsNil)
+	user, err = svr.AuthenticateUser(token1)
+	c.Assert(err, IsNil)
+	c.Assert(user.Role, Equals, RoleAdmin)
+
+	c.Assert(errors.Cause(svr.DeleteUser("u3")), Equals, ErrUserNotFound)
+	c.Assert(svr.DeleteUser("u1"), IsNil)
+	user, err = svr.AuthenticateUser(token1)
+	c.Assert(err, IsNil)
+	c.Assert(user, IsNil)
+}
@@ -212,6 +212,46 @@ func (t *regionTree) search(regionKey []byte) *metapb.Region {
 	return result.region
 }
 
+// scan returns at most limit regions in key order, from the region which
+// contains the key or the first region after the key.
+func (t *regionTree) scan(regionKey []byte, limit int) []*metapb.Region {
+	pivot := t.find(&metapb.Region{StartKey: regionKey})
+	if pivot == nil {
+		pivot = &regionItem{region: &metapb.Region{StartKey: regionKey}}
+	}
+
+	var regions []*metapb.Region
+	t.tree.DescendLessOrEqual(pivot, func(i btree.Item) bool {
+		if len(regions) >= limit {
+			return false
+		}
+		regions = append(regions, i.(*regionItem).region)
+		return true
+	})
+	return regions
+}
+
+// getPrev returns the region before the region which contains the key.
+func (t *regionTree) getPrev(regionKey []byte) *metapb.Region {
+	result := t.find(&metapb.Region{StartKey: regionKey})
+	if result == nil || len(result.region.GetStartKey()) == 0 {
+		return nil
+	}
+
+	var prev *regionItem
+	t.tree.AscendGreaterOrEqual(result, func(i btree.Item) bool {
+		if i == btree.Item(result) {
+			return true
+		}
+		prev = i.(*regionItem)
+		return false
+	})
+	if prev == nil {
+		return nil
+	}
+	return prev.region
+}
+
 // This is a helper function to find an item.
 func (t *regionTree) find(region *metapb.Region) *regionItem {
 	item := &regionItem{region: region}
@@ -0,0 +1,145 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+// maxRegionSaveBatch is the max number of regions saved in one txn, which
+// is the default max operations of an etcd txn.
+const maxRegionSaveBatch = 128
+
+// regionSaver coalesces the region metadata writes, the changed regions are
+// saved in batches every region-flush-interval, or once a batch is full. Only
+// the latest meta of a region is saved if it changes again before flushing.
+type regionSaver struct {
+	sync.Mutex
+	kv       *kv
+	interval time.Duration
+	pending  map[uint64]*metapb.Region
+	full     chan struct{}
+	// syncer replicates the saved regions to the followers if it is set.
+	syncer *regionSyncer
+}
+
+func newRegionSaver(kv *kv, interval time.Duration) *regionSaver {
+	return &regionSaver{
+		kv:       kv,
+		interval: interval,
+		pending:  make(map[uint64]*metapb.Region),
+		full:     make(chan struct{}, 1),
+	}
+}
+
+func (s *regionSaver) add(region *metapb.Region) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.pending[region.GetId()] = region
+	if len(s.pending) >= maxRegionSaveBatch {
+		select {
+		case s.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run flushes the regions until quit, the pending regions are flushed
+// before it returns.
+func (s *regionSaver) run(quit <-chan struct{}) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-quit:
+			if err := s.flush(); err != nil {
+				log.Errorf("flush regions before quit error: %v", err)
+			}
+			return
+		case <-ticker.C:
+		case <-s.full:
+		}
+		if err := s.flush(); err != nil {
+			log.Errorf("flush regions error: %v", err)
+		}
+	}
+}
+
+// flush saves all the pending regions. The regions failed to save are added
+// back unless they are changed again, so they are retried in next flush.
+func (s *regionSaver) flush() error {
+	s.Lock()
+	pending := s.pending
+	s.pending = make(map[uint64]*metapb.Region)
+	s.Unlock()
+
+	regions := make([]*metapb.Region, 0, maxRegionSaveBatch)
+	for _, region := range pending {
+		regions = append(regions, region)
+		if len(regions) < maxRegionSaveBatch {
+			continue
+		}
+		if err := s.save(regions, pending); err != nil {
+			return errors.Trace(err)
+		}
+		regions = regions[:0]
+	}
+	if len(regions) > 0 {
+		return errors.Trace(s.save(regions, pending))
+	}
+	return nil
+}
+
+// save saves the regions in one batch and removes them from the flushing
+// regions, the unsaved flushing regions are retried if it fails.
+func (s *regionSaver) save(regions []*metapb.Region, flushing map[uint64]*metapb.Region) error {
+	start := time.Now()
+	if err := s.kv.saveRegions(regions); err != nil {
+		s.retry(flushing)
+		return errors.Trace(err)
+	}
+	regionSaveDuration.Observe(time.Since(start).Seconds())
+	regionSaveBatchSize.Observe(float64(len(regions)))
+	if s.syncer != nil {
+		s.syncer.record(regions)
+	}
+	for _, region := range regions {
+		delete(flushing, region.GetId())
+	}
+	return nil
+}
+
+func (s *regionSaver) retry(regions map[uint64]*metapb.Region) {
+	s.Lock()
+	defer s.Unlock()
+
+	for id, region := range regions {
+		if _, ok := s.pending[id]; !ok {
+			s.pending[id] = region
+		}
+	}
+}
+
+func (s *regionSaver) pendingCount() int {
+	s.Lock()
+	defer s.Unlock()
+	return len(s.pending)
+}
@@ -0,0 +1,104 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+var _ = Suite(&testRegionSaverSuite{})
+
+type testRegionSaverSuite struct {
+	server  *Server
+	cleanup cleanUpFunc
+}
+
+func (s *testRegionSaverSuite) SetUpSuite(c *C) {
+	s.server, s.cleanup = mustRunTestServer(c)
+}
+
+func (s *testRegionSaverSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+// failedStorage fails all the batch writes.
+type failedStorage struct {
+	Storage
+}
+
+func (s *failedStorage) SaveBatch(kvs map[string]string) error {
+	return errors.New("failed to save batch")
+}
+
+func (s *testRegionSaverSuite) TestFlush(c *C) {
+	kv := newKV(s.server)
+	saver := newRegionSaver(kv, time.Hour)
+
+	// Only the latest meta of the region is saved.
+	n := maxRegionSaveBatch*2 + 1
+	for i := 1; i <= n; i++ {
+		saver.add(&metapb.Region{Id: uint64(i)})
+	}
+	saver.add(&metapb.Region{Id: 1, StartKey: []byte("a")})
+	c.Assert(saver.pendingCount(), Equals, n)
+	c.Assert(saver.flush(), IsNil)
+	c.Assert(saver.pendingCount(), Equals, 0)
+
+	cache := newRegionsInfo()
+	c.Assert(kv.loadRegions(cache, kvRangeLimit), IsNil)
+	c.Assert(cache.getRegionCount(), Equals, n)
+	c.Assert(cache.getRegion(1).GetStartKey(), DeepEquals, []byte("a"))
+
+	// The regions are kept if they fail to save.
+	saver.add(&metapb.Region{Id: 1})
+	kv.setRegionStorage(&failedStorage{Storage: kv.base})
+	c.Assert(saver.flush(), NotNil)
+	kv.setRegionStorage(kv.base)
+	c.Assert(saver.pendingCount(), Equals, 1)
+	c.Assert(saver.flush(), IsNil)
+	c.Assert(saver.pendingCount(), Equals, 0)
+}
+
+func (s *testRegionSaverSuite) TestRun(c *C) {
+	kv := newKV(s.server)
+	saver := newRegionSaver(kv, time.Hour)
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		saver.run(quit)
+		close(done)
+	}()
+
+	// A full batch is flushed before the interval.
+	for i := 1; i <= maxRegionSaveBatch; i++ {
+		saver.add(&metapb.Region{Id: uint64(1000 + i)})
+	}
+	for i := 0; i < 100 && saver.pendingCount() > 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(saver.pendingCount(), Equals, 0)
+
+	// The pending regions are flushed before quit.
+	saver.add(&metapb.Region{Id: 2000})
+	close(quit)
+	<-done
+	region := &metapb.Region{}
+	ok, err := kv.loadRegion(2000, region)
+	c.Assert(err, IsNil)
+	c.Assert(ok, IsTrue)
+}
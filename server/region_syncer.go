@@ -0,0 +1,212 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/tls"
+	"encoding/gob"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/pkg/apiutil"
+)
+
+const (
+	// regionSyncPath is served by the leader for the followers to pull the
+	// region changes.
+	regionSyncPath = "/pd/region_sync"
+	// maxRegionSyncHistory is the max number of region changes kept by the
+	// leader, the followers lagging behind more are synced fully.
+	maxRegionSyncHistory = 100000
+	regionSyncTimeout    = time.Minute
+)
+
+// regionSyncResponse has the regions changed since the requested index, or
+// all the regions if Full is true. Index is the index of the next change.
+type regionSyncResponse struct {
+	Epoch   int64
+	Index   uint64
+	Full    bool
+	Regions []*metapb.Region
+}
+
+// regionSyncer replicates the regions saved by the leader to the local region
+// storage of the followers, so a follower has the regions when it becomes
+// leader. The leader keeps the recently saved regions in memory, indexed
+// from 0 in every epoch of its leadership, and the followers pull the
+// changes after the last index they have.
+type regionSyncer struct {
+	sync.RWMutex
+	s *Server
+
+	// Used by the leader.
+	epoch   int64
+	start   uint64
+	history []*metapb.Region
+
+	// Used by the follower, only in the sync loop.
+	leaderEpoch int64
+	next        uint64
+	hc          *http.Client
+	hcScheme    string
+	hcTLS       *tls.Config
+}
+
+func newRegionSyncer(s *Server) *regionSyncer {
+	return &regionSyncer{s: s}
+}
+
+// reset starts a new epoch after becoming leader, the indexes of the
+// previous epochs are meaningless.
+func (rs *regionSyncer) reset() {
+	rs.Lock()
+	defer rs.Unlock()
+
+	rs.epoch = time.Now().UnixNano()
+	rs.start = 0
+	rs.history = nil
+}
+
+// record adds the saved regions to the history.
+func (rs *regionSyncer) record(regions []*metapb.Region) {
+	rs.Lock()
+	defer rs.Unlock()
+
+	rs.history = append(rs.history, regions...)
+	if n := len(rs.history) - maxRegionSyncHistory; n > 0 {
+		rs.history = append([]*metapb.Region(nil), rs.history[n:]...)
+		rs.start += uint64(n)
+	}
+}
+
+// changes returns the regions changed since the index of the epoch. Full is
+// true if the changes are not in the history, the regions are filled by the
+// caller then.
+func (rs *regionSyncer) changes(epoch int64, index uint64) *regionSyncResponse {
+	rs.RLock()
+	defer rs.RUnlock()
+
+	resp := &regionSyncResponse{
+		Epoch: rs.epoch,
+		Index: rs.start + uint64(len(rs.history)),
+	}
+	if epoch != rs.epoch || index < rs.start || index > resp.Index {
+		resp.Full = true
+		return resp
+	}
+	resp.Regions = append([]*metapb.Region(nil), rs.history[index-rs.start:]...)
+	return resp
+}
+
+func (rs *regionSyncer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !rs.s.IsLeader() {
+		http.Error(w, errNotLeader.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	query := r.URL.Query()
+	epoch, err := strconv.ParseInt(query.Get("epoch"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	index, err := strconv.ParseUint(query.Get("index"), 10, 64)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := rs.changes(epoch, index)
+	if resp.Full {
+		if cluster := rs.s.GetRaftCluster(); cluster != nil {
+			resp.Regions = cluster.cachedCluster.getMetaRegions()
+		}
+	}
+	if err = gob.NewEncoder(w).Encode(resp); err != nil {
+		log.Errorf("write region sync response err %v", err)
+	}
+}
+
+// run pulls the region changes from the leader every interval while the
+// server is a follower.
+func (rs *regionSyncer) run(interval time.Duration) {
+	defer rs.s.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-rs.s.client.Ctx().Done():
+			return
+		}
+		if rs.s.IsLeader() {
+			continue
+		}
+		if err := rs.sync(); err != nil {
+			log.Warnf("sync regions from leader err %v", err)
+		}
+	}
+}
+
+func (rs *regionSyncer) sync() error {
+	leader, err := rs.s.GetLeader()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if leader.GetId() == rs.s.ID() {
+		return nil
+	}
+	u, err := url.Parse(leader.GetAddr())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	// The client is created again if the TLS config is reloaded.
+	tlsCfg := rs.s.GetTLSConfig()
+	if rs.hc == nil || rs.hcScheme != u.Scheme || rs.hcTLS != tlsCfg {
+		rs.hc, rs.hcScheme, rs.hcTLS = apiutil.NewHTTPClient(u.Scheme, regionSyncTimeout, tlsCfg), u.Scheme, tlsCfg
+	}
+	u.Scheme = apiutil.ToHTTPScheme(u.Scheme)
+
+	r, err := rs.hc.Get(fmt.Sprintf("%s%s?epoch=%d&index=%d", u, regionSyncPath, rs.leaderEpoch, rs.next))
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return errors.Errorf("sync regions from %s: %s", leader.GetAddr(), r.Status)
+	}
+	resp := &regionSyncResponse{}
+	if err = gob.NewDecoder(r.Body).Decode(resp); err != nil {
+		return errors.Trace(err)
+	}
+
+	if len(resp.Regions) > 0 {
+		if err = rs.s.kv.saveRegions(resp.Regions); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if resp.Full {
+		log.Infof("%d regions are synced fully from leader %s", len(resp.Regions), leader.GetAddr())
+	}
+	rs.leaderEpoch, rs.next = resp.Epoch, resp.Index
+	return nil
+}
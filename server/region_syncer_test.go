@@ -0,0 +1,97 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+var _ = Suite(&testRegionSyncerSuite{})
+
+type testRegionSyncerSuite struct {
+	testClusterBaseSuite
+}
+
+func (s *testRegionSyncerSuite) TestChanges(c *C) {
+	rs := newRegionSyncer(nil)
+	rs.reset()
+	rs.record([]*metapb.Region{{Id: 1}, {Id: 2}})
+	rs.record([]*metapb.Region{{Id: 3}})
+
+	resp := rs.changes(rs.epoch, 1)
+	c.Assert(resp.Full, IsFalse)
+	c.Assert(resp.Index, Equals, uint64(3))
+	c.Assert(resp.Regions, DeepEquals, []*metapb.Region{{Id: 2}, {Id: 3}})
+	resp = rs.changes(rs.epoch, 3)
+	c.Assert(resp.Full, IsFalse)
+	c.Assert(resp.Regions, HasLen, 0)
+
+	// The changes of other epochs or out of the history are synced fully.
+	c.Assert(rs.changes(rs.epoch+1, 0).Full, IsTrue)
+	c.Assert(rs.changes(rs.epoch, 4).Full, IsTrue)
+	regions := make([]*metapb.Region, maxRegionSyncHistory)
+	for i := range regions {
+		regions[i] = &metapb.Region{Id: uint64(i + 4)}
+	}
+	rs.record(regions)
+	c.Assert(rs.changes(rs.epoch, 2).Full, IsTrue)
+	resp = rs.changes(rs.epoch, 3)
+	c.Assert(resp.Full, IsFalse)
+	c.Assert(resp.Regions, HasLen, maxRegionSyncHistory)
+	c.Assert(resp.Index, Equals, uint64(maxRegionSyncHistory+3))
+
+	rs.reset()
+	c.Assert(rs.changes(rs.epoch, 0).Regions, HasLen, 0)
+}
+
+func (s *testRegionSyncerSuite) TestSync(c *C) {
+	cfgs := NewTestMultiConfig(3)
+	for _, cfg := range cfgs {
+		cfg.RegionStorage = RegionStorageLocal
+		cfg.RegionFlushInterval.Duration = 50 * time.Millisecond
+	}
+	svrs, cleanup := newMultiTestServersWithCfgs(c, cfgs)
+	defer cleanup()
+	s.svr = mustWaitLeader(c, svrs)
+	s.client = s.svr.client
+
+	conn, err := rpcConnect(s.svr.GetAddr())
+	c.Assert(err, IsNil)
+	defer conn.Close()
+	s.bootstrapCluster(c, conn, s.svr.clusterID, "127.0.0.1:0")
+
+	cluster := s.svr.GetRaftCluster()
+	region := cluster.cachedCluster.searchRegion([]byte{}).clone()
+	region.RegionEpoch.Version++
+	c.Assert(cluster.cachedCluster.putRegion(region), IsNil)
+
+	// The followers have the region in their local storage.
+	for _, svr := range svrs {
+		if svr == s.svr {
+			continue
+		}
+		var synced bool
+		for i := 0; i < 100 && !synced; i++ {
+			time.Sleep(50 * time.Millisecond)
+			saved := &metapb.Region{}
+			ok, err := svr.kv.loadRegion(region.GetId(), saved)
+			c.Assert(err, IsNil)
+			synced = ok && saved.GetRegionEpoch().GetVersion() == region.GetRegionEpoch().GetVersion()
+		}
+		c.Assert(synced, IsTrue)
+	}
+}
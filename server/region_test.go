@@ -160,6 +160,30 @@ func (s *testRegionSuite) TestRegionTree(c *C) {
 	c.Assert(tree.search([]byte("e")), Equals, regionE)
 }
 
+func (s *testRegionSuite) TestRegionTreeScan(c *C) {
+	tree := newRegionTree()
+	c.Assert(tree.scan([]byte("a"), 10), HasLen, 0)
+	c.Assert(tree.getPrev([]byte("a")), IsNil)
+
+	regionA := newRegion([]byte(""), []byte("b"))
+	regionB := newRegion([]byte("b"), []byte("c"))
+	regionD := newRegion([]byte("d"), []byte(""))
+	tree.update(regionA)
+	tree.update(regionB)
+	tree.update(regionD)
+
+	c.Assert(tree.scan([]byte(""), 10), DeepEquals, []*metapb.Region{regionA, regionB, regionD})
+	c.Assert(tree.scan([]byte("a"), 2), DeepEquals, []*metapb.Region{regionA, regionB})
+	c.Assert(tree.scan([]byte("b"), 10), DeepEquals, []*metapb.Region{regionB, regionD})
+	// The key is not in any region.
+	c.Assert(tree.scan([]byte("c"), 10), DeepEquals, []*metapb.Region{regionD})
+
+	c.Assert(tree.getPrev([]byte("a")), IsNil)
+	c.Assert(tree.getPrev([]byte("b")), Equals, regionA)
+	c.Assert(tree.getPrev([]byte("e")), Equals, regionB)
+	c.Assert(tree.getPrev([]byte("c")), IsNil)
+}
+
 func splitRegions(regions []*metapb.Region) []*metapb.Region {
 	results := make([]*metapb.Region, 0, len(regions)*2)
 	for _, region := range regions {
@@ -0,0 +1,169 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"io"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+)
+
+// Replayer replays a capture into an offline coordinator which has the
+// cluster state at the beginning of the capture. The heartbeats and the
+// captured operators are applied one by one in the captured order, the
+// schedulers don't run, so replaying a capture gets the same responses
+// every time. The responses may diverge from the captured ones if the
+// operators time out, or the IDs are allocated by other requests during the
+// capture, e.g. splitting regions.
+type Replayer struct {
+	header *CaptureHeader
+	zr     *gzip.Reader
+	dec    *gob.Decoder
+	co     *coordinator
+}
+
+// ReplayResult is the replayed response of a captured heartbeat.
+type ReplayResult struct {
+	Record   *CaptureRecord
+	Response *pdpb.Response
+	// Diverged is true if the response is different from the captured one.
+	Diverged bool
+}
+
+// NewReplayer reads the header of the capture written by CaptureHeartbeats.
+func NewReplayer(r io.Reader) (*Replayer, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	dec := gob.NewDecoder(zr)
+	header := &CaptureHeader{}
+	if err = dec.Decode(header); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if header.Version != heartbeatCaptureVersion {
+		return nil, errors.Errorf("unsupported heartbeat capture version %d", header.Version)
+	}
+
+	opt := newScheduleOption(&Config{
+		Schedule:      header.Schedule,
+		Replication:   header.Replication,
+		LabelProperty: header.LabelProperty,
+	})
+	opt.SetFeatureGates(header.FeatureGates)
+	opt.SetNamespaces(header.Namespaces)
+	return &Replayer{
+		header: header,
+		zr:     zr,
+		dec:    dec,
+		co:     header.Snapshot.newCoordinator(&replayIDAllocator{last: header.LastID}, opt),
+	}, nil
+}
+
+// Header returns the header of the capture.
+func (r *Replayer) Header() *CaptureHeader {
+	return r.header
+}
+
+// Next replays the next heartbeat and the operators captured before it. It
+// returns io.EOF after the last heartbeat.
+func (r *Replayer) Next() (*ReplayResult, error) {
+	for {
+		encoded := &captureRecord{}
+		if err := r.dec.Decode(encoded); err != nil {
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			return nil, errors.Trace(err)
+		}
+		record, err := encoded.decode()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if record.Operator != nil {
+			r.applyOperator(record.Operator, record.Canceled)
+			continue
+		}
+
+		resp, err := r.apply(record.Request)
+		if err != nil {
+			resp = newError(err)
+		}
+		return &ReplayResult{
+			Record:   record,
+			Response: resp,
+			Diverged: isResponseDiverged(record.Response, resp),
+		}, nil
+	}
+}
+
+// Close releases the reader of the capture.
+func (r *Replayer) Close() error {
+	return errors.Trace(r.zr.Close())
+}
+
+func (r *Replayer) applyOperator(op *regionOperator, canceled bool) {
+	if !canceled {
+		r.co.addOperator(op, op.Source)
+		return
+	}
+	if running := r.co.getOperator(op.GetRegionID()); running != nil {
+		r.co.removeOperator(running, operatorStatusCancel)
+	}
+}
+
+func (r *Replayer) apply(req *pdpb.Request) (*pdpb.Response, error) {
+	switch req.GetCmdType() {
+	case pdpb.CommandType_StoreHeartbeat:
+		if err := r.co.cluster.handleStoreHeartbeat(req.GetStoreHeartbeat().GetStats()); err != nil {
+			return nil, errors.Trace(err)
+		}
+		return &pdpb.Response{StoreHeartbeat: &pdpb.StoreHeartbeatResponse{}}, nil
+	case pdpb.CommandType_RegionHeartbeat:
+		region := newRegionInfoFromHeartbeat(req.GetRegionHeartbeat())
+		if err := r.co.cluster.handleRegionHeartbeat(region); err != nil {
+			return nil, errors.Trace(err)
+		}
+		return &pdpb.Response{RegionHeartbeat: r.co.dispatch(region)}, nil
+	}
+	return nil, errors.Errorf("unexpected %s in heartbeat capture", req.GetCmdType())
+}
+
+// isResponseDiverged compares the results of the heartbeats, the error
+// messages are not compared.
+func isResponseDiverged(captured, replayed *pdpb.Response) bool {
+	if (captured.GetHeader().GetError() == nil) != (replayed.GetHeader().GetError() == nil) {
+		return true
+	}
+	return !proto.Equal(captured.GetRegionHeartbeat(), replayed.GetRegionHeartbeat())
+}
+
+// replayIDAllocator allocates the IDs after the last ID allocated by the
+// leader when the capture starts.
+type replayIDAllocator struct {
+	last uint64
+}
+
+func (alloc *replayIDAllocator) Alloc() (uint64, error) {
+	alloc.last++
+	return alloc.last, nil
+}
+
+func (alloc *replayIDAllocator) Status() (*IDAllocStatus, error) {
+	return &IDAllocStatus{Allocator: "replay", Step: 1, Last: alloc.last, End: alloc.last}, nil
+}
@@ -28,7 +28,7 @@ var _ = Suite(&testReplicationSuite{})
 type testReplicationSuite struct{}
 
 func (s *testReplicationSuite) TestDistinctScore(c *C) {
-	cluster := newClusterInfo(newMockIDAllocator())
+	cluster := newClusterInfo(NewMockIDAllocator())
 	tc := newTestClusterInfo(cluster)
 	rep := newTestReplication(3, "zone", "rack", "host")
 
@@ -46,7 +46,7 @@ func (s *testReplicationSuite) TestDistinctScore(c *C) {
 					"rack": rack,
 					"host": host,
 				}
-				tc.addLabelsStore(storeID, 1, 0.1, labels)
+				tc.AddLabelsStore(storeID, 1, 0.1, labels)
 				store := cluster.getStore(storeID)
 				stores = append(stores, store)
 
@@ -62,18 +62,18 @@ func (s *testReplicationSuite) TestDistinctScore(c *C) {
 		}
 	}
 
-	tc.addLabelsStore(100, 1, 0.1, map[string]string{})
+	tc.AddLabelsStore(100, 1, 0.1, map[string]string{})
 	store := cluster.getStore(100)
 	c.Assert(rep.GetDistinctScore(stores, store), Equals, float64(0))
 }
 
 func (s *testReplicationSuite) TestCompareStoreScore(c *C) {
-	cluster := newClusterInfo(newMockIDAllocator())
+	cluster := newClusterInfo(NewMockIDAllocator())
 	tc := newTestClusterInfo(cluster)
 
-	tc.addRegionStore(1, 1, 0.1)
-	tc.addRegionStore(2, 1, 0.1)
-	tc.addRegionStore(3, 1, 0.2)
+	tc.AddRegionStore(1, 1, 0.1)
+	tc.AddRegionStore(2, 1, 0.1)
+	tc.AddRegionStore(3, 1, 0.2)
 
 	store1 := cluster.getStore(1)
 	store2 := cluster.getStore(2)
@@ -0,0 +1,123 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ngaut/log"
+)
+
+// maxScheduleThrottleFactor is the max times the schedule interval is
+// enlarged by when the leader is overloaded.
+const maxScheduleThrottleFactor = 16
+
+// scheduleThrottle slows down the schedulers when the leader itself is the
+// bottleneck, so the scheduling doesn't compete with the heartbeats. The
+// factor is doubled for every overloaded check and halved for every check
+// not overloaded. A nil scheduleThrottle never throttles.
+type scheduleThrottle struct {
+	// factor multiplies the schedule interval, read without the lock.
+	factor int64
+	// inflight is the number of heartbeats being handled, and peak is the
+	// max of it since last check.
+	inflight int64
+	peak     int64
+
+	sync.Mutex
+	cpuTime     func() (time.Duration, error)
+	lastCheck   time.Time
+	lastCPUTime time.Duration
+}
+
+func newScheduleThrottle() *scheduleThrottle {
+	return &scheduleThrottle{
+		factor:  1,
+		cpuTime: processCPUTime,
+	}
+}
+
+func (t *scheduleThrottle) startHeartbeat() {
+	n := atomic.AddInt64(&t.inflight, 1)
+	for {
+		peak := atomic.LoadInt64(&t.peak)
+		if n <= peak || atomic.CompareAndSwapInt64(&t.peak, peak, n) {
+			return
+		}
+	}
+}
+
+func (t *scheduleThrottle) finishHeartbeat() {
+	atomic.AddInt64(&t.inflight, -1)
+}
+
+func (t *scheduleThrottle) getFactor() int64 {
+	if t == nil {
+		return 1
+	}
+	return atomic.LoadInt64(&t.factor)
+}
+
+// reset stops throttling and starts a new check period, it is called after
+// becoming leader.
+func (t *scheduleThrottle) reset() {
+	t.Lock()
+	defer t.Unlock()
+
+	atomic.StoreInt64(&t.factor, 1)
+	atomic.StoreInt64(&t.peak, atomic.LoadInt64(&t.inflight))
+	t.lastCheck, t.lastCPUTime = time.Now(), 0
+	if cpuTime, err := t.cpuTime(); err == nil {
+		t.lastCPUTime = cpuTime
+	}
+	scheduleThrottleGauge.Set(1)
+}
+
+// check computes the CPU usage of all the cores and the peak heartbeats
+// being handled since last check, and updates the factor. It returns the
+// new factor.
+func (t *scheduleThrottle) check(maxCPUUsage float64, maxHeartbeats int64) int64 {
+	t.Lock()
+	defer t.Unlock()
+
+	now := time.Now()
+	var usage float64
+	if cpuTime, err := t.cpuTime(); err == nil {
+		if elapsed := now.Sub(t.lastCheck); elapsed > 0 && !t.lastCheck.IsZero() {
+			usage = float64(cpuTime-t.lastCPUTime) / float64(elapsed) / float64(runtime.NumCPU())
+		}
+		t.lastCPUTime = cpuTime
+	}
+	t.lastCheck = now
+	peak := atomic.SwapInt64(&t.peak, atomic.LoadInt64(&t.inflight))
+
+	factor := atomic.LoadInt64(&t.factor)
+	newFactor := factor
+	if usage > maxCPUUsage || peak > maxHeartbeats {
+		if newFactor < maxScheduleThrottleFactor {
+			newFactor *= 2
+		}
+	} else if newFactor > 1 {
+		newFactor /= 2
+	}
+	if newFactor != factor {
+		log.Infof("schedule throttle factor %d -> %d, cpu usage %.2f, peak heartbeats %d", factor, newFactor, usage, peak)
+		atomic.StoreInt64(&t.factor, newFactor)
+	}
+	scheduleThrottleGauge.Set(float64(newFactor))
+	return newFactor
+}
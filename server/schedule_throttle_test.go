@@ -0,0 +1,97 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"runtime"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testScheduleThrottleSuite{})
+
+type testScheduleThrottleSuite struct{}
+
+func (s *testScheduleThrottleSuite) TestHeartbeats(c *C) {
+	t := newScheduleThrottle()
+	t.cpuTime = func() (time.Duration, error) { return 0, nil }
+	t.reset()
+	c.Assert(t.getFactor(), Equals, int64(1))
+
+	// The peak heartbeats exceed the threshold, the factor is doubled up to
+	// the max.
+	for _, factor := range []int64{2, 4, 8, 16, 16} {
+		for i := 0; i < 3; i++ {
+			t.startHeartbeat()
+		}
+		for i := 0; i < 3; i++ {
+			t.finishHeartbeat()
+		}
+		c.Assert(t.check(1, 2), Equals, factor)
+	}
+	// The heartbeats being handled are counted in next check.
+	t.startHeartbeat()
+	t.startHeartbeat()
+	t.startHeartbeat()
+	c.Assert(t.check(1, 2), Equals, int64(16))
+	c.Assert(t.check(1, 2), Equals, int64(16))
+	t.finishHeartbeat()
+	t.finishHeartbeat()
+	t.finishHeartbeat()
+	c.Assert(t.check(1, 2), Equals, int64(16))
+
+	// The factor is halved once it is not overloaded.
+	c.Assert(t.check(1, 2), Equals, int64(8))
+	c.Assert(t.check(1, 2), Equals, int64(4))
+	t.reset()
+	c.Assert(t.getFactor(), Equals, int64(1))
+	c.Assert(t.check(1, 2), Equals, int64(1))
+
+	// A nil throttle never throttles.
+	var nilThrottle *scheduleThrottle
+	c.Assert(nilThrottle.getFactor(), Equals, int64(1))
+}
+
+func (s *testScheduleThrottleSuite) TestCPUUsage(c *C) {
+	t := newScheduleThrottle()
+	var cpuTime time.Duration
+	t.cpuTime = func() (time.Duration, error) { return cpuTime, nil }
+	t.reset()
+
+	// The process uses all the cores since last check.
+	time.Sleep(10 * time.Millisecond)
+	cpuTime = time.Duration(runtime.NumCPU()) * time.Second
+	c.Assert(t.check(0.8, 100), Equals, int64(2))
+	c.Assert(t.check(0.8, 100), Equals, int64(1))
+
+	cpuTime, err := processCPUTime()
+	c.Assert(err, IsNil)
+	c.Assert(cpuTime, Greater, time.Duration(0))
+}
+
+func (s *testScheduleThrottleSuite) TestInterval(c *C) {
+	cluster := newClusterInfo(NewMockIDAllocator())
+	_, opt := newTestScheduleConfig()
+	opt.load().ScheduleInterval.Duration = time.Second
+	co := newCoordinator(cluster, opt)
+	co.throttle = newScheduleThrottle()
+
+	sc := newScheduleController(co, newBalanceLeaderScheduler(opt))
+	interval := sc.GetInterval()
+	co.throttle.startHeartbeat()
+	co.throttle.check(1, 0)
+	co.throttle.finishHeartbeat()
+	c.Assert(sc.GetInterval(), Equals, 2*interval)
+}
@@ -69,6 +69,7 @@ func (s *grantLeaderScheduler) Cleanup(cluster *clusterInfo) {
 func (s *grantLeaderScheduler) Schedule(cluster *clusterInfo) Operator {
 	region := cluster.randFollowerRegion(s.storeID)
 	if region == nil {
+		recordSchedule(s.GetName(), scheduleNoRegion)
 		return nil
 	}
 	return newTransferLeader(region, region.GetStorePeer(s.storeID))
@@ -85,6 +86,8 @@ func newEvictLeaderScheduler(opt *scheduleOption, storeID uint64) *evictLeaderSc
 	var filters []Filter
 	filters = append(filters, newStateFilter(opt))
 	filters = append(filters, newHealthFilter(opt))
+	filters = append(filters, newRejectLeaderFilter(opt))
+	filters = append(filters, newEngineFilter(opt))
 
 	return &evictLeaderScheduler{
 		opt:      opt,
@@ -117,10 +120,13 @@ func (s *evictLeaderScheduler) Cleanup(cluster *clusterInfo) {
 func (s *evictLeaderScheduler) Schedule(cluster *clusterInfo) Operator {
 	region := cluster.randLeaderRegion(s.storeID)
 	if region == nil {
+		recordSchedule(s.GetName(), scheduleNoRegion)
 		return nil
 	}
-	target := s.selector.SelectTarget(cluster.getFollowerStores(region))
+	stores := cluster.getFollowerStores(region)
+	target := s.selector.SelectTarget(stores)
 	if target == nil {
+		recordSchedule(s.GetName(), diagnoseSelect(stores, s.selector, nil, false))
 		return nil
 	}
 	return newTransferLeader(region, region.GetStorePeer(target.GetId()))
@@ -136,6 +142,8 @@ func newShuffleLeaderScheduler(opt *scheduleOption) *shuffleLeaderScheduler {
 	var filters []Filter
 	filters = append(filters, newStateFilter(opt))
 	filters = append(filters, newHealthFilter(opt))
+	filters = append(filters, newRejectLeaderFilter(opt))
+	filters = append(filters, newEngineFilter(opt))
 
 	return &shuffleLeaderScheduler{
 		opt:      opt,
@@ -168,7 +176,7 @@ func (s *shuffleLeaderScheduler) Schedule(cluster *clusterInfo) Operator {
 
 	// Select a store and transfer a leader from it.
 	if s.selected == nil {
-		region, newLeader := scheduleTransferLeader(cluster, s.selector)
+		region, newLeader := scheduleTransferLeader(cluster, s.GetName(), s.selector)
 		if region == nil {
 			return nil
 		}
@@ -181,9 +189,14 @@ func (s *shuffleLeaderScheduler) Schedule(cluster *clusterInfo) Operator {
 	storeID := s.selected.GetStoreId()
 	s.selected = nil
 
-	// Transfer a leader to the selected store.
+	// Transfer a leader to the selected store, unless it rejects leaders now.
+	if store := cluster.getStore(storeID); store == nil || newRejectLeaderFilter(s.opt).FilterTarget(store) || newEngineFilter(s.opt).FilterTarget(store) {
+		recordSchedule(s.GetName(), scheduleNoTarget)
+		return nil
+	}
 	region := cluster.randFollowerRegion(storeID)
 	if region == nil {
+		recordSchedule(s.GetName(), scheduleNoRegion)
 		return nil
 	}
 	return newTransferLeader(region, region.GetStorePeer(storeID))
@@ -229,11 +242,12 @@ func scheduleAddPeer(cluster *clusterInfo, s Selector, filters ...Filter) *metap
 }
 
 // scheduleRemovePeer schedules a region to remove the peer.
-func scheduleRemovePeer(cluster *clusterInfo, s Selector, filters ...Filter) (*regionInfo, *metapb.Peer) {
+func scheduleRemovePeer(cluster *clusterInfo, scheduler string, s Selector, filters ...Filter) (*regionInfo, *metapb.Peer) {
 	stores := cluster.getStores()
 
 	source := s.SelectSource(stores, filters...)
 	if source == nil {
+		recordSchedule(scheduler, diagnoseSelect(stores, s, filters, true))
 		return nil, nil
 	}
 
@@ -242,30 +256,35 @@ func scheduleRemovePeer(cluster *clusterInfo, s Selector, filters ...Filter) (*r
 		region = cluster.randLeaderRegion(source.GetId())
 	}
 	if region == nil {
+		recordSchedule(scheduler, scheduleNoRegion)
 		return nil, nil
 	}
 
 	return region, region.GetStorePeer(source.GetId())
 }
 
-// scheduleTransferLeader schedules a region to transfer leader to the peer.
-func scheduleTransferLeader(cluster *clusterInfo, s Selector, filters ...Filter) (*regionInfo, *metapb.Peer) {
+// scheduleTransferLeader schedules a region to transfer leader to the peer,
+// the filters apply to both the source and target stores.
+func scheduleTransferLeader(cluster *clusterInfo, scheduler string, s Selector, filters ...Filter) (*regionInfo, *metapb.Peer) {
 	sourceStores := cluster.getStores()
 
 	source := s.SelectSource(sourceStores, filters...)
 	if source == nil {
+		recordSchedule(scheduler, diagnoseSelect(sourceStores, s, filters, true))
 		return nil, nil
 	}
 
 	region := cluster.randLeaderRegion(source.GetId())
 	if region == nil {
+		recordSchedule(scheduler, scheduleNoRegion)
 		return nil, nil
 	}
 
 	targetStores := cluster.getFollowerStores(region)
 
-	target := s.SelectTarget(targetStores)
+	target := s.SelectTarget(targetStores, filters...)
 	if target == nil {
+		recordSchedule(scheduler, diagnoseSelect(targetStores, s, filters, false))
 		return nil, nil
 	}
 
@@ -20,7 +20,7 @@ var _ = Suite(&testShuffleLeaderSuite{})
 type testShuffleLeaderSuite struct{}
 
 func (s *testShuffleLeaderSuite) TestShuffle(c *C) {
-	cluster := newClusterInfo(newMockIDAllocator())
+	cluster := newClusterInfo(NewMockIDAllocator())
 	tc := newTestClusterInfo(cluster)
 
 	_, opt := newTestScheduleConfig()
@@ -28,19 +28,19 @@ func (s *testShuffleLeaderSuite) TestShuffle(c *C) {
 	c.Assert(sl.Schedule(cluster), IsNil)
 
 	// Add stores 1,2,3,4
-	tc.addLeaderStore(1, 6, 30)
-	tc.addLeaderStore(2, 7, 30)
-	tc.addLeaderStore(3, 8, 30)
-	tc.addLeaderStore(4, 9, 30)
+	tc.AddLeaderStore(1, 6, 30)
+	tc.AddLeaderStore(2, 7, 30)
+	tc.AddLeaderStore(3, 8, 30)
+	tc.AddLeaderStore(4, 9, 30)
 	// Add regions 1,2,3,4 with leaders in stores 1,2,3,4
-	tc.addLeaderRegion(1, 1, 2, 3, 4)
-	tc.addLeaderRegion(1, 2, 3, 4, 1)
-	tc.addLeaderRegion(2, 2, 3, 4, 1)
-	tc.addLeaderRegion(2, 3, 4, 1, 2)
-	tc.addLeaderRegion(3, 3, 4, 1, 2)
-	tc.addLeaderRegion(3, 4, 1, 2, 3)
-	tc.addLeaderRegion(4, 4, 1, 2, 3)
-	tc.addLeaderRegion(4, 1, 2, 3, 4)
+	tc.AddLeaderRegion(1, 1, 2, 3, 4)
+	tc.AddLeaderRegion(1, 2, 3, 4, 1)
+	tc.AddLeaderRegion(2, 2, 3, 4, 1)
+	tc.AddLeaderRegion(2, 3, 4, 1, 2)
+	tc.AddLeaderRegion(3, 3, 4, 1, 2)
+	tc.AddLeaderRegion(3, 4, 1, 2, 3)
+	tc.AddLeaderRegion(4, 4, 1, 2, 3)
+	tc.AddLeaderRegion(4, 1, 2, 3, 4)
 
 	for i := 0; i < 4; i++ {
 		bop := sl.Schedule(cluster)
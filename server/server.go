@@ -14,6 +14,8 @@
 package server
 
 import (
+	"crypto/cipher"
+	"crypto/tls"
 	"math/rand"
 	"net/http"
 	"path"
@@ -30,6 +32,7 @@ import (
 	"github.com/ngaut/log"
 	"github.com/ngaut/systimemon"
 	"github.com/pingcap/pd/pkg/etcdutil"
+	"github.com/pingcap/pd/pkg/tlsutil"
 )
 
 const (
@@ -49,12 +52,25 @@ type Server struct {
 	etcd *embed.Etcd
 
 	client *clientv3.Client
+	// tlsReloader has the TLS config to connect the etcd and pd members.
+	tlsReloader *tlsutil.Reloader
 
 	clusterID uint64
 
 	rootPath string
 
 	isLeaderValue int64
+	// notify the leader to resign.
+	resignCh chan struct{}
+	// for leader health check.
+	writeLatency     *latencyStat
+	heartbeatLatency *latencyStat
+	// the unix nanoseconds when the member becomes leader.
+	leaderSince int64
+	// for capturing the heartbeats handled by leader.
+	captures *heartbeatCaptures
+	// for slowing down the schedulers of the overloaded leader.
+	scheduleThrottle *scheduleThrottle
 	// leader value saved in etcd leader key.
 	// Every write will use this to check leader validation.
 	leaderValue string
@@ -67,20 +83,41 @@ type Server struct {
 	closed int64
 
 	// for tso
-	ts            atomic.Value
-	lastSavedTime time.Time
+	tso        *timestampOracle
+	tsoBatcher *tsoBatcher
+	// forward tso requests to leader if not leader.
+	tsoProxy *tsoProxy
+	// nil if dc-location is not configured.
+	localTSO *localTSOAllocator
 
 	// for id allocator, we can use one allocator for
 	// store, region and peer, because we just need
 	// a unique ID.
-	idAlloc *idAllocator
+	idAlloc IDAllocator
 
 	// for kv operation.
 	kv *kv
+	// nil if follower read is disabled.
+	followerCache *followerCache
+	// nil unless region-storage is local.
+	regionSyncer *regionSyncer
 
 	// for API operation.
 	handler *Handler
 
+	// for cluster events, the webhooks only run on leader.
+	eventBus *eventBus
+	webhooks *webhookManager
+
+	// serializes the updates of the gc safe point and the service safe points.
+	gcSafePointLock sync.Mutex
+	// serializes the updates of the keyspaces.
+	keyspaceLock sync.Mutex
+	// masterKey encrypts the data keys, nil if the encryption is disabled.
+	masterKey cipher.AEAD
+	// serializes the rotations of the data keys.
+	encryptionLock sync.Mutex
+
 	// for raft cluster
 	clusterLock sync.RWMutex
 	cluster     *RaftCluster
@@ -108,16 +145,30 @@ func NewServer(cfg *Config) (*Server, error) {
 func CreateServer(cfg *Config) *Server {
 	log.Infof("PD config - %v", cfg)
 	rand.Seed(time.Now().UnixNano())
+	if cfg.EnableTrace {
+		enableTrace(traceExporters[cfg.TraceExporter])
+	} else {
+		enableTrace(nil)
+	}
 
 	s := &Server{
 		cfg:           cfg,
 		scheduleOpt:   newScheduleOption(cfg),
 		isLeaderValue: 0,
+		resignCh:      make(chan struct{}, 1),
 		conns:         make(map[*conn]struct{}),
 		closed:        1,
+
+		writeLatency:     &latencyStat{},
+		heartbeatLatency: &latencyStat{},
+		captures:         newHeartbeatCaptures(),
+		scheduleThrottle: newScheduleThrottle(),
 	}
 
+	s.tsoProxy = newTSOProxy(s)
 	s.handler = newHandler(s)
+	s.eventBus = newEventBus()
+	s.webhooks = newWebhookManager(s.eventBus)
 	return s
 }
 
@@ -127,12 +178,29 @@ func (s *Server) StartEtcd(apiHandler http.Handler) error {
 	if err != nil {
 		return errors.Trace(err)
 	}
+	if s.tlsReloader, err = tlsutil.NewReloader(s.cfg.Security); err != nil {
+		return errors.Trace(err)
+	}
+	if s.cfg.Encryption.Enabled() {
+		if s.masterKey, err = loadMasterKey(s.cfg.Encryption.MasterKeyPath); err != nil {
+			return errors.Trace(err)
+		}
+	}
 	etcdCfg.UserHandlers = map[string]http.Handler{
 		pdRPCPrefix: s,
 	}
 	if apiHandler != nil {
 		etcdCfg.UserHandlers[pdAPIPrefix] = apiHandler
 	}
+	if s.cfg.RegionStorage == RegionStorageLocal {
+		s.regionSyncer = newRegionSyncer(s)
+		etcdCfg.UserHandlers[regionSyncPath] = s.regionSyncer
+	}
+	if s.cfg.EnableTrace {
+		for path, handler := range traceHandlers() {
+			etcdCfg.UserHandlers[path] = handler
+		}
+	}
 
 	log.Info("start embed etcd")
 
@@ -146,7 +214,7 @@ func (s *Server) StartEtcd(apiHandler http.Handler) error {
 	if err != nil {
 		return errors.Trace(err)
 	}
-	if err = etcdutil.CheckClusterID(etcd.Server.Cluster().ID(), urlmap); err != nil {
+	if err = etcdutil.CheckClusterID(etcd.Server.Cluster().ID(), urlmap, s.GetTLSConfig()); err != nil {
 		return errors.Trace(err)
 	}
 
@@ -156,6 +224,7 @@ func (s *Server) StartEtcd(apiHandler http.Handler) error {
 	client, err := clientv3.New(clientv3.Config{
 		Endpoints:   endpoints,
 		DialTimeout: etcdTimeout,
+		TLS:         s.GetTLSConfig(),
 	})
 	if err != nil {
 		return errors.Trace(err)
@@ -189,14 +258,36 @@ func (s *Server) StartEtcd(apiHandler http.Handler) error {
 		}
 	}
 
+	if s.cfg.restoreFile != "" {
+		if err = s.restoreBackup(s.cfg.restoreFile); err != nil {
+			return errors.Trace(err)
+		}
+	}
 	if err = s.initClusterID(); err != nil {
 		return errors.Trace(err)
 	}
 	log.Infof("init cluster id %v", s.clusterID)
 
 	s.rootPath = path.Join(pdRootPath, strconv.FormatUint(s.clusterID, 10))
-	s.idAlloc = &idAllocator{s: s}
+	if s.idAlloc, err = newIDAllocatorByName(s.cfg.IDAllocator, s); err != nil {
+		return errors.Trace(err)
+	}
+	s.tso = newTimestampOracle(s, "global", s.getTimestampPath(), s.leaderTxn)
+	s.tsoBatcher = newTSOBatcher("global", s.tso.getRespTS)
+	if len(s.cfg.DCLocation) != 0 {
+		s.localTSO = newLocalTSOAllocator(s, s.cfg.DCLocation)
+	}
 	s.kv = newKV(s)
+	if s.cfg.RegionStorage == RegionStorageLocal {
+		storage, err := newBoltStorage(path.Join(s.cfg.DataDir, regionStorageFile))
+		if err != nil {
+			return errors.Trace(err)
+		}
+		s.kv.setRegionStorage(storage)
+	}
+	if s.cfg.EnableFollowerRead {
+		s.followerCache = newFollowerCache(s)
+	}
 	s.cluster = newRaftCluster(s, s.clusterID)
 
 	// Server has started.
@@ -244,6 +335,7 @@ func (s *Server) Close() {
 	log.Info("closing server")
 
 	s.enableLeader(false)
+	s.tsoProxy.close()
 
 	if s.client != nil {
 		s.client.Close()
@@ -255,9 +347,55 @@ func (s *Server) Close() {
 
 	s.wg.Wait()
 
+	if s.kv != nil {
+		if err := s.kv.close(); err != nil {
+			log.Errorf("close kv failed: %v", err)
+		}
+	}
+
 	log.Info("close server")
 }
 
+// GracefulClose hands off the leadership and waits for the requests being
+// handled before closing the server, so a rolling restart only makes the
+// cluster unavailable for a short time. The etcd leadership is transferred
+// by etcd itself when it stops. It closes the server anyway after timeout.
+func (s *Server) GracefulClose(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+
+	if s.IsLeader() {
+		if err := s.ResignLeader(""); err != nil {
+			log.Warnf("resign leader before close err %v", err)
+		}
+		for time.Now().Before(deadline) {
+			leader, err := s.GetLeader()
+			if err == nil && leader != nil && !s.isSameLeader(leader) {
+				log.Infof("leadership is transferred to %s before close", leader)
+				break
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	for time.Now().Before(deadline) && s.hasBusyConnections() {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	s.Close()
+}
+
+func (s *Server) hasBusyConnections() bool {
+	s.connsLock.Lock()
+	defer s.connsLock.Unlock()
+
+	for conn := range s.conns {
+		if conn.isBusy() {
+			return true
+		}
+	}
+	return false
+}
+
 // isClosed checks whether server is closed or not.
 func (s *Server) isClosed() bool {
 	return atomic.LoadInt64(&s.closed) == 1
@@ -269,6 +407,29 @@ func (s *Server) Run() {
 	// address before run, so we set leader value here.
 	s.leaderValue = s.marshalLeader()
 
+	if s.localTSO != nil {
+		s.wg.Add(1)
+		go s.localTSO.loop()
+	}
+
+	if s.followerCache != nil {
+		s.wg.Add(1)
+		go s.followerCache.loop()
+	}
+
+	if s.regionSyncer != nil {
+		s.wg.Add(1)
+		go s.regionSyncer.run(s.cfg.RegionFlushInterval.Duration)
+	}
+
+	s.wg.Add(1)
+	go s.leaderPriorityLoop()
+
+	if s.cfg.Security.Enabled() {
+		s.wg.Add(1)
+		go s.certReloadLoop()
+	}
+
 	s.wg.Add(1)
 	s.leaderLoop()
 }
@@ -330,6 +491,15 @@ func (s *Server) GetClient() *clientv3.Client {
 	return s.client
 }
 
+// GetTLSConfig returns the TLS config to connect other members, nil if the
+// TLS is disabled.
+func (s *Server) GetTLSConfig() *tls.Config {
+	if s.tlsReloader == nil {
+		return nil
+	}
+	return s.tlsReloader.TLSConfig()
+}
+
 // ID returns the unique etcd ID for this server in etcd cluster.
 func (s *Server) ID() uint64 {
 	return s.id
@@ -366,7 +536,7 @@ func (s *Server) closeAllConnections() {
 // txn returns an etcd client transaction wrapper.
 // The wrapper will set a request timeout to the context and log slow transactions.
 func (s *Server) txn() clientv3.Txn {
-	return newSlowLogTxn(s.client)
+	return newSlowLogTxn(s.client, s.writeLatency)
 }
 
 // leaderTxn returns txn() with a leader comparison to guarantee that
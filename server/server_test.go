@@ -70,8 +70,12 @@ func cleanServer(cfg *Config) {
 }
 
 func newMultiTestServers(c *C, count int) ([]*Server, cleanupFunc) {
+	return newMultiTestServersWithCfgs(c, NewTestMultiConfig(count))
+}
+
+func newMultiTestServersWithCfgs(c *C, cfgs []*Config) ([]*Server, cleanupFunc) {
+	count := len(cfgs)
 	svrs := make([]*Server, 0, count)
-	cfgs := NewTestMultiConfig(count)
 
 	ch := make(chan *Server, count)
 	for i := 0; i < count; i++ {
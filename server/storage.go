@@ -0,0 +1,202 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/coreos/etcd/clientv3"
+	"github.com/juju/errors"
+)
+
+// Region storages.
+const (
+	// RegionStorageEtcd saves the region metadata in etcd with other metadata.
+	RegionStorageEtcd = "etcd"
+	// RegionStorageLocal saves the region metadata in a local embedded kv of
+	// every member, so the frequent region writes do not go through etcd.
+	RegionStorageLocal = "local"
+)
+
+const (
+	regionStorageFile = "region.db"
+	boltOpenTimeout   = 5 * time.Second
+)
+
+// Storage is the persistence layer of the metadata.
+type Storage interface {
+	// Load returns nil if the key does not exist.
+	Load(key string) ([]byte, error)
+	Save(key, value string) error
+	// LoadRange returns at most limit keys in [key, endKey) and their values.
+	LoadRange(key, endKey string, limit int) ([]string, [][]byte, error)
+	// SaveBatch saves the keys and values atomically.
+	SaveBatch(kvs map[string]string) error
+	Close() error
+}
+
+// etcdStorage writes by the txn, which succeeds only if the server is leader.
+type etcdStorage struct {
+	client *clientv3.Client
+	txn    func(cs ...clientv3.Cmp) clientv3.Txn
+}
+
+func newEtcdStorage(client *clientv3.Client, txn func(cs ...clientv3.Cmp) clientv3.Txn) *etcdStorage {
+	return &etcdStorage{
+		client: client,
+		txn:    txn,
+	}
+}
+
+func (s *etcdStorage) Load(key string) ([]byte, error) {
+	resp, err := kvGet(s.client, key)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if n := len(resp.Kvs); n == 0 {
+		return nil, nil
+	} else if n > 1 {
+		return nil, errors.Errorf("load more than one kvs: key %v kvs %v", key, n)
+	}
+	return resp.Kvs[0].Value, nil
+}
+
+func (s *etcdStorage) Save(key, value string) error {
+	resp, err := s.txn().Then(clientv3.OpPut(key, value)).Commit()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !resp.Succeeded {
+		return errors.Trace(errTxnFailed)
+	}
+	return nil
+}
+
+func (s *etcdStorage) LoadRange(key, endKey string, limit int) ([]string, [][]byte, error) {
+	resp, err := kvGet(s.client, key, clientv3.WithRange(endKey), clientv3.WithLimit(int64(limit)))
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	keys := make([]string, 0, len(resp.Kvs))
+	values := make([][]byte, 0, len(resp.Kvs))
+	for _, item := range resp.Kvs {
+		keys = append(keys, string(item.Key))
+		values = append(values, item.Value)
+	}
+	return keys, values, nil
+}
+
+func (s *etcdStorage) SaveBatch(kvs map[string]string) error {
+	ops := make([]clientv3.Op, 0, len(kvs))
+	for key, value := range kvs {
+		ops = append(ops, clientv3.OpPut(key, value))
+	}
+	resp, err := s.txn().Then(ops...).Commit()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if !resp.Succeeded {
+		return errors.Trace(errTxnFailed)
+	}
+	return nil
+}
+
+// Close does nothing, the etcd client is closed by the server.
+func (s *etcdStorage) Close() error {
+	return nil
+}
+
+var boltBucket = []byte("pd")
+
+// boltStorage saves the metadata in a local bolt db, the data is not
+// replicated to other members.
+type boltStorage struct {
+	db *bolt.DB
+}
+
+func newBoltStorage(path string) (*boltStorage, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err = tx.CreateBucketIfNotExists(boltBucket)
+		return errors.Trace(err)
+	})
+	if err != nil {
+		db.Close()
+		return nil, errors.Trace(err)
+	}
+	return &boltStorage{db: db}, nil
+}
+
+func (s *boltStorage) Load(key string) ([]byte, error) {
+	var value []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(boltBucket).Get([]byte(key)); v != nil {
+			// The value is only valid in the transaction.
+			value = append([]byte(nil), v...)
+		}
+		return nil
+	})
+	return value, errors.Trace(err)
+}
+
+func (s *boltStorage) Save(key, value string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return errors.Trace(tx.Bucket(boltBucket).Put([]byte(key), []byte(value)))
+	})
+	return errors.Trace(err)
+}
+
+func (s *boltStorage) LoadRange(key, endKey string, limit int) ([]string, [][]byte, error) {
+	var (
+		keys   []string
+		values [][]byte
+	)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltBucket).Cursor()
+		for k, v := c.Seek([]byte(key)); k != nil && len(keys) < limit; k, v = c.Next() {
+			if bytes.Compare(k, []byte(endKey)) >= 0 {
+				break
+			}
+			keys = append(keys, string(k))
+			values = append(values, append([]byte(nil), v...))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	return keys, values, nil
+}
+
+func (s *boltStorage) SaveBatch(kvs map[string]string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltBucket)
+		for key, value := range kvs {
+			if err := b.Put([]byte(key), []byte(value)); err != nil {
+				return errors.Trace(err)
+			}
+		}
+		return nil
+	})
+	return errors.Trace(err)
+}
+
+func (s *boltStorage) Close() error {
+	return errors.Trace(s.db.Close())
+}
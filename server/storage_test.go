@@ -0,0 +1,106 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+var _ = Suite(&testStorageSuite{})
+
+type testStorageSuite struct{}
+
+func (s *testStorageSuite) TestEtcdStorage(c *C) {
+	svr, cleanup := mustRunTestServer(c)
+	defer cleanup()
+
+	testStorage(c, newEtcdStorage(svr.client, svr.leaderTxn), path.Join(svr.rootPath, "test"))
+}
+
+func (s *testStorageSuite) TestBoltStorage(c *C) {
+	dir, err := ioutil.TempDir("", "pd_storage")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+
+	storage, err := newBoltStorage(path.Join(dir, regionStorageFile))
+	c.Assert(err, IsNil)
+	testStorage(c, storage, "/test")
+	c.Assert(storage.Close(), IsNil)
+
+	// The data is kept after reopening.
+	storage, err = newBoltStorage(path.Join(dir, regionStorageFile))
+	c.Assert(err, IsNil)
+	defer storage.Close()
+	value, err := storage.Load("/test/a")
+	c.Assert(err, IsNil)
+	c.Assert(string(value), Equals, "1")
+}
+
+func testStorage(c *C, storage Storage, prefix string) {
+	key := func(k string) string { return prefix + "/" + k }
+
+	value, err := storage.Load(key("a"))
+	c.Assert(err, IsNil)
+	c.Assert(value, IsNil)
+
+	c.Assert(storage.Save(key("a"), "1"), IsNil)
+	c.Assert(storage.SaveBatch(map[string]string{key("b"): "2", key("c"): "3", key("d"): "4"}), IsNil)
+	value, err = storage.Load(key("a"))
+	c.Assert(err, IsNil)
+	c.Assert(string(value), Equals, "1")
+
+	keys, values, err := storage.LoadRange(key("b"), key("d"), 10)
+	c.Assert(err, IsNil)
+	c.Assert(keys, DeepEquals, []string{key("b"), key("c")})
+	c.Assert(values, DeepEquals, [][]byte{[]byte("2"), []byte("3")})
+
+	keys, _, err = storage.LoadRange(key("a"), key("z"), 3)
+	c.Assert(err, IsNil)
+	c.Assert(keys, DeepEquals, []string{key("a"), key("b"), key("c")})
+}
+
+func (s *testStorageSuite) TestLocalRegionStorage(c *C) {
+	cfg := NewTestSingleConfig()
+	cfg.RegionStorage = RegionStorageLocal
+	svr, err := NewServer(cfg)
+	c.Assert(err, IsNil)
+	defer cleanServer(cfg)
+	go svr.Run()
+	mustWaitLeader(c, []*Server{svr})
+
+	region := &metapb.Region{Id: 123}
+	c.Assert(svr.kv.saveRegion(region), IsNil)
+	cache := newRegionsInfo()
+	c.Assert(svr.kv.loadRegions(cache, 3), IsNil)
+	c.Assert(cache.getMetaRegions(), DeepEquals, []*metapb.Region{region})
+
+	// The region is not saved in etcd.
+	value, err := svr.kv.base.Load(svr.kv.regionPath(123))
+	c.Assert(err, IsNil)
+	c.Assert(value, IsNil)
+
+	// The region storage is closed with the server.
+	svr.Close()
+	storage, err := newBoltStorage(path.Join(cfg.DataDir, regionStorageFile))
+	c.Assert(err, IsNil)
+	defer storage.Close()
+	value, err = storage.Load(svr.kv.regionPath(123))
+	c.Assert(err, IsNil)
+	c.Assert(value, NotNil)
+}
@@ -110,6 +110,20 @@ func (s *storeInfo) resourceScores() []int {
 	return scores
 }
 
+// engineLabelKey is the store label of the storage engine, the stores
+// without it or labeled defaultEngine store the regions in rows.
+const (
+	engineLabelKey = "engine"
+	defaultEngine  = "tikv"
+)
+
+// isSpecialEngine returns true if the store runs another storage engine than
+// the default, e.g. a columnar engine like tiflash.
+func (s *storeInfo) isSpecialEngine() bool {
+	engine := s.getLabelValue(engineLabelKey)
+	return engine != "" && engine != defaultEngine
+}
+
 func (s *storeInfo) getLabelValue(key string) string {
 	for _, label := range s.GetLabels() {
 		if label.GetKey() == key {
@@ -119,6 +133,17 @@ func (s *storeInfo) getLabelValue(key string) string {
 	return ""
 }
 
+// mergeLabel sets the label value, or adds the label if the key doesn't exist.
+func (s *storeInfo) mergeLabel(label *metapb.StoreLabel) {
+	for _, l := range s.GetLabels() {
+		if l.GetKey() == label.GetKey() {
+			l.Value = label.GetValue()
+			return
+		}
+	}
+	s.Labels = append(s.Labels, &metapb.StoreLabel{Key: label.GetKey(), Value: label.GetValue()})
+}
+
 func (s *storeInfo) getLocationID(keys []string) string {
 	id := ""
 	for _, k := range keys {
@@ -0,0 +1,65 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+)
+
+// certReloadLoop reloads the cert files every cert-reload-interval if they
+// are renewed.
+func (s *Server) certReloadLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.cfg.Security.CertReloadInterval.Duration)
+	defer ticker.Stop()
+
+	ctx := s.client.Ctx()
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := s.reloadTLSConfig(false); err != nil {
+				log.Errorf("reload cert files err %v", errors.ErrorStack(err))
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// ReloadTLSConfig loads the cert files again, the new connections to the
+// etcd and pd members use the reloaded certs, and the existing connections
+// are kept. The certs of the urls served by etcd are loaded once when
+// starting.
+func (s *Server) ReloadTLSConfig() error {
+	if !s.cfg.Security.Enabled() {
+		return errors.New("TLS is not enabled")
+	}
+	_, err := s.reloadTLSConfig(true)
+	return errors.Trace(err)
+}
+
+func (s *Server) reloadTLSConfig(force bool) (bool, error) {
+	reloaded, err := s.tlsReloader.Reload(force)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	if reloaded {
+		log.Infof("cert files are reloaded: %s, %s, %s", s.cfg.Security.CAPath, s.cfg.Security.CertPath, s.cfg.Security.KeyPath)
+	}
+	return reloaded, nil
+}
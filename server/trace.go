@@ -0,0 +1,149 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"golang.org/x/net/trace"
+)
+
+// The traces are collected by the trace exporter if enable-trace is set. By
+// default golang.org/x/net/trace shows the recent and slow traces on
+// /debug/requests of the client urls, and the operator events on
+// /debug/events, only localhost can view them.
+const (
+	traceFamilyCmd      = "pd.cmd"
+	traceFamilySchedule = "pd.schedule"
+	traceFamilyTxn      = "pd.etcd"
+	traceFamilyOperator = "pd.operator"
+)
+
+const (
+	traceRequestsPath = "/debug/requests"
+	traceEventsPath   = "/debug/events"
+)
+
+// defaultTraceExporter is the name of the exporter collecting the traces by
+// golang.org/x/net/trace.
+const defaultTraceExporter = "net-trace"
+
+// TraceExporter collects the traces and the event logs if enable-trace is
+// set, and serves them on the client urls.
+type TraceExporter interface {
+	NewTrace(family, title string) trace.Trace
+	NewEventLog(family, title string) trace.EventLog
+	// Handlers returns the handlers of the trace pages by path, they are
+	// registered on the client urls.
+	Handlers() map[string]http.Handler
+}
+
+var traceExporters = map[string]TraceExporter{
+	defaultTraceExporter: netTraceExporter{},
+}
+
+// RegisterTraceExporter registers a trace exporter, which is used by setting
+// trace-exporter to the name, e.g. an exporter sending the traces to a
+// tracing system. It should be called in init, and panics if the name is
+// registered already.
+func RegisterTraceExporter(name string, exporter TraceExporter) {
+	if _, ok := traceExporters[name]; ok {
+		panic("trace exporter " + name + " is registered already")
+	}
+	traceExporters[name] = exporter
+}
+
+// activeTraceExporter is global because the traces are created without the
+// server, e.g. by the etcd txns.
+var activeTraceExporter atomic.Value
+
+type traceExporterHolder struct {
+	exporter TraceExporter
+}
+
+// enableTrace sets the exporter of the traces, nil disables tracing.
+func enableTrace(exporter TraceExporter) {
+	activeTraceExporter.Store(traceExporterHolder{exporter: exporter})
+}
+
+func getTraceExporter() TraceExporter {
+	holder, _ := activeTraceExporter.Load().(traceExporterHolder)
+	return holder.exporter
+}
+
+// newTrace returns a trace of the family, or a no-op trace if tracing is
+// disabled.
+func newTrace(family, title string) trace.Trace {
+	if exporter := getTraceExporter(); exporter != nil {
+		return exporter.NewTrace(family, title)
+	}
+	return noopTrace{}
+}
+
+// newEventLog returns an event log of the family, or a no-op event log if
+// tracing is disabled.
+func newEventLog(family, title string) trace.EventLog {
+	if exporter := getTraceExporter(); exporter != nil {
+		return exporter.NewEventLog(family, title)
+	}
+	return noopEventLog{}
+}
+
+// traceHandlers returns the handlers of the trace pages of the exporter, or
+// nil if tracing is disabled.
+func traceHandlers() map[string]http.Handler {
+	if exporter := getTraceExporter(); exporter != nil {
+		return exporter.Handlers()
+	}
+	return nil
+}
+
+// netTraceExporter keeps the traces in the global registry of
+// golang.org/x/net/trace.
+type netTraceExporter struct{}
+
+func (netTraceExporter) NewTrace(family, title string) trace.Trace {
+	return trace.New(family, title)
+}
+
+func (netTraceExporter) NewEventLog(family, title string) trace.EventLog {
+	return trace.NewEventLog(family, title)
+}
+
+// Handlers returns the handlers of the trace pages, they are registered on
+// http.DefaultServeMux by golang.org/x/net/trace.
+func (netTraceExporter) Handlers() map[string]http.Handler {
+	return map[string]http.Handler{
+		traceRequestsPath: http.DefaultServeMux,
+		traceEventsPath:   http.DefaultServeMux,
+	}
+}
+
+type noopTrace struct{}
+
+func (noopTrace) LazyLog(x fmt.Stringer, sensitive bool)     {}
+func (noopTrace) LazyPrintf(format string, a ...interface{}) {}
+func (noopTrace) SetError()                                  {}
+func (noopTrace) SetRecycler(f func(interface{}))            {}
+func (noopTrace) SetTraceInfo(traceID, spanID uint64)        {}
+func (noopTrace) SetMaxEvents(m int)                         {}
+func (noopTrace) Finish()                                    {}
+
+type noopEventLog struct{}
+
+func (noopEventLog) Printf(format string, a ...interface{}) {}
+func (noopEventLog) Errorf(format string, a ...interface{}) {}
+func (noopEventLog) Finish()                                {}
@@ -0,0 +1,79 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+
+	. "github.com/pingcap/check"
+	"golang.org/x/net/trace"
+)
+
+var _ = Suite(&testTraceSuite{})
+
+type testTraceSuite struct{}
+
+func (s *testTraceSuite) TestEnableTrace(c *C) {
+	defer enableTrace(nil)
+
+	enableTrace(nil)
+	c.Assert(newTrace(traceFamilyCmd, "tso"), Equals, noopTrace{})
+	c.Assert(newEventLog(traceFamilyOperator, "test"), Equals, noopEventLog{})
+	c.Assert(traceHandlers(), HasLen, 0)
+
+	enableTrace(traceExporters[defaultTraceExporter])
+	tr := newTrace(traceFamilyCmd, "tso")
+	c.Assert(tr, Not(Equals), noopTrace{})
+	tr.Finish()
+	el := newEventLog(traceFamilyOperator, "test")
+	c.Assert(el, Not(Equals), noopEventLog{})
+	el.Finish()
+
+	c.Assert(traceHandlers(), HasLen, 2)
+}
+
+type testTraceExporter struct {
+	families []string
+}
+
+func (e *testTraceExporter) NewTrace(family, title string) trace.Trace {
+	e.families = append(e.families, family)
+	return noopTrace{}
+}
+
+func (e *testTraceExporter) NewEventLog(family, title string) trace.EventLog {
+	e.families = append(e.families, family)
+	return noopEventLog{}
+}
+
+func (e *testTraceExporter) Handlers() map[string]http.Handler {
+	return nil
+}
+
+func (s *testTraceSuite) TestTraceExporter(c *C) {
+	defer enableTrace(nil)
+
+	exporter := &testTraceExporter{}
+	RegisterTraceExporter("test", exporter)
+	defer delete(traceExporters, "test")
+	c.Assert(func() { RegisterTraceExporter("test", exporter) }, PanicMatches, ".*registered already")
+
+	cfg := NewConfig()
+	cfg.TraceExporter = "test"
+	c.Assert(cfg.adjust(), IsNil)
+	enableTrace(traceExporters[cfg.TraceExporter])
+	newTrace(traceFamilyCmd, "tso").Finish()
+	newEventLog(traceFamilyOperator, "test").Finish()
+	c.Assert(exporter.families, DeepEquals, []string{traceFamilyCmd, traceFamilyOperator})
+}
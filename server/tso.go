@@ -15,6 +15,7 @@ package server
 
 import (
 	"path"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -25,10 +26,9 @@ import (
 )
 
 const (
-	// update timestamp every updateTimestampStep.
-	updateTimestampStep  = 50 * time.Millisecond
 	updateTimestampGuard = time.Millisecond
-	maxLogical           = int64(1 << 18)
+	logicalBits          = 18
+	maxLogical           = int64(1 << logicalBits)
 )
 
 var (
@@ -40,34 +40,64 @@ type atomicObject struct {
 	logical  int64
 }
 
+// timestampOracle allocates timestamps. The physical part is persisted
+// at path with txn, which guarantees the oracle is still the allocator.
+type timestampOracle struct {
+	s *Server
+	// name is used as the metrics label.
+	name string
+	path string
+	txn  func(cs ...clientv3.Cmp) clientv3.Txn
+
+	// Protects the update of the physical part.
+	sync.Mutex
+	ts            atomic.Value
+	lastSavedTime time.Time
+
+	checkResult atomic.Value
+}
+
+func newTimestampOracle(s *Server, name string, path string, txn func(cs ...clientv3.Cmp) clientv3.Txn) *timestampOracle {
+	return &timestampOracle{
+		s:    s,
+		name: name,
+		path: path,
+		txn:  txn,
+	}
+}
+
 func (s *Server) getTimestampPath() string {
 	return path.Join(s.rootPath, "timestamp")
 }
 
-func (s *Server) loadTimestamp() (time.Time, error) {
-	data, err := getValue(s.client, s.getTimestampPath())
+func (t *timestampOracle) loadTimestamp() (time.Time, int64, error) {
+	return loadTimestamp(t.s.client, t.path)
+}
+
+// loadTimestamp returns the saved timestamp and its mod revision.
+func loadTimestamp(c *clientv3.Client, key string) (time.Time, int64, error) {
+	resp, err := kvGet(c, key)
 	if err != nil {
-		return zeroTime, errors.Trace(err)
+		return zeroTime, 0, errors.Trace(err)
 	}
-	if data == nil {
-		return zeroTime, nil
+	if len(resp.Kvs) == 0 {
+		return zeroTime, 0, nil
 	}
 
-	nano, err := bytesToUint64(data)
+	nano, err := bytesToUint64(resp.Kvs[0].Value)
 	if err != nil {
-		return zeroTime, errors.Trace(err)
+		return zeroTime, 0, errors.Trace(err)
 	}
 
-	return time.Unix(0, int64(nano)), nil
+	return time.Unix(0, int64(nano)), resp.Kvs[0].ModRevision, nil
 }
 
 // save timestamp, if lastTs is 0, we think the timestamp doesn't exist, so create it,
 // otherwise, update it.
-func (s *Server) saveTimestamp(now time.Time) error {
+func (t *timestampOracle) saveTimestamp(now time.Time, cs ...clientv3.Cmp) error {
 	data := uint64ToBytes(uint64(now.UnixNano()))
-	key := s.getTimestampPath()
 
-	resp, err := s.leaderTxn().Then(clientv3.OpPut(key, string(data))).Commit()
+	resp, err := t.txn(cs...).Then(clientv3.OpPut(t.path, string(data))).Commit()
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -75,16 +105,66 @@ func (s *Server) saveTimestamp(now time.Time) error {
 		return errors.New("save timestamp failed, maybe we lost leader")
 	}
 
-	s.lastSavedTime = now
+	t.lastSavedTime = now
 
 	return nil
 }
 
-func (s *Server) syncTimestamp() error {
-	last, err := s.loadTimestamp()
+// TSOCheckResult is the result of the timestamp check after the server
+// becomes the allocator.
+type TSOCheckResult struct {
+	CheckTime     time.Time `json:"check_time"`
+	LastSaved     time.Time `json:"last_saved"`
+	LastRevision  int64     `json:"last_revision"`
+	Saved         time.Time `json:"saved"`
+	SavedRevision int64     `json:"saved_revision"`
+	Error         string    `json:"error,omitempty"`
+}
+
+func (t *timestampOracle) getCheckResult() *TSOCheckResult {
+	result, _ := t.checkResult.Load().(*TSOCheckResult)
+	return result
+}
+
+// resetTimestamp makes the oracle refuse to serve until it is synced again.
+func (t *timestampOracle) resetTimestamp() {
+	t.Lock()
+	defer t.Unlock()
+
+	t.ts.Store(&atomicObject{})
+}
+
+// syncTimestamp loads the saved timestamp and makes sure the timestamps
+// generated later are greater than both it and lowerBound. The saved
+// timestamp is verified with its mod revision, and the oracle refuses
+// to serve until the check is passed.
+func (t *timestampOracle) syncTimestamp(lowerBound time.Time) (err error) {
+	t.Lock()
+	defer t.Unlock()
+
+	t.ts.Store(&atomicObject{})
+
+	result := &TSOCheckResult{CheckTime: time.Now()}
+	defer func() {
+		if err != nil {
+			result.Error = err.Error()
+			tsoCheckCounter.WithLabelValues("fail").Inc()
+			log.Errorf("check timestamp failed: %+v", result)
+		} else {
+			tsoCheckCounter.WithLabelValues("ok").Inc()
+			log.Infof("check timestamp ok: %+v", result)
+		}
+		t.checkResult.Store(result)
+	}()
+
+	last, rev, err := t.loadTimestamp()
 	if err != nil {
 		return errors.Trace(err)
 	}
+	result.LastSaved, result.LastRevision = last, rev
+	if lowerBound.After(last) {
+		last = lowerBound
+	}
 
 	var now time.Time
 
@@ -98,27 +178,40 @@ func (s *Server) syncTimestamp() error {
 		break
 	}
 
-	save := now.Add(s.cfg.TsoSaveInterval.Duration)
-	if err = s.saveTimestamp(save); err != nil {
+	// The saved timestamp must not be changed since we loaded it.
+	save := now.Add(t.s.cfg.TsoSaveInterval.Duration)
+	if err = t.saveTimestamp(save, clientv3.Compare(clientv3.ModRevision(t.path), "=", rev)); err != nil {
 		return errors.Trace(err)
 	}
 
+	saved, savedRev, err := t.loadTimestamp()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	result.Saved, result.SavedRevision = saved, savedRev
+	if !saved.Equal(save) || savedRev <= rev {
+		return errors.Errorf("saved timestamp %v revision %d mismatch, expect %v after revision %d", saved, savedRev, save, rev)
+	}
+
 	log.Debugf("sync and save timestamp ok: last %v save %v", last, save)
 
 	current := &atomicObject{
 		physical: now,
 	}
-	s.ts.Store(current)
+	t.ts.Store(current)
 
 	return nil
 }
 
-func (s *Server) updateTimestamp() error {
-	prev := s.ts.Load().(*atomicObject).physical
+func (t *timestampOracle) updateTimestamp() error {
+	t.Lock()
+	defer t.Unlock()
+
+	prev := t.ts.Load().(*atomicObject).physical
 	now := time.Now()
 
 	since := now.Sub(prev)
-	if since > 3*updateTimestampStep {
+	if since > 3*t.s.cfg.TsoUpdateInterval.Duration {
 		log.Warnf("clock offset: %v, prev: %v, now: %v", since, prev, now)
 	}
 	// Avoid the same physical time stamp
@@ -127,10 +220,46 @@ func (s *Server) updateTimestamp() error {
 		return nil
 	}
 
-	if now.Sub(s.lastSavedTime) >= 0 {
-		last := s.lastSavedTime
-		save := now.Add(s.cfg.TsoSaveInterval.Duration)
-		if err := s.saveTimestamp(save); err != nil {
+	return errors.Trace(t.setPhysical(prev, now))
+}
+
+// forwardTimestamp advances the physical time immediately when the logical
+// part of current is exhausted, the physical time may be a little ahead of
+// the local clock but it is still protected by the saved timestamp.
+func (t *timestampOracle) forwardTimestamp(current *atomicObject) error {
+	t.Lock()
+	defer t.Unlock()
+
+	// The timestamp has been updated by others.
+	if t.ts.Load().(*atomicObject) != current {
+		return nil
+	}
+
+	prev := current.physical
+	next := time.Now()
+	if next.Sub(prev) < updateTimestampGuard {
+		next = prev.Add(updateTimestampGuard)
+	}
+
+	log.Warnf("logical part is exhausted, forward physical timestamp, prev: %v, next: %v", prev, next)
+	return errors.Trace(t.setPhysical(prev, next))
+}
+
+// setPhysical must be called with lock held.
+func (t *timestampOracle) setPhysical(prev, next time.Time) error {
+	// Count the logical part used during the previous physical time.
+	if current, ok := t.ts.Load().(*atomicObject); ok && current.physical != zeroTime {
+		used := atomic.LoadInt64(&current.logical)
+		if used > maxLogical {
+			used = maxLogical
+		}
+		tsoLogicalUsage.WithLabelValues(t.name).Observe(float64(used))
+	}
+
+	if next.Sub(t.lastSavedTime) >= 0 {
+		last := t.lastSavedTime
+		save := next.Add(t.s.cfg.TsoSaveInterval.Duration)
+		if err := t.saveTimestamp(save); err != nil {
 			return errors.Trace(err)
 		}
 
@@ -138,20 +267,20 @@ func (s *Server) updateTimestamp() error {
 	}
 
 	current := &atomicObject{
-		physical: now,
+		physical: next,
 	}
-	s.ts.Store(current)
+	t.ts.Store(current)
 
 	return nil
 }
 
 const maxRetryCount = 100
 
-func (s *Server) getRespTS(count uint32) (pdpb.Timestamp, error) {
+func (t *timestampOracle) getRespTS(count uint32) (pdpb.Timestamp, error) {
 	var resp pdpb.Timestamp
 	for i := 0; i < maxRetryCount; i++ {
-		current, ok := s.ts.Load().(*atomicObject)
-		if !ok {
+		current, ok := t.ts.Load().(*atomicObject)
+		if !ok || current.physical == zeroTime {
 			log.Errorf("we haven't synced timestamp ok, wait and retry, retry count %d", i)
 			time.Sleep(200 * time.Millisecond)
 			continue
@@ -160,11 +289,138 @@ func (s *Server) getRespTS(count uint32) (pdpb.Timestamp, error) {
 		resp.Physical = current.physical.UnixNano() / int64(time.Millisecond)
 		resp.Logical = atomic.AddInt64(&current.logical, int64(count))
 		if resp.Logical >= maxLogical {
-			log.Errorf("logical part outside of max logical interval %v, please check ntp time, retry count %d", resp, i)
-			time.Sleep(updateTimestampStep)
+			// Advance the physical time and retry at once rather than waiting for the next update.
+			if err := t.forwardTimestamp(current); err != nil {
+				log.Errorf("forward timestamp failed %v, retry count %d", errors.ErrorStack(err), i)
+				time.Sleep(t.s.cfg.TsoUpdateInterval.Duration)
+			}
 			continue
 		}
 		return resp, nil
 	}
 	return resp, errors.New("can not get timestamp")
 }
+
+// composeTS composes a timestamp into an uint64.
+func composeTS(ts pdpb.Timestamp) uint64 {
+	return uint64(ts.GetPhysical()<<logicalBits + ts.GetLogical())
+}
+
+// GetTSOCheckResult returns the timestamp check result of the last time
+// the server became leader, nil if it has never been leader.
+func (s *Server) GetTSOCheckResult() *TSOCheckResult {
+	return s.tso.getCheckResult()
+}
+
+// GetExternalTS returns the external timestamp, 0 means it is not set.
+func (s *Server) GetExternalTS() (uint64, error) {
+	ts, err := s.kv.loadExternalTimestamp()
+	return ts, errors.Trace(err)
+}
+
+// SetExternalTS sets the external timestamp. It can't be less than the
+// previous one or greater than the current global timestamp.
+func (s *Server) SetExternalTS(ts uint64) error {
+	if !s.IsLeader() {
+		return errors.New("set external timestamp on non-leader")
+	}
+
+	prev, err := s.kv.loadExternalTimestamp()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if ts < prev {
+		return errors.Errorf("external timestamp %d is less than the previous one %d", ts, prev)
+	}
+
+	// Count 0 returns the current timestamp without allocating one.
+	current, err := s.tso.getRespTS(0)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if global := composeTS(current); ts > global {
+		return errors.Errorf("external timestamp %d is greater than the global timestamp %d", ts, global)
+	}
+
+	if ts == prev {
+		return nil
+	}
+	return errors.Trace(s.kv.saveExternalTimestamp(prev, ts))
+}
+
+type tsoRequest struct {
+	count uint32
+	// The fields below are protected by tsoBatcher.handleLock.
+	done bool
+	ts   pdpb.Timestamp
+	err  error
+}
+
+// tsoBatcher batches concurrent tso requests, requests arriving while
+// a batch is being handled are collected and answered together with
+// only one logical clock advance.
+type tsoBatcher struct {
+	sync.Mutex
+	pending []*tsoRequest
+
+	// Only one batch can be handled at the same time.
+	handleLock sync.Mutex
+
+	// name is used as the metrics label.
+	name  string
+	alloc func(count uint32) (pdpb.Timestamp, error)
+}
+
+func newTSOBatcher(name string, alloc func(count uint32) (pdpb.Timestamp, error)) *tsoBatcher {
+	return &tsoBatcher{name: name, alloc: alloc}
+}
+
+func (b *tsoBatcher) getTS(count uint32) (pdpb.Timestamp, error) {
+	req := &tsoRequest{count: count}
+
+	b.Lock()
+	b.pending = append(b.pending, req)
+	b.Unlock()
+
+	b.handleLock.Lock()
+	defer b.handleLock.Unlock()
+
+	// The request may be handled in the batch of another request.
+	if !req.done {
+		b.Lock()
+		batch := b.pending
+		b.pending = nil
+		b.Unlock()
+
+		b.handle(batch)
+	}
+
+	return req.ts, req.err
+}
+
+func (b *tsoBatcher) handle(batch []*tsoRequest) {
+	for len(batch) > 0 {
+		// Split the batch to make sure the total count doesn't reach maxLogical.
+		n, total := 0, uint32(0)
+		for ; n < len(batch); n++ {
+			if n > 0 && int64(total)+int64(batch[n].count) >= maxLogical {
+				break
+			}
+			total += batch[n].count
+		}
+
+		start := time.Now()
+		ts, err := b.alloc(total)
+		tsoBatchDuration.WithLabelValues(b.name).Observe(time.Since(start).Seconds())
+		tsoBatchSize.WithLabelValues(b.name).Observe(float64(n))
+
+		logical := ts.Logical - int64(total)
+		for _, req := range batch[:n] {
+			logical += int64(req.count)
+			req.ts = pdpb.Timestamp{Physical: ts.Physical, Logical: logical}
+			req.err = err
+			req.done = true
+		}
+		batch = batch[n:]
+	}
+}
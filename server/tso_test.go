@@ -16,6 +16,7 @@ package server
 import (
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/coreos/etcd/clientv3"
@@ -129,3 +130,94 @@ func (s *testTsoSuite) TestTso(c *C) {
 
 	wg.Wait()
 }
+
+func (s *testTsoSuite) TestLogicalExhausted(c *C) {
+	mustGetLeader(c, s.client, s.svr.getLeaderPath())
+
+	last, err := s.svr.tso.getRespTS(1)
+	c.Assert(err, IsNil)
+
+	// Exhaust the logical part, the physical part should be forwarded at once.
+	current := s.svr.tso.ts.Load().(*atomicObject)
+	atomic.StoreInt64(&current.logical, maxLogical-1)
+	ts, err := s.svr.tso.getRespTS(10)
+	c.Assert(err, IsNil)
+	c.Assert(ts.GetPhysical(), Greater, last.GetPhysical())
+	c.Assert(ts.GetLogical(), Less, maxLogical)
+}
+
+var _ = Suite(&testTSOBatcherSuite{})
+
+type testTSOBatcherSuite struct{}
+
+func (s *testTSOBatcherSuite) TestBatch(c *C) {
+	var (
+		mu      sync.Mutex
+		logical int64
+		allocs  int
+	)
+	b := newTSOBatcher("test", func(count uint32) (pdpb.Timestamp, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		c.Assert(int64(count), Less, maxLogical)
+		allocs++
+		logical += int64(count)
+		// Slow down the allocation to let requests pile up.
+		time.Sleep(time.Millisecond)
+		return pdpb.Timestamp{Physical: 1, Logical: logical}, nil
+	})
+
+	n, count := 100, uint32(10)
+	results := make(chan int64, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ts, err := b.getTS(count)
+			c.Assert(err, IsNil)
+			results <- ts.GetLogical()
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	// Every request gets a distinct range.
+	seen := make(map[int64]struct{})
+	for l := range results {
+		c.Assert(l%int64(count), Equals, int64(0))
+		seen[l] = struct{}{}
+	}
+	c.Assert(seen, HasLen, n)
+	c.Assert(logical, Equals, int64(n)*int64(count))
+	c.Assert(allocs, Less, n)
+}
+
+func (s *testTSOBatcherSuite) TestSplitBatch(c *C) {
+	var counts []uint32
+	b := newTSOBatcher("test", func(count uint32) (pdpb.Timestamp, error) {
+		counts = append(counts, count)
+		return pdpb.Timestamp{Logical: int64(count)}, nil
+	})
+
+	half := uint32(maxLogical / 2)
+	batch := []*tsoRequest{{count: half}, {count: half}, {count: 1}}
+	b.handle(batch)
+	c.Assert(counts, DeepEquals, []uint32{half, half + 1})
+	c.Assert(batch[0].ts.GetLogical(), Equals, int64(half))
+	c.Assert(batch[1].ts.GetLogical(), Equals, int64(half))
+	c.Assert(batch[2].ts.GetLogical(), Equals, int64(half+1))
+	for _, req := range batch {
+		c.Assert(req.done, IsTrue)
+	}
+}
+
+func (s *testTsoSuite) TestCheckTimestamp(c *C) {
+	mustGetLeader(c, s.client, s.svr.getLeaderPath())
+
+	result := s.svr.GetTSOCheckResult()
+	c.Assert(result, NotNil)
+	c.Assert(result.Error, Equals, "")
+	c.Assert(result.SavedRevision, Greater, result.LastRevision)
+	c.Assert(result.Saved.After(result.LastSaved), IsTrue)
+}
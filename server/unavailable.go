@@ -0,0 +1,142 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Reasons why a region is unavailable.
+const (
+	unavailableQuorumLost = "quorum_lost"
+	unavailableNoLeader   = "no_leader"
+)
+
+// UnavailableRegion is a region which can not serve, FirstSeen is the time
+// it is found unavailable and is kept until it recovers.
+type UnavailableRegion struct {
+	RegionID  uint64    `json:"region_id"`
+	Reason    string    `json:"reason"`
+	FirstSeen time.Time `json:"first_seen"`
+}
+
+// unavailableRegions tracks the unavailable regions incrementally by the
+// region heartbeats and the down stores, so no full scan of the regions is
+// needed.
+type unavailableRegions struct {
+	sync.RWMutex
+	bus     *eventBus
+	regions map[uint64]*UnavailableRegion
+	counts  map[string]int
+}
+
+func newUnavailableRegions(bus *eventBus) *unavailableRegions {
+	unavailableRegionGauge.Reset()
+	return &unavailableRegions{
+		bus:     bus,
+		regions: make(map[uint64]*UnavailableRegion),
+		counts:  make(map[string]int),
+	}
+}
+
+// isUnavailableRegion returns true if the majority of the peers are down, so
+// the region can not serve.
+func isUnavailableRegion(region *regionInfo) bool {
+	return len(region.DownPeers) > 0 && len(region.DownPeers)*2 >= len(region.GetPeers())
+}
+
+// update updates the region by its heartbeat. The region has a leader as the
+// heartbeat is sent by the leader, it is unavailable only if the quorum is lost.
+func (u *unavailableRegions) update(region *regionInfo) {
+	if isUnavailableRegion(region) {
+		u.add(region.GetId(), unavailableQuorumLost)
+		return
+	}
+
+	u.Lock()
+	defer u.Unlock()
+
+	if r, ok := u.regions[region.GetId()]; ok {
+		delete(u.regions, region.GetId())
+		u.setCount(r.Reason, -1)
+	}
+}
+
+// markNoLeader marks the regions whose leaders are on the down store.
+func (u *unavailableRegions) markNoLeader(regions []*regionInfo) {
+	for _, region := range regions {
+		u.add(region.GetId(), unavailableNoLeader)
+	}
+}
+
+// add adds the region or updates its reason, the event is published only if
+// the region is newly unavailable.
+func (u *unavailableRegions) add(regionID uint64, reason string) {
+	u.Lock()
+	defer u.Unlock()
+
+	if r, ok := u.regions[regionID]; ok {
+		if r.Reason != reason {
+			u.setCount(r.Reason, -1)
+			u.setCount(reason, 1)
+			r.Reason = reason
+		}
+		return
+	}
+
+	u.regions[regionID] = &UnavailableRegion{
+		RegionID:  regionID,
+		Reason:    reason,
+		FirstSeen: time.Now(),
+	}
+	u.setCount(reason, 1)
+	u.bus.publish(&ClusterEvent{
+		Type:     ClusterEventRegionUnavailable,
+		RegionID: regionID,
+		Message:  fmt.Sprintf("region %d is unavailable: %s", regionID, reason),
+	})
+}
+
+func (u *unavailableRegions) setCount(reason string, delta int) {
+	u.counts[reason] += delta
+	unavailableRegionGauge.WithLabelValues(reason).Set(float64(u.counts[reason]))
+}
+
+// list returns the unavailable regions sorted by the first seen time.
+func (u *unavailableRegions) list() []*UnavailableRegion {
+	u.RLock()
+	defer u.RUnlock()
+
+	regions := make([]*UnavailableRegion, 0, len(u.regions))
+	for _, r := range u.regions {
+		region := *r
+		regions = append(regions, &region)
+	}
+	sort.Sort(unavailableRegionsByFirstSeen(regions))
+	return regions
+}
+
+type unavailableRegionsByFirstSeen []*UnavailableRegion
+
+func (s unavailableRegionsByFirstSeen) Len() int      { return len(s) }
+func (s unavailableRegionsByFirstSeen) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s unavailableRegionsByFirstSeen) Less(i, j int) bool {
+	if s[i].FirstSeen.Equal(s[j].FirstSeen) {
+		return s[i].RegionID < s[j].RegionID
+	}
+	return s[i].FirstSeen.Before(s[j].FirstSeen)
+}
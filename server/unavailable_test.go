@@ -0,0 +1,67 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+)
+
+var _ = Suite(&testUnavailableRegionsSuite{})
+
+type testUnavailableRegionsSuite struct{}
+
+func (s *testUnavailableRegionsSuite) TestUnavailableRegions(c *C) {
+	bus := newEventBus()
+	_, events := bus.subscribe(10)
+	u := newUnavailableRegions(bus)
+
+	peers := []*metapb.Peer{{Id: 1}, {Id: 2}, {Id: 3}}
+	region1 := newRegionInfo(&metapb.Region{Id: 1, Peers: peers}, peers[0])
+	region2 := newRegionInfo(&metapb.Region{Id: 2, Peers: peers}, peers[0])
+
+	u.update(region1)
+	c.Assert(u.list(), HasLen, 0)
+
+	region1.DownPeers = []*pdpb.PeerStats{{Peer: peers[1]}, {Peer: peers[2]}}
+	u.update(region1)
+	u.markNoLeader([]*regionInfo{region2})
+	regions := u.list()
+	c.Assert(regions, HasLen, 2)
+	c.Assert(regions[0].RegionID, Equals, uint64(1))
+	c.Assert(regions[0].Reason, Equals, unavailableQuorumLost)
+	c.Assert(regions[1].RegionID, Equals, uint64(2))
+	c.Assert(regions[1].Reason, Equals, unavailableNoLeader)
+	c.Assert(u.counts[unavailableQuorumLost], Equals, 1)
+	c.Assert(u.counts[unavailableNoLeader], Equals, 1)
+	c.Assert(events, HasLen, 2)
+
+	// The region is not published again and keeps the first seen time.
+	u.update(region1)
+	u.markNoLeader([]*regionInfo{region1})
+	regions = u.list()
+	c.Assert(regions[0].RegionID, Equals, uint64(1))
+	c.Assert(regions[0].Reason, Equals, unavailableNoLeader)
+	c.Assert(u.counts[unavailableQuorumLost], Equals, 0)
+	c.Assert(u.counts[unavailableNoLeader], Equals, 2)
+	c.Assert(events, HasLen, 2)
+
+	// The regions recover after the heartbeats from the leaders.
+	region1.DownPeers = nil
+	u.update(region1)
+	u.update(region2)
+	c.Assert(u.list(), HasLen, 0)
+	c.Assert(u.counts[unavailableNoLeader], Equals, 0)
+}
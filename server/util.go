@@ -14,11 +14,11 @@
 package server
 
 import (
+	"crypto/tls"
 	"encoding/binary"
 	"fmt"
 	"math/rand"
 	"net"
-	"net/http"
 	"os"
 	"time"
 
@@ -31,6 +31,9 @@ import (
 	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/pingcap/kvproto/pkg/util"
 	"github.com/pingcap/pd/pkg/etcdutil"
+	"github.com/pingcap/pd/pkg/failpoint"
+	"github.com/pingcap/pd/pkg/logutil"
+	"github.com/pingcap/pd/pkg/rpcutil"
 	"golang.org/x/net/context"
 )
 
@@ -95,12 +98,18 @@ func getProtoMsg(c *clientv3.Client, key string, msg proto.Message, opts ...clie
 }
 
 func initOrGetClusterID(c *clientv3.Client, key string) (uint64, error) {
-	ctx, cancel := context.WithTimeout(c.Ctx(), requestTimeout)
-	defer cancel()
-
 	// Generate a random cluster ID.
 	ts := uint64(time.Now().Unix())
 	clusterID := (ts << 32) + uint64(rand.Uint32())
+	return initClusterIDValue(c, key, clusterID)
+}
+
+// initClusterIDValue saves the cluster ID if it does not exist, and returns
+// the saved cluster ID.
+func initClusterIDValue(c *clientv3.Client, key string, clusterID uint64) (uint64, error) {
+	ctx, cancel := context.WithTimeout(c.Ctx(), requestTimeout)
+	defer cancel()
+
 	value := uint64ToBytes(clusterID)
 
 	// Multiple PDs may try to init the cluster ID at the same time.
@@ -150,36 +159,53 @@ func uint64ToBytes(v uint64) []byte {
 // slowLogTxn wraps etcd transaction and log slow one.
 type slowLogTxn struct {
 	clientv3.Txn
-	cancel context.CancelFunc
+	cancel  context.CancelFunc
+	latency *latencyStat
 }
 
-func newSlowLogTxn(client *clientv3.Client) clientv3.Txn {
+func newSlowLogTxn(client *clientv3.Client, latency *latencyStat) clientv3.Txn {
 	ctx, cancel := context.WithTimeout(client.Ctx(), requestTimeout)
 	return &slowLogTxn{
-		Txn:    client.Txn(ctx),
-		cancel: cancel,
+		Txn:     client.Txn(ctx),
+		cancel:  cancel,
+		latency: latency,
 	}
 }
 
 func (t *slowLogTxn) If(cs ...clientv3.Cmp) clientv3.Txn {
 	return &slowLogTxn{
-		Txn:    t.Txn.If(cs...),
-		cancel: t.cancel,
+		Txn:     t.Txn.If(cs...),
+		cancel:  t.cancel,
+		latency: t.latency,
 	}
 }
 
 func (t *slowLogTxn) Then(ops ...clientv3.Op) clientv3.Txn {
 	return &slowLogTxn{
-		Txn:    t.Txn.Then(ops...),
-		cancel: t.cancel,
+		Txn:     t.Txn.Then(ops...),
+		cancel:  t.cancel,
+		latency: t.latency,
 	}
 }
 
 // Commit implements Txn Commit interface.
 func (t *slowLogTxn) Commit() (*clientv3.TxnResponse, error) {
+	tr := newTrace(traceFamilyTxn, "txn")
+	defer tr.Finish()
+
 	start := time.Now()
-	resp, err := t.Txn.Commit()
+	var resp *clientv3.TxnResponse
+	err := failpoint.Error(FailpointEtcdTxn)
+	if err == nil {
+		resp, err = t.Txn.Commit()
+	}
 	t.cancel()
+	if err != nil {
+		tr.LazyPrintf("err %v", err)
+		tr.SetError()
+	} else {
+		tr.LazyPrintf("succeeded %v, revision %d", resp.Succeeded, resp.Header.Revision)
+	}
 
 	cost := time.Now().Sub(start)
 	if cost > slowRequestTime {
@@ -191,6 +217,7 @@ func (t *slowLogTxn) Commit() (*clientv3.TxnResponse, error) {
 	}
 	txnCounter.WithLabelValues(label).Inc()
 	txnDuration.WithLabelValues(label).Observe(cost.Seconds())
+	t.latency.observe(cost)
 
 	return resp, errors.Trace(err)
 }
@@ -205,38 +232,14 @@ func sliceClone(strs []string) []string {
 }
 
 func rpcConnect(addr string) (net.Conn, error) {
-	req, err := http.NewRequest("GET", pdRPCPrefix, nil)
-	if err != nil {
-		return nil, errors.Trace(err)
-	}
-
-	urls, err := ParseUrls(addr)
-	if err != nil {
-		return nil, errors.Trace(err)
-	}
-
-	for _, url := range urls {
-		var conn net.Conn
-		switch url.Scheme {
-		// used in tests
-		case "unix", "unixs":
-			conn, err = net.Dial("unix", url.Host)
-		default:
-			conn, err = net.Dial("tcp", url.Host)
-		}
-
-		if err != nil {
-			continue
-		}
-		err = req.Write(conn)
-		if err != nil {
-			conn.Close()
-			continue
-		}
-		return conn, nil
-	}
+	return rpcConnectWithTLS(addr, nil)
+}
 
-	return nil, errors.Errorf("connect to %s failed", addr)
+// rpcConnectWithTLS connects to any one of the urls in addr, tlsCfg is used
+// by the urls in secure schemes.
+func rpcConnectWithTLS(addr string, tlsCfg *tls.Config) (net.Conn, error) {
+	conn, err := rpcutil.ConnectUrls(addr, 0, tlsCfg)
+	return conn, errors.Trace(err)
 }
 
 func rpcCall(conn net.Conn, reqID uint64, request *pdpb.Request) (*pdpb.Response, error) {
@@ -300,33 +303,32 @@ func (rf *redirectFormatter) Format(pkg string, level capnslog.LogLevel, depth i
 // Flush only for implementing Formatter.
 func (rf *redirectFormatter) Flush() {}
 
-// setLogOutput sets output path for all logs.
-func setLogOutput(path string) error {
-	// PD log.
-	log.SetOutputByName(path)
-	log.SetRotateByDay()
-
-	// ETCD log.
-	capnslog.SetFormatter(&redirectFormatter{})
-
-	return nil
-}
-
 // InitLogger initalizes PD's logger.
 func InitLogger(cfg *Config) error {
 	log.SetLevelByString(cfg.LogLevel)
 	log.SetHighlighting(false)
 
 	// Force redirect etcd log to stderr.
-	if len(cfg.LogFile) == 0 {
+	if len(cfg.LogFile) == 0 && cfg.Log.Format == logutil.FormatText {
 		capnslog.SetFormatter(capnslog.NewPrettyFormatter(os.Stderr, false))
 		return nil
 	}
 
-	err := setLogOutput(cfg.LogFile)
+	// PD log, the writer rotates the log file.
+	w, err := logutil.NewLogWriter(cfg.LogFile, &cfg.Log)
 	if err != nil {
 		return errors.Trace(err)
 	}
+	log.SetOutput(w)
+
+	// ETCD log. The json writer formats it with the etcd fields, and it
+	// takes the fields of PD log from the line prefix fixed by the flags.
+	if f, ok := w.(capnslog.Formatter); ok {
+		log.SetFlags(logutil.JSONLogFlags)
+		capnslog.SetFormatter(f)
+		return nil
+	}
+	capnslog.SetFormatter(&redirectFormatter{})
 
 	return nil
 }
@@ -0,0 +1,352 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulator
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/pkg/apiutil"
+	"github.com/pingcap/pd/pkg/metricutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+const (
+	// cmdDurationMetric is the histogram of the processing time of the
+	// commands handled by PD.
+	cmdDurationMetric = "pd_cmd_handle_cmds_duration_seconds"
+	metricsTimeout    = 10 * time.Second
+)
+
+// BenchConfig is the load of the heartbeat benchmark. A rate of 0 sends the
+// heartbeats as fast as possible.
+type BenchConfig struct {
+	// RegionRate is the region heartbeats sent per second by all the
+	// workers, the regions are divided among the workers.
+	RegionRate int
+	// StoreRate is the store heartbeats sent per second, the stores send
+	// the heartbeats in turn.
+	StoreRate int
+	// Concurrency is the number of the connections sending the region
+	// heartbeats.
+	Concurrency int
+	Duration    time.Duration
+}
+
+// LatencyStats is the latency distribution of the heartbeats.
+type LatencyStats struct {
+	Count  int
+	Errors int
+	P50    time.Duration
+	P90    time.Duration
+	P99    time.Duration
+	Max    time.Duration
+}
+
+func (s *LatencyStats) String() string {
+	return fmt.Sprintf("%d ok, %d errors, p50 %v, p90 %v, p99 %v, max %v", s.Count, s.Errors, s.P50, s.P90, s.P99, s.Max)
+}
+
+// BenchResult is the latency of the heartbeats seen by the simulator, and
+// the processing latency of PD. The latency of PD is estimated from the
+// buckets of its metrics, Max is the upper bound of the highest bucket.
+type BenchResult struct {
+	Duration     time.Duration
+	Region       *LatencyStats
+	Store        *LatencyStats
+	ServerRegion *LatencyStats
+	ServerStore  *LatencyStats
+}
+
+func (r *BenchResult) String() string {
+	return fmt.Sprintf("in %v, %.1f region heartbeats/s, %.1f store heartbeats/s\nregion heartbeat: %s\nstore heartbeat: %s\npd region heartbeat: %s\npd store heartbeat: %s",
+		r.Duration, float64(r.Region.Count)/r.Duration.Seconds(), float64(r.Store.Count)/r.Duration.Seconds(),
+		r.Region, r.Store, r.ServerRegion, r.ServerStore)
+}
+
+// Bench sends the heartbeats of the bootstrapped cluster at the rates of the
+// config, and returns the latencies. The operators returned by PD are not
+// applied, so the heartbeats are the same in the benchmark. The processing
+// latency of PD is read from the metrics of the first url, which should be
+// the leader.
+func (s *Simulator) Bench(cfg *BenchConfig) (*BenchResult, error) {
+	concurrency := cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	// The heartbeats are built before sending, so the cost of building is
+	// not measured.
+	regionHeartbeats := make([][]*pdpb.RegionHeartbeatRequest, concurrency)
+	for i, id := range s.cluster.regionIDs() {
+		if heartbeat := s.cluster.heartbeat(s.cluster.regions[id]); heartbeat != nil {
+			regionHeartbeats[i%concurrency] = append(regionHeartbeats[i%concurrency], heartbeat)
+		}
+	}
+	var storeStats []*pdpb.StoreStats
+	for _, id := range s.cluster.storeIDs() {
+		if !s.cluster.stores[id].isDown() {
+			storeStats = append(storeStats, s.cluster.storeStats(id))
+		}
+	}
+
+	before, err := s.scrapeCmdDurations()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	start := time.Now()
+	deadline := start.Add(cfg.Duration)
+	var (
+		wg           sync.WaitGroup
+		regionResult = make([]*latencyRecorder, concurrency)
+		storeResult  = &latencyRecorder{}
+	)
+	for i := 0; i < concurrency; i++ {
+		regionResult[i] = &latencyRecorder{}
+		c := newClient(s.client.urls)
+		c.clusterID = s.client.clusterID
+		heartbeats := regionHeartbeats[i]
+		wg.Add(1)
+		go func(rec *latencyRecorder) {
+			defer wg.Done()
+			defer c.close()
+			runPaced(deadline, cfg.RegionRate, concurrency, len(heartbeats), func(n int) {
+				t := time.Now()
+				_, err := c.regionHeartbeat(heartbeats[n])
+				rec.observe(time.Since(t), err)
+			})
+		}(regionResult[i])
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c := newClient(s.client.urls)
+		c.clusterID = s.client.clusterID
+		defer c.close()
+		runPaced(deadline, cfg.StoreRate, 1, len(storeStats), func(n int) {
+			t := time.Now()
+			err := c.storeHeartbeat(storeStats[n])
+			storeResult.observe(time.Since(t), err)
+		})
+	}()
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	after, err := s.scrapeCmdDurations()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	regionLabel := metricutil.GetCmdLabel(&pdpb.Request{CmdType: pdpb.CommandType_RegionHeartbeat})
+	storeLabel := metricutil.GetCmdLabel(&pdpb.Request{CmdType: pdpb.CommandType_StoreHeartbeat})
+	return &BenchResult{
+		Duration:     elapsed,
+		Region:       mergeLatencyRecorders(regionResult...).stats(),
+		Store:        storeResult.stats(),
+		ServerRegion: after[regionLabel].sub(before[regionLabel]).stats(),
+		ServerStore:  after[storeLabel].sub(before[storeLabel]).stats(),
+	}, nil
+}
+
+// runPaced calls send with the index of the next item in turn until the
+// deadline. The rate is shared by the workers, every worker sends rate/workers
+// per second, a rate of 0 sends without waiting.
+func runPaced(deadline time.Time, rate, workers, items int, send func(n int)) {
+	if items == 0 {
+		return
+	}
+	var interval time.Duration
+	if rate > 0 {
+		interval = time.Duration(int64(time.Second) * int64(workers) / int64(rate))
+	}
+	next := time.Now()
+	for n := 0; time.Now().Before(deadline); n = (n + 1) % items {
+		if interval > 0 {
+			if wait := next.Sub(time.Now()); wait > 0 {
+				time.Sleep(wait)
+			}
+			// The heartbeats falling behind are sent at once to keep the rate.
+			next = next.Add(interval)
+		}
+		send(n)
+	}
+}
+
+// latencyRecorder records the latencies of the heartbeats sent by one worker.
+type latencyRecorder struct {
+	latencies []time.Duration
+	errors    int
+}
+
+func (r *latencyRecorder) observe(latency time.Duration, err error) {
+	if err != nil {
+		r.errors++
+		return
+	}
+	r.latencies = append(r.latencies, latency)
+}
+
+func mergeLatencyRecorders(recorders ...*latencyRecorder) *latencyRecorder {
+	merged := &latencyRecorder{}
+	for _, r := range recorders {
+		merged.latencies = append(merged.latencies, r.latencies...)
+		merged.errors += r.errors
+	}
+	return merged
+}
+
+func (r *latencyRecorder) stats() *LatencyStats {
+	stats := &LatencyStats{Count: len(r.latencies), Errors: r.errors}
+	if len(r.latencies) == 0 {
+		return stats
+	}
+	sort.Sort(durationSlice(r.latencies))
+	percentile := func(q float64) time.Duration {
+		return r.latencies[int(q*float64(len(r.latencies)-1))]
+	}
+	stats.P50, stats.P90, stats.P99 = percentile(0.5), percentile(0.9), percentile(0.99)
+	stats.Max = r.latencies[len(r.latencies)-1]
+	return stats
+}
+
+type durationSlice []time.Duration
+
+func (s durationSlice) Len() int           { return len(s) }
+func (s durationSlice) Less(i, j int) bool { return s[i] < s[j] }
+func (s durationSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// histogram is the cumulative counts of the buckets of a histogram metric,
+// ordered by the upper bounds, and the count of all the samples.
+type histogram struct {
+	bounds []float64
+	counts []uint64
+	count  uint64
+}
+
+// sub returns the counts observed since the previous histogram.
+func (h *histogram) sub(prev *histogram) *histogram {
+	if h == nil {
+		return &histogram{}
+	}
+	diff := &histogram{bounds: h.bounds, counts: make([]uint64, len(h.counts)), count: h.count}
+	if prev != nil {
+		diff.count -= prev.count
+	}
+	for i, count := range h.counts {
+		if prev != nil && i < len(prev.counts) {
+			count -= prev.counts[i]
+		}
+		diff.counts[i] = count
+	}
+	return diff
+}
+
+// stats estimates the percentiles by interpolating linearly in the buckets,
+// the samples above the highest bucket are taken as its upper bound.
+func (h *histogram) stats() *LatencyStats {
+	stats := &LatencyStats{Count: int(h.count)}
+	if h.count == 0 {
+		return stats
+	}
+	percentile := func(q float64) time.Duration {
+		rank := q * float64(h.count)
+		lower, lowerCount := 0.0, uint64(0)
+		for i, count := range h.counts {
+			upper := h.bounds[i]
+			if math.IsInf(upper, 1) {
+				break
+			}
+			if count > lowerCount && float64(count) >= rank {
+				ratio := (rank - float64(lowerCount)) / float64(count-lowerCount)
+				return secondsToDuration(lower + (upper-lower)*ratio)
+			}
+			lower, lowerCount = upper, count
+		}
+		return secondsToDuration(lower)
+	}
+	stats.P50, stats.P90, stats.P99, stats.Max = percentile(0.5), percentile(0.9), percentile(0.99), percentile(1)
+	return stats
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// scrapeCmdDurations reads the processing time histograms of the commands
+// from the metrics of the first url, the histograms of the callers are
+// summed by the command type.
+func (s *Simulator) scrapeCmdDurations() (map[string]*histogram, error) {
+	u, err := url.Parse(strings.Split(s.client.urls, ",")[0])
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	hc := apiutil.NewHTTPClient(u.Scheme, metricsTimeout, nil)
+	u.Scheme = apiutil.ToHTTPScheme(u.Scheme)
+	u.Path = "/metrics"
+	r, err := hc.Get(u.String())
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("get metrics from %s: %s", u, r.Status)
+	}
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(r.Body)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	histograms := make(map[string]*histogram)
+	family, ok := families[cmdDurationMetric]
+	if !ok {
+		return histograms, nil
+	}
+	for _, m := range family.GetMetric() {
+		cmdType := metricLabel(m, "type")
+		h, ok := histograms[cmdType]
+		if !ok {
+			h = &histogram{}
+			for _, b := range m.GetHistogram().GetBucket() {
+				h.bounds = append(h.bounds, b.GetUpperBound())
+			}
+			h.counts = make([]uint64, len(h.bounds))
+			histograms[cmdType] = h
+		}
+		for i, b := range m.GetHistogram().GetBucket() {
+			if i < len(h.counts) {
+				h.counts[i] += b.GetCumulativeCount()
+			}
+		}
+		h.count += m.GetHistogram().GetSampleCount()
+	}
+	return histograms, nil
+}
+
+func metricLabel(m *dto.Metric, name string) string {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == name {
+			return l.GetValue()
+		}
+	}
+	return ""
+}
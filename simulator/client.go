@@ -0,0 +1,168 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulator
+
+import (
+	"net"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/pkg/rpcutil"
+	"github.com/twinj/uuid"
+)
+
+const connectTimeout = 3 * time.Second
+
+// client sends the requests of the fake stores to PD in one connection, the
+// followers proxy the requests to the leader. It is not thread safe.
+type client struct {
+	urls      string
+	clusterID uint64
+	conn      net.Conn
+	msgID     uint64
+}
+
+func newClient(urls string) *client {
+	return &client{urls: urls}
+}
+
+func (c *client) close() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
+
+// call sends the request, the connection is closed if it fails, and it is
+// connected again in next call.
+func (c *client) call(req *pdpb.Request) (*pdpb.Response, error) {
+	if c.conn == nil {
+		conn, err := rpcutil.ConnectUrls(c.urls, connectTimeout, nil)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		c.conn = conn
+	}
+	req.Header = &pdpb.RequestHeader{
+		Uuid:      uuid.NewV4().Bytes(),
+		ClusterId: c.clusterID,
+	}
+	c.msgID++
+	resp, err := rpcutil.Call(c.conn, c.msgID, req)
+	if err != nil {
+		c.close()
+		return nil, errors.Trace(err)
+	}
+	if pdErr := resp.GetHeader().GetError(); pdErr != nil {
+		return nil, errors.Errorf("%s: %s", req.GetCmdType(), pdErr.GetMessage())
+	}
+	return resp, nil
+}
+
+// initClusterID gets the cluster ID from the header of the members response.
+func (c *client) initClusterID() error {
+	resp, err := c.call(&pdpb.Request{
+		CmdType:      pdpb.CommandType_GetPDMembers,
+		GetPdMembers: &pdpb.GetPDMembersRequest{},
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	c.clusterID = resp.GetHeader().GetClusterId()
+	return nil
+}
+
+func (c *client) isBootstrapped() (bool, error) {
+	resp, err := c.call(&pdpb.Request{
+		CmdType:        pdpb.CommandType_IsBootstrapped,
+		IsBootstrapped: &pdpb.IsBootstrappedRequest{},
+	})
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	return resp.GetIsBootstrapped().GetBootstrapped(), nil
+}
+
+func (c *client) bootstrap(store *metapb.Store, region *metapb.Region) error {
+	_, err := c.call(&pdpb.Request{
+		CmdType: pdpb.CommandType_Bootstrap,
+		Bootstrap: &pdpb.BootstrapRequest{
+			Store:  store,
+			Region: region,
+		},
+	})
+	return errors.Trace(err)
+}
+
+func (c *client) allocID() (uint64, error) {
+	resp, err := c.call(&pdpb.Request{
+		CmdType: pdpb.CommandType_AllocId,
+		AllocId: &pdpb.AllocIdRequest{},
+	})
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	return resp.GetAllocId().GetId(), nil
+}
+
+func (c *client) putStore(store *metapb.Store) error {
+	_, err := c.call(&pdpb.Request{
+		CmdType:  pdpb.CommandType_PutStore,
+		PutStore: &pdpb.PutStoreRequest{Store: store},
+	})
+	return errors.Trace(err)
+}
+
+func (c *client) storeHeartbeat(stats *pdpb.StoreStats) error {
+	_, err := c.call(&pdpb.Request{
+		CmdType:        pdpb.CommandType_StoreHeartbeat,
+		StoreHeartbeat: &pdpb.StoreHeartbeatRequest{Stats: stats},
+	})
+	return errors.Trace(err)
+}
+
+func (c *client) regionHeartbeat(heartbeat *pdpb.RegionHeartbeatRequest) (*pdpb.RegionHeartbeatResponse, error) {
+	resp, err := c.call(&pdpb.Request{
+		CmdType:         pdpb.CommandType_RegionHeartbeat,
+		RegionHeartbeat: heartbeat,
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return resp.GetRegionHeartbeat(), nil
+}
+
+func (c *client) askSplit(region *metapb.Region) (*pdpb.AskSplitResponse, error) {
+	resp, err := c.call(&pdpb.Request{
+		CmdType:  pdpb.CommandType_AskSplit,
+		AskSplit: &pdpb.AskSplitRequest{Region: region},
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return resp.GetAskSplit(), nil
+}
+
+func (c *client) reportSplit(left, right *metapb.Region) error {
+	_, err := c.call(&pdpb.Request{
+		CmdType: pdpb.CommandType_ReportSplit,
+		ReportSplit: &pdpb.ReportSplitRequest{
+			Left:  left,
+			Right: right,
+		},
+	})
+	return errors.Trace(err)
+}
@@ -0,0 +1,297 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulator
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/ngaut/log"
+	"github.com/pingcap/kvproto/pkg/eraftpb"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+)
+
+type store struct {
+	meta      *metapb.Store
+	startTime time.Time
+	// downTime is the time the store is stopped, zero if it is up.
+	downTime time.Time
+}
+
+func (s *store) isDown() bool {
+	return !s.downTime.IsZero()
+}
+
+type region struct {
+	meta   *metapb.Region
+	leader *metapb.Peer
+	size   uint64
+	hot    bool
+}
+
+// cluster is the model of the fake stores and their regions, the regions
+// are changed by the operators returned by PD, the writes and the store
+// failures. It is not thread safe.
+type cluster struct {
+	cfg     *Config
+	stores  map[uint64]*store
+	regions map[uint64]*region
+}
+
+func newCluster(cfg *Config) *cluster {
+	return &cluster{
+		cfg:     cfg,
+		stores:  make(map[uint64]*store),
+		regions: make(map[uint64]*region),
+	}
+}
+
+func (c *cluster) addStore(id uint64) *store {
+	s := &store{
+		meta: &metapb.Store{
+			Id:      id,
+			Address: fmt.Sprintf("127.0.0.1:%d", 20160+len(c.stores)),
+			State:   metapb.StoreState_Up,
+		},
+		startTime: time.Now(),
+	}
+	c.stores[id] = s
+	return s
+}
+
+func (c *cluster) addRegion(meta *metapb.Region, hot bool) *region {
+	r := &region{
+		meta:   meta,
+		leader: meta.GetPeers()[0],
+		size:   uint64(c.cfg.RegionSize),
+		hot:    hot,
+	}
+	c.regions[meta.GetId()] = r
+	return r
+}
+
+// storeIDs returns the IDs of the stores in order.
+func (c *cluster) storeIDs() []uint64 {
+	ids := make([]uint64, 0, len(c.stores))
+	for id := range c.stores {
+		ids = append(ids, id)
+	}
+	sort.Sort(uint64Slice(ids))
+	return ids
+}
+
+// regionIDs returns the IDs of the regions in order.
+func (c *cluster) regionIDs() []uint64 {
+	ids := make([]uint64, 0, len(c.regions))
+	for id := range c.regions {
+		ids = append(ids, id)
+	}
+	sort.Sort(uint64Slice(ids))
+	return ids
+}
+
+func (c *cluster) storeStats(id uint64) *pdpb.StoreStats {
+	stats := &pdpb.StoreStats{
+		StoreId:   id,
+		Capacity:  uint64(c.cfg.StoreCapacity),
+		StartTime: uint32(c.stores[id].startTime.Unix()),
+	}
+	var used uint64
+	for _, r := range c.regions {
+		if findPeer(r.meta, id) != nil {
+			stats.RegionCount++
+			used += r.size
+		}
+	}
+	if used < stats.Capacity {
+		stats.Available = stats.Capacity - used
+	}
+	return stats
+}
+
+// heartbeat returns the heartbeat of the region sent by the leader, nil if
+// the leader is on a down store.
+func (c *cluster) heartbeat(r *region) *pdpb.RegionHeartbeatRequest {
+	if c.stores[r.leader.GetStoreId()].isDown() {
+		return nil
+	}
+	heartbeat := &pdpb.RegionHeartbeatRequest{
+		Region: proto.Clone(r.meta).(*metapb.Region),
+		Leader: r.leader,
+	}
+	for _, p := range r.meta.GetPeers() {
+		s := c.stores[p.GetStoreId()]
+		if !s.isDown() {
+			continue
+		}
+		downSeconds := uint64(time.Since(s.downTime).Seconds())
+		heartbeat.DownPeers = append(heartbeat.DownPeers, &pdpb.PeerStats{
+			Peer:        p,
+			DownSeconds: &downSeconds,
+		})
+	}
+	return heartbeat
+}
+
+// apply applies the operator returned by the region heartbeat, the peer is
+// added or removed at once.
+func (c *cluster) apply(id uint64, resp *pdpb.RegionHeartbeatResponse) {
+	r := c.regions[id]
+	if r == nil {
+		return
+	}
+	if changePeer := resp.GetChangePeer(); changePeer != nil {
+		peer := changePeer.GetPeer()
+		switch changePeer.GetChangeType() {
+		case eraftpb.ConfChangeType_AddNode:
+			if findPeer(r.meta, peer.GetStoreId()) == nil && c.stores[peer.GetStoreId()] != nil {
+				r.meta.Peers = append(r.meta.Peers, peer)
+				r.meta.RegionEpoch.ConfVer++
+			}
+		case eraftpb.ConfChangeType_RemoveNode:
+			for i, p := range r.meta.GetPeers() {
+				if p.GetId() != peer.GetId() {
+					continue
+				}
+				r.meta.Peers = append(r.meta.Peers[:i], r.meta.Peers[i+1:]...)
+				r.meta.RegionEpoch.ConfVer++
+				if r.leader.GetId() == peer.GetId() {
+					c.electLeader(r)
+				}
+				break
+			}
+		}
+	}
+	if transferLeader := resp.GetTransferLeader(); transferLeader != nil {
+		peer := findPeer(r.meta, transferLeader.GetPeer().GetStoreId())
+		if peer != nil && !c.stores[peer.GetStoreId()].isDown() {
+			r.leader = peer
+		}
+	}
+}
+
+// electLeader elects a leader on the up stores, the leader is kept if there
+// is no peer on the up stores.
+func (c *cluster) electLeader(r *region) {
+	for _, p := range r.meta.GetPeers() {
+		if !c.stores[p.GetStoreId()].isDown() {
+			r.leader = p
+			return
+		}
+	}
+}
+
+// setStoreDown stops or starts the store, the leaders on the stopped store
+// are elected again.
+func (c *cluster) setStoreDown(id uint64, down bool) {
+	s := c.stores[id]
+	if down {
+		s.downTime = time.Now()
+	} else {
+		s.downTime, s.startTime = time.Time{}, time.Now()
+	}
+	if !down {
+		return
+	}
+	for _, r := range c.regions {
+		if r.leader.GetStoreId() == id {
+			c.electLeader(r)
+		}
+	}
+}
+
+// write writes the hot regions for elapsed time, and returns the regions
+// to split.
+func (c *cluster) write(elapsed time.Duration) []*region {
+	var regions []*region
+	written := uint64(float64(c.cfg.HotWriteRate) * elapsed.Seconds())
+	for _, id := range c.regionIDs() {
+		r := c.regions[id]
+		if !r.hot {
+			continue
+		}
+		r.size += written
+		if r.size >= uint64(c.cfg.RegionSplitSize) {
+			regions = append(regions, r)
+		}
+	}
+	return regions
+}
+
+// split splits the region in half by the new region ID and peer IDs, the
+// right region keeps hot. It returns nil if there is no key to split.
+func (c *cluster) split(r *region, newRegionID uint64, newPeerIDs []uint64) (left, right *metapb.Region) {
+	key := splitKey(r.meta.GetStartKey(), r.meta.GetEndKey())
+	if key == nil || len(newPeerIDs) != len(r.meta.GetPeers()) {
+		log.Warnf("[sim] region %d can't split", r.meta.GetId())
+		return nil, nil
+	}
+
+	right = proto.Clone(r.meta).(*metapb.Region)
+	right.Id, right.StartKey = newRegionID, key
+	var leader *metapb.Peer
+	for i, p := range right.GetPeers() {
+		p.Id = newPeerIDs[i]
+		if p.GetStoreId() == r.leader.GetStoreId() {
+			leader = p
+		}
+	}
+	left = r.meta
+	left.EndKey = key
+	left.RegionEpoch.Version++
+	right.RegionEpoch.Version = left.GetRegionEpoch().GetVersion()
+
+	r.size /= 2
+	c.regions[newRegionID] = &region{
+		meta:   right,
+		leader: leader,
+		size:   r.size,
+		hot:    r.hot,
+	}
+	r.hot = false
+	return left, right
+}
+
+// splitKey returns a key between start and end, nil if there is none. The
+// right region after splitting is split again like appending writes, the
+// keys are greater than the split key.
+func splitKey(start, end []byte) []byte {
+	key := append(append([]byte(nil), start...), 0x80)
+	if len(start) == 0 {
+		key = []byte{0x00}
+	}
+	if len(end) > 0 && bytes.Compare(key, end) >= 0 {
+		return nil
+	}
+	return key
+}
+
+func findPeer(region *metapb.Region, storeID uint64) *metapb.Peer {
+	for _, p := range region.GetPeers() {
+		if p.GetStoreId() == storeID {
+			return p
+		}
+	}
+	return nil
+}
+
+type uint64Slice []uint64
+
+func (s uint64Slice) Len() int           { return len(s) }
+func (s uint64Slice) Less(i, j int) bool { return s[i] < s[j] }
+func (s uint64Slice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,122 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulator
+
+import (
+	"bytes"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/eraftpb"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+)
+
+var _ = Suite(&testClusterSuite{})
+
+type testClusterSuite struct{}
+
+func newTestCluster(cfg *Config) *cluster {
+	c := newCluster(cfg)
+	for id := uint64(1); id <= 3; id++ {
+		c.addStore(id)
+	}
+	// Region 10 has peers 11 and 12 on store 1 and 2.
+	c.addRegion(&metapb.Region{
+		Id:          10,
+		RegionEpoch: &metapb.RegionEpoch{ConfVer: 2, Version: 1},
+		Peers: []*metapb.Peer{
+			{Id: 11, StoreId: 1},
+			{Id: 12, StoreId: 2},
+		},
+	}, true)
+	return c
+}
+
+func (s *testClusterSuite) TestApply(c *C) {
+	cluster := newTestCluster(NewConfig())
+	r := cluster.regions[10]
+
+	addNode := eraftpb.ConfChangeType_AddNode
+	cluster.apply(10, &pdpb.RegionHeartbeatResponse{
+		ChangePeer: &pdpb.ChangePeer{ChangeType: &addNode, Peer: &metapb.Peer{Id: 13, StoreId: 3}},
+	})
+	c.Assert(r.meta.GetPeers(), HasLen, 3)
+	c.Assert(r.meta.GetRegionEpoch().GetConfVer(), Equals, uint64(3))
+	c.Assert(cluster.storeStats(3).GetRegionCount(), Equals, uint32(1))
+
+	cluster.apply(10, &pdpb.RegionHeartbeatResponse{
+		TransferLeader: &pdpb.TransferLeader{Peer: &metapb.Peer{Id: 12, StoreId: 2}},
+	})
+	c.Assert(r.leader.GetId(), Equals, uint64(12))
+
+	removeNode := eraftpb.ConfChangeType_RemoveNode
+	cluster.apply(10, &pdpb.RegionHeartbeatResponse{
+		ChangePeer: &pdpb.ChangePeer{ChangeType: &removeNode, Peer: &metapb.Peer{Id: 12, StoreId: 2}},
+	})
+	c.Assert(r.meta.GetPeers(), HasLen, 2)
+	c.Assert(r.meta.GetRegionEpoch().GetConfVer(), Equals, uint64(4))
+	c.Assert(r.leader.GetId(), Equals, uint64(11))
+}
+
+func (s *testClusterSuite) TestStoreDown(c *C) {
+	cluster := newTestCluster(NewConfig())
+	r := cluster.regions[10]
+
+	cluster.setStoreDown(1, true)
+	c.Assert(r.leader.GetStoreId(), Equals, uint64(2))
+	heartbeat := cluster.heartbeat(r)
+	c.Assert(heartbeat.GetDownPeers(), HasLen, 1)
+	c.Assert(heartbeat.GetDownPeers()[0].GetPeer().GetStoreId(), Equals, uint64(1))
+
+	cluster.setStoreDown(2, true)
+	c.Assert(cluster.heartbeat(r), IsNil)
+
+	cluster.setStoreDown(1, false)
+	cluster.setStoreDown(2, false)
+	c.Assert(cluster.heartbeat(r).GetDownPeers(), HasLen, 0)
+}
+
+func (s *testClusterSuite) TestSplit(c *C) {
+	cfg := NewConfig()
+	cfg.HotWriteRate = cfg.RegionSplitSize
+	cluster := newTestCluster(cfg)
+	r := cluster.regions[10]
+
+	regions := cluster.write(time.Second)
+	c.Assert(regions, HasLen, 1)
+	left, right := cluster.split(regions[0], 20, []uint64{21, 22})
+	c.Assert(left.GetId(), Equals, uint64(10))
+	c.Assert(right.GetId(), Equals, uint64(20))
+	c.Assert(left.GetEndKey(), DeepEquals, right.GetStartKey())
+	c.Assert(left.GetRegionEpoch().GetVersion(), Equals, uint64(2))
+	c.Assert(right.GetRegionEpoch().GetVersion(), Equals, uint64(2))
+	c.Assert(right.GetPeers()[0].GetId(), Equals, uint64(21))
+	c.Assert(cluster.regions[20].leader.GetStoreId(), Equals, r.leader.GetStoreId())
+
+	// The right region keeps hot.
+	c.Assert(r.hot, IsFalse)
+	c.Assert(cluster.regions[20].hot, IsTrue)
+	regions = cluster.write(time.Second)
+	c.Assert(regions, HasLen, 1)
+	_, right = cluster.split(regions[0], 30, []uint64{31, 32})
+	c.Assert(bytes.Compare(right.GetStartKey(), left.GetEndKey()), Equals, 1)
+}
+
+func (s *testClusterSuite) TestSplitKey(c *C) {
+	c.Assert(splitKey(nil, nil), NotNil)
+	c.Assert(splitKey(nil, []byte("k")), NotNil)
+	c.Assert(splitKey([]byte("k1"), []byte("k2")), DeepEquals, []byte("k1\x80"))
+	c.Assert(splitKey([]byte("k1"), []byte("k1\x00")), IsNil)
+}
@@ -0,0 +1,138 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/juju/errors"
+	"github.com/pingcap/pd/pkg/typeutil"
+)
+
+const (
+	defaultStoreCount              = 3
+	defaultRegionCount             = 1000
+	defaultReplicas                = 3
+	defaultStoreCapacity           = typeutil.ByteSize(1 << 40)
+	defaultRegionSize              = typeutil.ByteSize(64 << 20)
+	defaultRegionSplitSize         = typeutil.ByteSize(96 << 20)
+	defaultStoreHeartbeatInterval  = 10 * time.Second
+	defaultRegionHeartbeatInterval = 10 * time.Second
+	defaultHotWriteRate            = typeutil.ByteSize(1 << 20)
+	defaultDownAfter               = 10 * time.Minute
+)
+
+// Config is the workload of the simulated cluster.
+type Config struct {
+	StoreCount  int `toml:"store-count"`
+	RegionCount int `toml:"region-count"`
+	// Replicas is the number of peers of the initial regions, PD adds or
+	// removes the peers if it is not the max-replicas of PD.
+	Replicas      int               `toml:"replicas"`
+	StoreCapacity typeutil.ByteSize `toml:"store-capacity"`
+	// RegionSize is the initial size of the regions, a region splits once it
+	// is larger than RegionSplitSize.
+	RegionSize      typeutil.ByteSize `toml:"region-size"`
+	RegionSplitSize typeutil.ByteSize `toml:"region-split-size"`
+
+	StoreHeartbeatInterval  typeutil.Duration `toml:"store-heartbeat-interval"`
+	RegionHeartbeatInterval typeutil.Duration `toml:"region-heartbeat-interval"`
+
+	// HotRegionRatio is the ratio of the regions written in HotWriteRate per
+	// second, the hot regions are at the end of the key space, and the right
+	// one keeps hot after splitting like appending writes.
+	HotRegionRatio float64           `toml:"hot-region-ratio"`
+	HotWriteRate   typeutil.ByteSize `toml:"hot-write-rate"`
+
+	// DownStores is the number of stores which stop after DownAfter, they
+	// are started again after DownDuration unless it is 0.
+	DownStores   int               `toml:"down-stores"`
+	DownAfter    typeutil.Duration `toml:"down-after"`
+	DownDuration typeutil.Duration `toml:"down-duration"`
+}
+
+// NewConfig returns the default workload.
+func NewConfig() *Config {
+	cfg := &Config{}
+	cfg.adjust()
+	return cfg
+}
+
+// LoadConfig loads the workload from the toml file, the items not set are
+// the defaults.
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+	md, err := toml.DecodeFile(path, cfg)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return nil, errors.Errorf("unknown config items %v", undecoded)
+	}
+	cfg.adjust()
+	return cfg, errors.Trace(cfg.validate())
+}
+
+func (c *Config) adjust() {
+	if c.StoreCount == 0 {
+		c.StoreCount = defaultStoreCount
+	}
+	if c.RegionCount == 0 {
+		c.RegionCount = defaultRegionCount
+	}
+	if c.Replicas == 0 {
+		c.Replicas = defaultReplicas
+	}
+	if c.StoreCapacity == 0 {
+		c.StoreCapacity = defaultStoreCapacity
+	}
+	if c.RegionSize == 0 {
+		c.RegionSize = defaultRegionSize
+	}
+	if c.RegionSplitSize == 0 {
+		c.RegionSplitSize = defaultRegionSplitSize
+	}
+	if c.StoreHeartbeatInterval.Duration == 0 {
+		c.StoreHeartbeatInterval.Duration = defaultStoreHeartbeatInterval
+	}
+	if c.RegionHeartbeatInterval.Duration == 0 {
+		c.RegionHeartbeatInterval.Duration = defaultRegionHeartbeatInterval
+	}
+	if c.HotWriteRate == 0 {
+		c.HotWriteRate = defaultHotWriteRate
+	}
+	if c.DownAfter.Duration == 0 {
+		c.DownAfter.Duration = defaultDownAfter
+	}
+}
+
+func (c *Config) validate() error {
+	if c.Replicas > c.StoreCount {
+		return errors.Errorf("replicas %d should not be greater than store-count %d", c.Replicas, c.StoreCount)
+	}
+	if c.HotRegionRatio < 0 || c.HotRegionRatio > 1 {
+		return errors.Errorf("hot-region-ratio %v should be in [0, 1]", c.HotRegionRatio)
+	}
+	if c.DownStores < 0 || c.DownStores >= c.StoreCount {
+		return errors.Errorf("down-stores %d should be in [0, store-count)", c.DownStores)
+	}
+	return nil
+}
+
+func (c *Config) String() string {
+	return fmt.Sprintf("%d stores, %d regions, %d replicas, %.2f hot regions written %d bytes/s, %d stores down after %v",
+		c.StoreCount, c.RegionCount, c.Replicas, c.HotRegionRatio, uint64(c.HotWriteRate), c.DownStores, c.DownAfter.Duration)
+}
@@ -0,0 +1,45 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testConfigSuite{})
+
+type testConfigSuite struct{}
+
+func (s *testConfigSuite) TestLoadConfig(c *C) {
+	cfg, err := LoadConfig("../conf/simulator.toml")
+	c.Assert(err, IsNil)
+	c.Assert(cfg, DeepEquals, NewConfig())
+
+	dir, err := ioutil.TempDir("", "simulator")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "simulator.toml")
+
+	c.Assert(ioutil.WriteFile(name, []byte("store-count = 5\nunknown = 1\n"), 0600), IsNil)
+	_, err = LoadConfig(name)
+	c.Assert(err, NotNil)
+
+	c.Assert(ioutil.WriteFile(name, []byte("store-count = 2\n"), 0600), IsNil)
+	_, err = LoadConfig(name)
+	c.Assert(err, ErrorMatches, ".*replicas.*")
+}
@@ -0,0 +1,268 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulator
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/ngaut/log"
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+const (
+	writeInterval = time.Second
+	statsInterval = time.Minute
+)
+
+// Simulator runs the fake stores of the workload against a PD server. The
+// stores send the heartbeats, apply the operators returned by PD at once,
+// split the hot regions and stop as configured, so the scheduling can be
+// observed by the metrics and the apis of PD without real TiKVs.
+type Simulator struct {
+	cfg        *Config
+	client     *client
+	cluster    *cluster
+	downStores []uint64
+}
+
+// NewSimulator creates a simulator connecting the urls of PD.
+func NewSimulator(urls string, cfg *Config) *Simulator {
+	return &Simulator{
+		cfg:     cfg,
+		client:  newClient(urls),
+		cluster: newCluster(cfg),
+	}
+}
+
+// Close closes the connection to PD.
+func (s *Simulator) Close() {
+	s.client.close()
+}
+
+func regionKey(i, count int) []byte {
+	if i == 0 || i == count {
+		return nil
+	}
+	return []byte(fmt.Sprintf("k%08d", i))
+}
+
+// Bootstrap bootstraps the cluster by the stores and the regions of the
+// workload, which should not be bootstrapped before. The regions are placed
+// on the stores in turn.
+func (s *Simulator) Bootstrap() error {
+	if err := s.client.initClusterID(); err != nil {
+		return errors.Trace(err)
+	}
+	bootstrapped, err := s.client.isBootstrapped()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if bootstrapped {
+		return errors.New("cluster is bootstrapped, the simulator needs a fresh cluster")
+	}
+
+	for i := 0; i < s.cfg.StoreCount; i++ {
+		id, err := s.client.allocID()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		s.cluster.addStore(id)
+	}
+	storeIDs := s.cluster.storeIDs()
+	s.downStores = storeIDs[len(storeIDs)-s.cfg.DownStores:]
+
+	count := s.cfg.RegionCount
+	hotStart := count - int(float64(count)*s.cfg.HotRegionRatio)
+	var first *region
+	for i := 0; i < count; i++ {
+		meta := &metapb.Region{
+			StartKey:    regionKey(i, count),
+			EndKey:      regionKey(i+1, count),
+			RegionEpoch: &metapb.RegionEpoch{ConfVer: uint64(s.cfg.Replicas), Version: 2},
+		}
+		if meta.Id, err = s.client.allocID(); err != nil {
+			return errors.Trace(err)
+		}
+		for j := 0; j < s.cfg.Replicas; j++ {
+			peer := &metapb.Peer{StoreId: storeIDs[(i+j)%len(storeIDs)]}
+			if peer.Id, err = s.client.allocID(); err != nil {
+				return errors.Trace(err)
+			}
+			meta.Peers = append(meta.Peers, peer)
+		}
+		r := s.cluster.addRegion(meta, i >= hotStart)
+		if first == nil {
+			first = r
+		}
+	}
+
+	// The first region covers all the keys with one peer when bootstrapping,
+	// it is split by the heartbeats of the regions.
+	leaderStore := s.cluster.stores[first.leader.GetStoreId()]
+	err = s.client.bootstrap(leaderStore.meta, &metapb.Region{
+		Id:          first.meta.GetId(),
+		RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+		Peers:       []*metapb.Peer{first.leader},
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, id := range storeIDs {
+		if err = s.client.putStore(s.cluster.stores[id].meta); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if err = s.storeHeartbeats(); err != nil {
+		return errors.Trace(err)
+	}
+	if err = s.regionHeartbeats(); err != nil {
+		return errors.Trace(err)
+	}
+	log.Infof("[sim] cluster %d is bootstrapped with %v", s.client.clusterID, s.cfg)
+	return nil
+}
+
+// Run sends the heartbeats until quit, the failed heartbeats are sent again
+// in next interval.
+func (s *Simulator) Run(quit <-chan struct{}) {
+	storeTicker := time.NewTicker(s.cfg.StoreHeartbeatInterval.Duration)
+	defer storeTicker.Stop()
+	regionTicker := time.NewTicker(s.cfg.RegionHeartbeatInterval.Duration)
+	defer regionTicker.Stop()
+	writeTicker := time.NewTicker(writeInterval)
+	defer writeTicker.Stop()
+	statsTicker := time.NewTicker(statsInterval)
+	defer statsTicker.Stop()
+
+	var downCh, upCh <-chan time.Time
+	if len(s.downStores) > 0 {
+		downCh = time.After(s.cfg.DownAfter.Duration)
+	}
+	lastWrite := time.Now()
+	for {
+		var err error
+		select {
+		case <-quit:
+			return
+		case <-storeTicker.C:
+			err = s.storeHeartbeats()
+		case <-regionTicker.C:
+			err = s.regionHeartbeats()
+		case now := <-writeTicker.C:
+			err = s.splitRegions(s.cluster.write(now.Sub(lastWrite)))
+			lastWrite = now
+		case <-downCh:
+			s.setStoresDown(true)
+			if s.cfg.DownDuration.Duration > 0 {
+				upCh = time.After(s.cfg.DownDuration.Duration)
+			}
+		case <-upCh:
+			s.setStoresDown(false)
+		case <-statsTicker.C:
+			log.Infof("[sim] %s", s.Stats())
+		}
+		if err != nil {
+			log.Warnf("[sim] %v", errors.ErrorStack(err))
+		}
+	}
+}
+
+func (s *Simulator) storeHeartbeats() error {
+	for _, id := range s.cluster.storeIDs() {
+		if s.cluster.stores[id].isDown() {
+			continue
+		}
+		if err := s.client.storeHeartbeat(s.cluster.storeStats(id)); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+func (s *Simulator) regionHeartbeats() error {
+	for _, id := range s.cluster.regionIDs() {
+		heartbeat := s.cluster.heartbeat(s.cluster.regions[id])
+		if heartbeat == nil {
+			continue
+		}
+		resp, err := s.client.regionHeartbeat(heartbeat)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		s.cluster.apply(id, resp)
+	}
+	return nil
+}
+
+// splitRegions splits the regions by the IDs allocated by PD, the split
+// regions are reported at once.
+func (s *Simulator) splitRegions(regions []*region) error {
+	for _, r := range regions {
+		if s.cluster.heartbeat(r) == nil {
+			continue
+		}
+		resp, err := s.client.askSplit(r.meta)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		left, right := s.cluster.split(r, resp.GetNewRegionId(), resp.GetNewPeerIds())
+		if left == nil {
+			continue
+		}
+		if err = s.client.reportSplit(left, right); err != nil {
+			return errors.Trace(err)
+		}
+		for _, id := range []uint64{left.GetId(), right.GetId()} {
+			heartbeat := s.cluster.heartbeat(s.cluster.regions[id])
+			resp, err := s.client.regionHeartbeat(heartbeat)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			s.cluster.apply(id, resp)
+		}
+	}
+	return nil
+}
+
+func (s *Simulator) setStoresDown(down bool) {
+	for _, id := range s.downStores {
+		s.cluster.setStoreDown(id, down)
+	}
+	if down {
+		log.Infof("[sim] stores %v are down", s.downStores)
+	} else {
+		log.Infof("[sim] stores %v are up", s.downStores)
+	}
+}
+
+// Stats returns the regions, the leaders and the used size of the stores.
+func (s *Simulator) Stats() string {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%d regions", len(s.cluster.regions))
+	leaders := make(map[uint64]int)
+	for _, r := range s.cluster.regions {
+		leaders[r.leader.GetStoreId()]++
+	}
+	for _, id := range s.cluster.storeIDs() {
+		stats := s.cluster.storeStats(id)
+		fmt.Fprintf(buf, "\nstore %d: %d regions, %d leaders, %d MiB used", id, stats.GetRegionCount(), leaders[id], (stats.GetCapacity()-stats.GetAvailable())>>20)
+		if s.cluster.stores[id].isDown() {
+			buf.WriteString(", down")
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,120 @@
+// Copyright 2016 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulator
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/server"
+	"github.com/pingcap/pd/server/api"
+)
+
+func TestSimulator(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testSimulatorSuite{})
+
+type testSimulatorSuite struct {
+	cfg *server.Config
+	svr *server.Server
+}
+
+var stripUnix = strings.NewReplacer("unix://", "")
+
+func (s *testSimulatorSuite) SetUpTest(c *C) {
+	s.cfg = server.NewTestSingleConfig()
+	s.svr = server.CreateServer(s.cfg)
+	c.Assert(s.svr.StartEtcd(api.NewHandler(s.svr)), IsNil)
+	go s.svr.Run()
+	for i := 0; i < 100 && !s.svr.IsLeader(); i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	c.Assert(s.svr.IsLeader(), IsTrue)
+}
+
+func (s *testSimulatorSuite) TearDownTest(c *C) {
+	s.svr.Close()
+	os.RemoveAll(s.cfg.DataDir)
+	os.Remove(stripUnix.Replace(s.cfg.PeerUrls))
+	os.Remove(stripUnix.Replace(s.cfg.ClientUrls))
+}
+
+func (s *testSimulatorSuite) TestSimulator(c *C) {
+	cfg := NewConfig()
+	cfg.StoreCount = 4
+	cfg.RegionCount = 20
+	cfg.StoreHeartbeatInterval.Duration = 100 * time.Millisecond
+	cfg.RegionHeartbeatInterval.Duration = 100 * time.Millisecond
+	cfg.HotRegionRatio = 0.1
+	cfg.HotWriteRate = cfg.RegionSplitSize
+	cfg.DownStores = 1
+	cfg.DownAfter.Duration = 500 * time.Millisecond
+	c.Assert(cfg.validate(), IsNil)
+
+	sim := NewSimulator(s.cfg.ClientUrls, cfg)
+	defer sim.Close()
+	c.Assert(sim.Bootstrap(), IsNil)
+
+	cluster := s.svr.GetRaftCluster()
+	c.Assert(cluster, NotNil)
+	c.Assert(cluster.GetStores(), HasLen, 4)
+	c.Assert(cluster.GetRegions(), HasLen, 20)
+
+	quit := make(chan struct{})
+	time.AfterFunc(3*time.Second, func() { close(quit) })
+	sim.Run(quit)
+
+	// The hot regions are split, and the peers on the down store are
+	// reported.
+	c.Assert(len(cluster.GetRegions()), Greater, 20)
+	c.Assert(cluster.GetDownPeerRegions(), Not(HasLen), 0)
+	c.Assert(sim.Stats(), Matches, "(?s).*down.*")
+
+	// The cluster is bootstrapped already.
+	sim2 := NewSimulator(s.cfg.ClientUrls, cfg)
+	defer sim2.Close()
+	c.Assert(sim2.Bootstrap(), NotNil)
+}
+
+func (s *testSimulatorSuite) TestBench(c *C) {
+	cfg := NewConfig()
+	cfg.RegionCount = 100
+	sim := NewSimulator(s.cfg.ClientUrls, cfg)
+	defer sim.Close()
+	c.Assert(sim.Bootstrap(), IsNil)
+
+	result, err := sim.Bench(&BenchConfig{
+		RegionRate:  200,
+		StoreRate:   20,
+		Concurrency: 2,
+		Duration:    time.Second,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(result.Region.Errors, Equals, 0)
+	// Every worker sends at most one more heartbeat at the deadline.
+	c.Assert(result.Region.Count, Greater, 100)
+	c.Assert(result.Region.Count, LessEqual, 202)
+	c.Assert(result.Store.Count, Greater, 10)
+	c.Assert(result.Region.P50, LessEqual, result.Region.P99)
+	// The heartbeats are handled by the leader in the same process, so the
+	// metrics count all of them.
+	c.Assert(result.ServerRegion.Count, Equals, result.Region.Count)
+	c.Assert(result.ServerStore.Count, Equals, result.Store.Count)
+	c.Assert(result.ServerRegion.P99, Greater, time.Duration(0))
+}